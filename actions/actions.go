@@ -50,6 +50,7 @@ import (
 	"fmt"
 	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/schedule"
+	"time"
 )
 
 // ActionBuilder holds the action to build
@@ -131,6 +132,45 @@ func (ab *ActionBuilder) WithAnswerer(answerer slackscot.Answerer) *ActionBuilde
 	return ab
 }
 
+// WithMultiAnswerer sets the action's function answering with more than one message. It takes precedence over the
+// answerer (see WithAnswerer)
+func (ab *ActionBuilder) WithMultiAnswerer(answerer slackscot.MultiAnswerer) *ActionBuilder {
+	ab.action.AnswerAll = answerer
+	return ab
+}
+
+// WithInteractionHandler sets the action's handler of interactions with the interactive elements of its answers
+func (ab *ActionBuilder) WithInteractionHandler(handler slackscot.InteractionHandler) *ActionBuilder {
+	ab.action.InteractionHandler = handler
+	return ab
+}
+
+// WithReplyBehavior overrides the threaded replies configuration (and the plugin's) for the action's answers. See
+// slackscot.ReplyBehavior
+func (ab *ActionBuilder) WithReplyBehavior(replyBehavior slackscot.ReplyBehavior) *ActionBuilder {
+	ab.action.ReplyBehavior = &replyBehavior
+	return ab
+}
+
+// WithSideEffects sets the action as having side effects so that it only runs once per message (edits of the message
+// don't run it again). See slackscot.ActionDefinition.SideEffects
+func (ab *ActionBuilder) WithSideEffects() *ActionBuilder {
+	ab.action.SideEffects = true
+	return ab
+}
+
+// AdminOnly restricts the action to admins. See slackscot.ActionDefinition.AdminOnly
+func (ab *ActionBuilder) AdminOnly() *ActionBuilder {
+	ab.action.AdminOnly = true
+	return ab
+}
+
+// WithMiddleware adds middleware wrapping the action. See slackscot.Middleware
+func (ab *ActionBuilder) WithMiddleware(middleware ...slackscot.Middleware) *ActionBuilder {
+	ab.action.Middleware = append(ab.action.Middleware, middleware...)
+	return ab
+}
+
 // Hidden sets the action to hidden
 func (ab *ActionBuilder) Hidden() *ActionBuilder {
 	ab.action.Hidden = true
@@ -175,6 +215,18 @@ func (sab *ScheduledActionBuilder) WithAction(action slackscot.ScheduledAction)
 	return sab
 }
 
+// WithJitter sets the maximum random delay added to every run of the scheduled action
+func (sab *ScheduledActionBuilder) WithJitter(jitter time.Duration) *ScheduledActionBuilder {
+	sab.scheduledAction.Jitter = jitter
+	return sab
+}
+
+// WithMissedRuns sets what to do about runs missed while slackscot wasn't running (i.e. slackscot.RunOnceMissedRuns)
+func (sab *ScheduledActionBuilder) WithMissedRuns(policy slackscot.MissedRunPolicy) *ScheduledActionBuilder {
+	sab.scheduledAction.MissedRuns = policy
+	return sab
+}
+
 // Build returns the ScheduledActionDefinition
 func (sab *ScheduledActionBuilder) Build() slackscot.ScheduledActionDefinition {
 	return sab.scheduledAction
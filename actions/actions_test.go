@@ -4,8 +4,10 @@ import (
 	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/actions"
 	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/slack-go/slack"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNewCommandWithDefaults(t *testing.T) {
@@ -42,6 +44,50 @@ func TestNewActionWithAnswerer(t *testing.T) {
 	assert.Equal(t, &slackscot.Answer{Text: "fake answer"}, action.Answer(&slackscot.IncomingMessage{}))
 }
 
+func TestNewActionWithInteractionHandler(t *testing.T) {
+	action := actions.NewCommand().
+		WithInteractionHandler(func(i *slackscot.Interaction) *slack.ViewSubmissionResponse {
+			return slack.NewClearViewSubmissionResponse()
+		}).
+		Build()
+
+	assert.Equal(t, slack.NewClearViewSubmissionResponse(), action.InteractionHandler(&slackscot.Interaction{}))
+}
+
+func TestNewActionWithReplyBehavior(t *testing.T) {
+	action := actions.NewCommand().
+		WithReplyBehavior(slackscot.ReplyBehavior{ThreadedReplies: true, BroadcastThreadedReplies: true}).
+		Build()
+
+	assert.Equal(t, &slackscot.ReplyBehavior{ThreadedReplies: true, BroadcastThreadedReplies: true}, action.ReplyBehavior)
+}
+
+func TestNewActionWithSideEffects(t *testing.T) {
+	action := actions.NewCommand().
+		WithSideEffects().
+		Build()
+
+	assert.True(t, action.SideEffects)
+}
+
+func TestNewAdminOnlyAction(t *testing.T) {
+	action := actions.NewCommand().
+		AdminOnly().
+		Build()
+
+	assert.True(t, action.AdminOnly)
+}
+
+func TestNewActionWithMiddleware(t *testing.T) {
+	action := actions.NewCommand().
+		WithMiddleware(func(next slackscot.ActionHandler) slackscot.ActionHandler {
+			return next
+		}).
+		Build()
+
+	assert.Len(t, action.Middleware, 1)
+}
+
 func TestNewActionWithUsage(t *testing.T) {
 	action := actions.NewHearAction().
 		WithUsage("make something").
@@ -113,3 +159,24 @@ func TestNewScheduledActionWithAction(t *testing.T) {
 
 	assert.PanicsWithValue(t, "just checking that it's me", assert.PanicTestFunc(action.Action))
 }
+
+func TestNewScheduledActionWithJitterAndMissedRuns(t *testing.T) {
+	action := actions.NewScheduledAction().
+		WithJitter(5 * time.Minute).
+		WithMissedRuns(slackscot.RunOnceMissedRuns).
+		Build()
+
+	assert.Equal(t, 5*time.Minute, action.Jitter)
+	assert.Equal(t, slackscot.RunOnceMissedRuns, action.MissedRuns)
+}
+
+func TestNewActionWithMultiAnswerer(t *testing.T) {
+	a := actions.NewCommand().
+		WithMultiAnswerer(func(m *slackscot.IncomingMessage) []*slackscot.Answer {
+			return []*slackscot.Answer{{Text: "one"}, nil, {Text: "two"}}
+		}).
+		Build()
+
+	assert.Equal(t, []*slackscot.Answer{{Text: "one"}, {Text: "two"}}, a.Answers(&slackscot.IncomingMessage{}))
+	assert.Empty(t, actions.NewCommand().Build().Answers(&slackscot.IncomingMessage{}))
+}
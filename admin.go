@@ -0,0 +1,15 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+)
+
+// isAdmin returns true if the user is an admin (see config.AdminUsersKey)
+func (s *Slackscot) isAdmin(userID string) bool {
+	return containsString(s.config.GetStringSlice(config.AdminUsersKey), userID)
+}
+
+// adminOnlyRefusal returns the answer, only visible to the user, refusing an admin-only action to a non-admin
+func adminOnlyRefusal(m IncomingMessage) *Answer {
+	return &Answer{Text: "Sorry, only admins can do that :no_entry_sign:", Options: []AnswerOption{AnswerEphemeral(m.User)}}
+}
@@ -2,6 +2,8 @@ package slackscot
 
 import (
 	"github.com/slack-go/slack"
+	"strconv"
+	"time"
 )
 
 const (
@@ -13,6 +15,22 @@ const (
 	ThreadTimestamp = "threadTimestamp"
 	// EphemeralAnswerToOpt marks an answer to be sent as an ephemeral message to the provided userID
 	EphemeralAnswerToOpt = "ephemeralMsgToUserID"
+	// DeliverAtOpt is the name of the option indicating the unix time at which the answer should be delivered
+	DeliverAtOpt = "deliverAt"
+	// UsernameOpt is the name of the option indicating the username to post the answer as
+	UsernameOpt = "username"
+	// IconEmojiOpt is the name of the option indicating the emoji to use as the icon of the answer
+	IconEmojiOpt = "iconEmoji"
+	// IconURLOpt is the name of the option indicating the url of the image to use as the icon of the answer
+	IconURLOpt = "iconURL"
+	// InChannelOpt is the name of the option indicating that an answer to a message in a thread is posted in the channel
+	InChannelOpt = "inChannel"
+	// MetadataOpt is the name of the option holding the json-encoded metadata of the answer (see AnswerWithMetadata)
+	MetadataOpt = "metadata"
+
+	// outsideOfThreadOpt is the name of the option set on answers to messages in a thread that opted out of replying
+	// in that thread (see AnswerInChannel)
+	outsideOfThreadOpt = "outsideOfThread"
 )
 
 // Answer holds data of an Action's Answer: namely, its text and options
@@ -25,6 +43,18 @@ type Answer struct {
 
 	// BlockKit content blocks to apply when sending the message
 	ContentBlocks []slack.Block
+
+	// Interactive BlockKit elements (i.e. buttons) added in an actions block after the content blocks. User interactions
+	// with those are routed to the InteractionHandler of the action that answered. See ActionDefinition
+	InteractiveElements []slack.BlockElement
+
+	// Emoji reactions (i.e. thumbsup) added to the triggering message. Answers with reactions only (no text, content
+	// blocks or interactive elements) aren't sent as messages
+	Reactions []string
+
+	// Optional long-running task started in the background once the answer is sent. The answer's message is updated with
+	// the progress the task reports and then with its final answer. See AnswerWithProgress
+	Progress ProgressTask
 }
 
 // AnswerOption defines a function applied to Answers
@@ -68,6 +98,22 @@ func AnswerWithoutThreading() AnswerOption {
 	}
 }
 
+// AnswerInChannel posts the answer to a message that's part of a thread in the channel instead of in that thread. It
+// has no effect on answers to messages that aren't part of a thread
+func AnswerInChannel() AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[InChannelOpt] = "true"
+	}
+}
+
+// answerOutsideOfThread sets an answer to a message in a thread to be posted outside of any thread, regardless of
+// the threaded replies configuration
+func answerOutsideOfThread() AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[outsideOfThreadOpt] = "true"
+	}
+}
+
 // AnswerEphemeral sends the answer as an ephemeral message to the provided userID
 func AnswerEphemeral(userID string) AnswerOption {
 	return func(sendOpts map[string]string) {
@@ -75,6 +121,46 @@ func AnswerEphemeral(userID string) AnswerOption {
 	}
 }
 
+// AnswerWithDelay schedules the answer to be delivered after the given delay. Note that scheduled answers
+// are delivered by slack and aren't updated, scheduled again or deleted if the triggering message is later edited
+// or deleted. Ephemeral answers can't be scheduled and, like answers with a delay that isn't positive, are
+// delivered immediately
+func AnswerWithDelay(d time.Duration) AnswerOption {
+	return AnswerDeliveredAt(time.Now().Add(d))
+}
+
+// AnswerDeliveredAt schedules the answer to be delivered at the given time. The same limitations
+// as AnswerWithDelay apply
+func AnswerDeliveredAt(t time.Time) AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[DeliverAtOpt] = strconv.FormatInt(t.Unix(), 10)
+	}
+}
+
+// AnswerWithUsername posts the answer with a custom username instead of the bot's. Custom identities are only
+// applied if allowed by the workspace (see config.AllowCustomIdentitiesKey)
+func AnswerWithUsername(username string) AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[UsernameOpt] = username
+	}
+}
+
+// AnswerWithIconEmoji posts the answer with an emoji (i.e. :rotating_light:) as its icon instead of the bot's. The
+// same limitations as AnswerWithUsername apply
+func AnswerWithIconEmoji(emoji string) AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[IconEmojiOpt] = emoji
+	}
+}
+
+// AnswerWithIconURL posts the answer with an image (or an asset reference, i.e. asset:trophy) as its icon instead
+// of the bot's. The same limitations as AnswerWithUsername apply
+func AnswerWithIconURL(url string) AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[IconURLOpt] = url
+	}
+}
+
 // ApplyAnswerOpts applies answering options to build the send configuration
 func ApplyAnswerOpts(opts ...AnswerOption) (sendOptions map[string]string) {
 	sendOptions = make(map[string]string)
@@ -3,7 +3,9 @@ package slackscot_test
 import (
 	"github.com/alexandre-normand/slackscot"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestApplyAnswerOptions(t *testing.T) {
@@ -19,6 +21,7 @@ func TestApplyAnswerOptions(t *testing.T) {
 		{"noThreading", []slackscot.AnswerOption{slackscot.AnswerWithoutThreading()}, map[string]string{slackscot.ThreadedReplyOpt: "false"}},
 		{"threadReplyOnExistingThread", []slackscot.AnswerOption{slackscot.AnswerInExistingThread("1000")}, map[string]string{slackscot.ThreadedReplyOpt: "true", slackscot.ThreadTimestamp: "1000"}},
 		{"ephemeralAnswer", []slackscot.AnswerOption{slackscot.AnswerEphemeral("U12321")}, map[string]string{slackscot.EphemeralAnswerToOpt: "U12321"}},
+		{"deliveredAt", []slackscot.AnswerOption{slackscot.AnswerDeliveredAt(time.Unix(1546833210, 0))}, map[string]string{slackscot.DeliverAtOpt: "1546833210"}},
 	}
 
 	for _, tc := range testCases {
@@ -28,3 +31,14 @@ func TestApplyAnswerOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestAnswerWithDelay(t *testing.T) {
+	before := time.Now().Add(10 * time.Minute).Unix()
+	c := slackscot.ApplyAnswerOpts(slackscot.AnswerWithDelay(10 * time.Minute))
+	after := time.Now().Add(10 * time.Minute).Unix()
+
+	deliverAt, err := strconv.ParseInt(c[slackscot.DeliverAtOpt], 10, 64)
+	if assert.NoError(t, err) {
+		assert.True(t, deliverAt >= before && deliverAt <= after, "delivery time [%d] should be between [%d] and [%d]", deliverAt, before, after)
+	}
+}
@@ -0,0 +1,100 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"sort"
+)
+
+// Answer policy modes (see config.AnswerPolicyKey)
+const (
+	answerPolicyAll        = "all"
+	answerPolicyPriority   = "priority"
+	answerPolicyFirstMatch = "firstMatch"
+)
+
+// answerPolicy decides which of the answers triggered by a single message get sent when more
+// than one plugin (or action) answers it
+type answerPolicy struct {
+	mode       string
+	maxAnswers int
+}
+
+// pluginResponses holds the outgoing messages triggered by a plugin for a message
+type pluginResponses struct {
+	priority int
+	outMsgs  []OutgoingMessage
+}
+
+// newAnswerPolicy creates a new answerPolicy for a mode and a maximum number of answers (0 meaning no maximum). An error
+// is returned if the mode isn't one of all, priority or firstMatch
+func newAnswerPolicy(mode string, maxAnswers int) (ap answerPolicy, err error) {
+	switch mode {
+	case answerPolicyAll, answerPolicyPriority, answerPolicyFirstMatch:
+	default:
+		return ap, fmt.Errorf("%s config should be one of [%s, %s, %s] but was [%s]", config.AnswerPolicyKey, answerPolicyAll, answerPolicyPriority, answerPolicyFirstMatch, mode)
+	}
+
+	if maxAnswers < 0 {
+		return ap, fmt.Errorf("%s config should be 0 (no maximum) or more but was [%d]", config.MaxAnswersPerMessageKey, maxAnswers)
+	}
+
+	return answerPolicy{mode: mode, maxAnswers: maxAnswers}, nil
+}
+
+// apply returns the outgoing messages to send given all plugin responses (in plugin registration order).
+// With a policy other than the default (every answer, without maximum), duplicate answers (same text to the same
+// audience on the same channel and thread) are dropped so that they don't take the place of distinct ones
+func (ap answerPolicy) apply(responses []pluginResponses) (outMsgs []OutgoingMessage) {
+	if ap.mode == answerPolicyPriority {
+		sorted := append([]pluginResponses{}, responses...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].priority > sorted[j].priority
+		})
+		responses = sorted
+	}
+
+	outMsgs = make([]OutgoingMessage, 0)
+	seen := make(map[string]bool)
+
+	for _, pr := range responses {
+		for _, o := range pr.outMsgs {
+			if ap.mode == answerPolicyFirstMatch {
+				// The first match is the first answer along with the other answers of the same action, if it has many
+				if len(outMsgs) > 0 && (o.pluginActionID != outMsgs[0].pluginActionID || o.answerIndex == 0) {
+					return outMsgs
+				}
+			} else if ap.maxAnswers > 0 && len(outMsgs) >= ap.maxAnswers {
+				return outMsgs
+			}
+
+			if key, ok := dedupKey(o); ok && ap.dropsDuplicates() {
+				if seen[key] {
+					continue
+				}
+
+				seen[key] = true
+			}
+
+			outMsgs = append(outMsgs, o)
+		}
+	}
+
+	return outMsgs
+}
+
+// dropsDuplicates returns true if the policy isn't the default one, sending every answer as is
+func (ap answerPolicy) dropsDuplicates() bool {
+	return ap.mode != answerPolicyAll || ap.maxAnswers > 0
+}
+
+// dedupKey returns the key identifying duplicate answers. Answers with content blocks are never considered
+// duplicates since their text is only a fallback
+func dedupKey(o OutgoingMessage) (key string, ok bool) {
+	if len(o.ContentBlocks) > 0 {
+		return "", false
+	}
+
+	sendOpts := ApplyAnswerOpts(o.Options...)
+	return fmt.Sprintf("%s:%s:%s:%s", o.OutgoingMessage.Channel, sendOpts[ThreadTimestamp], sendOpts[EphemeralAnswerToOpt], o.OutgoingMessage.Text), true
+}
@@ -0,0 +1,156 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func newPolicyOutMsg(pluginName string, text string, opts ...AnswerOption) (o OutgoingMessage) {
+	return newOutMessageForAnswer(newSlackOutgoingMessage("Cgeneral", text), pluginName, Answer{Text: text, Options: opts})
+}
+
+func policyOutMsgTexts(outMsgs []OutgoingMessage) (texts []string) {
+	texts = make([]string, 0)
+	for _, o := range outMsgs {
+		texts = append(texts, o.OutgoingMessage.Text)
+	}
+
+	return texts
+}
+
+func TestAnswerPolicies(t *testing.T) {
+	responses := []pluginResponses{
+		{priority: 0, outMsgs: []OutgoingMessage{newPolicyOutMsg("low", "low 1"), newPolicyOutMsg("low", "low 2")}},
+		{priority: 0, outMsgs: []OutgoingMessage{}},
+		{priority: 5, outMsgs: []OutgoingMessage{newPolicyOutMsg("high", "high")}},
+		{priority: 1, outMsgs: []OutgoingMessage{newPolicyOutMsg("mid", "mid")}},
+	}
+
+	tests := map[string]struct {
+		mode          string
+		maxAnswers    int
+		expectedTexts []string
+	}{
+		"All":                  {answerPolicyAll, 0, []string{"low 1", "low 2", "high", "mid"}},
+		"AllCapped":            {answerPolicyAll, 3, []string{"low 1", "low 2", "high"}},
+		"Priority":             {answerPolicyPriority, 0, []string{"high", "mid", "low 1", "low 2"}},
+		"PriorityCapped":       {answerPolicyPriority, 2, []string{"high", "mid"}},
+		"FirstMatch":           {answerPolicyFirstMatch, 0, []string{"low 1"}},
+		"FirstMatchIgnoresMax": {answerPolicyFirstMatch, 3, []string{"low 1"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ap, err := newAnswerPolicy(tc.mode, tc.maxAnswers)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expectedTexts, policyOutMsgTexts(ap.apply(responses)))
+			}
+		})
+	}
+}
+
+func TestAnswerPolicyDropsDuplicates(t *testing.T) {
+	blocks := newPolicyOutMsg("blocks", "fallback")
+	blocks.ContentBlocks = []slack.Block{slack.NewDividerBlock()}
+
+	responses := []pluginResponses{
+		{outMsgs: []OutgoingMessage{newPolicyOutMsg("first", "same"), newPolicyOutMsg("first", "other"), blocks}},
+		{outMsgs: []OutgoingMessage{newPolicyOutMsg("second", "same"), newPolicyOutMsg("second", "same", AnswerEphemeral("U123")), blocks}},
+		{outMsgs: []OutgoingMessage{newPolicyOutMsg("third", "same", AnswerInExistingThread("1546833215.036900"))}},
+	}
+
+	ap, err := newAnswerPolicy(answerPolicyPriority, 0)
+	if assert.NoError(t, err) {
+		outMsgs := ap.apply(responses)
+		assert.Equal(t, []string{"same", "other", "fallback", "same", "fallback", "same"}, policyOutMsgTexts(outMsgs))
+		assert.Equal(t, "blocks", outMsgs[4].pluginActionID)
+	}
+}
+
+func TestDefaultAnswerPolicyKeepsDuplicates(t *testing.T) {
+	responses := []pluginResponses{
+		{outMsgs: []OutgoingMessage{newPolicyOutMsg("first", "same")}},
+		{outMsgs: []OutgoingMessage{newPolicyOutMsg("second", "same")}},
+	}
+
+	ap, err := newAnswerPolicy(answerPolicyAll, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"same", "same"}, policyOutMsgTexts(ap.apply(responses)))
+	}
+
+	ap, err = newAnswerPolicy(answerPolicyAll, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"same"}, policyOutMsgTexts(ap.apply(responses)))
+	}
+}
+
+func TestInvalidAnswerPolicy(t *testing.T) {
+	tests := map[string]struct {
+		mode          string
+		maxAnswers    int
+		expectedError string
+	}{
+		"InvalidMode":        {"loudest", 0, "answerPolicy.mode config should be one of [all, priority, firstMatch] but was [loudest]"},
+		"NegativeMaxAnswers": {answerPolicyAll, -1, "answerPolicy.maxAnswersPerMessage config should be 0 (no maximum) or more but was [-1]"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := config.NewViperWithDefaults()
+			v.Set(config.AnswerPolicyKey, tc.mode)
+			v.Set(config.MaxAnswersPerMessageKey, tc.maxAnswers)
+
+			_, err := New("chicadee", v)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func newAnsweringPlugin(name string, priority int, text string) (p *Plugin) {
+	return &Plugin{Name: name, Priority: priority, Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "hello")
+		},
+		Usage:       "hello",
+		Description: "Says hello",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: text}
+		},
+	}}}
+}
+
+func TestAnswerPolicyAppliedToMessages(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.AnswerPolicyKey, answerPolicyPriority)
+	v.Set(config.MaxAnswersPerMessageKey, 1)
+
+	plugins := []*Plugin{newAnsweringPlugin("polite", 0, "Hello there"), newAnsweringPlugin("enthusiastic", 10, "HELLO!")}
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, v, plugins, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "hello", "Alphonse", timestamp1)),
+	}, nil)
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "HELLO!", vals.Get("text"))
+	}
+}
+
+func TestFirstMatchAnswerPolicyKeepsAllAnswersOfFirstAction(t *testing.T) {
+	second := newPolicyOutMsg("multi", "multi 2")
+	second.answerIndex = 1
+
+	responses := []pluginResponses{
+		{outMsgs: []OutgoingMessage{newPolicyOutMsg("multi", "multi 1"), second, newPolicyOutMsg("multi", "again")}},
+		{outMsgs: []OutgoingMessage{newPolicyOutMsg("other", "other")}},
+	}
+
+	ap, err := newAnswerPolicy(answerPolicyFirstMatch, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"multi 1", "multi 2"}, policyOutMsgTexts(ap.apply(responses)))
+	}
+}
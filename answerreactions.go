@@ -0,0 +1,46 @@
+package slackscot
+
+import (
+	"github.com/slack-go/slack"
+	"strings"
+)
+
+// alreadyReactedErr is the error slack returns when adding a reaction that's already on a message (i.e. when an edit of
+// the triggering message runs the action again)
+const alreadyReactedErr = "already_reacted"
+
+// hasContent returns true if the outgoing message has something to send as a message, as opposed to answers with only
+// reactions
+func (o OutgoingMessage) hasContent() bool {
+	return o.Answer.Text != "" || len(o.ContentBlocks) > 0 || len(o.InteractiveElements) > 0 || o.Progress != nil
+}
+
+// addAnswerReactions adds the reactions of the outgoing messages (see Answer.Reactions) to their triggering message and
+// returns the outgoing messages left to send: those with content. Answer hooks run first so that blocked answers don't
+// get their reactions added nor are sent
+func (s *Slackscot) addAnswerReactions(reactor messageReactor, triggeringMsgID SlackMessageID, outMsgs []OutgoingMessage) (toSend []OutgoingMessage) {
+	toSend = make([]OutgoingMessage, 0, len(outMsgs))
+
+	for _, o := range outMsgs {
+		o, allow := s.applyAnswerHooksToMsg(o)
+		if !allow {
+			continue
+		}
+
+		for _, reaction := range o.Reactions {
+			name := strings.Trim(reaction, ":")
+			err := reactor.AddReaction(name, slack.NewRefToMessage(triggeringMsgID.channelID, triggeringMsgID.timestamp))
+			if err != nil && err.Error() == alreadyReactedErr {
+				s.log.Debugf("Reaction [%s] of [%s] already on [%s]", name, o.pluginActionID, triggeringMsgID)
+			} else if err != nil {
+				s.log.Printf("Unable to add reaction [%s] of [%s] to [%s]: %v", name, o.pluginActionID, triggeringMsgID, err)
+			}
+		}
+
+		if o.hasContent() {
+			toSend = append(toSend, o)
+		}
+	}
+
+	return toSend
+}
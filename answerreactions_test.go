@@ -0,0 +1,102 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newApproverPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "approver"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "ship it")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Reactions: []string{"thumbsup", ":rocket:"}}
+		},
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "deploy")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Deploying", Reactions: []string{"eyes"}}
+		},
+	}}
+
+	return p
+}
+
+// runApproverPlugin runs the approver plugin on the events and returns the chat driver once they're processed
+func runApproverPlugin(t *testing.T, events ...slack.RTMEvent) (driver *inMemoryChatDriver) {
+	driver = &inMemoryChatDriver{timeCursor: firstReplyTimestamp - replyTimeIncrementInSeconds}
+	s, ec, termination := startSlackscotForShutdown(t, newApproverPlugin(), driver)
+
+	for _, e := range events {
+		ec <- e
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, s.Shutdown(ctx))
+	<-termination
+
+	return driver
+}
+
+func TestAnswerWithReactionsOnly(t *testing.T) {
+	driver := runApproverPlugin(t, newRTMMessageEvent(newMessageEvent("Cgeneral", "ship it", "Alphonse", timestamp1)))
+
+	assert.Empty(t, driver.sentMsgs)
+	assert.Equal(t, []addedReaction{
+		{name: "thumbsup", item: slack.NewRefToMessage("Cgeneral", timestamp1)},
+		{name: "rocket", item: slack.NewRefToMessage("Cgeneral", timestamp1)},
+	}, driver.addedReactions)
+}
+
+func TestAnswerWithTextAndReactions(t *testing.T) {
+	driver := runApproverPlugin(t,
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "deploy", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage("deploy now", "Alphonse", timestamp1))))
+
+	if assert.Len(t, driver.sentMsgs, 1) {
+		assert.Equal(t, "Deploying", applySlackOptions(driver.sentMsgs[0].msgOptions...).Get("text"))
+	}
+	assert.Len(t, driver.updatedMsgs, 1)
+
+	// Edits run the action again so the reaction is added again (slack ignores it)
+	assert.Equal(t, []addedReaction{
+		{name: "eyes", item: slack.NewRefToMessage("Cgeneral", timestamp1)},
+		{name: "eyes", item: slack.NewRefToMessage("Cgeneral", timestamp1)},
+	}, driver.addedReactions)
+}
+
+type failingReactor struct {
+	err error
+}
+
+func (r failingReactor) AddReaction(name string, item slack.ItemRef) error {
+	return r.err
+}
+
+func TestAddAnswerReactionsErrors(t *testing.T) {
+	var logs strings.Builder
+	s := newProgressSlackscot(t)
+	s.log = NewSLogger(log.New(&logs, "", 0), true)
+
+	outMsgs := []OutgoingMessage{newOutMessageForAnswer(newSlackOutgoingMessage("Cgeneral", ""), "approver.hearAction[0]", Answer{Reactions: []string{"thumbsup"}})}
+
+	assert.Empty(t, s.addAnswerReactions(failingReactor{err: fmt.Errorf("already_reacted")}, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, outMsgs))
+	assert.Empty(t, s.addAnswerReactions(failingReactor{err: fmt.Errorf("invalid_name")}, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, outMsgs))
+
+	assert.Contains(t, logs.String(), "Reaction [thumbsup] of [approver.hearAction[0]] already on [Cgeneral/1546833210.036900]")
+	assert.Contains(t, logs.String(), "Unable to add reaction [thumbsup] of [approver.hearAction[0]] to [Cgeneral/1546833210.036900]: invalid_name")
+}
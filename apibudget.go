@@ -0,0 +1,207 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/metric"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Calls per minute allowed by slack's Web API rate limit tiers (see https://api.slack.com/docs/rate-limits)
+const (
+	apiTier1CallsPerMinute = 1
+	apiTier2CallsPerMinute = 20
+	apiTier3CallsPerMinute = 50
+	apiTier4CallsPerMinute = 100
+
+	// chat.postMessage has a special tier of roughly one message per second (per channel, with bursts tolerated)
+	apiPostMessageCallsPerMinute = 60
+)
+
+// apiBudgetWindow is the window over which calls are counted against the budget of their method
+const apiBudgetWindow = time.Minute
+
+// apiMethodCallsPerMinute holds the calls per minute allowed for known Web API methods. Other methods are assumed to
+// be tier 3 methods
+var apiMethodCallsPerMinute = map[string]int{
+	"chat.postMessage":      apiPostMessageCallsPerMinute,
+	"chat.postEphemeral":    apiTier4CallsPerMinute,
+	"chat.update":           apiTier3CallsPerMinute,
+	"chat.delete":           apiTier3CallsPerMinute,
+	"chat.unfurl":           apiTier3CallsPerMinute,
+	"reactions.add":         apiTier3CallsPerMinute,
+	"reactions.remove":      apiTier3CallsPerMinute,
+	"conversations.history": apiTier3CallsPerMinute,
+	"conversations.replies": apiTier3CallsPerMinute,
+	"conversations.info":    apiTier3CallsPerMinute,
+	"users.info":            apiTier4CallsPerMinute,
+	"users.list":            apiTier2CallsPerMinute,
+	"emoji.list":            apiTier2CallsPerMinute,
+	"files.upload":          apiTier2CallsPerMinute,
+	"views.open":            apiTier4CallsPerMinute,
+	"views.publish":         apiTier4CallsPerMinute,
+	"rtm.connect":           apiTier1CallsPerMinute,
+}
+
+// lowPriorityAPIMethods are the methods of calls that can be shed when nearing their budget (see
+// config.RateLimitShedLowPriorityAboveKey) without affecting answers themselves
+var lowPriorityAPIMethods = map[string]bool{
+	"reactions.add": true,
+	"chat.unfurl":   true,
+}
+
+// APIBudget is the usage of the rate limit budget of a slack Web API method over the last minute
+type APIBudget struct {
+	Method    string
+	Limit     int
+	Used      int
+	Remaining int
+}
+
+// apiBudget counts calls to slack's Web API per method against the calls per minute allowed by their rate limit tier.
+// It reports the remaining budget of methods as metrics and sheds low-priority calls nearing their budget, if enabled
+type apiBudget struct {
+	appName       string
+	meter         metric.Meter
+	shedThreshold float64
+	now           func() time.Time
+
+	sync.Mutex
+	calls            map[string][]time.Time
+	remainingMetric  metric.Int64Gauge
+	remainingGauges  map[string]metric.BoundInt64Gauge
+	callsMetric      metric.Int64Counter
+	callsCounters    map[string]metric.BoundInt64Counter
+	shedMetric       metric.Int64Counter
+	shedCallCounters map[string]metric.BoundInt64Counter
+}
+
+// newAPIBudget creates a new apiBudget shedding low-priority calls above the fraction of their budget set by
+// config.RateLimitShedLowPriorityAboveKey
+func newAPIBudget(v *viper.Viper, appName string, meter metric.Meter) (b *apiBudget) {
+	b = new(apiBudget)
+	b.appName = appName
+	b.meter = meter
+	b.shedThreshold = v.GetFloat64(config.RateLimitShedLowPriorityAboveKey)
+	b.now = time.Now
+	b.calls = make(map[string][]time.Time)
+	b.remainingMetric = meter.NewInt64Gauge("slackApiBudgetRemaining", metric.WithKeys(key.New("name"), key.New("method")))
+	b.remainingGauges = make(map[string]metric.BoundInt64Gauge)
+	b.callsMetric = meter.NewInt64Counter("slackApiCalls", metric.WithKeys(key.New("name"), key.New("method")))
+	b.callsCounters = make(map[string]metric.BoundInt64Counter)
+	b.shedMetric = meter.NewInt64Counter("slackApiCallsShed", metric.WithKeys(key.New("name"), key.New("method")))
+	b.shedCallCounters = make(map[string]metric.BoundInt64Counter)
+
+	return b
+}
+
+// methodLimit returns the calls per minute allowed for a method
+func methodLimit(method string) (limit int) {
+	if limit, ok := apiMethodCallsPerMinute[method]; ok {
+		return limit
+	}
+
+	return apiTier3CallsPerMinute
+}
+
+// usedLocked returns the number of calls to a method within the budget window, forgetting older calls. The lock
+// must be held
+func (b *apiBudget) usedLocked(method string, now time.Time) (used int) {
+	calls := b.calls[method]
+
+	i := 0
+	for i < len(calls) && now.Sub(calls[i]) >= apiBudgetWindow {
+		i++
+	}
+
+	b.calls[method] = calls[i:]
+
+	return len(b.calls[method])
+}
+
+// reserve counts a call to a method against its budget and returns true or, if the call is a low-priority one
+// and its method is over the shedding threshold, returns false without counting it
+func (b *apiBudget) reserve(method string) (allowed bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	now := b.now()
+	used := b.usedLocked(method, now)
+	limit := methodLimit(method)
+
+	labels := b.meter.Labels(key.New("name").String(b.appName), key.New("method").String(method))
+	if b.shedThreshold > 0 && lowPriorityAPIMethods[method] && float64(used+1) > b.shedThreshold*float64(limit) {
+		if _, ok := b.shedCallCounters[method]; !ok {
+			b.shedCallCounters[method] = b.shedMetric.Bind(labels)
+		}
+		b.shedCallCounters[method].Add(context.Background(), 1)
+
+		return false
+	}
+
+	b.calls[method] = append(b.calls[method], now)
+
+	if _, ok := b.callsCounters[method]; !ok {
+		b.callsCounters[method] = b.callsMetric.Bind(labels)
+		b.remainingGauges[method] = b.remainingMetric.Bind(labels)
+	}
+	b.callsCounters[method].Add(context.Background(), 1)
+	b.remainingGauges[method].Set(context.Background(), int64(maxInt(limit-used-1, 0)))
+
+	return true
+}
+
+// budgets returns the usage of the budget of every method called, by method name
+func (b *apiBudget) budgets() (budgets []APIBudget) {
+	b.Lock()
+	defer b.Unlock()
+
+	now := b.now()
+	budgets = make([]APIBudget, 0, len(b.calls))
+	for method := range b.calls {
+		used := b.usedLocked(method, now)
+		limit := methodLimit(method)
+		budgets = append(budgets, APIBudget{Method: method, Limit: limit, Used: used, Remaining: maxInt(limit-used, 0)})
+	}
+
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].Method < budgets[j].Method
+	})
+
+	return budgets
+}
+
+// APIBudgets returns the usage of the rate limit budget of the slack Web API methods called over the last minute,
+// by method name. Calls made by plugins with their SlackClient are included
+func (s *Slackscot) APIBudgets() (budgets []APIBudget) {
+	return s.apiBudget.budgets()
+}
+
+// httpDoer is implemented by http clients. It is satisfied by *http.Client and used by the slack client
+type httpDoer interface {
+	Do(req *http.Request) (resp *http.Response, err error)
+}
+
+// budgetedHTTPClient counts the calls to slack's Web API against their budget, shedding low-priority ones nearing it
+type budgetedHTTPClient struct {
+	client httpDoer
+	budget *apiBudget
+}
+
+// Do counts the call against the budget of its method (the last element of the url path, i.e. chat.postMessage)
+// before making it
+func (c budgetedHTTPClient) Do(req *http.Request) (resp *http.Response, err error) {
+	method := path.Base(req.URL.Path)
+	if !c.budget.reserve(method) {
+		return nil, fmt.Errorf("Shedding low-priority [%s] call to stay within its budget of %d calls per minute", method, methodLimit(method))
+	}
+
+	return c.client.Do(req)
+}
@@ -0,0 +1,77 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newSlackTestServer(t *testing.T) (server *httptest.Server) {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"ok": true}`))
+		require.NoError(t, err)
+	}))
+}
+
+func TestAPIBudgetCountsCallsPerMethod(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	now := time.Date(2019, 1, 7, 10, 0, 0, 0, time.UTC)
+	s.apiBudget.now = func() time.Time { return now }
+
+	server := newSlackTestServer(t)
+	defer server.Close()
+
+	sc := slack.New("token", slack.OptionAPIURL(server.URL+"/"), slack.OptionHTTPClient(budgetedHTTPClient{client: &http.Client{}, budget: s.apiBudget}))
+	require.NoError(t, sc.AddReaction("tada", slack.NewRefToMessage("Cgeneral", timestamp1)))
+	require.NoError(t, sc.AddReaction("tada", slack.NewRefToMessage("Cgeneral", timestamp2)))
+	_, _, err = sc.DeleteMessage("Cgeneral", timestamp1)
+	require.NoError(t, err)
+
+	assert.Equal(t, []APIBudget{{Method: "chat.delete", Limit: 50, Used: 1, Remaining: 49}, {Method: "reactions.add", Limit: 50, Used: 2, Remaining: 48}}, s.APIBudgets())
+
+	// Calls older than a minute no longer count against the budget
+	now = now.Add(time.Minute)
+	assert.Equal(t, []APIBudget{{Method: "chat.delete", Limit: 50, Used: 0, Remaining: 50}, {Method: "reactions.add", Limit: 50, Used: 0, Remaining: 50}}, s.APIBudgets())
+}
+
+func TestAPIBudgetShedsLowPriorityCallsNearLimit(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.RateLimitShedLowPriorityAboveKey, 0.1)
+
+	s, err := New("chickadee", v)
+	require.NoError(t, err)
+
+	server := newSlackTestServer(t)
+	defer server.Close()
+
+	sc := slack.New("token", slack.OptionAPIURL(server.URL+"/"), slack.OptionHTTPClient(budgetedHTTPClient{client: &http.Client{}, budget: s.apiBudget}))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, sc.AddReaction("tada", slack.NewRefToMessage("Cgeneral", timestamp1)))
+	}
+
+	err = sc.AddReaction("tada", slack.NewRefToMessage("Cgeneral", timestamp1))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Shedding low-priority [reactions.add] call to stay within its budget of 50 calls per minute")
+
+	// Other calls are never shed
+	for i := 0; i < 6; i++ {
+		_, _, err = sc.DeleteMessage("Cgeneral", timestamp1)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []APIBudget{{Method: "chat.delete", Limit: 50, Used: 6, Remaining: 44}, {Method: "reactions.add", Limit: 50, Used: 5, Remaining: 45}}, s.APIBudgets())
+}
+
+func TestUnknownAPIMethodsAreTier3(t *testing.T) {
+	assert.Equal(t, 50, methodLimit("bots.info"))
+	assert.Equal(t, 60, methodLimit("chat.postMessage"))
+	assert.Equal(t, 20, methodLimit("users.list"))
+}
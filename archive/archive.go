@@ -0,0 +1,49 @@
+// Package archive provides the sinks the archiver plugin streams channel messages to (see plugins.NewArchiver). Sinks
+// for JSONL objects on S3 and for SQL databases such as Postgres live here while the BigQuery sink lives in the
+// bigquerysink subpackage to keep its dependencies out of deployments that don't need it
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Message is an archived channel message. Fields can be empty if redacted by the archiver's rules
+type Message struct {
+	ChannelID       string    `json:"channelID" bigquery:"channel_id"`
+	UserID          string    `json:"userID,omitempty" bigquery:"user_id"`
+	Timestamp       string    `json:"ts" bigquery:"ts"`
+	ThreadTimestamp string    `json:"threadTS,omitempty" bigquery:"thread_ts"`
+	Text            string    `json:"text,omitempty" bigquery:"text"`
+	PostedAt        time.Time `json:"postedAt" bigquery:"posted_at"`
+}
+
+// Sink is implemented by destinations of archived messages
+type Sink interface {
+	// Write writes a batch of messages. On error, the whole batch is retried later so sinks should
+	// avoid partial writes when possible
+	Write(messages []Message) (err error)
+}
+
+// SinkFunc is an adapter to allow the use of a function as a Sink
+type SinkFunc func(messages []Message) (err error)
+
+// Write calls f(messages)
+func (f SinkFunc) Write(messages []Message) (err error) {
+	return f(messages)
+}
+
+// EncodeJSONL encodes messages as JSON lines
+func EncodeJSONL(messages []Message) (encoded []byte, err error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+
+	for _, m := range messages {
+		if err := encoder.Encode(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var messages = []Message{
+	{ChannelID: "Cgeneral", UserID: "U1", Timestamp: "1583139600.000100", Text: "hello", PostedAt: time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)},
+	{ChannelID: "Cgeneral", Timestamp: "1583139660.000100", ThreadTimestamp: "1583139600.000100", PostedAt: time.Date(2020, time.March, 2, 9, 1, 0, 0, time.UTC)},
+}
+
+func TestEncodeJSONL(t *testing.T) {
+	encoded, err := EncodeJSONL(messages)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"channelID":"Cgeneral","userID":"U1","ts":"1583139600.000100","text":"hello","postedAt":"2020-03-02T09:00:00Z"}
+{"channelID":"Cgeneral","ts":"1583139660.000100","threadTS":"1583139600.000100","postedAt":"2020-03-02T09:01:00Z"}
+`, string(encoded))
+}
+
+func TestS3SinkPutsSignedObject(t *testing.T) {
+	var path, authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		content, _ := ioutil.ReadAll(r.Body)
+		body = string(content)
+	}))
+	defer server.Close()
+
+	s := NewS3Sink(S3Config{Endpoint: server.URL + "/", Region: "us-east-1", Bucket: "analytics", Prefix: "/slack/", AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	s.now = func() time.Time { return time.Date(2020, time.March, 2, 9, 5, 0, 0, time.UTC) }
+
+	require.NoError(t, s.Write(messages[:1]))
+	assert.Equal(t, "/analytics/slack/dt=2020-03-02/1583139900000000000.jsonl", path)
+	assert.Regexp(t, "\\AAWS4-HMAC-SHA256 Credential=AKID/20200302/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}\\z", authorization)
+	assert.Equal(t, "{\"channelID\":\"Cgeneral\",\"userID\":\"U1\",\"ts\":\"1583139600.000100\",\"text\":\"hello\",\"postedAt\":\"2020-03-02T09:00:00Z\"}\n", body)
+}
+
+func TestS3SinkErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	s := NewS3Sink(S3Config{Endpoint: server.URL, Region: "us-east-1", Bucket: "analytics"})
+	s.now = func() time.Time { return time.Date(2020, time.March, 2, 9, 5, 0, 0, time.UTC) }
+
+	assert.EqualError(t, s.Write(messages), "unexpected status [403 Forbidden] writing [dt=2020-03-02/1583139900000000000.jsonl] to bucket [analytics]")
+}
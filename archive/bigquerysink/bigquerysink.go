@@ -0,0 +1,44 @@
+// Package bigquerysink provides an archive.Sink streaming messages into a BigQuery table
+package bigquerysink
+
+import (
+	"cloud.google.com/go/bigquery"
+	"context"
+	"github.com/alexandre-normand/slackscot/archive"
+	"google.golang.org/api/option"
+	"time"
+)
+
+const (
+	insertTimeout = 30 * time.Second
+)
+
+// inserter is implemented by bigquery.Inserter
+type inserter interface {
+	Put(ctx context.Context, src interface{}) (err error)
+}
+
+// BigQuerySink streams messages into a BigQuery table. The table's schema should match the bigquery tags of
+// archive.Message (see bigquery.InferSchema)
+type BigQuerySink struct {
+	inserter
+}
+
+// New returns a new BigQuerySink for the table of the dataset. Like with the datastoredb store, at least one option
+// to provide gcloud client credentials is required
+func New(gcloudProjectID string, datasetID string, tableID string, gcloudClientOpts ...option.ClientOption) (s *BigQuerySink, err error) {
+	client, err := bigquery.NewClient(context.Background(), gcloudProjectID, gcloudClientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BigQuerySink{inserter: client.Dataset(datasetID).Table(tableID).Inserter()}, nil
+}
+
+// Write streams the messages into the table
+func (s *BigQuerySink) Write(messages []archive.Message) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), insertTimeout)
+	defer cancel()
+
+	return s.Put(ctx, messages)
+}
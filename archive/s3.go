@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	s3RequestTimeout = 30 * time.Second
+	amzDateFormat    = "20060102T150405Z"
+	amzDayFormat     = "20060102"
+)
+
+// S3Config holds the bucket and credentials of an S3Sink
+type S3Config struct {
+	// Region of the bucket (i.e. us-east-1)
+	Region string
+	// Bucket objects are written to
+	Bucket string
+	// Prefix of the keys of objects, without a trailing slash
+	Prefix string
+	// AccessKeyID of the credentials, which need the s3:PutObject permission on the bucket
+	AccessKeyID string
+	// SecretAccessKey of the credentials
+	SecretAccessKey string
+	// Endpoint of the S3 API, defaults to https://s3.<region>.amazonaws.com. Set it to use S3-compatible storage
+	Endpoint string
+}
+
+// S3Sink writes each batch of messages as a JSONL object on S3. Objects are partitioned by day with keys like
+// <prefix>/dt=2020-03-02/1583139600000000000.jsonl so that they can be queried with Athena or loaded by
+// most data warehouses
+type S3Sink struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Sink creates a new S3Sink. Requests are signed with AWS signature version 4
+func NewS3Sink(config S3Config) (s *S3Sink) {
+	s = new(S3Sink)
+	s.config = config
+	s.config.Prefix = strings.Trim(config.Prefix, "/")
+	s.client = &http.Client{Timeout: s3RequestTimeout}
+	s.now = time.Now
+
+	if s.config.Endpoint == "" {
+		s.config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+
+	s.config.Endpoint = strings.TrimSuffix(s.config.Endpoint, "/")
+
+	return s
+}
+
+// Write uploads the messages as a new JSONL object
+func (s *S3Sink) Write(messages []Message) (err error) {
+	body, err := EncodeJSONL(messages)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	key := fmt.Sprintf("dt=%s/%d.jsonl", now.Format("2006-01-02"), now.UnixNano())
+	if s.config.Prefix != "" {
+		key = s.config.Prefix + "/" + key
+	}
+
+	return s.putObject(key, body, now)
+}
+
+// putObject uploads an object with a request signed with AWS signature version 4
+func (s *S3Sink) putObject(key string, body []byte, now time.Time) (err error) {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	path := fmt.Sprintf("/%s/%s", url.PathEscape(s.config.Bucket), strings.Join(segments, "/"))
+
+	req, err := http.NewRequest("PUT", s.config.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format(amzDateFormat)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{"PUT", path, "", "host:" + req.URL.Host, "x-amz-content-sha256:" + payloadHash, "x-amz-date:" + amzDate, "", signedHeaders, payloadHash}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format(amzDayFormat), s.config.Region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), now.Format(amzDayFormat))
+	for _, part := range []string{s.config.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.config.AccessKeyID, scope, signedHeaders, signature))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status [%s] writing [%s] to bucket [%s]", resp.Status, key, s.config.Bucket)
+	}
+
+	return nil
+}
+
+// sha256Hex returns the hex encoded sha256 hash of data
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with the key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
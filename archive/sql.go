@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SQLSink inserts messages as rows of a SQL table. The insert statement uses Postgres placeholders ($1, $2...) and
+// expects a table like:
+//
+//	CREATE TABLE slack_messages (
+//	  channel_id TEXT NOT NULL,
+//	  user_id TEXT,
+//	  ts TEXT NOT NULL,
+//	  thread_ts TEXT,
+//	  text TEXT,
+//	  posted_at TIMESTAMPTZ NOT NULL,
+//	  PRIMARY KEY (channel_id, ts)
+//	);
+//
+// The database driver is up to the caller (i.e. github.com/lib/pq or github.com/jackc/pgx/stdlib)
+type SQLSink struct {
+	db     *sql.DB
+	insert string
+}
+
+// NewSQLSink creates a new SQLSink inserting messages in the table
+func NewSQLSink(db *sql.DB, table string) (s *SQLSink) {
+	s = new(SQLSink)
+	s.db = db
+	s.insert = fmt.Sprintf("INSERT INTO %s (channel_id, user_id, ts, thread_ts, text, posted_at) VALUES ($1, $2, $3, $4, $5, $6)", table)
+
+	return s
+}
+
+// Write inserts the messages in a single transaction
+func (s *SQLSink) Write(messages []Message) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(s.insert)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range messages {
+		if _, err := stmt.Exec(m.ChannelID, m.UserID, m.Timestamp, m.ThreadTimestamp, m.Text, m.PostedAt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,163 @@
+// Package assets serves images (i.e. leaderboard banners) from slackscot's own http server for workspaces where
+// networks block external image hosts. Rich outputs refer to assets by name with an asset reference
+// (i.e. asset:trophy) that is resolved to the url of the asset on slackscot's server:
+//
+//	r := assets.NewRegistry("https://slackscot.example.com")
+//	r.Resolve("asset:trophy") // https://slackscot.example.com/assets/trophy
+//
+// A few images are bundled (see BundledTrophy and BundledTombstone) and more can be added with Add or from
+// configuration with AddFromConfig.
+package assets
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// PathPrefix is the path prefix of assets served by a Registry
+	PathPrefix = "/assets/"
+
+	// RefPrefix is the prefix of asset references (i.e. asset:trophy)
+	RefPrefix = "asset:"
+
+	dataURIPrefix = "data:"
+)
+
+// Asset is a named image served by a Registry
+type Asset struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Registry holds assets and serves them over http
+type Registry struct {
+	baseURL string
+
+	mutex  sync.RWMutex
+	assets map[string]Asset
+}
+
+// NewRegistry creates a new Registry with the bundled assets. The base url is the public url of the server serving
+// the registry's assets (i.e. https://slackscot.example.com). If empty, asset references resolve to nothing
+func NewRegistry(baseURL string) (r *Registry) {
+	r = new(Registry)
+	r.baseURL = strings.TrimSuffix(baseURL, "/")
+	r.assets = make(map[string]Asset)
+
+	for _, a := range bundled() {
+		r.Add(a)
+	}
+
+	return r
+}
+
+// Add adds an asset to the registry. Adding an asset with the name of an existing one replaces it
+func (r *Registry) Add(a Asset) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.assets[a.Name] = a
+}
+
+// AddFromConfig adds assets from a map of names to either data uris (i.e. data:image/png;base64,iVBORw0...) or paths
+// of image files
+func (r *Registry) AddFromConfig(sources map[string]string) (err error) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		a, err := loadAsset(name, sources[name])
+		if err != nil {
+			return err
+		}
+
+		r.Add(a)
+	}
+
+	return nil
+}
+
+// loadAsset loads an asset from a data uri or the path of a file
+func loadAsset(name string, source string) (a Asset, err error) {
+	a.Name = name
+
+	if strings.HasPrefix(source, dataURIPrefix) {
+		parts := strings.SplitN(strings.TrimPrefix(source, dataURIPrefix), ",", 2)
+		if len(parts) != 2 || !strings.HasSuffix(parts[0], ";base64") {
+			return a, fmt.Errorf("Invalid data uri for asset [%s], should be data:<content type>;base64,<data>", name)
+		}
+
+		a.ContentType = strings.TrimSuffix(parts[0], ";base64")
+		if a.Data, err = base64.StdEncoding.DecodeString(parts[1]); err != nil {
+			return a, fmt.Errorf("Invalid data uri for asset [%s]: %v", name, err)
+		}
+
+		return a, nil
+	}
+
+	if a.Data, err = ioutil.ReadFile(source); err != nil {
+		return a, fmt.Errorf("Error loading asset [%s]: %v", name, err)
+	}
+
+	a.ContentType = mime.TypeByExtension(filepath.Ext(source))
+	if a.ContentType == "" {
+		a.ContentType = http.DetectContentType(a.Data)
+	}
+
+	return a, nil
+}
+
+// Resolve returns the url of an image. Asset references (i.e. asset:trophy) are resolved to the url of the asset
+// on the registry's server. Other urls are returned as-is. Unknown assets or a registry without base url (or a nil
+// registry) resolve to an empty url so that callers can leave the image out rather than render a broken one
+func (r *Registry) Resolve(url string) (resolved string) {
+	if !strings.HasPrefix(url, RefPrefix) {
+		return url
+	}
+
+	if r == nil {
+		return ""
+	}
+
+	name := strings.TrimPrefix(url, RefPrefix)
+
+	r.mutex.RLock()
+	_, ok := r.assets[name]
+	r.mutex.RUnlock()
+
+	if !ok || r.baseURL == "" {
+		return ""
+	}
+
+	return r.baseURL + PathPrefix + name
+}
+
+// ServeHTTP serves the assets at PathPrefix<name>
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, PathPrefix)
+
+	r.mutex.RLock()
+	a, ok := r.assets[name]
+	r.mutex.RUnlock()
+
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(a.Data)
+}
@@ -0,0 +1,92 @@
+package assets_test
+
+import (
+	"github.com/alexandre-normand/slackscot/assets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	r := assets.NewRegistry("https://slackscot.example.com/")
+
+	assert.Equal(t, "https://slackscot.example.com/assets/trophy", r.Resolve("asset:trophy"))
+	assert.Equal(t, "https://slackscot.example.com/assets/tombstone", r.Resolve("asset:tombstone"))
+	assert.Equal(t, "", r.Resolve("asset:unicorn"))
+	assert.Equal(t, "https://example.com/image.png", r.Resolve("https://example.com/image.png"))
+}
+
+func TestResolveWithoutBaseURL(t *testing.T) {
+	r := assets.NewRegistry("")
+	assert.Equal(t, "", r.Resolve("asset:trophy"))
+
+	var nilRegistry *assets.Registry
+	assert.Equal(t, "", nilRegistry.Resolve("asset:trophy"))
+	assert.Equal(t, "https://example.com/image.png", nilRegistry.Resolve("https://example.com/image.png"))
+}
+
+func TestServeBundledAssets(t *testing.T) {
+	r := assets.NewRegistry("")
+
+	for _, name := range []string{assets.BundledTrophy, assets.BundledTombstone} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", assets.PathPrefix+name, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+
+		img, err := png.Decode(rec.Body)
+		require.NoError(t, err)
+		assert.Equal(t, 64, img.Bounds().Dx())
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", assets.PathPrefix+"unicorn", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAddFromConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logo.gif")
+	require.NoError(t, ioutil.WriteFile(path, []byte("GIF89a"), 0644))
+
+	r := assets.NewRegistry("https://slackscot.example.com")
+	require.NoError(t, r.AddFromConfig(map[string]string{"logo": path, "dot": "data:image/png;base64,aGVsbG8="}))
+
+	testCases := []struct {
+		name                string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{"logo", "image/gif", "GIF89a"},
+		{"dot", "image/png", "hello"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, "https://slackscot.example.com/assets/"+tc.name, r.Resolve("asset:"+tc.name))
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", assets.PathPrefix+tc.name, nil))
+			assert.Equal(t, tc.expectedContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestAddFromConfigWithInvalidSources(t *testing.T) {
+	r := assets.NewRegistry("")
+
+	assert.EqualError(t, r.AddFromConfig(map[string]string{"dot": "data:image/png,hello"}), "Invalid data uri for asset [dot], should be data:<content type>;base64,<data>")
+	assert.EqualError(t, r.AddFromConfig(map[string]string{"dot": "data:image/png;base64,!!"}), "Invalid data uri for asset [dot]: illegal base64 data at input byte 0")
+	assert.Error(t, r.AddFromConfig(map[string]string{"logo": "/does/not/exist.png"}))
+}
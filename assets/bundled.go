@@ -0,0 +1,87 @@
+package assets
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Names of the bundled assets
+const (
+	BundledTrophy    = "trophy"
+	BundledTombstone = "tombstone"
+)
+
+const bundledImageSize = 64
+
+var (
+	gold      = color.RGBA{R: 0xf2, G: 0xb7, B: 0x05, A: 0xff}
+	stone     = color.RGBA{R: 0x8a, G: 0x8d, B: 0x91, A: 0xff}
+	darkStone = color.RGBA{R: 0x5f, G: 0x62, B: 0x66, A: 0xff}
+)
+
+// bundled returns the bundled assets. Those are simple images drawn at startup so that they don't need to be
+// fetched from anywhere
+func bundled() (assets []Asset) {
+	return []Asset{
+		{Name: BundledTrophy, ContentType: "image/png", Data: encodePNG(drawTrophy())},
+		{Name: BundledTombstone, ContentType: "image/png", Data: encodePNG(drawTombstone())},
+	}
+}
+
+// drawTrophy draws a trophy cup with a stem and a base
+func drawTrophy() (img *image.RGBA) {
+	img = image.NewRGBA(image.Rect(0, 0, bundledImageSize, bundledImageSize))
+
+	fillEllipse(img, 32, 14, 20, 18, gold, func(x, y int) bool { return y >= 4 })
+	fillEllipse(img, 12, 16, 7, 7, gold, func(x, y int) bool { return x <= 14 })
+	fillEllipse(img, 52, 16, 7, 7, gold, func(x, y int) bool { return x >= 50 })
+	fillRect(img, image.Rect(29, 30, 35, 46), gold)
+	fillRect(img, image.Rect(20, 46, 44, 52), gold)
+	fillRect(img, image.Rect(16, 52, 48, 58), gold)
+
+	return img
+}
+
+// drawTombstone draws a tombstone with a cross
+func drawTombstone() (img *image.RGBA) {
+	img = image.NewRGBA(image.Rect(0, 0, bundledImageSize, bundledImageSize))
+
+	fillEllipse(img, 32, 24, 18, 18, stone, func(x, y int) bool { return y <= 24 })
+	fillRect(img, image.Rect(14, 24, 50, 56), stone)
+	fillRect(img, image.Rect(8, 56, 56, 60), darkStone)
+	fillRect(img, image.Rect(30, 16, 34, 40), darkStone)
+	fillRect(img, image.Rect(24, 22, 40, 26), darkStone)
+
+	return img
+}
+
+// fillRect fills a rectangle with the color
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, c)
+		}
+	}
+}
+
+// fillEllipse fills the points of an ellipse that are accepted by the filter with the color
+func fillEllipse(img *image.RGBA, cx int, cy int, rx int, ry int, c color.Color, filter func(x, y int) bool) {
+	for y := cy - ry; y <= cy+ry; y++ {
+		for x := cx - rx; x <= cx+rx; x++ {
+			dx, dy := float64(x-cx)/float64(rx), float64(y-cy)/float64(ry)
+			if dx*dx+dy*dy <= 1 && filter(x, y) {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// encodePNG encodes an image as png
+func encodePNG(img image.Image) (data []byte) {
+	var b bytes.Buffer
+	png.Encode(&b, img)
+
+	return b.Bytes()
+}
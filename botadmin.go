@@ -0,0 +1,122 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Names of the caches flushed by BotAdmin.FlushCaches
+const (
+	channelInfoCacheName = "channelInfo"
+	emojiCacheName       = "emoji"
+)
+
+// BotAdmin gives plugins (i.e. plugins.Admin) access to the management of the running slackscot instance. It's meant to
+// turn operational tasks into chat interactions so plugins using it should restrict their actions to admins (see
+// ActionDefinition.AdminOnly)
+type BotAdmin interface {
+	// PluginNames returns the names of the registered plugins, sorted
+	PluginNames() (names []string)
+
+	// IsPluginEnabled returns true if the plugin runs in the channel
+	IsPluginEnabled(pluginName string, channelID string) (enabled bool)
+
+	// SetPluginEnabled enables or disables a plugin in all channels at runtime. The change is persisted if slackscot has
+	// a feature flag storer (see OptionFeatureFlagStorer) and only lasts until slackscot restarts otherwise
+	SetPluginEnabled(pluginName string, enabled bool) (err error)
+
+	// Uptime returns for how long slackscot has been running
+	Uptime() (uptime time.Duration)
+
+	// Settings returns the effective configuration settings whose key starts with the prefix (case-insensitive), with
+	// secrets redacted (see config.EffectiveSettings)
+	Settings(prefix string) (settings []config.EffectiveSetting)
+
+	// FlushCaches drops the cached user info, channel info and emoji so that they're loaded from slack again and
+	// returns the names of the caches flushed. The responses to triggering messages are kept since dropping them would
+	// prevent updating and deleting those responses
+	FlushCaches() (flushed []string)
+}
+
+// botAdmin is the BotAdmin implementation injected in plugins
+type botAdmin struct {
+	s *Slackscot
+}
+
+// PluginNames returns the names of the registered plugins, sorted
+func (ba botAdmin) PluginNames() (names []string) {
+	names = make([]string, 0, len(ba.s.plugins))
+	for _, p := range ba.s.plugins {
+		names = append(names, p.Name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// IsPluginEnabled returns true if the plugin runs in the channel
+func (ba botAdmin) IsPluginEnabled(pluginName string, channelID string) (enabled bool) {
+	return ba.s.pluginRuns(pluginName, channelID, ba.s.isSafeModeChannel(channelID))
+}
+
+// SetPluginEnabled enables or disables a registered plugin in all channels. The plugin commands enabling and disabling
+// plugins can't be disabled since that would leave no way to enable plugins again
+func (ba botAdmin) SetPluginEnabled(pluginName string, enabled bool) (err error) {
+	if pluginName == pluginChannelsPluginName {
+		return fmt.Errorf("Plugin [%s] can't be disabled", pluginName)
+	}
+
+	for _, p := range ba.s.plugins {
+		if p.Name == pluginName {
+			return ba.s.pluginChannels.override(pluginName, allChannelsScope, enabled)
+		}
+	}
+
+	return fmt.Errorf("Plugin [%s] isn't registered", pluginName)
+}
+
+// Uptime returns for how long slackscot has been running
+func (ba botAdmin) Uptime() (uptime time.Duration) {
+	return time.Since(ba.s.startedAt)
+}
+
+// Settings returns the effective configuration settings whose key starts with the prefix, with secrets redacted
+func (ba botAdmin) Settings(prefix string) (settings []config.EffectiveSetting) {
+	prefix = strings.ToLower(prefix)
+
+	settings = make([]config.EffectiveSetting, 0)
+	for _, setting := range config.EffectiveSettings(ba.s.config, ba.s.configEnvPrefix, ba.s.configFlags) {
+		if strings.HasPrefix(setting.Key, prefix) {
+			settings = append(settings, setting)
+		}
+	}
+
+	return settings
+}
+
+// FlushCaches drops the cached user info, channel info and emoji, when cached
+func (ba botAdmin) FlushCaches() (flushed []string) {
+	flushed = make([]string, 0)
+
+	if cuf, ok := ba.s.userInfoFinder.(*cachingUserInfoFinder); ok && cuf.userProfileCache != nil {
+		cuf.userProfileCache.Purge()
+		flushed = append(flushed, userInfoCacheName)
+	}
+
+	if ccf, ok := ba.s.channelInfoFinder.(*cachingChannelInfoFinder); ok && ccf.channelCache != nil {
+		ccf.channelCache.Purge()
+		flushed = append(flushed, channelInfoCacheName)
+	}
+
+	if ef, ok := ba.s.emojiFinder.(*cachingEmojiFinder); ok {
+		ef.invalidate()
+		flushed = append(flushed, emojiCacheName)
+	}
+
+	ba.s.log.Printf("Flushed caches %v\n", flushed)
+
+	return flushed
+}
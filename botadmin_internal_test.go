@@ -0,0 +1,88 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newBotAdminSlackscot(t *testing.T) (s *Slackscot, karma *Plugin) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.TokenKey, "xoxb-secret")
+	v.Set(config.UserInfoCacheSizeKey, 10)
+	v.Set("plugins.karma.deniedChannelIDs", []string{"Crandom"})
+
+	s, err := New("robert", v)
+	require.NoError(t, err)
+
+	karma = &Plugin{Name: "karma"}
+	s.RegisterPlugin(karma)
+	s.RegisterPlugin(&Plugin{Name: "admin"})
+
+	s.channelInfoFinder = &countingChannelInfoFinder{}
+	s.emojiLister = &countingEmojiLister{}
+	require.NoError(t, s.injectServicesToPlugins(&flakyUserInfoFinder{}, s.log, nil, nil, nil, nil))
+
+	return s, karma
+}
+
+func TestBotAdminPlugins(t *testing.T) {
+	_, karma := newBotAdminSlackscot(t)
+
+	assert.Equal(t, []string{"admin", "karma"}, karma.BotAdmin.PluginNames())
+	assert.True(t, karma.BotAdmin.IsPluginEnabled("karma", "Cgeneral"))
+	assert.False(t, karma.BotAdmin.IsPluginEnabled("karma", "Crandom"))
+
+	// Without a feature flag storer, overrides only last until a restart
+	require.NoError(t, karma.BotAdmin.SetPluginEnabled("karma", true))
+	assert.True(t, karma.BotAdmin.IsPluginEnabled("karma", "Crandom"))
+
+	require.NoError(t, karma.BotAdmin.SetPluginEnabled("karma", false))
+	assert.False(t, karma.BotAdmin.IsPluginEnabled("karma", "Cgeneral"))
+
+	err := karma.BotAdmin.SetPluginEnabled("weather", false)
+	require.Error(t, err)
+	assert.Equal(t, "Plugin [weather] isn't registered", err.Error())
+
+	err = karma.BotAdmin.SetPluginEnabled(pluginChannelsPluginName, false)
+	require.Error(t, err)
+	assert.Equal(t, "Plugin [pluginChannels] can't be disabled", err.Error())
+}
+
+func TestBotAdminUptime(t *testing.T) {
+	s, karma := newBotAdminSlackscot(t)
+	s.startedAt = time.Now().Add(-time.Hour)
+
+	assert.True(t, karma.BotAdmin.Uptime() >= time.Hour)
+}
+
+func TestBotAdminSettingsRedactSecrets(t *testing.T) {
+	_, karma := newBotAdminSlackscot(t)
+
+	settings := karma.BotAdmin.Settings("Token")
+	assert.Equal(t, []config.EffectiveSetting{{Key: "token", Value: config.RedactedValue, Source: config.SourceOverride}}, settings)
+
+	settings = karma.BotAdmin.Settings("plugins.")
+	assert.Equal(t, []config.EffectiveSetting{{Key: "plugins.karma.deniedchannelids", Value: []string{"Crandom"}, Source: config.SourceOverride}}, settings)
+
+	assert.Empty(t, karma.BotAdmin.Settings("nothing"))
+}
+
+func TestBotAdminFlushCaches(t *testing.T) {
+	s, karma := newBotAdminSlackscot(t)
+
+	_, err := s.userInfoFinder.GetUserInfo("U21355")
+	require.NoError(t, err)
+	_, err = s.channelInfoFinder.GetConversationInfo("Cgeneral", false)
+	require.NoError(t, err)
+	_, err = s.emojiFinder.EmojiExists("partyparrot")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{userInfoCacheName, channelInfoCacheName, emojiCacheName}, karma.BotAdmin.FlushCaches())
+
+	assert.Equal(t, 0, s.userInfoFinder.(*cachingUserInfoFinder).userProfileCache.Len())
+	assert.Equal(t, 0, s.channelInfoFinder.(*cachingChannelInfoFinder).channelCache.Len())
+	assert.Nil(t, s.emojiFinder.(*cachingEmojiFinder).emoji)
+}
@@ -107,12 +107,28 @@ func (sb *Builder) loadPluginConfig(name string) (pc *config.PluginConfig, valid
 	return pc, true
 }
 
+// WithMiddleware adds middleware wrapping every command and hear action. See UseMiddleware
+func (sb *Builder) WithMiddleware(middleware ...Middleware) *Builder {
+	if sb.err != nil {
+		return sb
+	}
+
+	sb.bot.UseMiddleware(middleware...)
+
+	return sb
+}
+
 // Build returns the built slackscot instance. If there was an error during
-// setup, the error is returned along with a nil slackscot
+// setup (including unsatisfied plugin service requirements), the error is
+// returned along with a nil slackscot
 func (sb *Builder) Build() (s *Slackscot, err error) {
 	if sb.err != nil {
 		return nil, sb.err
 	}
 
+	if err = sb.bot.resolveServices(); err != nil {
+		return nil, err
+	}
+
 	return sb.bot, sb.err
 }
@@ -197,3 +197,22 @@ func (c CloseTester) Close() (err error) {
 
 	return nil
 }
+
+func TestNewSlackscotWithUnsatisfiedServiceRequirement(t *testing.T) {
+	b, err := slackscot.NewBot("jane", config.NewViperWithDefaults()).
+		WithPlugin(&slackscot.Plugin{Name: "recognition", Requires: []string{"karma.reader"}}).
+		Build()
+
+	assert.EqualError(t, err, "Service [karma.reader] required by plugin [recognition] isn't provided by any registered plugin")
+	assert.Nil(t, b)
+}
+
+func TestNewSlackscotWithPluginDependencyCycle(t *testing.T) {
+	b, err := slackscot.NewBot("jane", config.NewViperWithDefaults()).
+		WithPlugin(&slackscot.Plugin{Name: "a", Provides: map[string]interface{}{"x": 1}, Requires: []string{"y"}}).
+		WithPlugin(&slackscot.Plugin{Name: "b", Provides: map[string]interface{}{"y": 2}, Requires: []string{"x"}}).
+		Build()
+
+	assert.EqualError(t, err, "Dependency cycle detected between plugins: [a -> b -> a]")
+	assert.Nil(t, b)
+}
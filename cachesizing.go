@@ -0,0 +1,298 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/hashicorp/golang-lru"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/metric"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Names of the caches in cache metrics
+const (
+	userInfoCacheName  = "userInfo"
+	responsesCacheName = "responses"
+)
+
+const (
+	// targetCacheHitRate is the hit rate under which full caches grow when sized adaptively
+	targetCacheHitRate = 0.9
+
+	// minLookupsForCacheSizing is the minimum number of lookups between two adjustments of a cache size for its hit
+	// rate to be meaningful
+	minLookupsForCacheSizing = 100
+
+	bytesPerMegabyte = 1024 * 1024
+)
+
+// resizableCache is an ARC cache whose size can change at runtime. It counts the hits and misses of its lookups to
+// let its size adapt to them (see cacheSizer)
+type resizableCache struct {
+	lock   sync.RWMutex
+	cache  *lru.ARCCache
+	size   int
+	hits   int64
+	misses int64
+}
+
+// newResizableCache creates a new resizableCache of the given size
+func newResizableCache(size int) (c *resizableCache, err error) {
+	c = new(resizableCache)
+	c.size = size
+	c.cache, err = lru.NewARC(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Get looks up a key's value from the cache, counting the lookup as a hit or a miss
+func (c *resizableCache) Get(key interface{}) (value interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	value, ok = c.cache.Get(key)
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+
+	return value, ok
+}
+
+// Add adds a value to the cache
+func (c *resizableCache) Add(key, value interface{}) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	c.cache.Add(key, value)
+}
+
+// Remove removes a key from the cache
+func (c *resizableCache) Remove(key interface{}) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	c.cache.Remove(key)
+}
+
+// Purge removes all entries from the cache
+func (c *resizableCache) Purge() {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	c.cache.Purge()
+}
+
+// Contains checks if a key is in the cache without updating its recency or counting a lookup
+func (c *resizableCache) Contains(key interface{}) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.cache.Contains(key)
+}
+
+// Peek returns a key's value without updating its recency or counting a lookup
+func (c *resizableCache) Peek(key interface{}) (value interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.cache.Peek(key)
+}
+
+// Keys returns the keys in the cache
+func (c *resizableCache) Keys() []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.cache.Keys()
+}
+
+// Len returns the number of entries in the cache
+func (c *resizableCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.cache.Len()
+}
+
+// Size returns the maximum number of entries of the cache
+func (c *resizableCache) Size() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.size
+}
+
+// resize changes the size of the cache. Entries are kept, from the least to the most recently used, so that shrinking
+// evicts the least recently used ones
+func (c *resizableCache) resize(size int) (err error) {
+	resized, err := lru.NewARC(size)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, key := range c.cache.Keys() {
+		if value, ok := c.cache.Peek(key); ok {
+			resized.Add(key, value)
+		}
+	}
+
+	c.cache = resized
+	c.size = size
+
+	return nil
+}
+
+// takeStats returns the hits and misses of the cache since they were last taken
+func (c *resizableCache) takeStats() (hits int64, misses int64) {
+	return atomic.SwapInt64(&c.hits, 0), atomic.SwapInt64(&c.misses, 0)
+}
+
+// sizedCache is a cache managed by a cacheSizer
+type sizedCache struct {
+	cache       *resizableCache
+	minSize     int
+	sizeGauge   metric.BoundInt64Gauge
+	hitPctGauge metric.BoundInt64Gauge
+}
+
+// cacheSizer reports the sizes of caches as metrics and, when adaptive, adjusts them periodically (see
+// config.CacheSizingAdaptiveKey): full caches missing too often grow, up to a hard cap, and caches shrink back
+// towards their configured sizes when the heap grows over the memory limit
+type cacheSizer struct {
+	appName      string
+	meter        metric.Meter
+	logger       SLogger
+	adaptive     bool
+	interval     time.Duration
+	maxSize      int
+	memoryLimit  uint64
+	heapAlloc    func() uint64
+	caches       map[string]sizedCache
+	sizeMetric   metric.Int64Gauge
+	hitPctMetric metric.Int64Gauge
+}
+
+// newCacheSizer creates a new cacheSizer from the config.CacheSizing* configuration
+func newCacheSizer(v *viper.Viper, appName string, meter metric.Meter, logger SLogger) (cs *cacheSizer) {
+	cs = new(cacheSizer)
+	cs.appName = appName
+	cs.meter = meter
+	cs.logger = logger
+	cs.adaptive = v.GetBool(config.CacheSizingAdaptiveKey)
+	cs.interval = v.GetDuration(config.CacheSizingIntervalKey)
+	cs.maxSize = v.GetInt(config.CacheSizingMaxSizeKey)
+	cs.memoryLimit = uint64(v.GetInt(config.CacheSizingMemoryLimitKey)) * bytesPerMegabyte
+	cs.heapAlloc = func() uint64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+
+		return m.HeapAlloc
+	}
+	cs.caches = make(map[string]sizedCache)
+	cs.sizeMetric = meter.NewInt64Gauge("cacheSize", metric.WithKeys(key.New("name"), key.New("cache")))
+	cs.hitPctMetric = meter.NewInt64Gauge("cacheHitRatePercent", metric.WithKeys(key.New("name"), key.New("cache")))
+
+	return cs
+}
+
+// register adds a cache to size. Its current size is the minimum it shrinks to and is reported right away
+func (cs *cacheSizer) register(name string, cache *resizableCache) {
+	labels := cs.meter.Labels(key.New("name").String(cs.appName), key.New("cache").String(name))
+
+	sc := sizedCache{cache: cache, minSize: cache.Size(), sizeGauge: cs.sizeMetric.Bind(labels), hitPctGauge: cs.hitPctMetric.Bind(labels)}
+	sc.sizeGauge.Set(context.Background(), int64(sc.minSize))
+
+	cs.caches[name] = sc
+}
+
+// run adjusts the sizes of caches every interval until the context is done. It returns right away unless sizing is
+// adaptive
+func (cs *cacheSizer) run(ctx context.Context) {
+	if !cs.adaptive || cs.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cs.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cs.adjust()
+		}
+	}
+}
+
+// adjust adjusts the size of every cache according to its hit rate since the last adjustment and the heap size
+func (cs *cacheSizer) adjust() {
+	overMemoryLimit := cs.memoryLimit > 0 && cs.heapAlloc() > cs.memoryLimit
+
+	names := make([]string, 0, len(cs.caches))
+	for name := range cs.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sc := cs.caches[name]
+		hits, misses := sc.cache.takeStats()
+		size := sc.cache.Size()
+
+		var hitRate float64
+		if lookups := hits + misses; lookups > 0 {
+			hitRate = float64(hits) / float64(lookups)
+			sc.hitPctGauge.Set(context.Background(), int64(hitRate*100))
+		}
+
+		newSize := size
+		if overMemoryLimit {
+			newSize = maxInt(size/2, sc.minSize)
+		} else if hits+misses >= minLookupsForCacheSizing && hitRate < targetCacheHitRate && sc.cache.Len() >= size {
+			newSize = minInt(size*2, maxInt(cs.maxSize, sc.minSize))
+		}
+
+		if newSize != size {
+			if err := sc.cache.resize(newSize); err != nil {
+				cs.logger.Printf("Error resizing the [%s] cache to %d: %v\n", name, newSize, err)
+				continue
+			}
+
+			cs.logger.Debugf("Resized the [%s] cache from %d to %d (hit rate: %.2f, over memory limit: %t)\n", name, size, newSize, hitRate, overMemoryLimit)
+		}
+
+		sc.sizeGauge.Set(context.Background(), int64(newSize))
+	}
+}
+
+// minInt returns the smallest of two ints
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxInt returns the largest of two ints
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
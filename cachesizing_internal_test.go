@@ -0,0 +1,125 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// missAll looks up keys that aren't in the cache and adds them, filling it with misses
+func missAll(c *resizableCache, count int) {
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("miss-%d", i)
+		if _, ok := c.Get(key); !ok {
+			c.Add(key, i)
+		}
+	}
+}
+
+func newAdaptiveTestSlackscot(t *testing.T, maxSize int, memoryLimitMB int) (s *Slackscot) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ResponseCacheSizeKey, 100)
+	v.Set(config.CacheSizingAdaptiveKey, true)
+	v.Set(config.CacheSizingMaxSizeKey, maxSize)
+	v.Set(config.CacheSizingMemoryLimitKey, memoryLimitMB)
+
+	s, err := New("robert", v)
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestResizedCacheKeepsEntries(t *testing.T) {
+	c, err := newResizableCache(10)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		c.Add(i, fmt.Sprintf("value-%d", i))
+	}
+
+	require.NoError(t, c.resize(20))
+	assert.Equal(t, 20, c.Size())
+	assert.Equal(t, 10, c.Len())
+
+	value, ok := c.Get(9)
+	require.True(t, ok)
+	assert.Equal(t, "value-9", value)
+
+	// Shrinking evicts the least recently used entries
+	require.NoError(t, c.resize(5))
+	assert.Equal(t, 5, c.Len())
+	assert.False(t, c.Contains(0))
+}
+
+func TestFullCacheWithLowHitRateGrowsUpToCap(t *testing.T) {
+	s := newAdaptiveTestSlackscot(t, 300, 0)
+
+	missAll(s.triggeringMsgToResponse, 200)
+	s.cacheSizer.adjust()
+	assert.Equal(t, 200, s.triggeringMsgToResponse.Size())
+
+	missAll(s.triggeringMsgToResponse, 400)
+	s.cacheSizer.adjust()
+	assert.Equal(t, 300, s.triggeringMsgToResponse.Size())
+}
+
+func TestCacheWithHighHitRateKeepsItsSize(t *testing.T) {
+	s := newAdaptiveTestSlackscot(t, 300, 0)
+
+	missAll(s.triggeringMsgToResponse, 100)
+	for i := 0; i < 1000; i++ {
+		s.triggeringMsgToResponse.Get("miss-99")
+	}
+	s.cacheSizer.adjust()
+	assert.Equal(t, 100, s.triggeringMsgToResponse.Size())
+
+	// Too few lookups to tell
+	missAll(s.triggeringMsgToResponse, 50)
+	s.cacheSizer.adjust()
+	assert.Equal(t, 100, s.triggeringMsgToResponse.Size())
+}
+
+func TestCacheShrinksToConfiguredSizeOverMemoryLimit(t *testing.T) {
+	s := newAdaptiveTestSlackscot(t, 1000, 64)
+	heap := uint64(0)
+	s.cacheSizer.heapAlloc = func() uint64 { return heap }
+
+	missAll(s.triggeringMsgToResponse, 200)
+	s.cacheSizer.adjust()
+	missAll(s.triggeringMsgToResponse, 400)
+	s.cacheSizer.adjust()
+	assert.Equal(t, 400, s.triggeringMsgToResponse.Size())
+
+	heap = 65 * bytesPerMegabyte
+	s.cacheSizer.adjust()
+	assert.Equal(t, 200, s.triggeringMsgToResponse.Size())
+
+	s.cacheSizer.adjust()
+	s.cacheSizer.adjust()
+	assert.Equal(t, 100, s.triggeringMsgToResponse.Size())
+}
+
+func TestUserInfoCacheSizedAdaptively(t *testing.T) {
+	s := newAdaptiveTestSlackscot(t, 1000, 0)
+	s.config.Set(config.UserInfoCacheSizeKey, 100)
+
+	require.NoError(t, s.injectServicesToPlugins(&flakyUserInfoFinder{}, s.log, nil, nil, nil, nil))
+
+	for i := 0; i < 200; i++ {
+		s.userInfoFinder.GetUserInfo(fmt.Sprintf("U%d", i))
+	}
+	s.cacheSizer.adjust()
+
+	assert.Equal(t, 200, s.userInfoFinder.(*cachingUserInfoFinder).userProfileCache.Size())
+}
+
+func TestInvalidMaxCacheSize(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.CacheSizingAdaptiveKey, true)
+	v.Set(config.CacheSizingMaxSizeKey, 0)
+
+	_, err := New("robert", v)
+	assert.EqualError(t, err, "cacheSizing.maxSize config should be greater than 0 but was [0]")
+}
@@ -0,0 +1,132 @@
+package slackscot
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	channelActionType = "channelAction"
+)
+
+// ChannelEventType is the type of a channel lifecycle event, as named by slack
+type ChannelEventType string
+
+// Types of the channel lifecycle events routed to channel actions
+const (
+	ChannelCreated      ChannelEventType = "channel_created"
+	ChannelRenamed      ChannelEventType = "channel_rename"
+	ChannelArchived     ChannelEventType = "channel_archive"
+	MemberJoinedChannel ChannelEventType = "member_joined_channel"
+	MemberLeftChannel   ChannelEventType = "member_left_channel"
+)
+
+// ChannelActionDefinition represents how a channel action is triggered and what it does. Channel actions are
+// triggered by channel lifecycle events like channels being created or members joining channels (i.e. to welcome
+// new members or keep an index of channels)
+type ChannelActionDefinition struct {
+	// Indicates whether the action should be omitted from the help message
+	Hidden bool
+
+	// Matcher that will determine whether or not the action should be triggered
+	Match ChannelEventMatcher
+
+	// Usage example
+	Usage string
+
+	// Help description for the action
+	Description string
+
+	// Function to execute if the Matcher matches
+	Answer ChannelEventAnswerer
+}
+
+// IncomingChannelEvent represents a channel lifecycle event
+type IncomingChannelEvent struct {
+	// Type is the type of the event (i.e. MemberJoinedChannel)
+	Type ChannelEventType
+
+	// Channel is the id of the channel
+	Channel string
+
+	// ChannelName is the name of the channel when created or its new name when renamed. It's empty for other events
+	ChannelName string
+
+	// User is the id of the user who created or archived the channel or of the member who joined or left it. It's
+	// empty for renamed channels
+	User string
+
+	// Inviter is the id of the user who invited the member who joined the channel, if any
+	Inviter string
+}
+
+// ChannelEventMatcher is the function that determines whether or not a channel action should be triggered
+type ChannelEventMatcher func(e *IncomingChannelEvent) bool
+
+// ChannelEventAnswerer is what gets executed when a ChannelActionDefinition is triggered. To signal the absence of an
+// answer, an action should return nil. Answers are sent in the channel of the event (which slackscot needs to be a
+// member of) and can be made ephemeral (i.e. to welcome a new member) with AnswerEphemeral
+type ChannelEventAnswerer func(e *IncomingChannelEvent) *Answer
+
+// processChannelEvent routes a channel lifecycle event to the channel actions of all plugins and sends any triggered
+// answers
+func (s *Slackscot) processChannelEvent(driver chatDriver, e IncomingChannelEvent) {
+	if !s.hasChannelActions() {
+		return
+	}
+
+	safeMode := s.isSafeModeChannel(e.Channel)
+	pluginResps := make([]pluginResponses, 0)
+	for _, p := range s.plugins {
+		if !s.pluginRuns(p.Name, e.Channel, safeMode) {
+			continue
+		}
+
+		pluginResps = append(pluginResps, pluginResponses{priority: p.Priority, outMsgs: withPluginDefaults(p, s.tryChannelActions(p.Name, p.ChannelActions, &e))})
+	}
+
+	// Answers to channel events go through the same answer policy as answers to messages. Answer hooks run when they're
+	// sent (see sendNewMessage)
+	partThreads := make(map[string]string)
+	for _, o := range splitOutgoingMessages(s.answerPolicy.apply(pluginResps)) {
+		if _, err := s.sendMessagePart(driver, SlackMessageID{}, o, "", partThreads); err != nil {
+			s.log.Printf("Unable to send new message triggered by [%s] event on channel [%s]: %v\n", e.Type, e.Channel, err)
+		}
+	}
+}
+
+// hasChannelActions returns true if any plugin has channel actions
+func (s *Slackscot) hasChannelActions() bool {
+	for _, p := range s.plugins {
+		if len(p.ChannelActions) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tryChannelActions tries all channel actions of a plugin and returns the messages of their answers
+func (s *Slackscot) tryChannelActions(pluginName string, actions []ChannelActionDefinition, e *IncomingChannelEvent) (outMsgs []OutgoingMessage) {
+	before := time.Now()
+
+	for i, action := range actions {
+		if !action.Match(e) {
+			continue
+		}
+
+		if answer := action.Answer(e); answer != nil {
+			outMsgs = append(outMsgs, newOutMessageForAnswer(newSlackOutgoingMessage(e.Channel, answer.Text), getActionID(pluginName, channelActionType, i), *answer))
+		}
+	}
+
+	pm := s.getOrCreatePluginMetrics(pluginName)
+	pm.processingTimeMillis.Record(context.Background(), time.Since(before).Milliseconds())
+	pm.reactionCount.Add(context.Background(), int64(len(outMsgs)))
+
+	if len(outMsgs) > 0 && s.eventBus != nil {
+		s.eventBus.Publish(PluginAnswered{Plugin: pluginName, ActionType: channelActionType, Channel: e.Channel, Answers: len(outMsgs)})
+	}
+
+	return outMsgs
+}
@@ -0,0 +1,83 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"strings"
+	"testing"
+)
+
+func newChannelPlugin(events *[]IncomingChannelEvent) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "welcome"
+	p.ChannelActions = []ChannelActionDefinition{{
+		Match: func(e *IncomingChannelEvent) bool {
+			*events = append(*events, *e)
+			return e.Type == MemberJoinedChannel
+		},
+		Usage:       "join a channel",
+		Description: "Welcome new members",
+		Answer: func(e *IncomingChannelEvent) *Answer {
+			return &Answer{Text: fmt.Sprintf("Welcome, <@%s>!", e.User), Options: []AnswerOption{AnswerEphemeral(e.User)}}
+		},
+	}}
+
+	return p
+}
+
+func TestChannelActions(t *testing.T) {
+	var events []IncomingChannelEvent
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newChannelPlugin(&events), []slack.RTMEvent{
+		{Type: "channel_created", Data: &slack.ChannelCreatedEvent{Type: "channel_created", Channel: slack.ChannelCreatedInfo{ID: "Cbirds", Name: "birds", Creator: "Alphonse"}}},
+		{Type: "channel_rename", Data: &slack.ChannelRenameEvent{Type: "channel_rename", Channel: slack.ChannelRenameInfo{ID: "Cbirds", Name: "blue-jays"}}},
+		{Type: "member_joined_channel", Data: &slack.MemberJoinedChannelEvent{Type: "member_joined_channel", User: "Bernard", Channel: "Cbirds", Inviter: "Alphonse"}},
+		{Type: "member_left_channel", Data: &slack.MemberLeftChannelEvent{Type: "member_left_channel", User: "Bernard", Channel: "Cbirds"}},
+		{Type: "channel_archive", Data: &slack.ChannelArchiveEvent{Type: "channel_archive", Channel: "Cbirds", User: "Alphonse"}},
+	})
+
+	assert.Equal(t, []IncomingChannelEvent{
+		{Type: ChannelCreated, Channel: "Cbirds", ChannelName: "birds", User: "Alphonse"},
+		{Type: ChannelRenamed, Channel: "Cbirds", ChannelName: "blue-jays"},
+		{Type: MemberJoinedChannel, Channel: "Cbirds", User: "Bernard", Inviter: "Alphonse"},
+		{Type: MemberLeftChannel, Channel: "Cbirds", User: "Bernard"},
+		{Type: ChannelArchived, Channel: "Cbirds", User: "Alphonse"},
+	}, events)
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, "Cbirds", sentMsgs[0].channelID)
+
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Welcome, <@Bernard>!", vals.Get("text"))
+		assert.Equal(t, "Bernard", vals.Get("user"))
+	}
+}
+
+func TestChannelActionAnswersGoThroughAnswerPolicyAndHooks(t *testing.T) {
+	var events []IncomingChannelEvent
+	greeter := newChannelPlugin(&events)
+	greeter.Name = "greeter"
+
+	var logBuilder strings.Builder
+	blocker := AnswerHookFunc(func(answer *Answer) (modified *Answer, allow bool) {
+		return nil, !strings.Contains(answer.Text, "Alphonse")
+	})
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.AnswerPolicyKey, answerPolicyFirstMatch)
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, v, []*Plugin{newChannelPlugin(&events), greeter}, []slack.RTMEvent{
+		{Type: "member_joined_channel", Data: &slack.MemberJoinedChannelEvent{Type: "member_joined_channel", User: "Bernard", Channel: "Cbirds"}},
+		{Type: "member_joined_channel", Data: &slack.MemberJoinedChannelEvent{Type: "member_joined_channel", User: "Alphonse", Channel: "Cbirds"}},
+	}, nil, OptionAnswerHook(blocker), OptionLog(log.New(&logBuilder, "", 0)))
+
+	assert.Len(t, events, 4)
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, "Welcome, <@Bernard>!", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+	}
+
+	assert.Contains(t, logBuilder.String(), "Answer from [welcome.channelAction[0]] blocked by hook before sending")
+}
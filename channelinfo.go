@@ -0,0 +1,101 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/hashicorp/golang-lru"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+)
+
+const (
+	channelInfoCacheSizeDisabledValue = 0
+)
+
+// cachingChannelInfoFinder holds a cache and a loading ChannelInfoFinder to implement the ChannelInfoFinder loading
+// entries from cache
+type cachingChannelInfoFinder struct {
+	loader       ChannelInfoFinder
+	logger       SLogger
+	channelCache *lru.ARCCache
+}
+
+// NewCachingChannelInfoFinder creates a new channel info service with caching if enabled via
+// config.ChannelInfoCacheSizeKey. It requires an implementation of the interface that will do the actual loading
+// when not in cache
+func NewCachingChannelInfoFinder(v *viper.Viper, loader ChannelInfoFinder, logger SLogger) (cf ChannelInfoFinder, err error) {
+	ccf := new(cachingChannelInfoFinder)
+
+	cs := v.GetInt(config.ChannelInfoCacheSizeKey)
+
+	if cs > channelInfoCacheSizeDisabledValue {
+		ccf.channelCache, err = lru.NewARC(cs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ccf.loader = loader
+	ccf.logger = logger
+
+	return ccf, nil
+}
+
+// channelCacheKey returns the cache key of a channel's info. Channels loaded with their locale are cached separately
+// since the info loaded without it lacks the locale
+func channelCacheKey(channelID string, includeLocale bool) string {
+	return fmt.Sprintf("%s/%t", channelID, includeLocale)
+}
+
+// GetConversationInfo gets the channel info (i.e. its name and topic) or returns an error and a nil channel if not
+// found or an error occurred during retrieval
+func (c cachingChannelInfoFinder) GetConversationInfo(channelID string, includeLocale bool) (channel *slack.Channel, err error) {
+	if c.channelCache == nil {
+		c.logger.Debugf("Cache disabled, loading channel info for [%s] from slack instead\n", channelID)
+		return c.loader.GetConversationInfo(channelID, includeLocale)
+	}
+
+	key := channelCacheKey(channelID, includeLocale)
+	if cached, exists := c.channelCache.Get(key); exists {
+		c.logger.Debugf("Channel info in cache [%s] so using that\n", channelID)
+
+		cached, ok := cached.(slack.Channel)
+		if !ok {
+			return nil, fmt.Errorf("Error converting cached value for channel id [%s]", channelID)
+		}
+
+		return &cached, nil
+	}
+
+	c.logger.Debugf("Channel info for [%s] not found in cache, retrieving from slack and saving\n", channelID)
+	channel, err = c.loader.GetConversationInfo(channelID, includeLocale)
+
+	// Add the channel to cache if it was loaded without error
+	if channel != nil && err == nil {
+		c.channelCache.Add(key, *channel)
+	}
+
+	return channel, err
+}
+
+// invalidateChannel evicts the channel's info from cache, if enabled
+func (c cachingChannelInfoFinder) invalidateChannel(channelID string) {
+	if c.channelCache != nil {
+		c.channelCache.Remove(channelCacheKey(channelID, false))
+		c.channelCache.Remove(channelCacheKey(channelID, true))
+	}
+}
+
+// channelInfoInvalidator defines the interface for evicting the cached info of a channel
+type channelInfoInvalidator interface {
+	invalidateChannel(channelID string)
+}
+
+// invalidateChannelInfo evicts the cached info of a channel that changed (i.e. was renamed or archived) so that
+// plugins don't get a stale name for the cache lifetime
+func (s *Slackscot) invalidateChannelInfo(channelID string) {
+	if invalidator, ok := s.channelInfoFinder.(channelInfoInvalidator); ok {
+		s.log.Debugf("Channel [%s] changed, evicting its cached info\n", channelID)
+		invalidator.invalidateChannel(channelID)
+	}
+}
@@ -0,0 +1,83 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// countingChannelInfoFinder names channels after their id and counts calls
+type countingChannelInfoFinder struct {
+	calls int
+}
+
+func (f *countingChannelInfoFinder) GetConversationInfo(channelID string, includeLocale bool) (channel *slack.Channel, err error) {
+	f.calls++
+	if channelID == "Cmissing" {
+		return nil, fmt.Errorf("channel_not_found")
+	}
+
+	channel = new(slack.Channel)
+	channel.ID = channelID
+	channel.Name = fmt.Sprintf("birds-%d", f.calls)
+
+	return channel, nil
+}
+
+func TestInjectCachingChannelInfoFinder(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	karma := &Plugin{Name: "karma"}
+	s.RegisterPlugin(karma)
+
+	loader := &countingChannelInfoFinder{}
+	s.channelInfoFinder = loader
+	require.NoError(t, s.injectServicesToPlugins(&flakyUserInfoFinder{}, s.log, nil, nil, nil, nil))
+
+	for i := 0; i < 3; i++ {
+		c, err := karma.ChannelInfoFinder.GetConversationInfo("Cgeneral", false)
+		require.NoError(t, err)
+		assert.Equal(t, "birds-1", c.Name)
+	}
+	assert.Equal(t, 1, loader.calls)
+
+	// Incoming messages share the cache with plugins
+	inMsg := NewIncomingMessage("", slack.Msg{Channel: "Cgeneral"}, nil, s.channelInfoFinder)
+	_, err = inMsg.ChannelInfo()
+	require.NoError(t, err)
+	assert.Equal(t, 1, loader.calls)
+
+	// Errors aren't cached
+	_, err = karma.ChannelInfoFinder.GetConversationInfo("Cmissing", false)
+	assert.Error(t, err)
+	_, err = karma.ChannelInfoFinder.GetConversationInfo("Cmissing", false)
+	assert.Error(t, err)
+	assert.Equal(t, 3, loader.calls)
+
+	// Renamed channels are loaded again
+	s.invalidateChannelInfo("Cgeneral")
+
+	c, err := karma.ChannelInfoFinder.GetConversationInfo("Cgeneral", false)
+	require.NoError(t, err)
+	assert.Equal(t, "birds-4", c.Name)
+}
+
+func TestChannelInfoCacheDisabled(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ChannelInfoCacheSizeKey, 0)
+
+	s, err := New("robert", v)
+	require.NoError(t, err)
+
+	loader := &countingChannelInfoFinder{}
+	cf, err := NewCachingChannelInfoFinder(v, loader, s.log)
+	require.NoError(t, err)
+
+	cf.GetConversationInfo("Cgeneral", false)
+	cf.GetConversationInfo("Cgeneral", false)
+	assert.Equal(t, 2, loader.calls)
+}
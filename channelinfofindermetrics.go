@@ -0,0 +1,76 @@
+package slackscot
+
+// DO NOT EDIT!
+// This code is generated with http://github.com/hexdigest/gowrap tool
+// using opentelemetry.template template
+
+//go:generate gowrap gen -p github.com/alexandre-normand/slackscot -i ChannelInfoFinder -t opentelemetry.template -o channelinfofindermetrics.go
+
+import (
+	"context"
+	"time"
+	"unicode"
+
+	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/metric"
+)
+
+// ChannelInfoFinderWithTelemetry implements ChannelInfoFinder interface with all methods wrapped
+// with open telemetry metrics
+type ChannelInfoFinderWithTelemetry struct {
+	base               ChannelInfoFinder
+	methodCounters     map[string]metric.BoundInt64Counter
+	errCounters        map[string]metric.BoundInt64Counter
+	methodTimeMeasures map[string]metric.BoundInt64Measure
+}
+
+// NewChannelInfoFinderWithTelemetry returns an instance of the ChannelInfoFinder decorated with open telemetry timing and count metrics
+func NewChannelInfoFinderWithTelemetry(base ChannelInfoFinder, name string, meter metric.Meter) ChannelInfoFinderWithTelemetry {
+	return ChannelInfoFinderWithTelemetry{
+		base:               base,
+		methodCounters:     newChannelInfoFinderMethodCounters("Calls", name, meter),
+		errCounters:        newChannelInfoFinderMethodCounters("Errors", name, meter),
+		methodTimeMeasures: newChannelInfoFinderMethodTimeMeasures(name, meter),
+	}
+}
+
+func newChannelInfoFinderMethodTimeMeasures(appName string, meter metric.Meter) (boundTimeMeasures map[string]metric.BoundInt64Measure) {
+	boundTimeMeasures = make(map[string]metric.BoundInt64Measure)
+
+	nGetConversationInfoMeasure := []rune("ChannelInfoFinder_GetConversationInfo_ProcessingTimeMillis")
+	nGetConversationInfoMeasure[0] = unicode.ToLower(nGetConversationInfoMeasure[0])
+	mGetConversationInfo := meter.NewInt64Measure(string(nGetConversationInfoMeasure), metric.WithKeys(key.New("name")))
+	boundTimeMeasures["GetConversationInfo"] = mGetConversationInfo.Bind(meter.Labels(key.New("name").String(appName)))
+
+	return boundTimeMeasures
+}
+
+func newChannelInfoFinderMethodCounters(suffix string, appName string, meter metric.Meter) (boundCounters map[string]metric.BoundInt64Counter) {
+	boundCounters = make(map[string]metric.BoundInt64Counter)
+
+	nGetConversationInfoCounter := []rune("ChannelInfoFinder_GetConversationInfo_" + suffix)
+	nGetConversationInfoCounter[0] = unicode.ToLower(nGetConversationInfoCounter[0])
+	cGetConversationInfo := meter.NewInt64Counter(string(nGetConversationInfoCounter), metric.WithKeys(key.New("name")))
+	boundCounters["GetConversationInfo"] = cGetConversationInfo.Bind(meter.Labels(key.New("name").String(appName)))
+
+	return boundCounters
+}
+
+// GetConversationInfo implements ChannelInfoFinder
+func (_d ChannelInfoFinderWithTelemetry) GetConversationInfo(channelID string, includeLocale bool) (channel *slack.Channel, err error) {
+	_since := time.Now()
+	defer func() {
+		if err != nil {
+			errCounter := _d.errCounters["GetConversationInfo"]
+			errCounter.Add(context.Background(), 1)
+		}
+
+		methodCounter := _d.methodCounters["GetConversationInfo"]
+		methodCounter.Add(context.Background(), 1)
+
+		methodTimeMeasure := _d.methodTimeMeasures["GetConversationInfo"]
+		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+	}()
+	return _d.base.GetConversationInfo(channelID, includeLocale)
+}
@@ -37,10 +37,28 @@ type messageDeleter interface {
 	DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error)
 }
 
-// ChatDriver encompasses all MessageSender, MessageUpdater and MessageDeleter interfaces and is implemented by any values that
-// has all methods of those interfaces
+// messageFetcher is implemented by any value that has the GetConversationHistory method. It's used to resolve messages
+// that aren't part of the event that refers to them (i.e. the message reacted to in a reaction_added event)
+//
+// slack.Client implements this interface
+type messageFetcher interface {
+	GetConversationHistory(params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error)
+}
+
+// messageReactor is implemented by any value that has the AddReaction method. It's used to add the emoji reactions of
+// answers to their triggering messages (see Answer.Reactions)
+//
+// slack.Client implements this interface
+type messageReactor interface {
+	AddReaction(name string, item slack.ItemRef) error
+}
+
+// ChatDriver encompasses all MessageSender, MessageUpdater, MessageDeleter, MessageFetcher and MessageReactor interfaces
+// and is implemented by any values that has all methods of those interfaces
 type chatDriver interface {
 	messageDeleter
+	messageFetcher
+	messageReactor
 	messageSender
 	messageUpdater
 }
@@ -38,11 +38,21 @@ func NewchatDriverWithTelemetry(base chatDriver, name string, meter metric.Meter
 func newchatDriverMethodTimeMeasures(appName string, meter metric.Meter) (boundTimeMeasures map[string]metric.BoundInt64Measure) {
 	boundTimeMeasures = make(map[string]metric.BoundInt64Measure)
 
+	nAddReactionMeasure := []rune("chatDriver_AddReaction_ProcessingTimeMillis")
+	nAddReactionMeasure[0] = unicode.ToLower(nAddReactionMeasure[0])
+	mAddReaction := meter.NewInt64Measure(string(nAddReactionMeasure), metric.WithKeys(key.New("name")))
+	boundTimeMeasures["AddReaction"] = mAddReaction.Bind(meter.Labels(key.New("name").String(appName)))
+
 	nDeleteMessageMeasure := []rune("chatDriver_DeleteMessage_ProcessingTimeMillis")
 	nDeleteMessageMeasure[0] = unicode.ToLower(nDeleteMessageMeasure[0])
 	mDeleteMessage := meter.NewInt64Measure(string(nDeleteMessageMeasure), metric.WithKeys(key.New("name")))
 	boundTimeMeasures["DeleteMessage"] = mDeleteMessage.Bind(meter.Labels(key.New("name").String(appName)))
 
+	nGetConversationHistoryMeasure := []rune("chatDriver_GetConversationHistory_ProcessingTimeMillis")
+	nGetConversationHistoryMeasure[0] = unicode.ToLower(nGetConversationHistoryMeasure[0])
+	mGetConversationHistory := meter.NewInt64Measure(string(nGetConversationHistoryMeasure), metric.WithKeys(key.New("name")))
+	boundTimeMeasures["GetConversationHistory"] = mGetConversationHistory.Bind(meter.Labels(key.New("name").String(appName)))
+
 	nSendMessageMeasure := []rune("chatDriver_SendMessage_ProcessingTimeMillis")
 	nSendMessageMeasure[0] = unicode.ToLower(nSendMessageMeasure[0])
 	mSendMessage := meter.NewInt64Measure(string(nSendMessageMeasure), metric.WithKeys(key.New("name")))
@@ -59,11 +69,21 @@ func newchatDriverMethodTimeMeasures(appName string, meter metric.Meter) (boundT
 func newchatDriverMethodCounters(suffix string, appName string, meter metric.Meter) (boundCounters map[string]metric.BoundInt64Counter) {
 	boundCounters = make(map[string]metric.BoundInt64Counter)
 
+	nAddReactionCounter := []rune("chatDriver_AddReaction_" + suffix)
+	nAddReactionCounter[0] = unicode.ToLower(nAddReactionCounter[0])
+	cAddReaction := meter.NewInt64Counter(string(nAddReactionCounter), metric.WithKeys(key.New("name")))
+	boundCounters["AddReaction"] = cAddReaction.Bind(meter.Labels(key.New("name").String(appName)))
+
 	nDeleteMessageCounter := []rune("chatDriver_DeleteMessage_" + suffix)
 	nDeleteMessageCounter[0] = unicode.ToLower(nDeleteMessageCounter[0])
 	cDeleteMessage := meter.NewInt64Counter(string(nDeleteMessageCounter), metric.WithKeys(key.New("name")))
 	boundCounters["DeleteMessage"] = cDeleteMessage.Bind(meter.Labels(key.New("name").String(appName)))
 
+	nGetConversationHistoryCounter := []rune("chatDriver_GetConversationHistory_" + suffix)
+	nGetConversationHistoryCounter[0] = unicode.ToLower(nGetConversationHistoryCounter[0])
+	cGetConversationHistory := meter.NewInt64Counter(string(nGetConversationHistoryCounter), metric.WithKeys(key.New("name")))
+	boundCounters["GetConversationHistory"] = cGetConversationHistory.Bind(meter.Labels(key.New("name").String(appName)))
+
 	nSendMessageCounter := []rune("chatDriver_SendMessage_" + suffix)
 	nSendMessageCounter[0] = unicode.ToLower(nSendMessageCounter[0])
 	cSendMessage := meter.NewInt64Counter(string(nSendMessageCounter), metric.WithKeys(key.New("name")))
@@ -77,6 +97,24 @@ func newchatDriverMethodCounters(suffix string, appName string, meter metric.Met
 	return boundCounters
 }
 
+// AddReaction implements chatDriver
+func (_d chatDriverWithTelemetry) AddReaction(name string, item slack.ItemRef) (err error) {
+	_since := time.Now()
+	defer func() {
+		if err != nil {
+			errCounter := _d.errCounters["AddReaction"]
+			errCounter.Add(context.Background(), 1)
+		}
+
+		methodCounter := _d.methodCounters["AddReaction"]
+		methodCounter.Add(context.Background(), 1)
+
+		methodTimeMeasure := _d.methodTimeMeasures["AddReaction"]
+		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+	}()
+	return _d.base.AddReaction(name, item)
+}
+
 // DeleteMessage implements chatDriver
 func (_d chatDriverWithTelemetry) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
 	_since := time.Now()
@@ -95,6 +133,24 @@ func (_d chatDriverWithTelemetry) DeleteMessage(channelID string, timestamp stri
 	return _d.base.DeleteMessage(channelID, timestamp)
 }
 
+// GetConversationHistory implements chatDriver
+func (_d chatDriverWithTelemetry) GetConversationHistory(params *slack.GetConversationHistoryParameters) (gp1 *slack.GetConversationHistoryResponse, err error) {
+	_since := time.Now()
+	defer func() {
+		if err != nil {
+			errCounter := _d.errCounters["GetConversationHistory"]
+			errCounter.Add(context.Background(), 1)
+		}
+
+		methodCounter := _d.methodCounters["GetConversationHistory"]
+		methodCounter.Add(context.Background(), 1)
+
+		methodTimeMeasure := _d.methodTimeMeasures["GetConversationHistory"]
+		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+	}()
+	return _d.base.GetConversationHistory(params)
+}
+
 // SendMessage implements chatDriver
 func (_d chatDriverWithTelemetry) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
 	_since := time.Now()
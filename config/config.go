@@ -5,20 +5,65 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Slackscot global configuration keys
 const (
-	TokenKey                    = "token"                                  // Slack token, string
-	DebugKey                    = "debug"                                  // Debug mode, boolean
-	MaxAgeHandledMessages       = "maxAgeHandledMessages"                  // The maximum age of messages before they are ignored (applicable for message updates)
-	ResponseCacheSizeKey        = "responseCacheSize"                      // Response cache size in number of entries, int
-	TimeLocationKey             = "timeLocation"                           // Time Location as understood by time.LoadLocation
-	ThreadedRepliesKey          = "replyBehavior.threadedReplies"          // Threaded replies mode (slackscot will respond to all triggering messages using threads), boolean
-	BroadcastThreadedRepliesKey = "replyBehavior.broadcastThreadedReplies" // Broadcast threaded replies (slackscot will set broadcast on threaded replies, only applies if threaded replies are enabled), boolean
-	PluginsKey                  = "plugins"                                // Root element of the map of string key/values for plugins string
-	UserInfoCacheSizeKey        = "userInfoCacheSize"                      // The number of entries to keep in the user info cache, int value. Defaults to no caching (value of 0)
+	TokenKey                         = "token"                                  // Slack token, string
+	DebugKey                         = "debug"                                  // Debug mode, boolean
+	MaxAgeHandledMessages            = "maxAgeHandledMessages"                  // The maximum age of messages before they are ignored (applicable for message updates)
+	MessageUpdateAgeLimitKey         = "messageUpdateAgeLimit"                  // The maximum age, in minutes, of edited messages for their edits to trigger new answers, updates or deletions of answers, int. Takes precedence over MaxAgeHandledMessages for message updates. Defaults to no limit other than MaxAgeHandledMessages (value of 0)
+	ResponseCacheSizeKey             = "responseCacheSize"                      // Response cache size in number of entries, int
+	EventDedupWindowKey              = "eventDedupWindow"                       // How long message events are remembered to ignore the same events replayed by slack (i.e. after a reconnection), duration. Defaults to 5m. A value of 0 disables deduplication
+	ResponseRetentionKey             = "responseRetention"                      // How long the responses to triggering messages persisted with slackscot.OptionResponseStorer are kept, duration. Defaults to 24h
+	TimeLocationKey                  = "timeLocation"                           // Time Location as understood by time.LoadLocation
+	ThreadedRepliesKey               = "replyBehavior.threadedReplies"          // Threaded replies mode (slackscot will respond to all triggering messages using threads), boolean
+	BroadcastThreadedRepliesKey      = "replyBehavior.broadcastThreadedReplies" // Broadcast threaded replies (slackscot will set broadcast on threaded replies, only applies if threaded replies are enabled), boolean
+	PluginsKey                       = "plugins"                                // Root element of the map of string key/values for plugins string
+	UserInfoCacheSizeKey             = "userInfoCacheSize"                      // The number of entries to keep in the user info cache, int value. Defaults to no caching (value of 0)
+	ChannelInfoCacheSizeKey          = "channelInfoCacheSize"                   // The number of entries to keep in the channel info cache, int value. Defaults to 500. A value of 0 disables caching
+	EmojiListRefreshIntervalKey      = "emojiListRefreshInterval"               // How long the list of custom emoji found by plugins with their EmojiFinder is cached for, duration. The list is also refreshed when emoji are added or removed. Defaults to 1h
+	UserInfoCacheRetentionKey        = "userInfoCacheRetention"                 // How long the user info cache saved on shutdown (with slackscot.OptionResponseStorer) is reloaded on startup for, duration. Defaults to 24h. A value of 0 disables saving the cache
+	UserInfoFallbackKey              = "userInfoFallback.mode"                  // What plugins get when user info can't be loaded, string. One of "error" (default, the error is returned) or "mention" (a user named with its mention, i.e. <@U21355>). Plugins can override it with a userInfoFallback key in their configuration
+	UserInfoRetryAfterKey            = "userInfoFallback.retryAfter"            // How long failures to load a user's info are remembered before retrying, duration. Defaults to 1m. A value of 0 retries on every lookup
+	AllowCustomIdentitiesKey         = "allowCustomIdentities"                  // Whether answers can be posted with custom usernames and icons (see slackscot.Identity), boolean. Requires the chat:write.customize scope. Defaults to false (answers are always posted with the bot's identity)
+	ActionTimeoutKey                 = "actionTimeout"                          // Maximum duration of command and hear action answers, duration. Answers taking longer are dropped with a warning and the context of the action's message (see IncomingMessage.Context) is canceled. Defaults to no timeout (value of 0)
+	MaxConcurrentHandlersKey         = "maxConcurrentHandlers"                  // Maximum number of messages processed concurrently, int. When set, messages are dispatched to a queue per channel (preserving the order of processing of messages of a same channel) and a slow message only delays its own channel. Defaults to partitioned processing (value of 0, see MessageProcessingPartitionCount)
+	RateLimitEnabledKey              = "rateLimit.enabled"                      // Whether chat calls (sending, updating and deleting messages) are spaced according to slack's rate limits and retried when rejected, boolean. Defaults to true
+	RateLimitMaxRetriesKey           = "rateLimit.maxRetries"                   // Maximum number of retries of chat calls (sending, updating and deleting messages) rejected by slack's rate limits or failing with server errors, int. Retries wait for the Retry-After delay given by slack or an exponential backoff. Defaults to 3
+	RateLimitQueueSizeKey            = "rateLimit.queueSize"                    // Maximum number of chat calls waiting for their turn under slack's rate limits, int. Calls made while the queue is full fail with an error. Defaults to 100
+	RateLimitShedLowPriorityAboveKey = "rateLimit.shedLowPriorityAbove"         // Fraction (i.e. 0.8) of the per-minute budget of a slack Web API method above which low-priority calls (reactions and unfurls) are dropped with an error, float. Defaults to never dropping calls (value of 0)
+	CommandPrefixKey                 = "commandPrefix"                          // Command prefix (i.e. "!") recognized in channels in addition to at-mentioning the bot, string. Defaults to none (only mentions are commands). Note that slackscot.OptionCommandPrefix takes precedence and replaces mention matching altogether
+	AnswerPolicyKey                  = "answerPolicy.mode"                      // Policy applied when more than one plugin answers the same message, string. One of "all" (default, every answer in plugin registration order), "priority" (every answer ordered by plugin priority) or "firstMatch" (only the answers of the first answering action). Policies other than "all" without maximum also drop duplicate answers
+	MaxAnswersPerMessageKey          = "answerPolicy.maxAnswersPerMessage"      // The maximum number of answers sent for a single message, int. Defaults to no limit (value of 0)
+	ThreadParentDeletionKey          = "threadParentDeletion"                   // What happens to the responses to a deleted triggering message that started a thread, string. One of "delete" (default, like responses to other deleted messages), "replace" (the responses' text is replaced with "(original message deleted)") or "leave" (responses are left as they are)
+	WebhookListenAddressKey          = "webhooks.listenAddress"                 // Address (i.e. ":8080") of the http server receiving plugin webhooks, string. Defaults to none (webhooks disabled)
+	WebhookSharedSecretKey           = "webhooks.sharedSecret"                  // Secret that webhook requests must include in their X-Slackscot-Webhook-Secret header, string. Defaults to none (no verification)
+	SigningSecretKey                 = "signingSecret"                          // Signing secret of the slack app used to verify slash command and interaction requests, string. Required if plugins have slash commands or interaction handlers and webhooks are enabled
+	ThemeKey                         = "theme"                                  // Name of the theme of rich outputs of built-in plugins (i.e. the karma leaderboards), string. One of "classic" (default), "podium", "corporatePlain" (no emojis or images) or a custom theme registered with theme.Register
+	AssetsBaseURLKey                 = "assets.baseURL"                         // Public url (i.e. https://slackscot.example.com) of the webhook server that serves images of rich outputs (at /assets/<name>), string. Defaults to none (asset images left out)
+	AssetsKey                        = "assets.images"                          // Map of asset names to data uris (i.e. data:image/png;base64,...) or paths of image files served in addition to the bundled ones, string map. See assets.Registry
+	FeaturesKey                      = "features"                               // Root element of the map of feature flags by name, each with an enabled boolean (for all channels) and a channelIDs string slice (for specific channels). See slackscot.FeatureFlags
+	AdminUsersKey                    = "adminUserIDs"                           // Users allowed to run admin-only actions (see slackscot.ActionDefinition.AdminOnly, i.e. resetting karma or disabling plugins), string slice. Others get a refusal. Defaults to none
+	FeatureAdminIDsKey               = "featureAdminIDs"                        // Users allowed to override feature flags at runtime with the feature command, string slice. Defaults to none
+	StoreAdminIDsKey                 = "storeAdminIDs"                          // Users allowed to inspect and delete entries of inspectable storers with the store command, string slice. Defaults to none
+	ProgressUpdateIntervalKey        = "progressUpdateInterval"                 // Minimum interval between the updates of answers with the progress of their tasks (see slackscot.AnswerWithProgress), duration. Defaults to 3s
+	JobWorkersKey                    = "jobWorkers"                             // Number of workers running the jobs of plugins (see slackscot.JobQueue), int. Defaults to 2
+	JobMaxAttemptsKey                = "jobMaxAttempts"                         // Default maximum number of attempts of jobs before they're moved to the dead jobs silo, int. Defaults to 5
+	JobRetryBackoffKey               = "jobRetryBackoff"                        // Delay before the first retry of a failed job, doubling on every attempt (up to 1h), duration. Defaults to 30s
+	ShutdownTimeoutKey               = "shutdownTimeout"                        // Maximum duration of a graceful shutdown on SIGTERM or SIGINT, duration. Messages already received and running scheduled actions get that long to finish. Defaults to 20s (under kubernetes' default termination grace period of 30s)
+	ScheduleAdminIDsKey              = "scheduleAdminIDs"                       // Users allowed to list, pause, resume and immediately run scheduled actions with the schedule command, string slice. Defaults to none
+	CacheSizingAdaptiveKey           = "cacheSizing.adaptive"                   // Whether the user info and response caches are sized adaptively, boolean. Full caches with a hit rate under 90% double in size (up to CacheSizingMaxSizeKey) and caches halve (down to their configured sizes, see UserInfoCacheSizeKey and ResponseCacheSizeKey) while the heap is over CacheSizingMemoryLimitKey. Defaults to false (fixed sizes)
+	CacheSizingMaxSizeKey            = "cacheSizing.maxSize"                    // Hard cap on the number of entries of adaptively sized caches, int. Defaults to 100000
+	CacheSizingMemoryLimitKey        = "cacheSizing.memoryLimitMB"              // Heap size, in megabytes, over which adaptively sized caches shrink, int. Defaults to no limit (value of 0)
+	CacheSizingIntervalKey           = "cacheSizing.interval"                   // Interval between adjustments of adaptively sized caches, duration. Defaults to 5m
+	SharedChannelsSafeModeKey        = "sharedChannels.safeMode"                // Whether externally shared channels (Slack Connect channels shared with other organizations) are restricted to the plugins of SharedChannelsAllowedPluginsKey, boolean. This keeps fun plugins and plugins storing data out of channels where internal information could leak to partners. Defaults to false
+	SharedChannelsAllowedPluginsKey  = "sharedChannels.allowedPlugins"          // Plugins still running in externally shared channels in safe mode (see SharedChannelsSafeModeKey), string slice. Defaults to none
+	SharedChannelsIgnoreExternalKey  = "sharedChannels.ignoreExternalUsers"     // Whether messages and reactions from users of other organizations are ignored in externally shared channels in safe mode (see SharedChannelsSafeModeKey), boolean. Defaults to true
+	RetentionSweepIntervalKey        = "retention.sweepInterval"                // Interval between sweeps of the entries past their retention in the storers of slackscot.OptionRetentionStorer, duration. Defaults to 1h
 )
 
 // Advanced configuration keys, only change if you really know what you're doing and have reviewed the internals
@@ -31,12 +76,41 @@ const (
 const (
 	debugDefault                             = false
 	responseCacheSizeDefault                 = 5000
+	responseRetentionDefault                 = time.Duration(24) * time.Hour
+	eventDedupWindowDefault                  = time.Duration(5) * time.Minute
 	timeLocationDefault                      = "Local"
 	threadedRepliesDefault                   = false
 	broadcastThreadedRepliesDefault          = false
 	maxAgeHandledMessagesDefault             = time.Duration(24) * time.Hour
+	messageUpdateAgeLimitDefault             = 0
 	msgProcessingPartitionCountDefault       = 16
 	msgProcessingBufferedMessageCountDefault = 10
+	answerPolicyDefault                      = "all"
+	threadParentDeletionDefault              = "delete"
+	maxAnswersPerMessageDefault              = 0
+	themeDefault                             = "classic"
+	channelInfoCacheSizeDefault              = 500
+	userInfoCacheRetentionDefault            = time.Duration(24) * time.Hour
+	emojiListRefreshIntervalDefault          = time.Hour
+	userInfoFallbackDefault                  = "error"
+	userInfoRetryAfterDefault                = time.Minute
+	allowCustomIdentitiesDefault             = false
+	rateLimitEnabledDefault                  = true
+	rateLimitMaxRetriesDefault               = 3
+	rateLimitQueueSizeDefault                = 100
+	rateLimitShedLowPriorityAboveDefault     = 0.
+	shutdownTimeoutDefault                   = time.Duration(20) * time.Second
+	progressUpdateIntervalDefault            = time.Duration(3) * time.Second
+	jobWorkersDefault                        = 2
+	jobMaxAttemptsDefault                    = 5
+	jobRetryBackoffDefault                   = time.Duration(30) * time.Second
+	cacheSizingAdaptiveDefault               = false
+	cacheSizingMaxSizeDefault                = 100000
+	cacheSizingMemoryLimitDefault            = 0
+	cacheSizingIntervalDefault               = time.Duration(5) * time.Minute
+	sharedChannelsSafeModeDefault            = false
+	sharedChannelsIgnoreExternalDefault      = true
+	retentionSweepIntervalDefault            = time.Hour
 )
 
 // ReplyBehavior holds flags to define the replying behavior (use threads or not and broadcast replies or not)
@@ -53,12 +127,41 @@ func NewViperWithDefaults() (v *viper.Viper) {
 	v = viper.New()
 	v.SetDefault(DebugKey, debugDefault)
 	v.SetDefault(ResponseCacheSizeKey, responseCacheSizeDefault)
+	v.SetDefault(ResponseRetentionKey, responseRetentionDefault)
+	v.SetDefault(EventDedupWindowKey, eventDedupWindowDefault)
 	v.SetDefault(TimeLocationKey, timeLocationDefault)
 	v.SetDefault(ThreadedRepliesKey, threadedRepliesDefault)
 	v.SetDefault(BroadcastThreadedRepliesKey, broadcastThreadedRepliesDefault)
 	v.SetDefault(MaxAgeHandledMessages, maxAgeHandledMessagesDefault)
+	v.SetDefault(MessageUpdateAgeLimitKey, messageUpdateAgeLimitDefault)
 	v.SetDefault(MessageProcessingPartitionCount, msgProcessingPartitionCountDefault)
 	v.SetDefault(MessageProcessingBufferedMessageCount, msgProcessingBufferedMessageCountDefault)
+	v.SetDefault(AnswerPolicyKey, answerPolicyDefault)
+	v.SetDefault(ThreadParentDeletionKey, threadParentDeletionDefault)
+	v.SetDefault(MaxAnswersPerMessageKey, maxAnswersPerMessageDefault)
+	v.SetDefault(ThemeKey, themeDefault)
+	v.SetDefault(ChannelInfoCacheSizeKey, channelInfoCacheSizeDefault)
+	v.SetDefault(UserInfoCacheRetentionKey, userInfoCacheRetentionDefault)
+	v.SetDefault(EmojiListRefreshIntervalKey, emojiListRefreshIntervalDefault)
+	v.SetDefault(UserInfoFallbackKey, userInfoFallbackDefault)
+	v.SetDefault(UserInfoRetryAfterKey, userInfoRetryAfterDefault)
+	v.SetDefault(AllowCustomIdentitiesKey, allowCustomIdentitiesDefault)
+	v.SetDefault(RateLimitEnabledKey, rateLimitEnabledDefault)
+	v.SetDefault(RateLimitMaxRetriesKey, rateLimitMaxRetriesDefault)
+	v.SetDefault(RateLimitQueueSizeKey, rateLimitQueueSizeDefault)
+	v.SetDefault(RateLimitShedLowPriorityAboveKey, rateLimitShedLowPriorityAboveDefault)
+	v.SetDefault(ShutdownTimeoutKey, shutdownTimeoutDefault)
+	v.SetDefault(ProgressUpdateIntervalKey, progressUpdateIntervalDefault)
+	v.SetDefault(JobWorkersKey, jobWorkersDefault)
+	v.SetDefault(JobMaxAttemptsKey, jobMaxAttemptsDefault)
+	v.SetDefault(JobRetryBackoffKey, jobRetryBackoffDefault)
+	v.SetDefault(CacheSizingAdaptiveKey, cacheSizingAdaptiveDefault)
+	v.SetDefault(CacheSizingMaxSizeKey, cacheSizingMaxSizeDefault)
+	v.SetDefault(CacheSizingMemoryLimitKey, cacheSizingMemoryLimitDefault)
+	v.SetDefault(CacheSizingIntervalKey, cacheSizingIntervalDefault)
+	v.SetDefault(SharedChannelsSafeModeKey, sharedChannelsSafeModeDefault)
+	v.SetDefault(SharedChannelsIgnoreExternalKey, sharedChannelsIgnoreExternalDefault)
+	v.SetDefault(RetentionSweepIntervalKey, retentionSweepIntervalDefault)
 
 	return v
 }
@@ -87,7 +190,97 @@ func GetTimeLocation(v *viper.Viper) (timeLoc *time.Location, err error) {
 	return timeLoc, nil
 }
 
-// GetPluginConfig returns the viper sub-tree for a named plugin
+// PluginUserInfoFallbackKey is the key, in a plugin's configuration, overriding UserInfoFallbackKey for that plugin
+const PluginUserInfoFallbackKey = "userInfoFallback"
+
+// GetUserInfoFallback returns the user info fallback mode of a plugin: its override, if configured, or the
+// UserInfoFallbackKey value. The key of the returned value is also returned to help report invalid values
+func GetUserInfoFallback(v *viper.Viper, pluginName string) (mode string, key string) {
+	key = fmt.Sprintf("%s.%s.%s", PluginsKey, pluginName, PluginUserInfoFallbackKey)
+	if v.IsSet(key) {
+		return v.GetString(key), key
+	}
+
+	return v.GetString(UserInfoFallbackKey), UserInfoFallbackKey
+}
+
+// Keys, in a plugin's configuration, of the channels the plugin runs in (see GetPluginChannels)
+const (
+	PluginAllowedChannelIDsKey = "allowedChannelIDs"
+	PluginDeniedChannelIDsKey  = "deniedChannelIDs"
+)
+
+// GetPluginChannels returns the ids of the channels a plugin is restricted to (none meaning all channels) and of the
+// channels it's disabled in. For example, to only run karma in #fun:
+//
+//	"plugins": {
+//	  "karma": {
+//	    "allowedChannelIDs": ["C01234"]
+//	  }
+//	}
+func GetPluginChannels(v *viper.Viper, pluginName string) (allowed []string, denied []string) {
+	allowed = v.GetStringSlice(fmt.Sprintf("%s.%s.%s", PluginsKey, pluginName, PluginAllowedChannelIDsKey))
+	denied = v.GetStringSlice(fmt.Sprintf("%s.%s.%s", PluginsKey, pluginName, PluginDeniedChannelIDsKey))
+
+	return allowed, denied
+}
+
+// Keys, in a plugin's configuration, of the retention of the entries of its silos (see GetPluginRetention)
+const (
+	PluginRetentionKey = "retention"
+
+	// AllSilosRetentionKey is the key of the retention of the silos without a retention of their own
+	AllSilosRetentionKey = "*"
+)
+
+// GetPluginRetention returns the retention of the entries of all silos of a plugin and of specific silos, by silo name
+// (lowercased, like all configuration keys). Retentions are durations (i.e. 2160h) or numbers of days (i.e. 90d). For
+// example, to keep karma for a year and the links of an archive plugin for 90 days:
+//
+//	"plugins": {
+//	  "karma": {
+//	    "retention": {"*": "365d"}
+//	  },
+//	  "archiver": {
+//	    "retention": {"links": "90d"}
+//	  }
+//	}
+func GetPluginRetention(v *viper.Viper, pluginName string) (allSilos time.Duration, silos map[string]time.Duration, err error) {
+	key := fmt.Sprintf("%s.%s.%s", PluginsKey, pluginName, PluginRetentionKey)
+
+	silos = make(map[string]time.Duration)
+	for silo, rawRetention := range v.GetStringMapString(key) {
+		retention, err := parseRetention(rawRetention)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Invalid retention [%s] of silo [%s] at [%s]: %v", rawRetention, silo, key, err)
+		}
+
+		if silo == AllSilosRetentionKey {
+			allSilos = retention
+		} else {
+			silos[silo] = retention
+		}
+	}
+
+	return allSilos, silos, nil
+}
+
+// parseRetention parses a retention given as a number of days (i.e. 90d) or as a duration (i.e. 2160h)
+func parseRetention(rawRetention string) (retention time.Duration, err error) {
+	if days := strings.TrimSuffix(rawRetention, "d"); days != rawRetention {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(rawRetention)
+}
+
+// GetPluginConfig returns the viper sub-tree for a named plugin. If a typed configuration is registered for the plugin
+// (see Register), the configuration is also decoded into it and an error is returned if it's invalid
 func GetPluginConfig(v *viper.Viper, name string) (pluginConfig *PluginConfig, err error) {
 	pluginConfigPath := fmt.Sprintf("%s.%s", PluginsKey, name)
 	if ok := v.IsSet(pluginConfigPath); !ok {
@@ -96,5 +289,10 @@ func GetPluginConfig(v *viper.Viper, name string) (pluginConfig *PluginConfig, e
 
 	subViper := v.Sub(pluginConfigPath)
 	pc := PluginConfig(*subViper)
+
+	if err = decodeRegistered(name, &pc); err != nil {
+		return nil, err
+	}
+
 	return &pc, nil
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/alexandre-normand/slackscot/config"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
 )
@@ -13,12 +14,41 @@ func TestNewWithDefault(t *testing.T) {
 
 	assert.Equal(t, false, v.GetBool(config.DebugKey), "%s should be %t", config.DebugKey, false)
 	assert.Equal(t, 5000, v.GetInt(config.ResponseCacheSizeKey), "%s should be %d", config.ResponseCacheSizeKey, 5000)
+	assert.Equal(t, time.Duration(24)*time.Hour, v.GetDuration(config.ResponseRetentionKey), "%s should be %s", config.ResponseRetentionKey, time.Duration(24)*time.Hour)
+	assert.Equal(t, 5*time.Minute, v.GetDuration(config.EventDedupWindowKey), "%s should be %s", config.EventDedupWindowKey, 5*time.Minute)
 	assert.Equal(t, "Local", v.GetString(config.TimeLocationKey), "%s should be %s", config.TimeLocationKey, "Local")
 	assert.Equal(t, false, v.GetBool(config.ThreadedRepliesKey), "%s should be %t", config.ThreadedRepliesKey, false)
 	assert.Equal(t, false, v.GetBool(config.BroadcastThreadedRepliesKey), "%s should be %t", config.BroadcastThreadedRepliesKey, false)
 	assert.Equal(t, time.Duration(24)*time.Hour, v.GetDuration(config.MaxAgeHandledMessages), "%s should be %t", config.MaxAgeHandledMessages, time.Duration(24)*time.Hour)
+	assert.Equal(t, 0, v.GetInt(config.MessageUpdateAgeLimitKey), "%s should be %d", config.MessageUpdateAgeLimitKey, 0)
 	assert.Equal(t, 16, v.GetInt(config.MessageProcessingPartitionCount), "%s should be %d", config.MessageProcessingPartitionCount, 16)
 	assert.Equal(t, 10, v.GetInt(config.MessageProcessingBufferedMessageCount), "%s should be %d", config.MessageProcessingBufferedMessageCount, 10)
+	assert.Equal(t, "all", v.GetString(config.AnswerPolicyKey), "%s should be %s", config.AnswerPolicyKey, "all")
+	assert.Equal(t, "delete", v.GetString(config.ThreadParentDeletionKey), "%s should be %s", config.ThreadParentDeletionKey, "delete")
+	assert.Equal(t, 0, v.GetInt(config.MaxAnswersPerMessageKey), "%s should be %d", config.MaxAnswersPerMessageKey, 0)
+	assert.Equal(t, "classic", v.GetString(config.ThemeKey), "%s should be %s", config.ThemeKey, "classic")
+	assert.Equal(t, 500, v.GetInt(config.ChannelInfoCacheSizeKey), "%s should be %d", config.ChannelInfoCacheSizeKey, 500)
+	assert.Equal(t, time.Duration(24)*time.Hour, v.GetDuration(config.UserInfoCacheRetentionKey), "%s should be %s", config.UserInfoCacheRetentionKey, time.Duration(24)*time.Hour)
+	assert.Equal(t, time.Hour, v.GetDuration(config.EmojiListRefreshIntervalKey), "%s should be %s", config.EmojiListRefreshIntervalKey, time.Hour)
+	assert.Equal(t, "error", v.GetString(config.UserInfoFallbackKey), "%s should be %s", config.UserInfoFallbackKey, "error")
+	assert.Equal(t, time.Minute, v.GetDuration(config.UserInfoRetryAfterKey), "%s should be %s", config.UserInfoRetryAfterKey, time.Minute)
+	assert.Equal(t, false, v.GetBool(config.AllowCustomIdentitiesKey), "%s should be %t", config.AllowCustomIdentitiesKey, false)
+	assert.Equal(t, true, v.GetBool(config.RateLimitEnabledKey), "%s should be %t", config.RateLimitEnabledKey, true)
+	assert.Equal(t, 3, v.GetInt(config.RateLimitMaxRetriesKey), "%s should be %d", config.RateLimitMaxRetriesKey, 3)
+	assert.Equal(t, 100, v.GetInt(config.RateLimitQueueSizeKey), "%s should be %d", config.RateLimitQueueSizeKey, 100)
+	assert.Equal(t, 0., v.GetFloat64(config.RateLimitShedLowPriorityAboveKey), "%s should be %f", config.RateLimitShedLowPriorityAboveKey, 0.)
+	assert.Equal(t, 20*time.Second, v.GetDuration(config.ShutdownTimeoutKey), "%s should be %s", config.ShutdownTimeoutKey, 20*time.Second)
+	assert.Equal(t, 3*time.Second, v.GetDuration(config.ProgressUpdateIntervalKey), "%s should be %s", config.ProgressUpdateIntervalKey, 3*time.Second)
+	assert.Equal(t, 2, v.GetInt(config.JobWorkersKey), "%s should be %d", config.JobWorkersKey, 2)
+	assert.Equal(t, 5, v.GetInt(config.JobMaxAttemptsKey), "%s should be %d", config.JobMaxAttemptsKey, 5)
+	assert.Equal(t, 30*time.Second, v.GetDuration(config.JobRetryBackoffKey), "%s should be %s", config.JobRetryBackoffKey, 30*time.Second)
+	assert.Equal(t, false, v.GetBool(config.CacheSizingAdaptiveKey), "%s should be %t", config.CacheSizingAdaptiveKey, false)
+	assert.Equal(t, 100000, v.GetInt(config.CacheSizingMaxSizeKey), "%s should be %d", config.CacheSizingMaxSizeKey, 100000)
+	assert.Equal(t, 0, v.GetInt(config.CacheSizingMemoryLimitKey), "%s should be %d", config.CacheSizingMemoryLimitKey, 0)
+	assert.Equal(t, 5*time.Minute, v.GetDuration(config.CacheSizingIntervalKey), "%s should be %s", config.CacheSizingIntervalKey, 5*time.Minute)
+	assert.Equal(t, false, v.GetBool(config.SharedChannelsSafeModeKey), "%s should be %t", config.SharedChannelsSafeModeKey, false)
+	assert.Equal(t, true, v.GetBool(config.SharedChannelsIgnoreExternalKey), "%s should be %t", config.SharedChannelsIgnoreExternalKey, true)
+	assert.Equal(t, time.Hour, v.GetDuration(config.RetentionSweepIntervalKey), "%s should be %s", config.RetentionSweepIntervalKey, time.Hour)
 }
 
 func TestLayerConfigWithDefaults(t *testing.T) {
@@ -117,3 +147,60 @@ func TestGetPluginConfigWithMissingConfig(t *testing.T) {
 		assert.Contains(t, err.Error(), "Missing plugin configuration for plugin [pluginName]")
 	}
 }
+
+func TestGetUserInfoFallback(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.karma.userInfoFallback", "mention")
+
+	mode, key := config.GetUserInfoFallback(v, "karma")
+	assert.Equal(t, "mention", mode)
+	assert.Equal(t, "plugins.karma.userInfoFallback", key)
+
+	mode, key = config.GetUserInfoFallback(v, "help")
+	assert.Equal(t, "error", mode)
+	assert.Equal(t, config.UserInfoFallbackKey, key)
+}
+
+func TestGetPluginChannels(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.karma.allowedChannelIDs", []string{"Cfun"})
+	v.Set("plugins.karma.deniedChannelIDs", []string{"Cgeneral"})
+
+	allowed, denied := config.GetPluginChannels(v, "karma")
+	assert.Equal(t, []string{"Cfun"}, allowed)
+	assert.Equal(t, []string{"Cgeneral"}, denied)
+
+	allowed, denied = config.GetPluginChannels(v, "help")
+	assert.Empty(t, allowed)
+	assert.Empty(t, denied)
+}
+
+func TestGetPluginRetention(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.karma.retention", map[string]interface{}{"*": "365d"})
+	v.Set("plugins.archiver.retention", map[string]interface{}{"Links": "90d", "drafts": "72h"})
+
+	allSilos, silos, err := config.GetPluginRetention(v, "karma")
+	require.NoError(t, err)
+	assert.Equal(t, 365*24*time.Hour, allSilos)
+	assert.Empty(t, silos)
+
+	allSilos, silos, err = config.GetPluginRetention(v, "archiver")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), allSilos)
+	assert.Equal(t, map[string]time.Duration{"links": 90 * 24 * time.Hour, "drafts": 72 * time.Hour}, silos)
+
+	allSilos, silos, err = config.GetPluginRetention(v, "help")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), allSilos)
+	assert.Empty(t, silos)
+}
+
+func TestGetPluginRetentionWithInvalidValue(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.karma.retention", map[string]interface{}{"*": "a year"})
+
+	_, _, err := config.GetPluginRetention(v, "karma")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid retention [a year] of silo [*] at [plugins.karma.retention]")
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"flag"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Source is where the effective value of a configuration key comes from
+type Source string
+
+// Sources of configuration values, in order of precedence
+const (
+	SourceOverride Source = "override" // Set explicitly in code (i.e. viper.Set)
+	SourceFlag     Source = "flag"     // Command-line flag
+	SourceEnv      Source = "env"      // Environment variable (see NewViperFromEnv)
+	SourceFile     Source = "file"     // Configuration file (or remote configuration document)
+	SourceDefault  Source = "default"  // Default value (see NewViperWithDefaults)
+)
+
+// RedactedValue replaces the value of secret configuration keys
+const RedactedValue = "[redacted]"
+
+// secretKeyPattern matches the last segment of the configuration keys holding secrets (i.e. token, webhooks.sharedSecret or plugins.analytics.apiToken)
+var secretKeyPattern = regexp.MustCompile(`(?i)(token|secret|password|apikey|credentials)`)
+
+// EffectiveSetting is the resolved value of a configuration key along with its source
+type EffectiveSetting struct {
+	Key    string
+	Value  interface{}
+	Source Source
+}
+
+// EffectiveSettings returns the fully-resolved settings of a configuration, sorted by key, with secrets redacted.
+// Sources are determined by looking, in order of precedence, at the flags of the flag set that were set (matched by
+// name to the key, case-insensitive), the environment variables with the prefix named as expected by NewViperFromEnv,
+// // the configuration file and the defaults. Everything else is reported as an override. Both envPrefix and fs are optional
+func EffectiveSettings(v *viper.Viper, envPrefix string, fs *flag.FlagSet) (settings []EffectiveSetting) {
+	flagKeys := make(map[string]bool)
+	if fs != nil {
+		fs.Visit(func(f *flag.Flag) {
+			flagKeys[strings.ToLower(f.Name)] = true
+		})
+	}
+
+	envKeys := envConfigKeys(envPrefix)
+	defaults := NewViperWithDefaults()
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+
+	settings = make([]EffectiveSetting, 0, len(keys))
+	for _, key := range keys {
+		value := v.Get(key)
+
+		var source Source
+		switch {
+		case flagKeys[key]:
+			source = SourceFlag
+		case hasKeyOrParent(envKeys, key):
+			source = SourceEnv
+		case inConfigFile(v, key):
+			source = SourceFile
+		case defaults.IsSet(key) && reflect.DeepEqual(defaults.Get(key), value):
+			source = SourceDefault
+		default:
+			source = SourceOverride
+		}
+
+		if isSecretKey(key) && value != nil && value != "" {
+			value = RedactedValue
+		}
+
+		settings = append(settings, EffectiveSetting{Key: key, Value: value, Source: source})
+	}
+
+	return settings
+}
+
+// inConfigFile returns true if the key is in the configuration file. Viper only tells if a root key is in the file so
+// nested keys are looked up in the root's value which is the file's subtree unless the root is also overridden
+func inConfigFile(v *viper.Viper, key string) bool {
+	path := strings.Split(key, ".")
+	if !v.InConfig(path[0]) {
+		return false
+	}
+
+	value := v.Get(path[0])
+	for _, segment := range path[1:] {
+		m, err := cast.ToStringMapE(value)
+		if err != nil {
+			return false
+		}
+
+		if value = lookupCaseInsensitive(m, segment); value == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookupCaseInsensitive returns the value of a map key, ignoring case
+func lookupCaseInsensitive(m map[string]interface{}, key string) (value interface{}) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// envConfigKeys returns the (lowercase) configuration keys set by the environment variables with the prefix
+func envConfigKeys(prefix string) (keys map[string]bool) {
+	keys = make(map[string]bool)
+	if prefix == "" {
+		return keys
+	}
+
+	envPrefix := prefix + "_"
+	for _, variable := range os.Environ() {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+
+		path := strings.Split(strings.TrimPrefix(name, envPrefix), envNestingSeparator)
+		for i, segment := range path {
+			path[i] = strings.ToLower(strings.Replace(segment, "_", "", -1))
+		}
+
+		keys[strings.Join(path, ".")] = true
+	}
+
+	return keys
+}
+
+// hasKeyOrParent returns true if the key or one of its parents is in the set of keys. A parent is set when a whole
+// object is given as a JSON value
+func hasKeyOrParent(keys map[string]bool, key string) bool {
+	for k := key; ; k = k[:strings.LastIndex(k, ".")] {
+		if keys[k] {
+			return true
+		}
+
+		if !strings.Contains(k, ".") {
+			return false
+		}
+	}
+}
+
+// isSecretKey returns true if the last segment of the key names a secret
+func isSecretKey(key string) bool {
+	return secretKeyPattern.MatchString(key[strings.LastIndex(key, ".")+1:])
+}
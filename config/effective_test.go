@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"flag"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEffectiveSettings(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.json": `{"token": "xoxb-secret", "replyBehavior": {"broadcastThreadedReplies": true}, "plugins": {"analytics": {"apiToken": "s3cr3t", "defaultDays": 7}}}`,
+	})
+	defer os.RemoveAll(dir)
+
+	v, err := config.NewViperWithProfile(filepath.Join(dir, "config.json"), "")
+	require.NoError(t, err)
+
+	require.NoError(t, os.Setenv("TESTSCOT_ANSWER_POLICY__MODE", "priority"))
+	defer os.Unsetenv("TESTSCOT_ANSWER_POLICY__MODE")
+	v.Set(config.AnswerPolicyKey, "priority")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool(config.DebugKey, false, "debug mode")
+	require.NoError(t, fs.Parse([]string{"--debug"}))
+	v.Set(config.DebugKey, true)
+
+	v.Set(config.CommandPrefixKey, "!")
+
+	settings := make(map[string]config.EffectiveSetting)
+	for _, s := range config.EffectiveSettings(v, "TESTSCOT", fs) {
+		settings[s.Key] = s
+	}
+
+	assert.Equal(t, config.EffectiveSetting{Key: "token", Value: config.RedactedValue, Source: config.SourceFile}, settings["token"])
+	assert.Equal(t, config.EffectiveSetting{Key: "plugins.analytics.apitoken", Value: config.RedactedValue, Source: config.SourceFile}, settings["plugins.analytics.apitoken"])
+	assert.Equal(t, config.EffectiveSetting{Key: "plugins.analytics.defaultdays", Value: float64(7), Source: config.SourceFile}, settings["plugins.analytics.defaultdays"])
+	assert.Equal(t, config.EffectiveSetting{Key: "replybehavior.broadcastthreadedreplies", Value: true, Source: config.SourceFile}, settings["replybehavior.broadcastthreadedreplies"])
+	assert.Equal(t, config.EffectiveSetting{Key: "replybehavior.threadedreplies", Value: false, Source: config.SourceDefault}, settings["replybehavior.threadedreplies"])
+	assert.Equal(t, config.EffectiveSetting{Key: "answerpolicy.mode", Value: "priority", Source: config.SourceEnv}, settings["answerpolicy.mode"])
+	assert.Equal(t, config.EffectiveSetting{Key: "debug", Value: true, Source: config.SourceFlag}, settings["debug"])
+	assert.Equal(t, config.EffectiveSetting{Key: "commandprefix", Value: "!", Source: config.SourceOverride}, settings["commandprefix"])
+}
+
+func TestEffectiveSettingsWithoutSources(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.WebhookSharedSecretKey, "")
+
+	settings := config.EffectiveSettings(v, "", nil)
+	require.NotEmpty(t, settings)
+	assert.Equal(t, "advanced.messageprocessingbufferedmessagecount", settings[0].Key)
+
+	for _, s := range settings {
+		if s.Key == "webhooks.sharedsecret" {
+			assert.Equal(t, config.EffectiveSetting{Key: "webhooks.sharedsecret", Value: "", Source: config.SourceOverride}, s)
+		} else {
+			assert.Equal(t, config.SourceDefault, s.Source, "%s should come from defaults", s.Key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config_test
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func setEnv(t *testing.T, env map[string]string) (cleanup func()) {
+	for name, value := range env {
+		require.NoError(t, os.Setenv(name, value))
+	}
+
+	return func() {
+		for name := range env {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestNewViperFromEnv(t *testing.T) {
+	defer setEnv(t, map[string]string{
+		"TESTBOT_TOKEN":                                  "xoxb-token",
+		"TESTBOT_RESPONSE_CACHE_SIZE":                    "100",
+		"TESTBOT_REPLYBEHAVIOR__THREADEDREPLIES":         "true",
+		"TESTBOT_PLUGINS__FINGERQUOTER__CHANNEL_IDS":     "[\"C01234\", \"C56789\"]",
+		"TESTBOT_PLUGINS__ALERTMANAGER":                  "{\"routes\": {\"severity=critical\": \"Concall\"}}",
+		"TESTBOT_PLUGINS__ALERTMANAGER__CHANNELID":       "Calerts",
+		"TESTBOT_PLUGINS__KARMA":                         "{}",
+		"OTHERBOT_TOKEN":                                 "ignored",
+		"TESTBOT_PLUGINS__ALERTMANAGER__SILENCEDURATION": "2h",
+	})()
+
+	v, err := config.NewViperFromEnv("TESTBOT")
+	require.NoError(t, err)
+
+	assert.Equal(t, "xoxb-token", v.GetString(config.TokenKey))
+	assert.Equal(t, 100, v.GetInt(config.ResponseCacheSizeKey))
+	assert.Equal(t, true, v.GetBool(config.ThreadedRepliesKey))
+	assert.Equal(t, "Local", v.GetString(config.TimeLocationKey))
+
+	pc, err := config.GetPluginConfig(v, "fingerQuoter")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C01234", "C56789"}, pc.GetStringSlice("channelIDs"))
+
+	pc, err = config.GetPluginConfig(v, "alertmanager")
+	require.NoError(t, err)
+	assert.Equal(t, "Calerts", pc.GetString("channelID"))
+	assert.Equal(t, "2h0m0s", pc.GetDuration("silenceDuration").String())
+	assert.Equal(t, map[string]string{"severity=critical": "Concall"}, pc.GetStringMapString("routes"))
+
+	_, err = config.GetPluginConfig(v, "karma")
+	assert.NoError(t, err)
+}
+
+func TestNewViperFromEnvWithInvalidJSON(t *testing.T) {
+	defer setEnv(t, map[string]string{"TESTBOT_PLUGINS__KARMA": "{"})()
+
+	_, err := config.NewViperFromEnv("TESTBOT")
+	assert.EqualError(t, err, "Invalid value for environment variable [TESTBOT_PLUGINS__KARMA]: unexpected end of JSON input")
+}
+
+func TestNewViperFromEnvWithConflictingVariables(t *testing.T) {
+	defer setEnv(t, map[string]string{"TESTBOT_PLUGINS__KARMA": "enabled", "TESTBOT_PLUGINS__KARMA__CHANNELID": "C1"})()
+
+	_, err := config.NewViperFromEnv("TESTBOT")
+	assert.EqualError(t, err, "Invalid environment variable [TESTBOT_PLUGINS__KARMA__CHANNELID]: conflicting value for [karma]")
+}
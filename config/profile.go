@@ -0,0 +1,80 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ProfileFlagName is the name of the command-line flag selecting the configuration profile (see AddProfileFlag)
+	ProfileFlagName = "profile"
+
+	// ProfileEnvVar is the environment variable selecting the configuration profile when the flag isn't set
+	ProfileEnvVar = "SLACKSCOT_PROFILE"
+
+	// LocalOverlayName is the name of the optional overlay merged last, meant for local overrides that aren't committed
+	LocalOverlayName = "local"
+)
+
+// NewViperWithProfile creates a new viper instance, with defaults, from a base configuration file with, merged in
+// order, the overlay of the profile (if not empty) and the local overlay (if it exists). Overlays are files next to
+// the base with the profile name before the extension so that, for a base config.json and a profile prod, these are
+// merged:
+//
+//	config.json: the base configuration shared by all environments
+//	config.prod.json: the overlay of the prod profile, required if the profile is set
+//	config.local.json: local overrides, optional
+//
+// Nested keys are merged so overlays only need to include what differs from the base. Slices are replaced entirely
+func NewViperWithProfile(basePath string, profile string) (v *viper.Viper, err error) {
+	if strings.ContainsAny(profile, "/\\") || profile == LocalOverlayName {
+		return nil, fmt.Errorf("Invalid config profile [%s]", profile)
+	}
+
+	v = viper.New()
+	v.SetConfigFile(basePath)
+	if err = v.ReadInConfig(); err != nil {
+		return nil, errors.Wrapf(err, "Unable to load base config [%s]", basePath)
+	}
+
+	if profile != "" {
+		if err = mergeConfigFile(v, overlayPath(basePath, profile)); err != nil {
+			return nil, err
+		}
+	}
+
+	localPath := overlayPath(basePath, LocalOverlayName)
+	if _, err = os.Stat(localPath); err == nil {
+		if err = mergeConfigFile(v, localPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return LayerConfigWithDefaults(v), nil
+}
+
+// AddProfileFlag defines the profile flag on the flag set. Its default value is the value of ProfileEnvVar, if set
+func AddProfileFlag(fs *flag.FlagSet) (profile *string) {
+	return fs.String(ProfileFlagName, os.Getenv(ProfileEnvVar), "Configuration profile whose overlay is merged over the base configuration (i.e. dev, staging or prod)")
+}
+
+// overlayPath returns the path of an overlay of the base configuration file
+func overlayPath(basePath string, name string) string {
+	ext := filepath.Ext(basePath)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(basePath, ext), name, ext)
+}
+
+// mergeConfigFile merges the configuration file into the viper instance
+func mergeConfigFile(v *viper.Viper, path string) (err error) {
+	v.SetConfigFile(path)
+	if err = v.MergeInConfig(); err != nil {
+		return errors.Wrapf(err, "Unable to merge config overlay [%s]", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package config_test
+
+import (
+	"flag"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) (dir string) {
+	dir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	return dir
+}
+
+func TestNewViperWithProfile(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.json":       `{"token": "base", "replyBehavior": {"threadedReplies": true, "broadcastThreadedReplies": true}, "plugins": {"karma": {}, "ohMonday": {"channelIDs": ["Cbase"]}}}`,
+		"config.prod.json":  `{"token": "prod", "replyBehavior": {"broadcastThreadedReplies": false}, "plugins": {"ohMonday": {"channelIDs": ["Cprod"]}}}`,
+		"config.local.json": `{"debug": true}`,
+	})
+	defer os.RemoveAll(dir)
+
+	v, err := config.NewViperWithProfile(filepath.Join(dir, "config.json"), "prod")
+	require.NoError(t, err)
+
+	assert.Equal(t, "prod", v.GetString(config.TokenKey))
+	assert.Equal(t, true, v.GetBool(config.ThreadedRepliesKey))
+	assert.Equal(t, false, v.GetBool(config.BroadcastThreadedRepliesKey))
+	assert.Equal(t, true, v.GetBool(config.DebugKey))
+	assert.Equal(t, 5000, v.GetInt(config.ResponseCacheSizeKey))
+	assert.Equal(t, []string{"Cprod"}, v.GetStringSlice("plugins.ohMonday.channelIDs"))
+	assert.True(t, v.IsSet("plugins.karma"))
+}
+
+func TestNewViperWithoutProfile(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"config.yaml": "token: base\n"})
+	defer os.RemoveAll(dir)
+
+	v, err := config.NewViperWithProfile(filepath.Join(dir, "config.yaml"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "base", v.GetString(config.TokenKey))
+	assert.Equal(t, false, v.GetBool(config.DebugKey))
+}
+
+func TestNewViperWithMissingProfileOverlay(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"config.yaml": "token: base\n"})
+	defer os.RemoveAll(dir)
+
+	_, err := config.NewViperWithProfile(filepath.Join(dir, "config.yaml"), "staging")
+	assert.Contains(t, err.Error(), "Unable to merge config overlay ["+filepath.Join(dir, "config.staging.yaml")+"]")
+
+	_, err = config.NewViperWithProfile(filepath.Join(dir, "config.yaml"), "../prod")
+	assert.EqualError(t, err, "Invalid config profile [../prod]")
+}
+
+func TestAddProfileFlag(t *testing.T) {
+	require.NoError(t, os.Setenv(config.ProfileEnvVar, "staging"))
+	defer os.Unsetenv(config.ProfileEnvVar)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	profile := config.AddProfileFlag(fs)
+	require.NoError(t, fs.Parse([]string{}))
+	assert.Equal(t, "staging", *profile)
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	profile = config.AddProfileFlag(fs)
+	require.NoError(t, fs.Parse([]string{"--profile", "prod"}))
+	assert.Equal(t, "prod", *profile)
+}
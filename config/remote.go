@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultRemotePollInterval = 30 * time.Second
+	remoteErrorBackoff        = 10 * time.Second
+)
+
+// RemoteProvider is implemented by remote key/value stores (see NewConsulProvider and NewEtcdProvider) holding a
+// configuration document
+type RemoteProvider interface {
+	// Fetch returns the configuration document along with its version. When lastVersion isn't empty, providers
+	// supporting it wait for a version different than lastVersion (or for their own timeout) before returning
+	Fetch(ctx context.Context, lastVersion string) (document []byte, version string, err error)
+}
+
+// RemoteConfig loads the configuration from a RemoteProvider and watches it for changes. Every configuration
+// successfully loaded is cached on disk and used as the last-known-good configuration when the remote store
+// can't be reached
+type RemoteConfig struct {
+	provider     RemoteProvider
+	configType   string
+	cachePath    string
+	pollInterval time.Duration
+
+	// Version of the configuration returned by Load, empty if it came from the cache
+	loadedVersion string
+}
+
+// RemoteOption defines an option for a RemoteConfig
+type RemoteOption func(rc *RemoteConfig)
+
+// OptionRemotePollInterval sets the interval between fetches while watching providers that don't wait for
+// changes (i.e. etcd). Defaults to 30s
+func OptionRemotePollInterval(interval time.Duration) RemoteOption {
+	return func(rc *RemoteConfig) {
+		rc.pollInterval = interval
+	}
+}
+
+// NewRemoteConfig creates a new RemoteConfig for a document of the given type (any type supported by viper such as
+// json, yaml or toml) cached at cachePath
+func NewRemoteConfig(provider RemoteProvider, configType string, cachePath string, options ...RemoteOption) (rc *RemoteConfig, err error) {
+	if !stringInSlice(configType, viper.SupportedExts) {
+		return nil, fmt.Errorf("Unsupported remote config type [%s], should be one of %v", configType, viper.SupportedExts)
+	}
+
+	rc = new(RemoteConfig)
+	rc.provider = provider
+	rc.configType = configType
+	rc.cachePath = cachePath
+	rc.pollInterval = defaultRemotePollInterval
+
+	for _, option := range options {
+		option(rc)
+	}
+
+	return rc, nil
+}
+
+// Load fetches the configuration, with defaults, from the remote store. If that fails, the last-known-good
+// configuration cached on disk is loaded instead and an error is only returned if there isn't one
+func (rc *RemoteConfig) Load() (v *viper.Viper, err error) {
+	v, rc.loadedVersion, err = rc.fetch(context.Background(), "")
+	if err == nil {
+		return v, nil
+	}
+
+	document, cacheErr := ioutil.ReadFile(rc.cachePath)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("Error loading remote config (%v) without a cached config to fall back to: %v", err, cacheErr)
+	}
+
+	return rc.parse(document)
+}
+
+// Watch watches the remote store until the context is done and calls onChange with every configuration, with
+// defaults, different from the one returned by Load. Errors are also reported to onChange (with a nil configuration)
+// and the watch resumes after a backoff. Note that viper instances aren't safe for concurrent writes so applying a new
+// configuration to a running slackscot is up to the caller (i.e. by restarting it)
+func (rc *RemoteConfig) Watch(ctx context.Context, onChange func(v *viper.Viper, err error)) {
+	lastVersion := rc.loadedVersion
+
+	for ctx.Err() == nil {
+		v, version, err := rc.fetch(ctx, lastVersion)
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			onChange(nil, err)
+			sleep(ctx, remoteErrorBackoff)
+		case version == lastVersion:
+			sleep(ctx, rc.pollInterval)
+		default:
+			onChange(v, nil)
+			lastVersion = version
+		}
+	}
+}
+
+// fetch fetches the configuration, parses it and caches it on disk
+func (rc *RemoteConfig) fetch(ctx context.Context, lastVersion string) (v *viper.Viper, version string, err error) {
+	document, version, err := rc.provider.Fetch(ctx, lastVersion)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if version == lastVersion {
+		return nil, version, nil
+	}
+
+	if v, err = rc.parse(document); err != nil {
+		return nil, "", err
+	}
+
+	if err = rc.cache(document); err != nil {
+		return nil, "", err
+	}
+
+	return v, version, nil
+}
+
+// parse parses the configuration document and layers defaults under it
+func (rc *RemoteConfig) parse(document []byte) (v *viper.Viper, err error) {
+	v = viper.New()
+	v.SetConfigType(rc.configType)
+
+	if err = v.ReadConfig(bytes.NewReader(document)); err != nil {
+		return nil, err
+	}
+
+	return LayerConfigWithDefaults(v), nil
+}
+
+// cache atomically writes the document to the cache path
+func (rc *RemoteConfig) cache(document []byte) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(rc.cachePath), filepath.Base(rc.cachePath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(document); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), rc.cachePath)
+}
+
+// sleep waits for the duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// stringInSlice returns true if the slice contains the string
+func stringInSlice(s string, slice []string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package config_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// consulServer is a fake consul agent serving a single key
+type consulServer struct {
+	sync.Mutex
+	document string
+	index    int
+	down     bool
+	queries  []string
+}
+
+func (cs *consulServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cs.Lock()
+	defer cs.Unlock()
+
+	cs.queries = append(cs.queries, r.URL.RawQuery)
+	if cs.down || r.URL.Path != "/v1/kv/bots/chickadee" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("X-Consul-Index", fmt.Sprintf("%d", cs.index))
+	fmt.Fprint(w, cs.document)
+}
+
+func (cs *consulServer) set(document string) {
+	cs.Lock()
+	defer cs.Unlock()
+
+	cs.document = document
+	cs.index++
+}
+
+func TestRemoteConfigUnsupportedType(t *testing.T) {
+	_, err := config.NewRemoteConfig(nil, "xml", "")
+	assert.EqualError(t, err, fmt.Sprintf("Unsupported remote config type [xml], should be one of %v", viper.SupportedExts))
+}
+
+func TestRemoteConfigFallsBackToCachedConfig(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	cs := &consulServer{}
+	cs.set("{\"token\": \"xoxb-token\", \"replyBehavior\": {\"threadedReplies\": true}}")
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	rc, err := config.NewRemoteConfig(config.NewConsulProvider(server.URL, "/bots/chickadee", ""), "json", filepath.Join(tmpdir, "config.json"))
+	require.NoError(t, err)
+
+	v, err := rc.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "xoxb-token", v.GetString(config.TokenKey))
+	assert.Equal(t, true, v.GetBool(config.ThreadedRepliesKey))
+	assert.Equal(t, 5000, v.GetInt(config.ResponseCacheSizeKey))
+	assert.Equal(t, []string{"raw=true"}, cs.queries)
+
+	cs.down = true
+	v, err = rc.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "xoxb-token", v.GetString(config.TokenKey))
+
+	os.Remove(filepath.Join(tmpdir, "config.json"))
+	_, err = rc.Load()
+	assert.Error(t, err)
+}
+
+func TestRemoteConfigWatchesConsul(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	cs := &consulServer{}
+	cs.set("token: first")
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	rc, err := config.NewRemoteConfig(config.NewConsulProvider(server.URL, "bots/chickadee", ""), "yaml", filepath.Join(tmpdir, "config.yaml"), config.OptionRemotePollInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = rc.Load()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan string)
+	go rc.Watch(ctx, func(v *viper.Viper, err error) {
+		if err == nil {
+			changes <- v.GetString(config.TokenKey)
+		}
+	})
+
+	cs.set("token: second")
+	assert.Equal(t, "second", <-changes)
+	cancel()
+
+	cs.Lock()
+	defer cs.Unlock()
+	assert.Contains(t, cs.queries, "index=1&raw=true&wait=300s")
+
+	cached, err := ioutil.ReadFile(filepath.Join(tmpdir, "config.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "token: second", string(cached))
+}
+
+func TestEtcdProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path != "/v3/kv/range" || string(body) != fmt.Sprintf("{\"key\":\"%s\"}", base64.StdEncoding.EncodeToString([]byte("/bots/chickadee"))) {
+			fmt.Fprint(w, "{}")
+			return
+		}
+
+		fmt.Fprintf(w, "{\"kvs\": [{\"value\": \"%s\", \"mod_revision\": \"42\"}]}", base64.StdEncoding.EncodeToString([]byte("token: etcd")))
+	}))
+	defer server.Close()
+
+	document, version, err := config.NewEtcdProvider(server.URL, "/bots/chickadee").Fetch(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "token: etcd", string(document))
+	assert.Equal(t, "42", version)
+
+	_, _, err = config.NewEtcdProvider(server.URL, "/bots/missing").Fetch(context.Background(), "")
+	assert.EqualError(t, err, "Missing etcd key [/bots/missing]")
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	consulWaitTime       = 5 * time.Minute
+	remoteRequestTimeout = 30 * time.Second
+)
+
+// consulProvider fetches a configuration document from the consul key/value store
+type consulProvider struct {
+	address string
+	key     string
+	token   string
+	client  *http.Client
+}
+
+// NewConsulProvider creates a RemoteProvider fetching the configuration document at the key of the consul
+// key/value store. The address is the url of the consul agent (i.e. http://localhost:8500). The token is only
+// sent if not empty. Watches use blocking queries so changes are picked up as soon as they happen
+func NewConsulProvider(address string, key string, token string) RemoteProvider {
+	return &consulProvider{address: strings.TrimSuffix(address, "/"), key: strings.TrimPrefix(key, "/"), token: token, client: &http.Client{Timeout: consulWaitTime + remoteRequestTimeout}}
+}
+
+// Fetch returns the document at the key with the consul index as its version
+func (cp *consulProvider) Fetch(ctx context.Context, lastVersion string) (document []byte, version string, err error) {
+	query := url.Values{"raw": {"true"}}
+	if lastVersion != "" {
+		query.Set("index", lastVersion)
+		query.Set("wait", fmt.Sprintf("%.0fs", consulWaitTime.Seconds()))
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v1/kv/%s?%s", cp.address, cp.key, query.Encode()), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if cp.token != "" {
+		req.Header.Set("X-Consul-Token", cp.token)
+	}
+
+	resp, err := cp.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("Unexpected status [%s] getting consul key [%s]", resp.Status, cp.key)
+	}
+
+	document, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return document, resp.Header.Get("X-Consul-Index"), nil
+}
+
+// etcdProvider fetches a configuration document from etcd
+type etcdProvider struct {
+	endpoint string
+	key      string
+	client   *http.Client
+}
+
+// etcdRangeResponse is the response of the etcd v3 range api
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Value       string `json:"value"`
+		ModRevision string `json:"mod_revision"`
+	} `json:"kvs"`
+}
+
+// NewEtcdProvider creates a RemoteProvider fetching the configuration document at the key in etcd using the
+// v3 json api. The endpoint is the url of an etcd member (i.e. http://localhost:2379). Watches poll for changes
+func NewEtcdProvider(endpoint string, key string) RemoteProvider {
+	return &etcdProvider{endpoint: strings.TrimSuffix(endpoint, "/"), key: key, client: &http.Client{Timeout: remoteRequestTimeout}}
+}
+
+// Fetch returns the document at the key with its modification revision as its version
+func (ep *etcdProvider) Fetch(ctx context.Context, lastVersion string) (document []byte, version string, err error) {
+	body, err := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(ep.key))})
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequest("POST", ep.endpoint+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ep.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("Unexpected status [%s] getting etcd key [%s]", resp.Status, ep.key)
+	}
+
+	var rangeResp etcdRangeResponse
+	if err = json.NewDecoder(resp.Body).Decode(&rangeResp); err != nil {
+		return nil, "", err
+	}
+
+	if len(rangeResp.Kvs) == 0 {
+		return nil, "", fmt.Errorf("Missing etcd key [%s]", ep.key)
+	}
+
+	document, err = base64.StdEncoding.DecodeString(rangeResp.Kvs[0].Value)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return document, rangeResp.Kvs[0].ModRevision, nil
+}
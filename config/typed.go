@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Struct tags of typed plugin configurations
+const (
+	// DefaultTag holds the default value of a field, in the same format as it would be in the configuration (i.e. 30m for a time.Duration)
+	DefaultTag = "default"
+
+	// ValidateTag holds the comma-separated validation rules of a field: required, min=<n>, max=<n> and oneof=<a b c>
+	ValidateTag = "validate"
+
+	mapstructureTag = "mapstructure"
+)
+
+var typedConfigs = struct {
+	sync.Mutex
+	byPlugin map[string]interface{}
+}{byPlugin: make(map[string]interface{})}
+
+// Register registers a typed configuration struct for a plugin. Once registered, GetPluginConfig (and therefore
+// the slackscot Builder's WithConfigurablePluginErr) decodes and validates the plugin's configuration into it (see
+// Decode) so that plugins read typed fields instead of raw viper keys:
+//
+//	type karmaConfig struct {
+//	    ChannelIDs []string
+//	    MaxItems   int           `default:"5" validate:"min=1,max=20"`
+//	    Cooldown   time.Duration `default:"1m"`
+//	}
+//
+//	var cfg karmaConfig
+//	config.Register("karma", &cfg)
+//
+// Registering a plugin again replaces its previous registration
+func Register(pluginName string, target interface{}) (err error) {
+	if err = checkTypedConfigTarget(target); err != nil {
+		return err
+	}
+
+	typedConfigs.Lock()
+	defer typedConfigs.Unlock()
+
+	typedConfigs.byPlugin[pluginName] = target
+	return nil
+}
+
+// decodeRegistered decodes the plugin configuration into its registered typed configuration, if any
+func decodeRegistered(pluginName string, pc *PluginConfig) (err error) {
+	typedConfigs.Lock()
+	target, registered := typedConfigs.byPlugin[pluginName]
+	typedConfigs.Unlock()
+
+	if !registered {
+		return nil
+	}
+
+	return Decode(pluginName, pc, target)
+}
+
+// Decode decodes a plugin configuration into a typed configuration struct (given as a pointer). Fields are matched
+// to keys by name (case-insensitive) or by their mapstructure tag and only top-level fields support the DefaultTag
+// and ValidateTag. Defaults are also set in the plugin configuration so they're visible to raw key access
+func Decode(pluginName string, pc *PluginConfig, target interface{}) (err error) {
+	if err = checkTypedConfigTarget(target); err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(target).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := fieldKey(field)
+		if rules := field.Tag.Get(ValidateTag); hasRule(rules, "required") && !pc.IsSet(key) {
+			return fmt.Errorf("Missing %s config key: %s", pluginName, key)
+		}
+
+		if defaultValue, hasDefault := field.Tag.Lookup(DefaultTag); hasDefault {
+			pc.SetDefault(key, defaultValue)
+		}
+	}
+
+	if err = pc.Unmarshal(target); err != nil {
+		return fmt.Errorf("Invalid %s config: %v", pluginName, err)
+	}
+
+	v := reflect.ValueOf(target).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if err = validateField(pluginName, fieldKey(field), field.Tag.Get(ValidateTag), v.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkTypedConfigTarget returns an error if the target isn't a pointer to a struct
+func checkTypedConfigTarget(target interface{}) (err error) {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Typed config should be a pointer to a struct but was [%T]", target)
+	}
+
+	return nil
+}
+
+// fieldKey returns the configuration key of a field
+func fieldKey(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get(mapstructureTag), ",")[0]; name != "" {
+		return name
+	}
+
+	return strings.ToLower(field.Name[:1]) + field.Name[1:]
+}
+
+// hasRule returns true if the validation rules include the rule
+func hasRule(rules string, rule string) bool {
+	for _, r := range strings.Split(rules, ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateField validates the value of a field against its validation rules
+func validateField(pluginName string, key string, rules string, value reflect.Value) (err error) {
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || rule == "required" {
+			continue
+		}
+
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Invalid validation rule [%s] for %s config key %s", rule, pluginName, key)
+		}
+
+		switch parts[0] {
+		case "min", "max":
+			err = validateBound(pluginName, key, parts[0], parts[1], value)
+		case "oneof":
+			err = validateOneOf(pluginName, key, strings.Fields(parts[1]), value)
+		default:
+			err = fmt.Errorf("Invalid validation rule [%s] for %s config key %s", rule, pluginName, key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateBound validates that a numeric value (or the length of a string, slice or map) is within a bound
+func validateBound(pluginName string, key string, kind string, rawBound string, value reflect.Value) (err error) {
+	var actual, bound float64
+	var display interface{}
+
+	switch {
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(rawBound)
+		if err != nil {
+			return fmt.Errorf("Invalid %s bound [%s] for %s config key %s: %v", kind, rawBound, pluginName, key, err)
+		}
+
+		actual, bound, display = float64(value.Int()), float64(d), time.Duration(value.Int())
+	case value.Kind() >= reflect.Int && value.Kind() <= reflect.Int64:
+		actual, display = float64(value.Int()), value.Int()
+	case value.Kind() >= reflect.Uint && value.Kind() <= reflect.Uint64:
+		actual, display = float64(value.Uint()), value.Uint()
+	case value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64:
+		actual, display = value.Float(), value.Float()
+	case value.Kind() == reflect.String || value.Kind() == reflect.Slice || value.Kind() == reflect.Map:
+		actual, display = float64(value.Len()), value.Interface()
+	default:
+		return fmt.Errorf("Invalid %s rule for %s config key %s of type %s", kind, pluginName, key, value.Type())
+	}
+
+	if value.Type() != reflect.TypeOf(time.Duration(0)) {
+		if bound, err = strconv.ParseFloat(rawBound, 64); err != nil {
+			return fmt.Errorf("Invalid %s bound [%s] for %s config key %s: %v", kind, rawBound, pluginName, key, err)
+		}
+	}
+
+	if kind == "min" && actual < bound {
+		return fmt.Errorf("Invalid %s config key value for %s: [%v], should be at least %s", pluginName, key, display, rawBound)
+	}
+
+	if kind == "max" && actual > bound {
+		return fmt.Errorf("Invalid %s config key value for %s: [%v], should be at most %s", pluginName, key, display, rawBound)
+	}
+
+	return nil
+}
+
+// validateOneOf validates that a string value is one of the allowed values
+func validateOneOf(pluginName string, key string, allowed []string, value reflect.Value) (err error) {
+	if value.Kind() != reflect.String {
+		return fmt.Errorf("Invalid oneof rule for %s config key %s of type %s", pluginName, key, value.Type())
+	}
+
+	for _, a := range allowed {
+		if value.String() == a {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Invalid %s config key value for %s: [%s], should be one of %s", pluginName, key, value.String(), strings.Join(allowed, ", "))
+}
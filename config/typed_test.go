@@ -0,0 +1,82 @@
+package config_test
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type standupConfig struct {
+	ChannelID  string        `validate:"required"`
+	Members    []string      `validate:"min=1"`
+	Reminder   time.Duration `default:"15m" validate:"min=1m,max=2h"`
+	MaxUpdates int           `default:"10" validate:"max=50"`
+	Format     string        `default:"thread" validate:"oneof=thread digest"`
+	AtTime     string        `mapstructure:"at" default:"09:30"`
+}
+
+func TestDecodeWithDefaults(t *testing.T) {
+	pc := viper.New()
+	pc.Set("channelID", "Cstandup")
+	pc.Set("members", []string{"U1", "U2"})
+	pc.Set("maxUpdates", 20)
+
+	var cfg standupConfig
+	require.NoError(t, config.Decode("standup", pc, &cfg))
+	assert.Equal(t, standupConfig{ChannelID: "Cstandup", Members: []string{"U1", "U2"}, Reminder: 15 * time.Minute, MaxUpdates: 20, Format: "thread", AtTime: "09:30"}, cfg)
+
+	// Defaults are visible to raw key access as well
+	assert.Equal(t, 15*time.Minute, pc.GetDuration("reminder"))
+}
+
+func TestDecodeValidation(t *testing.T) {
+	testCases := []struct {
+		settings    map[string]interface{}
+		expectedErr string
+	}{
+		{map[string]interface{}{"members": []string{"U1"}}, "Missing standup config key: channelID"},
+		{map[string]interface{}{"channelID": "C1"}, "Invalid standup config key value for members: [[]], should be at least 1"},
+		{map[string]interface{}{"channelID": "C1", "members": []string{"U1"}, "reminder": "30s"}, "Invalid standup config key value for reminder: [30s], should be at least 1m"},
+		{map[string]interface{}{"channelID": "C1", "members": []string{"U1"}, "maxUpdates": 51}, "Invalid standup config key value for maxUpdates: [51], should be at most 50"},
+		{map[string]interface{}{"channelID": "C1", "members": []string{"U1"}, "format": "email"}, "Invalid standup config key value for format: [email], should be one of thread, digest"},
+		{map[string]interface{}{"channelID": "C1", "members": []string{"U1"}, "maxUpdates": "many"}, "Invalid standup config: 1 error(s) decoding:\n\n* cannot parse 'MaxUpdates' as int: strconv.ParseInt: parsing \"many\": invalid syntax"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expectedErr, func(t *testing.T) {
+			pc := viper.New()
+			for k, v := range tc.settings {
+				pc.Set(k, v)
+			}
+
+			var cfg standupConfig
+			assert.EqualError(t, config.Decode("standup", pc, &cfg), tc.expectedErr)
+		})
+	}
+}
+
+func TestRegisterInvalidTarget(t *testing.T) {
+	var cfg standupConfig
+	assert.EqualError(t, config.Register("standup", cfg), "Typed config should be a pointer to a struct but was [config_test.standupConfig]")
+}
+
+func TestGetPluginConfigDecodesRegisteredConfig(t *testing.T) {
+	var cfg standupConfig
+	require.NoError(t, config.Register("standupTest", &cfg))
+
+	v := viper.New()
+	v.Set("plugins.standupTest.channelID", "Cstandup")
+	v.Set("plugins.standupTest.members", "U1,U2")
+	v.Set("plugins.standupTest.at", "10:00")
+
+	_, err := config.GetPluginConfig(v, "standupTest")
+	require.NoError(t, err)
+	assert.Equal(t, standupConfig{ChannelID: "Cstandup", Members: []string{"U1", "U2"}, Reminder: 15 * time.Minute, MaxUpdates: 10, Format: "thread", AtTime: "10:00"}, cfg)
+
+	v.Set("plugins.standupTest.format", "email")
+	_, err = config.GetPluginConfig(v, "standupTest")
+	assert.EqualError(t, err, "Invalid standupTest config key value for format: [email], should be one of thread, digest")
+}
@@ -0,0 +1,183 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	consentPluginName = "consent"
+	optedOutUsersSilo = "optedOutUsers"
+)
+
+var consentRegex = regexp.MustCompile("(?i)\\Aopt (out|in)\\z")
+
+// UserConsent tells whether users opted out of the passive collection of data about them. Users opt out (or back in)
+// by sending opt out (or opt in) to slackscot in a direct message when it's given a storer with OptionConsentStorer.
+//
+// Slackscot already keeps the messages and reactions of users who opted out from the plugins collecting user data (see
+// Plugin.CollectsUserData). Those plugins should also check the users their data is about (i.e. karma given to
+// someone) with the UserConsent injected in them
+type UserConsent interface {
+	// HasOptedOut returns true if the user opted out of the collection of data about them
+	HasOptedOut(userID string) (optedOut bool)
+}
+
+// UserDataPurger deletes the data a plugin collected about a user (see Plugin.PurgeUserData)
+type UserDataPurger func(userID string) (err error)
+
+// userConsent is the UserConsent implementation. Users who opted out are kept in memory and written through to the
+// storer
+type userConsent struct {
+	storer store.GlobalSiloStringStorer
+
+	sync.RWMutex
+	optedOut map[string]bool
+}
+
+// OptionConsentStorer sets the storer persisting the users who opted out of the passive collection of data about them.
+// Setting it also adds the opt out and opt in commands for users to send in a direct message
+func OptionConsentStorer(storer store.GlobalSiloStringStorer) Option {
+	return func(s *Slackscot) {
+		s.consentStorer = storer
+	}
+}
+
+// newUserConsent creates the UserConsent with the users who opted out persisted in the storer, if any
+func newUserConsent(storer store.GlobalSiloStringStorer) (uc *userConsent, err error) {
+	uc = new(userConsent)
+	uc.storer = storer
+	uc.optedOut = make(map[string]bool)
+
+	if storer == nil {
+		return uc, nil
+	}
+
+	entries, err := storer.ScanSilo(optedOutUsersSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	for userID := range entries {
+		uc.optedOut[userID] = true
+	}
+
+	return uc, nil
+}
+
+// HasOptedOut returns true if the user opted out of the collection of data about them
+func (uc *userConsent) HasOptedOut(userID string) (optedOut bool) {
+	uc.RLock()
+	defer uc.RUnlock()
+
+	return uc.optedOut[userID]
+}
+
+// setOptedOut persists whether the user opted out
+func (uc *userConsent) setOptedOut(userID string, optedOut bool) (err error) {
+	uc.Lock()
+	defer uc.Unlock()
+
+	if optedOut {
+		err = uc.storer.PutSiloString(optedOutUsersSilo, userID, "true")
+	} else {
+		err = uc.storer.DeleteSiloString(optedOutUsersSilo, userID)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if optedOut {
+		uc.optedOut[userID] = true
+	} else {
+		delete(uc.optedOut, userID)
+	}
+
+	return nil
+}
+
+// ignoresUserData returns true if the plugin collects user data and the user opted out of it
+func (s *Slackscot) ignoresUserData(p *Plugin, userID string) bool {
+	return p.CollectsUserData && s.userConsent.HasOptedOut(userID)
+}
+
+// consentPlugin holds the opt out and opt in commands letting users opt out of the passive collection of data about them
+type consentPlugin struct {
+	Plugin
+
+	consent *userConsent
+	plugins []*Plugin
+}
+
+// newConsentPlugin creates the plugin of the opt out and opt in commands
+func (s *Slackscot) newConsentPlugin() *consentPlugin {
+	cp := new(consentPlugin)
+	cp.consent = s.userConsent
+	cp.plugins = s.plugins
+
+	cp.Plugin = Plugin{Name: consentPluginName, NormalizeCommands: true, Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.IsDirectMessage() && consentRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "opt out|in",
+		Description: "Opts you out of (or back in) the collection of data about you and deletes what was collected (in a direct message)",
+		Answer:      cp.answerConsent,
+	}}}
+
+	return cp
+}
+
+// answerConsent opts the user out, deleting the data plugins collected about them, or back in
+func (cp *consentPlugin) answerConsent(m *IncomingMessage) *Answer {
+	optOut := strings.ToLower(consentRegex.FindStringSubmatch(m.NormalizedText)[1]) == "out"
+
+	if err := cp.consent.setOptedOut(m.User, optOut); err != nil {
+		cp.Logger.Printf("Error setting the consent of [%s]: %v", m.User, err)
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't opt you %s :disappointed: (%v)", consentVerb(optOut), err)}
+	}
+
+	if !optOut {
+		return &Answer{Text: "You're opted back in, thanks :pray:"}
+	}
+
+	failed := cp.purgeUserData(m.User)
+	if len(failed) > 0 {
+		return &Answer{Text: fmt.Sprintf("You're opted out :shushing_face: but I couldn't delete what `%s` collected about you, please ask an admin for help :disappointed:", strings.Join(failed, "`, `"))}
+	}
+
+	return &Answer{Text: "You're opted out :shushing_face: I won't collect data about you anymore and deleted what I had"}
+}
+
+// purgeUserData deletes the data collected about the user by all plugins and returns the names of the plugins that
+// failed to do so, sorted
+func (cp *consentPlugin) purgeUserData(userID string) (failed []string) {
+	failed = make([]string, 0)
+
+	for _, p := range cp.plugins {
+		if p.PurgeUserData == nil {
+			continue
+		}
+
+		if err := p.PurgeUserData(userID); err != nil {
+			cp.Logger.Printf("Error purging the data of [%s] collected by [%s]: %v", userID, p.Name, err)
+			failed = append(failed, p.Name)
+		}
+	}
+
+	sort.Strings(failed)
+	return failed
+}
+
+// consentVerb returns the direction of a consent change as text
+func consentVerb(optOut bool) string {
+	if optOut {
+		return "out"
+	}
+
+	return "in"
+}
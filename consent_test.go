@@ -0,0 +1,111 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func newHearingPlugin(name string, collectsUserData bool, purged *[]string) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = name
+	p.CollectsUserData = collectsUserData
+	if purged != nil {
+		p.PurgeUserData = func(userID string) error {
+			*purged = append(*purged, userID)
+			return nil
+		}
+	}
+
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "hello")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("%s heard %s", name, m.User)}
+		}}}
+
+	return p
+}
+
+func TestUsersOptingOutOfDataCollection(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	purged := make([]string, 0)
+	plugins := []*Plugin{newHearingPlugin("tracker", true, &purged), newHearingPlugin("echo", false, nil)}
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, v, plugins, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "hello", "Ushy", "1546833200.036900")),
+		newRTMMessageEvent(newMessageEvent("", "opt out", "Ushy", "1546833210.036900", optionDirectMessage(botUserID))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "hello again", "Ushy", "1546833220.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "hello there", "Uchatty", "1546833230.036900")),
+		newRTMMessageEvent(newMessageEvent("", "Opt In", "Ushy", "1546833240.036900", optionDirectMessage(botUserID))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "hello once more", "Ushy", "1546833250.036900")),
+	}, nil, OptionConsentStorer(storer))
+
+	texts := make([]string, 0)
+	for _, m := range sentMsgs {
+		texts = append(texts, applySlackOptions(m.msgOptions...).Get("text"))
+	}
+
+	assert.Equal(t, []string{
+		"tracker heard Ushy",
+		"echo heard Ushy",
+		"You're opted out :shushing_face: I won't collect data about you anymore and deleted what I had",
+		"echo heard Ushy",
+		"tracker heard Uchatty",
+		"echo heard Uchatty",
+		"You're opted back in, thanks :pray:",
+		"tracker heard Ushy",
+		"echo heard Ushy",
+	}, texts)
+	assert.Equal(t, []string{"Ushy"}, purged)
+
+	optedOut, err := storer.ScanSilo(optedOutUsersSilo)
+	require.NoError(t, err)
+	assert.Empty(t, optedOut)
+}
+
+func TestUserConsentPersisted(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	uc, err := newUserConsent(storer)
+	require.NoError(t, err)
+	require.NoError(t, uc.setOptedOut("Ushy", true))
+
+	uc, err = newUserConsent(storer)
+	require.NoError(t, err)
+	assert.True(t, uc.HasOptedOut("Ushy"))
+	assert.False(t, uc.HasOptedOut("Uchatty"))
+
+	// Without a storer, nobody opted out
+	uc, err = newUserConsent(nil)
+	require.NoError(t, err)
+	assert.False(t, uc.HasOptedOut("Ushy"))
+}
+
+func TestPurgeUserDataReportsFailingPlugins(t *testing.T) {
+	failing := func(name string) *Plugin {
+		return &Plugin{Name: name, CollectsUserData: true, PurgeUserData: func(userID string) error {
+			return fmt.Errorf("%s is down", name)
+		}}
+	}
+
+	s, err := New("robert", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	cp := s.newConsentPlugin()
+	cp.plugins = []*Plugin{failing("karma"), newHearingPlugin("echo", false, nil), failing("archiver")}
+	cp.Logger = s.log
+
+	assert.Equal(t, []string{"archiver", "karma"}, cp.purgeUserData("Ushy"))
+}
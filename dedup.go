@@ -0,0 +1,39 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"time"
+)
+
+// eventKey returns the key identifying a message event for deduplication: its channel and timestamp along with, for
+// edits and deletions, the timestamp of the message edited or deleted
+func eventKey(m slack.MessageEvent) string {
+	switch {
+	case m.SubMessage != nil:
+		return fmt.Sprintf("%s/%s:%s/%s", m.Channel, m.Timestamp, m.SubType, m.SubMessage.Timestamp)
+	case m.DeletedTimestamp != "":
+		return fmt.Sprintf("%s/%s:%s/%s", m.Channel, m.Timestamp, m.SubType, m.DeletedTimestamp)
+	default:
+		return fmt.Sprintf("%s/%s", m.Channel, m.Timestamp)
+	}
+}
+
+// isDuplicateEvent returns true if the same message event was already seen within the config.EventDedupWindowKey
+// duration (i.e. replayed by slack after a reconnection) and records it as seen otherwise. Events without a timestamp
+// (like the acknowledgements of sent messages) are never duplicates
+func (s *Slackscot) isDuplicateEvent(m slack.MessageEvent, now time.Time) bool {
+	window := s.config.GetDuration(config.EventDedupWindowKey)
+	if window <= 0 || m.Timestamp == "" {
+		return false
+	}
+
+	key := eventKey(m)
+	if seenAt, seen := s.seenEvents.Get(key); seen && now.Sub(seenAt.(time.Time)) <= window {
+		return true
+	}
+
+	s.seenEvents.Add(key, now)
+	return false
+}
@@ -0,0 +1,73 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMessageEventsReplayedAfterReconnectionAnsweredOnce(t *testing.T) {
+	var resets int32
+	sentMsgs, updatedMsgs, deletedMsgs, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, newResetterPlugin(&resets, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp1)),
+		slack.RTMEvent{Type: "connected_event", Data: &slack.ConnectedEvent{ConnectionCount: 1}},
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp1)),
+		// The same timestamp in another channel is a different message
+		newRTMMessageEvent(newMessageEvent("Crandom", fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp1)),
+	})
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&resets))
+	if assert.Len(t, sentMsgs, 2) {
+		assert.Equal(t, "Cgeneral", sentMsgs[0].channelID)
+		assert.Equal(t, "Crandom", sentMsgs[1].channelID)
+	}
+	assert.Empty(t, updatedMsgs)
+	assert.Empty(t, deletedMsgs)
+	assert.Contains(t, logs, "Reconnected to slack, ignoring message events replayed within [5m0s]")
+}
+
+func TestEditsAndDeletionsOfSeenMessagesNotDuplicates(t *testing.T) {
+	var resets int32
+	sentMsgs, updatedMsgs, deletedMsgs, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newResetterPlugin(&resets, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage(fmt.Sprintf("<@%s> reset!", botUserID), "Alphonse", timestamp1))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage(fmt.Sprintf("<@%s> reset!", botUserID), "Alphonse", timestamp1))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", tenMinutesLaterTimestamp, optionDeletedMessage("Cgeneral", timestamp1))),
+	})
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&resets))
+	assert.Len(t, sentMsgs, 1)
+	assert.Len(t, updatedMsgs, 1)
+	assert.Len(t, deletedMsgs, 1)
+}
+
+func TestIsDuplicateEvent(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	s, err := New("BobbyTables", v)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	m := *newMessageEvent("Cgeneral", "hello", "Alphonse", timestamp1)
+
+	assert.False(t, s.isDuplicateEvent(m, now))
+	assert.True(t, s.isDuplicateEvent(m, now.Add(time.Minute)))
+	assert.False(t, s.isDuplicateEvent(m, now.Add(10*time.Minute)), "events seen outside of the window shouldn't be duplicates")
+	assert.False(t, s.isDuplicateEvent(*newMessageEvent("Cgeneral", "sent", "Alphonse", ""), now))
+	assert.False(t, s.isDuplicateEvent(*newMessageEvent("Cgeneral", "sent", "Alphonse", ""), now))
+}
+
+func TestIsDuplicateEventWithDeduplicationDisabled(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.EventDedupWindowKey, 0)
+	s, err := New("BobbyTables", v)
+	assert.NoError(t, err)
+
+	m := *newMessageEvent("Cgeneral", "hello", "Alphonse", timestamp1)
+
+	assert.False(t, s.isDuplicateEvent(m, time.Now()))
+	assert.False(t, s.isDuplicateEvent(m, time.Now()))
+}
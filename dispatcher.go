@@ -0,0 +1,140 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/slack-go/slack"
+	"sync"
+)
+
+// channelDispatcher processes messages with a pool of concurrent handlers while preserving the order of
+// processing of messages of a same channel. Each channel with messages to process gets its own queue consumed in
+// order so that a slow message only delays the processing of its own channel and never that of other channels (as
+// long as there are available handlers). Queues and their consumers are retired as soon as they have no more messages
+// so that only channels with messages in flight, rather than all channels ever seen, hold resources
+type channelDispatcher struct {
+	// Logger
+	log *sLogger
+
+	// process is the function processing each message
+	process func(msg slack.MessageEvent)
+
+	// handlers is a semaphore bounding the number of messages processed concurrently
+	handlers chan struct{}
+
+	// queueBufferSize is the capacity of each channel queue
+	queueBufferSize int
+
+	// channelQueues holds the queue of messages of each channel with messages in flight, by channel id. Queues and
+	// the closed state are guarded by queuesMutex
+	channelQueues map[string]*channelQueue
+	closed        bool
+	queuesMutex   sync.Mutex
+
+	// workers tracks the running channel queue consumers to wait for their termination on close
+	workers sync.WaitGroup
+
+	*instrumenter
+}
+
+// channelQueue holds the messages of a channel along with the number of those dispatched but not processed yet
+type channelQueue struct {
+	messages chan slack.MessageEvent
+	pending  int
+}
+
+// newChannelDispatcher creates a new channelDispatcher processing at most maxConcurrentHandlers messages at once
+func newChannelDispatcher(maxConcurrentHandlers int, queueBufferSize int, process func(msg slack.MessageEvent), log *sLogger, instrumenter *instrumenter) (cd *channelDispatcher) {
+	cd = new(channelDispatcher)
+	cd.log = log
+	cd.process = process
+	cd.handlers = make(chan struct{}, maxConcurrentHandlers)
+	cd.queueBufferSize = queueBufferSize
+	cd.channelQueues = make(map[string]*channelQueue)
+	cd.instrumenter = instrumenter
+
+	return cd
+}
+
+// dispatch queues a message for processing after all previously dispatched messages of the same channel. Messages
+// dispatched once the dispatcher is closed are rejected with an error
+func (cd *channelDispatcher) dispatch(msgEvent slack.MessageEvent) (err error) {
+	queue, err := cd.acquireChannelQueue(msgEvent.Channel)
+	if err != nil {
+		return fmt.Errorf("message [%s] of channel [%s] not dispatched: %w", msgEvent.Timestamp, msgEvent.Channel, err)
+	}
+
+	cd.log.Debugf("Dispatching message [%s] to the queue of channel [%s]", msgEvent.Timestamp, msgEvent.Channel)
+	d := measure(func() {
+		queue.messages <- msgEvent
+	})
+
+	cd.coreMetrics.msgDispatchLatencyMillis.Record(context.Background(), d.Milliseconds())
+
+	return nil
+}
+
+// acquireChannelQueue returns the queue of a channel with one more pending message, creating it and starting its
+// consumer if the channel has no messages in flight. An error is returned if the dispatcher is closed
+func (cd *channelDispatcher) acquireChannelQueue(channelID string) (queue *channelQueue, err error) {
+	cd.queuesMutex.Lock()
+	defer cd.queuesMutex.Unlock()
+
+	if cd.closed {
+		return nil, fmt.Errorf("dispatcher closed")
+	}
+
+	queue, ok := cd.channelQueues[channelID]
+	if !ok {
+		queue = &channelQueue{messages: make(chan slack.MessageEvent, cd.queueBufferSize)}
+		cd.channelQueues[channelID] = queue
+
+		cd.workers.Add(1)
+		go cd.consume(channelID, queue)
+	}
+
+	queue.pending = queue.pending + 1
+
+	return queue, nil
+}
+
+// consume processes the messages of a channel queue in order, each one once a handler is available, and retires the
+// queue once it has no more pending messages
+func (cd *channelDispatcher) consume(channelID string, queue *channelQueue) {
+	defer cd.workers.Done()
+
+	for msg := range queue.messages {
+		cd.handlers <- struct{}{}
+		cd.process(msg)
+		<-cd.handlers
+
+		if cd.releaseChannelQueue(channelID, queue) {
+			return
+		}
+	}
+}
+
+// releaseChannelQueue marks a message of a channel queue as processed and removes the queue if it has no more pending
+// messages, in which case it returns true. Messages dispatched after that get a new queue
+func (cd *channelDispatcher) releaseChannelQueue(channelID string, queue *channelQueue) (retired bool) {
+	cd.queuesMutex.Lock()
+	defer cd.queuesMutex.Unlock()
+
+	queue.pending = queue.pending - 1
+	if queue.pending > 0 {
+		return false
+	}
+
+	delete(cd.channelQueues, channelID)
+
+	return true
+}
+
+// close stops accepting messages and waits for the processing of the messages already dispatched
+func (cd *channelDispatcher) close() {
+	cd.queuesMutex.Lock()
+	cd.closed = true
+	cd.queuesMutex.Unlock()
+
+	cd.workers.Wait()
+}
@@ -0,0 +1,126 @@
+package slackscot
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/api/metric"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestChannelDispatcher(maxConcurrentHandlers int, process func(msg slack.MessageEvent)) (cd *channelDispatcher) {
+	return newChannelDispatcher(maxConcurrentHandlers, 1, process, &sLogger{logger: log.New(os.Stdout, "", log.LstdFlags)}, newInstrumenter("test", metric.NoopMeter{}))
+}
+
+func newDispatchedMsg(channelID string, timestamp string) (msg slack.MessageEvent) {
+	msg.Channel = channelID
+	msg.Timestamp = timestamp
+
+	return msg
+}
+
+func TestChannelDispatcherPreservesOrderPerChannel(t *testing.T) {
+	var processedMutex sync.Mutex
+	processed := make(map[string][]string)
+
+	cd := newTestChannelDispatcher(4, func(msg slack.MessageEvent) {
+		// Make the first messages slower to give later ones a chance to overtake them if ordering wasn't preserved
+		if msg.Timestamp == "1" {
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		processedMutex.Lock()
+		defer processedMutex.Unlock()
+		processed[msg.Channel] = append(processed[msg.Channel], msg.Timestamp)
+	})
+
+	for _, ts := range []string{"1", "2", "3", "4"} {
+		cd.dispatch(newDispatchedMsg("Cgeneral", ts))
+		cd.dispatch(newDispatchedMsg("Crandom", ts))
+	}
+	cd.close()
+
+	assert.Equal(t, map[string][]string{"Cgeneral": {"1", "2", "3", "4"}, "Crandom": {"1", "2", "3", "4"}}, processed)
+}
+
+func TestChannelDispatcherDoesNotStallOtherChannels(t *testing.T) {
+	release := make(chan bool)
+	processed := make(chan string, 1)
+
+	cd := newTestChannelDispatcher(2, func(msg slack.MessageEvent) {
+		if msg.Channel == "Cslow" {
+			<-release
+		}
+
+		processed <- msg.Channel
+	})
+
+	cd.dispatch(newDispatchedMsg("Cslow", "1"))
+	cd.dispatch(newDispatchedMsg("Cfast", "1"))
+
+	select {
+	case channelID := <-processed:
+		assert.Equal(t, "Cfast", channelID)
+	case <-time.After(time.Second):
+		assert.Fail(t, "processing of Cfast was stalled by Cslow")
+	}
+
+	close(release)
+	assert.Equal(t, "Cslow", <-processed)
+	cd.close()
+}
+
+func TestChannelDispatcherBoundsConcurrentHandlers(t *testing.T) {
+	var countMutex sync.Mutex
+	running := 0
+	maxRunning := 0
+
+	cd := newTestChannelDispatcher(2, func(msg slack.MessageEvent) {
+		countMutex.Lock()
+		running = running + 1
+		if running > maxRunning {
+			maxRunning = running
+		}
+		countMutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		countMutex.Lock()
+		running = running - 1
+		countMutex.Unlock()
+	})
+
+	for _, channelID := range []string{"C1", "C2", "C3", "C4", "C5", "C6"} {
+		cd.dispatch(newDispatchedMsg(channelID, "1"))
+	}
+	cd.close()
+
+	assert.LessOrEqual(t, maxRunning, 2)
+}
+
+func TestChannelDispatcherRetiresIdleChannelQueues(t *testing.T) {
+	cd := newTestChannelDispatcher(2, func(msg slack.MessageEvent) {})
+
+	for _, channelID := range []string{"C1", "C2", "C3", "C1"} {
+		assert.NoError(t, cd.dispatch(newDispatchedMsg(channelID, "1")))
+	}
+	cd.close()
+
+	assert.Empty(t, cd.channelQueues)
+}
+
+func TestChannelDispatcherRejectsMessagesOnceClosed(t *testing.T) {
+	processed := 0
+	cd := newTestChannelDispatcher(2, func(msg slack.MessageEvent) {
+		processed = processed + 1
+	})
+
+	assert.NoError(t, cd.dispatch(newDispatchedMsg("Cgeneral", "1")))
+	cd.close()
+
+	assert.EqualError(t, cd.dispatch(newDispatchedMsg("Cgeneral", "2")), "message [2] of channel [Cgeneral] not dispatched: dispatcher closed")
+	assert.Equal(t, 1, processed)
+}
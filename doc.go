@@ -13,8 +13,11 @@ and can support integrating with various metrics exporter.
 
 Plugins also have access to services injected on startup by slackscot such as:
  - UserInfoFinder: To query user info
+ - UsersInfoFinder: To query the info of many users at once (i.e. for leaderboards mentioning many users)
+ - ChannelInfoFinder: To query channel info (i.e. to show channel names and topics)
  - SLogger: To log debug/info statements
  - EmojiReactor: To emoji react to messages
+ - EmojiFinder: To check that custom emoji exist before using them
  - FileUploader: To upload files
  - RealTimeMessageSender: To send unmanaged real time messages outside the normal reaction flow (i.e. for sending many messages or sending via a scheduled action)
  - SlackClient: For advanced access to all the slack APIs via https://godoc.org/github.com/slack-go/slack#Client
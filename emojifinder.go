@@ -0,0 +1,89 @@
+package slackscot
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// EmojiFinder defines the interface for finding the custom emoji of the workspace (i.e. to check that a custom emoji
+// exists before reacting with it or using it in blocks)
+type EmojiFinder interface {
+	// EmojiExists returns true if a custom emoji (or an alias of an existing emoji) with the given name exists in the
+	// workspace. The name can be given with or without colons (i.e. :partyparrot: or partyparrot). Note that standard
+	// emoji (like thumbsup) aren't listed by slack so they're only found when aliased by a custom emoji
+	EmojiExists(name string) (exists bool, err error)
+}
+
+// emojiLister defines the interface for listing the custom emoji of the workspace with their url. It is satisfied by
+// *slack.Client
+type emojiLister interface {
+	GetEmoji() (emoji map[string]string, err error)
+}
+
+// cachingEmojiFinder implements the EmojiFinder by listing the custom emoji of the workspace at most once every
+// refresh interval (see config.EmojiListRefreshIntervalKey) or on emoji changes
+type cachingEmojiFinder struct {
+	lister          emojiLister
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	sync.Mutex
+	emoji    map[string]string
+	loadedAt time.Time
+}
+
+// newCachingEmojiFinder creates a new cachingEmojiFinder listing emoji with the lister
+func newCachingEmojiFinder(lister emojiLister, refreshInterval time.Duration) (ef *cachingEmojiFinder) {
+	return &cachingEmojiFinder{lister: lister, refreshInterval: refreshInterval, now: time.Now}
+}
+
+// EmojiExists returns true if a custom emoji with the name exists in the workspace. Emoji are listed on the first
+// lookup and again once the cached list is older than the refresh interval
+func (ef *cachingEmojiFinder) EmojiExists(name string) (exists bool, err error) {
+	emoji, err := ef.listEmoji()
+	if err != nil {
+		return false, err
+	}
+
+	_, exists = emoji[strings.Trim(name, ":")]
+
+	return exists, nil
+}
+
+// listEmoji returns the cached custom emoji of the workspace, listing them again if the list is missing or stale
+func (ef *cachingEmojiFinder) listEmoji() (emoji map[string]string, err error) {
+	ef.Lock()
+	defer ef.Unlock()
+
+	if ef.emoji != nil && ef.now().Sub(ef.loadedAt) < ef.refreshInterval {
+		return ef.emoji, nil
+	}
+
+	emoji, err = ef.lister.GetEmoji()
+	if err != nil {
+		return nil, err
+	}
+
+	ef.emoji = emoji
+	ef.loadedAt = ef.now()
+
+	return emoji, nil
+}
+
+// invalidate drops the cached list of emoji so that it's listed again on the next lookup (i.e. after an emoji was
+// added or removed)
+func (ef *cachingEmojiFinder) invalidate() {
+	ef.Lock()
+	defer ef.Unlock()
+
+	ef.emoji = nil
+}
+
+// invalidateEmoji drops the cached list of emoji, if any, when the emoji of the workspace change
+func (s *Slackscot) invalidateEmoji() {
+	if ef, ok := s.emojiFinder.(*cachingEmojiFinder); ok {
+		s.log.Debugf("Emoji changed, dropping the cached list of emoji\n")
+		ef.invalidate()
+	}
+}
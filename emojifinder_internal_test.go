@@ -0,0 +1,89 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// countingEmojiLister lists its emoji, or fails while failing is true, and counts calls
+type countingEmojiLister struct {
+	emoji   map[string]string
+	failing bool
+	calls   int
+}
+
+func (l *countingEmojiLister) GetEmoji() (emoji map[string]string, err error) {
+	l.calls++
+	if l.failing {
+		return nil, fmt.Errorf("slack is down")
+	}
+
+	return l.emoji, nil
+}
+
+func TestEmojiFinderCachesEmojiList(t *testing.T) {
+	lister := &countingEmojiLister{emoji: map[string]string{"partyparrot": "https://emoji.slack-edge.com/partyparrot.gif", "fiesta": "alias:partyparrot"}}
+	ef := newCachingEmojiFinder(lister, time.Hour)
+	now := time.Date(2019, 1, 7, 10, 0, 0, 0, time.UTC)
+	ef.now = func() time.Time { return now }
+
+	for name, expected := range map[string]bool{"partyparrot": true, ":partyparrot:": true, "fiesta": true, "sadparrot": false, "thumbsup": false} {
+		exists, err := ef.EmojiExists(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, exists, name)
+	}
+	assert.Equal(t, 1, lister.calls)
+
+	lister.emoji["sadparrot"] = "https://emoji.slack-edge.com/sadparrot.gif"
+	now = now.Add(time.Hour)
+
+	exists, err := ef.EmojiExists("sadparrot")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 2, lister.calls)
+}
+
+func TestEmojiFinderListingError(t *testing.T) {
+	lister := &countingEmojiLister{failing: true}
+	ef := newCachingEmojiFinder(lister, time.Hour)
+
+	_, err := ef.EmojiExists("partyparrot")
+	assert.EqualError(t, err, "slack is down")
+
+	// Failures aren't cached
+	lister.failing = false
+	lister.emoji = map[string]string{"partyparrot": "https://emoji.slack-edge.com/partyparrot.gif"}
+
+	exists, err := ef.EmojiExists("partyparrot")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 2, lister.calls)
+}
+
+func TestInjectEmojiFinderRefreshedOnEmojiChange(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	karma := &Plugin{Name: "karma"}
+	s.RegisterPlugin(karma)
+
+	lister := &countingEmojiLister{emoji: map[string]string{}}
+	s.emojiLister = lister
+	require.NoError(t, s.injectServicesToPlugins(&flakyUserInfoFinder{}, s.log, nil, nil, nil, nil))
+
+	exists, err := karma.EmojiFinder.EmojiExists("partyparrot")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	lister.emoji["partyparrot"] = "https://emoji.slack-edge.com/partyparrot.gif"
+	s.invalidateEmoji()
+
+	exists, err = karma.EmojiFinder.EmojiExists("partyparrot")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 2, lister.calls)
+}
@@ -0,0 +1,112 @@
+package slackscot
+
+import (
+	"sync"
+)
+
+// Event is implemented by any value published on the EventBus. The topic identifies the kind of event
+// and is what subscribers register for. By convention, topics are prefixed with the name of the publishing
+// plugin (i.e. karma.changed)
+type Event interface {
+	Topic() string
+}
+
+// EventHandler handles an event published on a subscribed topic
+type EventHandler func(e Event)
+
+// EventSubscription represents a plugin's subscription to a topic on the EventBus. Slackscot registers
+// all subscriptions of plugins when starting
+type EventSubscription struct {
+	// Topic of the events to receive
+	Topic string
+
+	// Handle is the function invoked for every event published on the topic
+	Handle EventHandler
+}
+
+// EventBus is an in-process publish/subscribe bus for plugins to communicate with each other without being coupled.
+// Event delivery is synchronous: Publish returns once all handlers have been invoked. Handlers doing slow work
+// (i.e. calling external services) should therefore do it in their own go routine
+type EventBus interface {
+	// Publish delivers the event to all subscribers of its topic
+	Publish(e Event)
+
+	// Subscribe registers a handler to receive all events published on a topic
+	Subscribe(topic string, handler EventHandler)
+}
+
+// eventBus is the EventBus implementation injected in plugins
+type eventBus struct {
+	sync.RWMutex
+	handlers map[string][]EventHandler
+	logger   SLogger
+}
+
+// newEventBus creates a new eventBus. The logger is used to report panicking handlers
+func newEventBus(logger SLogger) (eb *eventBus) {
+	eb = new(eventBus)
+	eb.handlers = make(map[string][]EventHandler)
+	eb.logger = logger
+
+	return eb
+}
+
+// Publish delivers the event to all subscribers of its topic. A panicking handler is logged and
+// doesn't prevent delivery to the other handlers
+func (eb *eventBus) Publish(e Event) {
+	eb.RLock()
+	handlers := eb.handlers[e.Topic()]
+	eb.RUnlock()
+
+	for _, h := range handlers {
+		eb.deliver(h, e)
+	}
+}
+
+// deliver invokes the handler with the event and recovers from any panic
+func (eb *eventBus) deliver(h EventHandler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			eb.logger.Printf("Event handler for topic [%s] panicked: %v", e.Topic(), r)
+		}
+	}()
+
+	h(e)
+}
+
+// Subscribe registers a handler to receive all events published on a topic
+func (eb *eventBus) Subscribe(topic string, handler EventHandler) {
+	eb.Lock()
+	defer eb.Unlock()
+
+	// Copy on write so that publishing never iterates over a slice being appended to
+	handlers := make([]EventHandler, len(eb.handlers[topic]), len(eb.handlers[topic])+1)
+	copy(handlers, eb.handlers[topic])
+	eb.handlers[topic] = append(handlers, handler)
+}
+
+const (
+	// PluginAnsweredTopic is the topic of the PluginAnswered events published by slackscot
+	PluginAnsweredTopic = "slackscot.pluginAnswered"
+)
+
+// PluginAnswered is published on the EventBus every time a plugin's commands or hear actions answer a message. This
+// gives plugins (i.e. analytics) visibility on the usage of other plugins
+type PluginAnswered struct {
+	// Plugin is the name of the plugin that answered
+	Plugin string
+
+	// ActionType is the type of the actions that answered (command, hearAction, reactionAction or channelAction)
+	ActionType string
+
+	// Channel is the id of the channel of the answered message
+	Channel string
+
+	// Answers is the number of answers
+	Answers int
+}
+
+// Topic returns the PluginAnsweredTopic
+func (e PluginAnswered) Topic() string {
+	return PluginAnsweredTopic
+}
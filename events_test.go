@@ -0,0 +1,129 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"strings"
+	"testing"
+)
+
+type thingHappened struct {
+	thing string
+}
+
+func (e thingHappened) Topic() string {
+	return "thing.happened"
+}
+
+type otherThingHappened struct{}
+
+func (e otherThingHappened) Topic() string {
+	return "otherthing.happened"
+}
+
+func TestEventsDeliveredToSubscribersOfTopic(t *testing.T) {
+	eb := newEventBus(NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+
+	received := make([]string, 0)
+	eb.Subscribe("thing.happened", func(e Event) {
+		received = append(received, "first:"+e.(thingHappened).thing)
+	})
+	eb.Subscribe("thing.happened", func(e Event) {
+		received = append(received, "second:"+e.(thingHappened).thing)
+	})
+	eb.Subscribe("otherthing.happened", func(e Event) {
+		received = append(received, "other")
+	})
+
+	eb.Publish(thingHappened{thing: "coffee"})
+
+	assert.Equal(t, []string{"first:coffee", "second:coffee"}, received)
+}
+
+func TestPublishWithoutSubscribers(t *testing.T) {
+	eb := newEventBus(NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+
+	assert.NotPanics(t, func() {
+		eb.Publish(otherThingHappened{})
+	})
+}
+
+func TestPanickingEventHandlerDoesNotPreventDelivery(t *testing.T) {
+	var logBuilder strings.Builder
+	eb := newEventBus(NewSLogger(log.New(&logBuilder, "", 0), false))
+
+	received := make([]string, 0)
+	eb.Subscribe("thing.happened", func(e Event) {
+		panic("boom")
+	})
+	eb.Subscribe("thing.happened", func(e Event) {
+		received = append(received, e.(thingHappened).thing)
+	})
+
+	eb.Publish(thingHappened{thing: "coffee"})
+
+	assert.Equal(t, []string{"coffee"}, received)
+	assert.Contains(t, logBuilder.String(), "Event handler for topic [thing.happened] panicked: boom")
+}
+
+func TestEventsPublishedBetweenPlugins(t *testing.T) {
+	received := make([]string, 0)
+
+	subscriber := &Plugin{Name: "celebration", EventSubscriptions: []EventSubscription{{Topic: "thing.happened", Handle: func(e Event) {
+		received = append(received, e.(thingHappened).thing)
+	}}}}
+
+	publisher := &Plugin{Name: "maker"}
+	publisher.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "make")
+		},
+		Usage:       "make `<thing>`",
+		Description: "Makes a thing",
+		Answer: func(m *IncomingMessage) *Answer {
+			thing := strings.TrimSpace(strings.TrimPrefix(m.NormalizedText, "make"))
+			publisher.EventBus.Publish(thingHappened{thing: thing})
+			return &Answer{Text: fmt.Sprintf("Made %s", thing)}
+		},
+	}}
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, nil, []*Plugin{subscriber, publisher}, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make coffee", "Alphonse", timestamp1)),
+	}, nil)
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Made coffee", vals.Get("text"))
+	}
+
+	assert.Equal(t, []string{"coffee"}, received)
+}
+
+func TestPluginAnsweredPublished(t *testing.T) {
+	received := make([]PluginAnswered, 0)
+
+	subscriber := &Plugin{Name: "analytics", EventSubscriptions: []EventSubscription{{Topic: PluginAnsweredTopic, Handle: func(e Event) {
+		received = append(received, e.(PluginAnswered))
+	}}}}
+
+	maker := &Plugin{Name: "maker"}
+	maker.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "make")
+		},
+		Usage:       "make `<thing>`",
+		Description: "Makes a thing",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Made it"}
+		},
+	}}
+
+	runSlackscotWithPluginsAndIncomingEvents(t, nil, []*Plugin{subscriber, maker}, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make coffee", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "drink coffee", "Alphonse", timestamp2)),
+	}, nil)
+
+	assert.Equal(t, []PluginAnswered{{Plugin: "maker", ActionType: "command", Channel: "DFromAlphonse", Answers: 1}}, received)
+}
@@ -0,0 +1,233 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/spf13/viper"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Core feature flags
+const (
+	// FeatureThreadedReplies makes slackscot reply in threads in the channels where it's enabled. Unlike
+	// config.ThreadedRepliesKey which applies everywhere, this allows rolling out threaded replies channel by channel
+	FeatureThreadedReplies = "threadedReplies"
+)
+
+const (
+	featureFlagsPluginName = "feature"
+	featureFlagsSilo       = "featureFlags"
+	allChannelsScope       = "*"
+)
+
+var featureOverrideRegex = regexp.MustCompile("(?i)\\Afeature (enable|disable|reset) ([\\w.-]+)(?: (?:(here)|<#(\\w+)(?:\\|[^>]*)?>))?\\z")
+var featureStatusRegex = regexp.MustCompile("(?i)\\Afeature status ([\\w.-]+)\\z")
+
+// FeatureFlags tells whether features are enabled in channels. Flags are defined in the configuration (see
+// config.FeaturesKey) and can be overridden at runtime by admins (see config.FeatureAdminIDsKey) with the feature
+// command when slackscot is given a storer with OptionFeatureFlagStorer. Overrides for a channel take precedence
+// over overrides for all channels which take precedence over the configuration.
+//
+// Here's an example of configuration enabling threaded replies in two channels:
+//
+//	"features": {
+//	  "threadedReplies": {
+//	    "enabled": false,
+//	    "channelIDs": ["C01234", "C56789"]
+//	  }
+//	}
+//
+// Plugins can gate their own behaviors with flags using the FeatureFlags injected in them
+type FeatureFlags interface {
+	// IsEnabled returns true if the feature is enabled in the channel
+	IsEnabled(feature string, channelID string) (enabled bool)
+}
+
+// featureFlags is the FeatureFlags implementation. Overrides are kept in memory and written through to the storer
+type featureFlags struct {
+	config *viper.Viper
+	storer store.GlobalSiloStringStorer
+
+	sync.RWMutex
+	overrides map[string]bool
+}
+
+// OptionFeatureFlagStorer sets the storer persisting runtime overrides of feature flags. Setting it also
+// adds the feature command for admins to enable or disable features
+func OptionFeatureFlagStorer(storer store.GlobalSiloStringStorer) Option {
+	return func(s *Slackscot) {
+		s.featureFlagStorer = storer
+	}
+}
+
+// newFeatureFlags creates the feature flags defined in the configuration with the overrides persisted in the storer, if any
+func newFeatureFlags(v *viper.Viper, storer store.GlobalSiloStringStorer) (ff *featureFlags, err error) {
+	ff = new(featureFlags)
+	ff.config = v
+	ff.storer = storer
+	ff.overrides = make(map[string]bool)
+
+	if storer == nil {
+		return ff, nil
+	}
+
+	entries, err := storer.ScanSilo(featureFlagsSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, rawValue := range entries {
+		if ff.overrides[key], err = strconv.ParseBool(rawValue); err != nil {
+			return nil, fmt.Errorf("Invalid override [%s] for feature flag [%s]: %v", rawValue, key, err)
+		}
+	}
+
+	return ff, nil
+}
+
+// overrideKey returns the key of a feature's override for a scope (a channel id or allChannelsScope)
+func overrideKey(feature string, scope string) string {
+	return fmt.Sprintf("%s:%s", feature, scope)
+}
+
+// IsEnabled returns true if the feature is enabled in the channel
+func (ff *featureFlags) IsEnabled(feature string, channelID string) (enabled bool) {
+	ff.RLock()
+	defer ff.RUnlock()
+
+	if enabled, exists := ff.overrides[overrideKey(feature, channelID)]; exists {
+		return enabled
+	}
+
+	if enabled, exists := ff.overrides[overrideKey(feature, allChannelsScope)]; exists {
+		return enabled
+	}
+
+	featureKey := fmt.Sprintf("%s.%s", config.FeaturesKey, feature)
+	for _, c := range ff.config.GetStringSlice(featureKey + ".channelIDs") {
+		if c == channelID {
+			return true
+		}
+	}
+
+	return ff.config.GetBool(featureKey + ".enabled")
+}
+
+// override persists an override of a feature for a scope
+func (ff *featureFlags) override(feature string, scope string, enabled bool) (err error) {
+	ff.Lock()
+	defer ff.Unlock()
+
+	key := overrideKey(feature, scope)
+	if err = ff.storer.PutSiloString(featureFlagsSilo, key, strconv.FormatBool(enabled)); err != nil {
+		return err
+	}
+
+	ff.overrides[key] = enabled
+	return nil
+}
+
+// reset deletes the override of a feature for a scope
+func (ff *featureFlags) reset(feature string, scope string) (err error) {
+	ff.Lock()
+	defer ff.Unlock()
+
+	key := overrideKey(feature, scope)
+	if err = ff.storer.DeleteSiloString(featureFlagsSilo, key); err != nil {
+		return err
+	}
+
+	delete(ff.overrides, key)
+	return nil
+}
+
+// featureFlagsPlugin holds the feature command letting admins override feature flags at runtime
+type featureFlagsPlugin struct {
+	Plugin
+
+	flags  *featureFlags
+	admins map[string]bool
+}
+
+// newFeatureFlagsPlugin creates the plugin of the feature command
+func (s *Slackscot) newFeatureFlagsPlugin() *featureFlagsPlugin {
+	fp := new(featureFlagsPlugin)
+	fp.flags = s.featureFlags
+	fp.admins = make(map[string]bool)
+	for _, userID := range s.config.GetStringSlice(config.FeatureAdminIDsKey) {
+		fp.admins[userID] = true
+	}
+
+	fp.Plugin = Plugin{Name: featureFlagsPluginName, NormalizeCommands: true, Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return featureOverrideRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "feature enable|disable|reset `<name>` [here|`<#channel>`]",
+		Description: "Overrides a feature flag in a channel or, by default, in all channels (admins only)",
+		Answer:      fp.overrideFeature,
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return featureStatusRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "feature status `<name>`",
+		Description: "Reports whether a feature is enabled in the channel",
+		Answer:      fp.reportFeature,
+	}}}
+
+	return fp
+}
+
+// overrideFeature enables, disables or resets a feature flag
+func (fp *featureFlagsPlugin) overrideFeature(m *IncomingMessage) *Answer {
+	if !fp.admins[m.User] {
+		return &Answer{Text: "Sorry, only feature admins can override feature flags :no_entry_sign:", Options: []AnswerOption{AnswerEphemeral(m.User)}}
+	}
+
+	matches := featureOverrideRegex.FindStringSubmatch(m.NormalizedText)
+	verb, feature := strings.ToLower(matches[1]), matches[2]
+
+	scope, where := allChannelsScope, "in all channels"
+	if matches[3] != "" {
+		scope, where = m.Channel, fmt.Sprintf("in <#%s>", m.Channel)
+	} else if matches[4] != "" {
+		scope, where = matches[4], fmt.Sprintf("in <#%s>", matches[4])
+	}
+
+	var err error
+	if verb == "reset" {
+		err = fp.flags.reset(feature, scope)
+	} else {
+		err = fp.flags.override(feature, scope, verb == "enable")
+	}
+
+	if err != nil {
+		fp.Logger.Printf("Error overriding feature flag [%s] for [%s]: %v", feature, scope, err)
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't %s `%s` :disappointed: (%v)", verb, feature, err)}
+	}
+
+	if verb == "reset" {
+		return &Answer{Text: fmt.Sprintf("`%s` is back to its configured state %s :leftwards_arrow_with_hook:", feature, where)}
+	}
+
+	return &Answer{Text: fmt.Sprintf("`%s` is %sd %s :triangular_flag_on_post:", feature, verb, where)}
+}
+
+// reportFeature reports whether a feature is enabled in the channel of the message
+func (fp *featureFlagsPlugin) reportFeature(m *IncomingMessage) *Answer {
+	feature := featureStatusRegex.FindStringSubmatch(m.NormalizedText)[1]
+
+	return &Answer{Text: fmt.Sprintf("`%s` is %s in <#%s>", feature, enabledOrDisabled(fp.flags.IsEnabled(feature, m.Channel)), m.Channel)}
+}
+
+// enabledOrDisabled returns the state of a flag as text
+func enabledOrDisabled(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+
+	return "disabled"
+}
@@ -0,0 +1,104 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func newFeatureFlagStorer(t *testing.T) (storer *store.LevelDB, cleanup func()) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+
+	storer, err = store.NewLevelDB("featureFlagsTest", tmpdir)
+	require.NoError(t, err)
+
+	return storer, func() {
+		storer.Close()
+		os.RemoveAll(tmpdir)
+	}
+}
+
+func TestFeatureFlagsFromConfig(t *testing.T) {
+	v := viper.New()
+	v.Set("features.digest.enabled", true)
+	v.Set("features.threadedReplies.channelIDs", []string{"Cbeta"})
+
+	ff, err := newFeatureFlags(v, nil)
+	require.NoError(t, err)
+
+	assert.True(t, ff.IsEnabled("digest", "Cgeneral"))
+	assert.True(t, ff.IsEnabled(FeatureThreadedReplies, "Cbeta"))
+	assert.False(t, ff.IsEnabled(FeatureThreadedReplies, "Cgeneral"))
+	assert.False(t, ff.IsEnabled("unknown", "Cgeneral"))
+}
+
+func TestFeatureFlagsOverridesPrecedence(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := viper.New()
+	v.Set("features.threadedReplies.channelIDs", []string{"Cbeta"})
+
+	ff, err := newFeatureFlags(v, storer)
+	require.NoError(t, err)
+
+	require.NoError(t, ff.override(FeatureThreadedReplies, allChannelsScope, true))
+	require.NoError(t, ff.override(FeatureThreadedReplies, "Cbeta", false))
+	assert.True(t, ff.IsEnabled(FeatureThreadedReplies, "Cgeneral"))
+	assert.False(t, ff.IsEnabled(FeatureThreadedReplies, "Cbeta"))
+
+	// Overrides are persisted
+	ff, err = newFeatureFlags(v, storer)
+	require.NoError(t, err)
+	assert.True(t, ff.IsEnabled(FeatureThreadedReplies, "Cgeneral"))
+	assert.False(t, ff.IsEnabled(FeatureThreadedReplies, "Cbeta"))
+
+	require.NoError(t, ff.reset(FeatureThreadedReplies, allChannelsScope))
+	require.NoError(t, ff.reset(FeatureThreadedReplies, "Cbeta"))
+	assert.False(t, ff.IsEnabled(FeatureThreadedReplies, "Cgeneral"))
+	assert.True(t, ff.IsEnabled(FeatureThreadedReplies, "Cbeta"))
+}
+
+func TestThreadedRepliesFeatureEnabledByAdmin(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.FeatureAdminIDsKey, []string{"Alphonse"})
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "feature enable threadedReplies here", "Ignored", "1546833200.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "feature enable threadedReplies here", "Alphonse", timestamp2, optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Crandom", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Crandom", "feature status threadedReplies", "Alphonse", "1546833220.036900", optionPublicMessageToBot(botUserID, "Crandom"))),
+	}, nil, OptionFeatureFlagStorer(storer))
+
+	if assert.Equal(t, 5, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Ignored>: Sorry, only feature admins can override feature flags :no_entry_sign:", vals.Get("text"))
+		assert.Equal(t, "Ignored", vals.Get("user"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `threadedReplies` is enabled in <#Cgeneral> :triangular_flag_on_post:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[2].msgOptions...)
+		assert.Equal(t, "I heard you say something about blue jays?", vals.Get("text"))
+		assert.Equal(t, timestamp1, vals.Get("thread_ts"))
+
+		vals = applySlackOptions(sentMsgs[3].msgOptions...)
+		assert.Equal(t, "I heard you say something about blue jays?", vals.Get("text"))
+		assert.Equal(t, "", vals.Get("thread_ts"))
+
+		vals = applySlackOptions(sentMsgs[4].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `threadedReplies` is disabled in <#Crandom>", vals.Get("text"))
+	}
+}
@@ -4,8 +4,10 @@ require (
 	cloud.google.com/go v0.38.0
 	github.com/alexandre-normand/figlet4go v1.0.0
 	github.com/fatih/color v1.7.0 // indirect
+	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db
 	github.com/gorilla/websocket v1.4.0
 	github.com/hashicorp/golang-lru v0.5.1
+	github.com/klauspost/compress v1.10.10
 	github.com/magiconair/properties v1.8.1 // indirect
 	github.com/marcsantiago/gocron v0.0.0-20181105173523-9617b75671b1
 	github.com/mattn/go-colorable v0.1.4 // indirect
@@ -27,7 +29,9 @@ require (
 	go.opentelemetry.io/otel v0.2.3
 	golang.org/x/net v0.0.0-20190923162816-aa69164e4478 // indirect
 	golang.org/x/sys v0.0.0-20191210023423-ac6580df4449 // indirect
+	golang.org/x/text v0.3.2
 	google.golang.org/api v0.20.0
+	gopkg.in/yaml.v2 v2.2.7
 )
 
 go 1.13
@@ -5,6 +5,7 @@ import (
 	"github.com/alexandre-normand/slackscot/config"
 	"io"
 	"strings"
+	"time"
 )
 
 type helpPlugin struct {
@@ -16,6 +17,10 @@ type helpPlugin struct {
 	commands               map[string][]ActionDefinition
 	hearActions            []ActionDefinition
 	pluginScheduledActions []pluginScheduledAction
+	scheduledActions       *scheduledActionRegistry
+	reactionActions        []ReactionActionDefinition
+	channelActions         []ChannelActionDefinition
+	slashCommands          []SlashCommandDefinition
 	cmdPrefix              string
 }
 
@@ -23,9 +28,10 @@ const (
 	helpPluginName = "help"
 )
 
-// pluginScheduledAction represents a plugin's scheduled action with the plugin name and the action's definition
+// pluginScheduledAction represents a plugin's scheduled action with the plugin name, the action's id and definition
 type pluginScheduledAction struct {
 	plugin string
+	id     string
 	ScheduledActionDefinition
 }
 
@@ -39,9 +45,13 @@ func (s *Slackscot) newHelpPlugin(version string) *helpPlugin {
 	helpPlugin.commands = commands
 	helpPlugin.hearActions = hearActions
 	helpPlugin.pluginScheduledActions = scheduledActions
+	helpPlugin.scheduledActions = s.scheduledActions
+	helpPlugin.reactionActions = findAllReactionActions(s.plugins)
+	helpPlugin.channelActions = findAllChannelActions(s.plugins)
+	helpPlugin.slashCommands = findAllSlashCommands(s.plugins)
 	helpPlugin.cmdPrefix = s.cmdMatcher.UsagePrefix()
 
-	helpPlugin.Plugin = Plugin{Name: helpPluginName, Commands: []ActionDefinition{{
+	helpPlugin.Plugin = Plugin{Name: helpPluginName, NormalizeCommands: true, Commands: []ActionDefinition{{
 		Match: func(m *IncomingMessage) bool {
 			return strings.HasPrefix(m.NormalizedText, "help")
 		},
@@ -53,8 +63,8 @@ func (s *Slackscot) newHelpPlugin(version string) *helpPlugin {
 	return helpPlugin
 }
 
-// showHelp generates a message providing a list of all of the slackscot commands and hear actions.
-// Note that ActionDefinitions with the flag Hidden set to true won't be included in the list
+// showHelp generates a message providing a list of all of the slackscot commands, hear actions, scheduled actions,
+// reaction actions, channel actions and slash commands. Note that definitions with the flag Hidden set to true won't be included in the list
 func (h *helpPlugin) showHelp(m *IncomingMessage) *Answer {
 	var b strings.Builder
 
@@ -86,7 +96,25 @@ func (h *helpPlugin) showHelp(m *IncomingMessage) *Answer {
 	if len(h.pluginScheduledActions) > 0 {
 		fmt.Fprintf(&b, "\nAnd do those things periodically:\n")
 
-		appendScheduledActions(&b, h.timeLocation, h.pluginScheduledActions)
+		appendScheduledActions(&b, h.timeLocation, h.pluginScheduledActions, h.scheduledActions)
+	}
+
+	if len(h.reactionActions) > 0 {
+		fmt.Fprintf(&b, "\nAnd react to the following reactions:\n")
+
+		appendReactionActions(&b, h.reactionActions)
+	}
+
+	if len(h.channelActions) > 0 {
+		fmt.Fprintf(&b, "\nAnd act on the following channel events:\n")
+
+		appendChannelActions(&b, h.channelActions)
+	}
+
+	if len(h.slashCommands) > 0 {
+		fmt.Fprintf(&b, "\nAnd respond to the following slash commands:\n")
+
+		appendSlashCommands(&b, h.slashCommands)
 	}
 
 	return &Answer{Text: b.String(), Options: []AnswerOption{AnswerInThread()}}
@@ -107,18 +135,76 @@ func appendActions(w io.Writer, prefix string, pluginNamespace string, actions [
 	for _, value := range actions {
 		if value.Usage != "" && !value.Hidden {
 			if len(pluginNamespace) > 0 {
-				fmt.Fprintf(w, "\t• `%s%s %s` - %s\n", prefix, pluginNamespace, value.Usage, value.Description)
+				fmt.Fprintf(w, "\t• `%s%s %s` - %s\n", prefix, pluginNamespace, value.Usage, actionHelpDescription(value))
 			} else {
-				fmt.Fprintf(w, "\t• `%s%s` - %s\n", prefix, value.Usage, value.Description)
+				fmt.Fprintf(w, "\t• `%s%s` - %s\n", prefix, value.Usage, actionHelpDescription(value))
 			}
 		}
 	}
 }
 
-func appendScheduledActions(w io.Writer, timeLocationName string, scheduledActions []pluginScheduledAction) {
+// actionHelpDescription returns the description of an action as shown in the help, annotated with a warning for
+// actions with side effects and a lock for admin-only actions
+func actionHelpDescription(action ActionDefinition) (description string) {
+	description = action.Description
+	if action.SideEffects {
+		description = fmt.Sprintf("%s :warning: _has side effects, edits don't run it again_", description)
+	}
+
+	if action.AdminOnly {
+		description = fmt.Sprintf("%s :lock: _admins only_", description)
+	}
+
+	return description
+}
+
+// appendScheduledActions appends the scheduled actions with their schedule, time zone and, when scheduled (see
+// scheduledActionRegistry), their next run
+func appendScheduledActions(w io.Writer, timeLocationName string, scheduledActions []pluginScheduledAction, registry *scheduledActionRegistry) {
 	for _, value := range scheduledActions {
 		if !value.ScheduledActionDefinition.Hidden {
-			fmt.Fprintf(w, "\t• [`%s`] `%s` (`%s`) - %s\n", value.plugin, value.ScheduledActionDefinition.Schedule, timeLocationName, value.ScheduledActionDefinition.Description)
+			timezone := timeLocationName
+			if value.Schedule.Timezone != "" {
+				timezone = value.Schedule.Timezone
+			}
+
+			fmt.Fprintf(w, "\t• [`%s`] `%s` (`%s`) - %s", value.plugin, value.ScheduledActionDefinition.Schedule, timezone, value.ScheduledActionDefinition.Description)
+
+			if registry != nil {
+				if e, ok := registry.find(value.id); ok {
+					if next, scheduled := registry.nextRun(e); scheduled {
+						fmt.Fprintf(w, " (next run at %s)", next.Format(time.RFC1123))
+					}
+				}
+			}
+
+			fmt.Fprintf(w, "\n")
+		}
+	}
+}
+
+func appendReactionActions(w io.Writer, reactionActions []ReactionActionDefinition) {
+	for _, value := range reactionActions {
+		if value.Usage != "" {
+			fmt.Fprintf(w, "\t• `%s` - %s\n", value.Usage, value.Description)
+		}
+	}
+}
+
+func appendChannelActions(w io.Writer, channelActions []ChannelActionDefinition) {
+	for _, value := range channelActions {
+		if value.Usage != "" {
+			fmt.Fprintf(w, "\t• `%s` - %s\n", value.Usage, value.Description)
+		}
+	}
+}
+
+func appendSlashCommands(w io.Writer, slashCommands []SlashCommandDefinition) {
+	for _, value := range slashCommands {
+		if value.Usage != "" {
+			fmt.Fprintf(w, "\t• `%s %s` - %s\n", value.Command, value.Usage, value.Description)
+		} else {
+			fmt.Fprintf(w, "\t• `%s` - %s\n", value.Command, value.Description)
 		}
 	}
 }
@@ -160,11 +246,56 @@ func filterNonHiddenActions(actions []ActionDefinition) (visibleActions []Action
 func filterNonHiddenScheduledActions(pluginName string, actions []ScheduledActionDefinition) (visibleActions []pluginScheduledAction) {
 	visibleActions = make([]pluginScheduledAction, 0)
 
-	for _, sa := range actions {
+	for i, sa := range actions {
 		if !sa.Hidden {
-			visibleActions = append(visibleActions, pluginScheduledAction{plugin: pluginName, ScheduledActionDefinition: sa})
+			visibleActions = append(visibleActions, pluginScheduledAction{plugin: pluginName, id: getActionID(pluginName, scheduledActionType, i), ScheduledActionDefinition: sa})
 		}
 	}
 
 	return visibleActions
 }
+
+// findAllReactionActions returns the reaction actions of all plugins that aren't hidden
+func findAllReactionActions(plugins []*Plugin) (reactionActions []ReactionActionDefinition) {
+	reactionActions = make([]ReactionActionDefinition, 0)
+
+	for _, p := range plugins {
+		for _, ra := range p.ReactionActions {
+			if !ra.Hidden {
+				reactionActions = append(reactionActions, ra)
+			}
+		}
+	}
+
+	return reactionActions
+}
+
+// findAllChannelActions returns the channel actions of all plugins that aren't hidden
+func findAllChannelActions(plugins []*Plugin) (channelActions []ChannelActionDefinition) {
+	channelActions = make([]ChannelActionDefinition, 0)
+
+	for _, p := range plugins {
+		for _, ca := range p.ChannelActions {
+			if !ca.Hidden {
+				channelActions = append(channelActions, ca)
+			}
+		}
+	}
+
+	return channelActions
+}
+
+// findAllSlashCommands returns the slash commands of all plugins that aren't hidden
+func findAllSlashCommands(plugins []*Plugin) (slashCommands []SlashCommandDefinition) {
+	slashCommands = make([]SlashCommandDefinition, 0)
+
+	for _, p := range plugins {
+		for _, sc := range p.SlashCommands {
+			if !sc.Hidden {
+				slashCommands = append(slashCommands, sc)
+			}
+		}
+	}
+
+	return slashCommands
+}
@@ -114,6 +114,41 @@ func TestHelpWithNamespacingDisabled(t *testing.T) {
 		"\t• [`thank`] `Every 30 seconds` (`Local`) - Sends a heartbeat every 30 seconds\n", a.Text)
 }
 
+func TestHelpWithActionWithSideEffects(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults(), OptionNoPluginNamespacing())
+	require.NoError(t, err)
+
+	p := newPluginWithActionsOfAllTypes(false)
+	p.Commands[0].SideEffects = true
+	s.RegisterPlugin(p)
+
+	help := s.newHelpPlugin("1.0.0")
+	help.UserInfoFinder = &userInfoFinder{}
+
+	a := help.Commands[0].Answer(&IncomingMessage{NormalizedText: "help"})
+	require.NotNil(t, a)
+
+	assert.Contains(t, a.Text, "\t• `<someone of something to thank>` - Format a thank you note :warning: _has side effects, edits don't run it again_\n")
+	assert.Contains(t, a.Text, "\t• `say `chickadee` and hear a chirp` - Chirp when hearing people talk about chickadees\n")
+}
+
+func TestHelpWithAdminOnlyAction(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults(), OptionNoPluginNamespacing())
+	require.NoError(t, err)
+
+	p := newPluginWithActionsOfAllTypes(false)
+	p.Commands[0].AdminOnly = true
+	s.RegisterPlugin(p)
+
+	help := s.newHelpPlugin("1.0.0")
+	help.UserInfoFinder = &userInfoFinder{}
+
+	a := help.Commands[0].Answer(&IncomingMessage{NormalizedText: "help"})
+	require.NotNil(t, a)
+
+	assert.Contains(t, a.Text, "\t• `<someone of something to thank>` - Format a thank you note :lock: _admins only_\n")
+}
+
 func TestHelpWithHiddenActions(t *testing.T) {
 	s, err := New("robert", config.NewViperWithDefaults(), OptionNoPluginNamespacing())
 	s.RegisterPlugin(newPluginWithActionsOfAllTypes(true))
@@ -218,3 +253,61 @@ func TestHelpWithNamespacingDisabledWithCommandOptionPrefix(t *testing.T) {
 		"\t• `say `chickadee` and hear a chirp` - Chirp when hearing people talk about chickadees\n\nAnd do those things periodically:\n"+
 		"\t• [`thank`] `Every 30 seconds` (`Local`) - Sends a heartbeat every 30 seconds\n", a.Text)
 }
+
+func TestHelpWithSlashCommands(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults(), OptionNoPluginNamespacing())
+	require.NoError(t, err)
+
+	s.RegisterPlugin(&Plugin{Name: "weather", SlashCommands: []SlashCommandDefinition{
+		{Command: "/weather", Usage: "<city>", Description: "Show the weather forecast"},
+		{Command: "/sunny", Description: "Check if it's sunny"},
+		{Hidden: true, Command: "/rain", Description: "Make it rain"},
+	}})
+
+	help := s.newHelpPlugin("1.0.0")
+	help.UserInfoFinder = &userInfoFinder{}
+
+	a := help.Commands[0].Answer(&IncomingMessage{NormalizedText: "help"})
+	require.NotNil(t, a)
+
+	assert.Equal(t, "🤝 Hi, `Daniel Quinn`! I'm `robert` (engine `v1.0.0`) and I listen to the team's chat and provides automated functions :genie:.\n\n"+
+		"And respond to the following slash commands:\n\t• `/weather <city>` - Show the weather forecast\n\t• `/sunny` - Check if it's sunny\n", a.Text)
+}
+
+func TestHelpWithReactionActions(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults(), OptionNoPluginNamespacing())
+	require.NoError(t, err)
+
+	s.RegisterPlugin(&Plugin{Name: "applause", ReactionActions: []ReactionActionDefinition{
+		{Usage: "react with :clap:", Description: "Applaud along"},
+		{Hidden: true, Usage: "react with :boom:", Description: "Explode"},
+	}})
+
+	help := s.newHelpPlugin("1.0.0")
+	help.UserInfoFinder = &userInfoFinder{}
+
+	a := help.Commands[0].Answer(&IncomingMessage{NormalizedText: "help"})
+	require.NotNil(t, a)
+
+	assert.Equal(t, "🤝 Hi, `Daniel Quinn`! I'm `robert` (engine `v1.0.0`) and I listen to the team's chat and provides automated functions :genie:.\n\n"+
+		"And react to the following reactions:\n\t• `react with :clap:` - Applaud along\n", a.Text)
+}
+
+func TestHelpWithChannelActions(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults(), OptionNoPluginNamespacing())
+	require.NoError(t, err)
+
+	s.RegisterPlugin(&Plugin{Name: "welcome", ChannelActions: []ChannelActionDefinition{
+		{Usage: "join a channel", Description: "Welcome new members"},
+		{Hidden: true, Usage: "create a channel", Description: "Index the channel"},
+	}})
+
+	help := s.newHelpPlugin("1.0.0")
+	help.UserInfoFinder = &userInfoFinder{}
+
+	a := help.Commands[0].Answer(&IncomingMessage{NormalizedText: "help"})
+	require.NotNil(t, a)
+
+	assert.Equal(t, "🤝 Hi, `Daniel Quinn`! I'm `robert` (engine `v1.0.0`) and I listen to the team's chat and provides automated functions :genie:.\n\n"+
+		"And act on the following channel events:\n\t• `join a channel` - Welcome new members\n", a.Text)
+}
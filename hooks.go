@@ -0,0 +1,61 @@
+package slackscot
+
+import (
+	"strings"
+)
+
+// AnswerHook is called with every answer before it's sent to slack, whatever triggered it (messages, reactions, channel
+// events, progress updates, etc.). Hooks can modify the answer (i.e. redacting sensitive content) by returning a modified
+// copy or block it altogether by returning allow as false. Returning a nil modified answer with allow as true lets the
+// answer through unchanged. Answers too long for a single message are split first so hooks see the text of each part.
+//
+// Note that hooks run on the message processing path so they should be fast. Messages sent directly by plugins (i.e.
+// scheduled actions or via the RealTimeMsgSender/SlackClient) don't go through hooks
+type AnswerHook interface {
+	BeforeSend(answer *Answer) (modified *Answer, allow bool)
+}
+
+// AnswerHookFunc is an adapter to allow the use of a function as an AnswerHook
+type AnswerHookFunc func(answer *Answer) (modified *Answer, allow bool)
+
+// BeforeSend calls f(answer)
+func (f AnswerHookFunc) BeforeSend(answer *Answer) (modified *Answer, allow bool) {
+	return f(answer)
+}
+
+// OptionAnswerHook adds an AnswerHook to run on all answers before they're sent. Hooks run in the order
+// they were added with each hook getting the answer as modified by the previous ones
+func OptionAnswerHook(hook AnswerHook) Option {
+	return func(s *Slackscot) {
+		s.answerHooks = append(s.answerHooks, hook)
+	}
+}
+
+// applyAnswerHooksToMsg runs all answer hooks on an outgoing message, unless they already ran on it, and returns its
+// possibly modified version along with whether or not it's allowed to be sent. Hooks get the answer with the text as
+// it's sent, without the mention added by the reply strategy
+func (s *Slackscot) applyAnswerHooksToMsg(o OutgoingMessage) (modified OutgoingMessage, allow bool) {
+	if o.hooked || len(s.answerHooks) == 0 {
+		return o, true
+	}
+
+	prefix := o.replyPrefix()
+	for _, hook := range s.answerHooks {
+		answer := o.Answer
+		answer.Text = strings.TrimPrefix(o.OutgoingMessage.Text, prefix)
+		m, allow := hook.BeforeSend(&answer)
+		if !allow {
+			s.log.Printf("Answer from [%s] blocked by hook before sending", o.pluginActionID)
+			return o, false
+		}
+
+		if m != nil {
+			o.Answer = *m
+			o.OutgoingMessage.Text = prefix + m.Text
+		}
+	}
+
+	o.hooked = true
+
+	return o, true
+}
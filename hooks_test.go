@@ -0,0 +1,89 @@
+package slackscot
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestAnswerHooksModifyAndBlockAnswers(t *testing.T) {
+	var logBuilder strings.Builder
+	logger := log.New(&logBuilder, "", 0)
+
+	redactor := AnswerHookFunc(func(answer *Answer) (modified *Answer, allow bool) {
+		answer.Text = strings.Replace(answer.Text, "darn", "****", -1)
+		return answer, true
+	})
+	blocker := AnswerHookFunc(func(answer *Answer) (modified *Answer, allow bool) {
+		return nil, !strings.Contains(answer.Text, "xoxb-")
+	})
+
+	plugins := []*Plugin{newAnsweringPlugin("grumpy", 0, "Oh darn, hello"), newAnsweringPlugin("leaky", 0, "Hello, my token is xoxb-1234")}
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, nil, plugins, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "hello", "Alphonse", timestamp1)),
+	}, nil, OptionAnswerHook(redactor), OptionAnswerHook(blocker), OptionLog(logger))
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Oh ****, hello", vals.Get("text"))
+	}
+
+	assert.Contains(t, logBuilder.String(), "Answer from [leaky.command[0]] blocked by hook before sending")
+}
+
+func TestAnswerHooksRunOnAnswersToReactions(t *testing.T) {
+	var logBuilder strings.Builder
+	logger := log.New(&logBuilder, "", 0)
+
+	blocker := AnswerHookFunc(func(answer *Answer) (modified *Answer, allow bool) {
+		return nil, !strings.Contains(answer.Text, "never mind")
+	})
+
+	var reactions []IncomingReaction
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, nil, []*Plugin{newReactionPlugin(&reactions)}, []slack.RTMEvent{
+		newRTMReactionEvent(true, "Bernard", "clap", "Cgeneral", timestamp1),
+		newRTMReactionEvent(false, "Bernard", "clap", "Cgeneral", timestamp1),
+	}, nil, OptionAnswerHook(blocker), OptionLog(logger))
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, "<@Bernard> applauds [Message at 1546833210.036900] from <@Alphonse>", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+	}
+
+	assert.Contains(t, logBuilder.String(), "Answer from [applause.reactionAction[0]] blocked by hook before sending")
+}
+
+func TestAnswerHooksRunInOrder(t *testing.T) {
+	s := &Slackscot{log: NewSLogger(log.New(&strings.Builder{}, "", 0), false)}
+	OptionAnswerHook(AnswerHookFunc(func(answer *Answer) (modified *Answer, allow bool) {
+		return &Answer{Text: answer.Text + " first"}, true
+	}))(s)
+	OptionAnswerHook(AnswerHookFunc(func(answer *Answer) (modified *Answer, allow bool) {
+		return &Answer{Text: answer.Text + " second"}, true
+	}))(s)
+	OptionAnswerHook(AnswerHookFunc(func(answer *Answer) (modified *Answer, allow bool) {
+		return nil, true
+	}))(s)
+
+	o, allow := s.applyAnswerHooksToMsg(newOutMessageForAnswer(newSlackOutgoingMessage("Cgeneral", "<@Bernard>: hook"), "test", Answer{Text: "hook"}))
+
+	assert.True(t, allow)
+	assert.Equal(t, "hook first second", o.Answer.Text)
+	assert.Equal(t, "<@Bernard>: hook first second", o.OutgoingMessage.Text)
+	assert.Equal(t, "Cgeneral", o.OutgoingMessage.Channel)
+
+	// Hooks only run once on an outgoing message, whatever layer it goes through
+	again, _ := s.applyAnswerHooksToMsg(o)
+	assert.Equal(t, o, again)
+}
+
+func TestNoAnswerHooks(t *testing.T) {
+	s := &Slackscot{}
+	o := newOutMessageForAnswer(newSlackOutgoingMessage("Cgeneral", "hook"), "test", Answer{Text: "hook"})
+
+	hooked, allow := s.applyAnswerHooksToMsg(o)
+	assert.True(t, allow)
+	assert.Equal(t, o, hooked)
+}
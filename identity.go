@@ -0,0 +1,81 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+)
+
+// Identity is the username and icon a plugin's answers are posted with instead of the bot's own identity (i.e. an
+// incident plugin posting as "Incident Bot" with a :rotating_light: icon). Answers can also override it with
+// AnswerWithUsername, AnswerWithIconEmoji and AnswerWithIconURL.
+//
+// Custom identities require the chat:write.customize scope and are only applied if config.AllowCustomIdentitiesKey
+// is enabled. Otherwise, answers are posted with the bot's identity
+type Identity struct {
+	Username string
+
+	// IconEmoji is an emoji (i.e. :rotating_light:) used as the icon. It takes precedence over IconURL
+	IconEmoji string
+
+	// IconURL is the url of an image (or an asset reference, i.e. asset:trophy) used as the icon
+	IconURL string
+}
+
+// answerOptions returns the answer options applying the identity
+func (id *Identity) answerOptions() (opts []AnswerOption) {
+	if id.Username != "" {
+		opts = append(opts, AnswerWithUsername(id.Username))
+	}
+
+	if id.IconEmoji != "" {
+		opts = append(opts, AnswerWithIconEmoji(id.IconEmoji))
+	}
+
+	if id.IconURL != "" {
+		opts = append(opts, AnswerWithIconURL(id.IconURL))
+	}
+
+	return opts
+}
+
+// withPluginIdentity applies the identity of a plugin, if any, to its outgoing messages. Options of the answers
+// are applied last so that they take precedence over the plugin's identity
+func withPluginIdentity(identity *Identity, outMsgs []OutgoingMessage) []OutgoingMessage {
+	if identity == nil {
+		return outMsgs
+	}
+
+	for i := range outMsgs {
+		outMsgs[i].Options = append(identity.answerOptions(), outMsgs[i].Options...)
+	}
+
+	return outMsgs
+}
+
+// identityMsgOptions returns the message options setting the identity an answer is posted with. That's the bot's own
+// identity unless the answer has a custom identity and the workspace allows them
+func (s *Slackscot) identityMsgOptions(sendOpts map[string]string) (options []slack.MsgOption) {
+	username, iconEmoji, iconURL := sendOpts[UsernameOpt], sendOpts[IconEmojiOpt], s.assets.Resolve(sendOpts[IconURLOpt])
+	if username == "" && iconEmoji == "" && iconURL == "" {
+		return []slack.MsgOption{slack.MsgOptionAsUser(true)}
+	}
+
+	if !s.config.GetBool(config.AllowCustomIdentitiesKey) {
+		s.log.Debugf("Ignoring custom identity [%s] since custom identities aren't allowed (see %s)\n", username, config.AllowCustomIdentitiesKey)
+		return []slack.MsgOption{slack.MsgOptionAsUser(true)}
+	}
+
+	// Slack ignores custom usernames and icons of messages posted as the bot user
+	options = []slack.MsgOption{slack.MsgOptionAsUser(false)}
+	if username != "" {
+		options = append(options, slack.MsgOptionUsername(username))
+	}
+
+	if iconEmoji != "" {
+		options = append(options, slack.MsgOptionIconEmoji(iconEmoji))
+	} else if iconURL != "" {
+		options = append(options, slack.MsgOptionIconURL(iconURL))
+	}
+
+	return options
+}
@@ -0,0 +1,88 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func newIncidentPlugin(identity *Identity) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "incident"
+	p.Identity = identity
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "incident")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Incident declared"}
+		},
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "page")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Paging on-call", Options: []AnswerOption{AnswerWithUsername("Pager"), AnswerWithIconURL("asset:trophy")}}
+		},
+	}}
+
+	return p
+}
+
+func TestAnswersWithPluginAndAnswerIdentities(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.AllowCustomIdentitiesKey, true)
+	v.Set(config.AssetsBaseURLKey, "https://slackscot.example.com")
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newIncidentPlugin(&Identity{Username: "Incident Bot", IconEmoji: ":rotating_light:"}), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "incident in prod", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "page the on-call", "Alphonse", timestamp2)),
+	})
+
+	if assert.Len(t, sentMsgs, 2) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "", vals.Get("as_user"))
+		assert.Equal(t, "Incident Bot", vals.Get("username"))
+		assert.Equal(t, ":rotating_light:", vals.Get("icon_emoji"))
+
+		// Answer options take precedence over the plugin's identity
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "Pager", vals.Get("username"))
+		assert.Equal(t, ":rotating_light:", vals.Get("icon_emoji"))
+	}
+}
+
+func TestAnswersWithIconURLResolved(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.AllowCustomIdentitiesKey, true)
+	v.Set(config.AssetsBaseURLKey, "https://slackscot.example.com")
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newIncidentPlugin(nil), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "page the on-call", "Alphonse", timestamp1)),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "", vals.Get("as_user"))
+		assert.Equal(t, "Pager", vals.Get("username"))
+		assert.Equal(t, "https://slackscot.example.com/assets/trophy", vals.Get("icon_url"))
+	}
+}
+
+func TestCustomIdentitiesIgnoredWhenNotAllowed(t *testing.T) {
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newIncidentPlugin(&Identity{Username: "Incident Bot", IconEmoji: ":rotating_light:"}), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "incident in prod", "Alphonse", timestamp1)),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Len(t, sentMsgs[0].msgOptions, 2)
+
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "true", vals.Get("as_user"))
+		assert.Equal(t, "", vals.Get("username"))
+		assert.Equal(t, "", vals.Get("icon_emoji"))
+	}
+}
@@ -0,0 +1,178 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// InteractionPath is the path slack sends interaction payloads to (the interactivity request URL of the slack app).
+	// Like slash commands, it's served by the webhook server so interactions are only handled when
+	// config.WebhookListenAddressKey is set
+	InteractionPath = "/slack/interactions"
+
+	// interactionIDPrefix is the prefix of the block and view callback identifiers that route interactions back to
+	// the action that created them
+	interactionIDPrefix = "slackscot:"
+)
+
+// Interaction is a user interaction with the interactive blocks of an Answer (block_actions) or the submission of a
+// modal view opened in response to one (view_submission)
+type Interaction struct {
+	slack.InteractionCallback
+
+	// CallbackID is the callback identifier to set on modal views opened (with the TriggerID) in response to the
+	// interaction in order to have their submission routed back to the same InteractionHandler. It's also the block
+	// identifier to keep when updating the interactive block of the message interacted with
+	CallbackID string
+}
+
+// InteractionHandler is what gets executed when a user interacts with the interactive blocks of an action's answer.
+// For view submissions, a non-nil response (i.e. with validation errors) is sent back to slack. It's otherwise ignored
+type InteractionHandler func(interaction *Interaction) (response *slack.ViewSubmissionResponse)
+
+// interactionHandler is a plugin's handler of the interactions of one of its actions
+type interactionHandler struct {
+	plugin string
+	handle InteractionHandler
+}
+
+// interactionID returns the identifier routing interactions to the action with the given id
+func interactionID(pluginActionID string) string {
+	return interactionIDPrefix + pluginActionID
+}
+
+// HearActionInteractionBlockID returns the block identifier routing interactions to the InteractionHandler of the
+// plugin's hear action at the given index. Interactive blocks of answers are identified by slackscot but plugins
+// posting messages themselves (i.e. from a webhook) give it to their interactive blocks, usually with a hidden hear
+// action that never matches and only handles their interactions
+func HearActionInteractionBlockID(pluginName string, index int) string {
+	return interactionID(getActionID(pluginName, hearActionType, index))
+}
+
+// newAnswerInteractiveBlock returns the block holding an answer's interactive elements, identified with the action
+// that answered so that interactions with them are routed back to it. A nil block is returned if the answer doesn't
+// have interactive elements
+func newAnswerInteractiveBlock(pluginActionID string, answer Answer) (block slack.Block) {
+	if len(answer.InteractiveElements) == 0 {
+		return nil
+	}
+
+	return slack.NewActionBlock(interactionID(pluginActionID), answer.InteractiveElements...)
+}
+
+// answerBlocks returns the content blocks of an outgoing message along with its interactive block, if any
+func answerBlocks(o OutgoingMessage) (blocks []slack.Block) {
+	blocks = o.ContentBlocks
+	if block := newAnswerInteractiveBlock(o.pluginActionID, o.Answer); block != nil {
+		blocks = append(append([]slack.Block{}, blocks...), block)
+	}
+
+	return blocks
+}
+
+// newInteractionHandler creates the http handler routing interaction payloads to the InteractionHandler of the
+// action that created the interactive block (or the modal view) and, if not nil, the configuration of workflow
+// steps to the workflowStepRouter. Requests must be signed with the slack app's signing secret. A nil handler is
+// returned if no action has an InteractionHandler and there are no workflow steps and an error is returned if the
+// signing secret is missing
+func newInteractionHandler(plugins []*Plugin, signingSecret string, workflowSteps *workflowStepRouter, logger SLogger) (handler http.Handler, err error) {
+	handlers := make(map[string]interactionHandler)
+
+	for _, p := range plugins {
+		addInteractionHandlers(handlers, p.Name, commandType, p.Commands, logger)
+		addInteractionHandlers(handlers, p.Name, hearActionType, p.HearActions, logger)
+	}
+
+	if len(handlers) == 0 && workflowSteps == nil {
+		return nil, nil
+	}
+
+	if signingSecret == "" {
+		return nil, fmt.Errorf("Missing signing secret, required to verify interaction requests")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callback, payload, status, err := parseInteractionCallback(r, signingSecret)
+		if err != nil {
+			logger.Printf("Rejecting interaction request: %v", err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		if workflowSteps != nil && workflowSteps.handleInteraction(w, payload) {
+			return
+		}
+
+		id := interactionCallbackID(callback)
+		handler, ok := handlers[id]
+		if !ok {
+			logger.Printf("Ignoring [%s] interaction without handler [%s]", callback.Type, id)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		logger.Debugf("Routing [%s] interaction [%s] to plugin [%s]\n", callback.Type, id, handler.plugin)
+		response := handler.handle(&Interaction{InteractionCallback: callback, CallbackID: id})
+
+		if response == nil || callback.Type != slack.InteractionTypeViewSubmission {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}), nil
+}
+
+// addInteractionHandlers adds the interaction handlers of a plugin's actions of the given type
+func addInteractionHandlers(handlers map[string]interactionHandler, pluginName string, actionType string, actions []ActionDefinition, logger SLogger) {
+	for i, a := range actions {
+		if a.InteractionHandler != nil {
+			id := interactionID(getActionID(pluginName, actionType, i))
+			logger.Debugf("Registering interaction handler [%s] for plugin [%s]\n", id, pluginName)
+			handlers[id] = interactionHandler{plugin: pluginName, handle: a.InteractionHandler}
+		}
+	}
+}
+
+// interactionCallbackID returns the identifier of the handler of an interaction. For view submissions, that's the
+// view's callback identifier. For block actions, it's the identifier of the (first) block acted on created by slackscot
+func interactionCallbackID(callback slack.InteractionCallback) (id string) {
+	if callback.Type == slack.InteractionTypeViewSubmission {
+		return callback.View.CallbackID
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		if strings.HasPrefix(action.BlockID, interactionIDPrefix) {
+			return action.BlockID
+		}
+	}
+
+	return ""
+}
+
+// parseInteractionCallback verifies that an interaction request is signed with the slack app's signing secret and
+// parses its payload, also returned raw. On error, the http status to respond with is returned along with the error
+func parseInteractionCallback(r *http.Request, signingSecret string) (callback slack.InteractionCallback, payload []byte, status int, err error) {
+	body, status, err := readVerifiedBody(r, signingSecret)
+	if err != nil {
+		return callback, nil, status, err
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return callback, nil, http.StatusBadRequest, err
+	}
+
+	payload = []byte(form.Get("payload"))
+	if err = json.Unmarshal(payload, &callback); err != nil {
+		return callback, nil, http.StatusBadRequest, err
+	}
+
+	return callback, payload, http.StatusOK, nil
+}
@@ -0,0 +1,173 @@
+package slackscot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newInteractionRequest(signingSecret string, callback interface{}) (r *http.Request) {
+	payload, _ := json.Marshal(callback)
+	body := url.Values{"payload": {string(payload)}}.Encode()
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, body)))
+
+	r = httptest.NewRequest("POST", InteractionPath, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	return r
+}
+
+func newBlockActionsPayload(blockID string, actionID string, value string) map[string]interface{} {
+	return map[string]interface{}{"type": "block_actions", "trigger_id": "T123", "user": map[string]string{"id": "U123"},
+		"actions": []map[string]string{{"type": "button", "block_id": blockID, "action_id": actionID, "value": value}}}
+}
+
+func newViewSubmissionPayload(callbackID string) map[string]interface{} {
+	return map[string]interface{}{"type": "view_submission", "user": map[string]string{"id": "U123"}, "view": map[string]string{"callback_id": callbackID}}
+}
+
+func newInteractivePlugin(interactions chan *Interaction) (p *Plugin) {
+	return &Plugin{Name: "vote", Commands: []ActionDefinition{
+		{Match: func(m *IncomingMessage) bool { return strings.HasPrefix(m.NormalizedText, "ignore") }, Answer: func(m *IncomingMessage) *Answer { return nil }},
+		{
+			Match: func(m *IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "vote")
+			},
+			Answer: func(m *IncomingMessage) *Answer {
+				return &Answer{Text: "Pizza?", InteractiveElements: []slack.BlockElement{slack.NewButtonBlockElement("yes", "pizza", slack.NewTextBlockObject("plain_text", "Yes", false, false))}}
+			},
+			InteractionHandler: func(interaction *Interaction) *slack.ViewSubmissionResponse {
+				interactions <- interaction
+
+				if interaction.Type == slack.InteractionTypeViewSubmission {
+					return slack.NewErrorsViewSubmissionResponse(map[string]string{"topping": "Pineapple isn't allowed"})
+				}
+
+				return nil
+			},
+		},
+	}}
+}
+
+func TestAnswerWithInteractiveElements(t *testing.T) {
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newInteractivePlugin(make(chan *Interaction, 1)), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s vote", formattedBotUserID), "Alphonse", timestamp1)),
+	})
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: Pizza?", vals.Get("text"))
+		assert.Equal(t, "[{\"type\":\"actions\",\"block_id\":\"slackscot:vote.command[1]\",\"elements\":[{\"type\":\"button\",\"text\":{\"type\":\"plain_text\",\"text\":\"Yes\"},\"action_id\":\"yes\",\"value\":\"pizza\"}]}]", vals.Get("blocks"))
+	}
+}
+
+func TestInteractionRouting(t *testing.T) {
+	interactions := make(chan *Interaction, 1)
+	handler, err := newInteractionHandler([]*Plugin{newInteractivePlugin(interactions)}, testSigningSecret, nil, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newInteractionRequest(testSigningSecret, newBlockActionsPayload("slackscot:vote.command[1]", "yes", "pizza")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	require.Len(t, interactions, 1)
+	interaction := <-interactions
+	assert.Equal(t, slack.InteractionTypeBlockActions, interaction.Type)
+	assert.Equal(t, "slackscot:vote.command[1]", interaction.CallbackID)
+	assert.Equal(t, "T123", interaction.TriggerID)
+	require.Len(t, interaction.ActionCallback.BlockActions, 1)
+	assert.Equal(t, "pizza", interaction.ActionCallback.BlockActions[0].Value)
+}
+
+func TestInteractionRoutingOfViewSubmission(t *testing.T) {
+	interactions := make(chan *Interaction, 1)
+	handler, err := newInteractionHandler([]*Plugin{newInteractivePlugin(interactions)}, testSigningSecret, nil, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newInteractionRequest(testSigningSecret, newViewSubmissionPayload("slackscot:vote.command[1]")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, "{\"response_action\":\"errors\",\"errors\":{\"topping\":\"Pineapple isn't allowed\"}}", rec.Body.String())
+
+	require.Len(t, interactions, 1)
+	assert.Equal(t, slack.InteractionTypeViewSubmission, (<-interactions).Type)
+}
+
+func TestInteractionWithoutHandler(t *testing.T) {
+	interactions := make(chan *Interaction, 1)
+	handler, err := newInteractionHandler([]*Plugin{newInteractivePlugin(interactions)}, testSigningSecret, nil, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	for _, payload := range []map[string]interface{}{newBlockActionsPayload("someBlock", "yes", "pizza"), newBlockActionsPayload("slackscot:vote.command[0]", "yes", "pizza"), newViewSubmissionPayload("someView")} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newInteractionRequest(testSigningSecret, payload))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	assert.Empty(t, interactions)
+}
+
+func TestInteractionWithInvalidSignature(t *testing.T) {
+	interactions := make(chan *Interaction, 1)
+	handler, err := newInteractionHandler([]*Plugin{newInteractivePlugin(interactions)}, testSigningSecret, nil, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newInteractionRequest("not the secret", newBlockActionsPayload("slackscot:vote.command[1]", "yes", "pizza")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, interactions)
+}
+
+func TestInteractionHandlerWithoutSigningSecret(t *testing.T) {
+	_, err := newInteractionHandler([]*Plugin{newInteractivePlugin(make(chan *Interaction))}, "", nil, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.EqualError(t, err, "Missing signing secret, required to verify interaction requests")
+
+	handler, err := newInteractionHandler([]*Plugin{newWebhookPlugin("ci", "build")}, "", nil, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.NoError(t, err)
+	assert.Nil(t, handler)
+}
+
+func TestInteractionRoutingOfHearActionBlockID(t *testing.T) {
+	interactions := make(chan *Interaction, 1)
+	p := &Plugin{Name: "alerts", HearActions: []ActionDefinition{{
+		Hidden: true,
+		Match: func(m *IncomingMessage) bool {
+			return false
+		},
+		InteractionHandler: func(interaction *Interaction) *slack.ViewSubmissionResponse {
+			interactions <- interaction
+			return nil
+		},
+	}}}
+
+	assert.Equal(t, "slackscot:alerts.hearAction[0]", HearActionInteractionBlockID("alerts", 0))
+
+	handler, err := newInteractionHandler([]*Plugin{p}, testSigningSecret, nil, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newInteractionRequest(testSigningSecret, newBlockActionsPayload(HearActionInteractionBlockID("alerts", 0), "silence", "{}")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, interactions, 1)
+	assert.Equal(t, "slackscot:alerts.hearAction[0]", (<-interactions).CallbackID)
+}
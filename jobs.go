@@ -0,0 +1,354 @@
+package slackscot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/spf13/viper"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// jobsSilo is the silo of the jobs waiting to run (or be retried), keyed by job id
+	jobsSilo = "jobs"
+
+	// DeadJobsSilo is the silo of the jobs that failed on all their attempts (or have no handler anymore), keyed by
+	// job id. They're kept there for inspection and are never run again
+	DeadJobsSilo = "deadJobs"
+
+	// jobPollInterval is the interval between checks for jobs due to be retried
+	jobPollInterval = time.Second
+
+	// maxJobRetryBackoff bounds the exponential backoff between attempts of failing jobs
+	maxJobRetryBackoff = time.Hour
+)
+
+// OptionJobStorer sets the storer persisting the jobs enqueued by plugins (see JobQueue). Jobs waiting to run (or be
+// retried) survive restarts and jobs failing on all their attempts are moved to its DeadJobsSilo. Plugins with jobs
+// require it
+func OptionJobStorer(storer store.GlobalSiloStringStorer) Option {
+	return func(s *Slackscot) {
+		s.jobStorer = storer
+	}
+}
+
+// Job is a unit of deferred work enqueued by a plugin (i.e. processing a file or syncing with an API)
+type Job struct {
+	ID string `json:"id"`
+
+	// Type is the name of the job's definition, namespaced by its plugin (i.e. reports.generate)
+	Type string `json:"type"`
+
+	// Payload holds the data of the job (i.e. a json-encoded request), as given when enqueued
+	Payload string `json:"payload"`
+
+	// Attempts is the number of attempts that failed so far
+	Attempts int `json:"attempts"`
+
+	// LastError is the error of the last failed attempt, if any
+	LastError string `json:"lastError,omitempty"`
+
+	EnqueuedAt    time.Time `json:"enqueuedAt"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+}
+
+// JobHandler runs a job. Returning an error fails the attempt and has the job retried later, with an exponential
+// backoff (see config.JobRetryBackoffKey), until it runs out of attempts. The context is canceled when slackscot shuts
+// down in which case the job is run again once restarted
+type JobHandler func(ctx context.Context, job Job) (err error)
+
+// JobDefinition defines a type of job a plugin can enqueue on its JobQueue
+type JobDefinition struct {
+	// Name of the job's type, unique within the plugin
+	Name string
+
+	// Handler running the jobs of this type
+	Handler JobHandler
+
+	// Maximum number of attempts before the job is moved to the DeadJobsSilo. Defaults to config.JobMaxAttemptsKey
+	MaxAttempts int
+}
+
+// JobQueue lets plugins defer slow work (i.e. processing a file or syncing with an API) to a pool of workers rather
+// than doing it while handling messages. Jobs are persisted (see OptionJobStorer) until they run successfully
+type JobQueue interface {
+	// Enqueue enqueues a job of the plugin's JobDefinition with the given name and returns its id
+	Enqueue(name string, payload string) (id string, err error)
+}
+
+// jobQueue runs the jobs of all plugins with a pool of workers
+type jobQueue struct {
+	storer      store.GlobalSiloStringStorer
+	definitions map[string]JobDefinition
+	logger      SLogger
+
+	workers      int
+	maxAttempts  int
+	retryBackoff time.Duration
+
+	// notify wakes up the queue when a job is enqueued
+	notify chan struct{}
+
+	// inFlight holds the ids of the jobs handed to workers and not done yet
+	inFlight      map[string]bool
+	inFlightMutex sync.Mutex
+
+	running  sync.WaitGroup
+	sequence uint64
+	now      func() time.Time
+}
+
+// pluginJobQueue is the JobQueue injected in a plugin, enqueuing the plugin's jobs
+type pluginJobQueue struct {
+	plugin string
+	queue  *jobQueue
+}
+
+// Enqueue enqueues a job of the plugin
+func (pq *pluginJobQueue) Enqueue(name string, payload string) (id string, err error) {
+	return pq.queue.enqueue(jobType(pq.plugin, name), payload)
+}
+
+// jobType returns the type of a plugin's job (i.e. reports.generate)
+func jobType(pluginName string, jobName string) string {
+	return fmt.Sprintf("%s.%s", pluginName, jobName)
+}
+
+// newJobQueue creates the job queue running the jobs of the plugins. It returns nil if no plugin has jobs and an error
+// if some do but there's no storer to persist them
+func newJobQueue(v *viper.Viper, storer store.GlobalSiloStringStorer, plugins []*Plugin, logger SLogger) (q *jobQueue, err error) {
+	definitions := make(map[string]JobDefinition)
+	for _, p := range plugins {
+		for _, d := range p.Jobs {
+			definitions[jobType(p.Name, d.Name)] = d
+		}
+	}
+
+	if len(definitions) == 0 {
+		return nil, nil
+	}
+
+	if storer == nil {
+		return nil, fmt.Errorf("plugins have jobs but no storer is set to persist them (see OptionJobStorer)")
+	}
+
+	q = new(jobQueue)
+	q.storer = storer
+	q.definitions = definitions
+	q.logger = logger
+	q.workers = v.GetInt(config.JobWorkersKey)
+	q.maxAttempts = v.GetInt(config.JobMaxAttemptsKey)
+	q.retryBackoff = v.GetDuration(config.JobRetryBackoffKey)
+	q.notify = make(chan struct{}, 1)
+	q.inFlight = make(map[string]bool)
+	q.now = time.Now
+
+	return q, nil
+}
+
+// enqueue persists a new job of the given type and wakes up the queue to run it
+func (q *jobQueue) enqueue(jobType string, payload string) (id string, err error) {
+	if _, ok := q.definitions[jobType]; !ok {
+		return "", fmt.Errorf("no job definition for [%s]", jobType)
+	}
+
+	now := q.now()
+	// Ids sort in the order jobs are enqueued
+	j := Job{ID: fmt.Sprintf("%020d-%06d", now.UnixNano(), atomic.AddUint64(&q.sequence, 1)%1000000), Type: jobType, Payload: payload, EnqueuedAt: now, NextAttemptAt: now}
+	if err = q.put(jobsSilo, j); err != nil {
+		return "", err
+	}
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+		// The queue is already due to check for jobs
+	}
+
+	return j.ID, nil
+}
+
+// put persists a job in the silo
+func (q *jobQueue) put(silo string, j Job) (err error) {
+	value, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	return q.storer.PutSiloString(silo, j.ID, string(value))
+}
+
+// run hands the jobs due to run to the workers until the context is done. Note that this is meant to run in a go
+// routine given that this is blocking
+func (q *jobQueue) run(ctx context.Context) {
+	work := make(chan Job)
+	defer close(work)
+
+	for i := 0; i < q.workers; i++ {
+		q.running.Add(1)
+		go func() {
+			defer q.running.Done()
+
+			for j := range work {
+				q.process(ctx, j)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, j := range q.takeDueJobs() {
+			select {
+			case work <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.notify:
+		}
+	}
+}
+
+// wait waits until the workers are done or the context is done, in which case the context's error is returned
+func (q *jobQueue) wait(ctx context.Context) (err error) {
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+
+	return waitUntilClosed(ctx, done)
+}
+
+// takeDueJobs returns the jobs due to run that aren't already handed to workers, in the order they were enqueued, and
+// marks them as in flight
+func (q *jobQueue) takeDueJobs() (due []Job) {
+	entries, err := q.storer.ScanSilo(jobsSilo)
+	if err != nil {
+		q.logger.Printf("Error loading jobs: %v", err)
+		return nil
+	}
+
+	q.inFlightMutex.Lock()
+	defer q.inFlightMutex.Unlock()
+
+	now := q.now()
+	for id, value := range entries {
+		if q.inFlight[id] {
+			continue
+		}
+
+		var j Job
+		if err := json.Unmarshal([]byte(value), &j); err != nil {
+			q.logger.Printf("Error decoding job [%s], ignoring it: %v", id, err)
+			continue
+		}
+
+		if !j.NextAttemptAt.After(now) {
+			q.inFlight[id] = true
+			due = append(due, j)
+		}
+	}
+
+	sort.Slice(due, func(i, k int) bool {
+		return due[i].ID < due[k].ID
+	})
+
+	return due
+}
+
+// process runs a job and, depending on the outcome, deletes it, schedules its next attempt or moves it to the
+// DeadJobsSilo
+func (q *jobQueue) process(ctx context.Context, j Job) {
+	// Only let the job be taken again once its new state is persisted
+	defer func() {
+		q.inFlightMutex.Lock()
+		delete(q.inFlight, j.ID)
+		q.inFlightMutex.Unlock()
+	}()
+
+	d, ok := q.definitions[j.Type]
+	if !ok {
+		j.LastError = fmt.Sprintf("no job definition for [%s]", j.Type)
+		q.bury(j)
+		return
+	}
+
+	err := runJobHandler(ctx, d.Handler, j)
+	switch {
+	case err == nil:
+		if err := q.storer.DeleteSiloString(jobsSilo, j.ID); err != nil {
+			q.logger.Printf("Error deleting completed job [%s] of [%s]: %v", j.ID, j.Type, err)
+		}
+	case ctx.Err() != nil:
+		q.logger.Printf("Job [%s] of [%s] interrupted by shutdown, it will run again once restarted: %v", j.ID, j.Type, err)
+	default:
+		q.retry(d, j, err)
+	}
+}
+
+// retry schedules the next attempt of a failed job or moves it to the DeadJobsSilo if it has no attempts left
+func (q *jobQueue) retry(d JobDefinition, j Job, err error) {
+	j.Attempts = j.Attempts + 1
+	j.LastError = err.Error()
+
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.maxAttempts
+	}
+
+	if j.Attempts >= maxAttempts {
+		q.bury(j)
+		return
+	}
+
+	backoff := q.retryBackoff
+	for i := 1; i < j.Attempts && backoff < maxJobRetryBackoff; i++ {
+		backoff = backoff * 2
+	}
+	backoff = minDuration(backoff, maxJobRetryBackoff)
+
+	j.NextAttemptAt = q.now().Add(backoff)
+	q.logger.Printf("Job [%s] of [%s] failed (attempt %d of %d), retrying in [%s]: %v", j.ID, j.Type, j.Attempts, maxAttempts, backoff, err)
+
+	if err := q.put(jobsSilo, j); err != nil {
+		q.logger.Printf("Error persisting the next attempt of job [%s] of [%s]: %v", j.ID, j.Type, err)
+	}
+}
+
+// bury moves a job to the DeadJobsSilo
+func (q *jobQueue) bury(j Job) {
+	q.logger.Printf("Job [%s] of [%s] moved to the [%s] silo after %d attempts: %s", j.ID, j.Type, DeadJobsSilo, j.Attempts, j.LastError)
+
+	if err := q.put(DeadJobsSilo, j); err != nil {
+		q.logger.Printf("Error moving job [%s] of [%s] to the [%s] silo: %v", j.ID, j.Type, DeadJobsSilo, err)
+		return
+	}
+
+	if err := q.storer.DeleteSiloString(jobsSilo, j.ID); err != nil {
+		q.logger.Printf("Error deleting dead job [%s] of [%s]: %v", j.ID, j.Type, err)
+	}
+}
+
+// runJobHandler runs a job handler, turning panics into errors
+func runJobHandler(ctx context.Context, handler JobHandler, j Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, j)
+}
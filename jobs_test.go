@@ -0,0 +1,254 @@
+package slackscot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSyncerPlugin(handler JobHandler) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "syncer"
+	p.Jobs = []JobDefinition{{Name: "sync", Handler: handler}, {Name: "fragile", Handler: handler, MaxAttempts: 2}}
+
+	return p
+}
+
+// newTestJobQueue creates a job queue for the plugin with a fake clock set at the returned time
+func newTestJobQueue(t *testing.T, storer store.GlobalSiloStringStorer, p *Plugin) (q *jobQueue, clock *time.Time) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.JobRetryBackoffKey, 10*time.Second)
+
+	q, err := newJobQueue(v, storer, []*Plugin{p}, &sLogger{logger: log.New(&strings.Builder{}, "", 0)})
+	require.NoError(t, err)
+
+	clock = new(time.Time)
+	*clock = time.Date(2020, time.March, 1, 10, 0, 0, 0, time.UTC)
+	q.now = func() time.Time {
+		return *clock
+	}
+
+	return q, clock
+}
+
+func decodeJobs(t *testing.T, storer store.GlobalSiloStringStorer, silo string) (jobs []Job) {
+	entries, err := storer.ScanSilo(silo)
+	require.NoError(t, err)
+
+	for _, value := range entries {
+		var j Job
+		require.NoError(t, json.Unmarshal([]byte(value), &j))
+		jobs = append(jobs, j)
+	}
+
+	return jobs
+}
+
+func TestJobsRunByWorkers(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	ran := make(chan string, 2)
+	q, _ := newTestJobQueue(t, storer, newSyncerPlugin(func(ctx context.Context, j Job) error {
+		ran <- j.Payload
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go q.run(ctx)
+
+	pq := &pluginJobQueue{plugin: "syncer", queue: q}
+	_, err := pq.Enqueue("sync", "calendar")
+	require.NoError(t, err)
+	_, err = pq.Enqueue("sync", "contacts")
+	require.NoError(t, err)
+
+	payloads := []string{<-ran, <-ran}
+	assert.ElementsMatch(t, []string{"calendar", "contacts"}, payloads)
+
+	cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	require.NoError(t, q.wait(waitCtx))
+
+	assert.Empty(t, decodeJobs(t, storer, jobsSilo))
+}
+
+func TestEnqueueUnknownJob(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	q, _ := newTestJobQueue(t, storer, newSyncerPlugin(nil))
+
+	_, err := (&pluginJobQueue{plugin: "syncer", queue: q}).Enqueue("dance", "")
+	assert.EqualError(t, err, "no job definition for [syncer.dance]")
+}
+
+func TestFailingJobsRetriedWithBackoffThenBuried(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	attempts := 0
+	q, clock := newTestJobQueue(t, storer, newSyncerPlugin(func(ctx context.Context, j Job) error {
+		attempts = attempts + 1
+		return fmt.Errorf("api unavailable")
+	}))
+
+	id, err := q.enqueue("syncer.fragile", "calendar")
+	require.NoError(t, err)
+
+	due := q.takeDueJobs()
+	require.Len(t, due, 1)
+	q.process(context.Background(), due[0])
+
+	jobs := decodeJobs(t, storer, jobsSilo)
+	if assert.Len(t, jobs, 1) {
+		assert.Equal(t, id, jobs[0].ID)
+		assert.Equal(t, 1, jobs[0].Attempts)
+		assert.Equal(t, "api unavailable", jobs[0].LastError)
+		assert.Equal(t, clock.Add(10*time.Second), jobs[0].NextAttemptAt)
+	}
+
+	// Not due yet
+	assert.Empty(t, q.takeDueJobs())
+
+	*clock = clock.Add(10 * time.Second)
+	due = q.takeDueJobs()
+	require.Len(t, due, 1)
+	q.process(context.Background(), due[0])
+
+	assert.Equal(t, 2, attempts)
+	assert.Empty(t, decodeJobs(t, storer, jobsSilo))
+
+	dead := decodeJobs(t, storer, DeadJobsSilo)
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, id, dead[0].ID)
+		assert.Equal(t, 2, dead[0].Attempts)
+		assert.Equal(t, "api unavailable", dead[0].LastError)
+	}
+}
+
+func TestJobRetryBackoffDoubles(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	q, clock := newTestJobQueue(t, storer, newSyncerPlugin(func(ctx context.Context, j Job) error {
+		panic("oops")
+	}))
+
+	_, err := q.enqueue("syncer.sync", "calendar")
+	require.NoError(t, err)
+
+	for _, backoff := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		due := q.takeDueJobs()
+		require.Len(t, due, 1)
+		q.process(context.Background(), due[0])
+
+		jobs := decodeJobs(t, storer, jobsSilo)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, clock.Add(backoff), jobs[0].NextAttemptAt)
+		assert.Equal(t, "job panicked: oops", jobs[0].LastError)
+
+		*clock = jobs[0].NextAttemptAt
+	}
+}
+
+func TestJobsPersistedAcrossRestarts(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	q, _ := newTestJobQueue(t, storer, newSyncerPlugin(nil))
+	first, err := q.enqueue("syncer.sync", "calendar")
+	require.NoError(t, err)
+	second, err := q.enqueue("syncer.sync", "contacts")
+	require.NoError(t, err)
+
+	restarted, _ := newTestJobQueue(t, storer, newSyncerPlugin(nil))
+	due := restarted.takeDueJobs()
+
+	if assert.Len(t, due, 2) {
+		assert.Equal(t, first, due[0].ID)
+		assert.Equal(t, second, due[1].ID)
+	}
+
+	// Jobs handed to workers aren't taken again
+	assert.Empty(t, restarted.takeDueJobs())
+}
+
+func TestInterruptedJobsKept(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	q, _ := newTestJobQueue(t, storer, newSyncerPlugin(func(ctx context.Context, j Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	_, err := q.enqueue("syncer.sync", "calendar")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	q.process(ctx, q.takeDueJobs()[0])
+
+	jobs := decodeJobs(t, storer, jobsSilo)
+	if assert.Len(t, jobs, 1) {
+		assert.Equal(t, 0, jobs[0].Attempts)
+	}
+}
+
+func TestJobsWithoutDefinitionBuried(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	q, _ := newTestJobQueue(t, storer, newSyncerPlugin(nil))
+	_, err := q.enqueue("syncer.sync", "calendar")
+	require.NoError(t, err)
+
+	// The plugin doesn't define the job anymore once restarted
+	p := newSyncerPlugin(nil)
+	p.Jobs = p.Jobs[1:]
+	restarted, _ := newTestJobQueue(t, storer, p)
+	restarted.process(context.Background(), restarted.takeDueJobs()[0])
+
+	dead := decodeJobs(t, storer, DeadJobsSilo)
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, "no job definition for [syncer.sync]", dead[0].LastError)
+	}
+}
+
+func TestNewJobQueue(t *testing.T) {
+	q, err := newJobQueue(config.NewViperWithDefaults(), nil, []*Plugin{newTestPlugin()}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+
+	_, err = newJobQueue(config.NewViperWithDefaults(), nil, []*Plugin{newSyncerPlugin(nil)}, nil)
+	assert.EqualError(t, err, "plugins have jobs but no storer is set to persist them (see OptionJobStorer)")
+}
+
+func TestJobQueueInjectedInPlugins(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	s, err := New("BobbyTables", config.NewViperWithDefaults(), OptionJobStorer(storer), OptionLog(log.New(&strings.Builder{}, "", 0)))
+	require.NoError(t, err)
+
+	p := newSyncerPlugin(nil)
+	s.RegisterPlugin(p)
+
+	var userInfoFinder userInfoFinder
+	require.NoError(t, s.injectServicesToPlugins(&userInfoFinder, s.log, nil, nil, nil, nil))
+
+	require.NotNil(t, p.JobQueue)
+	_, err = p.JobQueue.Enqueue("sync", "calendar")
+	assert.NoError(t, err)
+	assert.Len(t, decodeJobs(t, storer, jobsSilo), 1)
+}
@@ -0,0 +1,96 @@
+// Package langdetect provides lightweight detection of the language of short texts such as slack messages. Detection
+// is based on the frequency of common words of each language which is good enough to pick the right localized
+// catalog for a message without pulling in a full language identification model
+package langdetect
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Supported languages, identified by their ISO 639-1 code
+const (
+	English    = "en"
+	French     = "fr"
+	Spanish    = "es"
+	German     = "de"
+	Portuguese = "pt"
+	Italian    = "it"
+)
+
+const (
+	// Minimum number of common words a text must have for its language to be detected
+	minMatches = 2
+)
+
+var wordRegex = regexp.MustCompile("[\\p{L}']+")
+
+// commonWords holds the most frequent words of each supported language. Words shared by several
+// languages (i.e. "a" or "de") count for all of them
+var commonWords = map[string][]string{
+	English:    {"the", "and", "is", "are", "was", "to", "of", "in", "it", "you", "that", "this", "with", "for", "have", "what", "not", "can", "i", "my", "we", "be", "do", "on", "please", "thanks", "how"},
+	French:     {"le", "la", "les", "et", "est", "sont", "un", "une", "des", "du", "de", "je", "tu", "il", "nous", "vous", "pas", "que", "qui", "pour", "avec", "dans", "ce", "c'est", "merci", "comment", "quoi"},
+	Spanish:    {"el", "la", "los", "las", "y", "es", "son", "un", "una", "de", "del", "que", "yo", "tú", "nosotros", "no", "para", "con", "en", "por", "gracias", "cómo", "qué", "está", "muy", "pero"},
+	German:     {"der", "die", "das", "und", "ist", "sind", "ein", "eine", "nicht", "ich", "du", "wir", "sie", "mit", "für", "auf", "zu", "von", "den", "dem", "danke", "wie", "was", "auch", "bitte", "es"},
+	Portuguese: {"o", "os", "a", "as", "e", "é", "são", "um", "uma", "de", "do", "da", "que", "eu", "você", "nós", "não", "para", "com", "em", "obrigado", "obrigada", "como", "isso", "muito"},
+	Italian:    {"il", "lo", "la", "gli", "le", "e", "è", "sono", "un", "una", "di", "del", "che", "io", "tu", "noi", "non", "per", "con", "in", "grazie", "come", "questo", "molto", "anche"},
+}
+
+// languagesByWord is the reverse index of commonWords
+var languagesByWord = make(map[string][]string)
+
+func init() {
+	for language, words := range commonWords {
+		for _, w := range words {
+			languagesByWord[w] = append(languagesByWord[w], language)
+		}
+	}
+}
+
+// Detect returns the most likely language of the text along with a confidence between 0 and 1. If the
+// language can't be determined (text too short or ambiguous), an empty language is returned
+func Detect(text string) (language string, confidence float64) {
+	scores := make(map[string]int)
+	total := 0
+
+	for _, w := range wordRegex.FindAllString(strings.ToLower(text), -1) {
+		for _, l := range languagesByWord[w] {
+			scores[l] = scores[l] + 1
+			total = total + 1
+		}
+	}
+
+	ranked := make([]string, 0, len(scores))
+	for l := range scores {
+		ranked = append(ranked, l)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if scores[ranked[i]] == scores[ranked[j]] {
+			return ranked[i] < ranked[j]
+		}
+
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+
+	if len(ranked) == 0 || scores[ranked[0]] < minMatches || (len(ranked) > 1 && scores[ranked[0]] == scores[ranked[1]]) {
+		return "", 0
+	}
+
+	return ranked[0], float64(scores[ranked[0]]) / float64(total)
+}
+
+// Select returns the detected language of the text if it's one of the supported ones or the fallback otherwise. This is
+// meant for plugins to pick the localized catalog to answer a message with
+func Select(text string, supported []string, fallback string) (language string) {
+	detected, _ := Detect(text)
+
+	for _, s := range supported {
+		if s == detected {
+			return detected
+		}
+	}
+
+	return fallback
+}
@@ -0,0 +1,49 @@
+package langdetect_test
+
+import (
+	"github.com/alexandre-normand/slackscot/langdetect"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		text             string
+		expectedLanguage string
+	}{
+		{"What is the status of the deploy? Can you check it please", langdetect.English},
+		{"Est-ce que vous pouvez regarder le déploiement avec nous? Merci", langdetect.French},
+		{"¿Cómo está el despliegue? Gracias por la ayuda", langdetect.Spanish},
+		{"Wie ist der Stand von dem Deployment? Danke für die Hilfe", langdetect.German},
+		{"Você pode ver isso? Muito obrigado pela ajuda", langdetect.Portuguese},
+		{"Grazie per questo, non è molto chiaro", langdetect.Italian},
+		{"deploy", ""},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			language, _ := langdetect.Detect(tc.text)
+			assert.Equal(t, tc.expectedLanguage, language)
+		})
+	}
+}
+
+func TestDetectConfidence(t *testing.T) {
+	language, confidence := langdetect.Detect("the cat is on the table and it is happy")
+	assert.Equal(t, langdetect.English, language)
+	assert.Equal(t, 1., confidence)
+
+	// "la" is a common word in French, Spanish and Italian
+	language, confidence = langdetect.Detect("la table est belle")
+	assert.Equal(t, langdetect.French, language)
+	assert.Equal(t, 0.5, confidence)
+}
+
+func TestSelect(t *testing.T) {
+	supported := []string{langdetect.English, langdetect.French}
+
+	assert.Equal(t, langdetect.French, langdetect.Select("Merci pour le café, c'est super", supported, langdetect.English))
+	assert.Equal(t, langdetect.English, langdetect.Select("Gracias por la ayuda, es muy amable", supported, langdetect.English))
+	assert.Equal(t, langdetect.English, langdetect.Select("ok", supported, langdetect.English))
+}
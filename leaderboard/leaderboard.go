@@ -0,0 +1,185 @@
+// Package leaderboard renders ranked lists (i.e. the karma top list) as slack block kit blocks so that plugins
+// share the same look for their rankings:
+//
+//	r := leaderboard.New(leaderboard.MedalTheme)
+//	blocks := r.Render(":trophy: *Top Reactions* :trophy:", []leaderboard.Entry{{Name: ":tada:", Value: 42}, {Name: ":eyes:", Value: 12}})
+//	return &slackscot.Answer{ContentBlocks: blocks}
+package leaderboard
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"strings"
+)
+
+// MaxBlocks is the maximum number of blocks slack accepts in a message
+const MaxBlocks = 50
+
+// Entry is a ranked entry of a leaderboard
+type Entry struct {
+	Name  string
+	Value int
+}
+
+// Theme defines the look of a leaderboard
+type Theme struct {
+	// RankIcon returns the icon rendered in front of an entry given its rank (starting at 1)
+	RankIcon func(rank int) string
+
+	// BannerPrefix and BannerSuffix are flourishes (i.e. emojis) rendered on each side of the banner
+	BannerPrefix string
+	BannerSuffix string
+
+	// BannerImageURL is the optional url of an image rendered next to the banner
+	BannerImageURL string
+
+	// BannerImageAltText is the alternative text of the banner image
+	BannerImageAltText string
+}
+
+const bullet = "•"
+
+// Themes of leaderboards
+var (
+	// BulletTheme renders a bullet in front of all entries
+	BulletTheme = Theme{RankIcon: func(rank int) string { return bullet }}
+
+	// MedalTheme renders medals in front of the first three entries and bullets in front of the others
+	MedalTheme = Theme{RankIcon: podiumIcons(":first_place_medal:", ":second_place_medal:", ":third_place_medal:")}
+
+	// SkullTheme renders skulls in front of the first three entries and bullets in front of the others. It's meant for
+	// rankings of the worst things
+	SkullTheme = Theme{RankIcon: podiumIcons(":skull_and_crossbones:", ":skull:", ":skull:")}
+)
+
+// NumberedRankIcon renders the rank of entries as numbers (i.e. "1.")
+func NumberedRankIcon(rank int) string {
+	return fmt.Sprintf("%d.", rank)
+}
+
+// podiumIcons returns a function rendering the given icons for the first ranks and bullets for the others
+func podiumIcons(icons ...string) func(rank int) string {
+	return func(rank int) string {
+		if rank >= 1 && rank <= len(icons) {
+			return icons[rank-1]
+		}
+
+		return bullet
+	}
+}
+
+// Renderer renders leaderboards with a theme
+type Renderer struct {
+	theme         Theme
+	nameFormatter func(name string) string
+	imageResolver func(url string) string
+	maxBlocks     int
+}
+
+// Option defines an option for a Renderer
+type Option func(*Renderer)
+
+// OptionNameFormatter sets the function formatting the names of entries (i.e. to render user ids as mentions).
+// Defaults to rendering names as-is
+func OptionNameFormatter(nameFormatter func(name string) string) Option {
+	return func(r *Renderer) {
+		r.nameFormatter = nameFormatter
+	}
+}
+
+// OptionImageResolver sets the function resolving the url of the banner image (i.e. to resolve asset references to
+// urls of self-hosted images, see assets.Registry). An empty resolved url leaves the image out. Defaults to using
+// urls as-is
+func OptionImageResolver(imageResolver func(url string) string) Option {
+	return func(r *Renderer) {
+		r.imageResolver = imageResolver
+	}
+}
+
+// OptionMaxBlocks sets the maximum number of blocks of rendered leaderboards (i.e. to leave room for other blocks in
+// the same message). Leaderboards with more entries than fit are truncated. Defaults to MaxBlocks
+func OptionMaxBlocks(maxBlocks int) Option {
+	return func(r *Renderer) {
+		r.maxBlocks = maxBlocks
+	}
+}
+
+// New creates a new Renderer with the theme
+func New(theme Theme, options ...Option) (r *Renderer) {
+	r = new(Renderer)
+	r.theme = theme
+	r.nameFormatter = func(name string) string { return name }
+	r.imageResolver = func(url string) string { return url }
+	r.maxBlocks = MaxBlocks
+
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return r
+}
+
+// Render returns the blocks of a leaderboard: a section with the banner (with the theme's flourishes and image)
+// followed by a section for each entry, in order. If there are no entries, no blocks are returned so that callers
+// can answer something else. Leaderboards that don't fit in the maximum number of blocks are truncated and end with
+// a section telling how many more entries there are (see Truncated)
+func (r *Renderer) Render(banner string, entries []Entry) (blocks []slack.Block) {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	truncated := r.Truncated(entries)
+	entries = entries[:len(entries)-truncated]
+
+	var bannerImage *slack.Accessory
+	if imageURL := r.imageResolver(r.theme.BannerImageURL); imageURL != "" {
+		bannerImage = slack.NewAccessory(slack.NewImageBlockElement(imageURL, r.theme.BannerImageAltText))
+	}
+
+	blocks = make([]slack.Block, 0, len(entries)+1)
+	blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, r.theme.BannerPrefix+banner+r.theme.BannerSuffix, false, false), nil, bannerImage))
+
+	for i, e := range entries {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, r.renderEntry(i+1, e), false, false), nil, nil))
+	}
+
+	if truncated > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("_and %d more…_", truncated), false, false), nil, nil))
+	}
+
+	return blocks
+}
+
+// Truncated returns the number of entries left out of the leaderboard rendered by Render in order to fit in the
+// maximum number of blocks
+func (r *Renderer) Truncated(entries []Entry) (count int) {
+	// One block for the banner and, if truncated, one for the count of entries left out
+	if len(entries)+1 <= r.maxBlocks {
+		return 0
+	}
+
+	shown := r.maxBlocks - 2
+	if shown < 0 {
+		shown = 0
+	}
+
+	return len(entries) - shown
+}
+
+// RenderText returns the full leaderboard as plain text with one line per entry (i.e. to upload leaderboards too big
+// for a message as a file)
+func (r *Renderer) RenderText(banner string, entries []Entry) (text string) {
+	var b strings.Builder
+	b.WriteString(r.theme.BannerPrefix + banner + r.theme.BannerSuffix + "\n")
+
+	for i, e := range entries {
+		b.WriteString(r.renderEntry(i+1, e) + "\n")
+	}
+
+	return b.String()
+}
+
+// renderEntry renders an entry given its rank
+func (r *Renderer) renderEntry(rank int, e Entry) (render string) {
+	return fmt.Sprintf("%s %s `%d`", r.theme.RankIcon(rank), r.nameFormatter(e.Name), e.Value)
+}
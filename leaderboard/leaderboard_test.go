@@ -0,0 +1,98 @@
+package leaderboard_test
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot/leaderboard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var entries = []leaderboard.Entry{{Name: "@U1", Value: 10}, {Name: "coffee", Value: 7}, {Name: "tea", Value: 3}, {Name: "decaf", Value: -2}}
+
+func renderTexts(t *testing.T, r *leaderboard.Renderer) (texts []string) {
+	blocks := r.Render("*Top*", entries)
+
+	render, err := json.Marshal(blocks)
+	require.NoError(t, err)
+
+	var sections []struct {
+		Type string
+		Text struct {
+			Type string
+			Text string
+		}
+	}
+	require.NoError(t, json.Unmarshal(render, &sections))
+
+	for _, s := range sections {
+		assert.Equal(t, "section", s.Type)
+		assert.Equal(t, "mrkdwn", s.Text.Type)
+		texts = append(texts, s.Text.Text)
+	}
+
+	return texts
+}
+
+func TestRenderWithBulletTheme(t *testing.T) {
+	assert.Equal(t, []string{"*Top*", "• @U1 `10`", "• coffee `7`", "• tea `3`", "• decaf `-2`"}, renderTexts(t, leaderboard.New(leaderboard.BulletTheme)))
+}
+
+func TestRenderWithMedalTheme(t *testing.T) {
+	assert.Equal(t, []string{"*Top*", ":first_place_medal: @U1 `10`", ":second_place_medal: coffee `7`", ":third_place_medal: tea `3`", "• decaf `-2`"}, renderTexts(t, leaderboard.New(leaderboard.MedalTheme)))
+}
+
+func TestRenderWithSkullThemeAndNameFormatter(t *testing.T) {
+	r := leaderboard.New(leaderboard.SkullTheme, leaderboard.OptionNameFormatter(strings.ToUpper))
+
+	assert.Equal(t, []string{"*Top*", ":skull_and_crossbones: @U1 `10`", ":skull: COFFEE `7`", ":skull: TEA `3`", "• DECAF `-2`"}, renderTexts(t, r))
+}
+
+func TestRenderWithoutEntries(t *testing.T) {
+	assert.Nil(t, leaderboard.New(leaderboard.MedalTheme).Render("*Top*", nil))
+}
+
+func TestRenderWithBannerFlourishesAndImage(t *testing.T) {
+	r := leaderboard.New(leaderboard.Theme{RankIcon: leaderboard.NumberedRankIcon, BannerPrefix: ":trophy: ", BannerSuffix: " :trophy:", BannerImageURL: "https://example.com/trophy.png", BannerImageAltText: "trophy"})
+
+	render, err := json.Marshal(r.Render("*Top*", entries[:1]))
+	require.NoError(t, err)
+
+	assert.Equal(t, "[{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\":trophy: *Top* :trophy:\"},\"accessory\":{\"type\":\"image\",\"image_url\":\"https://example.com/trophy.png\",\"alt_text\":\"trophy\"}},{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"1. @U1 `10`\"}}]", string(render))
+}
+
+func TestRenderWithImageResolver(t *testing.T) {
+	theme := leaderboard.Theme{RankIcon: leaderboard.NumberedRankIcon, BannerImageURL: "asset:trophy", BannerImageAltText: "trophy"}
+
+	render, err := json.Marshal(leaderboard.New(theme, leaderboard.OptionImageResolver(func(url string) string { return "https://slackscot.example.com/assets/trophy" })).Render("*Top*", entries[:1]))
+	require.NoError(t, err)
+	assert.Contains(t, string(render), "\"image_url\":\"https://slackscot.example.com/assets/trophy\"")
+
+	render, err = json.Marshal(leaderboard.New(theme, leaderboard.OptionImageResolver(func(url string) string { return "" })).Render("*Top*", entries[:1]))
+	require.NoError(t, err)
+	assert.NotContains(t, string(render), "accessory")
+}
+
+func TestRenderTruncatedToMaxBlocks(t *testing.T) {
+	r := leaderboard.New(leaderboard.Theme{RankIcon: leaderboard.NumberedRankIcon}, leaderboard.OptionMaxBlocks(4))
+
+	assert.Equal(t, []string{"*Top*", "1. @U1 `10`", "2. coffee `7`", "_and 2 more…_"}, renderTexts(t, r))
+	assert.Equal(t, 2, r.Truncated(entries))
+	assert.Equal(t, 0, r.Truncated(entries[:3]))
+}
+
+func TestRenderDefaultsToSlackMaxBlocks(t *testing.T) {
+	many := make([]leaderboard.Entry, 100)
+	r := leaderboard.New(leaderboard.BulletTheme)
+
+	assert.Len(t, r.Render("*Top*", many), leaderboard.MaxBlocks)
+	assert.Equal(t, 52, r.Truncated(many))
+	assert.Equal(t, 0, r.Truncated(many[:leaderboard.MaxBlocks-1]))
+}
+
+func TestRenderText(t *testing.T) {
+	r := leaderboard.New(leaderboard.Theme{RankIcon: leaderboard.NumberedRankIcon, BannerPrefix: ":trophy: "}, leaderboard.OptionMaxBlocks(2))
+
+	assert.Equal(t, ":trophy: *Top*\n1. @U1 `10`\n2. coffee `7`\n3. tea `3`\n4. decaf `-2`\n", r.RenderText("*Top*", entries))
+}
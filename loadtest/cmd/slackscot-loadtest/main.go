@@ -0,0 +1,105 @@
+// Command slackscot-loadtest generates synthetic traffic against a slackscot instance running some of the plugins
+// of this repository (karma, triggerer, fingerQuoter and versionner) and reports the latency of its answers. Plugins
+// are enabled by having their section in the configuration's plugins (versionner is always enabled):
+//
+//	slackscot-loadtest --config config.json --rate 200 --duration 1m --channels 500 --users 10000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/loadtest"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	configPath := flag.String("config", "", "Path to the slackscot configuration file (defaults are used if not set)")
+	rate := flag.Float64("rate", 10, "Messages per second")
+	duration := flag.Duration("duration", time.Duration(10)*time.Second, "Duration of the traffic generation")
+	channels := flag.Int("channels", 10, "Number of channels")
+	users := flag.Int("users", 100, "Number of users")
+	commandRatio := flag.Float64("commandRatio", 0.1, "Ratio of messages that are commands to the bot")
+	answerTimeout := flag.Duration("answerTimeout", time.Duration(5)*time.Second, "How long to wait for answers after the last message")
+	flag.Parse()
+
+	v := config.NewViperWithDefaults()
+	if *configPath != "" {
+		v = viper.New()
+		v.SetConfigFile(*configPath)
+		if err := v.ReadInConfig(); err != nil {
+			log.Fatalf("Error loading configuration [%s]: %v", *configPath, err)
+		}
+
+		v = config.LayerConfigWithDefaults(v)
+	}
+
+	storagePath, err := ioutil.TempDir("", "slackscot-loadtest")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(storagePath)
+
+	toRegister, closers, err := newPlugins(v, storagePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := loadtest.New(loadtest.OptionRate(*rate), loadtest.OptionDuration(*duration), loadtest.OptionChannels(*channels),
+		loadtest.OptionUsers(*users), loadtest.OptionCommandRatio(*commandRatio), loadtest.OptionAnswerTimeout(*answerTimeout)).Run(v, toRegister...)
+
+	for _, c := range closers {
+		c.Close()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(report)
+}
+
+// newPlugins creates the plugins enabled in the configuration, with their storers in the storage path
+func newPlugins(v *viper.Viper, storagePath string) (toRegister []*slackscot.Plugin, closers []*store.LevelDB, err error) {
+	toRegister = []*slackscot.Plugin{plugins.NewVersionner("slackscot-loadtest", slackscot.VERSION)}
+
+	for _, name := range []string{plugins.KarmaPluginName, plugins.TriggererPluginName} {
+		if !v.IsSet(fmt.Sprintf("%s.%s", config.PluginsKey, name)) {
+			continue
+		}
+
+		storer, err := store.NewLevelDB(name, storagePath)
+		if err != nil {
+			return nil, closers, err
+		}
+		closers = append(closers, storer)
+
+		if name == plugins.KarmaPluginName {
+			toRegister = append(toRegister, plugins.NewKarma(storer))
+		} else {
+			toRegister = append(toRegister, plugins.NewTriggerer(storer))
+		}
+	}
+
+	if v.IsSet(fmt.Sprintf("%s.%s", config.PluginsKey, plugins.FingerQuoterPluginName)) {
+		c, err := config.GetPluginConfig(v, plugins.FingerQuoterPluginName)
+		if err != nil {
+			return nil, closers, err
+		}
+
+		fingerQuoter, err := plugins.NewFingerQuoter(c)
+		if err != nil {
+			return nil, closers, err
+		}
+		toRegister = append(toRegister, fingerQuoter)
+	}
+
+	return toRegister, closers, nil
+}
@@ -0,0 +1,475 @@
+// Package loadtest generates synthetic real-time messaging traffic against a slackscot instance and measures the
+// latency of its answers. The instance runs against an in-memory fake slack server (see
+// github.com/slack-go/slack/slacktest) so that the capacity of plugins can be evaluated before enabling them in
+// a large workspace:
+//
+//	g := loadtest.New(loadtest.OptionRate(200), loadtest.OptionDuration(time.Minute), loadtest.OptionChannels(500), loadtest.OptionUsers(10000))
+//	report, err := g.Run(config.NewViperWithDefaults(), plugins.NewKarma(storer))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	fmt.Println(report)
+//
+// Answers are correlated to the messages that triggered them by their thread timestamp so threaded replies are
+// always enabled on the instance under test. Messages sent, updated or deleted by other means (i.e. the
+// slackscot.RealTimeMessageSender) aren't measured
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRate          = 10.
+	defaultDuration      = time.Duration(10) * time.Second
+	defaultChannelCount  = 10
+	defaultUserCount     = 100
+	defaultCommandRatio  = 0.1
+	defaultAnswerTimeout = time.Duration(5) * time.Second
+	warmupInterval       = time.Duration(100) * time.Millisecond
+	warmupCommand        = "help"
+	botName              = "loadtest"
+	botID                = "B0LOADTEST"
+)
+
+// defaultMessages is a mix of chatter and messages triggering some of the common plugins (i.e. karma and fingerQuoter)
+var defaultMessages = []string{
+	"good morning everyone",
+	"anyone knows if the build is green?",
+	"thanks for the help, coffee++",
+	"I'll \"fix\" it after lunch",
+	"lol",
+	"the deploy went out 10 minutes ago",
+	"standup in 5",
+	"tests--",
+	"can someone review my PR? https://github.com/alexandre-normand/slackscot/pull/1",
+	"I'm out tomorrow :palm_tree:",
+}
+
+// defaultCommands are sent to the bot (prefixed by its mention) at the command ratio
+var defaultCommands = []string{"help"}
+
+// Generator generates synthetic traffic at a constant rate over random channels and users
+type Generator struct {
+	rate          float64
+	duration      time.Duration
+	channelCount  int
+	userCount     int
+	messages      []string
+	commands      []string
+	commandRatio  float64
+	answerTimeout time.Duration
+	random        *rand.Rand
+	botOptions    []slackscot.Option
+}
+
+// Option defines an option for a Generator
+type Option func(*Generator)
+
+// OptionRate sets the rate of messages generated, in messages per second. Defaults to 10
+func OptionRate(messagesPerSecond float64) Option {
+	return func(g *Generator) {
+		g.rate = messagesPerSecond
+	}
+}
+
+// OptionDuration sets the duration of the traffic generation. Defaults to 10 seconds
+func OptionDuration(duration time.Duration) Option {
+	return func(g *Generator) {
+		g.duration = duration
+	}
+}
+
+// OptionChannels sets the number of channels messages are spread over. Defaults to 10
+func OptionChannels(count int) Option {
+	return func(g *Generator) {
+		g.channelCount = count
+	}
+}
+
+// OptionUsers sets the number of users messages are sent from. Defaults to 100
+func OptionUsers(count int) Option {
+	return func(g *Generator) {
+		g.userCount = count
+	}
+}
+
+// OptionMessages sets the texts of the messages, picked at random. Defaults to a mix of chatter and messages
+// triggering common plugins
+func OptionMessages(texts ...string) Option {
+	return func(g *Generator) {
+		g.messages = texts
+	}
+}
+
+// OptionCommands sets the commands (without the bot mention), picked at random when a command is sent. Defaults to help
+func OptionCommands(commands ...string) Option {
+	return func(g *Generator) {
+		g.commands = commands
+	}
+}
+
+// OptionCommandRatio sets the ratio (between 0 and 1) of messages that are commands to the bot. Defaults to 0.1
+func OptionCommandRatio(ratio float64) Option {
+	return func(g *Generator) {
+		g.commandRatio = ratio
+	}
+}
+
+// OptionAnswerTimeout sets how long to wait for answers after the last message is sent. This is also how long to
+// wait for the bot to answer a warm-up message before starting. Defaults to 5 seconds
+func OptionAnswerTimeout(timeout time.Duration) Option {
+	return func(g *Generator) {
+		g.answerTimeout = timeout
+	}
+}
+
+// OptionRandSource sets the source of randomness of the generator, useful to reproduce the same traffic
+func OptionRandSource(src rand.Source) Option {
+	return func(g *Generator) {
+		g.random = rand.New(src)
+	}
+}
+
+// OptionBotOptions adds options to the slackscot instance under test. By default, its logs are discarded
+func OptionBotOptions(options ...slackscot.Option) Option {
+	return func(g *Generator) {
+		g.botOptions = append(g.botOptions, options...)
+	}
+}
+
+// New creates a new Generator
+func New(options ...Option) (g *Generator) {
+	g = new(Generator)
+	g.rate = defaultRate
+	g.duration = defaultDuration
+	g.channelCount = defaultChannelCount
+	g.userCount = defaultUserCount
+	g.messages = defaultMessages
+	g.commands = defaultCommands
+	g.commandRatio = defaultCommandRatio
+	g.answerTimeout = defaultAnswerTimeout
+	g.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	g.botOptions = []slackscot.Option{slackscot.OptionLog(log.New(ioutil.Discard, "", 0))}
+
+	for _, opt := range options {
+		opt(g)
+	}
+
+	return g
+}
+
+// Report holds the results of a load test
+type Report struct {
+	Sent      int             // Number of messages sent
+	Commands  int             // Number of messages sent that were commands
+	Answered  int             // Number of messages that got at least one answer
+	Answers   int             // Number of answers (a message can get more than one answer, one per triggered action)
+	Elapsed   time.Duration   // Time between the first message sent and the last answer received (or the end of the generation, if later)
+	Latencies []time.Duration // Sorted latencies of the first answer of each answered message
+}
+
+// Percentile returns the latency at the given percentile (between 0 and 100) using the nearest-rank method
+func (r *Report) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	rank := int(float64(len(r.Latencies))*p/100. + 0.5)
+	if rank < 1 {
+		rank = 1
+	} else if rank > len(r.Latencies) {
+		rank = len(r.Latencies)
+	}
+
+	return r.Latencies[rank-1]
+}
+
+// String returns a summary of the report
+func (r *Report) String() string {
+	rate := 0.
+	if r.Elapsed > 0 {
+		rate = float64(r.Sent) / r.Elapsed.Seconds()
+	}
+
+	return fmt.Sprintf("Sent [%d] messages ([%d] commands) in [%s] ([%.1f]/s), [%d] answered with [%d] answers, latency p50 [%s], p90 [%s], p99 [%s], max [%s]",
+		r.Sent, r.Commands, r.Elapsed, rate, r.Answered, r.Answers, r.Percentile(50), r.Percentile(90), r.Percentile(99), r.Percentile(100))
+}
+
+// Run starts a slackscot instance with the configuration and plugins against a fake slack server, waits for it to
+// answer a warm-up command and generates traffic. It returns once all messages are answered or once the answer
+// timeout elapsed after the last message. Note that threaded replies are enabled on the configuration
+func (g *Generator) Run(v *viper.Viper, plugins ...*slackscot.Plugin) (report *Report, err error) {
+	if g.rate <= 0 || g.channelCount <= 0 || g.userCount <= 0 || len(g.messages) == 0 || (g.commandRatio > 0 && len(g.commands) == 0) {
+		return nil, fmt.Errorf("Invalid load test: rate [%f], channels [%d] and users [%d] should be greater than 0 with at least one message and command", g.rate, g.channelCount, g.userCount)
+	}
+
+	rec := newAnswerRecorder()
+	server := slacktest.NewTestServer(func(c slacktest.Customize) {
+		c.Handle("/chat.postMessage", rec.handleMessage)
+		c.Handle("/chat.postEphemeral", rec.handleMessage)
+		c.Handle("/reactions.add", rec.handleReaction)
+		c.Handle("/users.info", handleUsersInfo)
+	})
+	server.SetBotName(botName)
+	server.Start()
+	defer server.Stop()
+
+	v.Set(config.ThreadedRepliesKey, true)
+	// The test server doesn't enforce slack's rate limits and spacing calls would only measure those
+	v.Set(config.RateLimitEnabledKey, false)
+
+	termination := make(chan bool)
+	options := append([]slackscot.Option{slackscot.OptionWithSlackOption(slack.OptionAPIURL(server.GetAPIURL())), slackscot.OptionTestMode(termination)}, g.botOptions...)
+	s, err := slackscot.New(botName, v, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range plugins {
+		s.RegisterPlugin(p)
+	}
+
+	if err = s.Run(); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		server.SendToWebsocket("{\"type\":\"goodbye\"}")
+		<-termination
+	}()
+
+	if err = g.warmUp(server, rec); err != nil {
+		return nil, err
+	}
+
+	report = new(Report)
+	start := time.Now()
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / g.rate))
+	defer ticker.Stop()
+
+	count := int(g.rate * g.duration.Seconds())
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			<-ticker.C
+		}
+
+		text := g.messages[g.random.Intn(len(g.messages))]
+		if g.random.Float64() < g.commandRatio {
+			text = fmt.Sprintf("<@%s> %s", server.BotID, g.commands[g.random.Intn(len(g.commands))])
+			report.Commands++
+		}
+
+		g.send(server, rec, fmt.Sprintf("C%08d", g.random.Intn(g.channelCount)), fmt.Sprintf("U%08d", g.random.Intn(g.userCount)), text, false)
+		report.Sent++
+	}
+
+	generationEnd := time.Now()
+	for deadline := generationEnd.Add(g.answerTimeout); time.Now().Before(deadline) && rec.answeredCount() < report.Sent; {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec.fillReport(report, start, generationEnd)
+	return report, nil
+}
+
+// warmUp sends a warm-up command at regular intervals until the bot answers one of them, meaning it's connected
+// and ready to process messages
+func (g *Generator) warmUp(server *slacktest.Server, rec *answerRecorder) (err error) {
+	deadline := time.Now().Add(g.answerTimeout)
+	for !rec.warmedUp() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Bot didn't answer warm-up command [%s] within [%s]", warmupCommand, g.answerTimeout)
+		}
+
+		g.send(server, rec, "C00000000", "U00000000", fmt.Sprintf("<@%s> %s", server.BotID, warmupCommand), true)
+		time.Sleep(warmupInterval)
+	}
+
+	return nil
+}
+
+// send sends a message to the bot via the fake slack server's websocket
+func (g *Generator) send(server *slacktest.Server, rec *answerRecorder, channelID string, userID string, text string, warmup bool) {
+	m := slack.Message{}
+	m.Type = slack.TYPE_MESSAGE
+	m.Channel = channelID
+	m.User = userID
+	m.Text = text
+	m.Timestamp = rec.newMessage(warmup)
+
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		// This can't happen with a slack.Message but if it somehow did, the message simply goes unanswered
+		return
+	}
+
+	server.SendToWebsocket(string(encoded))
+}
+
+// answerRecorder issues unique timestamps to the messages sent and records when the answers to each arrive
+type answerRecorder struct {
+	sync.Mutex
+	base       int64
+	sequence   int64
+	sentAt     map[string]time.Time
+	answeredAt map[string]time.Time
+	warmups    map[string]bool
+	answers    int
+	lastAnswer time.Time
+	warm       bool
+}
+
+func newAnswerRecorder() (rec *answerRecorder) {
+	rec = new(answerRecorder)
+	rec.base = time.Now().Unix()
+	rec.sentAt = make(map[string]time.Time)
+	rec.answeredAt = make(map[string]time.Time)
+	rec.warmups = make(map[string]bool)
+
+	return rec
+}
+
+// newMessage returns a new unique message timestamp and records its sending time
+func (rec *answerRecorder) newMessage(warmup bool) (timestamp string) {
+	rec.Lock()
+	defer rec.Unlock()
+
+	timestamp = rec.nextTimestamp()
+	if warmup {
+		rec.warmups[timestamp] = true
+	} else {
+		rec.sentAt[timestamp] = time.Now()
+	}
+
+	return timestamp
+}
+
+// nextTimestamp returns a new unique slack timestamp. The caller must hold the lock
+func (rec *answerRecorder) nextTimestamp() string {
+	rec.sequence++
+	return fmt.Sprintf("%d.%06d", rec.base+rec.sequence/1000000, rec.sequence%1000000)
+}
+
+// recordAnswer records an answer to the message with the given timestamp
+func (rec *answerRecorder) recordAnswer(timestamp string) {
+	now := time.Now()
+
+	rec.Lock()
+	defer rec.Unlock()
+
+	if rec.warmups[timestamp] {
+		rec.warm = true
+		return
+	}
+
+	if _, sent := rec.sentAt[timestamp]; !sent {
+		return
+	}
+
+	rec.answers++
+	rec.lastAnswer = now
+	if _, answered := rec.answeredAt[timestamp]; !answered {
+		rec.answeredAt[timestamp] = now
+	}
+}
+
+func (rec *answerRecorder) warmedUp() bool {
+	rec.Lock()
+	defer rec.Unlock()
+
+	return rec.warm
+}
+
+func (rec *answerRecorder) answeredCount() int {
+	rec.Lock()
+	defer rec.Unlock()
+
+	return len(rec.answeredAt)
+}
+
+// fillReport fills the report with the answers recorded
+func (rec *answerRecorder) fillReport(report *Report, start time.Time, generationEnd time.Time) {
+	rec.Lock()
+	defer rec.Unlock()
+
+	report.Answered = len(rec.answeredAt)
+	report.Answers = rec.answers
+	report.Latencies = make([]time.Duration, 0, len(rec.answeredAt))
+	for ts, answeredAt := range rec.answeredAt {
+		report.Latencies = append(report.Latencies, answeredAt.Sub(rec.sentAt[ts]))
+	}
+
+	sort.Slice(report.Latencies, func(i, j int) bool { return report.Latencies[i] < report.Latencies[j] })
+
+	end := generationEnd
+	if rec.lastAnswer.After(end) {
+		end = rec.lastAnswer
+	}
+
+	report.Elapsed = end.Sub(start)
+}
+
+// handleMessage handles chat.postMessage and chat.postEphemeral by recording the answer to the message in whose
+// thread it's posted
+func (rec *answerRecorder) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rec.recordAnswer(r.PostForm.Get("thread_ts"))
+
+	rec.Lock()
+	timestamp := rec.nextTimestamp()
+	rec.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": r.PostForm.Get("channel"), "ts": timestamp, "message_ts": timestamp})
+}
+
+// handleReaction handles reactions.add by recording the answer to the message reacted to
+func (rec *answerRecorder) handleReaction(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rec.recordAnswer(r.PostForm.Get("timestamp"))
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, "{\"ok\": true}")
+}
+
+// handleUsersInfo handles users.info by returning a user with the requested id. The bot user also gets a bot ID in
+// its profile, which is how slackscot recognizes its own messages
+func handleUsersInfo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := slack.User{ID: r.Form.Get("user"), Name: r.Form.Get("user")}
+	if user.ID == slacktest.BotIDFromContext(r.Context()) {
+		user.Name = slacktest.BotNameFromContext(r.Context())
+		user.IsBot = true
+		user.Profile.BotID = botID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "user": user})
+}
@@ -0,0 +1,72 @@
+package loadtest_test
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/loadtest"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPongPlugin() (p *slackscot.Plugin) {
+	return plugin.New("pong").
+		WithHearAction(actions.NewHearAction().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "ping")
+			}).
+			WithUsage("ping").
+			WithDescription("Reply with pong").
+			WithAnswerer(func(m *slackscot.IncomingMessage) *slackscot.Answer {
+				return &slackscot.Answer{Text: "pong"}
+			}).
+			Build()).
+		Build()
+}
+
+func TestRun(t *testing.T) {
+	g := loadtest.New(loadtest.OptionRate(100), loadtest.OptionDuration(200*time.Millisecond), loadtest.OptionChannels(3), loadtest.OptionUsers(5),
+		loadtest.OptionMessages("ping"), loadtest.OptionCommandRatio(0.5), loadtest.OptionRandSource(rand.NewSource(1)))
+
+	report, err := g.Run(config.NewViperWithDefaults(), newPongPlugin())
+	require.NoError(t, err)
+
+	assert.Equal(t, 20, report.Sent)
+	assert.True(t, report.Commands > 0 && report.Commands < 20, "some messages should be help commands but got [%d]", report.Commands)
+	assert.Equal(t, 20, report.Answered)
+	assert.Equal(t, 20, report.Answers)
+	assert.Len(t, report.Latencies, 20)
+	assert.True(t, report.Percentile(50) <= report.Percentile(100))
+	assert.Contains(t, report.String(), "Sent [20] messages")
+}
+
+func TestRunWithUnansweredMessages(t *testing.T) {
+	g := loadtest.New(loadtest.OptionRate(50), loadtest.OptionDuration(100*time.Millisecond), loadtest.OptionMessages("chatter"),
+		loadtest.OptionCommandRatio(0), loadtest.OptionAnswerTimeout(500*time.Millisecond))
+
+	report, err := g.Run(config.NewViperWithDefaults(), newPongPlugin())
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, report.Sent)
+	assert.Equal(t, 0, report.Answered)
+	assert.Equal(t, time.Duration(0), report.Percentile(99))
+}
+
+func TestRunInvalid(t *testing.T) {
+	_, err := loadtest.New(loadtest.OptionRate(0)).Run(config.NewViperWithDefaults())
+	assert.EqualError(t, err, "Invalid load test: rate [0.000000], channels [10] and users [100] should be greater than 0 with at least one message and command")
+}
+
+func TestReportPercentile(t *testing.T) {
+	r := loadtest.Report{Latencies: []time.Duration{1 * time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond}}
+
+	assert.Equal(t, 1*time.Millisecond, r.Percentile(0))
+	assert.Equal(t, 2*time.Millisecond, r.Percentile(50))
+	assert.Equal(t, 4*time.Millisecond, r.Percentile(90))
+	assert.Equal(t, 4*time.Millisecond, r.Percentile(100))
+}
@@ -0,0 +1,84 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/langdetect"
+	"github.com/slack-go/slack"
+)
+
+// NewIncomingMessage creates a new IncomingMessage for a slack.Msg with the given normalized text. The userInfoFinder
+// and channelInfoFinder are used to resolve the user and channel info on demand and can be nil in which case
+// UserInfo and ChannelInfo return an error. This is mostly useful for testing as slackscot creates the IncomingMessage
+// instances handed to plugin actions
+func NewIncomingMessage(normalizedText string, m slack.Msg, userInfoFinder UserInfoFinder, channelInfoFinder ChannelInfoFinder) (inMsg IncomingMessage) {
+	inMsg.NormalizedText = normalizedText
+	inMsg.Msg = m
+	inMsg.userInfoFinder = userInfoFinder
+	inMsg.channelInfoFinder = channelInfoFinder
+
+	return inMsg
+}
+
+// Context returns the context of the action handling the message. It's canceled when slackscot shuts down or when
+// the action exceeds its deadline (see config.ActionTimeoutKey) so that long-running actions can stop early. It's
+// never nil
+func (m *IncomingMessage) Context() (ctx context.Context) {
+	if m.ctx == nil {
+		return context.Background()
+	}
+
+	return m.ctx
+}
+
+// IsDirectMessage returns true if the message was sent in a direct message channel with the bot
+func (m *IncomingMessage) IsDirectMessage() bool {
+	return isDirectMessage(m.Msg)
+}
+
+// IsThreadReply returns true if the message is a reply in a thread (as opposed to a message on the channel or
+// the thread's parent message)
+func (m *IncomingMessage) IsThreadReply() bool {
+	return m.ThreadTimestamp != "" && m.ThreadTimestamp != m.Timestamp
+}
+
+// ThreadID returns the identifier (timestamp) of the thread the message is part of. For messages not
+// in a thread, this is the message's own timestamp which is what a new thread would be started on
+func (m *IncomingMessage) ThreadID() (threadID string) {
+	threadID, _ = resolveThreadTimestamp(m.Msg)
+	return threadID
+}
+
+// TeamID returns the identifier of the team (workspace) the message was sent in, if known
+func (m *IncomingMessage) TeamID() (teamID string) {
+	return m.Team
+}
+
+// Language returns the detected language (ISO 639-1 code) of the message or an empty string if it can't be
+// determined. Plugins with localized answers can use it (or langdetect.Select) to pick the catalog to answer
+// with for each message
+func (m *IncomingMessage) Language() (language string) {
+	language, _ = langdetect.Detect(m.NormalizedText)
+	return language
+}
+
+// UserInfo resolves the info of the user who sent the message. Lookups go through the slackscot user info
+// cache (if enabled) so plugins don't need to do it themselves
+func (m *IncomingMessage) UserInfo() (user *slack.User, err error) {
+	if m.userInfoFinder == nil {
+		return nil, fmt.Errorf("no user info finder available to resolve user info for [%s]", m.User)
+	}
+
+	return m.userInfoFinder.GetUserInfo(m.User)
+}
+
+// ChannelInfo resolves the info of the channel the message was sent on. Lookups go through the slackscot channel
+// info cache (if enabled, see config.ChannelInfoCacheSizeKey) but cache misses call the slack API so plugins should
+// avoid calling it in Match functions
+func (m *IncomingMessage) ChannelInfo() (channel *slack.Channel, err error) {
+	if m.channelInfoFinder == nil {
+		return nil, fmt.Errorf("no channel info finder available to resolve channel info for [%s]", m.Channel)
+	}
+
+	return m.channelInfoFinder.GetConversationInfo(m.Channel, false)
+}
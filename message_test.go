@@ -0,0 +1,125 @@
+package slackscot_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type userInfoFinderMock struct {
+	users map[string]slack.User
+}
+
+func (u *userInfoFinderMock) GetUserInfo(userID string) (user *slack.User, err error) {
+	if user, ok := u.users[userID]; ok {
+		return &user, nil
+	}
+
+	return nil, fmt.Errorf("user [%s] not found", userID)
+}
+
+type channelInfoFinderMock struct {
+	channels map[string]slack.Channel
+}
+
+func (c *channelInfoFinderMock) GetConversationInfo(channelID string, includeLocale bool) (channel *slack.Channel, err error) {
+	if channel, ok := c.channels[channelID]; ok {
+		return &channel, nil
+	}
+
+	return nil, fmt.Errorf("channel [%s] not found", channelID)
+}
+
+func TestIncomingMessageContextHelpers(t *testing.T) {
+	tests := map[string]struct {
+		msg                   slack.Msg
+		expectedDirectMessage bool
+		expectedThreadReply   bool
+		expectedThreadID      string
+	}{
+		"ChannelMessage": {
+			msg:                   slack.Msg{Channel: "Cgeneral", Timestamp: "1546833210.036900"},
+			expectedDirectMessage: false,
+			expectedThreadReply:   false,
+			expectedThreadID:      "1546833210.036900",
+		},
+		"DirectMessage": {
+			msg:                   slack.Msg{Channel: "DFromAlphonse", Timestamp: "1546833210.036900"},
+			expectedDirectMessage: true,
+			expectedThreadReply:   false,
+			expectedThreadID:      "1546833210.036900",
+		},
+		"ThreadParent": {
+			msg:                   slack.Msg{Channel: "Cgeneral", Timestamp: "1546833210.036900", ThreadTimestamp: "1546833210.036900"},
+			expectedDirectMessage: false,
+			expectedThreadReply:   false,
+			expectedThreadID:      "1546833210.036900",
+		},
+		"ThreadReply": {
+			msg:                   slack.Msg{Channel: "Cgeneral", Timestamp: "1546833214.036900", ThreadTimestamp: "1546833210.036900"},
+			expectedDirectMessage: false,
+			expectedThreadReply:   true,
+			expectedThreadID:      "1546833210.036900",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			inMsg := slackscot.NewIncomingMessage("hello", tc.msg, nil, nil)
+
+			assert.Equal(t, tc.expectedDirectMessage, inMsg.IsDirectMessage())
+			assert.Equal(t, tc.expectedThreadReply, inMsg.IsThreadReply())
+			assert.Equal(t, tc.expectedThreadID, inMsg.ThreadID())
+		})
+	}
+}
+
+func TestIncomingMessageTeamID(t *testing.T) {
+	inMsg := slackscot.NewIncomingMessage("hello", slack.Msg{Team: "T1234"}, nil, nil)
+
+	assert.Equal(t, "T1234", inMsg.TeamID())
+}
+
+func TestIncomingMessageLanguage(t *testing.T) {
+	inMsg := slackscot.NewIncomingMessage("merci pour le coup de main, c'est top", slack.Msg{}, nil, nil)
+	assert.Equal(t, "fr", inMsg.Language())
+
+	inMsg = slackscot.NewIncomingMessage("lgtm", slack.Msg{}, nil, nil)
+	assert.Equal(t, "", inMsg.Language())
+}
+
+func TestIncomingMessageUserAndChannelInfo(t *testing.T) {
+	uf := &userInfoFinderMock{users: map[string]slack.User{"U1234": {ID: "U1234", RealName: "Alphonse Desjardins"}}}
+	cf := &channelInfoFinderMock{channels: map[string]slack.Channel{"Cgeneral": {GroupConversation: slack.GroupConversation{Name: "general"}}}}
+
+	inMsg := slackscot.NewIncomingMessage("hello", slack.Msg{Channel: "Cgeneral", User: "U1234"}, uf, cf)
+
+	user, err := inMsg.UserInfo()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Alphonse Desjardins", user.RealName)
+	}
+
+	channel, err := inMsg.ChannelInfo()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "general", channel.Name)
+	}
+}
+
+func TestIncomingMessageUserAndChannelInfoWithoutFinders(t *testing.T) {
+	inMsg := slackscot.NewIncomingMessage("hello", slack.Msg{Channel: "Cgeneral", User: "U1234"}, nil, nil)
+
+	_, err := inMsg.UserInfo()
+	assert.EqualError(t, err, "no user info finder available to resolve user info for [U1234]")
+
+	_, err = inMsg.ChannelInfo()
+	assert.EqualError(t, err, "no channel info finder available to resolve channel info for [Cgeneral]")
+}
+
+func TestIncomingMessageContextDefaultsToBackground(t *testing.T) {
+	m := slackscot.NewIncomingMessage("hello", slack.Msg{}, nil, nil)
+
+	assert.Equal(t, context.Background(), m.Context())
+}
@@ -0,0 +1,68 @@
+package slackscot
+
+import (
+	"sync"
+)
+
+// messageSequencer runs the operations on the responses to a triggering message (sending, updating and deleting
+// them) one at a time, in the order they were submitted. Messages of a channel are already processed in order but
+// progress tasks update their answers in the background so, without it, an update could reach slack after the answer
+// was deleted following the deletion of its triggering message (failing and leaving a ghost message if it was sent
+// again)
+type messageSequencer struct {
+	mutex sync.Mutex
+
+	// queues holds the turns of the operations waiting on each triggering message, the first one being the running
+	// operation's. Queues are removed once empty
+	queues map[SlackMessageID][]chan struct{}
+}
+
+// newMessageSequencer creates a new messageSequencer
+func newMessageSequencer() (ms *messageSequencer) {
+	ms = new(messageSequencer)
+	ms.queues = make(map[SlackMessageID][]chan struct{})
+
+	return ms
+}
+
+// do runs the operation once the operations submitted before it for the same triggering message are done. Operations
+// must not submit other operations for the same triggering message since they would wait forever
+func (ms *messageSequencer) do(msgID SlackMessageID, op func()) {
+	<-ms.enqueue(msgID)
+	defer ms.next(msgID)
+
+	op()
+}
+
+// enqueue adds a turn at the end of the queue of the triggering message. The turn is closed when it's its operation's
+// turn to run
+func (ms *messageSequencer) enqueue(msgID SlackMessageID) (turn chan struct{}) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	turn = make(chan struct{})
+	queue := ms.queues[msgID]
+	ms.queues[msgID] = append(queue, turn)
+
+	if len(queue) == 0 {
+		close(turn)
+	}
+
+	return turn
+}
+
+// next removes the turn of the operation done from the queue of the triggering message and gives the turn to the
+// next operation, if any
+func (ms *messageSequencer) next(msgID SlackMessageID) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	queue := ms.queues[msgID][1:]
+	if len(queue) == 0 {
+		delete(ms.queues, msgID)
+		return
+	}
+
+	ms.queues[msgID] = queue
+	close(queue[0])
+}
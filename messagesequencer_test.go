@@ -0,0 +1,146 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForQueueLength waits until the queue of the triggering message holds the given number of turns
+func waitForQueueLength(t *testing.T, ms *messageSequencer, msgID SlackMessageID, length int) {
+	queueLength := func() int {
+		ms.mutex.Lock()
+		defer ms.mutex.Unlock()
+
+		return len(ms.queues[msgID])
+	}
+
+	for deadline := time.Now().Add(time.Second); queueLength() != length; time.Sleep(time.Millisecond) {
+		require.True(t, time.Now().Before(deadline), "queue of [%s] should have %d turns but has %d", msgID, length, queueLength())
+	}
+}
+
+func TestMessageSequencerRunsOperationsInOrder(t *testing.T) {
+	ms := newMessageSequencer()
+	msgID := SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}
+
+	var mutex sync.Mutex
+	var ops []string
+	record := func(op string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		ops = append(ops, op)
+	}
+
+	release := make(chan struct{})
+	var done sync.WaitGroup
+	done.Add(3)
+	go ms.do(msgID, func() {
+		defer done.Done()
+
+		<-release
+		record("send")
+	})
+	waitForQueueLength(t, ms, msgID, 1)
+
+	go ms.do(msgID, func() {
+		defer done.Done()
+		record("update")
+	})
+	waitForQueueLength(t, ms, msgID, 2)
+
+	go ms.do(msgID, func() {
+		defer done.Done()
+		record("delete")
+	})
+	waitForQueueLength(t, ms, msgID, 3)
+
+	// Operations on other triggering messages don't wait
+	ms.do(SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2}, func() {
+		record("other")
+	})
+
+	close(release)
+	done.Wait()
+
+	assert.Equal(t, []string{"other", "send", "update", "delete"}, ops)
+	waitForQueueLength(t, ms, msgID, 0)
+}
+
+// slowUpdateRecorder records the updates and deletions of messages, in order. Updates signal that they started and
+// take a while to complete
+type slowUpdateRecorder struct {
+	*progressRecorder
+	updating chan struct{}
+
+	mutex sync.Mutex
+	calls []string
+}
+
+func (r *slowUpdateRecorder) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	close(r.updating)
+	time.Sleep(50 * time.Millisecond)
+
+	r.record("update " + applySlackOptions(options...).Get("text"))
+	return channelID, timestamp, "", nil
+}
+
+func (r *slowUpdateRecorder) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	r.record("delete " + channelID + "/" + timestamp)
+	return channelID, timestamp, nil
+}
+
+func (r *slowUpdateRecorder) record(call string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func TestDeletionOfTriggeringMessageWaitsForProgressUpdate(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := &slowUpdateRecorder{progressRecorder: new(progressRecorder), updating: make(chan struct{})}
+
+	answer := AnswerWithProgress("Syncing", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		return &Answer{Text: "Synced"}, nil
+	})
+
+	s.sendOutgoingMessages(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, []OutgoingMessage{newProgressOutgoingMessage(answer)})
+
+	// Delete the triggering message while the final update of the answer is in flight
+	<-recorder.updating
+	s.processDeletedMessage(recorder, slack.MessageEvent{Msg: slack.Msg{Type: "message", SubType: "message_deleted", Channel: "Cgeneral", DeletedTimestamp: timestamp1}})
+	waitForProgressTasks(t, s)
+
+	assert.Equal(t, []string{"update <@Alphonse>: ✅ Synced", "delete Cgeneral/1546833214.036900"}, recorder.calls)
+}
+
+func TestProgressUpdateQueuedBehindDeletionSkipped(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := &slowUpdateRecorder{progressRecorder: new(progressRecorder), updating: make(chan struct{})}
+	triggeringMsgID := SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}
+
+	release := make(chan struct{})
+	answer := AnswerWithProgress("Syncing", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		<-release
+		return &Answer{Text: "Synced"}, nil
+	})
+
+	s.sendOutgoingMessages(recorder, triggeringMsgID, []OutgoingMessage{newProgressOutgoingMessage(answer)})
+
+	// Hold the triggering message's turn (like a deletion in progress) until the final update waits for its turn
+	s.messageSequencer.do(triggeringMsgID, func() {
+		close(release)
+		waitForQueueLength(t, s.messageSequencer, triggeringMsgID, 2)
+
+		s.stopProgressTask(SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2})
+	})
+	waitForProgressTasks(t, s)
+
+	assert.Empty(t, recorder.calls)
+}
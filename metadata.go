@@ -0,0 +1,64 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"net/url"
+)
+
+// invalidMetadataOpt is the name of the option set on answers with metadata that couldn't be encoded, holding the
+// encoding error
+const invalidMetadataOpt = "invalidMetadata"
+
+// MessageMetadata is structured metadata attached to an answer (see AnswerWithMetadata). It isn't visible to users but
+// is included in the message as seen by the Events API and other apps so that automations can correlate a bot's messages
+// with the events they're about (i.e. an incident_declared event with the incident's id).
+//
+// See https://api.slack.com/metadata/using
+type MessageMetadata struct {
+	// EventType is the type of the event the message is about (i.e. incident_declared)
+	EventType string `json:"event_type"`
+
+	// EventPayload holds the structured data of the event. Values must be json-encodable
+	EventPayload map[string]interface{} `json:"event_payload"`
+}
+
+// AnswerWithMetadata attaches metadata to the answer. Metadata is kept when answers are updated following edits of their
+// triggering messages. Ephemeral messages don't support metadata so ephemeral answers are sent without it
+func AnswerWithMetadata(metadata MessageMetadata) AnswerOption {
+	return func(sendOpts map[string]string) {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			sendOpts[invalidMetadataOpt] = err.Error()
+			return
+		}
+
+		if metadata.EventType == "" {
+			sendOpts[invalidMetadataOpt] = "metadata needs an event type"
+			return
+		}
+
+		delete(sendOpts, invalidMetadataOpt)
+		sendOpts[MetadataOpt] = string(encoded)
+	}
+}
+
+// metadataMsgOption returns the message option attaching the answer's metadata, if any, to the message sent with the
+// given mode (i.e. slack.MsgOptionPost). Slack's client doesn't support metadata so the parameter is set directly. That
+// resets the endpoint the message is sent to which is why it's always followed by the sending mode and must come before
+// other options changing it (i.e. slack.MsgOptionSchedule)
+func metadataMsgOption(sendOpts map[string]string, mode slack.MsgOption) (option slack.MsgOption, err error) {
+	if reason, invalid := sendOpts[invalidMetadataOpt]; invalid {
+		return nil, fmt.Errorf("invalid metadata: %s", reason)
+	}
+
+	metadata, ok := sendOpts[MetadataOpt]
+	if !ok {
+		return nil, nil
+	}
+
+	return slack.MsgOptionCompose(slack.UnsafeMsgOptionEndpoint("", func(values url.Values) {
+		values.Set("metadata", metadata)
+	}), mode), nil
+}
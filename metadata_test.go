@@ -0,0 +1,109 @@
+package slackscot
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newIncidentDeclarationPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "declarer"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "declare")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			options := []AnswerOption{AnswerWithMetadata(MessageMetadata{EventType: "incident_declared", EventPayload: map[string]interface{}{"id": 42, "severity": strings.TrimPrefix(m.NormalizedText, "declare ")}})}
+			switch {
+			case strings.HasSuffix(m.NormalizedText, "later"):
+				options = append(options, AnswerWithDelay(time.Hour))
+			case strings.HasSuffix(m.NormalizedText, "quietly"):
+				options = append(options, AnswerEphemeral(m.User))
+			}
+
+			return &Answer{Text: "Incident declared", Options: options}
+		},
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "mystery")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Something happened", Options: []AnswerOption{AnswerWithMetadata(MessageMetadata{EventPayload: map[string]interface{}{"id": 42}})}}
+		},
+	}}
+
+	return p
+}
+
+// applySlackOptionsWithEndpoint applies the message options and returns the endpoint the message would be sent to
+// along with its values
+func applySlackOptionsWithEndpoint(opts ...slack.MsgOption) (endpoint string, vals map[string][]string) {
+	endpoint, vals, _ = slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", opts...)
+	return endpoint, vals
+}
+
+func TestAnswerWithMetadata(t *testing.T) {
+	sentMsgs, updatedMsgs, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newIncidentDeclarationPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "declare sev2", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage("declare sev1", "Alphonse", timestamp1))),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		endpoint, vals := applySlackOptionsWithEndpoint(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "https://slack.com/api/chat.postMessage", endpoint)
+		assert.Equal(t, []string{`{"event_type":"incident_declared","event_payload":{"id":42,"severity":"sev2"}}`}, vals["metadata"])
+		assert.Equal(t, []string{"Incident declared"}, vals["text"])
+	}
+
+	if assert.Len(t, updatedMsgs, 1) {
+		endpoint, vals := applySlackOptionsWithEndpoint(updatedMsgs[0].msgOptions...)
+		assert.Equal(t, "https://slack.com/api/chat.update", endpoint)
+		assert.Equal(t, []string{`{"event_type":"incident_declared","event_payload":{"id":42,"severity":"sev1"}}`}, vals["metadata"])
+		assert.Equal(t, []string{updatedMsgs[0].timestamp}, vals["ts"])
+	}
+}
+
+func TestScheduledAnswerWithMetadata(t *testing.T) {
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newIncidentDeclarationPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "declare sev3 later", "Alphonse", timestamp1)),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		endpoint, vals := applySlackOptionsWithEndpoint(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "https://slack.com/api/chat.scheduleMessage", endpoint)
+		assert.Equal(t, []string{`{"event_type":"incident_declared","event_payload":{"id":42,"severity":"sev3 later"}}`}, vals["metadata"])
+	}
+}
+
+func TestEphemeralAnswerSentWithoutMetadata(t *testing.T) {
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, newIncidentDeclarationPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "declare sev3 quietly", "Alphonse", timestamp1)),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		endpoint, vals := applySlackOptionsWithEndpoint(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "https://slack.com/api/chat.postEphemeral", endpoint)
+		assert.Empty(t, vals["metadata"])
+	}
+
+	assert.Contains(t, logs, "Ephemeral messages don't support metadata so sending answer of [declarer.hearAction[0]] without it")
+}
+
+func TestAnswerWithInvalidMetadataNotSent(t *testing.T) {
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, newIncidentDeclarationPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "mystery", "Alphonse", timestamp1)),
+	})
+
+	assert.Empty(t, sentMsgs)
+	assert.Contains(t, logs, "Unable to send new message triggered by [Cgeneral/1546833210.036900]: answer of [declarer.hearAction[1]] not sent: invalid metadata: metadata needs an event type")
+}
+
+func TestAnswerWithMetadataOfUnencodablePayload(t *testing.T) {
+	sendOpts := ApplyAnswerOpts(AnswerWithMetadata(MessageMetadata{EventType: "broken", EventPayload: map[string]interface{}{"ch": make(chan int)}}))
+
+	_, err := metadataMsgOption(sendOpts, slack.MsgOptionPost())
+	assert.EqualError(t, err, "invalid metadata: json: unsupported type: chan int")
+}
@@ -0,0 +1,80 @@
+package slackscot
+
+// ActionCall describes a command or hear action handling a message, as seen by middleware
+type ActionCall struct {
+	// Plugin is the name of the plugin of the action
+	Plugin string
+
+	// ActionType is the type of action (command or hearAction)
+	ActionType string
+
+	// ActionID identifies the action (i.e. karma.command[0], see getActionID)
+	ActionID string
+
+	// Action is the definition of the action
+	Action ActionDefinition
+}
+
+// ActionHandler handles a message matched by an action and returns its answers. Nil answers are ignored
+type ActionHandler func(call ActionCall, m *IncomingMessage) (answers []*Answer)
+
+// Middleware wraps the handling of messages by actions for cross-cutting concerns like logging, authorization checks,
+// metrics or panic recovery. A middleware can run code before and after calling next, change the answers it returns or
+// not call it at all to skip the action (i.e. for an unauthorized user). For example, a middleware logging the
+// duration of all actions:
+//
+//	s.UseMiddleware(func(next slackscot.ActionHandler) slackscot.ActionHandler {
+//		return func(call slackscot.ActionCall, m *slackscot.IncomingMessage) []*slackscot.Answer {
+//			defer func(start time.Time) {
+//				log.Printf("[%s] took %s", call.ActionID, time.Since(start))
+//			}(time.Now())
+//
+//			return next(call, m)
+//		}
+//	})
+//
+// Middleware runs within the action's timeout (see config.ActionTimeoutKey) and the message's Context is canceled
+// the same way
+type Middleware func(next ActionHandler) ActionHandler
+
+// UseMiddleware adds middleware wrapping every command and hear action of all plugins. Middleware added first runs
+// first (outermost) and slackscot middleware runs around the middleware of plugins (see Plugin.Middleware) which
+// runs around the middleware of actions (see ActionDefinition.Middleware). This should be invoked prior to calling Run
+func (s *Slackscot) UseMiddleware(middleware ...Middleware) {
+	s.middleware = append(s.middleware, middleware...)
+}
+
+// answerAction is the innermost ActionHandler returning the answers of the action itself
+func answerAction(call ActionCall, m *IncomingMessage) (answers []*Answer) {
+	return call.Action.Answers(m)
+}
+
+// chainMiddleware returns the handler running the middleware of slackscot, of the plugin and of the action around
+// the action itself
+func (s *Slackscot) chainMiddleware(p *Plugin, action ActionDefinition) (handler ActionHandler) {
+	handler = answerAction
+
+	chain := make([]Middleware, 0, len(s.middleware)+len(action.Middleware))
+	chain = append(chain, s.middleware...)
+	if p != nil {
+		chain = append(chain, p.Middleware...)
+	}
+	chain = append(chain, action.Middleware...)
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i](handler)
+	}
+
+	return handler
+}
+
+// handleAction runs the action on the message through the middleware chain. Nil answers are left out
+func (s *Slackscot) handleAction(call ActionCall, p *Plugin, m *IncomingMessage) (answers []*Answer) {
+	for _, answer := range s.chainMiddleware(p, call.Action)(call, m) {
+		if answer != nil {
+			answers = append(answers, answer)
+		}
+	}
+
+	return answers
+}
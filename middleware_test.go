@@ -0,0 +1,191 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tracingMiddleware records its name in the trace before and after calling the next handler
+func tracingMiddleware(name string, trace *[]string) Middleware {
+	return func(next ActionHandler) ActionHandler {
+		return func(call ActionCall, m *IncomingMessage) []*Answer {
+			*trace = append(*trace, fmt.Sprintf("%s>", name))
+			answers := next(call, m)
+			*trace = append(*trace, fmt.Sprintf("<%s", name))
+
+			return answers
+		}
+	}
+}
+
+func newMiddlewareTestPlugin(trace *[]string, middleware ...Middleware) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "chatty"
+	p.Middleware = middleware
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "hello")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			*trace = append(*trace, "action")
+			return &Answer{Text: "Hi there"}
+		},
+	}}
+
+	return p
+}
+
+func newMiddlewareTestMessage(text string, user string) (m IncomingMessage) {
+	return NewIncomingMessage(text, slack.Msg{Channel: "Cgeneral", User: user, Text: text, Timestamp: timestamp1}, nil, nil)
+}
+
+func TestMiddlewareChainOrder(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	trace := make([]string, 0)
+	s.UseMiddleware(tracingMiddleware("first", &trace), tracingMiddleware("second", &trace))
+
+	p := newMiddlewareTestPlugin(&trace, tracingMiddleware("plugin", &trace))
+	p.Commands[0].Middleware = []Middleware{tracingMiddleware("action", &trace)}
+
+	m := newMiddlewareTestMessage("hello", "Alphonse")
+	outMsgs, _ := s.tryPluginActions(p, commandType, p.Commands, m, m, send)
+
+	if assert.Len(t, outMsgs, 1) {
+		assert.Equal(t, "Hi there", outMsgs[0].Answer.Text)
+	}
+	assert.Equal(t, []string{"first>", "second>", "plugin>", "action>", "action", "<action", "<plugin", "<second", "<first"}, trace)
+}
+
+func TestMiddlewareSkipsAction(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	var calls []ActionCall
+	s.UseMiddleware(func(next ActionHandler) ActionHandler {
+		return func(call ActionCall, m *IncomingMessage) []*Answer {
+			calls = append(calls, call)
+			if m.User != "Alphonse" {
+				return []*Answer{{Text: fmt.Sprintf("Sorry <@%s>, you're not allowed to do that", m.User)}, nil}
+			}
+
+			return next(call, m)
+		}
+	})
+
+	trace := make([]string, 0)
+	p := newMiddlewareTestPlugin(&trace)
+
+	m := newMiddlewareTestMessage("hello", "Bernard")
+	outMsgs, _ := s.tryPluginActions(p, commandType, p.Commands, m, m, send)
+
+	if assert.Len(t, outMsgs, 1) {
+		assert.Equal(t, "Sorry <@Bernard>, you're not allowed to do that", outMsgs[0].Answer.Text)
+	}
+	assert.Empty(t, trace)
+
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "chatty", calls[0].Plugin)
+		assert.Equal(t, commandType, calls[0].ActionType)
+		assert.Equal(t, "chatty.command[0]", calls[0].ActionID)
+	}
+}
+
+func TestMiddlewareRecoversFromPanic(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionTimeoutKey, time.Second)
+
+	s, err := New("chickadee", v)
+	require.NoError(t, err)
+
+	s.UseMiddleware(func(next ActionHandler) ActionHandler {
+		return func(call ActionCall, m *IncomingMessage) (answers []*Answer) {
+			defer func() {
+				if r := recover(); r != nil {
+					answers = []*Answer{{Text: fmt.Sprintf("Oops, [%s] failed: %v", call.ActionID, r)}}
+				}
+			}()
+
+			return next(call, m)
+		}
+	})
+
+	p := new(Plugin)
+	p.Name = "fragile"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return true
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			panic("boom")
+		},
+	}}
+
+	m := newMiddlewareTestMessage("anything", "Alphonse")
+	outMsgs, _ := s.tryPluginActions(p, hearActionType, p.HearActions, m, m, send)
+
+	if assert.Len(t, outMsgs, 1) {
+		assert.Equal(t, "Oops, [fragile.hearAction[0]] failed: boom", outMsgs[0].Answer.Text)
+	}
+}
+
+func TestBuilderWithMiddleware(t *testing.T) {
+	s, err := NewBot("chickadee", config.NewViperWithDefaults()).
+		WithMiddleware(func(next ActionHandler) ActionHandler {
+			return next
+		}).
+		Build()
+	require.NoError(t, err)
+
+	assert.Len(t, s.middleware, 1)
+}
+
+func TestAdminOnlyActionRefusedToNonAdmins(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.AdminUsersKey, []string{"Alphonse"})
+
+	s, err := New("chickadee", v)
+	require.NoError(t, err)
+
+	var calls []ActionCall
+	s.UseMiddleware(func(next ActionHandler) ActionHandler {
+		return func(call ActionCall, m *IncomingMessage) []*Answer {
+			calls = append(calls, call)
+			return next(call, m)
+		}
+	})
+
+	trace := make([]string, 0)
+	p := newMiddlewareTestPlugin(&trace)
+	p.Commands[0].AdminOnly = true
+	p.Commands[0].SideEffects = true
+
+	m := newMiddlewareTestMessage("hello", "Bernard")
+	outMsgs, _ := s.tryPluginActions(p, commandType, p.Commands, m, m, send)
+
+	if assert.Len(t, outMsgs, 1) {
+		assert.Equal(t, "Sorry, only admins can do that :no_entry_sign:", outMsgs[0].Answer.Text)
+		assert.Equal(t, "Bernard", ApplyAnswerOpts(outMsgs[0].Answer.Options...)[EphemeralAnswerToOpt])
+	}
+	assert.Empty(t, trace)
+	assert.Empty(t, calls)
+
+	// Refusals don't count as runs of actions with side effects
+	assert.False(t, s.hasRunAction("Cgeneral", timestamp1, "chatty.command[0]"))
+
+	m = newMiddlewareTestMessage("hello", "Alphonse")
+	outMsgs, _ = s.tryPluginActions(p, commandType, p.Commands, m, m, send)
+
+	if assert.Len(t, outMsgs, 1) {
+		assert.Equal(t, "Hi there", outMsgs[0].Answer.Text)
+	}
+	assert.Equal(t, []string{"action"}, trace)
+	assert.Len(t, calls, 1)
+}
@@ -0,0 +1,37 @@
+package slackscot
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// trailingPunctuation holds the characters trimmed from the end of a command when normalizing
+	trailingPunctuation = ".!?,;…"
+)
+
+// slackEntityRegex matches slack-formatted entities such as user mentions (<@U1234>), channel
+// references (<#C1234|general>) and links. Those are case-sensitive and must be preserved
+// as-is by normalization
+var slackEntityRegex = regexp.MustCompile("<[^>]*>")
+
+// NormalizeCommandText returns a normalized version of a command text so that small variations
+// in how users type commands don't affect matching. Normalization does the following:
+//  1. Case folding (everything is lowercased except for slack entities like <@U1234>)
+//  2. Collapsing of consecutive whitespace into a single space (and trimming of leading/trailing spaces)
+//  3. Removal of trailing punctuation (i.e. "Help!" becomes "help")
+func NormalizeCommandText(text string) (normalized string) {
+	var b strings.Builder
+
+	last := 0
+	for _, loc := range slackEntityRegex.FindAllStringIndex(text, -1) {
+		b.WriteString(strings.ToLower(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(strings.ToLower(text[last:]))
+
+	normalized = strings.Join(strings.Fields(b.String()), " ")
+
+	return strings.TrimRight(normalized, trailingPunctuation)
+}
@@ -0,0 +1,53 @@
+package slackscot_test
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeCommandText(t *testing.T) {
+	tests := map[string]struct {
+		text     string
+		expected string
+	}{
+		"AlreadyNormalized": {
+			text:     "help",
+			expected: "help",
+		},
+		"CaseFolding": {
+			text:     "HeLP",
+			expected: "help",
+		},
+		"TrailingPunctuation": {
+			text:     "Help!",
+			expected: "help",
+		},
+		"ManyTrailingPunctuationCharacters": {
+			text:     "help?!...",
+			expected: "help",
+		},
+		"InnerPunctuationPreserved": {
+			text:     "trigger on hello, world with hi!",
+			expected: "trigger on hello, world with hi",
+		},
+		"WhitespaceCollapsing": {
+			text:     "  karma    top\t 5  ",
+			expected: "karma top 5",
+		},
+		"MentionsPreserved": {
+			text:     "Thanks <@U1234ABC> and <#C12AB|General>!",
+			expected: "thanks <@U1234ABC> and <#C12AB|General>",
+		},
+		"Empty": {
+			text:     "",
+			expected: "",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, slackscot.NormalizeCommandText(tc.text))
+		})
+	}
+}
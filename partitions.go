@@ -0,0 +1,75 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
+	"sort"
+)
+
+// Names of slackscot's own storers in partition manifests
+const (
+	responsesStorerName = "responses"
+	jobsStorerName      = "jobs"
+)
+
+// knownStorers returns all storers known to slackscot by name: its own (responses, feature flags, scheduled actions and
+// jobs) and the inspectable storers (see OptionInspectableStorer)
+func (s *Slackscot) knownStorers() (storers map[string]store.GlobalSiloStringStorer) {
+	storers = make(map[string]store.GlobalSiloStringStorer)
+	for name, storer := range s.inspectableStorers {
+		storers[name] = storer
+	}
+
+	for name, storer := range map[string]store.GlobalSiloStringStorer{responsesStorerName: s.responseStorer, featureFlagsPluginName: s.featureFlagStorer, scheduledActionsPluginName: s.scheduledActionStorer, jobsStorerName: s.jobStorer} {
+		if storer != nil {
+			storers[name] = storer
+		}
+	}
+
+	return storers
+}
+
+// ExportPartition exports all data of a partition (i.e. a channel or team id, see store.ExportPartition) from every
+// storer known to slackscot: its own storers and the inspectable ones (see OptionInspectableStorer). Manifests are
+// returned by storer name. Make plugin storers inspectable for their data to be included
+func (s *Slackscot) ExportPartition(partition string) (manifests map[string]store.PartitionManifest, err error) {
+	return s.forEachKnownStorer(partition, store.ExportPartition)
+}
+
+// DeletePartition deletes all data of a partition (i.e. when offboarding a channel or team, see store.DeletePartition)
+// from every storer known to slackscot and returns manifests of what was removed by storer name. On error, the
+// manifests list what was removed until then
+func (s *Slackscot) DeletePartition(partition string) (manifests map[string]store.PartitionManifest, err error) {
+	manifests, err = s.forEachKnownStorer(partition, store.DeletePartition)
+	for name, manifest := range manifests {
+		if len(manifest.Entries) > 0 {
+			s.log.Printf("Deleted [%d] entries of partition [%s] from storer [%s]\n", len(manifest.Entries), partition, name)
+		}
+	}
+
+	return manifests, err
+}
+
+// forEachKnownStorer applies the partition operation to every known storer, in order of name, and returns their
+// manifests by storer name. It stops at the first error
+func (s *Slackscot) forEachKnownStorer(partition string, operation func(storer store.GlobalSiloStringStorer, partition string) (store.PartitionManifest, error)) (manifests map[string]store.PartitionManifest, err error) {
+	storers := s.knownStorers()
+
+	names := make([]string, 0, len(storers))
+	for name := range storers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifests = make(map[string]store.PartitionManifest)
+	for _, name := range names {
+		manifest, err := operation(storers[name], partition)
+		manifests[name] = manifest
+
+		if err != nil {
+			return manifests, fmt.Errorf("Error with partition [%s] of storer [%s]: %v", partition, name, err)
+		}
+	}
+
+	return manifests, nil
+}
@@ -0,0 +1,42 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDeletePartitionFromKnownStorers(t *testing.T) {
+	responseStorer, cleanupResponses := newFeatureFlagStorer(t)
+	defer cleanupResponses()
+
+	karmaStorer, cleanupKarma := newFeatureFlagStorer(t)
+	defer cleanupKarma()
+
+	require.NoError(t, responseStorer.PutSiloString(responsesSilo, "Cgeneral/1546833210.036900", "{}"))
+	require.NoError(t, karmaStorer.PutSiloString("Cgeneral", "birds", "3"))
+	require.NoError(t, karmaStorer.PutSiloString("Crandom", "birds", "1"))
+
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionResponseStorer(responseStorer), OptionInspectableStorer("karma", karmaStorer))
+	require.NoError(t, err)
+
+	manifests, err := s.ExportPartition("Cgeneral")
+	require.NoError(t, err)
+	assert.Equal(t, []store.PartitionEntry{{Silo: "Cgeneral", Key: "birds", Value: "3"}}, manifests["karma"].Entries)
+	assert.Equal(t, []store.PartitionEntry{{Silo: responsesSilo, Key: "Cgeneral/1546833210.036900", Value: "{}"}}, manifests[responsesStorerName].Entries)
+
+	manifests, err = s.DeletePartition("Cgeneral")
+	require.NoError(t, err)
+	assert.Len(t, manifests, 2)
+	assert.Equal(t, []store.PartitionEntry{{Silo: "Cgeneral", Key: "birds"}}, manifests["karma"].Entries)
+
+	entries, err := karmaStorer.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"Crandom": {"birds": "1"}}, entries)
+
+	entries, err = responseStorer.GlobalScan()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -48,6 +48,7 @@ package plugin
 
 import (
 	"github.com/alexandre-normand/slackscot"
+	"net/http"
 )
 
 // PluginBuilder holds a plugin to build. This is used to set up
@@ -86,6 +87,109 @@ func (pb *PluginBuilder) WithCommandNamespacing() *PluginBuilder {
 	return pb
 }
 
+// WithCommandNormalization enables command text normalization for that plugin. See slackscot.NormalizeCommandText
+func (pb *PluginBuilder) WithCommandNormalization() *PluginBuilder {
+	pb.plugin.NormalizeCommands = true
+	return pb
+}
+
+// WithBotListening lets that plugin's commands and hear actions be triggered by messages from other bots
+func (pb *PluginBuilder) WithBotListening() *PluginBuilder {
+	pb.plugin.ListenToBots = true
+	return pb
+}
+
+// WithUserDataCollection marks that plugin as passively collecting data about users with the function deleting the
+// data about a user when they opt out, if any. See slackscot.UserConsent
+func (pb *PluginBuilder) WithUserDataCollection(purger slackscot.UserDataPurger) *PluginBuilder {
+	pb.plugin.CollectsUserData = true
+	pb.plugin.PurgeUserData = purger
+	return pb
+}
+
+// WithMiddleware adds middleware wrapping all of the plugin's commands and hear actions. See slackscot.Middleware
+func (pb *PluginBuilder) WithMiddleware(middleware ...slackscot.Middleware) *PluginBuilder {
+	pb.plugin.Middleware = append(pb.plugin.Middleware, middleware...)
+	return pb
+}
+
+// WithProvidedService adds a named service that this plugin makes available to other plugins
+func (pb *PluginBuilder) WithProvidedService(name string, service interface{}) *PluginBuilder {
+	if pb.plugin.Provides == nil {
+		pb.plugin.Provides = make(map[string]interface{})
+	}
+
+	pb.plugin.Provides[name] = service
+	return pb
+}
+
+// WithRequiredService declares a named service provided by another plugin that this plugin needs
+func (pb *PluginBuilder) WithRequiredService(name string) *PluginBuilder {
+	pb.plugin.Requires = append(pb.plugin.Requires, name)
+	return pb
+}
+
+// WithPriority sets the priority of the plugin's answers over those of other plugins when the answer
+// policy is priority (see config.AnswerPolicyKey). Higher priorities go first
+func (pb *PluginBuilder) WithPriority(priority int) *PluginBuilder {
+	pb.plugin.Priority = priority
+	return pb
+}
+
+// WithIdentity sets the username and icon the plugin's answers are posted with instead of the bot's. See slackscot.Identity
+func (pb *PluginBuilder) WithIdentity(identity slackscot.Identity) *PluginBuilder {
+	pb.plugin.Identity = &identity
+	return pb
+}
+
+// WithReplyBehavior overrides the threaded replies configuration for the plugin's answers. See slackscot.ReplyBehavior
+func (pb *PluginBuilder) WithReplyBehavior(replyBehavior slackscot.ReplyBehavior) *PluginBuilder {
+	pb.plugin.ReplyBehavior = &replyBehavior
+	return pb
+}
+
+// WithEventSubscription subscribes the plugin to events published on a topic by other plugins
+func (pb *PluginBuilder) WithEventSubscription(topic string, handler slackscot.EventHandler) *PluginBuilder {
+	pb.plugin.EventSubscriptions = append(pb.plugin.EventSubscriptions, slackscot.EventSubscription{Topic: topic, Handle: handler})
+	return pb
+}
+
+// WithWebhook adds a webhook to the plugin, served at /webhooks/<plugin name>/<path>. See slackscot.WebhookDefinition
+func (pb *PluginBuilder) WithWebhook(path string, handler http.HandlerFunc) *PluginBuilder {
+	pb.plugin.Webhooks = append(pb.plugin.Webhooks, slackscot.WebhookDefinition{Path: path, Handle: handler})
+	return pb
+}
+
+// WithReactionAction adds a reaction action to the plugin. See slackscot.ReactionActionDefinition
+func (pb *PluginBuilder) WithReactionAction(reactionAction slackscot.ReactionActionDefinition) *PluginBuilder {
+	pb.plugin.ReactionActions = append(pb.plugin.ReactionActions, reactionAction)
+	return pb
+}
+
+// WithChannelAction adds a channel action to the plugin. See slackscot.ChannelActionDefinition
+func (pb *PluginBuilder) WithChannelAction(channelAction slackscot.ChannelActionDefinition) *PluginBuilder {
+	pb.plugin.ChannelActions = append(pb.plugin.ChannelActions, channelAction)
+	return pb
+}
+
+// WithSlashCommand adds a slash command to the plugin. See slackscot.SlashCommandDefinition
+func (pb *PluginBuilder) WithSlashCommand(slashCommand slackscot.SlashCommandDefinition) *PluginBuilder {
+	pb.plugin.SlashCommands = append(pb.plugin.SlashCommands, slashCommand)
+	return pb
+}
+
+// WithWorkflowStep adds a custom Workflow Builder step to the plugin. See slackscot.WorkflowStepDefinition
+func (pb *PluginBuilder) WithWorkflowStep(step slackscot.WorkflowStepDefinition) *PluginBuilder {
+	pb.plugin.WorkflowSteps = append(pb.plugin.WorkflowSteps, step)
+	return pb
+}
+
+// WithJob adds a type of job the plugin can enqueue on its JobQueue. See slackscot.JobDefinition
+func (pb *PluginBuilder) WithJob(job slackscot.JobDefinition) *PluginBuilder {
+	pb.plugin.Jobs = append(pb.plugin.Jobs, job)
+	return pb
+}
+
 // WithScheduledAction adds a scheduled action to the plugin
 func (pb *PluginBuilder) WithScheduledAction(scheduledAction slackscot.ScheduledActionDefinition) *PluginBuilder {
 	pb.plugin.ScheduledActions = append(pb.plugin.ScheduledActions, scheduledAction)
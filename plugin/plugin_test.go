@@ -1,10 +1,15 @@
 package plugin_test
 
 import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/actions"
 	"github.com/alexandre-normand/slackscot/plugin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -76,3 +81,196 @@ func TestPluginWithCommandNamespacing(t *testing.T) {
 	require.NotNil(t, p)
 	assert.True(t, p.NamespaceCommands)
 }
+
+func TestPluginWithCommandNormalization(t *testing.T) {
+	p := plugin.New("loopy").
+		WithCommandNormalization().
+		Build()
+
+	require.NotNil(t, p)
+	assert.True(t, p.NormalizeCommands)
+}
+
+func TestPluginWithBotListening(t *testing.T) {
+	p := plugin.New("loopy").
+		WithBotListening().
+		Build()
+
+	require.NotNil(t, p)
+	assert.True(t, p.ListenToBots)
+}
+
+func TestPluginWithUserDataCollection(t *testing.T) {
+	purged := make([]string, 0)
+	p := plugin.New("tracker").
+		WithUserDataCollection(func(userID string) error {
+			purged = append(purged, userID)
+			return nil
+		}).
+		Build()
+
+	require.NotNil(t, p)
+	assert.True(t, p.CollectsUserData)
+	require.NoError(t, p.PurgeUserData("U21355"))
+	assert.Equal(t, []string{"U21355"}, purged)
+}
+
+func TestPluginWithMiddleware(t *testing.T) {
+	passThrough := func(next slackscot.ActionHandler) slackscot.ActionHandler {
+		return next
+	}
+
+	p := plugin.New("loopy").
+		WithMiddleware(passThrough).
+		WithMiddleware(passThrough, passThrough).
+		Build()
+
+	require.NotNil(t, p)
+	assert.Len(t, p.Middleware, 3)
+}
+
+func TestPluginWithProvidedServices(t *testing.T) {
+	p := plugin.New("loopy").
+		WithProvidedService("loopy.counter", 42).
+		WithProvidedService("loopy.name", "loopy").
+		Build()
+
+	require.NotNil(t, p)
+	assert.Equal(t, map[string]interface{}{"loopy.counter": 42, "loopy.name": "loopy"}, p.Provides)
+}
+
+func TestPluginWithRequiredServices(t *testing.T) {
+	p := plugin.New("loopy").
+		WithRequiredService("karma.reader").
+		WithRequiredService("loopy.counter").
+		Build()
+
+	require.NotNil(t, p)
+	assert.Equal(t, []string{"karma.reader", "loopy.counter"}, p.Requires)
+}
+
+func TestPluginWithEventSubscriptions(t *testing.T) {
+	received := make([]string, 0)
+
+	p := plugin.New("loopy").
+		WithEventSubscription("karma.changed", func(e slackscot.Event) {
+			received = append(received, e.Topic())
+		}).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.EventSubscriptions, 1)
+	assert.Equal(t, "karma.changed", p.EventSubscriptions[0].Topic)
+
+	p.EventSubscriptions[0].Handle(testEvent{})
+	assert.Equal(t, []string{"test.event"}, received)
+}
+
+type testEvent struct{}
+
+func (e testEvent) Topic() string {
+	return "test.event"
+}
+
+func TestPluginWithPriority(t *testing.T) {
+	p := plugin.New("loopy").
+		WithPriority(10).
+		Build()
+
+	require.NotNil(t, p)
+	assert.Equal(t, 10, p.Priority)
+}
+
+func TestPluginWithWebhook(t *testing.T) {
+	p := plugin.New("loopy").
+		WithWebhook("build", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.Webhooks, 1)
+	assert.Equal(t, "build", p.Webhooks[0].Path)
+
+	rec := httptest.NewRecorder()
+	p.Webhooks[0].Handle(rec, httptest.NewRequest("POST", "/webhooks/loopy/build", nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestPluginWithJob(t *testing.T) {
+	p := plugin.New("loopy").
+		WithJob(slackscot.JobDefinition{Name: "sync", MaxAttempts: 3, Handler: func(ctx context.Context, job slackscot.Job) error {
+			return fmt.Errorf("sync of [%s] failed", job.Payload)
+		}}).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.Jobs, 1)
+	assert.Equal(t, "sync", p.Jobs[0].Name)
+	assert.Equal(t, 3, p.Jobs[0].MaxAttempts)
+	assert.EqualError(t, p.Jobs[0].Handler(context.Background(), slackscot.Job{Payload: "calendar"}), "sync of [calendar] failed")
+}
+
+func TestPluginWithSlashCommand(t *testing.T) {
+	p := plugin.New("loopy").
+		WithSlashCommand(slackscot.SlashCommandDefinition{Command: "/loop", Usage: "<times>", Description: "Loop a few times", Answer: func(cmd *slackscot.SlashCommand) *slackscot.SlashCommandAnswer {
+			return &slackscot.SlashCommandAnswer{Text: "looping " + cmd.Text}
+		}}).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.SlashCommands, 1)
+	assert.Equal(t, "/loop", p.SlashCommands[0].Command)
+
+	cmd := slackscot.SlashCommand{}
+	cmd.Text = "twice"
+	assert.Equal(t, &slackscot.SlashCommandAnswer{Text: "looping twice"}, p.SlashCommands[0].Answer(&cmd))
+}
+
+func TestPluginWithReactionAction(t *testing.T) {
+	p := plugin.New("loopy").
+		WithReactionAction(slackscot.ReactionActionDefinition{Usage: "react with :repeat:", Description: "Loop again"}).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.ReactionActions, 1)
+	assert.Equal(t, "react with :repeat:", p.ReactionActions[0].Usage)
+}
+
+func TestPluginWithChannelAction(t *testing.T) {
+	p := plugin.New("welcome").
+		WithChannelAction(slackscot.ChannelActionDefinition{Usage: "join a channel", Description: "Welcome new members"}).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.ChannelActions, 1)
+	assert.Equal(t, "join a channel", p.ChannelActions[0].Usage)
+}
+
+func TestPluginWithIdentity(t *testing.T) {
+	p := plugin.New("incident").
+		WithIdentity(slackscot.Identity{Username: "Incident Bot", IconEmoji: ":rotating_light:"}).
+		Build()
+
+	require.NotNil(t, p)
+	assert.Equal(t, &slackscot.Identity{Username: "Incident Bot", IconEmoji: ":rotating_light:"}, p.Identity)
+}
+
+func TestPluginWithReplyBehavior(t *testing.T) {
+	p := plugin.New("reporter").
+		WithReplyBehavior(slackscot.ReplyBehavior{ThreadedReplies: true}).
+		Build()
+
+	require.NotNil(t, p)
+	assert.Equal(t, &slackscot.ReplyBehavior{ThreadedReplies: true}, p.ReplyBehavior)
+}
+
+func TestPluginWithWorkflowStep(t *testing.T) {
+	p := plugin.New("karma").
+		WithWorkflowStep(slackscot.WorkflowStepDefinition{CallbackID: "give_karma", Inputs: []slackscot.WorkflowStepInput{{Name: "thing", Label: "Thing"}}}).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.WorkflowSteps, 1)
+	assert.Equal(t, "give_karma", p.WorkflowSteps[0].CallbackID)
+}
@@ -0,0 +1,199 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/spf13/viper"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	pluginChannelsPluginName = "pluginChannels"
+	pluginChannelsSilo       = "pluginChannels"
+)
+
+var pluginChannelsOverrideRegex = regexp.MustCompile("(?i)\\A(enable|disable|reset) ([\\w.-]+)(?: in (?:(here)|<#(\\w+)(?:\\|[^>]*)?>))?\\z")
+
+// pluginChannels tells whether plugins run in channels. Plugins run in all channels unless restricted to some
+// channels or disabled in others in their configuration (see config.GetPluginChannels). Admins (see
+// config.AdminUsersKey) can also enable or disable plugins at runtime, with overrides persisted when slackscot is given
+// a storer with OptionFeatureFlagStorer. Like feature flags, overrides for a channel take precedence over overrides for all
+// channels which take precedence over the configuration
+type pluginChannels struct {
+	config *viper.Viper
+	storer store.GlobalSiloStringStorer
+
+	sync.RWMutex
+	overrides map[string]bool
+}
+
+// newPluginChannels creates the pluginChannels configured with the overrides persisted in the storer, if any
+func newPluginChannels(v *viper.Viper, storer store.GlobalSiloStringStorer) (pc *pluginChannels, err error) {
+	pc = new(pluginChannels)
+	pc.config = v
+	pc.storer = storer
+	pc.overrides = make(map[string]bool)
+
+	if storer == nil {
+		return pc, nil
+	}
+
+	entries, err := storer.ScanSilo(pluginChannelsSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, rawValue := range entries {
+		if pc.overrides[key], err = strconv.ParseBool(rawValue); err != nil {
+			return nil, fmt.Errorf("Invalid override [%s] for plugin channels [%s]: %v", rawValue, key, err)
+		}
+	}
+
+	return pc, nil
+}
+
+// isEnabled returns true if the plugin runs in the channel
+func (pc *pluginChannels) isEnabled(pluginName string, channelID string) (enabled bool) {
+	pc.RLock()
+	defer pc.RUnlock()
+
+	if enabled, exists := pc.overrides[overrideKey(pluginName, channelID)]; exists {
+		return enabled
+	}
+
+	if enabled, exists := pc.overrides[overrideKey(pluginName, allChannelsScope)]; exists {
+		return enabled
+	}
+
+	allowed, denied := config.GetPluginChannels(pc.config, pluginName)
+	if containsString(denied, channelID) {
+		return false
+	}
+
+	return len(allowed) == 0 || containsString(allowed, channelID)
+}
+
+// override sets an override enabling or disabling a plugin for a scope (a channel id or allChannelsScope). The override
+// is persisted if there's a storer and only lasts until slackscot restarts otherwise
+func (pc *pluginChannels) override(pluginName string, scope string, enabled bool) (err error) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	key := overrideKey(pluginName, scope)
+	if pc.storer != nil {
+		if err = pc.storer.PutSiloString(pluginChannelsSilo, key, strconv.FormatBool(enabled)); err != nil {
+			return err
+		}
+	}
+
+	pc.overrides[key] = enabled
+	return nil
+}
+
+// reset deletes the override of a plugin for a scope
+func (pc *pluginChannels) reset(pluginName string, scope string) (err error) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	key := overrideKey(pluginName, scope)
+	if pc.storer != nil {
+		if err = pc.storer.DeleteSiloString(pluginChannelsSilo, key); err != nil {
+			return err
+		}
+	}
+
+	delete(pc.overrides, key)
+	return nil
+}
+
+// containsString returns true if the value is in the slice
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pluginChannelsPlugin holds the commands letting admins enable or disable plugins in channels at runtime
+type pluginChannelsPlugin struct {
+	Plugin
+
+	channels *pluginChannels
+	plugins  []*Plugin
+}
+
+// newPluginChannelsPlugin creates the plugin of the enable, disable and reset commands
+func (s *Slackscot) newPluginChannelsPlugin() *pluginChannelsPlugin {
+	pp := new(pluginChannelsPlugin)
+	pp.channels = s.pluginChannels
+	pp.plugins = s.plugins
+
+	pp.Plugin = Plugin{Name: pluginChannelsPluginName, NormalizeCommands: true, Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			matches := pluginChannelsOverrideRegex.FindStringSubmatch(m.NormalizedText)
+			if matches == nil {
+				return false
+			}
+
+			_, exists := pp.pluginNamed(matches[2])
+			return exists
+		},
+		Usage:       "enable|disable|reset `<plugin>` [in here|`<#channel>`]",
+		Description: "Enables or disables a plugin in a channel or, by default, in all channels",
+		AdminOnly:   true,
+		Answer:      pp.overridePlugin,
+	}}}
+
+	return pp
+}
+
+// pluginNamed returns the name, as registered, of the plugin with the given name (ignoring case, since commands are
+// normalized) and true if there's one, other than this plugin
+func (pp *pluginChannelsPlugin) pluginNamed(name string) (pluginName string, exists bool) {
+	for _, p := range pp.plugins {
+		if strings.EqualFold(p.Name, name) && p.Name != pluginChannelsPluginName {
+			return p.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// overridePlugin enables, disables or resets a plugin in a channel or in all channels
+func (pp *pluginChannelsPlugin) overridePlugin(m *IncomingMessage) *Answer {
+	matches := pluginChannelsOverrideRegex.FindStringSubmatch(m.NormalizedText)
+	verb := strings.ToLower(matches[1])
+	pluginName, _ := pp.pluginNamed(matches[2])
+
+	scope, where := allChannelsScope, "in all channels"
+	if matches[3] != "" {
+		scope, where = m.Channel, fmt.Sprintf("in <#%s>", m.Channel)
+	} else if matches[4] != "" {
+		scope, where = matches[4], fmt.Sprintf("in <#%s>", matches[4])
+	}
+
+	var err error
+	if verb == "reset" {
+		err = pp.channels.reset(pluginName, scope)
+	} else {
+		err = pp.channels.override(pluginName, scope, verb == "enable")
+	}
+
+	if err != nil {
+		pp.Logger.Printf("Error overriding channels of plugin [%s] for [%s]: %v", pluginName, scope, err)
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't %s `%s` :disappointed: (%v)", verb, pluginName, err)}
+	}
+
+	if verb == "reset" {
+		return &Answer{Text: fmt.Sprintf("`%s` is back to its configured channels %s :leftwards_arrow_with_hook:", pluginName, where)}
+	}
+
+	return &Answer{Text: fmt.Sprintf("`%s` is %sd %s :electric_plug:", pluginName, verb, where)}
+}
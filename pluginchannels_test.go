@@ -0,0 +1,99 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPluginChannelsFromConfig(t *testing.T) {
+	v := viper.New()
+	v.Set("plugins.karma.allowedChannelIDs", []string{"Cfun", "Cgeneral"})
+	v.Set("plugins.karma.deniedChannelIDs", []string{"Cgeneral"})
+	v.Set("plugins.triggerer.deniedChannelIDs", []string{"Cannouncements"})
+
+	pc, err := newPluginChannels(v, nil)
+	require.NoError(t, err)
+
+	assert.True(t, pc.isEnabled("karma", "Cfun"))
+	assert.False(t, pc.isEnabled("karma", "Cgeneral"))
+	assert.False(t, pc.isEnabled("karma", "Crandom"))
+	assert.True(t, pc.isEnabled("triggerer", "Cgeneral"))
+	assert.False(t, pc.isEnabled("triggerer", "Cannouncements"))
+	assert.True(t, pc.isEnabled("unknown", "Cgeneral"))
+}
+
+func TestPluginChannelsOverridesPrecedence(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := viper.New()
+	v.Set("plugins.karma.allowedChannelIDs", []string{"Cfun"})
+
+	pc, err := newPluginChannels(v, storer)
+	require.NoError(t, err)
+
+	require.NoError(t, pc.override("karma", allChannelsScope, true))
+	require.NoError(t, pc.override("karma", "Cfun", false))
+	assert.True(t, pc.isEnabled("karma", "Cgeneral"))
+	assert.False(t, pc.isEnabled("karma", "Cfun"))
+
+	// Overrides are persisted
+	pc, err = newPluginChannels(v, storer)
+	require.NoError(t, err)
+	assert.True(t, pc.isEnabled("karma", "Cgeneral"))
+	assert.False(t, pc.isEnabled("karma", "Cfun"))
+
+	require.NoError(t, pc.reset("karma", allChannelsScope))
+	require.NoError(t, pc.reset("karma", "Cfun"))
+	assert.False(t, pc.isEnabled("karma", "Cgeneral"))
+	assert.True(t, pc.isEnabled("karma", "Cfun"))
+}
+
+func TestPluginRestrictedToAllowedChannels(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set("plugins.noRules.allowedChannelIDs", []string{"Cfun"})
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cfun", "blue jays", "Alphonse", timestamp1)),
+	}, nil)
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, "Cfun", sentMsgs[0].channelID)
+		assert.Equal(t, "I heard you say something about blue jays?", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+	}
+}
+
+func TestPluginDisabledInChannelByAdmin(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.AdminUsersKey, []string{"Alphonse"})
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "disable noRules in <#Cgeneral|general>", "Ignored", "1546833200.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "disable noRules in <#Cgeneral|general>", "Alphonse", timestamp2, optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Crandom", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionFeatureFlagStorer(storer))
+
+	if assert.Len(t, sentMsgs, 3) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Ignored>: Sorry, only admins can do that :no_entry_sign:", vals.Get("text"))
+		assert.Equal(t, "Ignored", vals.Get("user"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `noRules` is disabled in <#Cgeneral> :electric_plug:", vals.Get("text"))
+
+		assert.Equal(t, "Crandom", sentMsgs[2].channelID)
+		vals = applySlackOptions(sentMsgs[2].msgOptions...)
+		assert.Equal(t, "I heard you say something about blue jays?", vals.Get("text"))
+	}
+}
@@ -0,0 +1,168 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// AdminPluginName holds identifying name for the admin plugin
+	AdminPluginName = "admin"
+)
+
+var adminPluginsRegex = regexp.MustCompile("(?i)\\Aadmin plugins\\z")
+var adminToggleRegex = regexp.MustCompile("(?i)\\Aadmin (enable|disable) ([\\w.-]+)\\z")
+var adminUptimeRegex = regexp.MustCompile("(?i)\\Aadmin uptime\\z")
+var adminConfigRegex = regexp.MustCompile("(?i)\\Aadmin config(?: (\\S+))?\\z")
+var adminFlushRegex = regexp.MustCompile("(?i)\\Aadmin flush caches\\z")
+
+// Admin holds the plugin data for the admin plugin. The admin plugin lets admins (see config.AdminUsersKey) manage the
+// running bot from slack: listing plugins, enabling or disabling them, checking the uptime, looking at the
+// configuration (with secrets redacted) and flushing caches. All of its commands are admin-only
+type Admin struct {
+	*slackscot.Plugin
+}
+
+// NewAdmin creates a new instance of the admin plugin
+func NewAdmin() (p *Admin) {
+	p = new(Admin)
+
+	p.Plugin = plugin.New(AdminPluginName).
+		WithCommandNormalization().
+		WithCommand(actions.NewCommand().
+			AdminOnly().
+			WithMatcher(matchRegex(adminPluginsRegex)).
+			WithUsage("admin plugins").
+			WithDescription("Lists the registered plugins and whether they're enabled in the channel").
+			WithAnswerer(p.listPlugins).
+			Build()).
+		WithCommand(actions.NewCommand().
+			AdminOnly().
+			WithMatcher(matchRegex(adminToggleRegex)).
+			WithUsage("admin enable|disable `<plugin>`").
+			WithDescription("Enables or disables a plugin in all channels").
+			WithAnswerer(p.togglePlugin).
+			Build()).
+		WithCommand(actions.NewCommand().
+			AdminOnly().
+			WithMatcher(matchRegex(adminUptimeRegex)).
+			WithUsage("admin uptime").
+			WithDescription("Shows for how long the bot has been running").
+			WithAnswerer(p.showUptime).
+			Build()).
+		WithCommand(actions.NewCommand().
+			AdminOnly().
+			WithMatcher(matchRegex(adminConfigRegex)).
+			WithUsage("admin config [`<key prefix>`]").
+			WithDescription("Shows the configuration values, with secrets redacted").
+			WithAnswerer(p.showConfig).
+			Build()).
+		WithCommand(actions.NewCommand().
+			AdminOnly().
+			WithMatcher(matchRegex(adminFlushRegex)).
+			WithUsage("admin flush caches").
+			WithDescription("Flushes the cached user info, channel info and emoji").
+			WithAnswerer(p.flushCaches).
+			Build()).
+		Build()
+
+	return p
+}
+
+// matchRegex returns a matcher of the normalized text of messages against the regex
+func matchRegex(regex *regexp.Regexp) slackscot.Matcher {
+	return func(m *slackscot.IncomingMessage) bool {
+		return regex.MatchString(m.NormalizedText)
+	}
+}
+
+// listPlugins lists the registered plugins with their status in the channel
+func (a *Admin) listPlugins(m *slackscot.IncomingMessage) *slackscot.Answer {
+	var b strings.Builder
+	b.WriteString("Plugins in this channel:")
+
+	for _, name := range a.BotAdmin.PluginNames() {
+		status := ":white_check_mark:"
+		if !a.BotAdmin.IsPluginEnabled(name, m.Channel) {
+			status = ":no_entry_sign:"
+		}
+
+		fmt.Fprintf(&b, "\n%s `%s`", status, name)
+	}
+
+	return &slackscot.Answer{Text: b.String()}
+}
+
+// togglePlugin enables or disables a plugin in all channels. The admin plugin can't disable itself since that would
+// leave no way to enable plugins again
+func (a *Admin) togglePlugin(m *slackscot.IncomingMessage) *slackscot.Answer {
+	matches := adminToggleRegex.FindStringSubmatch(m.NormalizedText)
+	verb := strings.ToLower(matches[1])
+
+	name, exists := a.pluginNamed(matches[2])
+	if !exists {
+		return &slackscot.Answer{Text: fmt.Sprintf("There's no `%s` plugin :shrug:", matches[2])}
+	}
+
+	if name == AdminPluginName {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I can't %s myself :upside_down_face:", verb)}
+	}
+
+	if err := a.BotAdmin.SetPluginEnabled(name, verb == "enable"); err != nil {
+		a.Logger.Printf("[%s] Error trying to %s plugin [%s]: %v", AdminPluginName, verb, name, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't %s `%s` :disappointed: (%v)", verb, name, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("`%s` is %sd in all channels :electric_plug:", name, verb)}
+}
+
+// pluginNamed returns the name, as registered, of the plugin with the given name (ignoring case since commands are
+// normalized) and true if there's one
+func (a *Admin) pluginNamed(name string) (pluginName string, exists bool) {
+	for _, n := range a.BotAdmin.PluginNames() {
+		if strings.EqualFold(n, name) {
+			return n, true
+		}
+	}
+
+	return "", false
+}
+
+// showUptime answers with the uptime of the bot, to the second
+func (a *Admin) showUptime(m *slackscot.IncomingMessage) *slackscot.Answer {
+	return &slackscot.Answer{Text: fmt.Sprintf("I've been up for `%s` :stopwatch:", a.BotAdmin.Uptime().Round(time.Second))}
+}
+
+// showConfig answers with the configuration values whose key starts with the optional prefix
+func (a *Admin) showConfig(m *slackscot.IncomingMessage) *slackscot.Answer {
+	prefix := adminConfigRegex.FindStringSubmatch(m.NormalizedText)[1]
+
+	settings := a.BotAdmin.Settings(prefix)
+	if len(settings) == 0 {
+		return &slackscot.Answer{Text: fmt.Sprintf("No configuration key starts with `%s` :shrug:", prefix)}
+	}
+
+	var b strings.Builder
+	b.WriteString("```")
+	for _, setting := range settings {
+		fmt.Fprintf(&b, "\n%s: [%v] (%s)", setting.Key, setting.Value, setting.Source)
+	}
+	b.WriteString("\n```")
+
+	return &slackscot.Answer{Text: b.String()}
+}
+
+// flushCaches flushes the caches of the bot and answers with the names of those flushed
+func (a *Admin) flushCaches(m *slackscot.IncomingMessage) *slackscot.Answer {
+	flushed := a.BotAdmin.FlushCaches()
+	if len(flushed) == 0 {
+		return &slackscot.Answer{Text: "There were no caches to flush :shrug:"}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Flushed caches `%s` :toilet:", strings.Join(flushed, "`, `"))}
+}
@@ -0,0 +1,171 @@
+package plugins_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type botAdmin struct {
+	disabled map[string]bool
+	err      error
+	flushed  []string
+}
+
+func newBotAdmin() (ba *botAdmin) {
+	return &botAdmin{disabled: map[string]bool{"triggerer": true}, flushed: []string{"userInfo", "emoji"}}
+}
+
+func (ba *botAdmin) PluginNames() (names []string) {
+	return []string{"admin", "karma", "triggerer"}
+}
+
+func (ba *botAdmin) IsPluginEnabled(pluginName string, channelID string) (enabled bool) {
+	return !ba.disabled[pluginName]
+}
+
+func (ba *botAdmin) SetPluginEnabled(pluginName string, enabled bool) (err error) {
+	if ba.err != nil {
+		return ba.err
+	}
+
+	ba.disabled[pluginName] = !enabled
+	return nil
+}
+
+func (ba *botAdmin) Uptime() (uptime time.Duration) {
+	return 26*time.Hour + 3*time.Minute + 4*time.Second + 300*time.Millisecond
+}
+
+func (ba *botAdmin) Settings(prefix string) (settings []config.EffectiveSetting) {
+	all := []config.EffectiveSetting{
+		{Key: "plugins.karma.apitoken", Value: config.RedactedValue, Source: config.SourceFile},
+		{Key: "responsecachesize", Value: 5000, Source: config.SourceDefault},
+	}
+
+	settings = make([]config.EffectiveSetting, 0)
+	for _, s := range all {
+		if strings.HasPrefix(s.Key, prefix) {
+			settings = append(settings, s)
+		}
+	}
+
+	return settings
+}
+
+func (ba *botAdmin) FlushCaches() (flushed []string) {
+	return ba.flushed
+}
+
+func newAdmin(ba slackscot.BotAdmin) (p *plugins.Admin) {
+	p = plugins.NewAdmin()
+	p.BotAdmin = ba
+
+	return p
+}
+
+func TestAdminCommandsAreAdminOnly(t *testing.T) {
+	p := plugins.NewAdmin()
+
+	for _, c := range p.Commands {
+		assert.True(t, c.AdminOnly, c.Usage)
+	}
+}
+
+func TestAdminListPlugins(t *testing.T) {
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(newAdmin(newBotAdmin()).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin plugins"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Plugins in this channel:\n:white_check_mark: `admin`\n:white_check_mark: `karma`\n:no_entry_sign: `triggerer`")
+	})
+}
+
+func TestAdminEnableAndDisablePlugin(t *testing.T) {
+	ba := newBotAdmin()
+	p := newAdmin(ba)
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p.Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin disable Karma"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "`karma` is disabled in all channels :electric_plug:")
+	})
+	assert.True(t, ba.disabled["karma"])
+
+	assertplugin.AnswersAndReacts(p.Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin enable triggerer"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "`triggerer` is enabled in all channels :electric_plug:")
+	})
+	assert.False(t, ba.disabled["triggerer"])
+}
+
+func TestAdminDisableUnknownPlugin(t *testing.T) {
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(newAdmin(newBotAdmin()).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin disable weather"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "There's no `weather` plugin :shrug:")
+	})
+}
+
+func TestAdminCantDisableItself(t *testing.T) {
+	ba := newBotAdmin()
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(newAdmin(ba).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin disable admin"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, I can't disable myself :upside_down_face:")
+	})
+	assert.False(t, ba.disabled["admin"])
+}
+
+func TestAdminErrorDisablingPlugin(t *testing.T) {
+	ba := newBotAdmin()
+	ba.err = fmt.Errorf("can't store override")
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(newAdmin(ba).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin disable karma"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, I couldn't disable `karma` :disappointed: (can't store override)")
+	})
+}
+
+func TestAdminUptime(t *testing.T) {
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(newAdmin(newBotAdmin()).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin uptime"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "I've been up for `26h3m4s` :stopwatch:")
+	})
+}
+
+func TestAdminConfig(t *testing.T) {
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(newAdmin(newBotAdmin()).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin config"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "```\nplugins.karma.apitoken: [[redacted]] (file)\nresponsecachesize: [5000] (default)\n```")
+	})
+
+	assertplugin.AnswersAndReacts(newAdmin(newBotAdmin()).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin config plugins."}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "```\nplugins.karma.apitoken: [[redacted]] (file)\n```")
+	})
+
+	assertplugin.AnswersAndReacts(newAdmin(newBotAdmin()).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin config webhooks"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "No configuration key starts with `webhooks` :shrug:")
+	})
+}
+
+func TestAdminFlushCaches(t *testing.T) {
+	ba := newBotAdmin()
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(newAdmin(ba).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin flush caches"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Flushed caches `userInfo`, `emoji` :toilet:")
+	})
+
+	ba.flushed = []string{}
+	assertplugin.AnswersAndReacts(newAdmin(ba).Plugin, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> admin flush caches"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "There were no caches to flush :shrug:")
+	})
+}
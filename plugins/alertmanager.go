@@ -0,0 +1,395 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/slack-go/slack"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// AlertmanagerPluginName holds identifying name for the alertmanager plugin
+	AlertmanagerPluginName = "alertmanager"
+
+	// AlertmanagerAlertsWebhookPath is the path of the webhook receiving alert notifications, served at
+	// /webhooks/alertmanager/alerts. It should be the url of a webhook receiver in the alertmanager configuration
+	AlertmanagerAlertsWebhookPath = "alerts"
+
+	// AlertmanagerSilenceActionID is the action ID of the button silencing a group of alerts
+	AlertmanagerSilenceActionID = "alertmanager.silence"
+)
+
+// Configuration keys
+const (
+	alertmanagerChannelIDKey       = "channelID"       // Channel where alerts not matching any route are posted, required
+	alertmanagerRoutesKey          = "routes"          // Map of label matchers (i.e. "team=payments,severity=critical") to channel IDs, optional
+	alertmanagerBearerTokenKey     = "bearerToken"     // Bearer token alertmanager must send alerts with, optional
+	alertmanagerURLKey             = "url"             // Url of the alertmanager API used to create silences. Silence buttons are only shown when set
+	alertmanagerSilenceDurationKey = "silenceDuration" // Duration of silences, defaults to 1h
+)
+
+const (
+	defaultAlertmanagerSilenceDuration = time.Hour
+	alertmanagerRequestTimeout         = 10 * time.Second
+)
+
+// Alert statuses
+const (
+	alertFiring   = "firing"
+	alertResolved = "resolved"
+)
+
+// alertColors holds the attachment color of alerts by status
+var alertColors = map[string]string{
+	alertFiring:   "#E01E5A",
+	alertResolved: "#2EB67D",
+}
+
+// alertGroup is a notification of a group of alerts as sent by the alertmanager webhook receiver
+type alertGroup struct {
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupKey          string            `json:"groupKey"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []alert           `json:"alerts"`
+}
+
+// alert is a single alert of a group
+type alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// alertRoute sends alerts whose labels match all of its matchers to a channel
+type alertRoute struct {
+	matchers  map[string]string
+	channelID string
+}
+
+// silenceMatcher is a label matcher of a silence, as expected by the alertmanager API
+type silenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+}
+
+// silence is a silence creation request of the alertmanager API
+type silence struct {
+	Matchers  []silenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+}
+
+// Alertmanager holds the plugin data for the alertmanager plugin
+type Alertmanager struct {
+	*slackscot.Plugin
+	channelID       string
+	routes          []alertRoute
+	bearerToken     string
+	url             string
+	silenceDuration time.Duration
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewAlertmanager creates a new instance of the alertmanager plugin. Alert notifications received on its webhook
+// are posted to the channels of the routes matching their common labels (or the default channel) with firing
+// and resolved alerts grouped and color-coded. When the alertmanager url is configured, firing alerts have a
+// button to silence them whose clicks are routed to the plugin by slackscot's interaction handler (see
+// slackscot.InteractionPath)
+func NewAlertmanager(c *config.PluginConfig) (p *slackscot.Plugin, err error) {
+	return newAlertmanager(c, time.Now)
+}
+
+// newAlertmanager creates a new instance of the alertmanager plugin using now to get the current time
+func newAlertmanager(c *config.PluginConfig, now func() time.Time) (p *slackscot.Plugin, err error) {
+	if !c.IsSet(alertmanagerChannelIDKey) {
+		return nil, fmt.Errorf("Missing %s config key: %s", AlertmanagerPluginName, alertmanagerChannelIDKey)
+	}
+
+	c.SetDefault(alertmanagerSilenceDurationKey, defaultAlertmanagerSilenceDuration)
+
+	am := new(Alertmanager)
+	am.channelID = c.GetString(alertmanagerChannelIDKey)
+	am.bearerToken = c.GetString(alertmanagerBearerTokenKey)
+	am.url = strings.TrimSuffix(c.GetString(alertmanagerURLKey), "/")
+	am.silenceDuration = c.GetDuration(alertmanagerSilenceDurationKey)
+	am.client = &http.Client{Timeout: alertmanagerRequestTimeout}
+	am.now = now
+
+	for rawMatchers, channelID := range c.GetStringMapString(alertmanagerRoutesKey) {
+		route, err := parseAlertRoute(rawMatchers, channelID)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s route [%s]: %v", AlertmanagerPluginName, rawMatchers, err)
+		}
+
+		am.routes = append(am.routes, route)
+	}
+
+	builder := plugin.New(AlertmanagerPluginName).
+		WithWebhook(AlertmanagerAlertsWebhookPath, am.receiveAlerts)
+
+	// Alerts are posted by the webhook so the hear action never matches and only handles clicks of the silence buttons
+	if am.url != "" {
+		builder = builder.WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return false
+			}).
+			WithDescription("Silence alerts on clicks of their silence button").
+			WithInteractionHandler(am.receiveInteraction).
+			Build())
+	}
+
+	am.Plugin = builder.Build()
+
+	return am.Plugin, nil
+}
+
+// parseAlertRoute parses comma-separated label matchers such as "team=payments,severity=critical"
+func parseAlertRoute(rawMatchers string, channelID string) (route alertRoute, err error) {
+	route.channelID = channelID
+	route.matchers = make(map[string]string)
+
+	for _, m := range strings.Split(rawMatchers, ",") {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return route, fmt.Errorf("matcher [%s] should be of the form label=value", m)
+		}
+
+		route.matchers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return route, nil
+}
+
+// matches returns true if all the route's matchers match the labels. Since configuration keys are lowercased
+// when loaded, labels and values are matched ignoring case
+func (r alertRoute) matches(labels map[string]string) bool {
+	for name, value := range r.matchers {
+		matched := false
+		for l, v := range labels {
+			if strings.EqualFold(l, name) && strings.EqualFold(v, value) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// receiveAlerts posts a group of alerts received on the webhook to the channels it's routed to
+func (am *Alertmanager) receiveAlerts(w http.ResponseWriter, r *http.Request) {
+	if am.bearerToken != "" && r.Header.Get("Authorization") != "Bearer "+am.bearerToken {
+		am.Logger.Printf("[%s] Rejecting alerts with invalid authorization", AlertmanagerPluginName)
+		http.Error(w, "invalid authorization", http.StatusUnauthorized)
+		return
+	}
+
+	var group alertGroup
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		am.Logger.Printf("[%s] Error decoding alerts: %v", AlertmanagerPluginName, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	text, attachments := am.renderAlertGroup(group)
+	for _, channelID := range am.routeAlertGroup(group) {
+		if _, _, err := am.SlackClient.PostMessage(channelID, slack.MsgOptionText(text, false), slack.MsgOptionAttachments(attachments...), slack.MsgOptionAsUser(true)); err != nil {
+			am.Logger.Printf("[%s] Error posting alerts [%s] to [%s]: %v", AlertmanagerPluginName, group.GroupKey, channelID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// routeAlertGroup returns the distinct channels of the routes matching the group's common labels or the default
+// channel if none match
+func (am *Alertmanager) routeAlertGroup(group alertGroup) (channelIDs []string) {
+	channelIDs = make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, route := range am.routes {
+		if route.matches(group.CommonLabels) && !seen[route.channelID] {
+			seen[route.channelID] = true
+			channelIDs = append(channelIDs, route.channelID)
+		}
+	}
+
+	// Routes are kept in a map in the configuration so sort channels to post in a stable order
+	sort.Strings(channelIDs)
+
+	if len(channelIDs) == 0 {
+		channelIDs = append(channelIDs, am.channelID)
+	}
+
+	return channelIDs
+}
+
+// renderAlertGroup returns the fallback text of a group of alerts along with an attachment for its firing alerts and
+// one for its resolved alerts
+func (am *Alertmanager) renderAlertGroup(group alertGroup) (text string, attachments []slack.Attachment) {
+	alertsByStatus := map[string][]alert{}
+	for _, a := range group.Alerts {
+		alertsByStatus[a.Status] = append(alertsByStatus[a.Status], a)
+	}
+
+	firing, resolved := alertsByStatus[alertFiring], alertsByStatus[alertResolved]
+	text = fmt.Sprintf("[FIRING:%d, RESOLVED:%d] %s", len(firing), len(resolved), formatLabels(group.GroupLabels))
+
+	attachments = make([]slack.Attachment, 0)
+	if len(firing) > 0 {
+		blocks := append([]slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":fire: *Firing (%d)*", len(firing)), false, false), nil, nil)}, alertBlocks(firing)...)
+
+		if am.url != "" && len(group.CommonLabels) > 0 {
+			labels, _ := json.Marshal(group.CommonLabels)
+			button := slack.NewButtonBlockElement(AlertmanagerSilenceActionID, string(labels), slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Silence for %s", am.silenceDuration), false, false))
+			blocks = append(blocks, slack.NewActionBlock(slackscot.HearActionInteractionBlockID(AlertmanagerPluginName, 0), button))
+		}
+
+		attachments = append(attachments, slack.Attachment{Color: alertColors[alertFiring], Blocks: slack.Blocks{BlockSet: blocks}})
+	}
+
+	if len(resolved) > 0 {
+		blocks := append([]slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":white_check_mark: *Resolved (%d)*", len(resolved)), false, false), nil, nil)}, alertBlocks(resolved)...)
+		attachments = append(attachments, slack.Attachment{Color: alertColors[alertResolved], Blocks: slack.Blocks{BlockSet: blocks}})
+	}
+
+	return text, attachments
+}
+
+// alertBlocks renders each alert as a section with its summary, description and labels
+func alertBlocks(alerts []alert) (blocks []slack.Block) {
+	for _, a := range alerts {
+		title := a.Annotations["summary"]
+		if title == "" {
+			title = a.Labels["alertname"]
+		}
+
+		if a.GeneratorURL != "" {
+			title = fmt.Sprintf("<%s|%s>", a.GeneratorURL, title)
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%s*", title)
+		if description := a.Annotations["description"]; description != "" {
+			fmt.Fprintf(&b, "\n%s", description)
+		}
+		fmt.Fprintf(&b, "\n`%s`", formatLabels(a.Labels))
+
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, b.String(), false, false), nil, nil))
+	}
+
+	return blocks
+}
+
+// formatLabels renders labels sorted by name as name=value pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, value))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, " ")
+}
+
+// receiveInteraction creates silences on clicks of the silence button
+func (am *Alertmanager) receiveInteraction(i *slackscot.Interaction) *slack.ViewSubmissionResponse {
+	if i.Type != slack.InteractionTypeBlockActions {
+		return nil
+	}
+
+	for _, action := range i.ActionCallback.BlockActions {
+		if action.ActionID == AlertmanagerSilenceActionID {
+			am.silence(i.User.ID, i.Channel.ID, i.Message.Timestamp, action.Value)
+		}
+	}
+
+	return nil
+}
+
+// silence creates a silence matching the labels and reports it in the thread of the alerts message
+func (am *Alertmanager) silence(userID string, channelID string, timestamp string, rawLabels string) {
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(rawLabels), &labels); err != nil {
+		am.Logger.Printf("[%s] Error decoding labels to silence [%s]: %v", AlertmanagerPluginName, rawLabels, err)
+		return
+	}
+
+	text := fmt.Sprintf(":no_bell: <@%s> silenced these alerts for %s", userID, am.silenceDuration)
+
+	silenceID, err := am.createSilence(userID, labels)
+	if err != nil {
+		am.Logger.Printf("[%s] Error creating silence for [%s]: %v", AlertmanagerPluginName, formatLabels(labels), err)
+		text = fmt.Sprintf("Sorry <@%s>, I couldn't silence these alerts :disappointed: (%v)", userID, err)
+	} else {
+		am.Logger.Debugf("[%s] Created silence [%s] for [%s]", AlertmanagerPluginName, silenceID, formatLabels(labels))
+	}
+
+	if _, _, err := am.SlackClient.PostMessage(channelID, slack.MsgOptionText(text, false), slack.MsgOptionTS(timestamp), slack.MsgOptionAsUser(true)); err != nil {
+		am.Logger.Printf("[%s] Error reporting silence to [%s]: %v", AlertmanagerPluginName, channelID, err)
+	}
+}
+
+// createSilence creates a silence of the configured duration with the alertmanager API and returns its ID
+func (am *Alertmanager) createSilence(userID string, labels map[string]string) (silenceID string, err error) {
+	now := am.now()
+	s := silence{StartsAt: now, EndsAt: now.Add(am.silenceDuration), CreatedBy: userID, Comment: fmt.Sprintf("Silenced from slack by %s", userID)}
+
+	for name, value := range labels {
+		s.Matchers = append(s.Matchers, silenceMatcher{Name: name, Value: value})
+	}
+
+	sort.Slice(s.Matchers, func(i, j int) bool {
+		return s.Matchers[i].Name < s.Matchers[j].Name
+	})
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := am.client.Post(am.url+"/api/v2/silences", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status [%s] from alertmanager", resp.Status)
+	}
+
+	var created struct {
+		SilenceID string `json:"silenceID"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+
+	return created.SilenceID, err
+}
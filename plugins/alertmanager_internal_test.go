@@ -0,0 +1,180 @@
+package plugins
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const alertGroupPayload = `{
+  "version": "4",
+  "status": "firing",
+  "receiver": "slackscot",
+  "groupKey": "{}:{alertname=\"HighLatency\"}",
+  "groupLabels": {"alertname": "HighLatency"},
+  "commonLabels": {"alertname": "HighLatency", "team": "payments"},
+  "alerts": [
+    {"status": "firing", "labels": {"alertname": "HighLatency", "team": "payments", "instance": "api-1"}, "annotations": {"summary": "High latency on api-1", "description": "p99 is 2s"}, "generatorURL": "https://prometheus.example.com/graph"},
+    {"status": "resolved", "labels": {"alertname": "HighLatency", "team": "payments", "instance": "api-2"}, "annotations": {}}
+  ]
+}`
+
+func newAlertmanagerTestConfig() *viper.Viper {
+	c := viper.New()
+	c.Set("channelID", "Calerts")
+	c.Set("routes", map[string]string{"team=payments": "Cpayments", "team=payments,severity=critical": "Cpager"})
+
+	return c
+}
+
+func TestAlertmanagerMissingConfig(t *testing.T) {
+	_, err := NewAlertmanager(viper.New())
+	assert.EqualError(t, err, "Missing alertmanager config key: channelID")
+
+	c := newAlertmanagerTestConfig()
+	c.Set("routes", map[string]string{"team": "Cpayments"})
+	_, err = NewAlertmanager(c)
+	assert.EqualError(t, err, "Invalid alertmanager route [team]: matcher [team] should be of the form label=value")
+}
+
+func TestAlertmanagerPostsRoutedAlerts(t *testing.T) {
+	posted := postedMessages{}
+	testServer := newPostedMessagesServer(&posted)
+	defer testServer.Stop()
+
+	p, err := NewAlertmanager(newAlertmanagerTestConfig())
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.HandlesWebhook(p, AlertmanagerAlertsWebhookPath, httptest.NewRequest("POST", "/webhooks/alertmanager/alerts", strings.NewReader(alertGroupPayload)), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+
+	require.Len(t, posted.messages, 1)
+	m := posted.messages[0]
+	assert.Equal(t, "Cpayments", m.channel)
+	assert.Equal(t, "[FIRING:1, RESOLVED:1] alertname=HighLatency", m.text)
+
+	if assert.Len(t, m.attachments, 2) {
+		assert.Equal(t, "#E01E5A", m.attachments[0].Color)
+		assert.Equal(t, "#2EB67D", m.attachments[1].Color)
+
+		// No silence button without an alertmanager url
+		firing := m.attachments[0].Blocks.BlockSet
+		if assert.Len(t, firing, 2) {
+			assert.Equal(t, ":fire: *Firing (1)*", firing[0].(*slack.SectionBlock).Text.Text)
+			assert.Equal(t, "*<https://prometheus.example.com/graph|High latency on api-1>*\np99 is 2s\n`alertname=HighLatency instance=api-1 team=payments`", firing[1].(*slack.SectionBlock).Text.Text)
+		}
+
+		resolved := m.attachments[1].Blocks.BlockSet
+		if assert.Len(t, resolved, 2) {
+			assert.Equal(t, ":white_check_mark: *Resolved (1)*", resolved[0].(*slack.SectionBlock).Text.Text)
+			assert.Equal(t, "*HighLatency*\n`alertname=HighLatency instance=api-2 team=payments`", resolved[1].(*slack.SectionBlock).Text.Text)
+		}
+	}
+
+	unrouted := strings.Replace(alertGroupPayload, `"team": "payments"}`, `"team": "search"}`, 1)
+	assertplugin.HandlesWebhook(p, AlertmanagerAlertsWebhookPath, httptest.NewRequest("POST", "/webhooks/alertmanager/alerts", strings.NewReader(unrouted)), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+
+	critical := strings.Replace(alertGroupPayload, `"team": "payments"}`, `"team": "PAYMENTS", "severity": "critical"}`, 1)
+	assertplugin.HandlesWebhook(p, AlertmanagerAlertsWebhookPath, httptest.NewRequest("POST", "/webhooks/alertmanager/alerts", strings.NewReader(critical)), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+
+	channels := make([]string, 0)
+	for _, m := range posted.messages[1:] {
+		channels = append(channels, m.channel)
+	}
+	assert.Equal(t, []string{"Calerts", "Cpager", "Cpayments"}, channels)
+
+	assertplugin.HandlesWebhook(p, AlertmanagerAlertsWebhookPath, httptest.NewRequest("POST", "/webhooks/alertmanager/alerts", strings.NewReader("not json")), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestAlertmanagerRejectsAlertsWithInvalidBearerToken(t *testing.T) {
+	c := newAlertmanagerTestConfig()
+	c.Set("bearerToken", "t0k3n")
+
+	p, err := NewAlertmanager(c)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.HandlesWebhook(p, AlertmanagerAlertsWebhookPath, httptest.NewRequest("POST", "/webhooks/alertmanager/alerts", strings.NewReader(alertGroupPayload)), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+}
+
+func TestAlertmanagerSilencesAlerts(t *testing.T) {
+	posted := postedMessages{}
+	testServer := newPostedMessagesServer(&posted)
+	defer testServer.Stop()
+
+	var silences []silence
+	alertmanagerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/silences" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var s silence
+		_ = json.NewDecoder(r.Body).Decode(&s)
+		silences = append(silences, s)
+
+		_, _ = w.Write([]byte(`{"silenceID": "7d4a1b2c"}`))
+	}))
+	defer alertmanagerServer.Close()
+
+	c := newAlertmanagerTestConfig()
+	c.Set("url", alertmanagerServer.URL+"/")
+	c.Set("silenceDuration", "2h")
+
+	now := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	p, err := newAlertmanager(c, func() time.Time { return now })
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.HandlesWebhook(p, AlertmanagerAlertsWebhookPath, httptest.NewRequest("POST", "/webhooks/alertmanager/alerts", strings.NewReader(alertGroupPayload)), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+
+	require.Len(t, posted.messages, 1)
+	firing := posted.messages[0].attachments[0].Blocks.BlockSet
+	require.Len(t, firing, 3)
+	actionBlock := firing[2].(*slack.ActionBlock)
+	assert.Equal(t, "slackscot:alertmanager.hearAction[0]", actionBlock.BlockID)
+	button := actionBlock.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	assert.Equal(t, "Silence for 2h0m0s", button.Text.Text)
+
+	interaction := newBlockActionsInteraction("U21355", actionBlock.BlockID, AlertmanagerSilenceActionID, button.Value)
+	interaction.Channel.ID = "Cpayments"
+	interaction.Message.Timestamp = "1546833210.036900"
+	require.Len(t, p.HearActions, 1)
+	assert.Nil(t, p.HearActions[0].InteractionHandler(interaction))
+
+	if assert.Len(t, silences, 1) {
+		assert.Equal(t, []silenceMatcher{{Name: "alertname", Value: "HighLatency"}, {Name: "team", Value: "payments"}}, silences[0].Matchers)
+		assert.True(t, now.Equal(silences[0].StartsAt))
+		assert.True(t, now.Add(2*time.Hour).Equal(silences[0].EndsAt))
+		assert.Equal(t, "U21355", silences[0].CreatedBy)
+	}
+
+	if assert.Len(t, posted.messages, 2) {
+		assert.Equal(t, postedMessage{channel: "Cpayments", text: ":no_bell: <@U21355> silenced these alerts for 2h0m0s", threadTS: "1546833210.036900"}, posted.messages[1])
+	}
+}
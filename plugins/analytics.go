@@ -0,0 +1,232 @@
+package plugins
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// AnalyticsPluginName holds identifying name for the analytics plugin
+	AnalyticsPluginName = "analytics"
+)
+
+// Configuration keys
+const (
+	analyticsAPITokenKey    = "apiToken"    // Bearer token API requests must be sent with, required
+	analyticsDefaultDaysKey = "defaultDays" // Number of days returned when a request has no from date, defaults to 30
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 366
+	analyticsDateFormat  = "2006-01-02"
+	messagesMetric       = "messages"
+	karmaMetric          = "karma"
+	pluginsMetric        = "plugins"
+)
+
+// Analytics holds the plugin data for the analytics plugin
+type Analytics struct {
+	*slackscot.Plugin
+	storer      store.GlobalSiloStringStorer
+	apiToken    string
+	defaultDays int
+	now         func() time.Time
+
+	// Guards the read-modify-write of daily counts since messages and events are handled concurrently
+	sync.Mutex
+}
+
+// analyticsDay holds the counts of a metric for a day
+type analyticsDay struct {
+	Date   string         `json:"date"`
+	Counts map[string]int `json:"counts"`
+}
+
+// analyticsReport is the response of the analytics API
+type analyticsReport struct {
+	Metric string         `json:"metric"`
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Days   []analyticsDay `json:"days"`
+	Totals map[string]int `json:"totals"`
+}
+
+// NewAnalytics creates a new instance of the analytics plugin. It records daily usage metrics and serves them as
+// JSON on read-only webhooks (requiring the apiToken as a bearer token) so that dashboards (i.e. Grafana with the JSON
+// datasource or Metabase) can be built without scraping slack:
+//
+//	GET /webhooks/analytics/messages: messages seen by hear actions, by channel
+//	GET /webhooks/analytics/karma: karma given (or taken away when negative), by thing
+//	GET /webhooks/analytics/plugins: answers, by plugin
+//
+// The date range is given with the from and to query parameters (YYYY-MM-DD, in UTC) and defaults to the last
+// defaultDays days. Karma is recorded from the karma plugin's KarmaChanged events
+func NewAnalytics(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	return newAnalytics(c, storer, time.Now)
+}
+
+// newAnalytics creates a new instance of the analytics plugin using now to get the current time
+func newAnalytics(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (p *slackscot.Plugin, err error) {
+	if !c.IsSet(analyticsAPITokenKey) {
+		return nil, fmt.Errorf("Missing %s config key: %s", AnalyticsPluginName, analyticsAPITokenKey)
+	}
+
+	c.SetDefault(analyticsDefaultDaysKey, defaultAnalyticsDays)
+
+	a := new(Analytics)
+	a.storer = storer
+	a.apiToken = c.GetString(analyticsAPITokenKey)
+	a.defaultDays = c.GetInt(analyticsDefaultDaysKey)
+	a.now = now
+
+	if a.defaultDays <= 0 {
+		return nil, fmt.Errorf("Invalid %s config key value for %s: [%d], should be greater than 0", AnalyticsPluginName, analyticsDefaultDaysKey, a.defaultDays)
+	}
+
+	a.Plugin = plugin.New(AnalyticsPluginName).
+		WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return true
+			}).
+			WithAnswerer(func(m *slackscot.IncomingMessage) *slackscot.Answer {
+				a.increment(messagesMetric, m.Channel, 1)
+				return nil
+			}).
+			Build()).
+		WithEventSubscription(KarmaChangedTopic, func(e slackscot.Event) {
+			if kc, ok := e.(KarmaChanged); ok {
+				a.increment(karmaMetric, kc.Thing, kc.Delta)
+			}
+		}).
+		WithEventSubscription(slackscot.PluginAnsweredTopic, func(e slackscot.Event) {
+			if pa, ok := e.(slackscot.PluginAnswered); ok {
+				a.increment(pluginsMetric, pa.Plugin, pa.Answers)
+			}
+		}).
+		WithWebhook(messagesMetric, a.serveMetric(messagesMetric)).
+		WithWebhook(karmaMetric, a.serveMetric(karmaMetric)).
+		WithWebhook(pluginsMetric, a.serveMetric(pluginsMetric)).
+		Build()
+
+	return a.Plugin, nil
+}
+
+// analyticsSiloName returns the name of the silo holding the counts of a metric for a day
+func analyticsSiloName(metric string, day time.Time) string {
+	return fmt.Sprintf("%s:%s", metric, day.Format(analyticsDateFormat))
+}
+
+// increment adds delta to today's count of the key for the metric
+func (a *Analytics) increment(metric string, key string, delta int) {
+	a.Lock()
+	defer a.Unlock()
+
+	silo := analyticsSiloName(metric, a.now().UTC())
+	count := 0
+	if rawValue, err := a.storer.GetSiloString(silo, key); err == nil {
+		count, _ = strconv.Atoi(rawValue)
+	}
+
+	if err := a.storer.PutSiloString(silo, key, strconv.Itoa(count+delta)); err != nil {
+		a.Logger.Printf("[%s] Error recording %s for [%s]: %v", AnalyticsPluginName, metric, key, err)
+	}
+}
+
+// serveMetric returns the handler serving the daily counts of a metric
+func (a *Analytics) serveMetric(metric string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+a.apiToken)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		from, to, err := a.parseRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := a.report(metric, from, to)
+		if err != nil {
+			a.Logger.Printf("[%s] Error reading %s: %v", AnalyticsPluginName, metric, err)
+			http.Error(w, "error reading metrics", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// parseRange returns the date range of the request from its from and to query parameters
+func (a *Analytics) parseRange(r *http.Request) (from time.Time, to time.Time, err error) {
+	today := a.now().UTC().Truncate(24 * time.Hour)
+
+	to = today
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse(analyticsDateFormat, value); err != nil {
+			return from, to, fmt.Errorf("invalid to date [%s], should be YYYY-MM-DD", value)
+		}
+	}
+
+	from = to.AddDate(0, 0, -(a.defaultDays - 1))
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(analyticsDateFormat, value); err != nil {
+			return from, to, fmt.Errorf("invalid from date [%s], should be YYYY-MM-DD", value)
+		}
+	}
+
+	if from.After(to) {
+		return from, to, fmt.Errorf("from date [%s] is after to date [%s]", from.Format(analyticsDateFormat), to.Format(analyticsDateFormat))
+	}
+
+	if to.Sub(from) >= maxAnalyticsDays*24*time.Hour {
+		return from, to, fmt.Errorf("date range is over the maximum of %d days", maxAnalyticsDays)
+	}
+
+	return from, to, nil
+}
+
+// report returns the daily counts and totals of a metric over a date range
+func (a *Analytics) report(metric string, from time.Time, to time.Time) (report analyticsReport, err error) {
+	report = analyticsReport{Metric: metric, From: from.Format(analyticsDateFormat), To: to.Format(analyticsDateFormat), Days: make([]analyticsDay, 0), Totals: make(map[string]int)}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		entries, err := a.storer.ScanSilo(analyticsSiloName(metric, day))
+		if err != nil {
+			return report, err
+		}
+
+		counts := make(map[string]int)
+		for key, rawValue := range entries {
+			count, err := strconv.Atoi(rawValue)
+			if err != nil {
+				continue
+			}
+
+			counts[key] = count
+			report.Totals[key] += count
+		}
+
+		report.Days = append(report.Days, analyticsDay{Date: day.Format(analyticsDateFormat), Counts: counts})
+	}
+
+	return report, nil
+}
@@ -0,0 +1,96 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAnalyticsMissingAPIToken(t *testing.T) {
+	_, err := NewAnalytics(viper.New(), nil)
+	assert.EqualError(t, err, "Missing analytics config key: apiToken")
+}
+
+func TestAnalyticsServesDailyMetrics(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("analyticsTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	c := viper.New()
+	c.Set("apiToken", "s3cr3t")
+	c.Set("defaultDays", 2)
+
+	now := time.Date(2020, time.March, 1, 15, 0, 0, 0, time.UTC)
+	p, err := newAnalytics(c, storer, func() time.Time { return now })
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	hears := func(channel string) {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: channel, User: "U1", Text: "hello"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		})
+	}
+
+	publish := func(e slackscot.Event) {
+		for _, sub := range p.EventSubscriptions {
+			if sub.Topic == e.Topic() {
+				sub.Handle(e)
+			}
+		}
+	}
+
+	hears("Cgeneral")
+	hears("Cgeneral")
+	publish(KarmaChanged{Channel: "Cgeneral", Thing: "@U2", Karma: 2, Delta: 2})
+
+	now = now.Add(24 * time.Hour)
+	hears("Crandom")
+	publish(KarmaChanged{Channel: "Crandom", Thing: "@U2", Karma: 1, Delta: -1})
+	publish(slackscot.PluginAnswered{Plugin: "karma", ActionType: "hearAction", Channel: "Crandom", Answers: 1})
+
+	get := func(path string, query string, token string) (response *httptest.ResponseRecorder) {
+		req := httptest.NewRequest("GET", "/webhooks/analytics/"+path+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		assertplugin.HandlesWebhook(p, path, req, func(t *testing.T, r *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+			response = r
+			return true
+		})
+
+		return response
+	}
+
+	response := get("messages", "", "s3cr3t")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"metric":"messages","from":"2020-03-01","to":"2020-03-02","days":[{"date":"2020-03-01","counts":{"Cgeneral":2}},{"date":"2020-03-02","counts":{"Crandom":1}}],"totals":{"Cgeneral":2,"Crandom":1}}`, response.Body.String())
+
+	response = get("karma", "?from=2020-02-29&to=2020-03-01", "s3cr3t")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"metric":"karma","from":"2020-02-29","to":"2020-03-01","days":[{"date":"2020-02-29","counts":{}},{"date":"2020-03-01","counts":{"@U2":2}}],"totals":{"@U2":2}}`, response.Body.String())
+
+	response = get("plugins", "", "s3cr3t")
+	assert.JSONEq(t, `{"metric":"plugins","from":"2020-03-01","to":"2020-03-02","days":[{"date":"2020-03-01","counts":{}},{"date":"2020-03-02","counts":{"karma":1}}],"totals":{"karma":1}}`, response.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, get("messages", "", "wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("messages", "", "").Code)
+
+	response = get("messages", "?from=2020-03-02&to=2020-03-01", "s3cr3t")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, "from date [2020-03-02] is after to date [2020-03-01]\n", response.Body.String())
+}
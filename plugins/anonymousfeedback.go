@@ -0,0 +1,339 @@
+package plugins
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AnonymousFeedbackPluginName holds identifying name for the anonymous feedback plugin
+	AnonymousFeedbackPluginName = "anonymousFeedback"
+)
+
+// Configuration keys
+const (
+	feedbackHashKeyKey           = "hashKey"             // Secret key used to hash sender IDs, required
+	feedbackChannelIDsKey        = "channelIDs"          // Channels feedback can be sent to, required
+	feedbackModerationChannelKey = "moderationChannelID" // Channel where moderators review feedback and abuse reports, required
+	feedbackModeratorIDsKey      = "moderatorIDs"        // Users allowed to approve, reject and ban, required
+	feedbackModerateKey          = "moderate"            // Whether feedback needs to be approved before it's posted, defaults to false
+	feedbackMaxPerDayKey         = "maxPerDay"           // Maximum feedback a user can send in 24 hours, defaults to 3
+)
+
+const (
+	defaultFeedbackMaxPerDay = 3
+	feedbackSilo             = "feedback"
+	feedbackSendsSilo        = "sends"
+	feedbackBansSilo         = "bans"
+	feedbackRateWindow       = 24 * time.Hour
+)
+
+// Feedback statuses
+const (
+	feedbackPending  = "pending"
+	feedbackPosted   = "posted"
+	feedbackRejected = "rejected"
+)
+
+var sendFeedbackRegex = regexp.MustCompile("(?msi)\\Afeedback <#(C[A-Z0-9]+)(?:\\|[^>]*)?>\\s+(.+)\\z")
+var moderateFeedbackRegex = regexp.MustCompile("(?i)\\Afeedback (approve|reject|ban) ([a-z0-9]+)\\z")
+var reportFeedbackRegex = regexp.MustCompile("(?i)\\Afeedback report ([a-z0-9]+)\\z")
+
+// feedback is what's persisted for every feedback. Only the hash of the sender's ID is kept so feedback can't be
+// traced back to its sender while still allowing bans
+type feedback struct {
+	ID         string    `json:"id"`
+	SenderHash string    `json:"senderHash"`
+	ChannelID  string    `json:"channelID"`
+	Text       string    `json:"text"`
+	Status     string    `json:"status"`
+	Submitted  time.Time `json:"submitted"`
+	Reports    int       `json:"reports"`
+}
+
+// AnonymousFeedback holds the plugin data for the anonymous feedback plugin
+type AnonymousFeedback struct {
+	*slackscot.Plugin
+	sync.Mutex
+	storer              store.GlobalSiloStringStorer
+	now                 func() time.Time
+	hashKey             []byte
+	channelIDs          map[string]bool
+	moderationChannelID string
+	moderators          map[string]bool
+	moderate            bool
+	maxPerDay           int
+}
+
+// NewAnonymousFeedback creates a new instance of the anonymous feedback plugin. Users send feedback by direct message
+// and it gets posted anonymously to the requested channel (once approved by a moderator if moderation is enabled)
+func NewAnonymousFeedback(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	return newAnonymousFeedback(c, storer, time.Now)
+}
+
+func newAnonymousFeedback(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (p *slackscot.Plugin, err error) {
+	for _, key := range []string{feedbackHashKeyKey, feedbackChannelIDsKey, feedbackModerationChannelKey, feedbackModeratorIDsKey} {
+		if !c.IsSet(key) {
+			return nil, fmt.Errorf("Missing %s config key: %s", AnonymousFeedbackPluginName, key)
+		}
+	}
+
+	c.SetDefault(feedbackMaxPerDayKey, defaultFeedbackMaxPerDay)
+
+	af := new(AnonymousFeedback)
+	af.storer = storer
+	af.now = now
+	af.hashKey = []byte(c.GetString(feedbackHashKeyKey))
+	af.moderationChannelID = c.GetString(feedbackModerationChannelKey)
+	af.moderate = c.GetBool(feedbackModerateKey)
+	af.maxPerDay = c.GetInt(feedbackMaxPerDayKey)
+
+	af.channelIDs = make(map[string]bool)
+	for _, channelID := range c.GetStringSlice(feedbackChannelIDsKey) {
+		af.channelIDs[channelID] = true
+	}
+
+	af.moderators = make(map[string]bool)
+	for _, userID := range c.GetStringSlice(feedbackModeratorIDsKey) {
+		af.moderators[userID] = true
+	}
+
+	af.Plugin = plugin.New(AnonymousFeedbackPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return sendFeedbackRegex.MatchString(m.NormalizedText) }).
+			WithUsage("feedback #<channel> <text>").
+			WithDescription("Send anonymous feedback to a channel (in a direct message with me)").
+			WithAnswerer(af.send).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return reportFeedbackRegex.MatchString(m.NormalizedText) }).
+			WithUsage("feedback report <id>").
+			WithDescription("Report abusive feedback to the moderators").
+			WithAnswerer(af.report).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return moderateFeedbackRegex.MatchString(m.NormalizedText) }).
+			WithUsage("feedback approve|reject|ban <id>").
+			WithDescription("Approve or reject pending feedback or ban its sender (moderators only)").
+			WithAnswerer(af.moderateFeedback).
+			Build()).
+		Build()
+
+	return af.Plugin, nil
+}
+
+// send records feedback and posts it to its channel or submits it to the moderators
+func (af *AnonymousFeedback) send(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if !m.IsDirectMessage() {
+		return &slackscot.Answer{Text: "Feedback is only accepted in a direct message with me to keep it anonymous :shushing_face:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+	}
+
+	match := sendFeedbackRegex.FindStringSubmatch(m.NormalizedText)
+	channelID, text := match[1], strings.TrimSpace(match[2])
+
+	if !af.channelIDs[channelID] {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, <#%s> doesn't accept anonymous feedback :no_entry_sign:", channelID)}
+	}
+
+	af.Lock()
+	defer af.Unlock()
+
+	senderHash := af.hashSender(m.User)
+	if _, err := af.storer.GetSiloString(feedbackBansSilo, senderHash); err == nil {
+		return &slackscot.Answer{Text: "Sorry, you're not allowed to send feedback anymore :no_entry_sign:"}
+	}
+
+	sends, err := af.getRecentSends(senderHash)
+	if err != nil {
+		af.Logger.Printf("[%s] Error loading recent feedback: %v", AnonymousFeedbackPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't record your feedback :disappointed: (%v)", err)}
+	}
+
+	if len(sends) >= af.maxPerDay {
+		return &slackscot.Answer{Text: fmt.Sprintf("You've already sent %d feedback in the last 24 hours, please try again later :hourglass:", len(sends))}
+	}
+
+	f := feedback{ID: strconv.FormatInt(af.now().UnixNano(), 36), SenderHash: senderHash, ChannelID: channelID, Text: text, Status: feedbackPending, Submitted: af.now()}
+	if !af.moderate {
+		f.Status = feedbackPosted
+	}
+
+	if err = af.putFeedback(f); err == nil {
+		err = af.putRecentSends(senderHash, append(sends, f.Submitted))
+	}
+
+	if err != nil {
+		af.Logger.Printf("[%s] Error persisting feedback: %v", AnonymousFeedbackPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't record your feedback :disappointed: (%v)", err)}
+	}
+
+	if af.moderate {
+		af.sendMessage(af.moderationChannelID, fmt.Sprintf(":inbox_tray: New feedback `%s` for <#%s>:\n>%s\nApprove with `feedback approve %s` or reject with `feedback reject %s`", f.ID, f.ChannelID, quoteFeedback(f.Text), f.ID, f.ID))
+		return &slackscot.Answer{Text: fmt.Sprintf("Thanks! Your feedback will be posted to <#%s> once a moderator approves it :pray:", channelID)}
+	}
+
+	af.postFeedback(f)
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Thanks! Your feedback was posted anonymously to <#%s> :pray:", channelID)}
+}
+
+// report records an abuse report for posted feedback and notifies the moderators
+func (af *AnonymousFeedback) report(m *slackscot.IncomingMessage) *slackscot.Answer {
+	id := strings.ToLower(reportFeedbackRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	af.Lock()
+	defer af.Unlock()
+
+	f, err := af.getFeedback(id)
+	if err != nil || f.Status != feedbackPosted {
+		return &slackscot.Answer{Text: fmt.Sprintf("I don't know any posted feedback `%s` :shrug:", id), Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+	}
+
+	f.Reports = f.Reports + 1
+	if err := af.putFeedback(f); err != nil {
+		af.Logger.Printf("[%s] Error persisting report of feedback [%s]: %v", AnonymousFeedbackPluginName, id, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't record your report :disappointed: (%v)", err), Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+	}
+
+	af.sendMessage(af.moderationChannelID, fmt.Sprintf(":triangular_flag_on_post: Feedback `%s` posted to <#%s> was reported (%d reports so far):\n>%s\nBan its sender with `feedback ban %s`", f.ID, f.ChannelID, f.Reports, quoteFeedback(f.Text), f.ID))
+
+	return &slackscot.Answer{Text: "Thanks for the report, the moderators will have a look :eyes:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+}
+
+// moderateFeedback approves or rejects pending feedback or bans the sender of feedback
+func (af *AnonymousFeedback) moderateFeedback(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if !af.moderators[m.User] {
+		return &slackscot.Answer{Text: "Sorry, only moderators can do that :no_entry_sign:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+	}
+
+	match := moderateFeedbackRegex.FindStringSubmatch(m.NormalizedText)
+	decision, id := strings.ToLower(match[1]), strings.ToLower(match[2])
+
+	af.Lock()
+	defer af.Unlock()
+
+	f, err := af.getFeedback(id)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("I don't know any feedback `%s` :shrug:", id)}
+	}
+
+	if decision == "ban" {
+		if err := af.storer.PutSiloString(feedbackBansSilo, f.SenderHash, af.now().Format(time.RFC3339)); err != nil {
+			af.Logger.Printf("[%s] Error banning sender of feedback [%s]: %v", AnonymousFeedbackPluginName, id, err)
+			return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't ban the sender :disappointed: (%v)", err)}
+		}
+
+		return &slackscot.Answer{Text: fmt.Sprintf("The sender of feedback `%s` can't send feedback anymore :hammer:", id)}
+	}
+
+	if f.Status != feedbackPending {
+		return &slackscot.Answer{Text: fmt.Sprintf("Feedback `%s` was already %s", id, f.Status)}
+	}
+
+	f.Status = feedbackRejected
+	if decision == "approve" {
+		f.Status = feedbackPosted
+	}
+
+	if err := af.putFeedback(f); err != nil {
+		af.Logger.Printf("[%s] Error persisting moderation of feedback [%s]: %v", AnonymousFeedbackPluginName, id, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't record your decision :disappointed: (%v)", err)}
+	}
+
+	if f.Status == feedbackPosted {
+		af.postFeedback(f)
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Feedback `%s` %s :ok_hand:", id, f.Status)}
+}
+
+// postFeedback posts feedback anonymously to its channel
+func (af *AnonymousFeedback) postFeedback(f feedback) {
+	af.sendMessage(f.ChannelID, fmt.Sprintf(":speech_balloon: Anonymous feedback:\n>%s\n_Report abuse with `feedback report %s`_", quoteFeedback(f.Text), f.ID))
+}
+
+// sendMessage sends a message to a channel
+func (af *AnonymousFeedback) sendMessage(channelID string, text string) {
+	af.RealTimeMsgSender.SendMessage(af.RealTimeMsgSender.NewOutgoingMessage(text, channelID))
+}
+
+// hashSender returns the keyed hash of a user ID
+func (af *AnonymousFeedback) hashSender(userID string) string {
+	mac := hmac.New(sha256.New, af.hashKey)
+	mac.Write([]byte(userID))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getRecentSends returns the times a sender sent feedback within the rate limiting window
+func (af *AnonymousFeedback) getRecentSends(senderHash string) (sends []time.Time, err error) {
+	sends = make([]time.Time, 0)
+
+	value, err := af.storer.GetSiloString(feedbackSendsSilo, senderHash)
+	if err != nil {
+		// No feedback sent yet
+		return sends, nil
+	}
+
+	var all []time.Time
+	if err := json.Unmarshal([]byte(value), &all); err != nil {
+		return nil, err
+	}
+
+	for _, s := range all {
+		if af.now().Sub(s) < feedbackRateWindow {
+			sends = append(sends, s)
+		}
+	}
+
+	return sends, nil
+}
+
+// putRecentSends persists the times a sender sent feedback
+func (af *AnonymousFeedback) putRecentSends(senderHash string, sends []time.Time) (err error) {
+	value, err := json.Marshal(sends)
+	if err != nil {
+		return err
+	}
+
+	return af.storer.PutSiloString(feedbackSendsSilo, senderHash, string(value))
+}
+
+// getFeedback returns the feedback with the given ID
+func (af *AnonymousFeedback) getFeedback(id string) (f feedback, err error) {
+	value, err := af.storer.GetSiloString(feedbackSilo, id)
+	if err != nil {
+		return f, err
+	}
+
+	err = json.Unmarshal([]byte(value), &f)
+	return f, err
+}
+
+// putFeedback persists feedback
+func (af *AnonymousFeedback) putFeedback(f feedback) (err error) {
+	value, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	return af.storer.PutSiloString(feedbackSilo, f.ID, string(value))
+}
+
+// quoteFeedback continues a block quote on every line of the feedback text
+func quoteFeedback(text string) string {
+	return strings.Replace(text, "\n", "\n>", -1)
+}
@@ -0,0 +1,187 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newFeedbackTestConfig(moderate bool) *viper.Viper {
+	c := viper.New()
+	c.Set("hashKey", "s3cr3t")
+	c.Set("channelIDs", []string{"CLEADS"})
+	c.Set("moderationChannelID", "CMODS")
+	c.Set("moderatorIDs", []string{"UMOD"})
+	c.Set("moderate", moderate)
+	c.Set("maxPerDay", 2)
+
+	return c
+}
+
+func feedbackID(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 36)
+}
+
+func TestAnonymousFeedbackMissingConfig(t *testing.T) {
+	for _, key := range []string{"hashKey", "channelIDs", "moderationChannelID", "moderatorIDs"} {
+		c := newFeedbackTestConfig(false)
+		settings := c.AllSettings()
+		delete(settings, strings.ToLower(key))
+
+		incomplete := viper.New()
+		for k, v := range settings {
+			incomplete.Set(k, v)
+		}
+
+		_, err := NewAnonymousFeedback(incomplete, nil)
+		assert.EqualError(t, err, "Missing anonymousFeedback config key: "+key)
+	}
+}
+
+func TestAnonymousFeedbackPostedAnonymously(t *testing.T) {
+	storer, cleanup := NewTestStorer(t, "anonymousFeedbackTest")
+	defer cleanup()
+
+	clock := &testClock{now: time.Date(2023, time.March, 14, 12, 0, 0, 0, time.UTC)}
+	p, err := newAnonymousFeedback(newFeedbackTestConfig(false), storer, clock.Now)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> feedback <#CLEADS|team-leads> hi"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Feedback is only accepted in a direct message with me to keep it anonymous :shushing_face:")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "D123", User: "U21355", Text: "feedback <#COTHER|random> hi"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, <#COTHER> doesn't accept anonymous feedback :no_entry_sign:")
+	})
+
+	id := feedbackID(clock.now)
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "D123", User: "U21355", Text: "feedback <#CLEADS|team-leads> Standups run too long\nCan we timebox them?"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Thanks! Your feedback was posted anonymously to <#CLEADS> :pray:") &&
+			assert.Equal(t, map[string][]string{"CLEADS": {":speech_balloon: Anonymous feedback:\n>Standups run too long\n>Can we timebox them?\n_Report abuse with `feedback report " + id + "`_"}}, sentMsgs)
+	})
+
+	stored, err := storer.ScanSilo(feedbackSilo)
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+	assert.NotContains(t, stored[id], "U21355")
+}
+
+func TestAnonymousFeedbackRateLimiting(t *testing.T) {
+	storer, cleanup := NewTestStorer(t, "anonymousFeedbackTest")
+	defer cleanup()
+
+	clock := &testClock{now: time.Date(2023, time.March, 14, 12, 0, 0, 0, time.UTC)}
+	p, err := newAnonymousFeedback(newFeedbackTestConfig(false), storer, clock.Now)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	send := func(expectedAnswer string) {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "D123", User: "U21355", Text: "feedback <#CLEADS> more snacks please"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], expectedAnswer)
+		})
+		clock.now = clock.now.Add(time.Hour)
+	}
+
+	send("Thanks! Your feedback was posted anonymously to <#CLEADS> :pray:")
+	send("Thanks! Your feedback was posted anonymously to <#CLEADS> :pray:")
+	send("You've already sent 2 feedback in the last 24 hours, please try again later :hourglass:")
+
+	clock.now = clock.now.Add(22 * time.Hour)
+	send("Thanks! Your feedback was posted anonymously to <#CLEADS> :pray:")
+}
+
+func TestAnonymousFeedbackModeration(t *testing.T) {
+	storer, cleanup := NewTestStorer(t, "anonymousFeedbackTest")
+	defer cleanup()
+
+	clock := &testClock{now: time.Date(2023, time.March, 14, 12, 0, 0, 0, time.UTC)}
+	p, err := newAnonymousFeedback(newFeedbackTestConfig(true), storer, clock.Now)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	approvedID := feedbackID(clock.now)
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "D123", User: "U21355", Text: "feedback <#CLEADS> Great offsite!"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Thanks! Your feedback will be posted to <#CLEADS> once a moderator approves it :pray:") &&
+			assert.Equal(t, map[string][]string{"CMODS": {":inbox_tray: New feedback `" + approvedID + "` for <#CLEADS>:\n>Great offsite!\nApprove with `feedback approve " + approvedID + "` or reject with `feedback reject " + approvedID + "`"}}, sentMsgs)
+	})
+
+	clock.now = clock.now.Add(time.Minute)
+	rejectedID := feedbackID(clock.now)
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "D123", User: "U21355", Text: "feedback <#CLEADS> Meh"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "CMODS", User: "U21356", Text: "<@bot> feedback approve " + approvedID}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, only moderators can do that :no_entry_sign:") && assert.Empty(t, sentMsgs)
+	})
+
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "CMODS", User: "UMOD", Text: "<@bot> feedback approve " + approvedID}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Feedback `"+approvedID+"` posted :ok_hand:") &&
+			assert.Equal(t, map[string][]string{"CLEADS": {":speech_balloon: Anonymous feedback:\n>Great offsite!\n_Report abuse with `feedback report " + approvedID + "`_"}}, sentMsgs)
+	})
+
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "CMODS", User: "UMOD", Text: "<@bot> feedback reject " + rejectedID}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Feedback `"+rejectedID+"` rejected :ok_hand:") && assert.Empty(t, sentMsgs)
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "CMODS", User: "UMOD", Text: "<@bot> feedback approve " + rejectedID}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Feedback `"+rejectedID+"` was already rejected")
+	})
+
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "CLEADS", User: "U21357", Text: "<@bot> feedback report " + rejectedID}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "I don't know any posted feedback `"+rejectedID+"` :shrug:") && assert.Empty(t, sentMsgs)
+	})
+}
+
+func TestAnonymousFeedbackAbuseReportAndBan(t *testing.T) {
+	storer, cleanup := NewTestStorer(t, "anonymousFeedbackTest")
+	defer cleanup()
+
+	clock := &testClock{now: time.Date(2023, time.March, 14, 12, 0, 0, 0, time.UTC)}
+	p, err := newAnonymousFeedback(newFeedbackTestConfig(false), storer, clock.Now)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	id := feedbackID(clock.now)
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "D123", User: "U21355", Text: "feedback <#CLEADS> something abusive"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "CLEADS", User: "U21357", Text: "<@bot> feedback report " + id}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Thanks for the report, the moderators will have a look :eyes:") &&
+			assert.Equal(t, map[string][]string{"CMODS": {":triangular_flag_on_post: Feedback `" + id + "` posted to <#CLEADS> was reported (1 reports so far):\n>something abusive\nBan its sender with `feedback ban " + id + "`"}}, sentMsgs)
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "CMODS", User: "UMOD", Text: "<@bot> feedback ban " + id}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "The sender of feedback `"+id+"` can't send feedback anymore :hammer:")
+	})
+
+	clock.now = clock.now.Add(time.Minute)
+	assertplugin.AnswersAndReactsWithSentMessages(p, &slack.Msg{Channel: "D123", User: "U21355", Text: "feedback <#CLEADS> more abuse"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMsgs map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, you're not allowed to send feedback anymore :no_entry_sign:") && assert.Empty(t, sentMsgs)
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "D456", User: "U21356", Text: "feedback <#CLEADS> unrelated feedback"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Thanks! Your feedback was posted anonymously to <#CLEADS> :pray:")
+	})
+}
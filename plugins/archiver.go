@@ -0,0 +1,284 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/archive"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ArchiverPluginName holds identifying name for the archiver plugin
+	ArchiverPluginName = "archiver"
+)
+
+// Configuration keys
+const (
+	archiverChannelIDsKey        = "channelIDs"        // Channels to archive, defaults to all channels the bot is in
+	archiverIgnoredChannelIDsKey = "ignoredChannelIDs" // Channels never archived
+	archiverRedactedFieldsKey    = "redactedFields"    // Fields removed from archived messages, any of user, text and threadTimestamp
+	archiverRedactionPatternsKey = "redactionPatterns" // Map of names to regular expressions replaced by [REDACTED:<name>] in archived text
+	archiverBatchSizeKey         = "batchSize"         // Number of messages written to the sink at once, defaults to 100
+)
+
+const (
+	defaultArchiverBatchSize = 100
+	// Maximum number of batches buffered while the sink fails. Older messages are dropped past that
+	maxArchiverBufferedBatches = 10
+	archiverOptOutSilo         = "optOut"
+	redactedUserField          = "user"
+	redactedTextField          = "text"
+	redactedThreadField        = "threadTimestamp"
+)
+
+// Archiver holds the plugin data for the archiver plugin
+type Archiver struct {
+	*slackscot.Plugin
+	sink              archive.Sink
+	storer            store.GlobalSiloStringStorer
+	channelIDs        []string
+	ignoredChannelIDs []string
+	redactedFields    map[string]bool
+	redactionPatterns map[string]*regexp.Regexp
+	batchSize         int
+	now               func() time.Time
+
+	// Guards the buffered messages and opted out users since messages and scheduled flushes run concurrently
+	sync.Mutex
+	buffered  []archive.Message
+	optedOut  map[string]bool
+	flushLock sync.Mutex
+}
+
+// NewArchiver creates a new instance of the archiver plugin. It streams the messages of the channels the bot is in
+// to a sink (see the archive package) for analytics. Messages are written in batches of batchSize and at least every
+// minute. Direct messages are never archived and users can opt out of having their messages archived. Fields can
+// be redacted entirely (redactedFields) or partially with regular expressions (redactionPatterns)
+func NewArchiver(c *config.PluginConfig, sink archive.Sink, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	return newArchiver(c, sink, storer, time.Now)
+}
+
+// newArchiver creates a new instance of the archiver plugin using now to get the current time
+func newArchiver(c *config.PluginConfig, sink archive.Sink, storer store.GlobalSiloStringStorer, now func() time.Time) (p *slackscot.Plugin, err error) {
+	c.SetDefault(archiverBatchSizeKey, defaultArchiverBatchSize)
+
+	a := new(Archiver)
+	a.sink = sink
+	a.storer = storer
+	a.now = now
+	a.channelIDs = c.GetStringSlice(archiverChannelIDsKey)
+	a.ignoredChannelIDs = c.GetStringSlice(archiverIgnoredChannelIDsKey)
+	a.batchSize = c.GetInt(archiverBatchSizeKey)
+
+	if a.batchSize <= 0 {
+		return nil, fmt.Errorf("Invalid %s config key value for %s: [%d], should be greater than 0", ArchiverPluginName, archiverBatchSizeKey, a.batchSize)
+	}
+
+	a.redactedFields = make(map[string]bool)
+	for _, field := range c.GetStringSlice(archiverRedactedFieldsKey) {
+		if field != redactedUserField && field != redactedTextField && field != redactedThreadField {
+			return nil, fmt.Errorf("Invalid %s redacted field [%s], should be one of %s, %s or %s", ArchiverPluginName, field, redactedUserField, redactedTextField, redactedThreadField)
+		}
+
+		a.redactedFields[field] = true
+	}
+
+	a.redactionPatterns = make(map[string]*regexp.Regexp)
+	for name, pattern := range c.GetStringMapString(archiverRedactionPatternsKey) {
+		a.redactionPatterns[name], err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s redaction pattern [%s]: %v", ArchiverPluginName, name, err)
+		}
+	}
+
+	optedOut, err := storer.ScanSilo(archiverOptOutSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	a.optedOut = make(map[string]bool)
+	for userID := range optedOut {
+		a.optedOut[userID] = true
+	}
+
+	a.Plugin = plugin.New(ArchiverPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "archive opt out")
+			}).
+			WithUsage("archive opt out").
+			WithDescription("Stops the archival of your messages for analytics").
+			WithAnswerer(a.optOut).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "archive opt in")
+			}).
+			WithUsage("archive opt in").
+			WithDescription("Resumes the archival of your messages for analytics").
+			WithAnswerer(a.optIn).
+			Build()).
+		WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(a.isArchived).
+			WithAnswerer(a.archive).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithInterval(1, schedule.Minutes).Build()).
+			WithDescription("Write buffered messages to the archive").
+			WithAction(a.flush).
+			Build()).
+		WithUserDataCollection(a.dropUserMessages).
+		Build()
+
+	return a.Plugin, nil
+}
+
+// isArchived returns true if the message should be archived
+func (a *Archiver) isArchived(m *slackscot.IncomingMessage) bool {
+	if strings.HasPrefix(m.Channel, "D") || !isChannelEnabled(m.Channel, a.channelIDs, a.ignoredChannelIDs) {
+		return false
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	return !a.optedOut[m.User]
+}
+
+// archive buffers the message, redacted, and writes the buffered messages once there's a full batch
+func (a *Archiver) archive(m *slackscot.IncomingMessage) *slackscot.Answer {
+	message := a.redact(archive.Message{ChannelID: m.Channel, UserID: m.User, Timestamp: m.Timestamp, ThreadTimestamp: m.ThreadTimestamp, Text: m.Text, PostedAt: a.now()})
+
+	if postedAt, err := parseSlackTimestamp(m.Timestamp); err == nil {
+		message.PostedAt = postedAt
+	}
+
+	a.Lock()
+	a.buffered = append(a.buffered, message)
+	full := len(a.buffered) >= a.batchSize
+	a.Unlock()
+
+	if full {
+		a.flush()
+	}
+
+	return nil
+}
+
+// redact applies the redaction rules to the message
+func (a *Archiver) redact(message archive.Message) archive.Message {
+	if a.redactedFields[redactedUserField] {
+		message.UserID = ""
+	}
+
+	if a.redactedFields[redactedThreadField] {
+		message.ThreadTimestamp = ""
+	}
+
+	if a.redactedFields[redactedTextField] {
+		message.Text = ""
+	}
+
+	for name, pattern := range a.redactionPatterns {
+		message.Text = pattern.ReplaceAllString(message.Text, fmt.Sprintf("[REDACTED:%s]", name))
+	}
+
+	return message
+}
+
+// flush writes the buffered messages to the sink in batches. On error, messages are kept for the next
+// flush unless the buffer is full in which case the oldest are dropped
+func (a *Archiver) flush() {
+	// Only one flush at a time to keep messages in order
+	a.flushLock.Lock()
+	defer a.flushLock.Unlock()
+
+	for {
+		a.Lock()
+		batch := a.buffered
+		if len(batch) > a.batchSize {
+			batch = batch[:a.batchSize]
+		}
+		a.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := a.sink.Write(batch); err != nil {
+			a.Logger.Printf("[%s] Error writing [%d] messages to the archive: %v", ArchiverPluginName, len(batch), err)
+			a.dropOverflow()
+			return
+		}
+
+		a.Lock()
+		a.buffered = a.buffered[len(batch):]
+		a.Unlock()
+	}
+}
+
+// dropOverflow drops the oldest buffered messages past the maximum buffer size
+func (a *Archiver) dropOverflow() {
+	a.Lock()
+	defer a.Unlock()
+
+	max := a.batchSize * maxArchiverBufferedBatches
+	if overflow := len(a.buffered) - max; overflow > 0 {
+		a.Logger.Printf("[%s] Dropping [%d] messages after failing to write to the archive", ArchiverPluginName, overflow)
+		a.buffered = a.buffered[overflow:]
+	}
+}
+
+// dropUserMessages drops the buffered messages of a user who opted out of the collection of data about them (see
+// slackscot.UserConsent). Messages already written to the archive have to be deleted from the sink
+func (a *Archiver) dropUserMessages(userID string) (err error) {
+	a.Lock()
+	defer a.Unlock()
+
+	kept := a.buffered[:0]
+	for _, m := range a.buffered {
+		if m.UserID != userID {
+			kept = append(kept, m)
+		}
+	}
+	a.buffered = kept
+
+	return nil
+}
+
+// optOut stops the archival of the user's messages
+func (a *Archiver) optOut(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := a.storer.PutSiloString(archiverOptOutSilo, m.User, "true"); err != nil {
+		a.Logger.Printf("[%s] Error opting out [%s]: %v", ArchiverPluginName, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't opt you out :disappointed: (%v)", err)}
+	}
+
+	a.Lock()
+	a.optedOut[m.User] = true
+	a.Unlock()
+
+	return &slackscot.Answer{Text: "You're opted out, I won't archive your messages :shushing_face:"}
+}
+
+// optIn resumes the archival of the user's messages
+func (a *Archiver) optIn(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := a.storer.DeleteSiloString(archiverOptOutSilo, m.User); err != nil {
+		a.Logger.Printf("[%s] Error opting in [%s]: %v", ArchiverPluginName, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't opt you in :disappointed: (%v)", err)}
+	}
+
+	a.Lock()
+	delete(a.optedOut, m.User)
+	a.Unlock()
+
+	return &slackscot.Answer{Text: "You're opted in, I'll archive your messages :card_file_box:"}
+}
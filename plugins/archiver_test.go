@@ -0,0 +1,163 @@
+package plugins_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/archive"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestArchiverInvalidRedactedField(t *testing.T) {
+	pc := viper.New()
+	pc.Set("redactedFields", []string{"email"})
+
+	_, err := plugins.NewArchiver(pc, nil, nil)
+	assert.EqualError(t, err, "Invalid archiver redacted field [email], should be one of user, text or threadTimestamp")
+}
+
+func TestArchiverInvalidBatchSize(t *testing.T) {
+	pc := viper.New()
+	pc.Set("batchSize", 0)
+
+	_, err := plugins.NewArchiver(pc, nil, nil)
+	assert.EqualError(t, err, "Invalid archiver config key value for batchSize: [0], should be greater than 0")
+}
+
+func TestArchiverWritesRedactedMessagesInBatches(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "archiverTest")
+	defer cleanup()
+
+	var batches [][]archive.Message
+	sink := archive.SinkFunc(func(messages []archive.Message) error {
+		batches = append(batches, messages)
+		return nil
+	})
+
+	pc := viper.New()
+	pc.Set("ignoredChannelIDs", []string{"Cprivate"})
+	pc.Set("redactedFields", []string{"user"})
+	pc.Set("redactionPatterns", map[string]string{"email": "[a-z.]+@[a-z.]+"})
+	pc.Set("batchSize", 2)
+
+	p, err := plugins.NewArchiver(pc, sink, storer)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	hears := func(channel string, user string, ts string, text string) {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: channel, User: user, Timestamp: ts, Text: text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		})
+	}
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "Ushy", Text: "<@bot> archive opt out"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "You're opted out, I won't archive your messages :shushing_face:")
+	})
+
+	hears("Cgeneral", "U1", "1583139600.000000", "write to jane.doe@example.com")
+	hears("Cprivate", "U1", "1583139601.000000", "secret")
+	hears("Cgeneral", "Ushy", "1583139602.000000", "not for analytics")
+	assert.Empty(t, batches)
+
+	hears("Cgeneral", "U2", "1583139603.000000", "hello")
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, []archive.Message{
+			{ChannelID: "Cgeneral", Timestamp: "1583139600.000000", Text: "write to [REDACTED:email]", PostedAt: time.Unix(1583139600, 0).UTC()},
+			{ChannelID: "Cgeneral", Timestamp: "1583139603.000000", Text: "hello", PostedAt: time.Unix(1583139603, 0).UTC()},
+		}, batches[0])
+	}
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "Ushy", Text: "<@bot> archive opt in"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "You're opted in, I'll archive your messages :card_file_box:")
+	})
+
+	hears("Cgeneral", "Ushy", "1583139604.000000", "ok then")
+	assertplugin.RunsOnSchedule(p, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, sentMsgs)
+	})
+
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, []archive.Message{{ChannelID: "Cgeneral", Timestamp: "1583139604.000000", Text: "ok then", PostedAt: time.Unix(1583139604, 0).UTC()}}, batches[1])
+	}
+}
+
+func TestArchiverKeepsMessagesOnSinkErrors(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "archiverTest")
+	defer cleanup()
+
+	var written []archive.Message
+	failing := true
+	sink := archive.SinkFunc(func(messages []archive.Message) error {
+		if failing {
+			return fmt.Errorf("unavailable")
+		}
+
+		written = append(written, messages...)
+		return nil
+	})
+
+	pc := viper.New()
+	pc.Set("batchSize", 1)
+
+	p, err := plugins.NewArchiver(pc, sink, storer)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	for i := 0; i < 12; i++ {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U1", Timestamp: fmt.Sprintf("15831396%02d.000000", i), Text: fmt.Sprintf("message %d", i)}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		})
+	}
+
+	failing = false
+	assertplugin.RunsOnSchedule(p, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return true
+	})
+
+	// Only the last 10 batches are kept while the sink fails
+	if assert.Len(t, written, 10) {
+		assert.Equal(t, "message 2", written[0].Text)
+		assert.Equal(t, "message 11", written[9].Text)
+	}
+}
+
+func TestArchiverDropsBufferedMessagesOfUsersWhoOptedOut(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "archiverTest")
+	defer cleanup()
+
+	var batches [][]archive.Message
+	sink := archive.SinkFunc(func(messages []archive.Message) error {
+		batches = append(batches, messages)
+		return nil
+	})
+
+	pc := viper.New()
+	pc.Set("batchSize", 4)
+
+	p, err := plugins.NewArchiver(pc, sink, storer)
+	require.NoError(t, err)
+	require.True(t, p.CollectsUserData)
+
+	assertplugin := assertplugin.New(t, "bot")
+	for i, user := range []string{"U1", "Ushy", "U1"} {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: user, Timestamp: fmt.Sprintf("158313960%d.000000", i), Text: "hello"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		})
+	}
+
+	require.NoError(t, p.PurgeUserData("U1"))
+	assertplugin.RunsOnSchedule(p, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, sentMsgs)
+	})
+
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, []archive.Message{{ChannelID: "Cgeneral", UserID: "Ushy", Timestamp: "1583139601.000000", Text: "hello", PostedAt: time.Unix(1583139601, 0).UTC()}}, batches[0])
+	}
+}
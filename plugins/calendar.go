@@ -0,0 +1,229 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// CalendarPluginName holds identifying name for the calendar plugin
+	CalendarPluginName = "calendar"
+)
+
+// Configuration keys
+const (
+	calendarsKey          = "calendars"    // Map of channel IDs to the ID of the calendar of the team of that channel
+	calendarAgendaTimeKey = "agendaAtTime" // Time of the day the agenda is posted on each channel, defaults to 08:00
+	calendarTimezoneKey   = "timezone"     // Timezone (i.e. America/Los_Angeles) used for the day's boundaries and times. Defaults to the local timezone
+)
+
+const (
+	defaultAgendaAtTime = "08:00"
+	calendarTokensSilo  = "calendarTokens"
+)
+
+var meetingsTodayRegex = regexp.MustCompile("(?i)\\Awhat meetings (?:does <#(\\w+)(?:\\|[^>]*)?>|do we) have today\\??\\z")
+
+// CalendarEvent is a meeting on a calendar
+type CalendarEvent struct {
+	Title string
+	Start time.Time
+	End   time.Time
+	Room  string
+}
+
+// CalendarProvider is implemented by calendar services (i.e. Google Calendar or Outlook)
+type CalendarProvider interface {
+	// GetEvents returns the events of a calendar starting before to and ending after from
+	GetEvents(calendarID string, from time.Time, to time.Time) (events []CalendarEvent, err error)
+}
+
+// CalendarProviderFunc is an adapter to allow the use of a function as a CalendarProvider
+type CalendarProviderFunc func(calendarID string, from time.Time, to time.Time) (events []CalendarEvent, err error)
+
+// GetEvents calls f(calendarID, from, to)
+func (f CalendarProviderFunc) GetEvents(calendarID string, from time.Time, to time.Time) (events []CalendarEvent, err error) {
+	return f(calendarID, from, to)
+}
+
+// CalendarTokenStore persists the OAuth tokens of calendar providers. Providers are free to encode
+// tokens as they wish (i.e. the JSON encoding of an oauth2.Token)
+type CalendarTokenStore struct {
+	storer store.GlobalSiloStringStorer
+}
+
+// NewCalendarTokenStore creates a new CalendarTokenStore persisting tokens with the storer
+func NewCalendarTokenStore(storer store.GlobalSiloStringStorer) (ts *CalendarTokenStore) {
+	return &CalendarTokenStore{storer: storer}
+}
+
+// PutToken saves the token to access a calendar
+func (ts *CalendarTokenStore) PutToken(calendarID string, token string) (err error) {
+	return ts.storer.PutSiloString(calendarTokensSilo, calendarID, token)
+}
+
+// GetToken returns the token to access a calendar
+func (ts *CalendarTokenStore) GetToken(calendarID string) (token string, err error) {
+	return ts.storer.GetSiloString(calendarTokensSilo, calendarID)
+}
+
+// DeleteToken removes the token to access a calendar
+func (ts *CalendarTokenStore) DeleteToken(calendarID string) (err error) {
+	return ts.storer.DeleteSiloString(calendarTokensSilo, calendarID)
+}
+
+// Calendar holds the plugin data for the calendar plugin
+type Calendar struct {
+	*slackscot.Plugin
+	provider  CalendarProvider
+	calendars map[string]string
+	location  *time.Location
+	now       func() time.Time
+}
+
+// NewCalendar creates a new instance of the calendar plugin. It answers about the meetings of the day of a channel's
+// team and posts the daily agenda on each channel with a calendar, warning about room conflicts
+func NewCalendar(c *config.PluginConfig, provider CalendarProvider) (p *slackscot.Plugin, err error) {
+	cal, err := newCalendar(c, provider, time.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	return cal.Plugin, nil
+}
+
+// newCalendar creates a new instance of the calendar plugin with the given function to get the current time
+func newCalendar(c *config.PluginConfig, provider CalendarProvider, now func() time.Time) (cal *Calendar, err error) {
+	c.SetDefault(calendarAgendaTimeKey, defaultAgendaAtTime)
+
+	cal = new(Calendar)
+	cal.provider = provider
+	cal.now = now
+
+	cal.location = time.Local
+	if tz := c.GetString(calendarTimezoneKey); tz != "" {
+		if cal.location, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("Invalid %s timezone [%s]: %v", CalendarPluginName, tz, err)
+		}
+	}
+
+	// Configuration map keys are lowercased when loaded so channel IDs are restored to their original upper case
+	cal.calendars = make(map[string]string)
+	for channelID, calendarID := range c.GetStringMapString(calendarsKey) {
+		cal.calendars[strings.ToUpper(channelID)] = calendarID
+	}
+
+	cal.Plugin = plugin.New(CalendarPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return meetingsTodayRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("what meetings does <#channel> have today").
+			WithDescription("Lists the meetings of the day of a channel's team (or of this channel's team with `what meetings do we have today`)").
+			WithAnswerer(cal.meetingsToday).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithUnit(schedule.Days).AtTime(c.GetString(calendarAgendaTimeKey)).Build()).
+			WithDescription("Post the daily agenda on each channel with a calendar").
+			WithAction(cal.postAgendas).
+			Build()).
+		Build()
+
+	return cal, nil
+}
+
+// meetingsToday answers with the agenda of the day of the requested channel
+func (cal *Calendar) meetingsToday(m *slackscot.IncomingMessage) *slackscot.Answer {
+	channelID := meetingsTodayRegex.FindStringSubmatch(m.NormalizedText)[1]
+	if channelID == "" {
+		channelID = m.Channel
+	}
+
+	if _, ok := cal.calendars[channelID]; !ok {
+		return &slackscot.Answer{Text: fmt.Sprintf("I don't know the calendar of <#%s> :shrug:", channelID)}
+	}
+
+	agenda, err := cal.agenda(channelID)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't get the meetings of <#%s> :disappointed: (%v)", channelID, err)}
+	}
+
+	return &slackscot.Answer{Text: agenda}
+}
+
+// postAgendas posts the agenda of the day on every channel with a calendar
+func (cal *Calendar) postAgendas() {
+	channelIDs := make([]string, 0, len(cal.calendars))
+	for channelID := range cal.calendars {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for _, channelID := range channelIDs {
+		agenda, err := cal.agenda(channelID)
+		if err != nil {
+			continue
+		}
+
+		cal.RealTimeMsgSender.SendMessage(cal.RealTimeMsgSender.NewOutgoingMessage(agenda, channelID))
+	}
+}
+
+// agenda renders the meetings of the day of a channel's calendar along with room conflicts
+func (cal *Calendar) agenda(channelID string) (agenda string, err error) {
+	now := cal.now().In(cal.location)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cal.location)
+
+	events, err := cal.provider.GetEvents(cal.calendars[channelID], startOfDay, startOfDay.AddDate(0, 0, 1))
+	if err != nil {
+		cal.Logger.Printf("[%s] Error getting events of calendar [%s] for [%s]: %v", CalendarPluginName, cal.calendars[channelID], channelID, err)
+		return "", err
+	}
+
+	if len(events) == 0 {
+		return fmt.Sprintf("No meetings today for <#%s> :tada:", channelID), nil
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Today's meetings for <#%s>* :calendar:", channelID)
+	for _, e := range events {
+		fmt.Fprintf(&b, "\n• %s-%s %s", e.Start.In(cal.location).Format("15:04"), e.End.In(cal.location).Format("15:04"), e.Title)
+		if e.Room != "" {
+			fmt.Fprintf(&b, " (%s)", e.Room)
+		}
+	}
+
+	for _, conflict := range findRoomConflicts(events) {
+		fmt.Fprintf(&b, "\n:warning: Room conflict in *%s*: %s and %s overlap", conflict[0].Room, conflict[0].Title, conflict[1].Title)
+	}
+
+	return b.String(), nil
+}
+
+// findRoomConflicts returns all pairs of events booked in the same room at overlapping times. Events must be sorted by start time
+func findRoomConflicts(events []CalendarEvent) (conflicts [][2]CalendarEvent) {
+	conflicts = make([][2]CalendarEvent, 0)
+
+	for i := 0; i < len(events); i++ {
+		for j := i + 1; j < len(events) && events[j].Start.Before(events[i].End); j++ {
+			if events[i].Room != "" && strings.EqualFold(events[i].Room, events[j].Room) {
+				conflicts = append(conflicts, [2]CalendarEvent{events[i], events[j]})
+			}
+		}
+	}
+
+	return conflicts
+}
@@ -0,0 +1,110 @@
+package plugins_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// teamEvents returns events relative to the start of the requested day
+func teamEvents(calendarID string, from time.Time, to time.Time) (events []plugins.CalendarEvent, err error) {
+	switch calendarID {
+	case "team@example.com":
+		return []plugins.CalendarEvent{
+			{Title: "Planning", Start: from.Add(10 * time.Hour), End: from.Add(11 * time.Hour), Room: "Everest"},
+			{Title: "Standup", Start: from.Add(9 * time.Hour), End: from.Add(9*time.Hour + 15*time.Minute)},
+			{Title: "Design review", Start: from.Add(10*time.Hour + 30*time.Minute), End: from.Add(12 * time.Hour), Room: "everest"},
+		}, nil
+	case "quiet@example.com":
+		return []plugins.CalendarEvent{}, nil
+	}
+
+	return nil, fmt.Errorf("calendar not found")
+}
+
+func newCalendarConfig() (c *viper.Viper) {
+	c = viper.New()
+	c.Set("timezone", "UTC")
+	c.Set("calendars", map[string]string{"Cteam": "team@example.com", "Cquiet": "quiet@example.com", "Cbroken": "broken@example.com"})
+
+	return c
+}
+
+func TestCalendarMeetingsToday(t *testing.T) {
+	p, err := plugins.NewCalendar(newCalendarConfig(), plugins.CalendarProviderFunc(teamEvents))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		channel        string
+		text           string
+		expectedAnswer string
+	}{
+		{"Cgeneral", "<@bot> what meetings does <#CTEAM|team> have today?", "*Today's meetings for <#CTEAM>* :calendar:\n• 09:00-09:15 Standup\n• 10:00-11:00 Planning (Everest)\n• 10:30-12:00 Design review (everest)\n:warning: Room conflict in *Everest*: Planning and Design review overlap"},
+		{"CQUIET", "<@bot> what meetings do we have today", "No meetings today for <#CQUIET> :tada:"},
+		{"Cgeneral", "<@bot> what meetings does <#CUNKNOWN> have today", "I don't know the calendar of <#CUNKNOWN> :shrug:"},
+		{"Cgeneral", "<@bot> what meetings does <#CBROKEN> have today", "Sorry, I couldn't get the meetings of <#CBROKEN> :disappointed: (calendar not found)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin := assertplugin.New(t, "bot")
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: tc.channel, User: "U21355", Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
+
+func TestCalendarDailyAgenda(t *testing.T) {
+	p, err := plugins.NewCalendar(newCalendarConfig(), plugins.CalendarProviderFunc(teamEvents))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.RunsOnSchedule(p, schedule.New().WithUnit(schedule.Days).AtTime("08:00").Build(), func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{
+			"CQUIET": {"No meetings today for <#CQUIET> :tada:"},
+			"CTEAM":  {"*Today's meetings for <#CTEAM>* :calendar:\n• 09:00-09:15 Standup\n• 10:00-11:00 Planning (Everest)\n• 10:30-12:00 Design review (everest)\n:warning: Room conflict in *Everest*: Planning and Design review overlap"},
+		}, sentMsgs)
+	})
+}
+
+func TestCalendarInvalidTimezone(t *testing.T) {
+	c := newCalendarConfig()
+	c.Set("timezone", "Mars/Olympus_Mons")
+
+	_, err := plugins.NewCalendar(c, plugins.CalendarProviderFunc(teamEvents))
+	assert.EqualError(t, err, "Invalid calendar timezone [Mars/Olympus_Mons]: unknown time zone Mars/Olympus_Mons")
+}
+
+func TestCalendarTokenStore(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("calendarTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	ts := plugins.NewCalendarTokenStore(storer)
+	require.NoError(t, ts.PutToken("team@example.com", `{"access_token":"abc"}`))
+
+	token, err := ts.GetToken("team@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, `{"access_token":"abc"}`, token)
+
+	require.NoError(t, ts.DeleteToken("team@example.com"))
+	_, err = ts.GetToken("team@example.com")
+	assert.Error(t, err)
+}
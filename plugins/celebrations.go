@@ -0,0 +1,357 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// CelebrationsPluginName holds identifying name for the celebrations plugin
+	CelebrationsPluginName = "celebrations"
+)
+
+// Configuration keys
+const (
+	celebrationsChannelIDKey = "channelID" // Channel where celebration messages are posted, required
+	celebrationsAtHourKey    = "atHour"    // Hour of the day (in each user's own timezone) at which celebrations are posted. Defaults to 9
+	celebrationsAdminIDsKey  = "adminIDs"  // IDs of the users allowed to import celebrations
+)
+
+const (
+	defaultCelebrationsAtHour = 9
+	birthdayKind              = "birthday"
+	anniversaryKind           = "anniversary"
+	celebrationsOptOutSilo    = "optOut"
+	celebratedSilo            = "celebrated"
+	birthdayLayout            = "01-02"
+	anniversaryLayout         = "2006-01-02"
+)
+
+var rememberBirthdayRegex = regexp.MustCompile("(?i)\\Aremember my birthday is (\\d{2}-\\d{2})\\s*\\z")
+var rememberAnniversaryRegex = regexp.MustCompile("(?i)\\Aremember my work anniversary is (\\d{4}-\\d{2}-\\d{2})\\s*\\z")
+var forgetCelebrationRegex = regexp.MustCompile("(?i)\\Aforget my (birthday|work anniversary)\\s*\\z")
+var importCelebrationRegex = regexp.MustCompile("(?i)<@(\\w+)>\\s+(birthday|anniversary)\\s+(\\d{4}-\\d{2}-\\d{2}|\\d{2}-\\d{2})")
+
+// Celebrations holds the plugin data for the celebrations plugin. Users register their birthday and work anniversary
+// and a message is posted on the configured channel on the day, at the configured hour of each user's own timezone
+type Celebrations struct {
+	*slackscot.Plugin
+	storer    store.GlobalSiloStringStorer
+	channelID string
+	atHour    int
+	admins    map[string]bool
+	now       func() time.Time
+}
+
+// NewCelebrations creates a new instance of the celebrations plugin. Birthdays, anniversaries and opt-outs are persisted
+// with the storer, in silos by kind
+func NewCelebrations(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	cb, err := newCelebrations(c, storer, time.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	return cb.Plugin, nil
+}
+
+// newCelebrations creates a new instance of the celebrations plugin using the now function to get the current time
+func newCelebrations(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (cb *Celebrations, err error) {
+	if ok := c.IsSet(celebrationsChannelIDKey); !ok {
+		return nil, fmt.Errorf("Missing %s config key: %s", CelebrationsPluginName, celebrationsChannelIDKey)
+	}
+
+	c.SetDefault(celebrationsAtHourKey, defaultCelebrationsAtHour)
+
+	cb = new(Celebrations)
+	cb.storer = storer
+	cb.channelID = c.GetString(celebrationsChannelIDKey)
+	cb.atHour = c.GetInt(celebrationsAtHourKey)
+	cb.now = now
+	cb.admins = make(map[string]bool)
+	for _, a := range c.GetStringSlice(celebrationsAdminIDsKey) {
+		cb.admins[a] = true
+	}
+
+	if cb.atHour < 0 || cb.atHour > 23 {
+		return nil, fmt.Errorf("Invalid %s config key value for %s: [%d], should be between 0 and 23", CelebrationsPluginName, celebrationsAtHourKey, cb.atHour)
+	}
+
+	cb.Plugin = plugin.New(CelebrationsPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return rememberBirthdayRegex.MatchString(m.NormalizedText) }).
+			WithUsage("remember my birthday is <MM-DD>").
+			WithDescription("Registers your birthday to be celebrated").
+			WithAnswerer(cb.rememberBirthday).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return rememberAnniversaryRegex.MatchString(m.NormalizedText) }).
+			WithUsage("remember my work anniversary is <YYYY-MM-DD>").
+			WithDescription("Registers your work anniversary (the day you started) to be celebrated").
+			WithAnswerer(cb.rememberAnniversary).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return forgetCelebrationRegex.MatchString(m.NormalizedText) }).
+			WithUsage("forget my birthday|work anniversary").
+			WithDescription("Deletes your registered birthday or work anniversary").
+			WithAnswerer(cb.forget).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "celebrations opt out")
+			}).
+			WithUsage("celebrations opt out").
+			WithDescription("Stops any celebration of your birthday and work anniversary").
+			WithAnswerer(cb.optOut).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "celebrations opt in")
+			}).
+			WithUsage("celebrations opt in").
+			WithDescription("Resumes the celebration of your birthday and work anniversary").
+			WithAnswerer(cb.optIn).
+			Build()).
+		WithCommand(actions.NewCommand().
+			Hidden().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "import celebrations")
+			}).
+			WithUsage("import celebrations <@user> birthday|anniversary <date> ...").
+			WithDescription("Imports celebrations in bulk, one `<@user> birthday <MM-DD>` or `<@user> anniversary <YYYY-MM-DD>` per line (admins only)").
+			WithAnswerer(cb.importCelebrations).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithInterval(1, schedule.Hours).Build()).
+			WithDescription("Post the celebrations of users for whom it's the day and the celebration hour").
+			WithAction(cb.celebrate).
+			Build()).
+		Build()
+
+	return cb, nil
+}
+
+// rememberBirthday registers the birthday of the user
+func (cb *Celebrations) rememberBirthday(m *slackscot.IncomingMessage) *slackscot.Answer {
+	date := rememberBirthdayRegex.FindStringSubmatch(m.NormalizedText)[1]
+	return cb.remember(m.User, birthdayKind, date)
+}
+
+// rememberAnniversary registers the work anniversary of the user
+func (cb *Celebrations) rememberAnniversary(m *slackscot.IncomingMessage) *slackscot.Answer {
+	date := rememberAnniversaryRegex.FindStringSubmatch(m.NormalizedText)[1]
+	return cb.remember(m.User, anniversaryKind, date)
+}
+
+// remember validates and persists the date of a user's celebration of the given kind
+func (cb *Celebrations) remember(userID string, kind string, date string) *slackscot.Answer {
+	if err := cb.putCelebration(userID, kind, date); err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't remember your %s :disappointed: (%v)", kind, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Got it, I'll celebrate your %s on `%s` :tada:", kind, date)}
+}
+
+// putCelebration validates and persists the date of a user's celebration of the given kind
+func (cb *Celebrations) putCelebration(userID string, kind string, date string) (err error) {
+	layout := birthdayLayout
+	if kind == anniversaryKind {
+		layout = anniversaryLayout
+	}
+
+	if _, err := time.Parse(layout, date); err != nil {
+		return fmt.Errorf("invalid date [%s]", date)
+	}
+
+	if err := cb.storer.PutSiloString(kind, userID, date); err != nil {
+		cb.Logger.Printf("[%s] Error persisting %s of [%s]: %v", CelebrationsPluginName, kind, userID, err)
+		return err
+	}
+
+	return nil
+}
+
+// forget deletes the user's birthday or work anniversary
+func (cb *Celebrations) forget(m *slackscot.IncomingMessage) *slackscot.Answer {
+	kind := birthdayKind
+	if strings.ToLower(forgetCelebrationRegex.FindStringSubmatch(m.NormalizedText)[1]) != birthdayKind {
+		kind = anniversaryKind
+	}
+
+	if err := cb.storer.DeleteSiloString(kind, m.User); err != nil {
+		cb.Logger.Printf("[%s] Error deleting %s of [%s]: %v", CelebrationsPluginName, kind, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't forget your %s :disappointed: (%v)", kind, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Your %s is forgotten :wastebasket:", kind)}
+}
+
+// optOut stops the celebrations of the user
+func (cb *Celebrations) optOut(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := cb.storer.PutSiloString(celebrationsOptOutSilo, m.User, "true"); err != nil {
+		cb.Logger.Printf("[%s] Error opting out [%s]: %v", CelebrationsPluginName, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't opt you out :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: "You're opted out, I won't celebrate you :shushing_face:"}
+}
+
+// optIn resumes the celebrations of the user
+func (cb *Celebrations) optIn(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := cb.storer.DeleteSiloString(celebrationsOptOutSilo, m.User); err != nil {
+		cb.Logger.Printf("[%s] Error opting in [%s]: %v", CelebrationsPluginName, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't opt you in :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: "You're opted in, I'll celebrate you :tada:"}
+}
+
+// importCelebrations registers celebrations of many users at once. Only admins can import
+func (cb *Celebrations) importCelebrations(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if !cb.admins[m.User] {
+		return &slackscot.Answer{Text: "Sorry, only admins can import celebrations :no_entry_sign:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+	}
+
+	imported := 0
+	failures := make([]string, 0)
+	for _, match := range importCelebrationRegex.FindAllStringSubmatch(m.NormalizedText, -1) {
+		userID, kind, date := match[1], strings.ToLower(match[2]), match[3]
+
+		if err := cb.putCelebration(userID, kind, date); err != nil {
+			failures = append(failures, fmt.Sprintf("<@%s> %s %s (%v)", userID, kind, date, err))
+			continue
+		}
+
+		imported++
+	}
+
+	text := fmt.Sprintf("Imported %d celebration(s)", imported)
+	if len(failures) > 0 {
+		text = fmt.Sprintf("%s, failed to import:\n%s", text, strings.Join(failures, "\n"))
+	}
+
+	return &slackscot.Answer{Text: text}
+}
+
+// celebrate posts the celebrations of users for whom it's the day and the celebration hour in their own timezone.
+// Celebrations posted are recorded so that each is only posted once a year
+func (cb *Celebrations) celebrate() {
+	optedOut, err := cb.storer.ScanSilo(celebrationsOptOutSilo)
+	if err != nil {
+		cb.Logger.Printf("[%s] Error loading opt-outs: %v", CelebrationsPluginName, err)
+		return
+	}
+
+	for _, kind := range []string{birthdayKind, anniversaryKind} {
+		dates, err := cb.storer.ScanSilo(kind)
+		if err != nil {
+			cb.Logger.Printf("[%s] Error loading %s dates: %v", CelebrationsPluginName, kind, err)
+			continue
+		}
+
+		userIDs := make([]string, 0, len(dates))
+		for userID := range dates {
+			userIDs = append(userIDs, userID)
+		}
+		sort.Strings(userIDs)
+
+		for _, userID := range userIDs {
+			if _, ok := optedOut[userID]; !ok {
+				cb.celebrateUser(userID, kind, dates[userID])
+			}
+		}
+	}
+}
+
+// celebrateUser posts the celebration of a kind for a user if it's the day and hour in the user's timezone
+func (cb *Celebrations) celebrateUser(userID string, kind string, date string) {
+	localNow := cb.now().In(cb.userLocation(userID))
+	if localNow.Hour() != cb.atHour {
+		return
+	}
+
+	message, isToday := celebrationMessage(userID, kind, date, localNow)
+	if !isToday {
+		return
+	}
+
+	celebratedKey := fmt.Sprintf("%s:%s", kind, userID)
+	today := localNow.Format(anniversaryLayout)
+	if last, err := cb.storer.GetSiloString(celebratedSilo, celebratedKey); err == nil && last == today {
+		return
+	}
+
+	cb.RealTimeMsgSender.SendMessage(cb.RealTimeMsgSender.NewOutgoingMessage(message, cb.channelID))
+
+	if err := cb.storer.PutSiloString(celebratedSilo, celebratedKey, today); err != nil {
+		cb.Logger.Printf("[%s] Error recording %s celebration of [%s]: %v", CelebrationsPluginName, kind, userID, err)
+	}
+}
+
+// userLocation returns the time location of the user or the local one if it's unknown
+func (cb *Celebrations) userLocation(userID string) (loc *time.Location) {
+	user, err := cb.UserInfoFinder.GetUserInfo(userID)
+	if err != nil || user.TZ == "" {
+		return time.Local
+	}
+
+	loc, err = time.LoadLocation(user.TZ)
+	if err != nil {
+		cb.Logger.Debugf("[%s] Unknown time zone [%s] for [%s], using local time: %v", CelebrationsPluginName, user.TZ, userID, err)
+		return time.Local
+	}
+
+	return loc
+}
+
+// celebrationMessage returns the message celebrating a user on the day along with whether or not the celebration
+// is on that day. Birthdays on February 29th are celebrated on February 28th on non-leap years
+func celebrationMessage(userID string, kind string, date string, day time.Time) (message string, isToday bool) {
+	if kind == birthdayKind {
+		birthday, err := time.Parse(birthdayLayout, date)
+		if err != nil || !isSameDayOfYear(birthday, day) {
+			return "", false
+		}
+
+		return fmt.Sprintf(":birthday: Happy birthday <@%s>! :tada:", userID), true
+	}
+
+	start, err := time.Parse(anniversaryLayout, date)
+	if err != nil || !isSameDayOfYear(start, day) {
+		return "", false
+	}
+
+	years := day.Year() - start.Year()
+	if years < 1 {
+		return "", false
+	}
+
+	unit := "years"
+	if years == 1 {
+		unit = "year"
+	}
+
+	return fmt.Sprintf(":confetti_ball: Happy work anniversary <@%s>! %d %s already :clap:", userID, years, unit), true
+}
+
+// isSameDayOfYear returns true if the date falls on the same month and day as the given day
+func isSameDayOfYear(date time.Time, day time.Time) bool {
+	if date.Month() == time.February && date.Day() == 29 && !isLeapYear(day.Year()) {
+		return day.Month() == time.February && day.Day() == 28
+	}
+
+	return date.Month() == day.Month() && date.Day() == day.Day()
+}
+
+// isLeapYear returns true if the year is a leap year
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
@@ -0,0 +1,94 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+type tzUserInfoFinder struct {
+	timezones map[string]string
+}
+
+func (f tzUserInfoFinder) GetUserInfo(userID string) (user *slack.User, err error) {
+	tz, ok := f.timezones[userID]
+	if !ok {
+		return nil, fmt.Errorf("user [%s] not found", userID)
+	}
+
+	return &slack.User{ID: userID, TZ: tz}, nil
+}
+
+func TestCelebrationsPostedAtUserLocalHour(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("celebrationsTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	require.NoError(t, storer.PutSiloString("birthday", "Utokyo", "03-14"))
+	require.NoError(t, storer.PutSiloString("birthday", "Umontreal", "03-14"))
+	require.NoError(t, storer.PutSiloString("birthday", "Uleap", "02-29"))
+	require.NoError(t, storer.PutSiloString("anniversary", "Umontreal", "2015-03-14"))
+	require.NoError(t, storer.PutSiloString("anniversary", "Unewbie", "2023-03-14"))
+	require.NoError(t, storer.PutSiloString("birthday", "Ushy", "03-14"))
+	require.NoError(t, storer.PutSiloString("optOut", "Ushy", "true"))
+
+	pc := viper.New()
+	pc.Set("channelID", "Ccelebrations")
+
+	var now time.Time
+	cb, err := newCelebrations(pc, storer, func() time.Time { return now })
+	require.NoError(t, err)
+	cb.UserInfoFinder = tzUserInfoFinder{timezones: map[string]string{"Utokyo": "Asia/Tokyo", "Umontreal": "America/Montreal", "Uleap": "America/Montreal", "Unewbie": "America/Montreal", "Ushy": "America/Montreal"}}
+
+	everyHour := schedule.New().WithInterval(1, schedule.Hours).Build()
+	assertplugin := assertplugin.New(t, "bot")
+
+	// 09:00 in Tokyo on March 14th
+	now = time.Date(2023, time.March, 14, 0, 30, 0, 0, time.UTC)
+	assertplugin.RunsOnSchedule(cb.Plugin, everyHour, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Ccelebrations": {":birthday: Happy birthday <@Utokyo>! :tada:"}}, sentMsgs)
+	})
+
+	// Running again in the same hour doesn't celebrate twice
+	assertplugin.RunsOnSchedule(cb.Plugin, everyHour, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, sentMsgs)
+	})
+
+	// 09:00 in Montreal on March 14th
+	now = time.Date(2023, time.March, 14, 13, 30, 0, 0, time.UTC)
+	assertplugin.RunsOnSchedule(cb.Plugin, everyHour, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Ccelebrations": {
+			":birthday: Happy birthday <@Umontreal>! :tada:",
+			":confetti_ball: Happy work anniversary <@Umontreal>! 8 years already :clap:",
+		}}, sentMsgs)
+	})
+
+	// 09:00 in Montreal on February 28th of a non-leap year
+	now = time.Date(2023, time.February, 28, 14, 30, 0, 0, time.UTC)
+	assertplugin.RunsOnSchedule(cb.Plugin, everyHour, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Ccelebrations": {":birthday: Happy birthday <@Uleap>! :tada:"}}, sentMsgs)
+	})
+
+	// A year later, everyone is celebrated again including the one year anniversary
+	now = time.Date(2024, time.March, 14, 13, 30, 0, 0, time.UTC)
+	assertplugin.RunsOnSchedule(cb.Plugin, everyHour, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Ccelebrations": {
+			":birthday: Happy birthday <@Umontreal>! :tada:",
+			":confetti_ball: Happy work anniversary <@Umontreal>! 9 years already :clap:",
+			":confetti_ball: Happy work anniversary <@Unewbie>! 1 year already :clap:",
+		}}, sentMsgs)
+	})
+}
@@ -0,0 +1,82 @@
+package plugins_test
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCelebrationsMissingChannelID(t *testing.T) {
+	_, err := plugins.NewCelebrations(viper.New(), nil)
+	assert.EqualError(t, err, "Missing celebrations config key: channelID")
+}
+
+func TestCelebrationsInvalidAtHour(t *testing.T) {
+	pc := viper.New()
+	pc.Set("channelID", "Cgeneral")
+	pc.Set("atHour", 24)
+
+	_, err := plugins.NewCelebrations(pc, nil)
+	assert.EqualError(t, err, "Invalid celebrations config key value for atHour: [24], should be between 0 and 23")
+}
+
+func TestCelebrationsCommands(t *testing.T) {
+	testCases := []struct {
+		user           string
+		text           string
+		expectedAnswer string
+	}{
+		{"U21355", "<@bot> remember my birthday is 03-14", "Got it, I'll celebrate your birthday on `03-14` :tada:"},
+		{"U21355", "<@bot> remember my birthday is 13-14", "Sorry, I couldn't remember your birthday :disappointed: (invalid date [13-14])"},
+		{"U21355", "<@bot> remember my work anniversary is 2015-06-01", "Got it, I'll celebrate your anniversary on `2015-06-01` :tada:"},
+		{"U21355", "<@bot> forget my work anniversary", "Your anniversary is forgotten :wastebasket:"},
+		{"U21355", "<@bot> celebrations opt out", "You're opted out, I won't celebrate you :shushing_face:"},
+		{"U21355", "<@bot> celebrations opt in", "You're opted in, I'll celebrate you :tada:"},
+		{"U21355", "<@bot> import celebrations <@U1> birthday 01-02", "Sorry, only admins can import celebrations :no_entry_sign:"},
+		{"Uadmin", "<@bot> import celebrations\n<@U1> birthday 01-02\n<@U2> anniversary 2019-11-20\n<@U3> birthday 02-31", "Imported 2 celebration(s), failed to import:\n<@U3> birthday 02-31 (invalid date [02-31])"},
+	}
+
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("celebrationsTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	pc := viper.New()
+	pc.Set("channelID", "Ccelebrations")
+	pc.Set("adminIDs", []string{"Uadmin"})
+
+	p, err := plugins.NewCelebrations(pc, storer)
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin := assertplugin.New(t, "bot")
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: tc.user, Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+
+	birthday, err := storer.GetSiloString("birthday", "U1")
+	assert.NoError(t, err)
+	assert.Equal(t, "01-02", birthday)
+
+	anniversary, err := storer.GetSiloString("anniversary", "U2")
+	assert.NoError(t, err)
+	assert.Equal(t, "2019-11-20", anniversary)
+
+	_, err = storer.GetSiloString("anniversary", "U21355")
+	assert.Error(t, err)
+}
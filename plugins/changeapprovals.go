@@ -0,0 +1,351 @@
+package plugins
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ChangeApprovalsPluginName holds identifying name for the change approvals plugin
+	ChangeApprovalsPluginName = "changeApprovals"
+
+	// ChangeApprovalsPlansWebhookPath is the path of the webhook receiving plans to approve, served at
+	// /webhooks/changeApprovals/plans
+	ChangeApprovalsPlansWebhookPath = "plans"
+
+	// ChangeApprovalsSignatureHeader is the header holding the signature of verdicts sent to the callback url
+	ChangeApprovalsSignatureHeader = "X-Slackscot-Signature"
+)
+
+// Configuration keys
+const (
+	changeChannelIDKey      = "channelID"      // Channel where plans are posted for approval, required
+	changeApproversKey      = "approvers"      // Map of approver roles to the IDs of the users with that role, required
+	changeCallbackURLKey    = "callbackURL"    // Url verdicts are sent to, required
+	changeCallbackSecretKey = "callbackSecret" // Secret verdicts are signed with (see ChangeApprovalsSignatureHeader), optional
+	changeBearerTokenKey    = "bearerToken"    // Bearer token plans must be sent with, optional
+)
+
+const (
+	changeRequestsSilo     = "changes"
+	defaultApproverRole    = "default"
+	changeApproveActionID  = "changeApprovals.approve"
+	changeRejectActionID   = "changeApprovals.reject"
+	changePending          = "pending"
+	changeApproved         = "approved"
+	changeRejected         = "rejected"
+	maxChangeDetailsLength = 2500
+	changeCallbackTimeout  = 10 * time.Second
+)
+
+// changeRequest is an infrastructure change plan (i.e. a terraform plan) waiting for approval along with its verdict
+type changeRequest struct {
+	ID               string    `json:"id"`
+	Workspace        string    `json:"workspace"`
+	Add              int       `json:"add"`
+	Change           int       `json:"change"`
+	Destroy          int       `json:"destroy"`
+	Details          string    `json:"details"`
+	URL              string    `json:"url"`
+	ApproverRole     string    `json:"approverRole"`
+	MessageTimestamp string    `json:"messageTimestamp,omitempty"`
+	Status           string    `json:"status,omitempty"`
+	DecidedBy        string    `json:"decidedBy,omitempty"`
+	DecidedAt        time.Time `json:"decidedAt,omitempty"`
+}
+
+// changeVerdict is the decision on a change request sent to the callback url
+type changeVerdict struct {
+	ID        string    `json:"id"`
+	Workspace string    `json:"workspace"`
+	Verdict   string    `json:"verdict"`
+	DecidedBy string    `json:"decidedBy"`
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+// ChangeApprovals holds the plugin data for the change approvals plugin
+type ChangeApprovals struct {
+	*slackscot.Plugin
+	storer         store.GlobalSiloStringStorer
+	channelID      string
+	approvers      map[string]map[string]bool
+	callbackURL    string
+	callbackSecret string
+	bearerToken    string
+	client         *http.Client
+	now            func() time.Time
+
+	// Guards the read-modify-write of change requests since button clicks are received concurrently
+	sync.Mutex
+}
+
+// NewChangeApprovals creates a new instance of the change approvals plugin. Plans received on its webhook are posted with
+// approve and reject buttons that only users with the plan's approver role can use. Decisions are recorded with the
+// storer and sent to the callback url so the pipeline that sent the plan can apply or discard it. Button clicks are
+// routed to the plugin by slackscot's interaction handler (see slackscot.InteractionPath)
+func NewChangeApprovals(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	return newChangeApprovals(c, storer, time.Now)
+}
+
+// newChangeApprovals creates a new instance of the change approvals plugin using now to get the current time
+func newChangeApprovals(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (p *slackscot.Plugin, err error) {
+	for _, k := range []string{changeChannelIDKey, changeApproversKey, changeCallbackURLKey} {
+		if !c.IsSet(k) {
+			return nil, fmt.Errorf("Missing %s config key: %s", ChangeApprovalsPluginName, k)
+		}
+	}
+
+	ca := new(ChangeApprovals)
+	ca.storer = storer
+	ca.channelID = c.GetString(changeChannelIDKey)
+	ca.callbackURL = c.GetString(changeCallbackURLKey)
+	ca.callbackSecret = c.GetString(changeCallbackSecretKey)
+	ca.bearerToken = c.GetString(changeBearerTokenKey)
+	ca.client = &http.Client{Timeout: changeCallbackTimeout}
+	ca.now = now
+
+	ca.approvers = make(map[string]map[string]bool)
+	for role, userIDs := range c.GetStringMapStringSlice(changeApproversKey) {
+		ca.approvers[role] = make(map[string]bool)
+		for _, userID := range userIDs {
+			ca.approvers[role][userID] = true
+		}
+	}
+
+	ca.Plugin = plugin.New(ChangeApprovalsPluginName).
+		WithWebhook(ChangeApprovalsPlansWebhookPath, ca.receivePlan).
+		// Plans are posted by the webhook so the hear action never matches and only handles clicks of their buttons
+		WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return false
+			}).
+			WithDescription("Record decisions on plans on clicks of their approve and reject buttons").
+			WithInteractionHandler(ca.receiveInteraction).
+			Build()).
+		Build()
+
+	return ca.Plugin, nil
+}
+
+// receivePlan posts a plan received on the webhook for approval
+func (ca *ChangeApprovals) receivePlan(w http.ResponseWriter, r *http.Request) {
+	if ca.bearerToken != "" && r.Header.Get("Authorization") != "Bearer "+ca.bearerToken {
+		ca.Logger.Printf("[%s] Rejecting plan with invalid authorization", ChangeApprovalsPluginName)
+		http.Error(w, "invalid authorization", http.StatusUnauthorized)
+		return
+	}
+
+	var cr changeRequest
+	if err := json.NewDecoder(r.Body).Decode(&cr); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if cr.ApproverRole == "" {
+		cr.ApproverRole = defaultApproverRole
+	}
+
+	// Configuration map keys are lowercased when loaded
+	cr.ApproverRole = strings.ToLower(cr.ApproverRole)
+	if cr.ID == "" || ca.approvers[cr.ApproverRole] == nil {
+		http.Error(w, fmt.Sprintf("plans need an id and a known approver role but got id [%s] and role [%s]", cr.ID, cr.ApproverRole), http.StatusBadRequest)
+		return
+	}
+
+	ca.Lock()
+	defer ca.Unlock()
+
+	if _, err := ca.storer.GetSiloString(changeRequestsSilo, cr.ID); err == nil {
+		http.Error(w, fmt.Sprintf("plan [%s] was already received", cr.ID), http.StatusConflict)
+		return
+	}
+
+	cr.Status = changePending
+	_, timestamp, err := ca.SlackClient.PostMessage(ca.channelID, slack.MsgOptionText(changeRequestFallbackText(cr), false), slack.MsgOptionBlocks(renderChangeRequest(cr)...), slack.MsgOptionAsUser(true))
+	if err != nil {
+		ca.Logger.Printf("[%s] Error posting plan [%s]: %v", ChangeApprovalsPluginName, cr.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cr.MessageTimestamp = timestamp
+	if err := ca.saveChangeRequest(cr); err != nil {
+		ca.Logger.Printf("[%s] Error saving plan [%s]: %v", ChangeApprovalsPluginName, cr.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// receiveInteraction records decisions from clicks on the approve and reject buttons
+func (ca *ChangeApprovals) receiveInteraction(i *slackscot.Interaction) *slack.ViewSubmissionResponse {
+	if i.Type != slack.InteractionTypeBlockActions {
+		return nil
+	}
+
+	for _, action := range i.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case changeApproveActionID:
+			ca.decide(action.Value, i.User.ID, changeApproved)
+		case changeRejectActionID:
+			ca.decide(action.Value, i.User.ID, changeRejected)
+		}
+	}
+
+	return nil
+}
+
+// decide records the verdict of a user on a pending change request, sends it to the callback url and updates the
+// request's message. Users without the request's approver role are told so privately
+func (ca *ChangeApprovals) decide(id string, userID string, verdict string) {
+	ca.Lock()
+	defer ca.Unlock()
+
+	cr, err := ca.loadChangeRequest(id)
+	if err != nil {
+		ca.Logger.Printf("[%s] Error loading plan [%s]: %v", ChangeApprovalsPluginName, id, err)
+		return
+	}
+
+	if !ca.approvers[cr.ApproverRole][userID] {
+		ca.Logger.Printf("[%s] Denying decision of [%s] on plan [%s] without the [%s] role", ChangeApprovalsPluginName, userID, id, cr.ApproverRole)
+		ca.tell(userID, fmt.Sprintf("Sorry, only users with the `%s` approver role can decide on plan `%s` :no_entry:", cr.ApproverRole, id))
+		return
+	}
+
+	if cr.Status != changePending {
+		ca.tell(userID, fmt.Sprintf("Plan `%s` was already %s by <@%s>", id, cr.Status, cr.DecidedBy))
+		return
+	}
+
+	cr.Status, cr.DecidedBy, cr.DecidedAt = verdict, userID, ca.now()
+	if err := ca.sendVerdict(changeVerdict{ID: cr.ID, Workspace: cr.Workspace, Verdict: cr.Status, DecidedBy: cr.DecidedBy, DecidedAt: cr.DecidedAt}); err != nil {
+		ca.Logger.Printf("[%s] Error sending verdict on plan [%s]: %v", ChangeApprovalsPluginName, id, err)
+		ca.tell(userID, fmt.Sprintf("Sorry, I couldn't deliver your verdict on plan `%s` :disappointed: (%v). Please try again", id, err))
+		return
+	}
+
+	if err := ca.saveChangeRequest(cr); err != nil {
+		ca.Logger.Printf("[%s] Error saving verdict on plan [%s]: %v", ChangeApprovalsPluginName, id, err)
+	}
+
+	if _, _, _, err := ca.SlackClient.UpdateMessage(ca.channelID, cr.MessageTimestamp, slack.MsgOptionText(changeRequestFallbackText(cr), false), slack.MsgOptionBlocks(renderChangeRequest(cr)...)); err != nil {
+		ca.Logger.Printf("[%s] Error updating message of plan [%s]: %v", ChangeApprovalsPluginName, id, err)
+	}
+}
+
+// tell sends an ephemeral message to a user in the approvals channel
+func (ca *ChangeApprovals) tell(userID string, text string) {
+	if _, err := ca.SlackClient.PostEphemeral(ca.channelID, userID, slack.MsgOptionText(text, false)); err != nil {
+		ca.Logger.Printf("[%s] Error sending ephemeral message to [%s]: %v", ChangeApprovalsPluginName, userID, err)
+	}
+}
+
+// sendVerdict posts a verdict to the callback url, signing it with the callback secret if configured
+func (ca *ChangeApprovals) sendVerdict(verdict changeVerdict) (err error) {
+	body, err := json.Marshal(verdict)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", ca.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if ca.callbackSecret != "" {
+		mac := hmac.New(sha256.New, []byte(ca.callbackSecret))
+		mac.Write(body)
+		req.Header.Set(ChangeApprovalsSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := ca.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status [%s] from callback", resp.Status)
+	}
+
+	return nil
+}
+
+// loadChangeRequest loads a change request by ID
+func (ca *ChangeApprovals) loadChangeRequest(id string) (cr changeRequest, err error) {
+	encoded, err := ca.storer.GetSiloString(changeRequestsSilo, id)
+	if err != nil {
+		return cr, err
+	}
+
+	err = json.Unmarshal([]byte(encoded), &cr)
+	return cr, err
+}
+
+// saveChangeRequest persists a change request
+func (ca *ChangeApprovals) saveChangeRequest(cr changeRequest) (err error) {
+	encoded, err := json.Marshal(cr)
+	if err != nil {
+		return err
+	}
+
+	return ca.storer.PutSiloString(changeRequestsSilo, cr.ID, string(encoded))
+}
+
+// changeRequestFallbackText returns the text of a change request message for clients that can't show blocks
+func changeRequestFallbackText(cr changeRequest) string {
+	return fmt.Sprintf("Plan %s for %s: %d to add, %d to change, %d to destroy (%s)", cr.ID, cr.Workspace, cr.Add, cr.Change, cr.Destroy, cr.Status)
+}
+
+// renderChangeRequest renders the blocks of a change request's message. Buttons are only included while it's pending
+func renderChangeRequest(cr changeRequest) (blocks []slack.Block) {
+	title := fmt.Sprintf("Plan `%s`", cr.ID)
+	if cr.URL != "" {
+		title = fmt.Sprintf("<%s|Plan `%s`>", cr.URL, cr.ID)
+	}
+
+	summary := fmt.Sprintf(":building_construction: *%s* for *%s*\n:heavy_plus_sign: %d to add · :pencil2: %d to change · :heavy_minus_sign: %d to destroy", title, cr.Workspace, cr.Add, cr.Change, cr.Destroy)
+	blocks = []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, summary, false, false), nil, nil)}
+
+	if details := strings.TrimSpace(cr.Details); details != "" {
+		if runes := []rune(details); len(runes) > maxChangeDetailsLength {
+			details = string(runes[:maxChangeDetailsLength]) + "\n…"
+		}
+
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("```\n%s\n```", details), false, false), nil, nil))
+	}
+
+	switch cr.Status {
+	case changeApproved:
+		return append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":white_check_mark: Approved by <@%s>", cr.DecidedBy), false, false)))
+	case changeRejected:
+		return append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":no_entry_sign: Rejected by <@%s>", cr.DecidedBy), false, false)))
+	}
+
+	approveButton := slack.NewButtonBlockElement(changeApproveActionID, cr.ID, slack.NewTextBlockObject(slack.PlainTextType, "Approve", false, false))
+	approveButton.Style = slack.StylePrimary
+	rejectButton := slack.NewButtonBlockElement(changeRejectActionID, cr.ID, slack.NewTextBlockObject(slack.PlainTextType, "Reject", false, false))
+	rejectButton.Style = slack.StyleDanger
+
+	return append(blocks,
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Needs approval from the `%s` role", cr.ApproverRole), false, false)),
+		slack.NewActionBlock(slackscot.HearActionInteractionBlockID(ChangeApprovalsPluginName, 0), approveButton, rejectButton))
+}
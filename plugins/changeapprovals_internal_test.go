@@ -0,0 +1,149 @@
+package plugins
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const changePlanPayload = `{"id": "run-42", "workspace": "network-prod", "add": 2, "change": 1, "destroy": 0, "details": "+ aws_security_group.api", "url": "https://ci.example.com/runs/42", "approverRole": "netops"}`
+
+// newDecision creates a click on the approve or reject button of a plan
+func newDecision(userID string, actionID string, planID string) (i *slackscot.Interaction) {
+	i = newBlockActionsInteraction(userID, slackscot.HearActionInteractionBlockID(ChangeApprovalsPluginName, 0), actionID, planID)
+	i.Channel.ID = "Cchanges"
+
+	return i
+}
+
+func newChangeApprovalsTestConfig(callbackURL string) *viper.Viper {
+	c := viper.New()
+	c.Set("channelID", "Cchanges")
+	c.Set("approvers", map[string][]string{"netops": {"Unetops"}, "default": {"Ulead"}})
+	c.Set("callbackURL", callbackURL)
+	c.Set("callbackSecret", "c4llb4ck")
+	c.Set("bearerToken", "t0k3n")
+
+	return c
+}
+
+func TestChangeApprovalsMissingConfig(t *testing.T) {
+	c := viper.New()
+	_, err := NewChangeApprovals(c, nil)
+	assert.EqualError(t, err, "Missing changeApprovals config key: channelID")
+
+	c.Set("channelID", "Cchanges")
+	_, err = NewChangeApprovals(c, nil)
+	assert.EqualError(t, err, "Missing changeApprovals config key: approvers")
+}
+
+func TestChangeApprovalsWorkflow(t *testing.T) {
+	posted := postedMessages{}
+	testServer := newPostedMessagesServer(&posted)
+	defer testServer.Stop()
+
+	var verdicts []changeVerdict
+	var signatures []string
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		var v changeVerdict
+		_ = json.Unmarshal(body, &v)
+		verdicts = append(verdicts, v)
+		signatures = append(signatures, r.Header.Get(ChangeApprovalsSignatureHeader))
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer callbackServer.Close()
+
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("changeApprovalsTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	now := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	p, err := newChangeApprovals(newChangeApprovalsTestConfig(callbackServer.URL), storer, func() time.Time { return now })
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	newPlanRequest := func(token string, payload string) *http.Request {
+		r := httptest.NewRequest("POST", "/webhooks/changeApprovals/plans", strings.NewReader(payload))
+		r.Header.Set("Authorization", "Bearer "+token)
+
+		return r
+	}
+
+	handlesWebhook := func(path string, r *http.Request, expectedStatus int) {
+		assertplugin.HandlesWebhook(p, path, r, func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+			return assert.Equal(t, expectedStatus, response.Code)
+		})
+	}
+
+	handlesWebhook(ChangeApprovalsPlansWebhookPath, newPlanRequest("not the token", changePlanPayload), http.StatusUnauthorized)
+	handlesWebhook(ChangeApprovalsPlansWebhookPath, newPlanRequest("t0k3n", `{"id": "run-43", "approverRole": "dba"}`), http.StatusBadRequest)
+	handlesWebhook(ChangeApprovalsPlansWebhookPath, newPlanRequest("t0k3n", changePlanPayload), http.StatusAccepted)
+	handlesWebhook(ChangeApprovalsPlansWebhookPath, newPlanRequest("t0k3n", changePlanPayload), http.StatusConflict)
+
+	require.Len(t, posted.messages, 1)
+	assert.Equal(t, "Cchanges", posted.messages[0].channel)
+	assert.Equal(t, "Plan run-42 for network-prod: 2 to add, 1 to change, 0 to destroy (pending)", posted.messages[0].text)
+	if assert.Len(t, posted.messages[0].blocks, 4) {
+		assert.Equal(t, ":building_construction: *<https://ci.example.com/runs/42|Plan `run-42`>* for *network-prod*\n:heavy_plus_sign: 2 to add · :pencil2: 1 to change · :heavy_minus_sign: 0 to destroy", posted.messages[0].blocks[0].(*slack.SectionBlock).Text.Text)
+		assert.Equal(t, "```\n+ aws_security_group.api\n```", posted.messages[0].blocks[1].(*slack.SectionBlock).Text.Text)
+		assert.Equal(t, "slackscot:changeApprovals.hearAction[0]", posted.messages[0].blocks[3].(*slack.ActionBlock).BlockID)
+	}
+
+	// Only users with the netops role can decide
+	decide := p.HearActions[0].InteractionHandler
+	decide(newDecision("Ulead", changeApproveActionID, "run-42"))
+	assert.Empty(t, verdicts)
+	require.Len(t, posted.messages, 2)
+	assert.Equal(t, postedMessage{channel: "Cchanges", user: "Ulead", text: "Sorry, only users with the `netops` approver role can decide on plan `run-42` :no_entry:"}, posted.messages[1])
+
+	decide(newDecision("Unetops", changeApproveActionID, "run-42"))
+
+	if assert.Len(t, verdicts, 1) {
+		assert.Equal(t, changeVerdict{ID: "run-42", Workspace: "network-prod", Verdict: "approved", DecidedBy: "Unetops", DecidedAt: now}, verdicts[0])
+
+		mac := hmac.New(sha256.New, []byte("c4llb4ck"))
+		encoded, _ := json.Marshal(verdicts[0])
+		mac.Write(encoded)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signatures[0])
+	}
+
+	require.Len(t, posted.messages, 3)
+	update := posted.messages[2]
+	assert.Equal(t, "1546833210.036900", update.ts)
+	if assert.Len(t, update.blocks, 3) {
+		assert.Equal(t, ":white_check_mark: Approved by <@Unetops>", update.blocks[2].(*slack.ContextBlock).ContextElements.Elements[0].(*slack.TextBlockObject).Text)
+	}
+
+	cr, err := storer.GetSiloString(changeRequestsSilo, "run-42")
+	require.NoError(t, err)
+	assert.Contains(t, cr, `"status":"approved","decidedBy":"Unetops"`)
+
+	// Decisions are final
+	decide(newDecision("Unetops", changeRejectActionID, "run-42"))
+	assert.Len(t, verdicts, 1)
+	require.Len(t, posted.messages, 4)
+	assert.Equal(t, "Plan `run-42` was already approved by <@Unetops>", posted.messages[3].text)
+}
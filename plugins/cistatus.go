@@ -0,0 +1,195 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"net/http"
+	"regexp"
+)
+
+const (
+	// CIStatusPluginName holds identifying name for the CI status plugin
+	CIStatusPluginName = "ciStatus"
+
+	// CIStatusWebhookPath is the path of the webhook receiving pipeline events, served at /webhooks/ciStatus/pipelines
+	CIStatusWebhookPath = "pipelines"
+)
+
+// Pipeline statuses
+const (
+	PipelineRunning  = "running"
+	PipelinePassed   = "passed"
+	PipelineFailed   = "failed"
+	PipelineCanceled = "canceled"
+)
+
+// Configuration keys
+const (
+	ciChannelIDKey = "channelID" // Channel where pipeline events are posted, required
+)
+
+const (
+	ciMessagesSilo = "messages"
+	ciBranchesSilo = "branches"
+)
+
+var ciStatusRegex = regexp.MustCompile("(?i)\\Aci status (\\S+)\\z")
+
+// pipelineStatusEmojis holds the emoji shown for each pipeline status
+var pipelineStatusEmojis = map[string]string{
+	PipelineRunning:  ":hourglass_flowing_sand:",
+	PipelinePassed:   ":white_check_mark:",
+	PipelineFailed:   ":x:",
+	PipelineCanceled: ":no_entry_sign:",
+}
+
+// PipelineEvent is a change of status of a CI pipeline
+type PipelineEvent struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	Status string `json:"status"`
+	URL    string `json:"url"`
+}
+
+// PipelineEventParser is implemented by CI services (i.e. GitHub Actions, GitLab CI or Jenkins) to parse their webhook requests
+type PipelineEventParser interface {
+	// ParsePipelineEvent parses a webhook request sent by the CI service. A nil event is returned for
+	// valid requests that aren't pipeline events
+	ParsePipelineEvent(r *http.Request) (event *PipelineEvent, err error)
+}
+
+// PipelineEventParserFunc is an adapter to allow the use of a function as a PipelineEventParser
+type PipelineEventParserFunc func(r *http.Request) (event *PipelineEvent, err error)
+
+// ParsePipelineEvent calls f(r)
+func (f PipelineEventParserFunc) ParsePipelineEvent(r *http.Request) (event *PipelineEvent, err error) {
+	return f(r)
+}
+
+// CIStatus holds the plugin data for the CI status plugin
+type CIStatus struct {
+	*slackscot.Plugin
+	parser    PipelineEventParser
+	storer    store.GlobalSiloStringStorer
+	channelID string
+}
+
+// NewCIStatus creates a new instance of the CI status plugin. Pipeline events received on its webhook are posted
+// to the configured channel, updating the same message as the pipeline progresses. The message of each pipeline
+// in progress and the latest event of each branch are persisted with the storer
+func NewCIStatus(c *config.PluginConfig, parser PipelineEventParser, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	if !c.IsSet(ciChannelIDKey) {
+		return nil, fmt.Errorf("Missing %s config key: %s", CIStatusPluginName, ciChannelIDKey)
+	}
+
+	ci := new(CIStatus)
+	ci.parser = parser
+	ci.storer = storer
+	ci.channelID = c.GetString(ciChannelIDKey)
+
+	ci.Plugin = plugin.New(CIStatusPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return ciStatusRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("ci status <branch>").
+			WithDescription("Reports the status of the latest pipeline of a branch").
+			WithAnswerer(ci.branchStatus).
+			Build()).
+		WithWebhook(CIStatusWebhookPath, ci.receivePipelineEvent).
+		Build()
+
+	return ci.Plugin, nil
+}
+
+// branchStatus answers with the latest pipeline event of a branch
+func (ci *CIStatus) branchStatus(m *slackscot.IncomingMessage) *slackscot.Answer {
+	branch := ciStatusRegex.FindStringSubmatch(m.NormalizedText)[1]
+
+	rawEvent, err := ci.storer.GetSiloString(ciBranchesSilo, branch)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("I haven't seen any pipeline for `%s` :shrug:", branch)}
+	}
+
+	var event PipelineEvent
+	if err := json.Unmarshal([]byte(rawEvent), &event); err != nil {
+		ci.Logger.Printf("[%s] Error decoding latest event of branch [%s]: %v", CIStatusPluginName, branch, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't read the status of `%s` :disappointed: (%v)", branch, err)}
+	}
+
+	return &slackscot.Answer{Text: formatPipelineEvent(event)}
+}
+
+// receivePipelineEvent posts a pipeline event received on the webhook, updating the message of the pipeline if one was already posted
+func (ci *CIStatus) receivePipelineEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := ci.parser.ParsePipelineEvent(r)
+	if err != nil {
+		ci.Logger.Printf("[%s] Error parsing pipeline event: %v", CIStatusPluginName, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if event != nil {
+		if err := ci.postPipelineEvent(*event); err != nil {
+			ci.Logger.Printf("[%s] Error posting event of pipeline [%s]: %v", CIStatusPluginName, event.ID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// postPipelineEvent posts or updates the message of a pipeline and records the event as the latest of its branch. Once
+// a pipeline is done, its message is forgotten
+func (ci *CIStatus) postPipelineEvent(event PipelineEvent) (err error) {
+	text := slack.MsgOptionText(formatPipelineEvent(event), false)
+
+	if timestamp, err := ci.storer.GetSiloString(ciMessagesSilo, event.ID); err == nil {
+		_, _, _, err = ci.SlackClient.UpdateMessage(ci.channelID, timestamp, text)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, timestamp, err = ci.SlackClient.PostMessage(ci.channelID, text, slack.MsgOptionAsUser(true))
+		if err != nil {
+			return err
+		}
+
+		if event.Status == PipelineRunning {
+			if err = ci.storer.PutSiloString(ciMessagesSilo, event.ID, timestamp); err != nil {
+				return err
+			}
+		}
+	}
+
+	if event.Status != PipelineRunning {
+		if err = ci.storer.DeleteSiloString(ciMessagesSilo, event.ID); err != nil {
+			return err
+		}
+	}
+
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return ci.storer.PutSiloString(ciBranchesSilo, event.Branch, string(encoded))
+}
+
+// formatPipelineEvent renders a pipeline event on a single line
+func formatPipelineEvent(event PipelineEvent) string {
+	emoji, ok := pipelineStatusEmojis[event.Status]
+	if !ok {
+		emoji = ":grey_question:"
+	}
+
+	return fmt.Sprintf("%s *%s* on `%s` %s: <%s|details>", emoji, event.Name, event.Branch, event.Status, event.URL)
+}
@@ -0,0 +1,118 @@
+package plugins_test
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type chatCalls struct {
+	sync.Mutex
+	calls []string
+}
+
+func (c *chatCalls) record(call string) {
+	c.Lock()
+	defer c.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func newChatServer(calls *chatCalls) (testServer *slacktest.Server) {
+	testServer = slacktest.NewTestServer(func(c slacktest.Customize) {
+		c.Handle("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			calls.record("post " + r.Form.Get("channel") + ": " + r.Form.Get("text"))
+			_, _ = w.Write([]byte(`{"ok": true, "channel": "Cci", "ts": "1546833210.036900"}`))
+		})
+		c.Handle("/chat.update", func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			calls.record("update " + r.Form.Get("channel") + "/" + r.Form.Get("ts") + ": " + r.Form.Get("text"))
+			_, _ = w.Write([]byte(`{"ok": true, "channel": "Cci", "ts": "1546833210.036900"}`))
+		})
+	})
+	testServer.Start()
+
+	return testServer
+}
+
+func parseJSONPipelineEvent(r *http.Request) (event *plugins.PipelineEvent, err error) {
+	event = new(plugins.PipelineEvent)
+	if err = json.NewDecoder(r.Body).Decode(event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func TestCIStatusMissingChannel(t *testing.T) {
+	_, err := plugins.NewCIStatus(viper.New(), plugins.PipelineEventParserFunc(parseJSONPipelineEvent), nil)
+	assert.EqualError(t, err, "Missing ciStatus config key: channelID")
+}
+
+func TestCIStatusUpdatesPipelineMessage(t *testing.T) {
+	calls := chatCalls{}
+	testServer := newChatServer(&calls)
+	defer testServer.Stop()
+
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("ciStatusTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	c := viper.New()
+	c.Set("channelID", "Cci")
+
+	p, err := plugins.NewCIStatus(c, plugins.PipelineEventParserFunc(parseJSONPipelineEvent), storer)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> ci status main"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "I haven't seen any pipeline for `main` :shrug:")
+	})
+
+	events := []string{
+		`{"id": "42", "name": "build", "branch": "main", "status": "running", "url": "https://ci.example.com/42"}`,
+		`{"id": "42", "name": "build", "branch": "main", "status": "failed", "url": "https://ci.example.com/42"}`,
+		`{"id": "43", "name": "build", "branch": "main", "status": "passed", "url": "https://ci.example.com/43"}`,
+	}
+
+	for _, e := range events {
+		assertplugin.HandlesWebhook(p, plugins.CIStatusWebhookPath, httptest.NewRequest("POST", "/webhooks/ciStatus/pipelines", strings.NewReader(e)), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+			return assert.Equal(t, http.StatusNoContent, response.Code)
+		})
+	}
+
+	assert.Equal(t, []string{
+		"post Cci: :hourglass_flowing_sand: *build* on `main` running: <https://ci.example.com/42|details>",
+		"update Cci/1546833210.036900: :x: *build* on `main` failed: <https://ci.example.com/42|details>",
+		"post Cci: :white_check_mark: *build* on `main` passed: <https://ci.example.com/43|details>",
+	}, calls.calls)
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> ci status main"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":white_check_mark: *build* on `main` passed: <https://ci.example.com/43|details>")
+	})
+
+	assertplugin.HandlesWebhook(p, plugins.CIStatusWebhookPath, httptest.NewRequest("POST", "/webhooks/ciStatus/pipelines", strings.NewReader("not json")), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
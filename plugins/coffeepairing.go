@@ -0,0 +1,276 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// CoffeePairingPluginName holds identifying name for the coffee pairing plugin
+	CoffeePairingPluginName = "coffeePairing"
+)
+
+// Configuration keys
+const (
+	coffeeWeekdayKey = "weekday" // Day of the week pairings are made, defaults to Monday
+	coffeeAtTimeKey  = "atTime"  // Time of the day pairings are made, defaults to 10:00
+)
+
+const (
+	defaultCoffeeWeekday   = "Monday"
+	defaultCoffeeAtTime    = "10:00"
+	coffeeHistorySiloFmt   = "history:%s"
+	coffeeChannelsSilo     = "channels"
+	coffeeIntroMessageFmt  = ":coffee: Hi %s! You've been paired for a virtual coffee this week by the folks of <#%s>. Find a time that works and enjoy :slightly_smiling_face:"
+	coffeeRoundMessageFmt  = ":coffee: New coffee round: %d people paired in %d group(s). Check your DMs! Say `coffee join` to be part of the next one"
+	coffeeMembersSiloFmt   = "members:%s"
+	coffeeHistoryKeyFormat = "%s:%s"
+)
+
+// CoffeePairing holds the plugin data for the coffee pairing plugin. Channel members opt in to be randomly paired
+// on a schedule for virtual coffees. Past pairings are recorded to avoid pairing the same people over and over
+type CoffeePairing struct {
+	*slackscot.Plugin
+	storer store.GlobalSiloStringStorer
+	random *rand.Rand
+}
+
+// NewCoffeePairing creates a new instance of the coffee pairing plugin. Members, channels and pairing history are
+// persisted with the storer
+func NewCoffeePairing(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	c.SetDefault(coffeeWeekdayKey, defaultCoffeeWeekday)
+	c.SetDefault(coffeeAtTimeKey, defaultCoffeeAtTime)
+
+	cp := new(CoffeePairing)
+	cp.storer = storer
+	cp.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	cp.Plugin = plugin.New(CoffeePairingPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return strings.HasPrefix(m.NormalizedText, "coffee join") }).
+			WithUsage("coffee join").
+			WithDescription("Opt in to be paired with someone from this channel for a virtual coffee").
+			WithAnswerer(cp.join).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return strings.HasPrefix(m.NormalizedText, "coffee leave") }).
+			WithUsage("coffee leave").
+			WithDescription("Opt out of virtual coffee pairings for this channel").
+			WithAnswerer(cp.leave).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return strings.HasPrefix(m.NormalizedText, "coffee stats") }).
+			WithUsage("coffee stats").
+			WithDescription("Reports participation in virtual coffees for this channel").
+			WithAnswerer(cp.stats).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().Every(c.GetString(coffeeWeekdayKey)).AtTime(c.GetString(coffeeAtTimeKey)).Build()).
+			WithDescription("Pair channel members for virtual coffees").
+			WithAction(cp.pairAll).
+			Build()).
+		Build()
+
+	return cp.Plugin, nil
+}
+
+// join opts the user in for pairings on the channel
+func (cp *CoffeePairing) join(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := cp.storer.PutSiloString(fmt.Sprintf(coffeeMembersSiloFmt, m.Channel), m.User, "true"); err != nil {
+		cp.Logger.Printf("[%s] Error adding member [%s] on [%s]: %v", CoffeePairingPluginName, m.User, m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't add you :disappointed: (%v)", err)}
+	}
+
+	if err := cp.storer.PutSiloString(coffeeChannelsSilo, m.Channel, "true"); err != nil {
+		cp.Logger.Printf("[%s] Error adding channel [%s]: %v", CoffeePairingPluginName, m.Channel, err)
+	}
+
+	return &slackscot.Answer{Text: "You're in for the next coffee round :coffee:"}
+}
+
+// leave opts the user out of pairings on the channel
+func (cp *CoffeePairing) leave(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := cp.storer.DeleteSiloString(fmt.Sprintf(coffeeMembersSiloFmt, m.Channel), m.User); err != nil {
+		cp.Logger.Printf("[%s] Error removing member [%s] on [%s]: %v", CoffeePairingPluginName, m.User, m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't remove you :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: "You won't be paired anymore, come back anytime :wave:"}
+}
+
+// stats answers with the participation stats of the channel
+func (cp *CoffeePairing) stats(m *slackscot.IncomingMessage) *slackscot.Answer {
+	members, err := cp.storer.ScanSilo(fmt.Sprintf(coffeeMembersSiloFmt, m.Channel))
+	if err != nil {
+		cp.Logger.Printf("[%s] Error loading members of [%s]: %v", CoffeePairingPluginName, m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the stats :disappointed: (%v)", err)}
+	}
+
+	history, err := cp.storer.ScanSilo(fmt.Sprintf(coffeeHistorySiloFmt, m.Channel))
+	if err != nil {
+		cp.Logger.Printf("[%s] Error loading pairing history of [%s]: %v", CoffeePairingPluginName, m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the stats :disappointed: (%v)", err)}
+	}
+
+	total, yours := 0, 0
+	partners := make(map[string]bool)
+	for key, rawCount := range history {
+		count, _ := strconv.Atoi(rawCount)
+		total = total + count
+
+		users := strings.Split(key, ":")
+		for i, u := range users {
+			if u == m.User {
+				yours = yours + count
+				partners[users[1-i]] = true
+			}
+		}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("*Coffee stats* :coffee:\n• %d member(s) opted in\n• %d coffee pairing(s) made so far\n• You had %d coffee(s) with %d different people", len(members), total, yours, len(partners))}
+}
+
+// pairAll pairs the members of all channels with at least one member
+func (cp *CoffeePairing) pairAll() {
+	channels, err := cp.storer.ScanSilo(coffeeChannelsSilo)
+	if err != nil {
+		cp.Logger.Printf("[%s] Error loading channels: %v", CoffeePairingPluginName, err)
+		return
+	}
+
+	channelIDs := make([]string, 0, len(channels))
+	for channelID := range channels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for _, channelID := range channelIDs {
+		cp.pairChannel(channelID)
+	}
+}
+
+// pairChannel pairs the members of a channel, introduces each group in a direct message and reports on the channel
+func (cp *CoffeePairing) pairChannel(channelID string) {
+	members, err := cp.storer.ScanSilo(fmt.Sprintf(coffeeMembersSiloFmt, channelID))
+	if err != nil {
+		cp.Logger.Printf("[%s] Error loading members of [%s]: %v", CoffeePairingPluginName, channelID, err)
+		return
+	}
+
+	history, err := cp.storer.ScanSilo(fmt.Sprintf(coffeeHistorySiloFmt, channelID))
+	if err != nil {
+		cp.Logger.Printf("[%s] Error loading pairing history of [%s]: %v", CoffeePairingPluginName, channelID, err)
+		return
+	}
+
+	userIDs := make([]string, 0, len(members))
+	for userID := range members {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+
+	groups := makeCoffeeGroups(userIDs, history, cp.random)
+	if len(groups) == 0 {
+		return
+	}
+
+	for _, group := range groups {
+		if err := cp.introduce(channelID, group); err != nil {
+			cp.Logger.Printf("[%s] Error introducing %v: %v", CoffeePairingPluginName, group, err)
+			continue
+		}
+
+		for _, key := range coffeeHistoryKeys(group) {
+			count, _ := strconv.Atoi(history[key])
+			if err := cp.storer.PutSiloString(fmt.Sprintf(coffeeHistorySiloFmt, channelID), key, strconv.Itoa(count+1)); err != nil {
+				cp.Logger.Printf("[%s] Error recording pairing [%s]: %v", CoffeePairingPluginName, key, err)
+			}
+		}
+	}
+
+	cp.RealTimeMsgSender.SendMessage(cp.RealTimeMsgSender.NewOutgoingMessage(fmt.Sprintf(coffeeRoundMessageFmt, len(userIDs), len(groups)), channelID))
+}
+
+// introduce opens a group direct message with the members of the group and posts an intro
+func (cp *CoffeePairing) introduce(channelID string, group []string) (err error) {
+	dm, _, _, err := cp.SlackClient.OpenConversation(&slack.OpenConversationParameters{Users: group})
+	if err != nil {
+		return err
+	}
+
+	mentions := make([]string, 0, len(group))
+	for _, u := range group {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", u))
+	}
+
+	_, _, err = cp.SlackClient.PostMessage(dm.ID, slack.MsgOptionText(fmt.Sprintf(coffeeIntroMessageFmt, strings.Join(mentions, " and "), channelID), false), slack.MsgOptionAsUser(true))
+	return err
+}
+
+// makeCoffeeGroups randomly pairs users, avoiding users who were already paired together when possible. With an odd number of
+// users, the last pair becomes a group of three. A single user can't be paired and results in no groups
+func makeCoffeeGroups(userIDs []string, history map[string]string, random *rand.Rand) (groups [][]string) {
+	groups = make([][]string, 0)
+	if len(userIDs) < 2 {
+		return groups
+	}
+
+	shuffled := append([]string{}, userIDs...)
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	for len(shuffled) >= 2 {
+		first := shuffled[0]
+		partnerIndex := 1
+
+		// Prefer the first partner never paired with, falling back to the next one in line
+		for i := 1; i < len(shuffled); i++ {
+			if _, pairedBefore := history[coffeeHistoryKey(first, shuffled[i])]; !pairedBefore {
+				partnerIndex = i
+				break
+			}
+		}
+
+		groups = append(groups, []string{first, shuffled[partnerIndex]})
+		shuffled = append(shuffled[1:partnerIndex], shuffled[partnerIndex+1:]...)
+	}
+
+	if len(shuffled) == 1 {
+		groups[len(groups)-1] = append(groups[len(groups)-1], shuffled[0])
+	}
+
+	return groups
+}
+
+// coffeeHistoryKeys returns the history keys of all pairs of users of a group
+func coffeeHistoryKeys(group []string) (keys []string) {
+	keys = make([]string, 0)
+	for i := 0; i < len(group); i++ {
+		for j := i + 1; j < len(group); j++ {
+			keys = append(keys, coffeeHistoryKey(group[i], group[j]))
+		}
+	}
+
+	return keys
+}
+
+// coffeeHistoryKey returns the history key of a pair of users, regardless of their order
+func coffeeHistoryKey(userA string, userB string) (key string) {
+	if userA > userB {
+		userA, userB = userB, userA
+	}
+
+	return fmt.Sprintf(coffeeHistoryKeyFormat, userA, userB)
+}
@@ -0,0 +1,106 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMakeCoffeeGroupsAvoidsRepeats(t *testing.T) {
+	history := map[string]string{"U1:U2": "1", "U3:U4": "1"}
+
+	for seed := int64(0); seed < 20; seed++ {
+		groups := makeCoffeeGroups([]string{"U1", "U2", "U3", "U4"}, history, rand.New(rand.NewSource(seed)))
+
+		require.Len(t, groups, 2)
+		for _, g := range groups {
+			assert.Len(t, g, 2)
+			_, pairedBefore := history[coffeeHistoryKey(g[0], g[1])]
+			assert.False(t, pairedBefore, "seed [%d] repeated pairing %v", seed, g)
+		}
+	}
+}
+
+func TestMakeCoffeeGroupsWithOddNumberOfUsers(t *testing.T) {
+	groups := makeCoffeeGroups([]string{"U1", "U2", "U3"}, map[string]string{}, rand.New(rand.NewSource(1)))
+
+	require.Len(t, groups, 1)
+	sorted := append([]string{}, groups[0]...)
+	sort.Strings(sorted)
+	assert.Equal(t, []string{"U1", "U2", "U3"}, sorted)
+}
+
+func TestMakeCoffeeGroupsWithSingleUser(t *testing.T) {
+	assert.Empty(t, makeCoffeeGroups([]string{"U1"}, map[string]string{}, rand.New(rand.NewSource(1))))
+}
+
+func TestCoffeePairingRound(t *testing.T) {
+	var mutex sync.Mutex
+	openedWith := make([]string, 0)
+	intros := make([]string, 0)
+
+	testServer := slacktest.NewTestServer(func(c slacktest.Customize) {
+		c.Handle("/conversations.open", func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			mutex.Lock()
+			openedWith = append(openedWith, r.Form.Get("users"))
+			mutex.Unlock()
+			_, _ = w.Write([]byte(`{"ok": true, "channel": {"id": "Gpair"}}`))
+		})
+		c.Handle("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			mutex.Lock()
+			intros = append(intros, r.Form.Get("text"))
+			mutex.Unlock()
+			_, _ = w.Write([]byte(`{"ok": true, "channel": "Gpair", "ts": "1546833210.036900"}`))
+		})
+	})
+	testServer.Start()
+	defer testServer.Stop()
+
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("coffeePairingTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	require.NoError(t, storer.PutSiloString("channels", "Ccoffee", "true"))
+	require.NoError(t, storer.PutSiloString("members:Ccoffee", "U1", "true"))
+	require.NoError(t, storer.PutSiloString("members:Ccoffee", "U2", "true"))
+
+	p, err := NewCoffeePairing(viper.New(), storer)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.RunsOnSchedule(p, schedule.New().Every("Monday").AtTime("10:00").Build(), func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Ccoffee": {":coffee: New coffee round: 2 people paired in 1 group(s). Check your DMs! Say `coffee join` to be part of the next one"}}, sentMsgs)
+	})
+
+	require.Len(t, openedWith, 1)
+	users := strings.Split(openedWith[0], ",")
+	sort.Strings(users)
+	assert.Equal(t, []string{"U1", "U2"}, users)
+
+	require.Len(t, intros, 1)
+	assert.Contains(t, intros[0], "You've been paired for a virtual coffee this week by the folks of <#Ccoffee>")
+
+	count, err := storer.GetSiloString("history:Ccoffee", "U1:U2")
+	require.NoError(t, err)
+	assert.Equal(t, "1", count)
+}
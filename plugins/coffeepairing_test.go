@@ -0,0 +1,54 @@
+package plugins_test
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCoffeePairingJoinLeaveAndStats(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("coffeePairingTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	require.NoError(t, storer.PutSiloString("history:Ccoffee", "U1:U2", "2"))
+	require.NoError(t, storer.PutSiloString("history:Ccoffee", "U1:U3", "1"))
+
+	p, err := plugins.NewCoffeePairing(viper.New(), storer)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		user           string
+		text           string
+		expectedAnswer string
+	}{
+		{"U1", "<@bot> coffee join", "You're in for the next coffee round :coffee:"},
+		{"U2", "<@bot> coffee join", "You're in for the next coffee round :coffee:"},
+		{"U3", "<@bot> coffee join", "You're in for the next coffee round :coffee:"},
+		{"U3", "<@bot> coffee leave", "You won't be paired anymore, come back anytime :wave:"},
+		{"U1", "<@bot> coffee stats", "*Coffee stats* :coffee:\n• 2 member(s) opted in\n• 3 coffee pairing(s) made so far\n• You had 3 coffee(s) with 2 different people"},
+		{"U4", "<@bot> coffee stats", "*Coffee stats* :coffee:\n• 2 member(s) opted in\n• 3 coffee pairing(s) made so far\n• You had 0 coffee(s) with 0 different people"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin := assertplugin.New(t, "bot")
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Ccoffee", User: tc.user, Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ConverterPluginName holds identifying name for the converter plugin
+	ConverterPluginName = "converter"
+)
+
+// Configuration keys
+const (
+	converterLocaleKey = "locale" // Locale used to format converted amounts (i.e. en-US or fr-CA), defaults to en
+)
+
+const (
+	defaultConverterLocale = "en"
+)
+
+var convertRegex = regexp.MustCompile("(?i)\\Aconvert\\s+(-?[0-9][0-9,]*(?:\\.[0-9]+)?)\\s*(\\S+)\\s+(?:to|in|into)\\s+(\\S+)\\s*\\z")
+var currencyCodeRegex = regexp.MustCompile("\\A[A-Za-z]{3}\\z")
+
+// FXRateProvider is implemented by foreign exchange rate providers (i.e. a client of an exchange rates API)
+type FXRateProvider interface {
+	// GetRates returns the rates of currencies (by ISO 4217 code) for one unit of the base currency
+	GetRates(base string) (rates map[string]float64, err error)
+}
+
+// FXRateProviderFunc is an adapter to allow the use of a function as a FXRateProvider
+type FXRateProviderFunc func(base string) (rates map[string]float64, err error)
+
+// GetRates calls f(base)
+func (f FXRateProviderFunc) GetRates(base string) (rates map[string]float64, err error) {
+	return f(base)
+}
+
+// conversionUnit is a unit of measure convertible to the other units of its dimension through a base unit:
+// base = value * factor + offset
+type conversionUnit struct {
+	name      string
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// Units by name and alias
+var converterUnits = map[string]conversionUnit{}
+
+func init() {
+	for _, u := range []struct {
+		conversionUnit
+		aliases []string
+	}{
+		{conversionUnit{"mm", "length", 0.001, 0}, []string{"millimeter", "millimeters", "millimetre", "millimetres"}},
+		{conversionUnit{"cm", "length", 0.01, 0}, []string{"centimeter", "centimeters", "centimetre", "centimetres"}},
+		{conversionUnit{"m", "length", 1, 0}, []string{"meter", "meters", "metre", "metres"}},
+		{conversionUnit{"km", "length", 1000, 0}, []string{"kilometer", "kilometers", "kilometre", "kilometres"}},
+		{conversionUnit{"in", "length", 0.0254, 0}, []string{"inch", "inches"}},
+		{conversionUnit{"ft", "length", 0.3048, 0}, []string{"foot", "feet"}},
+		{conversionUnit{"yd", "length", 0.9144, 0}, []string{"yard", "yards"}},
+		{conversionUnit{"mi", "length", 1609.344, 0}, []string{"mile", "miles"}},
+		{conversionUnit{"g", "mass", 0.001, 0}, []string{"gram", "grams"}},
+		{conversionUnit{"kg", "mass", 1, 0}, []string{"kilogram", "kilograms", "kilo", "kilos"}},
+		{conversionUnit{"oz", "mass", 0.028349523125, 0}, []string{"ounce", "ounces"}},
+		{conversionUnit{"lb", "mass", 0.45359237, 0}, []string{"lbs", "pound", "pounds"}},
+		{conversionUnit{"ml", "volume", 0.001, 0}, []string{"milliliter", "milliliters", "millilitre", "millilitres"}},
+		{conversionUnit{"l", "volume", 1, 0}, []string{"liter", "liters", "litre", "litres"}},
+		{conversionUnit{"gal", "volume", 3.785411784, 0}, []string{"gallon", "gallons"}},
+		{conversionUnit{"°C", "temperature", 1, 273.15}, []string{"c", "celsius"}},
+		{conversionUnit{"°F", "temperature", 5. / 9., 273.15 - 32.*5./9.}, []string{"f", "fahrenheit"}},
+		{conversionUnit{"K", "temperature", 1, 0}, []string{"k", "kelvin"}},
+		{conversionUnit{"km/h", "speed", 1 / 3.6, 0}, []string{"kph", "kmh"}},
+		{conversionUnit{"m/s", "speed", 1, 0}, []string{"mps"}},
+		{conversionUnit{"mph", "speed", 0.44704, 0}, []string{}},
+		{conversionUnit{"kn", "speed", 0.514444, 0}, []string{"knot", "knots"}},
+	} {
+		converterUnits[strings.ToLower(u.name)] = u.conversionUnit
+		for _, alias := range u.aliases {
+			converterUnits[alias] = u.conversionUnit
+		}
+	}
+}
+
+// cachedRates are the rates of a base currency along with the day they were fetched
+type cachedRates struct {
+	rates map[string]float64
+	day   string
+}
+
+// Converter holds the plugin data for the converter plugin
+type Converter struct {
+	*slackscot.Plugin
+	provider FXRateProvider
+	printer  *message.Printer
+	now      func() time.Time
+
+	cacheLock sync.Mutex
+	cache     map[string]cachedRates
+}
+
+// NewConverter creates a new instance of the converter plugin. Currency rates come from the FXRateProvider and are
+// fetched at most once a day for each base currency
+func NewConverter(c *config.PluginConfig, provider FXRateProvider) (p *slackscot.Plugin, err error) {
+	return newConverter(c, provider, time.Now)
+}
+
+func newConverter(c *config.PluginConfig, provider FXRateProvider, now func() time.Time) (p *slackscot.Plugin, err error) {
+	c.SetDefault(converterLocaleKey, defaultConverterLocale)
+
+	tag, err := language.Parse(c.GetString(converterLocaleKey))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s config key value for %s: [%s] (%v)", ConverterPluginName, converterLocaleKey, c.GetString(converterLocaleKey), err)
+	}
+
+	cv := new(Converter)
+	cv.provider = provider
+	cv.printer = message.NewPrinter(tag)
+	cv.now = now
+	cv.cache = make(map[string]cachedRates)
+
+	cv.Plugin = plugin.New(ConverterPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return convertRegex.MatchString(m.NormalizedText) }).
+			WithUsage("convert <amount> <unit|currency> to <unit|currency>").
+			WithDescription("Converts an amount between units (i.e. `5 miles to km`) or currencies (i.e. `100 USD to EUR`)").
+			WithAnswerer(cv.convert).
+			Build()).
+		Build()
+
+	return cv.Plugin, nil
+}
+
+// convert answers with the converted amount. Units are tried first and anything else that looks like
+// a currency code is converted as a currency
+func (cv *Converter) convert(m *slackscot.IncomingMessage) *slackscot.Answer {
+	match := convertRegex.FindStringSubmatch(m.NormalizedText)
+	amount, err := strconv.ParseFloat(strings.Replace(match[1], ",", "", -1), 64)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, `%s` isn't a number I understand :thinking_face:", match[1])}
+	}
+
+	from, fromIsUnit := converterUnits[strings.ToLower(match[2])]
+	to, toIsUnit := converterUnits[strings.ToLower(match[3])]
+
+	if fromIsUnit && toIsUnit {
+		if from.dimension != to.dimension {
+			return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I can't convert %s to %s :no_entry_sign:", from.dimension, to.dimension)}
+		}
+
+		converted := ((amount*from.factor + from.offset) - to.offset) / to.factor
+		return &slackscot.Answer{Text: fmt.Sprintf("%s %s = %s %s", cv.formatAmount(amount, 4), from.name, cv.formatAmount(converted, 4), to.name)}
+	}
+
+	if fromIsUnit || toIsUnit || !currencyCodeRegex.MatchString(match[2]) || !currencyCodeRegex.MatchString(match[3]) {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I don't know how to convert `%s` to `%s` :thinking_face:", match[2], match[3])}
+	}
+
+	base, target := strings.ToUpper(match[2]), strings.ToUpper(match[3])
+	rates, err := cv.getRates(base)
+	if err != nil {
+		cv.Logger.Printf("[%s] Error getting rates for [%s]: %v", ConverterPluginName, base, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't get the exchange rates for %s :disappointed: (%v)", base, err)}
+	}
+
+	rate, ok := rates[target]
+	if !ok {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I don't have an exchange rate from %s to %s :shrug:", base, target)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("%s %s = %s %s", cv.formatCurrency(amount), base, cv.formatCurrency(amount*rate), target)}
+}
+
+// getRates returns the rates of a base currency, fetching them again when the cached ones are from a previous day
+func (cv *Converter) getRates(base string) (rates map[string]float64, err error) {
+	cv.cacheLock.Lock()
+	defer cv.cacheLock.Unlock()
+
+	today := cv.now().Format("2006-01-02")
+	if cached, ok := cv.cache[base]; ok && cached.day == today {
+		return cached.rates, nil
+	}
+
+	rates, err = cv.provider.GetRates(base)
+	if err != nil {
+		return nil, err
+	}
+
+	cv.cache[base] = cachedRates{rates: rates, day: today}
+
+	return rates, nil
+}
+
+// formatAmount formats an amount for the configured locale with at most the given number of decimals
+func (cv *Converter) formatAmount(amount float64, maxDecimals int) string {
+	return cv.printer.Sprint(number.Decimal(amount, number.MaxFractionDigits(maxDecimals)))
+}
+
+// formatCurrency formats a currency amount for the configured locale with two decimals
+func (cv *Converter) formatCurrency(amount float64) string {
+	return cv.printer.Sprint(number.Decimal(amount, number.MinFractionDigits(2), number.MaxFractionDigits(2)))
+}
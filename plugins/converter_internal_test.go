@@ -0,0 +1,103 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestFXRateProvider(calls *[]string) FXRateProvider {
+	return FXRateProviderFunc(func(base string) (rates map[string]float64, err error) {
+		*calls = append(*calls, base)
+
+		if base != "USD" {
+			return nil, fmt.Errorf("unsupported base currency")
+		}
+
+		return map[string]float64{"EUR": 0.9, "JPY": 133.25}, nil
+	})
+}
+
+func TestConvertUnits(t *testing.T) {
+	testCases := []struct {
+		text           string
+		expectedAnswer string
+	}{
+		{"convert 5 miles to km", "5 mi = 8.0467 km"},
+		{"convert 1,500 m in mi", "1,500 m = 0.9321 mi"},
+		{"convert 100 F to C", "100 °F = 37.7778 °C"},
+		{"convert -40 celsius to fahrenheit", "-40 °C = -40 °F"},
+		{"convert 2.5 kg to lbs", "2.5 kg = 5.5116 lb"},
+		{"convert 60 mph to km/h", "60 mph = 96.5606 km/h"},
+		{"convert 3 gallons to l", "3 gal = 11.3562 l"},
+		{"convert 5 kg to km", "Sorry, I can't convert mass to length :no_entry_sign:"},
+		{"convert 5 parsecs to km", "Sorry, I don't know how to convert `parsecs` to `km` :thinking_face:"},
+	}
+
+	p, err := NewConverter(viper.New(), newTestFXRateProvider(&[]string{}))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> " + tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
+
+func TestConvertCurrenciesWithDailyRatesCaching(t *testing.T) {
+	calls := []string{}
+	now := time.Date(2023, time.March, 14, 9, 0, 0, 0, time.UTC)
+
+	p, err := newConverter(viper.New(), newTestFXRateProvider(&calls), func() time.Time { return now })
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertConversion := func(text string, expectedAnswer string) {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> " + text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], expectedAnswer)
+		})
+	}
+
+	assertConversion("convert 100 USD to EUR", "100.00 USD = 90.00 EUR")
+	assertConversion("convert 1234.5 usd into jpy", "1,234.50 USD = 164,497.12 JPY")
+	assertConversion("convert 10 USD to XYZ", "Sorry, I don't have an exchange rate from USD to XYZ :shrug:")
+	assertConversion("convert 10 GBP to USD", "Sorry, I couldn't get the exchange rates for GBP :disappointed: (unsupported base currency)")
+	assert.Equal(t, []string{"USD", "GBP"}, calls)
+
+	now = now.Add(16 * time.Hour)
+	assertConversion("convert 100 USD to EUR", "100.00 USD = 90.00 EUR")
+	assert.Equal(t, []string{"USD", "GBP", "USD"}, calls)
+}
+
+func TestConvertWithLocale(t *testing.T) {
+	c := viper.New()
+	c.Set("locale", "de-DE")
+
+	p, err := NewConverter(c, newTestFXRateProvider(&[]string{}))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> convert 1234.5 USD to EUR"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "1.234,50 USD = 1.111,05 EUR")
+	})
+}
+
+func TestConverterInvalidLocale(t *testing.T) {
+	c := viper.New()
+	c.Set("locale", "not a locale!")
+
+	_, err := NewConverter(c, nil)
+	assert.Error(t, err)
+}
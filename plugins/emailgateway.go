@@ -0,0 +1,317 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/slack-go/slack"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+const (
+	// EmailGatewayPluginName holds identifying name for the email gateway plugin
+	EmailGatewayPluginName = "emailGateway"
+
+	// EnvelopeRecipientsHeader is the header holding the envelope recipients of emails (as opposed to the To and Cc
+	// headers which don't include blind copies). EmailSource implementations should set it when they know them
+	EnvelopeRecipientsHeader = "X-Envelope-To"
+)
+
+// Configuration keys
+const (
+	emailRoutesKey              = "routes"              // Map of email addresses to the channel ID their emails are posted to, required
+	emailDefaultChannelIDKey    = "defaultChannelID"    // Channel emails to other addresses are posted to, defaults to none (emails are dropped)
+	emailPollIntervalMinutesKey = "pollIntervalMinutes" // How often new emails are fetched, in minutes. Defaults to 1
+	emailMaxBodyLengthKey       = "maxBodyLength"       // Maximum length of email bodies in messages, defaults to 2000
+)
+
+const (
+	defaultEmailPollIntervalMinutes = 1
+	defaultEmailMaxBodyLength       = 2000
+)
+
+// EmailSource is implemented by email ingestion components (i.e. an SMTP receiver or an IMAP mailbox client)
+type EmailSource interface {
+	// FetchEmails returns the emails received since the last call
+	FetchEmails() (emails []*mail.Message, err error)
+}
+
+// EmailSourceFunc is an adapter to allow the use of a function as an EmailSource
+type EmailSourceFunc func() (emails []*mail.Message, err error)
+
+// FetchEmails calls f()
+func (f EmailSourceFunc) FetchEmails() (emails []*mail.Message, err error) {
+	return f()
+}
+
+// emailAttachment is a file attached to an email
+type emailAttachment struct {
+	filename string
+	content  []byte
+}
+
+// parsedEmail holds the parts of an email posted to channels
+type parsedEmail struct {
+	from        string
+	subject     string
+	body        string
+	recipients  []string
+	attachments []emailAttachment
+}
+
+// EmailGateway holds the plugin data for the email gateway plugin
+type EmailGateway struct {
+	*slackscot.Plugin
+	source           EmailSource
+	routes           map[string]string
+	defaultChannelID string
+	maxBodyLength    int
+}
+
+// NewEmailGateway creates a new instance of the email gateway plugin. Emails fetched from the source are posted to
+// the channels their recipients are routed to, with their attachments uploaded in the message's thread. This is
+// mostly useful for alert emails from systems that can't call webhooks
+func NewEmailGateway(c *config.PluginConfig, source EmailSource) (p *slackscot.Plugin, err error) {
+	if !c.IsSet(emailRoutesKey) {
+		return nil, fmt.Errorf("Missing %s config key: %s", EmailGatewayPluginName, emailRoutesKey)
+	}
+
+	c.SetDefault(emailPollIntervalMinutesKey, defaultEmailPollIntervalMinutes)
+	c.SetDefault(emailMaxBodyLengthKey, defaultEmailMaxBodyLength)
+
+	pollInterval := c.GetInt(emailPollIntervalMinutesKey)
+	if pollInterval <= 0 {
+		return nil, fmt.Errorf("Invalid %s config key value for %s: [%d], should be greater than 0", EmailGatewayPluginName, emailPollIntervalMinutesKey, pollInterval)
+	}
+
+	eg := new(EmailGateway)
+	eg.source = source
+	eg.defaultChannelID = c.GetString(emailDefaultChannelIDKey)
+	eg.maxBodyLength = c.GetInt(emailMaxBodyLengthKey)
+
+	// Configuration map keys are lowercased when loaded which is fine since email addresses are matched ignoring case
+	eg.routes = make(map[string]string)
+	for address, channelID := range c.GetStringMapString(emailRoutesKey) {
+		eg.routes[strings.ToLower(address)] = channelID
+	}
+
+	eg.Plugin = plugin.New(EmailGatewayPluginName).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithInterval(uint64(pollInterval), schedule.Minutes).Build()).
+			WithDescription("Post received emails to their channels").
+			WithAction(eg.postEmails).
+			Build()).
+		Build()
+
+	return eg.Plugin, nil
+}
+
+// postEmails fetches new emails and posts them to their channels
+func (eg *EmailGateway) postEmails() {
+	emails, err := eg.source.FetchEmails()
+	if err != nil {
+		eg.Logger.Printf("[%s] Error fetching emails: %v", EmailGatewayPluginName, err)
+		return
+	}
+
+	for _, msg := range emails {
+		email, err := parseEmail(msg)
+		if err != nil {
+			eg.Logger.Printf("[%s] Error parsing email: %v", EmailGatewayPluginName, err)
+			continue
+		}
+
+		channelIDs := eg.routeEmail(email)
+		if len(channelIDs) == 0 {
+			eg.Logger.Debugf("[%s] Dropping email [%s] to %v with no route", EmailGatewayPluginName, email.subject, email.recipients)
+			continue
+		}
+
+		for _, channelID := range channelIDs {
+			if err := eg.postEmail(channelID, email); err != nil {
+				eg.Logger.Printf("[%s] Error posting email [%s] to [%s]: %v", EmailGatewayPluginName, email.subject, channelID, err)
+			}
+		}
+	}
+}
+
+// routeEmail returns the distinct channels an email is routed to
+func (eg *EmailGateway) routeEmail(email parsedEmail) (channelIDs []string) {
+	channelIDs = make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, recipient := range email.recipients {
+		if channelID, ok := eg.routes[strings.ToLower(recipient)]; ok && !seen[channelID] {
+			seen[channelID] = true
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+
+	if len(channelIDs) == 0 && eg.defaultChannelID != "" {
+		channelIDs = append(channelIDs, eg.defaultChannelID)
+	}
+
+	return channelIDs
+}
+
+// postEmail posts an email to a channel and uploads its attachments in the message's thread
+func (eg *EmailGateway) postEmail(channelID string, email parsedEmail) (err error) {
+	_, timestamp, err := eg.SlackClient.PostMessage(channelID, slack.MsgOptionText(formatEmail(email, eg.maxBodyLength), false), slack.MsgOptionAsUser(true))
+	if err != nil {
+		return err
+	}
+
+	for _, a := range email.attachments {
+		_, err := eg.FileUploader.UploadFile(slack.FileUploadParameters{Reader: bytes.NewReader(a.content), Filename: a.filename, Title: a.filename, Channels: []string{channelID}, ThreadTimestamp: timestamp})
+		if err != nil {
+			eg.Logger.Printf("[%s] Error uploading attachment [%s] of email [%s]: %v", EmailGatewayPluginName, a.filename, email.subject, err)
+		}
+	}
+
+	return nil
+}
+
+// formatEmail renders an email as a message with its body quoted and truncated to maxBodyLength
+func formatEmail(email parsedEmail, maxBodyLength int) string {
+	body := strings.TrimSpace(email.body)
+	if runes := []rune(body); len(runes) > maxBodyLength {
+		body = string(runes[:maxBodyLength]) + "…"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ":envelope_with_arrow: *%s*\n_From %s_", email.subject, email.from)
+	if body != "" {
+		fmt.Fprintf(&b, "\n>%s", strings.Replace(body, "\n", "\n>", -1))
+	}
+
+	if len(email.attachments) > 0 {
+		fmt.Fprintf(&b, "\n:paperclip: %d attachment(s) in thread", len(email.attachments))
+	}
+
+	return b.String()
+}
+
+// parseEmail extracts the sender, subject, recipients, text body and attachments of an email
+func parseEmail(msg *mail.Message) (email parsedEmail, err error) {
+	decoder := new(mime.WordDecoder)
+
+	email.subject, err = decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		email.subject = msg.Header.Get("Subject")
+	}
+
+	email.from = msg.Header.Get("From")
+	if from, err := msg.Header.AddressList("From"); err == nil && len(from) > 0 {
+		email.from = from[0].Address
+		if from[0].Name != "" {
+			email.from = fmt.Sprintf("%s <%s>", from[0].Name, from[0].Address)
+		}
+	}
+
+	headers := []string{"To", "Cc"}
+	if msg.Header.Get(EnvelopeRecipientsHeader) != "" {
+		headers = []string{EnvelopeRecipientsHeader}
+	}
+
+	for _, h := range headers {
+		if addresses, err := msg.Header.AddressList(h); err == nil {
+			for _, a := range addresses {
+				email.recipients = append(email.recipients, a.Address)
+			}
+		}
+	}
+
+	err = parseEmailPart(&email, msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", msg.Body)
+	return email, err
+}
+
+// parseEmailPart parses a part of an email (or the whole email if it's not multipart), keeping the first text part as
+// the body and parts with a filename as attachments
+func parseEmailPart(email *parsedEmail, contentType string, transferEncoding string, disposition string, body io.Reader) (err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := parseEmailPart(email, part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := ioutil.ReadAll(decodeTransferEncoding(transferEncoding, body))
+	if err != nil {
+		return err
+	}
+
+	filename := params["name"]
+	if _, dispositionParams, err := mime.ParseMediaType(disposition); err == nil && dispositionParams["filename"] != "" {
+		filename = dispositionParams["filename"]
+	}
+
+	if filename != "" {
+		email.attachments = append(email.attachments, emailAttachment{filename: filename, content: content})
+		return nil
+	}
+
+	if mediaType == "text/plain" && email.body == "" {
+		email.body = string(content)
+	}
+
+	return nil
+}
+
+// decodeTransferEncoding returns a reader decoding the content transfer encoding of a part
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// newlineStripper removes line breaks from the underlying reader since base64 encoded parts are wrapped
+type newlineStripper struct {
+	r io.Reader
+}
+
+// Read reads from the underlying reader, dropping carriage returns and line feeds
+func (n *newlineStripper) Read(p []byte) (count int, err error) {
+	count, err = n.r.Read(p)
+
+	kept := 0
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+
+	return kept, err
+}
@@ -0,0 +1,138 @@
+package plugins_test
+
+import (
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+const alertEmail = "From: Monitoring <monitoring@example.com>\r\n" +
+	"To: alerts@example.com\r\n" +
+	"Subject: =?utf-8?q?Disk_usage_at_95=25?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Host db-1 is running out of disk space.\r\n" +
+	"Usage: 95=25\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/csv; name=\"usage.csv\"\r\n" +
+	"Content-Disposition: attachment; filename=\"usage.csv\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"aG9zdCx1c2FnZQpkYi0x\r\nLDk1Cg==\r\n" +
+	"--b1--\r\n"
+
+var everyMinute = schedule.New().WithInterval(1, schedule.Minutes).Build()
+
+func newEmailGatewayTestConfig() *viper.Viper {
+	c := viper.New()
+	c.Set("routes", map[string]string{"alerts@example.com": "Calerts", "oncall@example.com": "Concall"})
+
+	return c
+}
+
+func TestEmailGatewayMissingRoutes(t *testing.T) {
+	_, err := plugins.NewEmailGateway(viper.New(), plugins.EmailSourceFunc(func() ([]*mail.Message, error) { return nil, nil }))
+	assert.EqualError(t, err, "Missing emailGateway config key: routes")
+}
+
+func TestEmailGatewayPostsEmailsWithAttachments(t *testing.T) {
+	calls := chatCalls{}
+	testServer := newChatServer(&calls)
+	defer testServer.Stop()
+
+	source, err := plugins.NewSMTPEmailSource("127.0.0.1:0", "slackscot.example.com", []string{"alerts@example.com", "oncall@example.com"})
+	require.NoError(t, err)
+	defer source.Close()
+
+	err = smtp.SendMail(source.Addr().String(), nil, "monitoring@example.com", []string{"nobody@example.com"}, []byte(alertEmail))
+	assert.Error(t, err)
+
+	err = smtp.SendMail(source.Addr().String(), nil, "monitoring@example.com", []string{"alerts@example.com"}, []byte(alertEmail))
+	require.NoError(t, err)
+
+	p, err := plugins.NewEmailGateway(newEmailGatewayTestConfig(), source)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.RunsOnSchedule(p, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		if !assert.Len(t, fileUploads, 1) {
+			return false
+		}
+
+		content, err := ioutil.ReadAll(fileUploads[0].Reader)
+		return assert.NoError(t, err) &&
+			assert.Equal(t, "usage.csv", fileUploads[0].Filename) &&
+			assert.Equal(t, "host,usage\ndb-1,95\n", string(content)) &&
+			assert.Equal(t, []string{"Calerts"}, fileUploads[0].Channels) &&
+			assert.Equal(t, "1546833210.036900", fileUploads[0].ThreadTimestamp)
+	})
+
+	assert.Equal(t, []string{"post Calerts: :envelope_with_arrow: *Disk usage at 95%*\n_From Monitoring <monitoring@example.com>_\n>Host db-1 is running out of disk space.\n>Usage: 95%\n:paperclip: 1 attachment(s) in thread"}, calls.calls)
+
+	// Emails are only posted once
+	assertplugin.RunsOnSchedule(p, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, fileUploads)
+	})
+
+	assert.Len(t, calls.calls, 1)
+}
+
+func TestEmailGatewayRouting(t *testing.T) {
+	calls := chatCalls{}
+	testServer := newChatServer(&calls)
+	defer testServer.Stop()
+
+	emails := []string{
+		"From: ci@example.com\r\nTo: Alerts <ALERTS@example.com>, oncall@example.com\r\nCc: alerts@example.com\r\nSubject: Build broken\r\n\r\nmain is red\r\n",
+		"From: ci@example.com\r\nTo: someone@example.com\r\nSubject: Unrouted\r\n\r\nhello\r\n",
+	}
+
+	source := plugins.EmailSourceFunc(func() (messages []*mail.Message, err error) {
+		for _, e := range emails {
+			msg, err := mail.ReadMessage(strings.NewReader(e))
+			if err != nil {
+				return nil, err
+			}
+
+			messages = append(messages, msg)
+		}
+
+		return messages, nil
+	})
+
+	c := newEmailGatewayTestConfig()
+	c.Set("defaultChannelID", "Cemails")
+	c.Set("maxBodyLength", 4)
+
+	p, err := plugins.NewEmailGateway(c, source)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.RunsOnSchedule(p, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, fileUploads)
+	})
+
+	assert.ElementsMatch(t, []string{
+		"post Calerts: :envelope_with_arrow: *Build broken*\n_From ci@example.com_\n>main…",
+		"post Concall: :envelope_with_arrow: *Build broken*\n_From ci@example.com_\n>main…",
+		"post Cemails: :envelope_with_arrow: *Unrouted*\n_From ci@example.com_\n>hell…",
+	}, calls.calls)
+}
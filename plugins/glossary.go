@@ -0,0 +1,293 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// GlossaryPluginName holds identifying name for the glossary plugin
+	GlossaryPluginName = "glossary"
+)
+
+// Configuration keys
+const (
+	glossaryOfferDefinitionsKey = "offerDefinitions" // Whether definitions are offered when known acronyms appear in messages, defaults to false
+	glossaryOfferCooldownKey    = "offerCooldown"    // Minimum time between two offers of the same definition in a channel, defaults to 24h
+)
+
+const (
+	defaultGlossaryOfferCooldown = 24 * time.Hour
+	glossaryTermsSilo            = "terms"
+)
+
+var glossaryAddRegex = regexp.MustCompile("(?msi)\\Aglossary add (.+?)\\s*=>\\s*(.+)\\z")
+var glossaryRemoveRegex = regexp.MustCompile("(?i)\\Aglossary remove (.+)\\z")
+var defineRegex = regexp.MustCompile("(?i)\\Adefine (.+)\\z")
+var acronymRegex = regexp.MustCompile("\\b[A-Z][A-Z0-9]{1,7}\\b")
+
+// glossaryEntry is what's persisted for every term
+type glossaryEntry struct {
+	Term       string    `json:"term"`
+	Definition string    `json:"definition"`
+	Author     string    `json:"author"`
+	Added      time.Time `json:"added"`
+}
+
+// Glossary holds the plugin data for the glossary plugin
+type Glossary struct {
+	*slackscot.Plugin
+	storer        store.GlobalSiloStringStorer
+	now           func() time.Time
+	offerCooldown time.Duration
+
+	offersMutex sync.Mutex
+	// lastOffers holds the last time a term was offered by channel and term
+	lastOffers map[string]time.Time
+}
+
+// NewGlossary creates a new instance of the glossary plugin. Terms are shared by the whole workspace and persisted
+// with the storer. When enabled, definitions of known acronyms appearing in messages are offered in a thread
+func NewGlossary(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin) {
+	return newGlossary(c, storer, time.Now)
+}
+
+func newGlossary(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (p *slackscot.Plugin) {
+	c.SetDefault(glossaryOfferCooldownKey, defaultGlossaryOfferCooldown)
+
+	g := new(Glossary)
+	g.storer = storer
+	g.now = now
+	g.offerCooldown = c.GetDuration(glossaryOfferCooldownKey)
+	g.lastOffers = make(map[string]time.Time)
+
+	pb := plugin.New(GlossaryPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return defineRegex.MatchString(m.NormalizedText) }).
+			WithUsage("define <term>").
+			WithDescription("Look up the definition of a term in the glossary").
+			WithAnswerer(g.define).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return glossaryAddRegex.MatchString(m.NormalizedText) }).
+			WithUsage("glossary add <term> => <definition>").
+			WithDescription("Add or update the definition of a term").
+			WithAnswerer(g.add).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return glossaryRemoveRegex.MatchString(m.NormalizedText) }).
+			WithUsage("glossary remove <term>").
+			WithDescription("Remove a term from the glossary").
+			WithAnswerer(g.remove).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return strings.HasPrefix(m.NormalizedText, "glossary export") }).
+			WithUsage("glossary export").
+			WithDescription("Exports the glossary as a csv file").
+			WithAnswerer(g.export).
+			Build())
+
+	if c.GetBool(glossaryOfferDefinitionsKey) {
+		pb = pb.WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return acronymRegex.MatchString(m.NormalizedText) }).
+			WithUsage("SLO").
+			WithDescription("Offer definitions of known acronyms mentioned in messages").
+			WithAnswerer(g.offerDefinitions).
+			Build())
+	}
+
+	g.Plugin = pb.Build()
+
+	return g.Plugin
+}
+
+// define answers with the definition of a term or of the closest known term
+func (g *Glossary) define(m *slackscot.IncomingMessage) *slackscot.Answer {
+	term := strings.TrimSpace(defineRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	entry, err := g.getEntry(term)
+	if err == nil {
+		return &slackscot.Answer{Text: formatGlossaryEntry(entry)}
+	}
+
+	entries, err := g.scanEntries()
+	if err != nil {
+		g.Logger.Printf("[%s] Error loading glossary: %v", GlossaryPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the glossary :disappointed: (%v)", err)}
+	}
+
+	if closest, found := findClosestTerm(term, entries); found {
+		return &slackscot.Answer{Text: fmt.Sprintf("I don't know `%s` but maybe you meant:\n%s", term, formatGlossaryEntry(closest))}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("`%s` isn't in the glossary yet :thinking_face:. Add it with `glossary add %s => <definition>`", term, term)}
+}
+
+// add records the definition of a term along with its author
+func (g *Glossary) add(m *slackscot.IncomingMessage) *slackscot.Answer {
+	match := glossaryAddRegex.FindStringSubmatch(m.NormalizedText)
+	entry := glossaryEntry{Term: strings.TrimSpace(match[1]), Definition: strings.TrimSpace(match[2]), Author: m.User, Added: g.now()}
+
+	value, err := json.Marshal(entry)
+	if err == nil {
+		err = g.storer.PutSiloString(glossaryTermsSilo, strings.ToLower(entry.Term), string(value))
+	}
+
+	if err != nil {
+		g.Logger.Printf("[%s] Error persisting definition of [%s]: %v", GlossaryPluginName, entry.Term, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't save the definition of `%s` :disappointed: (%v)", entry.Term, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Added `%s` to the glossary :books:", entry.Term)}
+}
+
+// remove deletes a term from the glossary
+func (g *Glossary) remove(m *slackscot.IncomingMessage) *slackscot.Answer {
+	term := strings.TrimSpace(glossaryRemoveRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	if _, err := g.getEntry(term); err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("`%s` isn't in the glossary :shrug:", term)}
+	}
+
+	if err := g.storer.DeleteSiloString(glossaryTermsSilo, strings.ToLower(term)); err != nil {
+		g.Logger.Printf("[%s] Error deleting definition of [%s]: %v", GlossaryPluginName, term, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't remove `%s` :disappointed: (%v)", term, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Removed `%s` from the glossary :wastebasket:", term)}
+}
+
+// export uploads the glossary as a csv file
+func (g *Glossary) export(m *slackscot.IncomingMessage) *slackscot.Answer {
+	entries, err := g.scanEntries()
+	if err != nil {
+		g.Logger.Printf("[%s] Error loading glossary: %v", GlossaryPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the glossary :disappointed: (%v)", err)}
+	}
+
+	var content bytes.Buffer
+	w := csv.NewWriter(&content)
+	w.Write([]string{"term", "definition", "author", "added"})
+	for _, e := range entries {
+		w.Write([]string{e.Term, e.Definition, e.Author, e.Added.Format(time.RFC3339)})
+	}
+	w.Flush()
+
+	_, err = g.FileUploader.UploadFile(slack.FileUploadParameters{Content: content.String(), Filetype: "csv", Filename: "glossary.csv", Title: "Glossary export", Channels: []string{m.Channel}})
+	if err != nil {
+		g.Logger.Printf("[%s] Error uploading glossary export: %v", GlossaryPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't upload the export :disappointed: (%v)", err)}
+	}
+
+	return nil
+}
+
+// offerDefinitions answers in a thread with the definitions of known acronyms mentioned in a message. A definition
+// isn't offered again in the same channel until the cooldown expires
+func (g *Glossary) offerDefinitions(m *slackscot.IncomingMessage) *slackscot.Answer {
+	g.offersMutex.Lock()
+	defer g.offersMutex.Unlock()
+
+	lines := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, acronym := range acronymRegex.FindAllString(m.NormalizedText, -1) {
+		offerKey := m.Channel + ":" + strings.ToLower(acronym)
+		if seen[offerKey] || len(lines) >= defaultItemCount {
+			continue
+		}
+		seen[offerKey] = true
+
+		if last, ok := g.lastOffers[offerKey]; ok && g.now().Sub(last) < g.offerCooldown {
+			continue
+		}
+
+		entry, err := g.getEntry(acronym)
+		if err != nil {
+			continue
+		}
+
+		g.lastOffers[offerKey] = g.now()
+		lines = append(lines, formatGlossaryEntry(entry))
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf(":books: From the glossary:\n%s", strings.Join(lines, "\n")), Options: []slackscot.AnswerOption{slackscot.AnswerInThread()}}
+}
+
+// getEntry returns the glossary entry of a term, ignoring case
+func (g *Glossary) getEntry(term string) (entry glossaryEntry, err error) {
+	value, err := g.storer.GetSiloString(glossaryTermsSilo, strings.ToLower(term))
+	if err != nil {
+		return entry, err
+	}
+
+	err = json.Unmarshal([]byte(value), &entry)
+	return entry, err
+}
+
+// scanEntries returns all valid glossary entries sorted by term
+func (g *Glossary) scanEntries() (entries []glossaryEntry, err error) {
+	raw, err := g.storer.ScanSilo(glossaryTermsSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = make([]glossaryEntry, 0, len(raw))
+	for _, value := range raw {
+		var e glossaryEntry
+		if err := json.Unmarshal([]byte(value), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Term) < strings.ToLower(entries[j].Term)
+	})
+
+	return entries, nil
+}
+
+// findClosestTerm returns the entry whose term is the fewest edits away from the given term. Short terms tolerate
+// a single edit and longer ones two
+func findClosestTerm(term string, entries []glossaryEntry) (closest glossaryEntry, found bool) {
+	term = strings.ToLower(term)
+
+	maxDistance := 1
+	if len([]rune(term)) > 4 {
+		maxDistance = 2
+	}
+
+	bestDistance := maxDistance + 1
+	for _, e := range entries {
+		if d := editDistance(term, strings.ToLower(e.Term)); d < bestDistance {
+			closest = e
+			bestDistance = d
+			found = true
+		}
+	}
+
+	return closest, found
+}
+
+// formatGlossaryEntry renders a glossary entry on a single line
+func formatGlossaryEntry(e glossaryEntry) string {
+	return fmt.Sprintf("*%s*: %s (added by <@%s>)", e.Term, e.Definition, e.Author)
+}
@@ -0,0 +1,110 @@
+package plugins_test
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestGlossaryAddDefineAndRemove(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "glossaryTest")
+	defer cleanup()
+
+	testCases := []struct {
+		text           string
+		expectedAnswer string
+	}{
+		{"<@bot> define SLO", "`SLO` isn't in the glossary yet :thinking_face:. Add it with `glossary add SLO => <definition>`"},
+		{"<@bot> glossary add SLO => Service Level Objective", "Added `SLO` to the glossary :books:"},
+		{"<@bot> define slo", "*SLO*: Service Level Objective (added by <@U21355>)"},
+		{"<@bot> define SLA", "I don't know `SLA` but maybe you meant:\n*SLO*: Service Level Objective (added by <@U21355>)"},
+		{"<@bot> define XYZ", "`XYZ` isn't in the glossary yet :thinking_face:. Add it with `glossary add XYZ => <definition>`"},
+		{"<@bot> glossary add Error Budget => How much unreliability an SLO allows", "Added `Error Budget` to the glossary :books:"},
+		{"<@bot> define error budgte", "I don't know `error budgte` but maybe you meant:\n*Error Budget*: How much unreliability an SLO allows (added by <@U21355>)"},
+		{"<@bot> glossary remove slo", "Removed `slo` from the glossary :wastebasket:"},
+		{"<@bot> glossary remove slo", "`slo` isn't in the glossary :shrug:"},
+	}
+
+	p := plugins.NewGlossary(viper.New(), storer)
+	assertplugin := assertplugin.New(t, "bot")
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
+
+func TestGlossaryExport(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "glossaryTest")
+	defer cleanup()
+
+	p := plugins.NewGlossary(viper.New(), storer)
+	assertplugin := assertplugin.New(t, "bot")
+
+	for _, text := range []string{"<@bot> glossary add SLO => Service Level Objective", "<@bot> glossary add API => Application, \"Programming\" Interface"} {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1)
+		})
+	}
+
+	assertplugin.AnswersAndReactsWithUploads(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> glossary export"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, fileUploads []slack.FileUploadParameters) bool {
+		if !assert.Empty(t, answers) || !assert.Len(t, fileUploads, 1) {
+			return false
+		}
+
+		lines := strings.Split(strings.TrimSpace(fileUploads[0].Content), "\n")
+		return assert.Equal(t, "glossary.csv", fileUploads[0].Filename) &&
+			assert.Equal(t, []string{"Cgeneral"}, fileUploads[0].Channels) &&
+			assert.Len(t, lines, 3) &&
+			assert.Equal(t, "term,definition,author,added", lines[0]) &&
+			assert.True(t, strings.HasPrefix(lines[1], "API,\"Application, \"\"Programming\"\" Interface\",U21355,"), lines[1]) &&
+			assert.True(t, strings.HasPrefix(lines[2], "SLO,Service Level Objective,U21355,"), lines[2])
+	})
+}
+
+func TestGlossaryOffersDefinitionsOfKnownAcronyms(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "glossaryTest")
+	defer cleanup()
+
+	c := viper.New()
+	c.Set("offerDefinitions", true)
+
+	p := plugins.NewGlossary(c, storer)
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> glossary add SLO => Service Level Objective"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21356", Text: "We missed our SLO and the API is down"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":books: From the glossary:\n*SLO*: Service Level Objective (added by <@U21355>)") &&
+			assertanswer.HasOptions(t, answers[0], assertanswer.ResolvedAnswerOption{Key: slackscot.ThreadedReplyOpt, Value: "true"})
+	})
+
+	// The same definition isn't offered again in the same channel until the cooldown expires
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21356", Text: "The SLO is still at risk"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Empty(t, answers)
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cother", User: "U21356", Text: "What's our SLO?"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+}
+
+func TestGlossaryDoesNotOfferDefinitionsByDefault(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "glossaryTest")
+	defer cleanup()
+
+	p := plugins.NewGlossary(viper.New(), storer)
+
+	assert.Empty(t, p.HearActions)
+}
@@ -0,0 +1,258 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/slack-go/slack"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// GrafanaPluginName holds identifying name for the grafana plugin
+	GrafanaPluginName = "grafana"
+
+	// GrafanaAlertsWebhookPath is the path of the webhook receiving grafana alert notifications, served at
+	// /webhooks/grafana/alerts. It should be the url of a webhook notification channel in grafana
+	GrafanaAlertsWebhookPath = "alerts"
+)
+
+// Configuration keys
+const (
+	grafanaURLKey             = "url"             // Url of grafana, required
+	grafanaAPIKeyKey          = "apiKey"          // Grafana API key with the editor role, required
+	grafanaChannelIDKey       = "channelID"       // Channel where alerts are posted, required
+	grafanaWebhookUsernameKey = "webhookUsername" // Basic auth username of the webhook notification channel, optional
+	grafanaWebhookPasswordKey = "webhookPassword" // Basic auth password of the webhook notification channel, optional
+)
+
+const (
+	grafanaRequestTimeout = 30 * time.Second
+)
+
+var annotateRegex = regexp.MustCompile("(?i)\\Aannotate (\\S+) (.+)\\z")
+
+// grafanaStateEmojis holds the emoji shown for each grafana alert state
+var grafanaStateEmojis = map[string]string{
+	"alerting": ":red_circle:",
+	"ok":       ":large_green_circle:",
+	"no_data":  ":grey_question:",
+	"pending":  ":hourglass_flowing_sand:",
+	"paused":   ":double_vertical_bar:",
+}
+
+// grafanaAlert is an alert notification sent by a grafana webhook notification channel
+type grafanaAlert struct {
+	Title       string             `json:"title"`
+	RuleName    string             `json:"ruleName"`
+	RuleURL     string             `json:"ruleUrl"`
+	State       string             `json:"state"`
+	ImageURL    string             `json:"imageUrl"`
+	Message     string             `json:"message"`
+	EvalMatches []grafanaEvalMatch `json:"evalMatches"`
+}
+
+// grafanaEvalMatch is a series that matched the alert rule
+type grafanaEvalMatch struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+}
+
+// grafanaAnnotation is an annotation creation request of the grafana API
+type grafanaAnnotation struct {
+	Tags []string `json:"tags"`
+	Text string   `json:"text"`
+}
+
+// Grafana holds the plugin data for the grafana plugin
+type Grafana struct {
+	*slackscot.Plugin
+	url             string
+	apiKey          string
+	channelID       string
+	webhookUsername string
+	webhookPassword string
+	client          *http.Client
+}
+
+// NewGrafana creates a new instance of the grafana plugin. It adds a command writing annotations tagged with an
+// environment (i.e. to mark deployments on dashboards) and posts alerts received on its webhook along with
+// the rendered image of their panel
+func NewGrafana(c *config.PluginConfig) (p *slackscot.Plugin, err error) {
+	for _, k := range []string{grafanaURLKey, grafanaAPIKeyKey, grafanaChannelIDKey} {
+		if !c.IsSet(k) {
+			return nil, fmt.Errorf("Missing %s config key: %s", GrafanaPluginName, k)
+		}
+	}
+
+	g := new(Grafana)
+	g.url = strings.TrimSuffix(c.GetString(grafanaURLKey), "/")
+	g.apiKey = c.GetString(grafanaAPIKeyKey)
+	g.channelID = c.GetString(grafanaChannelIDKey)
+	g.webhookUsername = c.GetString(grafanaWebhookUsernameKey)
+	g.webhookPassword = c.GetString(grafanaWebhookPasswordKey)
+	g.client = &http.Client{Timeout: grafanaRequestTimeout}
+
+	g.Plugin = plugin.New(GrafanaPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return annotateRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("annotate <environment> '<text>'").
+			WithDescription("Adds an annotation tagged with the environment to grafana dashboards").
+			WithAnswerer(g.annotate).
+			Build()).
+		WithWebhook(GrafanaAlertsWebhookPath, g.receiveAlert).
+		Build()
+
+	return g.Plugin, nil
+}
+
+// annotate writes a grafana annotation with the text tagged with the environment and the requester
+func (g *Grafana) annotate(m *slackscot.IncomingMessage) *slackscot.Answer {
+	match := annotateRegex.FindStringSubmatch(m.NormalizedText)
+	environment, text := match[1], strings.Trim(match[2], "'\"‘’“” ")
+
+	annotation := grafanaAnnotation{Tags: []string{environment, "slackscot"}, Text: text}
+	if user, err := g.UserInfoFinder.GetUserInfo(m.User); err == nil {
+		annotation.Text = fmt.Sprintf("%s (by %s)", text, user.RealName)
+	}
+
+	if err := g.createAnnotation(annotation); err != nil {
+		g.Logger.Printf("[%s] Error creating annotation [%s] on [%s]: %v", GrafanaPluginName, text, environment, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't annotate `%s` :disappointed: (%v)", environment, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf(":pushpin: Annotated `%s` with _%s_", environment, text)}
+}
+
+// createAnnotation creates an annotation with the grafana API. The time of the annotation defaults to now
+func (g *Grafana) createAnnotation(annotation grafanaAnnotation) (err error) {
+	body, err := json.Marshal(annotation)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", g.url+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status [%s] from grafana", resp.Status)
+	}
+
+	return nil
+}
+
+// receiveAlert posts an alert received on the webhook and uploads its panel image in the message's thread
+func (g *Grafana) receiveAlert(w http.ResponseWriter, r *http.Request) {
+	if g.webhookPassword != "" {
+		if username, password, ok := r.BasicAuth(); !ok || username != g.webhookUsername || password != g.webhookPassword {
+			g.Logger.Printf("[%s] Rejecting alert with invalid credentials", GrafanaPluginName)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var alert grafanaAlert
+	if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+		g.Logger.Printf("[%s] Error decoding alert: %v", GrafanaPluginName, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, timestamp, err := g.SlackClient.PostMessage(g.channelID, slack.MsgOptionText(formatGrafanaAlert(alert), false), slack.MsgOptionAsUser(true))
+	if err != nil {
+		g.Logger.Printf("[%s] Error posting alert [%s]: %v", GrafanaPluginName, alert.Title, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if alert.ImageURL != "" {
+		g.uploadPanelImage(alert, timestamp)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// uploadPanelImage downloads the rendered panel image of an alert and uploads it in the thread of the alert message.
+// Failures are only logged since the alert message was already posted
+func (g *Grafana) uploadPanelImage(alert grafanaAlert, timestamp string) {
+	image, err := g.fetchImage(alert.ImageURL)
+	if err != nil {
+		g.Logger.Printf("[%s] Error fetching panel image of alert [%s]: %v", GrafanaPluginName, alert.Title, err)
+		return
+	}
+
+	_, err = g.FileUploader.UploadFile(slack.FileUploadParameters{Reader: bytes.NewReader(image), Filename: "panel.png", Title: alert.RuleName, Channels: []string{g.channelID}, ThreadTimestamp: timestamp})
+	if err != nil {
+		g.Logger.Printf("[%s] Error uploading panel image of alert [%s]: %v", GrafanaPluginName, alert.Title, err)
+	}
+}
+
+// fetchImage downloads an image, authenticating with the API key when the image is served by grafana
+func (g *Grafana) fetchImage(url string) (image []byte, err error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(url, g.url+"/") {
+		req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status [%s] from [%s]", resp.Status, url)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// formatGrafanaAlert renders an alert with its state, message and matching series
+func formatGrafanaAlert(alert grafanaAlert) string {
+	emoji, ok := grafanaStateEmojis[alert.State]
+	if !ok {
+		emoji = ":bell:"
+	}
+
+	title := alert.Title
+	if alert.RuleURL != "" {
+		title = fmt.Sprintf("<%s|%s>", alert.RuleURL, alert.Title)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s *%s*", emoji, title)
+	if alert.Message != "" {
+		fmt.Fprintf(&b, "\n%s", alert.Message)
+	}
+
+	for _, m := range alert.EvalMatches {
+		fmt.Fprintf(&b, "\n• `%s`: %g", m.Metric, m.Value)
+	}
+
+	return b.String()
+}
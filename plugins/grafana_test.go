@@ -0,0 +1,157 @@
+package plugins_test
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/alexandre-normand/slackscot/test/capture"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const grafanaAlertPayload = `{
+  "title": "[Alerting] API latency",
+  "ruleName": "API latency",
+  "ruleUrl": "https://grafana.example.com/d/api",
+  "state": "alerting",
+  "imageUrl": "%s/render/panel.png",
+  "message": "p99 latency is above 2s",
+  "evalMatches": [{"metric": "api-1", "value": 2.5}]
+}`
+
+// grafanaAnnotationRequest is an annotation request received by the test grafana server
+type grafanaAnnotationRequest struct {
+	authorization string
+	tags          []string
+	text          string
+}
+
+func newGrafanaServer(annotations *[]grafanaAnnotationRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/annotations":
+			var a struct {
+				Tags []string `json:"tags"`
+				Text string   `json:"text"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&a)
+			*annotations = append(*annotations, grafanaAnnotationRequest{authorization: r.Header.Get("Authorization"), tags: a.Tags, text: a.Text})
+
+			_, _ = w.Write([]byte(`{"message": "Annotation added", "id": 1}`))
+		case "/render/panel.png":
+			if r.Header.Get("Authorization") != "Bearer k3y" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			_, _ = w.Write([]byte("png"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func newGrafanaTestConfig(url string) *viper.Viper {
+	c := viper.New()
+	c.Set("url", url)
+	c.Set("apiKey", "k3y")
+	c.Set("channelID", "Calerts")
+
+	return c
+}
+
+func TestGrafanaMissingConfig(t *testing.T) {
+	c := viper.New()
+	_, err := plugins.NewGrafana(c)
+	assert.EqualError(t, err, "Missing grafana config key: url")
+
+	c.Set("url", "https://grafana.example.com")
+	_, err = plugins.NewGrafana(c)
+	assert.EqualError(t, err, "Missing grafana config key: apiKey")
+
+	c.Set("apiKey", "k3y")
+	_, err = plugins.NewGrafana(c)
+	assert.EqualError(t, err, "Missing grafana config key: channelID")
+}
+
+func TestGrafanaAnnotate(t *testing.T) {
+	var annotations []grafanaAnnotationRequest
+	grafanaServer := newGrafanaServer(&annotations)
+	defer grafanaServer.Close()
+
+	p, err := plugins.NewGrafana(newGrafanaTestConfig(grafanaServer.URL))
+	require.NoError(t, err)
+	p.UserInfoFinder = userInfoFinder{}
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> annotate prod 'deployed v2.1'"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":pushpin: Annotated `prod` with _deployed v2.1_")
+	})
+
+	assert.Equal(t, []grafanaAnnotationRequest{{authorization: "Bearer k3y", tags: []string{"prod", "slackscot"}, text: "deployed v2.1 (by Bernard Tremblay)"}}, annotations)
+
+	grafanaServer.Close()
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> annotate staging rollback"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assert.True(t, strings.HasPrefix(answers[0].Text, "Sorry, I couldn't annotate `staging` :disappointed:"))
+	})
+}
+
+func TestGrafanaPostsAlertsWithPanelImage(t *testing.T) {
+	var annotations []grafanaAnnotationRequest
+	grafanaServer := newGrafanaServer(&annotations)
+	defer grafanaServer.Close()
+
+	calls := chatCalls{}
+	testServer := newChatServer(&calls)
+	defer testServer.Stop()
+
+	c := newGrafanaTestConfig(grafanaServer.URL)
+	c.Set("webhookUsername", "grafana")
+	c.Set("webhookPassword", "s3cr3t")
+
+	p, err := plugins.NewGrafana(c)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+	handle := p.Webhooks[0].Handle
+	newAlertRequest := func(password string) *http.Request {
+		r := httptest.NewRequest("POST", "/webhooks/grafana/alerts", strings.NewReader(strings.Replace(grafanaAlertPayload, "%s", grafanaServer.URL, 1)))
+		r.SetBasicAuth("grafana", password)
+
+		return r
+	}
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.HandlesWebhook(p, plugins.GrafanaAlertsWebhookPath, newAlertRequest("not the password"), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+	assert.Empty(t, calls.calls)
+
+	// Capture uploads once the test services are injected
+	uploads := capture.NewFileUploader()
+	p.FileUploader = slackscot.NewFileUploader(uploads)
+
+	response := httptest.NewRecorder()
+	handle(response, newAlertRequest("s3cr3t"))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	assert.Equal(t, []string{"post Calerts: :red_circle: *<https://grafana.example.com/d/api|[Alerting] API latency>*\np99 latency is above 2s\n• `api-1`: 2.5"}, calls.calls)
+
+	if assert.Len(t, uploads.FileUploads, 1) {
+		image, err := ioutil.ReadAll(uploads.FileUploads[0].Reader)
+		assert.NoError(t, err)
+		assert.Equal(t, "png", string(image))
+		assert.Equal(t, []string{"Calerts"}, uploads.FileUploads[0].Channels)
+		assert.Equal(t, "1546833210.036900", uploads.FileUploads[0].ThreadTimestamp)
+	}
+}
@@ -0,0 +1,70 @@
+package plugins
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"net/http"
+	"sync"
+)
+
+// newBlockActionsInteraction returns a click by the user on a button routed to a plugin's interaction handler
+func newBlockActionsInteraction(userID string, blockID string, actionID string, value string) (i *slackscot.Interaction) {
+	i = &slackscot.Interaction{CallbackID: blockID}
+	i.Type = slack.InteractionTypeBlockActions
+	i.User.ID = userID
+	i.ActionCallback.BlockActions = []*slack.BlockAction{{BlockID: blockID, ActionID: actionID, Value: value}}
+
+	return i
+}
+
+// postedMessage is a message posted to the test slack server. Updates have the timestamp of the message they update
+// and ephemeral messages the user they're shown to
+type postedMessage struct {
+	channel     string
+	text        string
+	threadTS    string
+	ts          string
+	user        string
+	attachments []slack.Attachment
+	blocks      []slack.Block
+}
+
+// postedMessages records messages posted to the test slack server
+type postedMessages struct {
+	sync.Mutex
+	messages []postedMessage
+}
+
+func newPostedMessagesServer(posted *postedMessages) (testServer *slacktest.Server) {
+	testServer = slacktest.NewTestServer(func(c slacktest.Customize) {
+		record := func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+
+			m := postedMessage{channel: r.Form.Get("channel"), text: r.Form.Get("text"), threadTS: r.Form.Get("thread_ts"), ts: r.Form.Get("ts"), user: r.Form.Get("user")}
+			if attachments := r.Form.Get("attachments"); attachments != "" {
+				_ = json.Unmarshal([]byte(attachments), &m.attachments)
+			}
+
+			if blocks := r.Form.Get("blocks"); blocks != "" {
+				var decoded slack.Blocks
+				_ = json.Unmarshal([]byte(blocks), &decoded)
+				m.blocks = decoded.BlockSet
+			}
+
+			posted.Lock()
+			posted.messages = append(posted.messages, m)
+			posted.Unlock()
+
+			_, _ = w.Write([]byte(`{"ok": true, "channel": "` + m.channel + `", "ts": "1546833210.036900", "message_ts": "1546833210.036900"}`))
+		}
+
+		c.Handle("/chat.postMessage", record)
+		c.Handle("/chat.update", record)
+		c.Handle("/chat.postEphemeral", record)
+	})
+	testServer.Start()
+
+	return testServer
+}
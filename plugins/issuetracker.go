@@ -0,0 +1,203 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	// IssueTrackerPluginName holds identifying name for the issue tracker plugin
+	IssueTrackerPluginName = "issueTracker"
+
+	// IssueTrackerTransitionsWebhookPath is the path of the webhook receiving issue transitions, served at
+	// /webhooks/issueTracker/transitions
+	IssueTrackerTransitionsWebhookPath = "transitions"
+)
+
+// Configuration keys
+const (
+	issueTrackerCommandKey     = "command"            // Word of the commands (i.e. "jira" for "jira create"), defaults to jira
+	issueTrackerProjectsKey    = "projects"           // Project keys of the issues to expand, defaults to all
+	issueTrackerTransitionsKey = "transitionChannels" // Map of project keys to the channel ID transitions of their issues are posted to
+)
+
+const (
+	defaultIssueTrackerCommand = "jira"
+)
+
+var issueKeyRegex = regexp.MustCompile("\\b([A-Z][A-Z0-9]+)-(\\d+)\\b")
+
+// Issue is an issue of an issue tracker
+type Issue struct {
+	Key      string
+	Summary  string
+	Status   string
+	Assignee string
+	URL      string
+}
+
+// IssueTransition is the change of status of an issue
+type IssueTransition struct {
+	Issue Issue
+	From  string
+	To    string
+}
+
+// IssueTracker is implemented by issue tracking services (i.e. Jira, GitLab or Linear)
+type IssueTracker interface {
+	// GetIssue returns the issue with the given key (i.e. PROJ-123)
+	GetIssue(key string) (issue *Issue, err error)
+
+	// CreateIssue creates a new issue in a project on behalf of a slack user
+	CreateIssue(project string, summary string, reporterID string) (issue *Issue, err error)
+
+	// ParseTransition parses a webhook request sent by the issue tracker. A nil transition
+	// is returned for valid requests that aren't transitions
+	ParseTransition(r *http.Request) (transition *IssueTransition, err error)
+}
+
+// issueTracker holds the plugin data for the issue tracker plugin
+type issueTracker struct {
+	*slackscot.Plugin
+	tracker            IssueTracker
+	projects           map[string]bool
+	transitionChannels map[string]string
+	createRegex        *regexp.Regexp
+}
+
+// NewIssueTracker creates a new instance of the issue tracker plugin. It expands issue keys mentioned in messages
+// with their summary, status and assignee, creates issues on command and posts issue transitions received
+// on its webhook
+func NewIssueTracker(c *config.PluginConfig, tracker IssueTracker) (p *slackscot.Plugin, err error) {
+	c.SetDefault(issueTrackerCommandKey, defaultIssueTrackerCommand)
+
+	it := new(issueTracker)
+	it.tracker = tracker
+
+	it.projects = make(map[string]bool)
+	for _, project := range c.GetStringSlice(issueTrackerProjectsKey) {
+		it.projects[project] = true
+	}
+
+	// Configuration map keys are lowercased when loaded so project keys are restored to their original upper case
+	it.transitionChannels = make(map[string]string)
+	for project, channelID := range c.GetStringMapString(issueTrackerTransitionsKey) {
+		it.transitionChannels[strings.ToUpper(project)] = channelID
+	}
+
+	command := c.GetString(issueTrackerCommandKey)
+	if it.createRegex, err = regexp.Compile(fmt.Sprintf("(?is)\\A%s create ([A-Za-z][A-Za-z0-9]+)\\s+(.+)\\z", regexp.QuoteMeta(command))); err != nil {
+		return nil, err
+	}
+
+	it.Plugin = plugin.New(IssueTrackerPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return it.createRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage(fmt.Sprintf("%s create <PROJECT> <summary>", command)).
+			WithDescription("Create an issue in a project").
+			WithAnswerer(it.createIssue).
+			Build()).
+		WithHearAction(actions.NewHearAction().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return len(it.findIssueKeys(m.NormalizedText)) > 0
+			}).
+			WithUsage("PROJ-123").
+			WithDescription("Expand mentioned issues with their summary, status and assignee").
+			WithAnswerer(it.expandIssues).
+			Build()).
+		WithWebhook(IssueTrackerTransitionsWebhookPath, it.receiveTransition).
+		Build()
+
+	return it.Plugin, nil
+}
+
+// findIssueKeys returns the distinct keys of issues of the enabled projects mentioned in the text, at most defaultItemCount of them
+func (it *issueTracker) findIssueKeys(text string) (keys []string) {
+	keys = make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, match := range issueKeyRegex.FindAllStringSubmatch(text, -1) {
+		if len(it.projects) > 0 && !it.projects[match[1]] {
+			continue
+		}
+
+		if !seen[match[0]] && len(keys) < defaultItemCount {
+			seen[match[0]] = true
+			keys = append(keys, match[0])
+		}
+	}
+
+	return keys
+}
+
+// expandIssues answers with the details of mentioned issues. Keys that aren't issues (i.e. UTF-8) are silently ignored
+func (it *issueTracker) expandIssues(m *slackscot.IncomingMessage) *slackscot.Answer {
+	lines := make([]string, 0)
+
+	for _, key := range it.findIssueKeys(m.NormalizedText) {
+		issue, err := it.tracker.GetIssue(key)
+		if err != nil {
+			it.Logger.Debugf("[%s] Error getting issue [%s]: %v", IssueTrackerPluginName, key, err)
+			continue
+		}
+
+		lines = append(lines, formatIssue(issue))
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return &slackscot.Answer{Text: strings.Join(lines, "\n")}
+}
+
+// createIssue creates an issue and answers with its link
+func (it *issueTracker) createIssue(m *slackscot.IncomingMessage) *slackscot.Answer {
+	matches := it.createRegex.FindStringSubmatch(m.NormalizedText)
+	project, summary := strings.ToUpper(matches[1]), strings.TrimSpace(matches[2])
+
+	issue, err := it.tracker.CreateIssue(project, summary, m.User)
+	if err != nil {
+		it.Logger.Printf("[%s] Error creating issue in [%s]: %v", IssueTrackerPluginName, project, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't create the issue :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Created <%s|%s>: %s :memo:", issue.URL, issue.Key, issue.Summary)}
+}
+
+// receiveTransition posts an issue transition received on the webhook to the channel of its project
+func (it *issueTracker) receiveTransition(w http.ResponseWriter, r *http.Request) {
+	transition, err := it.tracker.ParseTransition(r)
+	if err != nil {
+		it.Logger.Printf("[%s] Error parsing transition: %v", IssueTrackerPluginName, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if transition != nil {
+		project := strings.SplitN(transition.Issue.Key, "-", 2)[0]
+		if channelID, ok := it.transitionChannels[project]; ok {
+			it.RealTimeMsgSender.SendMessage(it.RealTimeMsgSender.NewOutgoingMessage(fmt.Sprintf(":arrows_counterclockwise: <%s|%s> moved from *%s* to *%s*: %s", transition.Issue.URL, transition.Issue.Key, transition.From, transition.To, transition.Issue.Summary), channelID))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// formatIssue renders an issue on a single line
+func formatIssue(issue *Issue) string {
+	assignee := "Unassigned"
+	if issue.Assignee != "" {
+		assignee = issue.Assignee
+	}
+
+	return fmt.Sprintf("<%s|%s> %s · *%s* · %s", issue.URL, issue.Key, issue.Summary, issue.Status, assignee)
+}
@@ -0,0 +1,140 @@
+package plugins_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeIssueTracker struct {
+	issues map[string]plugins.Issue
+}
+
+func (f *fakeIssueTracker) GetIssue(key string) (issue *plugins.Issue, err error) {
+	i, ok := f.issues[key]
+	if !ok {
+		return nil, fmt.Errorf("issue [%s] not found", key)
+	}
+
+	return &i, nil
+}
+
+func (f *fakeIssueTracker) CreateIssue(project string, summary string, reporterID string) (issue *plugins.Issue, err error) {
+	if project == "LOCKED" {
+		return nil, fmt.Errorf("project [%s] is read-only", project)
+	}
+
+	key := fmt.Sprintf("%s-%d", project, len(f.issues)+1)
+	f.issues[key] = plugins.Issue{Key: key, Summary: summary, Status: "To Do", URL: "https://issues.example.com/" + key}
+	i := f.issues[key]
+
+	return &i, nil
+}
+
+func (f *fakeIssueTracker) ParseTransition(r *http.Request) (transition *plugins.IssueTransition, err error) {
+	var event struct {
+		Key  string `json:"key"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&event); err != nil {
+		return nil, err
+	}
+
+	if event.From == event.To {
+		return nil, nil
+	}
+
+	return &plugins.IssueTransition{Issue: f.issues[event.Key], From: event.From, To: event.To}, nil
+}
+
+func newFakeIssueTracker() (f *fakeIssueTracker) {
+	return &fakeIssueTracker{issues: map[string]plugins.Issue{
+		"PROJ-123": {Key: "PROJ-123", Summary: "Login is broken", Status: "In Progress", Assignee: "Alice", URL: "https://issues.example.com/PROJ-123"},
+		"OPS-7":    {Key: "OPS-7", Summary: "Rotate certificates", Status: "To Do", URL: "https://issues.example.com/OPS-7"},
+	}}
+}
+
+func TestIssueTrackerExpandsIssues(t *testing.T) {
+	p, err := plugins.NewIssueTracker(viper.New(), newFakeIssueTracker())
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "Is PROJ-123 related to OPS-7? Also, PROJ-123 again and UTF-8"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "<https://issues.example.com/PROJ-123|PROJ-123> Login is broken · *In Progress* · Alice\n<https://issues.example.com/OPS-7|OPS-7> Rotate certificates · *To Do* · Unassigned")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "Files must be in UTF-8"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Empty(t, answers)
+	})
+}
+
+func TestIssueTrackerExpandsOnlyConfiguredProjects(t *testing.T) {
+	c := viper.New()
+	c.Set("projects", []string{"OPS"})
+
+	p, err := plugins.NewIssueTracker(c, newFakeIssueTracker())
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "Is PROJ-123 related to OPS-7?"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "<https://issues.example.com/OPS-7|OPS-7> Rotate certificates · *To Do* · Unassigned")
+	})
+}
+
+func TestIssueTrackerCreatesIssues(t *testing.T) {
+	c := viper.New()
+	c.Set("command", "issue")
+
+	p, err := plugins.NewIssueTracker(c, newFakeIssueTracker())
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> issue create ops Renew the wildcard certificate"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Created <https://issues.example.com/OPS-3|OPS-3>: Renew the wildcard certificate :memo:")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> issue create locked Anything"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, I couldn't create the issue :disappointed: (project [LOCKED] is read-only)")
+	})
+}
+
+func TestIssueTrackerPostsTransitions(t *testing.T) {
+	c := viper.New()
+	c.Set("transitionChannels", map[string]string{"PROJ": "Cproj"})
+
+	p, err := plugins.NewIssueTracker(c, newFakeIssueTracker())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		body             string
+		expectedCode     int
+		expectedMessages map[string][]string
+	}{
+		{`{"key": "PROJ-123", "from": "In Progress", "to": "Done"}`, http.StatusNoContent, map[string][]string{"Cproj": {":arrows_counterclockwise: <https://issues.example.com/PROJ-123|PROJ-123> moved from *In Progress* to *Done*: Login is broken"}}},
+		{`{"key": "OPS-7", "from": "To Do", "to": "Done"}`, http.StatusNoContent, map[string][]string{}},
+		{`{"key": "PROJ-123", "from": "Done", "to": "Done"}`, http.StatusNoContent, map[string][]string{}},
+		{`not json`, http.StatusBadRequest, map[string][]string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.body, func(t *testing.T) {
+			assertplugin := assertplugin.New(t, "bot")
+			assertplugin.HandlesWebhook(p, plugins.IssueTrackerTransitionsWebhookPath, httptest.NewRequest("POST", "/webhooks/issueTracker/transitions", strings.NewReader(tc.body)), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+				return assert.Equal(t, tc.expectedCode, response.Code) && assert.Equal(t, tc.expectedMessages, sentMsgs)
+			})
+		})
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/leaderboard"
 	"github.com/alexandre-normand/slackscot/plugin"
 	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/theme"
 	"github.com/slack-go/slack"
 	"log"
 	"regexp"
@@ -24,19 +26,63 @@ const (
 	// KarmaPluginName holds identifying name for the karma plugin
 	KarmaPluginName  = "karma"
 	defaultItemCount = 5
+
+	// expandActionID is the action id of the button expanding truncated leaderboards
+	expandActionID = "expand"
 )
 
+const (
+	// KarmaReaderServiceName is the name of the KarmaReader service provided by the karma plugin
+	KarmaReaderServiceName = "karma.reader"
+
+	// KarmaChangedTopic is the topic of the KarmaChanged events published by the karma plugin on the slackscot EventBus
+	KarmaChangedTopic = "karma.changed"
+)
+
+// KarmaChanged is published on the slackscot EventBus every time karma is given or taken away. This allows
+// other plugins to react to karma changes (i.e. celebrating someone reaching 100 karma) without the karma plugin
+// knowing about them
+type KarmaChanged struct {
+	// Channel is the id of the channel where the karma change happened
+	Channel string
+
+	// Thing that had its karma changed. Users are identified by their id prefixed with '@' (i.e. @U21355)
+	Thing string
+
+	// Karma is the new karma of the thing in the channel
+	Karma int
+
+	// Delta is the change in karma (negative when karma is taken away)
+	Delta int
+}
+
+// Topic returns the KarmaChangedTopic
+func (e KarmaChanged) Topic() string {
+	return KarmaChangedTopic
+}
+
+// KarmaReader gives read access to recorded karma. The karma plugin provides an implementation
+// of it to other plugins under the KarmaReaderServiceName service name. Things are identified the same way
+// the karma plugin records them which means that users are identified by their id prefixed with '@' (i.e. @U21355)
+type KarmaReader interface {
+	// GetKarma returns the karma of a thing in a channel. Things without recorded karma have a karma of 0
+	GetKarma(channelID string, thing string) (karma int, err error)
+
+	// GetGlobalKarma returns the karma of a thing over all channels. Things without recorded karma have a karma of 0.
+	// Note that it reads the karma of all channels on every call so it's best kept off hot paths
+	GetGlobalKarma(thing string) (karma int, err error)
+}
+
 var karmaRegex = regexp.MustCompile("(?:\\A|\\W)(?:(<(@[\\w']+)>\\s?))(\\+{2,6}|\\-{2,6}).*")
 
 // Ranker represents attributes and behavior to process a ranking list
 type ranker struct {
-	name             string
-	regexp           *regexp.Regexp
-	bannerText       string
-	bannerImgLink    string
-	bannerImgAltText string
-	scanner          karmaScanner
-	sorter           karmaSorter
+	name     string
+	regexp   *regexp.Regexp
+	title    string
+	worst    bool
+	lister   karmaLister
+	ordering karmaOrdering
 }
 
 var globalTopRanker ranker
@@ -46,28 +92,30 @@ var worstRanker ranker
 
 func init() {
 	globalTopRanker = ranker{name: "global top",
-		regexp:     regexp.MustCompile("(?i)\\A(global top)+(?:\\s+(\\d*))*\\z"),
-		bannerText: ":leaves::leaves::leaves::trophy: *Global Top* :trophy::leaves::leaves::leaves:",
-		scanner:    scanGlobalKarma,
-		sorter:     sortTop}
+		regexp:   regexp.MustCompile("(?i)\\A(global top)+(?:\\s+(\\d*))*\\z"),
+		title:    "*Global Top*",
+		lister:   listGlobalKarma,
+		ordering: topFirst}
 
 	topRanker = ranker{name: "top",
-		regexp:     regexp.MustCompile("(?i)\\A(top)+(?:\\s+(\\d*))*\\z"),
-		bannerText: ":leaves::leaves::leaves::trophy: *Top* :trophy::leaves::leaves::leaves:",
-		scanner:    scanChannelKarma,
-		sorter:     sortTop}
+		regexp:   regexp.MustCompile("(?i)\\A(top)+(?:\\s+(\\d*))*\\z"),
+		title:    "*Top*",
+		lister:   listChannelKarma,
+		ordering: topFirst}
 
 	globalWorstRanker = ranker{name: "global worst",
-		regexp:     regexp.MustCompile("(?i)\\A(global worst)+(?:\\s+(\\d*))*\\z"),
-		bannerText: ":fallen_leaf::fallen_leaf::fallen_leaf::space_invader: *Global Worst* :space_invader::fallen_leaf::fallen_leaf::fallen_leaf:",
-		scanner:    scanGlobalKarma,
-		sorter:     sortWorst}
+		regexp:   regexp.MustCompile("(?i)\\A(global worst)+(?:\\s+(\\d*))*\\z"),
+		title:    "*Global Worst*",
+		worst:    true,
+		lister:   listGlobalKarma,
+		ordering: worstFirst}
 
 	worstRanker = ranker{name: "worst",
-		regexp:     regexp.MustCompile("(?i)\\A(worst)+(?:\\s+(\\d*))*\\z"),
-		bannerText: ":fallen_leaf::fallen_leaf::fallen_leaf::space_invader: *Worst* :space_invader::fallen_leaf::fallen_leaf::fallen_leaf:",
-		scanner:    scanChannelKarma,
-		sorter:     sortWorst}
+		regexp:   regexp.MustCompile("(?i)\\A(worst)+(?:\\s+(\\d*))*\\z"),
+		title:    "*Worst*",
+		worst:    true,
+		lister:   listChannelKarma,
+		ordering: worstFirst}
 }
 
 // NewKarma creates a new instance of the Karma plugin
@@ -81,27 +129,32 @@ func NewKarma(storer store.GlobalSiloStringStorer) (karma *slackscot.Plugin) {
 			WithUsage("top [count]").
 			WithDescriptionf("Return the top things ever recorded in this channel (default of %d items)", defaultItemCount).
 			WithAnswerer(k.answerKarmaTop).
+			WithInteractionHandler(k.newRankListExpander(topRanker)).
 			Build()).
 		WithCommand(actions.NewCommand().
 			WithMatcher(matchKarmaWorstReport).
 			WithUsage("worst [count]").
 			WithDescriptionf("Return the worst things ever recorded in this channel (default of %d items)", defaultItemCount).
 			WithAnswerer(k.answerKarmaWorst).
+			WithInteractionHandler(k.newRankListExpander(worstRanker)).
 			Build()).
 		WithCommand(actions.NewCommand().
 			WithMatcher(matchGlobalKarmaTopReport).
 			WithUsage("global top [count]").
 			WithDescriptionf("Return the top things ever over all channels (default of %d items)", defaultItemCount).
 			WithAnswerer(k.answerGlobalKarmaTop).
+			WithInteractionHandler(k.newRankListExpander(globalTopRanker)).
 			Build()).
 		WithCommand(actions.NewCommand().
 			WithMatcher(matchGlobalKarmaWorstReport).
 			WithUsage("global worst [count]").
 			WithDescriptionf("Return the worst things ever over all channels (default of %d items)", defaultItemCount).
 			WithAnswerer(k.answerGlobalKarmaWorst).
+			WithInteractionHandler(k.newRankListExpander(globalWorstRanker)).
 			Build()).
 		WithCommand(actions.NewCommand().
 			Hidden().
+			AdminOnly().
 			WithMatcher(matchKarmaReset).
 			WithUsage("reset").
 			WithDescription("Resets all recorded karma for the current channel").
@@ -113,6 +166,8 @@ func NewKarma(storer store.GlobalSiloStringStorer) (karma *slackscot.Plugin) {
 			WithDescription("Keep track of karma. Increments larger than `1` (up to `5`) can be achieved with extra `+` or `-` signs").
 			WithAnswerer(k.recordKarma).
 			Build()).
+		WithProvidedService(KarmaReaderServiceName, KarmaReader(k)).
+		WithUserDataCollection(k.purgeUserKarma).
 		Build()
 
 	k.karmaStorer = storer
@@ -167,13 +222,18 @@ func (k *Karma) recordKarma(message *slackscot.IncomingMessage) *slackscot.Answe
 		return &slackscot.Answer{Text: "*Attributing yourself karma is frown upon* :face_with_raised_eyebrow:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(message.User)}}
 	}
 
+	// Users who opted out don't get karma. The UserConsent is only missing when the plugin isn't run by slackscot
+	if k.UserConsent != nil && strings.HasPrefix(thing, "@") && k.UserConsent.HasOptedOut(strings.TrimPrefix(thing, "@")) {
+		return &slackscot.Answer{Text: "Sorry, they opted out of karma :shushing_face:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(message.User)}}
+	}
+
 	rawValue, err := k.karmaStorer.GetSiloString(message.Channel, thing)
 	if err != nil {
 		rawValue = "0"
 	}
 	karma, err := strconv.Atoi(rawValue)
 	if err != nil {
-		k.Logger.Printf("[%s] Error parsing current karma value [%s], something's wrong and resetting to 0: %v", KarmaPluginName, rawValue, err)
+		k.quarantineKarma(message.Channel, thing, err)
 		karma = 0
 	}
 
@@ -181,11 +241,13 @@ func (k *Karma) recordKarma(message *slackscot.IncomingMessage) *slackscot.Answe
 	answerText := ""
 	renderedThing := k.renderThing(thing)
 
+	delta := 0
 	instruction := match[3]
 	if strings.HasPrefix(instruction, "+") {
 		incrementSymbols := strings.TrimPrefix(instruction, "+")
 		increment := len(incrementSymbols)
 		karma = karma + increment
+		delta = increment
 
 		if increment == 1 {
 			answerText = fmt.Sprintf("`%s` just gained karma (`%s`: %d)", renderedThing, renderedThing, karma)
@@ -197,6 +259,7 @@ func (k *Karma) recordKarma(message *slackscot.IncomingMessage) *slackscot.Answe
 		decrementSymbols := strings.TrimPrefix(instruction, "-")
 		decrement := len(decrementSymbols)
 		karma = karma - decrement
+		delta = -decrement
 
 		if decrement == 1 {
 			answerText = fmt.Sprintf("`%s` just lost karma (`%s`: %d)", renderedThing, renderedThing, karma)
@@ -212,9 +275,46 @@ func (k *Karma) recordKarma(message *slackscot.IncomingMessage) *slackscot.Answe
 		return nil
 	}
 
+	// Let other plugins know about the change. The EventBus is only missing when the plugin isn't run by slackscot
+	if k.EventBus != nil {
+		k.EventBus.Publish(KarmaChanged{Channel: message.Channel, Thing: thing, Karma: karma, Delta: delta})
+	}
+
 	return &slackscot.Answer{Text: answerText}
 }
 
+// purgeUserKarma deletes the karma of a user in all channels
+func (k *Karma) purgeUserKarma(userID string) (err error) {
+	entriesByChannel, err := k.karmaStorer.GlobalScan()
+	if err != nil {
+		return err
+	}
+
+	thing := "@" + userID
+	for channelID, entries := range entriesByChannel {
+		if _, ok := entries[thing]; !ok {
+			continue
+		}
+
+		if err = k.karmaStorer.DeleteSiloString(channelID, thing); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quarantineKarma moves a corrupted karma value out of the way (when run by slackscot) so that it starts over from 0
+func (k *Karma) quarantineKarma(channelID string, thing string, err error) {
+	// The Quarantiner is only missing when the plugin isn't run by slackscot
+	if k.Quarantiner == nil {
+		k.Logger.Printf("[%s] Error parsing current karma value of [%s], something's wrong and resetting to 0: %v", KarmaPluginName, thing, err)
+		return
+	}
+
+	k.Quarantiner.Quarantine(k.karmaStorer, channelID, thing, err)
+}
+
 // renderThing renders the thing value. In most cases, it should just return the value
 // untouched but if it starts with '@', it tries to find the user info matching the value
 // and returns that instead (if found a match)
@@ -268,27 +368,66 @@ func (k *Karma) clearChannelKarma(m *slackscot.IncomingMessage) *slackscot.Answe
 	return &slackscot.Answer{Text: "karma all cleared :white_check_mark::boom:"}
 }
 
-// karmaSorter is a function sorting pairList of karma entries. Used to plug in top/worst sorting
-type karmaSorter func(pl pairList)
+// GetKarma returns the karma of a thing in a channel. Like when recording karma, a missing value is a karma of 0 but
+// failures to get it are returned as errors
+func (k *Karma) GetKarma(channelID string, thing string) (karma int, err error) {
+	rawValue, err := k.karmaStorer.GetSiloString(channelID, thing)
+	if store.IsNotFound(err) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if karma, err = strconv.Atoi(rawValue); err != nil && k.Quarantiner != nil {
+		k.quarantineKarma(channelID, thing, err)
+		return 0, nil
+	}
 
-// sortWorst sorts karma from the lowest value to the highest
-func sortWorst(pl pairList) {
-	sort.Sort(pl)
+	return karma, err
 }
 
-// sortWorst sorts karma from the highest to lowest
-func sortTop(pl pairList) {
-	sort.Sort(sort.Reverse(pl))
+// GetGlobalKarma returns the karma of a thing merged over all channels. Since karma is stored by channel, this scans
+// all recorded karma (see scanGlobalKarma) on every call so it's meant for occasional lookups, not hot paths
+func (k *Karma) GetGlobalKarma(thing string) (karma int, err error) {
+	entries, err := scanGlobalKarma(k.karmaStorer, "")
+	if err != nil {
+		return 0, err
+	}
+
+	if rawValue, ok := entries[thing]; ok {
+		return strconv.Atoi(rawValue)
+	}
+
+	return 0, nil
 }
 
-// karmaScanner is a function that returns karma entries for a given channel. It is used
-// to plug in different behaviors like channel scanning and global scanning
-type karmaScanner func(karmaStorer store.GlobalSiloStringStorer, channelID string) (entries map[string]string, err error)
+// karmaOrdering is a function returning true if karma entry a ranks before b. Used to plug in top/worst ranking
+type karmaOrdering func(a pair, b pair) bool
+
+// worstFirst ranks karma from the lowest value to the highest
+func worstFirst(a pair, b pair) bool {
+	return pairList{a, b}.Less(0, 1)
+}
 
-// scanChannelKarma scans the silo for the given channel id and returns only the entries for that
-// channel
-func scanChannelKarma(karmaStorer store.GlobalSiloStringStorer, channelID string) (entries map[string]string, err error) {
-	return karmaStorer.ScanSilo(channelID)
+// topFirst ranks karma from the highest value to the lowest
+func topFirst(a pair, b pair) bool {
+	return pairList{b, a}.Less(0, 1)
+}
+
+// karmaLister is a function that returns the first count karma entries for a given channel in the order
+// of the ranking. It is used to plug in different behaviors like channel ranking and global ranking
+type karmaLister func(karmaStorer store.GlobalSiloStringStorer, channelID string, count int, ordering karmaOrdering) (pairs pairList, err error)
+
+// listChannelKarma scans the silo for the given channel id and ranks only the entries for that channel
+func listChannelKarma(karmaStorer store.GlobalSiloStringStorer, channelID string, count int, ordering karmaOrdering) (pairs pairList, err error) {
+	entries, err := karmaStorer.ScanSilo(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return getRankedList(entries, count, ordering)
 }
 
 // scanGlobalKarma invokes a GlobalScan and merges karma over all channels. If there's
@@ -342,45 +481,77 @@ func (k *Karma) answerKarmaRankList(m *slackscot.IncomingMessage, ranker ranker)
 		count, _ = strconv.Atoi(rawCount)
 	}
 
-	values, err := ranker.scanner(k.karmaStorer, m.Channel)
-	if err != nil {
-		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't get the %s [%d] things for you. If you must know, this happened: %v", ranker.name, count, err)}
-	}
-
-	pairs, err := getRankedList(values, count, ranker.sorter)
+	pairs, err := ranker.lister(k.karmaStorer, m.Channel, count, ranker.ordering)
 	if err != nil {
 		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't get the %s [%d] things for you. If you must know, this happened: %v", ranker.name, count, err)}
 	}
 
-	if len(pairs) > 0 {
-		blocks := make([]slack.Block, 0)
+	entries := pairs.entries()
+	r := k.newLeaderboard(ranker, renderThingName)
 
-		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", ranker.bannerText, false, false), nil, nil))
-		blocks = append(blocks, k.formatList(pairs)...)
+	blocks := r.Render(ranker.title, entries)
+	if len(blocks) == 0 {
+		return &slackscot.Answer{Text: "Sorry, no recorded karma found :disappointed:"}
+	}
 
-		return &slackscot.Answer{Text: "", ContentBlocks: blocks}
+	answer := &slackscot.Answer{Text: "", ContentBlocks: blocks}
+	if r.Truncated(entries) > 0 {
+		answer.InteractiveElements = []slack.BlockElement{slack.NewButtonBlockElement(expandActionID, strconv.Itoa(len(entries)), slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Show all %d", len(entries)), false, false))}
 	}
 
-	return &slackscot.Answer{Text: "Sorry, no recorded karma found :disappointed:"}
+	return answer
 }
 
-// formatList formats a list of ranked items using the rankRenderer to render the rank icons and returns the resulting block kit blocks
-func (k *Karma) formatList(pl pairList) (blocks []slack.Block) {
-	blocks = make([]slack.Block, 0)
+// newRankListExpander returns the handler of the button of truncated leaderboards. It uploads the full leaderboard
+// as a file in the thread of the truncated one
+func (k *Karma) newRankListExpander(ranker ranker) slackscot.InteractionHandler {
+	return func(i *slackscot.Interaction) *slack.ViewSubmissionResponse {
+		if len(i.ActionCallback.BlockActions) == 0 || i.ActionCallback.BlockActions[0].ActionID != expandActionID {
+			return nil
+		}
 
-	rank := 1
-	for _, pair := range pl {
-		blocks = append(blocks, formatRankedElement(pair, rank))
-		rank = rank + 1
-	}
+		count, err := strconv.Atoi(i.ActionCallback.BlockActions[0].Value)
+		if err != nil {
+			k.Logger.Printf("[%s] Invalid count [%s] to expand %s leaderboard: %v", KarmaPluginName, i.ActionCallback.BlockActions[0].Value, ranker.name, err)
+			return nil
+		}
+
+		pairs, err := ranker.lister(k.karmaStorer, i.Channel.ID, count, ranker.ordering)
+		if err != nil {
+			k.Logger.Printf("[%s] Error getting the %s [%d] things to expand leaderboard: %v", KarmaPluginName, ranker.name, count, err)
+			return nil
+		}
+
+		threadTS := i.Message.ThreadTimestamp
+		if threadTS == "" {
+			threadTS = i.Message.Timestamp
+		}
 
-	return blocks
+		// Mentions aren't rendered in files so users are named instead
+		content := k.newLeaderboard(ranker, newUserNameFormatter(k.findUserNames(pairs))).RenderText(ranker.title, pairs.entries())
+		if _, err = k.FileUploader.UploadFile(slack.FileUploadParameters{Content: content, Filetype: "text", Filename: strings.Replace(ranker.name, " ", "-", -1) + ".txt", Title: fmt.Sprintf("Karma %s %d", ranker.name, count), Channels: []string{i.Channel.ID}, ThreadTimestamp: threadTS}); err != nil {
+			k.Logger.Printf("[%s] Error uploading expanded %s leaderboard: %v", KarmaPluginName, ranker.name, err)
+		}
+
+		return nil
+	}
 }
 
-// formatRankedElement formats one ranked element in a list. It adds 3 blocks: one for the rank (icon),
-// one for the ranked "thing" and one for its karma value. The 3 block objects are then wrapped in a context block
-func formatRankedElement(p pair, rank int) (block slack.Block) {
-	return *slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("• %s `%d`", renderThingName(p.Key), p.Value), false, false), nil, nil)
+// newLeaderboard returns the leaderboard renderer of the ranker with the injected theme (or the default one, if none)
+// rendering things with the name formatter. Banner images are resolved with the injected assets so that themes can use
+// images served by slackscot itself. One block is left for the button expanding truncated leaderboards
+func (k *Karma) newLeaderboard(ranker ranker, nameFormatter func(thing string) string) (r *leaderboard.Renderer) {
+	t := theme.Default
+	if k.Theme != nil {
+		t = *k.Theme
+	}
+
+	lt := t.TopLeaderboard
+	if ranker.worst {
+		lt = t.WorstLeaderboard
+	}
+
+	return leaderboard.New(lt, leaderboard.OptionNameFormatter(nameFormatter), leaderboard.OptionImageResolver(k.Assets.Resolve), leaderboard.OptionMaxBlocks(leaderboard.MaxBlocks-1))
 }
 
 // renderThingName renders a karma item by formatting a user id with the required symbols such that it looks
@@ -393,6 +564,46 @@ func renderThingName(thing string) (render string) {
 	return thing
 }
 
+// findUserNames returns the real names of the users among the things of the pairs, by user id. Users are looked up at
+// once with the UsersInfoFinder rather than one by one. Users that can't be found are left out
+func (k *Karma) findUserNames(pairs pairList) (names map[string]string) {
+	names = make(map[string]string)
+
+	userIDs := make([]string, 0)
+	for _, p := range pairs {
+		if strings.HasPrefix(p.Key, "@") {
+			userIDs = append(userIDs, strings.TrimPrefix(p.Key, "@"))
+		}
+	}
+
+	if len(userIDs) == 0 || k.UsersInfoFinder == nil {
+		return names
+	}
+
+	users, err := k.UsersInfoFinder.GetUsersInfo(userIDs)
+	if err != nil {
+		k.Logger.Debugf("[%s] Error getting the info of users to name in leaderboard: %v", KarmaPluginName, err)
+	}
+
+	for _, u := range users {
+		names[u.ID] = u.RealName
+	}
+
+	return names
+}
+
+// newUserNameFormatter returns a name formatter rendering users with their name (i.e. @Alphonse) when found in names
+// and other things like renderThingName
+func newUserNameFormatter(names map[string]string) func(thing string) string {
+	return func(thing string) string {
+		if name, ok := names[strings.TrimPrefix(thing, "@")]; ok && strings.HasPrefix(thing, "@") {
+			return "@" + name
+		}
+
+		return renderThingName(thing)
+	}
+}
+
 // pair holds a key (thing name) and its count
 type pair struct {
 	Key   string
@@ -410,6 +621,16 @@ func (p pairList) Less(i, j int) bool {
 
 func (p pairList) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
 
+// entries returns the pairs as leaderboard entries
+func (p pairList) entries() (entries []leaderboard.Entry) {
+	entries = make([]leaderboard.Entry, len(p))
+	for i, pair := range p {
+		entries[i] = leaderboard.Entry{Name: pair.Key, Value: pair.Value}
+	}
+
+	return entries
+}
+
 func convertToPairs(wordFrequencies map[string]int) pairList {
 	pl := make(pairList, len(wordFrequencies))
 	i := 0
@@ -421,7 +642,7 @@ func convertToPairs(wordFrequencies map[string]int) pairList {
 	return pl
 }
 
-func getRankedList(rawData map[string]string, count int, sort karmaSorter) (results pairList, err error) {
+func getRankedList(rawData map[string]string, count int, ordering karmaOrdering) (results pairList, err error) {
 	wordWithFrequencies, err := convertMapValues(rawData)
 	if err != nil {
 		return results, err
@@ -429,7 +650,7 @@ func getRankedList(rawData map[string]string, count int, sort karmaSorter) (resu
 
 	pl := convertToPairs(wordWithFrequencies)
 
-	sort(pl)
+	sort.Slice(pl, func(i, j int) bool { return ordering(pl[i], pl[j]) })
 
 	limit := count
 
@@ -4,16 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/assets"
+	"github.com/alexandre-normand/slackscot/leaderboard"
 	"github.com/alexandre-normand/slackscot/plugins"
 	"github.com/alexandre-normand/slackscot/store"
 	"github.com/alexandre-normand/slackscot/store/mocks"
 	"github.com/alexandre-normand/slackscot/test/assertanswer"
 	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/alexandre-normand/slackscot/test/capture"
+	"github.com/alexandre-normand/slackscot/theme"
 	"github.com/slack-go/slack"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -133,6 +139,42 @@ func TestInvalidStoredKarmaShouldResetValue(t *testing.T) {
 	})
 }
 
+type quarantinedKey struct {
+	silo string
+	key  string
+}
+
+type quarantiner struct {
+	quarantined []quarantinedKey
+}
+
+func (q *quarantiner) Quarantine(storer store.SiloStringStorer, silo string, key string, reason error) (err error) {
+	q.quarantined = append(q.quarantined, quarantinedKey{silo: silo, key: key})
+	return nil
+}
+
+func TestInvalidStoredKarmaQuarantined(t *testing.T) {
+	mockStorer := &mocks.Storer{}
+	defer mockStorer.AssertExpectations(t)
+
+	mockStorer.On("GetSiloString", "myLittleChannel", "@U21355").Return("abc", nil)
+	mockStorer.On("PutSiloString", "myLittleChannel", "@U21355", "1").Return(nil)
+
+	var userInfoFinder userInfoFinder
+	var q quarantiner
+	p := plugins.NewKarma(mockStorer)
+	p.UserInfoFinder = userInfoFinder
+	p.Quarantiner = &q
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "myLittleChannel", Text: "<@U21355>++"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "`Bernard Tremblay` just gained karma (`Bernard Tremblay`: 1)")
+	})
+
+	assert.Equal(t, []quarantinedKey{{silo: "myLittleChannel", key: "@U21355"}}, q.quarantined)
+}
+
 func TestErrorGettingList(t *testing.T) {
 	mockStorer := &mocks.Storer{}
 	defer mockStorer.AssertExpectations(t)
@@ -319,6 +361,149 @@ func TestTopFormatting(t *testing.T) {
 	})
 }
 
+func TestWorstFormattingWithCorporatePlainTheme(t *testing.T) {
+	mockStorer := &mocks.Storer{}
+	defer mockStorer.AssertExpectations(t)
+
+	mockStorer.On("ScanSilo", "myLittleChannel").Return(map[string]string{"thing": "-10", "@someone": "3", "birds": "9"}, nil)
+
+	var userInfoFinder userInfoFinder
+	p := plugins.NewKarma(mockStorer)
+	p.UserInfoFinder = userInfoFinder
+	p.Theme = &theme.CorporatePlain
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "myLittleChannel", Text: "<@bot> worst 2"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		require.Len(t, answers, 1)
+
+		render, err := json.Marshal(answers[0].ContentBlocks)
+		require.NoError(t, err)
+
+		return assert.Equal(t, "[{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"*Worst*\"}},{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"1. thing `-10`\"}},{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"2. \\u003c@someone\\u003e `3`\"}}]", string(render))
+	})
+}
+
+func TestTopFormattingWithTrophyCaseThemeAndSelfHostedAssets(t *testing.T) {
+	mockStorer := &mocks.Storer{}
+	defer mockStorer.AssertExpectations(t)
+
+	mockStorer.On("ScanSilo", "myLittleChannel").Return(map[string]string{"birds": "9"}, nil)
+
+	var userInfoFinder userInfoFinder
+	p := plugins.NewKarma(mockStorer)
+	p.UserInfoFinder = userInfoFinder
+	p.Theme = &theme.TrophyCase
+	p.Assets = assets.NewRegistry("https://slackscot.example.com")
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "myLittleChannel", Text: "<@bot> top 1"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		require.Len(t, answers, 1)
+
+		render, err := json.Marshal(answers[0].ContentBlocks)
+		require.NoError(t, err)
+
+		return assert.Equal(t, "[{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\":leaves::leaves::leaves::trophy: *Top* :trophy::leaves::leaves::leaves:\"},\"accessory\":{\"type\":\"image\",\"image_url\":\"https://slackscot.example.com/assets/trophy\",\"alt_text\":\"trophy\"}},{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"• birds `9`\"}}]", string(render))
+	})
+}
+
+func TestTopListingTruncatedToSlackBlockLimitAndExpandedAsFile(t *testing.T) {
+	mockStorer := &mocks.Storer{}
+	defer mockStorer.AssertExpectations(t)
+
+	things := make(map[string]string)
+	for i := 1; i <= 60; i++ {
+		things[fmt.Sprintf("thing%02d", i)] = strconv.Itoa(i)
+	}
+	mockStorer.On("ScanSilo", "myLittleChannel").Return(things, nil).Twice()
+
+	var userInfoFinder userInfoFinder
+	p := plugins.NewKarma(mockStorer)
+	p.UserInfoFinder = userInfoFinder
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "myLittleChannel", Text: "<@bot> top 100"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		require.Len(t, answers, 1)
+		require.Len(t, answers[0].ContentBlocks, leaderboard.MaxBlocks-1)
+
+		more, err := json.Marshal(answers[0].ContentBlocks[len(answers[0].ContentBlocks)-1])
+		require.NoError(t, err)
+
+		button, err := json.Marshal(answers[0].InteractiveElements)
+		require.NoError(t, err)
+
+		return assert.Equal(t, "{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"_and 13 more…_\"}}", string(more)) &&
+			assert.Equal(t, "[{\"type\":\"button\",\"text\":{\"type\":\"plain_text\",\"text\":\"Show all 60\"},\"action_id\":\"expand\",\"value\":\"60\"}]", string(button))
+	})
+
+	uploads := capture.NewFileUploader()
+	p.FileUploader = slackscot.NewFileUploader(uploads)
+
+	require.NotNil(t, p.Commands[0].InteractionHandler)
+	interaction := &slackscot.Interaction{}
+	interaction.Channel.ID = "myLittleChannel"
+	interaction.Message.Timestamp = "1546833210.036900"
+	interaction.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: "expand", Value: "60"}}
+	assert.Nil(t, p.Commands[0].InteractionHandler(interaction))
+
+	if assert.Len(t, uploads.FileUploads, 1) {
+		assert.Equal(t, []string{"myLittleChannel"}, uploads.FileUploads[0].Channels)
+		assert.Equal(t, "1546833210.036900", uploads.FileUploads[0].ThreadTimestamp)
+		assert.Equal(t, "top.txt", uploads.FileUploads[0].Filename)
+
+		lines := strings.Split(strings.TrimSuffix(uploads.FileUploads[0].Content, "\n"), "\n")
+		if assert.Len(t, lines, 61) {
+			assert.Equal(t, "• thing60 `60`", lines[1])
+			assert.Equal(t, "• thing01 `1`", lines[60])
+		}
+	}
+}
+
+// usersInfoFinder finds the users named in its users and counts the calls to find them
+type usersInfoFinder struct {
+	users map[string]string
+	calls int
+}
+
+func (u *usersInfoFinder) GetUsersInfo(userIDs []string) (users []slack.User, err error) {
+	u.calls++
+	for _, userID := range userIDs {
+		if name, ok := u.users[userID]; ok {
+			users = append(users, slack.User{ID: userID, RealName: name})
+		}
+	}
+
+	return users, nil
+}
+
+func TestExpandedListingNamesUsersWithSingleLookup(t *testing.T) {
+	mockStorer := &mocks.Storer{}
+	defer mockStorer.AssertExpectations(t)
+
+	mockStorer.On("ScanSilo", "myLittleChannel").Return(map[string]string{"@U1": "3", "@U2": "2", "@U3": "1", "birds": "4"}, nil)
+
+	finder := &usersInfoFinder{users: map[string]string{"U1": "Alphonse", "U2": "Bernard"}}
+	p := plugins.NewKarma(mockStorer)
+	p.UsersInfoFinder = finder
+
+	uploads := capture.NewFileUploader()
+	p.FileUploader = slackscot.NewFileUploader(uploads)
+
+	interaction := &slackscot.Interaction{}
+	interaction.Channel.ID = "myLittleChannel"
+	interaction.Message.Timestamp = "1546833210.036900"
+	interaction.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: "expand", Value: "4"}}
+	assert.Nil(t, p.Commands[0].InteractionHandler(interaction))
+
+	assert.Equal(t, 1, finder.calls)
+	if assert.Len(t, uploads.FileUploads, 1) {
+		lines := strings.Split(strings.TrimSuffix(uploads.FileUploads[0].Content, "\n"), "\n")
+		assert.Equal(t, []string{"• birds `4`", "• @Alphonse `3`", "• @Bernard `2`", "• <@U3> `1`"}, lines[1:])
+	}
+}
+
 func TestTopListingWithoutRequestedCount(t *testing.T) {
 	mockStorer := &mocks.Storer{}
 	defer mockStorer.AssertExpectations(t)
@@ -472,3 +657,143 @@ func TestLessItemsThanRequestedWorstCount(t *testing.T) {
 		return assertanswer.HasText(t, answers[0], "") && assert.Equal(t, "[{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\":fallen_leaf::fallen_leaf::fallen_leaf::space_invader: *Worst* :space_invader::fallen_leaf::fallen_leaf::fallen_leaf:\"}},{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"• thing `1`\"}},{\"type\":\"section\",\"text\":{\"type\":\"mrkdwn\",\"text\":\"• bird `2`\"}}]", string(render))
 	})
 }
+
+func TestKarmaReaderService(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("karmaTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	require.NoError(t, storer.PutSiloString("Cgeneral", "@U21355", "3"))
+	require.NoError(t, storer.PutSiloString("Coceanlife", "@U21355", "4"))
+
+	p := plugins.NewKarma(storer)
+
+	service, ok := p.Provides[plugins.KarmaReaderServiceName]
+	require.True(t, ok)
+
+	reader, ok := service.(plugins.KarmaReader)
+	require.True(t, ok)
+
+	karma, err := reader.GetKarma("Cgeneral", "@U21355")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, karma)
+	}
+
+	karma, err = reader.GetKarma("Cother", "@U21355")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, karma)
+	}
+
+	karma, err = reader.GetGlobalKarma("@U21355")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 7, karma)
+	}
+
+	karma, err = reader.GetGlobalKarma("@U9999")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, karma)
+	}
+}
+
+func TestKarmaReaderServiceReturnsStorerErrors(t *testing.T) {
+	mockStorer := &mocks.Storer{}
+	defer mockStorer.AssertExpectations(t)
+
+	mockStorer.On("GetSiloString", "Cgeneral", "@U21355").Return("", fmt.Errorf("connection refused"))
+
+	p := plugins.NewKarma(mockStorer)
+	reader := p.Provides[plugins.KarmaReaderServiceName].(plugins.KarmaReader)
+
+	_, err := reader.GetKarma("Cgeneral", "@U21355")
+	assert.EqualError(t, err, "connection refused")
+}
+
+type eventCaptor struct {
+	events []slackscot.Event
+}
+
+func (c *eventCaptor) Publish(e slackscot.Event) {
+	c.events = append(c.events, e)
+}
+
+func (c *eventCaptor) Subscribe(topic string, handler slackscot.EventHandler) {
+}
+
+func TestKarmaChangesArePublished(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("karmaTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	var userInfoFinder userInfoFinder
+	captor := new(eventCaptor)
+	p := plugins.NewKarma(storer)
+	p.UserInfoFinder = userInfoFinder
+	p.EventBus = captor
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: "<@U21355>+++"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: "<@U21355>--"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+
+	assert.Equal(t, []slackscot.Event{
+		plugins.KarmaChanged{Channel: "Cgeneral", Thing: "@U21355", Karma: 2, Delta: 2},
+		plugins.KarmaChanged{Channel: "Cgeneral", Thing: "@U21355", Karma: 1, Delta: -1},
+	}, captor.events)
+	assert.Equal(t, plugins.KarmaChangedTopic, captor.events[0].Topic())
+}
+
+type optedOutUsers map[string]bool
+
+func (o optedOutUsers) HasOptedOut(userID string) bool {
+	return o[userID]
+}
+
+func TestKarmaOfUsersWhoOptedOut(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("karmaTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	optedOut := optedOutUsers{}
+	p := plugins.NewKarma(storer)
+	p.UserInfoFinder = userInfoFinder{}
+	p.UserConsent = optedOut
+	require.True(t, p.CollectsUserData)
+
+	assertplugin := assertplugin.New(t, "bot")
+	for _, channelID := range []string{"Cgeneral", "Coceanlife"} {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{User: "Ualphonse", Channel: channelID, Text: "<@U21355>++ and <@U21356>++"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "`Bernard Tremblay` just gained karma (`Bernard Tremblay`: 1)")
+		})
+	}
+
+	require.NoError(t, storer.PutSiloString("Cgeneral", "@U21356", "3"))
+	optedOut["U21355"] = true
+	require.NoError(t, p.PurgeUserData("U21355"))
+
+	entries, err := storer.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"Cgeneral": {"@U21356": "3"}}, entries)
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{User: "Ualphonse", Channel: "Cgeneral", Text: "<@U21355>++"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, they opted out of karma :shushing_face:") && assertanswer.HasOptions(t, answers[0], assertanswer.ResolvedAnswerOption{Key: slackscot.EphemeralAnswerToOpt, Value: "Ualphonse"})
+	})
+
+	entries, err = storer.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"Cgeneral": {"@U21356": "3"}}, entries)
+}
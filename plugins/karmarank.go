@@ -0,0 +1,222 @@
+package plugins
+
+import (
+	"container/heap"
+	"github.com/alexandre-normand/slackscot/store"
+	"hash/fnv"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+const (
+	// minKarmaEntriesPerWorker is the minimum number of karma entries to justify an additional worker when ranking
+	// karma over all channels. Smaller data sets are ranked with fewer workers (down to a single one)
+	minKarmaEntriesPerWorker = 5000
+)
+
+// maxKarmaRankingWorkers bounds the number of goroutines ranking karma over all channels
+var maxKarmaRankingWorkers = runtime.GOMAXPROCS(0)
+
+// listGlobalKarma invokes a GlobalScan and ranks karma merged over all channels. The GlobalScan itself is a single
+// serial read of the storer since its interface doesn't allow scanning channels independently: only the parsing and
+// merging of the scanned karma is done in parallel (see rankGlobalKarma)
+func listGlobalKarma(karmaStorer store.GlobalSiloStringStorer, channelID string, count int, ordering karmaOrdering) (pairs pairList, err error) {
+	entriesByChannel, err := karmaStorer.GlobalScan()
+	if err != nil {
+		return nil, err
+	}
+
+	return rankGlobalKarma(entriesByChannel, count, ordering, maxKarmaRankingWorkers)
+}
+
+// rankGlobalKarma merges karma over all channels and returns the first count entries in the order of the ranking.
+// This is done in parallel by a bounded pool of workers in two phases:
+//
+//  1. Channels are split between workers which parse karma values and sum them in partial totals sharded by thing
+//  2. Each shard (with all the karma of its things) is merged by a worker that keeps a partial top-count heap
+//
+// The partial ranked lists of all shards are then merge-sorted into the final list
+func rankGlobalKarma(entriesByChannel map[string]map[string]string, count int, ordering karmaOrdering, maxWorkers int) (pairs pairList, err error) {
+	if count <= 0 {
+		return pairList{}, nil
+	}
+
+	workers := karmaRankingWorkers(entriesByChannel, maxWorkers)
+
+	// Phase 1: sum karma of channels in partial totals, sharded by thing
+	channels := make(chan map[string]string)
+	partials := make([][]map[string]int, workers)
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		partials[w] = make([]map[string]int, workers)
+		for shard := range partials[w] {
+			partials[w][shard] = make(map[string]int)
+		}
+
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for entries := range channels {
+				if errs[w] != nil {
+					continue
+				}
+
+				for thing, rawValue := range entries {
+					value, err := strconv.Atoi(rawValue)
+					if err != nil {
+						errs[w] = err
+						break
+					}
+
+					partials[w][karmaShard(thing, workers)][thing] += value
+				}
+			}
+		}(w)
+	}
+
+	for _, entries := range entriesByChannel {
+		channels <- entries
+	}
+	close(channels)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Phase 2: merge the partial totals of each shard and keep its top entries
+	ranked := make([]pairList, workers)
+	for shard := 0; shard < workers; shard++ {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+
+			totals := make(map[string]int)
+			for w := range partials {
+				for thing, value := range partials[w][shard] {
+					totals[thing] += value
+				}
+			}
+
+			ranked[shard] = topKarma(totals, count, ordering)
+		}(shard)
+	}
+	wg.Wait()
+
+	return mergeRankedKarma(ranked, count, ordering), nil
+}
+
+// karmaRankingWorkers returns the number of workers to use to rank the entries
+func karmaRankingWorkers(entriesByChannel map[string]map[string]string, maxWorkers int) (workers int) {
+	total := 0
+	for _, entries := range entriesByChannel {
+		total += len(entries)
+	}
+
+	workers = 1 + total/minKarmaEntriesPerWorker
+	if workers > maxWorkers {
+		workers = maxWorkers
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	return workers
+}
+
+// karmaShard returns the shard of a thing
+func karmaShard(thing string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(thing))
+
+	return int(h.Sum32() % uint32(shards))
+}
+
+// topKarma returns the first count entries of the totals in the order of the ranking using a bounded heap whose root
+// is the last of the entries kept
+func topKarma(totals map[string]int, count int, ordering karmaOrdering) (pairs pairList) {
+	// The count comes from users so it's bounded by the number of entries before sizing anything with it
+	if count > len(totals) {
+		count = len(totals)
+	}
+
+	h := &karmaHeap{pairs: make(pairList, 0, count), ordering: ordering}
+	for thing, value := range totals {
+		p := pair{thing, value}
+		if h.Len() < count {
+			heap.Push(h, p)
+		} else if ordering(p, h.pairs[0]) {
+			h.pairs[0] = p
+			heap.Fix(h, 0)
+		}
+	}
+
+	pairs = make(pairList, h.Len())
+	for i := len(pairs) - 1; i >= 0; i-- {
+		pairs[i] = heap.Pop(h).(pair)
+	}
+
+	return pairs
+}
+
+// mergeRankedKarma merge-sorts ranked lists of karma and returns the first count entries
+func mergeRankedKarma(ranked []pairList, count int, ordering karmaOrdering) (pairs pairList) {
+	total := 0
+	for _, pl := range ranked {
+		total += len(pl)
+	}
+
+	// The count comes from users so it's bounded by the number of ranked entries before sizing anything with it
+	if count > total {
+		count = total
+	}
+
+	pairs = make(pairList, 0, count)
+	next := make([]int, len(ranked))
+
+	for len(pairs) < count {
+		first := -1
+		for i, pl := range ranked {
+			if next[i] < len(pl) && (first == -1 || ordering(pl[next[i]], ranked[first][next[first]])) {
+				first = i
+			}
+		}
+
+		if first == -1 {
+			break
+		}
+
+		pairs = append(pairs, ranked[first][next[first]])
+		next[first]++
+	}
+
+	return pairs
+}
+
+// karmaHeap is a heap of karma entries whose root is the one ranking last
+type karmaHeap struct {
+	pairs    pairList
+	ordering karmaOrdering
+}
+
+func (h karmaHeap) Len() int { return len(h.pairs) }
+
+func (h karmaHeap) Less(i, j int) bool { return h.ordering(h.pairs[j], h.pairs[i]) }
+
+func (h karmaHeap) Swap(i, j int) { h.pairs[i], h.pairs[j] = h.pairs[j], h.pairs[i] }
+
+func (h *karmaHeap) Push(x interface{}) { h.pairs = append(h.pairs, x.(pair)) }
+
+func (h *karmaHeap) Pop() interface{} {
+	last := h.pairs[len(h.pairs)-1]
+	h.pairs = h.pairs[:len(h.pairs)-1]
+
+	return last
+}
@@ -0,0 +1,123 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func newRandomKarma(channelCount int, thingCount int, entriesPerChannel int) (entriesByChannel map[string]map[string]string) {
+	random := rand.New(rand.NewSource(1))
+
+	entriesByChannel = make(map[string]map[string]string)
+	for c := 0; c < channelCount; c++ {
+		entries := make(map[string]string)
+		for i := 0; i < entriesPerChannel; i++ {
+			entries[fmt.Sprintf("thing%d", random.Intn(thingCount))] = strconv.Itoa(random.Intn(200) - 100)
+		}
+
+		entriesByChannel[fmt.Sprintf("C%d", c)] = entries
+	}
+
+	return entriesByChannel
+}
+
+func TestRankGlobalKarmaMatchesSequentialRanking(t *testing.T) {
+	entriesByChannel := newRandomKarma(300, 20000, 100)
+
+	merged, err := scanGlobalKarma(&globalScanner{entriesByChannel}, "")
+	require.NoError(t, err)
+
+	for _, ordering := range []karmaOrdering{topFirst, worstFirst} {
+		for _, count := range []int{1, 5, 100} {
+			expected, err := getRankedList(merged, count, ordering)
+			require.NoError(t, err)
+
+			for _, workers := range []int{1, 3, 8} {
+				pairs, err := rankGlobalKarma(entriesByChannel, count, ordering, workers)
+				require.NoError(t, err)
+				assert.Equal(t, expected, pairs, "count [%d] with [%d] workers", count, workers)
+			}
+		}
+	}
+}
+
+func TestRankGlobalKarmaWithFewerThingsThanCount(t *testing.T) {
+	pairs, err := rankGlobalKarma(map[string]map[string]string{"C1": {"thing": "1", "bird": "3"}, "C2": {"thing": "4"}}, 5, topFirst, 4)
+	require.NoError(t, err)
+	assert.Equal(t, pairList{{"thing", 5}, {"bird", 3}}, pairs)
+
+	pairs, err = rankGlobalKarma(map[string]map[string]string{}, 5, topFirst, 4)
+	require.NoError(t, err)
+	assert.Empty(t, pairs)
+}
+
+func TestRankGlobalKarmaWithHugeCount(t *testing.T) {
+	entriesByChannel := map[string]map[string]string{"C1": {"thing": "1", "bird": "3"}, "C2": {"thing": "4"}}
+
+	pairs, err := rankGlobalKarma(entriesByChannel, 1<<55, topFirst, 4)
+	require.NoError(t, err)
+	assert.Equal(t, pairList{{"thing", 5}, {"bird", 3}}, pairs)
+
+	assert.Equal(t, pairList{{"thing", 5}}, topKarma(map[string]int{"thing": 5}, 1<<55, topFirst))
+	assert.Equal(t, pairList{{"bird", 3}, {"thing", 1}}, mergeRankedKarma([]pairList{{{"bird", 3}}, {{"thing", 1}}}, 1<<55, topFirst))
+}
+
+func TestRankGlobalKarmaWithInvalidValue(t *testing.T) {
+	entriesByChannel := newRandomKarma(50, 20000, 200)
+	entriesByChannel["C1"]["thing"] = "abc"
+
+	_, err := rankGlobalKarma(entriesByChannel, 5, worstFirst, 4)
+	assert.EqualError(t, err, "strconv.Atoi: parsing \"abc\": invalid syntax")
+}
+
+func BenchmarkRankGlobalKarma(b *testing.B) {
+	entriesByChannel := newRandomKarma(1000, 100000, 500)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rankGlobalKarma(entriesByChannel, defaultItemCount, topFirst, maxKarmaRankingWorkers)
+	}
+}
+
+func BenchmarkSequentialRankGlobalKarma(b *testing.B) {
+	entriesByChannel := newRandomKarma(1000, 100000, 500)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		merged, _ := scanGlobalKarma(&globalScanner{entriesByChannel}, "")
+		getRankedList(merged, defaultItemCount, topFirst)
+	}
+}
+
+// globalScanner is a partial store.GlobalSiloStringStorer only supporting GlobalScan
+type globalScanner struct {
+	entriesByChannel map[string]map[string]string
+}
+
+func (gs *globalScanner) GlobalScan() (entries map[string]map[string]string, err error) {
+	return gs.entriesByChannel, nil
+}
+
+func (gs *globalScanner) GetSiloString(silo string, key string) (value string, err error) {
+	return "", nil
+}
+
+func (gs *globalScanner) PutSiloString(silo string, key string, value string) (err error) {
+	return nil
+}
+
+func (gs *globalScanner) DeleteSiloString(silo string, key string) (err error) {
+	return nil
+}
+
+func (gs *globalScanner) ScanSilo(silo string) (entries map[string]string, err error) {
+	return gs.entriesByChannel[silo], nil
+}
+
+func (gs *globalScanner) Close() (err error) {
+	return nil
+}
@@ -0,0 +1,378 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// KnowledgeBasePluginName holds identifying name for the knowledge base plugin
+	KnowledgeBasePluginName = "knowledgeBase"
+	minKnowledgeMatchScore  = 0.5
+)
+
+var learnRegex = regexp.MustCompile("(?msi)\\Alearn (global )?'([^']+)'\\s*=>\\s*(.+)")
+var unlearnRegex = regexp.MustCompile("(?i)\\Aunlearn (global )?'([^']+)'")
+var askRegex = regexp.MustCompile("(?msi)\\Aask (.+)")
+var knowledgeWordRegex = regexp.MustCompile("[\\p{L}\\p{N}]+")
+
+// KnowledgeBase holds the plugin data for the knowledge base plugin. Curators teach answers to topics, either
+// for a channel or globally, and users ask questions that are matched to the closest known topic
+type KnowledgeBase struct {
+	*slackscot.Plugin
+	storer   store.GlobalSiloStringStorer
+	curators map[string]bool
+}
+
+// knowledge is what's persisted for every topic
+type knowledge struct {
+	Answer string `json:"answer"`
+	Author string `json:"author"`
+	Asks   int    `json:"asks"`
+}
+
+// knowledgeMatch is a topic matching a question along with its score
+type knowledgeMatch struct {
+	silo  string
+	topic string
+	knowledge
+	score float64
+}
+
+// NewKnowledgeBase creates a new instance of the knowledge base plugin. If curatorIDs is empty, everyone is allowed
+// to teach answers, otherwise only the users with those IDs are
+func NewKnowledgeBase(storer store.GlobalSiloStringStorer, curatorIDs []string) (p *slackscot.Plugin) {
+	kb := new(KnowledgeBase)
+	kb.storer = storer
+	kb.curators = make(map[string]bool)
+	for _, c := range curatorIDs {
+		kb.curators[c] = true
+	}
+
+	kb.Plugin = plugin.New(KnowledgeBasePluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return learnRegex.MatchString(m.NormalizedText) }).
+			WithUsage("learn [global] '<topic>' => <answer>").
+			WithDescription("Teach me the answer to a topic for this channel (or everywhere with `global`)").
+			WithAnswerer(kb.learn).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return unlearnRegex.MatchString(m.NormalizedText) }).
+			WithUsage("unlearn [global] '<topic>'").
+			WithDescription("Make me forget the answer to a topic for this channel (or everywhere with `global`)").
+			WithAnswerer(kb.unlearn).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return askRegex.MatchString(m.NormalizedText) }).
+			WithUsage("ask <question>").
+			WithDescription("Ask me a question and I'll answer with what I learned about the closest topic").
+			WithAnswerer(kb.ask).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return strings.HasPrefix(m.NormalizedText, "kb stats") }).
+			WithUsage("kb stats").
+			WithDescription("Lists the most asked topics").
+			WithAnswerer(kb.stats).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return strings.HasPrefix(m.NormalizedText, "kb export") }).
+			WithUsage("kb export").
+			WithDescription("Exports everything I learned (for this channel and globally) as a json file").
+			WithAnswerer(kb.export).
+			Build()).
+		Build()
+
+	return kb.Plugin
+}
+
+// isCurator returns true if the user is allowed to teach answers
+func (kb *KnowledgeBase) isCurator(userID string) bool {
+	return len(kb.curators) == 0 || kb.curators[userID]
+}
+
+// resolveSilo returns the silo for a scope which is the channel unless global is requested
+func resolveSilo(m *slackscot.IncomingMessage, global string) (silo string) {
+	if global != "" {
+		return globalSiloName
+	}
+
+	return m.Channel
+}
+
+// learn records the answer to a topic
+func (kb *KnowledgeBase) learn(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if !kb.isCurator(m.User) {
+		return &slackscot.Answer{Text: "Sorry, only curators can teach me things :no_entry_sign:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+	}
+
+	match := learnRegex.FindStringSubmatch(m.NormalizedText)
+	topic := strings.ToLower(strings.TrimSpace(match[2]))
+	k := knowledge{Answer: strings.TrimSpace(match[3]), Author: m.User}
+
+	// Keep the stats of a topic when its answer is updated
+	if existing, err := kb.getKnowledge(resolveSilo(m, match[1]), topic); err == nil {
+		k.Asks = existing.Asks
+	}
+
+	if err := kb.putKnowledge(resolveSilo(m, match[1]), topic, k); err != nil {
+		kb.Logger.Printf("[%s] Error persisting knowledge for topic [%s]: %v", KnowledgeBasePluginName, topic, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't learn about `%s` :disappointed: (%v)", topic, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Got it, I now know about `%s` :brain:", topic)}
+}
+
+// unlearn deletes the answer to a topic
+func (kb *KnowledgeBase) unlearn(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if !kb.isCurator(m.User) {
+		return &slackscot.Answer{Text: "Sorry, only curators can make me forget things :no_entry_sign:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+	}
+
+	match := unlearnRegex.FindStringSubmatch(m.NormalizedText)
+	topic := strings.ToLower(strings.TrimSpace(match[2]))
+	silo := resolveSilo(m, match[1])
+
+	if _, err := kb.getKnowledge(silo, topic); err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("I don't know anything about `%s` :shrug:", topic)}
+	}
+
+	if err := kb.storer.DeleteSiloString(silo, topic); err != nil {
+		kb.Logger.Printf("[%s] Error deleting knowledge for topic [%s]: %v", KnowledgeBasePluginName, topic, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't forget about `%s` :disappointed: (%v)", topic, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Forgot everything about `%s` :wastebasket:", topic)}
+}
+
+// ask answers a question with the knowledge of the best matching topic (preferring the channel's knowledge over
+// global knowledge when they're equally good matches)
+func (kb *KnowledgeBase) ask(m *slackscot.IncomingMessage) *slackscot.Answer {
+	question := askRegex.FindStringSubmatch(m.NormalizedText)[1]
+
+	best, found, err := kb.findBestMatch(question, m.Channel)
+	if err != nil {
+		kb.Logger.Printf("[%s] Error searching knowledge: %v", KnowledgeBasePluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't search what I know :disappointed: (%v)", err)}
+	}
+
+	if !found {
+		return &slackscot.Answer{Text: "I don't know anything about that yet :thinking_face:. A curator can teach me with `learn '<topic>' => <answer>`"}
+	}
+
+	best.Asks = best.Asks + 1
+	if err := kb.putKnowledge(best.silo, best.topic, best.knowledge); err != nil {
+		kb.Logger.Printf("[%s] Error updating stats of topic [%s]: %v", KnowledgeBasePluginName, best.topic, err)
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("*%s*: %s", best.topic, best.Answer)}
+}
+
+// findBestMatch returns the topic that best matches the question in the channel's knowledge or the global
+// knowledge. Channel knowledge comes first and is kept over global knowledge unless the latter is a strictly better match
+func (kb *KnowledgeBase) findBestMatch(question string, channelID string) (best knowledgeMatch, found bool, err error) {
+	all, err := kb.scanKnowledge(channelID)
+	if err != nil {
+		return best, false, err
+	}
+
+	questionWords := extractKnowledgeWords(question)
+	for _, candidate := range all {
+		candidate.score = scoreKnowledgeMatch(extractKnowledgeWords(candidate.topic), questionWords)
+		if candidate.score >= minKnowledgeMatchScore && (!found || candidate.score > best.score) {
+			best = candidate
+			found = true
+		}
+	}
+
+	return best, found, nil
+}
+
+// stats answers with the most asked topics for the channel and globally
+func (kb *KnowledgeBase) stats(m *slackscot.IncomingMessage) *slackscot.Answer {
+	all, err := kb.scanKnowledge(m.Channel)
+	if err != nil {
+		kb.Logger.Printf("[%s] Error loading knowledge: %v", KnowledgeBasePluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load what I know :disappointed: (%v)", err)}
+	}
+
+	if len(all) == 0 {
+		return &slackscot.Answer{Text: "I haven't learned anything yet :baby:"}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Asks > all[j].Asks
+	})
+
+	var b strings.Builder
+	b.WriteString("*Most asked topics* :bar_chart:")
+	for i, k := range all {
+		if i >= defaultItemCount {
+			break
+		}
+
+		fmt.Fprintf(&b, "\n• `%s`: asked %d times", k.topic, k.Asks)
+	}
+
+	return &slackscot.Answer{Text: b.String()}
+}
+
+// export uploads everything known for the channel and globally as a json file
+func (kb *KnowledgeBase) export(m *slackscot.IncomingMessage) *slackscot.Answer {
+	exported := make(map[string]map[string]knowledge)
+	for _, silo := range []string{m.Channel, globalSiloName} {
+		entries, err := kb.scanSilo(silo)
+		if err != nil {
+			kb.Logger.Printf("[%s] Error loading knowledge: %v", KnowledgeBasePluginName, err)
+			return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load what I know :disappointed: (%v)", err)}
+		}
+
+		scope := "channel"
+		if silo == globalSiloName {
+			scope = "global"
+		}
+		exported[scope] = entries
+	}
+
+	content, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't export what I know :disappointed: (%v)", err)}
+	}
+
+	_, err = kb.FileUploader.UploadFile(slack.FileUploadParameters{Content: string(content), Filetype: "json", Filename: "knowledge.json", Title: "Knowledge base export", Channels: []string{m.Channel}})
+	if err != nil {
+		kb.Logger.Printf("[%s] Error uploading knowledge export: %v", KnowledgeBasePluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't upload the export :disappointed: (%v)", err)}
+	}
+
+	return nil
+}
+
+// scanKnowledge returns all the knowledge of a channel and the global knowledge
+func (kb *KnowledgeBase) scanKnowledge(channelID string) (all []knowledgeMatch, err error) {
+	all = make([]knowledgeMatch, 0)
+
+	for _, silo := range []string{channelID, globalSiloName} {
+		entries, err := kb.scanSilo(silo)
+		if err != nil {
+			return nil, err
+		}
+
+		topics := make([]string, 0, len(entries))
+		for topic := range entries {
+			topics = append(topics, topic)
+		}
+		sort.Strings(topics)
+
+		for _, topic := range topics {
+			all = append(all, knowledgeMatch{silo: silo, topic: topic, knowledge: entries[topic]})
+		}
+	}
+
+	return all, nil
+}
+
+// scanSilo returns all the valid knowledge of a silo by topic
+func (kb *KnowledgeBase) scanSilo(silo string) (entries map[string]knowledge, err error) {
+	raw, err := kb.storer.ScanSilo(silo)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = make(map[string]knowledge)
+	for topic, value := range raw {
+		var k knowledge
+		if err := json.Unmarshal([]byte(value), &k); err == nil {
+			entries[topic] = k
+		}
+	}
+
+	return entries, nil
+}
+
+// getKnowledge returns the knowledge recorded for a topic
+func (kb *KnowledgeBase) getKnowledge(silo string, topic string) (k knowledge, err error) {
+	value, err := kb.storer.GetSiloString(silo, topic)
+	if err != nil {
+		return k, err
+	}
+
+	err = json.Unmarshal([]byte(value), &k)
+	return k, err
+}
+
+// putKnowledge persists the knowledge for a topic
+func (kb *KnowledgeBase) putKnowledge(silo string, topic string, k knowledge) (err error) {
+	value, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+
+	return kb.storer.PutSiloString(silo, topic, string(value))
+}
+
+// extractKnowledgeWords returns the lowercased words of a text
+func extractKnowledgeWords(text string) (words []string) {
+	return knowledgeWordRegex.FindAllString(strings.ToLower(text), -1)
+}
+
+// scoreKnowledgeMatch returns the fraction of the topic words found in the question. Words of 4 characters or more
+// also match question words that are one edit away to tolerate typos and plurals
+func scoreKnowledgeMatch(topicWords []string, questionWords []string) (score float64) {
+	if len(topicWords) == 0 {
+		return 0
+	}
+
+	matched := 0
+	for _, tw := range topicWords {
+		for _, qw := range questionWords {
+			if tw == qw || (len(tw) >= 4 && editDistance(tw, qw) <= 1) {
+				matched++
+				break
+			}
+		}
+	}
+
+	return float64(matched) / float64(len(topicWords))
+}
+
+// editDistance returns the levenshtein distance between two strings
+func editDistance(a string, b string) (distance int) {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev = cur
+	}
+
+	return prev[len(rb)]
+}
+
+// minInt returns the smallest of two ints
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,95 @@
+package plugins_test
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKnowledgeBaseLearnAndAsk(t *testing.T) {
+	testCases := []struct {
+		text           string
+		channel        string
+		expectedAnswer string
+	}{
+		{"<@bot> ask how do I set up the vpn?", "Cgeneral", "I don't know anything about that yet :thinking_face:. A curator can teach me with `learn '<topic>' => <answer>`"},
+		{"<@bot> learn global 'VPN setup' => Install the client from https://vpn.example.com", "Cgeneral", "Got it, I now know about `vpn setup` :brain:"},
+		{"<@bot> ask how do I set up the vpn?", "Cgeneral", "*vpn setup*: Install the client from https://vpn.example.com"},
+		{"<@bot> ask vpn setpu", "Cother", "*vpn setup*: Install the client from https://vpn.example.com"},
+		{"<@bot> learn 'vpn setup' => Ask #it-help, we use a different vpn", "Cgeneral", "Got it, I now know about `vpn setup` :brain:"},
+		{"<@bot> ask vpn setup", "Cgeneral", "*vpn setup*: Ask #it-help, we use a different vpn"},
+		{"<@bot> ask vpn setup", "Cother", "*vpn setup*: Install the client from https://vpn.example.com"},
+		{"<@bot> ask what's for lunch?", "Cgeneral", "I don't know anything about that yet :thinking_face:. A curator can teach me with `learn '<topic>' => <answer>`"},
+		{"<@bot> unlearn 'vpn setup'", "Cgeneral", "Forgot everything about `vpn setup` :wastebasket:"},
+		{"<@bot> unlearn 'vpn setup'", "Cgeneral", "I don't know anything about `vpn setup` :shrug:"},
+		{"<@bot> ask vpn setup", "Cgeneral", "*vpn setup*: Install the client from https://vpn.example.com"},
+		{"<@bot> kb stats", "Cgeneral", "*Most asked topics* :bar_chart:\n• `vpn setup`: asked 4 times"},
+	}
+
+	storer, cleanup := plugins.NewTestStorer(t, "knowledgeBaseTest")
+	defer cleanup()
+
+	p := plugins.NewKnowledgeBase(storer, nil)
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin := assertplugin.New(t, "bot")
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: tc.channel, User: "U21355", Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
+
+func TestKnowledgeBaseOnlyCuratorsTeach(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "knowledgeBaseTest")
+	defer cleanup()
+
+	p := plugins.NewKnowledgeBase(storer, []string{"Ucurator"})
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> learn 'lunch' => pizza"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, only curators can teach me things :no_entry_sign:") && assertanswer.HasOptions(t, answers[0], assertanswer.ResolvedAnswerOption{Key: slackscot.EphemeralAnswerToOpt, Value: "U21355"})
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "Ucurator", Text: "<@bot> learn 'lunch' => pizza"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Got it, I now know about `lunch` :brain:")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> unlearn 'lunch'"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, only curators can make me forget things :no_entry_sign:")
+	})
+}
+
+func TestKnowledgeBaseExport(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "knowledgeBaseTest")
+	defer cleanup()
+
+	p := plugins.NewKnowledgeBase(storer, nil)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> learn 'lunch' => pizza"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> learn global 'holidays' => See the wiki"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+
+	assertplugin.AnswersAndReactsWithUploads(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> kb export"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, fileUploads []slack.FileUploadParameters) bool {
+		if !assert.Empty(t, answers) || !assert.Len(t, fileUploads, 1) {
+			return false
+		}
+
+		var exported map[string]map[string]map[string]interface{}
+		return assert.Equal(t, "knowledge.json", fileUploads[0].Filename) &&
+			assert.Equal(t, []string{"Cgeneral"}, fileUploads[0].Channels) &&
+			assert.NoError(t, json.Unmarshal([]byte(fileUploads[0].Content), &exported)) &&
+			assert.Equal(t, "pizza", exported["channel"]["lunch"]["answer"]) &&
+			assert.Equal(t, "See the wiki", exported["global"]["holidays"]["answer"])
+	})
+}
@@ -0,0 +1,337 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/slack-go/slack"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// KubernetesPluginName holds identifying name for the kubernetes plugin
+	KubernetesPluginName = "kubernetes"
+)
+
+// Configuration keys
+const (
+	k8sNamespacesKey          = "namespaces"          // Namespaces to watch, required
+	k8sChannelIDKey           = "channelID"           // Channel where summaries are posted, required
+	k8sPollIntervalMinutesKey = "pollIntervalMinutes" // How often namespaces are checked, in minutes. Defaults to 1
+	k8sDedupWindowKey         = "dedupWindow"         // Duration during which the same problem isn't reported again, defaults to 1h
+	k8sMaxSummariesPerHourKey = "maxSummariesPerHour" // Maximum number of summaries posted per hour, defaults to 6
+)
+
+const (
+	defaultK8sPollIntervalMinutes = 1
+	defaultK8sDedupWindow         = time.Hour
+	defaultK8sMaxSummariesPerHour = 6
+	maxK8sSummaryLines            = 15
+	crashLoopBackOff              = "CrashLoopBackOff"
+)
+
+var k8sStatusRegex = regexp.MustCompile("(?i)\\Ak8s status (?:(\\S+)/)?(\\S+)\\z")
+
+// KubernetesEvent is a warning event of a kubernetes object
+type KubernetesEvent struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Reason    string
+	Message   string
+	Count     int
+	LastSeen  time.Time
+}
+
+// KubernetesContainerStatus is the status of a container of a pod
+type KubernetesContainerStatus struct {
+	Name          string
+	Ready         bool
+	RestartCount  int
+	WaitingReason string
+}
+
+// KubernetesPod is a pod along with the status of its containers
+type KubernetesPod struct {
+	Namespace  string
+	Name       string
+	Phase      string
+	Containers []KubernetesContainerStatus
+}
+
+// KubernetesDeployment is the rollout status of a deployment
+type KubernetesDeployment struct {
+	Namespace         string
+	Name              string
+	Replicas          int
+	ReadyReplicas     int
+	UpdatedReplicas   int
+	AvailableReplicas int
+	LabelSelector     string
+}
+
+// KubernetesClient is implemented by clients of the kubernetes API (see KubernetesAPIClient)
+type KubernetesClient interface {
+	// ListWarningEvents returns the warning events of a namespace
+	ListWarningEvents(namespace string) (events []KubernetesEvent, err error)
+
+	// ListPods returns the pods of a namespace matching the label selector (all pods if empty)
+	ListPods(namespace string, labelSelector string) (pods []KubernetesPod, err error)
+
+	// GetDeployment returns the status of a deployment
+	GetDeployment(namespace string, name string) (deployment KubernetesDeployment, err error)
+}
+
+// Kubernetes holds the plugin data for the kubernetes plugin
+type Kubernetes struct {
+	*slackscot.Plugin
+	client              KubernetesClient
+	namespaces          []string
+	channelID           string
+	dedupWindow         time.Duration
+	maxSummariesPerHour int
+	now                 func() time.Time
+	started             time.Time
+
+	sync.Mutex
+	reported   map[string]time.Time
+	summaries  []time.Time
+	suppressed int
+}
+
+// NewKubernetes creates a new instance of the kubernetes plugin. It periodically checks the watched namespaces for
+// warning events and crash looping pods and posts summaries of new problems. Problems are only reported once per
+// dedup window and summaries are rate limited. It also adds a command reporting the rollout status of deployments
+func NewKubernetes(c *config.PluginConfig, client KubernetesClient) (p *slackscot.Plugin, err error) {
+	return newKubernetes(c, client, time.Now)
+}
+
+// newKubernetes creates a new instance of the kubernetes plugin using now to get the current time
+func newKubernetes(c *config.PluginConfig, client KubernetesClient, now func() time.Time) (p *slackscot.Plugin, err error) {
+	for _, k := range []string{k8sNamespacesKey, k8sChannelIDKey} {
+		if !c.IsSet(k) {
+			return nil, fmt.Errorf("Missing %s config key: %s", KubernetesPluginName, k)
+		}
+	}
+
+	c.SetDefault(k8sPollIntervalMinutesKey, defaultK8sPollIntervalMinutes)
+	c.SetDefault(k8sDedupWindowKey, defaultK8sDedupWindow)
+	c.SetDefault(k8sMaxSummariesPerHourKey, defaultK8sMaxSummariesPerHour)
+
+	pollInterval := c.GetInt(k8sPollIntervalMinutesKey)
+	if pollInterval <= 0 {
+		return nil, fmt.Errorf("Invalid %s config key value for %s: [%d], should be greater than 0", KubernetesPluginName, k8sPollIntervalMinutesKey, pollInterval)
+	}
+
+	k := new(Kubernetes)
+	k.client = client
+	k.namespaces = c.GetStringSlice(k8sNamespacesKey)
+	k.channelID = c.GetString(k8sChannelIDKey)
+	k.dedupWindow = c.GetDuration(k8sDedupWindowKey)
+	k.maxSummariesPerHour = c.GetInt(k8sMaxSummariesPerHourKey)
+	k.now = now
+	k.started = now()
+	k.reported = make(map[string]time.Time)
+
+	k.Plugin = plugin.New(KubernetesPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return k8sStatusRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("k8s status [<namespace>/]<deployment>").
+			WithDescription("Reports the rollout status of a deployment and its pods").
+			WithAnswerer(k.deploymentStatus).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithInterval(uint64(pollInterval), schedule.Minutes).Build()).
+			WithDescription(fmt.Sprintf("Report warning events and crash loops in %s", strings.Join(k.namespaces, ", "))).
+			WithAction(k.reportProblems).
+			Build()).
+		Build()
+
+	return k.Plugin, nil
+}
+
+// reportProblems posts a summary of the warning events and crash looping pods that weren't reported during the
+// dedup window. Events that happened before the plugin started are ignored to avoid reporting old problems on restarts
+func (k *Kubernetes) reportProblems() {
+	problems := make(map[string]string)
+
+	for _, ns := range k.namespaces {
+		events, err := k.client.ListWarningEvents(ns)
+		if err != nil {
+			k.Logger.Printf("[%s] Error listing events of namespace [%s]: %v", KubernetesPluginName, ns, err)
+		}
+
+		for _, e := range events {
+			if e.LastSeen.Before(k.started) {
+				continue
+			}
+
+			key := fmt.Sprintf("event:%s/%s/%s:%s", e.Namespace, e.Kind, e.Name, e.Reason)
+			problems[key] = fmt.Sprintf(":warning: `%s/%s` %s *%s*: %s", e.Namespace, e.Name, strings.ToLower(e.Kind), e.Reason, e.Message)
+		}
+
+		pods, err := k.client.ListPods(ns, "")
+		if err != nil {
+			k.Logger.Printf("[%s] Error listing pods of namespace [%s]: %v", KubernetesPluginName, ns, err)
+		}
+
+		for _, p := range pods {
+			for _, c := range p.Containers {
+				if c.WaitingReason == crashLoopBackOff {
+					key := fmt.Sprintf("crashloop:%s/%s/%s", p.Namespace, p.Name, c.Name)
+					problems[key] = fmt.Sprintf(":boom: `%s/%s` container *%s* is crash looping (%d restarts)", p.Namespace, p.Name, c.Name, c.RestartCount)
+				}
+			}
+		}
+	}
+
+	lines := k.newProblems(problems)
+	if len(lines) == 0 {
+		return
+	}
+
+	summary, ok := k.summarize(lines)
+	if !ok {
+		k.Logger.Debugf("[%s] Rate limiting summary of %d problem(s)", KubernetesPluginName, len(lines))
+		return
+	}
+
+	if _, _, err := k.SlackClient.PostMessage(k.channelID, slack.MsgOptionText(summary, false), slack.MsgOptionAsUser(true)); err != nil {
+		k.Logger.Printf("[%s] Error posting summary: %v", KubernetesPluginName, err)
+	}
+}
+
+// newProblems returns the sorted lines of the problems that weren't reported during the dedup window and marks them
+// as reported
+func (k *Kubernetes) newProblems(problems map[string]string) (lines []string) {
+	k.Lock()
+	defer k.Unlock()
+
+	now := k.now()
+	for key, reportedAt := range k.reported {
+		if now.Sub(reportedAt) >= k.dedupWindow {
+			delete(k.reported, key)
+		}
+	}
+
+	for key, line := range problems {
+		if _, ok := k.reported[key]; !ok {
+			k.reported[key] = now
+			lines = append(lines, line)
+		}
+	}
+
+	sort.Strings(lines)
+
+	return lines
+}
+
+// summarize renders the summary of the problems unless the maximum number of summaries was posted in the last hour.
+// Rate limited problems are counted and mentioned in the next summary
+func (k *Kubernetes) summarize(lines []string) (summary string, ok bool) {
+	k.Lock()
+	defer k.Unlock()
+
+	now := k.now()
+	recent := make([]time.Time, 0, len(k.summaries))
+	for _, s := range k.summaries {
+		if now.Sub(s) < time.Hour {
+			recent = append(recent, s)
+		}
+	}
+	k.summaries = recent
+
+	if len(k.summaries) >= k.maxSummariesPerHour {
+		k.suppressed = k.suppressed + len(lines)
+		return "", false
+	}
+
+	k.summaries = append(k.summaries, now)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Kubernetes problems* (%d new)", len(lines))
+	for i, l := range lines {
+		if i == maxK8sSummaryLines {
+			fmt.Fprintf(&b, "\n_…and %d more_", len(lines)-maxK8sSummaryLines)
+			break
+		}
+
+		fmt.Fprintf(&b, "\n%s", l)
+	}
+
+	if k.suppressed > 0 {
+		fmt.Fprintf(&b, "\n_%d problem(s) weren't reported because of rate limiting_", k.suppressed)
+		k.suppressed = 0
+	}
+
+	return b.String(), true
+}
+
+// deploymentStatus answers with the rollout status of a deployment and the state of its pods. When the namespace
+// isn't given, the deployment is looked up in the watched namespaces
+func (k *Kubernetes) deploymentStatus(m *slackscot.IncomingMessage) *slackscot.Answer {
+	match := k8sStatusRegex.FindStringSubmatch(m.NormalizedText)
+	namespaces, name := k.namespaces, match[2]
+	if match[1] != "" {
+		namespaces = []string{match[1]}
+	}
+
+	var lastErr error
+	for _, ns := range namespaces {
+		deployment, err := k.client.GetDeployment(ns, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		pods, err := k.client.ListPods(ns, deployment.LabelSelector)
+		if err != nil {
+			k.Logger.Printf("[%s] Error listing pods of deployment [%s/%s]: %v", KubernetesPluginName, ns, name, err)
+		}
+
+		return &slackscot.Answer{Text: formatDeploymentStatus(deployment, pods)}
+	}
+
+	k.Logger.Debugf("[%s] Error getting deployment [%s] in %v: %v", KubernetesPluginName, name, namespaces, lastErr)
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't get the status of deployment `%s` in %s :disappointed: (%v)", name, strings.Join(namespaces, ", "), lastErr)}
+}
+
+// formatDeploymentStatus renders the replica counts of a deployment followed by its unhealthy pods
+func formatDeploymentStatus(d KubernetesDeployment, pods []KubernetesPod) string {
+	emoji := ":white_check_mark:"
+	if d.ReadyReplicas < d.Replicas || d.UpdatedReplicas < d.Replicas {
+		emoji = ":hourglass_flowing_sand:"
+	}
+
+	if d.AvailableReplicas == 0 && d.Replicas > 0 {
+		emoji = ":x:"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s *%s/%s*: %d/%d ready, %d updated, %d available", emoji, d.Namespace, d.Name, d.ReadyReplicas, d.Replicas, d.UpdatedReplicas, d.AvailableReplicas)
+
+	for _, p := range pods {
+		for _, c := range p.Containers {
+			if !c.Ready || c.WaitingReason != "" {
+				reason := c.WaitingReason
+				if reason == "" {
+					reason = "not ready"
+				}
+
+				fmt.Fprintf(&b, "\n• `%s` container *%s*: %s (%d restarts)", p.Name, c.Name, reason, c.RestartCount)
+			}
+		}
+	}
+
+	return b.String()
+}
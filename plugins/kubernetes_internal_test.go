@@ -0,0 +1,160 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// fakeKubernetesClient is a KubernetesClient returning canned events, pods and deployments
+type fakeKubernetesClient struct {
+	events      map[string][]KubernetesEvent
+	pods        map[string][]KubernetesPod
+	deployments map[string]KubernetesDeployment
+}
+
+func (f *fakeKubernetesClient) ListWarningEvents(namespace string) (events []KubernetesEvent, err error) {
+	return f.events[namespace], nil
+}
+
+func (f *fakeKubernetesClient) ListPods(namespace string, labelSelector string) (pods []KubernetesPod, err error) {
+	return f.pods[namespace], nil
+}
+
+func (f *fakeKubernetesClient) GetDeployment(namespace string, name string) (deployment KubernetesDeployment, err error) {
+	deployment, ok := f.deployments[namespace+"/"+name]
+	if !ok {
+		return deployment, fmt.Errorf("deployment %s not found", name)
+	}
+
+	return deployment, nil
+}
+
+func newKubernetesTestConfig() *viper.Viper {
+	c := viper.New()
+	c.Set("namespaces", []string{"payments", "search"})
+	c.Set("channelID", "Cops")
+	c.Set("maxSummariesPerHour", 2)
+
+	return c
+}
+
+func TestKubernetesMissingConfig(t *testing.T) {
+	_, err := NewKubernetes(viper.New(), &fakeKubernetesClient{})
+	assert.EqualError(t, err, "Missing kubernetes config key: namespaces")
+
+	c := viper.New()
+	c.Set("namespaces", []string{"payments"})
+	_, err = NewKubernetes(c, &fakeKubernetesClient{})
+	assert.EqualError(t, err, "Missing kubernetes config key: channelID")
+}
+
+func TestKubernetesReportsDeduplicatedAndRateLimitedProblems(t *testing.T) {
+	posted := postedMessages{}
+	testServer := newPostedMessagesServer(&posted)
+	defer testServer.Stop()
+
+	clock := testClock{now: time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)}
+	client := &fakeKubernetesClient{
+		events: map[string][]KubernetesEvent{
+			"payments": {
+				{Namespace: "payments", Kind: "Pod", Name: "api-1", Reason: "FailedScheduling", Message: "0/3 nodes are available", LastSeen: clock.now.Add(time.Minute)},
+				{Namespace: "payments", Kind: "Pod", Name: "api-0", Reason: "BackOff", Message: "old news", LastSeen: clock.now.Add(-time.Hour)},
+			},
+		},
+		pods: map[string][]KubernetesPod{
+			"search": {{Namespace: "search", Name: "indexer-1", Containers: []KubernetesContainerStatus{{Name: "indexer", WaitingReason: "CrashLoopBackOff", RestartCount: 7}}}},
+		},
+	}
+
+	p, err := newKubernetes(newKubernetesTestConfig(), client, clock.Now)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	everyMinute := schedule.New().WithInterval(1, schedule.Minutes).Build()
+	runOnSchedule := func() {
+		assertplugin.RunsOnSchedule(p, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+			return true
+		})
+	}
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	runOnSchedule()
+
+	if assert.Len(t, posted.messages, 1) {
+		assert.Equal(t, "Cops", posted.messages[0].channel)
+		assert.Equal(t, "*Kubernetes problems* (2 new)\n"+
+			":boom: `search/indexer-1` container *indexer* is crash looping (7 restarts)\n"+
+			":warning: `payments/api-1` pod *FailedScheduling*: 0/3 nodes are available", posted.messages[0].text)
+	}
+
+	// The same problems aren't reported again during the dedup window
+	clock.now = clock.now.Add(10 * time.Minute)
+	runOnSchedule()
+	assert.Len(t, posted.messages, 1)
+
+	newEvent := func(name string) KubernetesEvent {
+		return KubernetesEvent{Namespace: "payments", Kind: "Pod", Name: name, Reason: "Unhealthy", Message: "Readiness probe failed", LastSeen: clock.now}
+	}
+
+	client.events["payments"] = append(client.events["payments"], newEvent("api-2"))
+	runOnSchedule()
+	require.Len(t, posted.messages, 2)
+	assert.Equal(t, "*Kubernetes problems* (1 new)\n:warning: `payments/api-2` pod *Unhealthy*: Readiness probe failed", posted.messages[1].text)
+
+	// Only 2 summaries are posted per hour
+	client.events["payments"] = append(client.events["payments"], newEvent("api-3"))
+	runOnSchedule()
+	assert.Len(t, posted.messages, 2)
+
+	// Once the dedup window and the rate limit are over, problems are reported again along with the count of suppressed ones
+	clock.now = clock.now.Add(time.Hour)
+	client.events["payments"] = []KubernetesEvent{newEvent("api-4")}
+	client.pods = nil
+	runOnSchedule()
+
+	if assert.Len(t, posted.messages, 3) {
+		assert.Equal(t, "*Kubernetes problems* (1 new)\n:warning: `payments/api-4` pod *Unhealthy*: Readiness probe failed\n_1 problem(s) weren't reported because of rate limiting_", posted.messages[2].text)
+	}
+}
+
+func TestKubernetesDeploymentStatus(t *testing.T) {
+	client := &fakeKubernetesClient{
+		deployments: map[string]KubernetesDeployment{
+			"search/indexer": {Namespace: "search", Name: "indexer", Replicas: 3, ReadyReplicas: 2, UpdatedReplicas: 3, AvailableReplicas: 2},
+			"infra/proxy":    {Namespace: "infra", Name: "proxy", Replicas: 2, ReadyReplicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		pods: map[string][]KubernetesPod{
+			"search": {
+				{Namespace: "search", Name: "indexer-1", Containers: []KubernetesContainerStatus{{Name: "indexer", Ready: true}}},
+				{Namespace: "search", Name: "indexer-2", Containers: []KubernetesContainerStatus{{Name: "indexer", WaitingReason: "CrashLoopBackOff", RestartCount: 7}}},
+			},
+		},
+	}
+
+	p, err := NewKubernetes(newKubernetesTestConfig(), client)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cops", User: "U21355", Text: "<@bot> k8s status indexer"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":hourglass_flowing_sand: *search/indexer*: 2/3 ready, 3 updated, 2 available\n• `indexer-2` container *indexer*: CrashLoopBackOff (7 restarts)")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cops", User: "U21355", Text: "<@bot> k8s status infra/proxy"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":white_check_mark: *infra/proxy*: 2/2 ready, 2 updated, 2 available")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cops", User: "U21355", Text: "<@bot> k8s status ghost"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, I couldn't get the status of deployment `ghost` in payments, search :disappointed: (deployment ghost not found)")
+	})
+}
@@ -0,0 +1,337 @@
+package plugins
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	kubernetesRequestTimeout = 30 * time.Second
+	inClusterTokenPath       = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAPath          = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// KubernetesAPIClient is a KubernetesClient calling the kubernetes API directly. It only needs read access to
+// events, pods and deployments of the watched namespaces
+type KubernetesAPIClient struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+// kubeconfig holds the parts of a kubeconfig file used to connect to a cluster with a token or client certificate
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// NewKubernetesAPIClient creates a new kubernetes API client for the server authenticating with a bearer token (if
+// not empty). A nil tlsConfig uses the default TLS configuration
+func NewKubernetesAPIClient(server string, token string, tlsConfig *tls.Config) (k *KubernetesAPIClient) {
+	k = new(KubernetesAPIClient)
+	k.server = strings.TrimSuffix(server, "/")
+	k.token = token
+	k.client = &http.Client{Timeout: kubernetesRequestTimeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+	return k
+}
+
+// NewInClusterKubernetesClient creates a new kubernetes API client using the service account of the pod slackscot
+// runs in
+func NewInClusterKubernetesClient() (k *KubernetesAPIClient, err error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	token, err := ioutil.ReadFile(inClusterTokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(inClusterCAPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid certificate authority in [%s]", inClusterCAPath)
+	}
+
+	return NewKubernetesAPIClient("https://"+net.JoinHostPort(host, port), strings.TrimSpace(string(token)), tlsConfig), nil
+}
+
+// NewKubeconfigKubernetesClient creates a new kubernetes API client with the cluster and user of the current context
+// of a kubeconfig file. Only token and client certificate authentication are supported (not exec or auth provider plugins)
+func NewKubeconfigKubernetesClient(path string) (k *KubernetesAPIClient, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var kc kubeconfig
+	if err := yaml.Unmarshal(content, &kc); err != nil {
+		return nil, err
+	}
+
+	var clusterName, userName string
+	for _, c := range kc.Contexts {
+		if c.Name == kc.CurrentContext {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+		}
+	}
+
+	if clusterName == "" {
+		return nil, fmt.Errorf("current context [%s] not found in [%s]", kc.CurrentContext, path)
+	}
+
+	tlsConfig := new(tls.Config)
+	var server, token string
+
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+
+		server = c.Cluster.Server
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+
+		ca, err := readKubeconfigData(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority)
+		if err != nil {
+			return nil, err
+		}
+
+		if ca != nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("invalid certificate authority for cluster [%s]", clusterName)
+			}
+		}
+	}
+
+	if server == "" {
+		return nil, fmt.Errorf("cluster [%s] not found in [%s]", clusterName, path)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+
+		token = u.User.Token
+
+		cert, err := readKubeconfigData(u.User.ClientCertificateData, u.User.ClientCertificate)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := readKubeconfigData(u.User.ClientKeyData, u.User.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if cert != nil && key != nil {
+			certificate, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+	}
+
+	return NewKubernetesAPIClient(server, token, tlsConfig), nil
+}
+
+// readKubeconfigData returns the base64 encoded data if set or the content of the file otherwise. Nil is returned
+// if neither are set
+func readKubeconfigData(data string, path string) (content []byte, err error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	if path != "" {
+		return ioutil.ReadFile(path)
+	}
+
+	return nil, nil
+}
+
+// ListWarningEvents returns the warning events of a namespace
+func (k *KubernetesAPIClient) ListWarningEvents(namespace string) (events []KubernetesEvent, err error) {
+	var list struct {
+		Items []struct {
+			InvolvedObject struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"involvedObject"`
+			Reason        string    `json:"reason"`
+			Message       string    `json:"message"`
+			Count         int       `json:"count"`
+			LastTimestamp time.Time `json:"lastTimestamp"`
+		} `json:"items"`
+	}
+
+	if err := k.get(fmt.Sprintf("/api/v1/namespaces/%s/events", url.PathEscape(namespace)), url.Values{"fieldSelector": {"type=Warning"}}, &list); err != nil {
+		return nil, err
+	}
+
+	events = make([]KubernetesEvent, 0, len(list.Items))
+	for _, i := range list.Items {
+		events = append(events, KubernetesEvent{Namespace: namespace, Kind: i.InvolvedObject.Kind, Name: i.InvolvedObject.Name, Reason: i.Reason, Message: i.Message, Count: i.Count, LastSeen: i.LastTimestamp})
+	}
+
+	return events, nil
+}
+
+// ListPods returns the pods of a namespace matching the label selector (all pods if empty)
+func (k *KubernetesAPIClient) ListPods(namespace string, labelSelector string) (pods []KubernetesPod, err error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Phase             string `json:"phase"`
+				ContainerStatuses []struct {
+					Name         string `json:"name"`
+					Ready        bool   `json:"ready"`
+					RestartCount int    `json:"restartCount"`
+					State        struct {
+						Waiting *struct {
+							Reason string `json:"reason"`
+						} `json:"waiting"`
+					} `json:"state"`
+				} `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+
+	query := url.Values{}
+	if labelSelector != "" {
+		query.Set("labelSelector", labelSelector)
+	}
+
+	if err := k.get(fmt.Sprintf("/api/v1/namespaces/%s/pods", url.PathEscape(namespace)), query, &list); err != nil {
+		return nil, err
+	}
+
+	pods = make([]KubernetesPod, 0, len(list.Items))
+	for _, i := range list.Items {
+		pod := KubernetesPod{Namespace: namespace, Name: i.Metadata.Name, Phase: i.Status.Phase}
+		for _, cs := range i.Status.ContainerStatuses {
+			status := KubernetesContainerStatus{Name: cs.Name, Ready: cs.Ready, RestartCount: cs.RestartCount}
+			if cs.State.Waiting != nil {
+				status.WaitingReason = cs.State.Waiting.Reason
+			}
+
+			pod.Containers = append(pod.Containers, status)
+		}
+
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// GetDeployment returns the status of a deployment
+func (k *KubernetesAPIClient) GetDeployment(namespace string, name string) (deployment KubernetesDeployment, err error) {
+	var d struct {
+		Spec struct {
+			Replicas int `json:"replicas"`
+			Selector struct {
+				MatchLabels map[string]string `json:"matchLabels"`
+			} `json:"selector"`
+		} `json:"spec"`
+		Status struct {
+			ReadyReplicas     int `json:"readyReplicas"`
+			UpdatedReplicas   int `json:"updatedReplicas"`
+			AvailableReplicas int `json:"availableReplicas"`
+		} `json:"status"`
+	}
+
+	if err := k.get(fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments/%s", url.PathEscape(namespace), url.PathEscape(name)), nil, &d); err != nil {
+		return deployment, err
+	}
+
+	selector := make([]string, 0, len(d.Spec.Selector.MatchLabels))
+	for label, value := range d.Spec.Selector.MatchLabels {
+		selector = append(selector, fmt.Sprintf("%s=%s", label, value))
+	}
+	sort.Strings(selector)
+
+	return KubernetesDeployment{Namespace: namespace, Name: name, Replicas: d.Spec.Replicas, ReadyReplicas: d.Status.ReadyReplicas, UpdatedReplicas: d.Status.UpdatedReplicas,
+		AvailableReplicas: d.Status.AvailableReplicas, LabelSelector: strings.Join(selector, ",")}, nil
+}
+
+// get calls the kubernetes API and decodes its json response in v
+func (k *KubernetesAPIClient) get(path string, query url.Values, v interface{}) (err error) {
+	u := k.server + path
+	if len(query) > 0 {
+		u = u + "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s not found", path)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status [%s] from [%s]", resp.Status, path)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,112 @@
+package plugins_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newKubernetesAPIServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/namespaces/payments/events":
+			if r.URL.Query().Get("fieldSelector") != "type=Warning" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			fmt.Fprint(w, `{"items": [{"involvedObject": {"kind": "Pod", "name": "api-1"}, "reason": "FailedScheduling", "message": "0/3 nodes are available", "count": 4, "lastTimestamp": "2020-03-02T09:00:00Z"}]}`)
+		case "/api/v1/namespaces/payments/pods":
+			fmt.Fprintf(w, `{"items": [{"metadata": {"name": "api-1"}, "status": {"phase": "Running", "containerStatuses": [{"name": "api", "ready": false, "restartCount": 3, "state": {"waiting": {"reason": "CrashLoopBackOff"}}}]}}], "selector": "%s"}`, r.URL.Query().Get("labelSelector"))
+		case "/apis/apps/v1/namespaces/payments/deployments/api":
+			fmt.Fprint(w, `{"spec": {"replicas": 3, "selector": {"matchLabels": {"tier": "web", "app": "api"}}}, "status": {"readyReplicas": 2, "updatedReplicas": 3, "availableReplicas": 2}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestKubernetesAPIClient(t *testing.T) {
+	server := newKubernetesAPIServer()
+	defer server.Close()
+
+	client := plugins.NewKubernetesAPIClient(server.URL, "t0k3n", nil)
+
+	events, err := client.ListWarningEvents("payments")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []plugins.KubernetesEvent{{Namespace: "payments", Kind: "Pod", Name: "api-1", Reason: "FailedScheduling", Message: "0/3 nodes are available", Count: 4, LastSeen: time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)}}, events)
+	}
+
+	pods, err := client.ListPods("payments", "app=api")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []plugins.KubernetesPod{{Namespace: "payments", Name: "api-1", Phase: "Running", Containers: []plugins.KubernetesContainerStatus{{Name: "api", RestartCount: 3, WaitingReason: "CrashLoopBackOff"}}}}, pods)
+	}
+
+	deployment, err := client.GetDeployment("payments", "api")
+	if assert.NoError(t, err) {
+		assert.Equal(t, plugins.KubernetesDeployment{Namespace: "payments", Name: "api", Replicas: 3, ReadyReplicas: 2, UpdatedReplicas: 3, AvailableReplicas: 2, LabelSelector: "app=api,tier=web"}, deployment)
+	}
+
+	_, err = client.GetDeployment("payments", "ghost")
+	assert.EqualError(t, err, "/apis/apps/v1/namespaces/payments/deployments/ghost not found")
+
+	_, err = plugins.NewKubernetesAPIClient(server.URL, "not the token", nil).ListWarningEvents("payments")
+	assert.Error(t, err)
+}
+
+func TestKubeconfigKubernetesClient(t *testing.T) {
+	server := newKubernetesAPIServer()
+	defer server.Close()
+
+	tmpdir, err := ioutil.TempDir("", "kubeconfig")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: staging
+  cluster:
+    server: https://staging.example.com
+- name: prod
+  cluster:
+    server: %s
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: slackscot
+users:
+- name: slackscot
+  user:
+    token: t0k3n
+`, server.URL)
+
+	path := filepath.Join(tmpdir, "config")
+	require.NoError(t, ioutil.WriteFile(path, []byte(kubeconfig), 0600))
+
+	client, err := plugins.NewKubeconfigKubernetesClient(path)
+	require.NoError(t, err)
+
+	events, err := client.ListWarningEvents("payments")
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("current-context: dev\n"), 0600))
+	_, err = plugins.NewKubeconfigKubernetesClient(path)
+	assert.EqualError(t, err, fmt.Sprintf("current context [dev] not found in [%s]", path))
+}
@@ -0,0 +1,280 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KudosPluginName holds identifying name for the kudos plugin
+	KudosPluginName = "kudos"
+
+	// KudosGiveActionID is the action ID of the home tab button to give kudos. An interactivity handler receiving
+	// it should call OpenGiveKudosModal with the trigger ID of the interaction
+	KudosGiveActionID = "kudos.give"
+
+	// KudosModalCallbackID is the callback ID of the modal to give kudos. An interactivity handler receiving
+	// its submission should call SubmitKudos with the submitting user and the view state
+	KudosModalCallbackID = "kudos.modal"
+)
+
+const (
+	kudosReceivedSilo    = "received"
+	kudosGivenSilo       = "given"
+	kudosRecentSilo      = "recent"
+	kudosRecipientBlock  = "kudos.recipient"
+	kudosRecipientAction = "kudos.recipient.select"
+	kudosReasonBlock     = "kudos.reason"
+	kudosReasonAction    = "kudos.reason.input"
+)
+
+var kudosRegex = regexp.MustCompile("(?is)\\Akudos <@(\\w+)>\\s*(?:for\\s+)?(.*)\\z")
+
+// kudo is a single recognition received by a user
+type kudo struct {
+	From   string `json:"from"`
+	Reason string `json:"reason"`
+}
+
+// Kudos holds the plugin data for the kudos plugin. Kudos given and received are shown to each user on a
+// personal dashboard in the bot's home tab
+type Kudos struct {
+	*slackscot.Plugin
+	storer store.GlobalSiloStringStorer
+}
+
+// NewKudos creates a new instance of the kudos plugin. Since the home tab is published via the SlackClient, the
+// bot must have the App Home enabled. Note that the home tab is refreshed whenever kudos are given or received since
+// opening the home tab isn't an event delivered by the real time messaging API
+func NewKudos(storer store.GlobalSiloStringStorer) (k *Kudos) {
+	k = new(Kudos)
+	k.storer = storer
+
+	k.Plugin = plugin.New(KudosPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return kudosRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("kudos <@someone> for <reason>").
+			WithDescription("Recognize someone's work with kudos").
+			WithAnswerer(k.giveKudosFromMessage).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "kudos board")
+			}).
+			WithUsage("kudos board").
+			WithDescription("Refresh your kudos dashboard in my home tab").
+			WithAnswerer(k.refreshBoard).
+			Build()).
+		Build()
+
+	return k
+}
+
+// giveKudosFromMessage records kudos given with the kudos command
+func (k *Kudos) giveKudosFromMessage(m *slackscot.IncomingMessage) *slackscot.Answer {
+	matches := kudosRegex.FindStringSubmatch(m.NormalizedText)
+	recipient, reason := matches[1], strings.TrimSpace(matches[2])
+
+	if err := k.giveKudos(m.User, recipient, reason); err != nil {
+		return &slackscot.Answer{Text: err.Error()}
+	}
+
+	if reason == "" {
+		return &slackscot.Answer{Text: fmt.Sprintf(":clap: Kudos to <@%s> from <@%s>!", recipient, m.User)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf(":clap: Kudos to <@%s> from <@%s> for %s", recipient, m.User, reason)}
+}
+
+// refreshBoard publishes the home tab of the user
+func (k *Kudos) refreshBoard(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := k.PublishHome(m.User); err != nil {
+		k.Logger.Printf("[%s] Error publishing home tab of [%s]: %v", KudosPluginName, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't refresh your kudos board :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: "Your kudos board is up to date in my *Home* tab :trophy:", Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+}
+
+// giveKudos records kudos and refreshes the home tab of both the giver and recipient. The error returned, if any,
+// is meant to be shown to the giver
+func (k *Kudos) giveKudos(giverID string, recipientID string, reason string) (err error) {
+	if giverID == recipientID {
+		return fmt.Errorf("Nice try but you can't give yourself kudos :wink:")
+	}
+
+	if err = k.increment(kudosGivenSilo, giverID); err == nil {
+		err = k.increment(kudosReceivedSilo, recipientID)
+	}
+
+	if err == nil {
+		err = k.addRecent(recipientID, kudo{From: giverID, Reason: reason})
+	}
+
+	if err != nil {
+		k.Logger.Printf("[%s] Error recording kudos from [%s] to [%s]: %v", KudosPluginName, giverID, recipientID, err)
+		return fmt.Errorf("Sorry, I couldn't record those kudos :disappointed: (%v)", err)
+	}
+
+	for _, userID := range []string{giverID, recipientID} {
+		if err := k.PublishHome(userID); err != nil {
+			k.Logger.Printf("[%s] Error publishing home tab of [%s]: %v", KudosPluginName, userID, err)
+		}
+	}
+
+	return nil
+}
+
+// increment adds one to the count of a user in a silo
+func (k *Kudos) increment(silo string, userID string) (err error) {
+	count, err := k.count(silo, userID)
+	if err != nil {
+		return err
+	}
+
+	return k.storer.PutSiloString(silo, userID, strconv.Itoa(count+1))
+}
+
+// count returns the count of a user in a silo, defaulting to 0
+func (k *Kudos) count(silo string, userID string) (count int, err error) {
+	rawCount, err := k.storer.GetSiloString(silo, userID)
+	if err != nil {
+		return 0, nil
+	}
+
+	return strconv.Atoi(rawCount)
+}
+
+// addRecent adds a kudo to the most recent kudos received by a user, keeping only the last few
+func (k *Kudos) addRecent(userID string, received kudo) (err error) {
+	recent, err := k.recent(userID)
+	if err != nil {
+		return err
+	}
+
+	recent = append([]kudo{received}, recent...)
+	if len(recent) > defaultItemCount {
+		recent = recent[:defaultItemCount]
+	}
+
+	encoded, err := json.Marshal(recent)
+	if err != nil {
+		return err
+	}
+
+	return k.storer.PutSiloString(kudosRecentSilo, userID, string(encoded))
+}
+
+// recent returns the most recent kudos received by a user
+func (k *Kudos) recent(userID string) (recent []kudo, err error) {
+	recent = make([]kudo, 0)
+
+	encoded, err := k.storer.GetSiloString(kudosRecentSilo, userID)
+	if err != nil {
+		return recent, nil
+	}
+
+	err = json.Unmarshal([]byte(encoded), &recent)
+	return recent, err
+}
+
+// PublishHome publishes the kudos dashboard of a user in the bot's home tab
+func (k *Kudos) PublishHome(userID string) (err error) {
+	view, err := k.homeView(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.SlackClient.PublishView(userID, view, "")
+	return err
+}
+
+// homeView renders the kudos dashboard of a user
+func (k *Kudos) homeView(userID string) (view slack.HomeTabViewRequest, err error) {
+	received, err := k.count(kudosReceivedSilo, userID)
+	if err != nil {
+		return view, err
+	}
+
+	given, err := k.count(kudosGivenSilo, userID)
+	if err != nil {
+		return view, err
+	}
+
+	recent, err := k.recent(userID)
+	if err != nil {
+		return view, err
+	}
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "*Your kudos* :trophy:", false, false), []*slack.TextBlockObject{
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Received*\n%d", received), false, false),
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Given*\n%d", given), false, false),
+		}, nil),
+		slack.NewDividerBlock(),
+	}
+
+	if len(recent) == 0 {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "No kudos received yet, keep up the good work :muscle:", false, false), nil, nil))
+	} else {
+		lines := make([]string, 0, len(recent))
+		for _, r := range recent {
+			if r.Reason == "" {
+				lines = append(lines, fmt.Sprintf("• From <@%s>", r.From))
+			} else {
+				lines = append(lines, fmt.Sprintf("• From <@%s> for %s", r.From, r.Reason))
+			}
+		}
+
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Recent kudos*\n%s", strings.Join(lines, "\n")), false, false), nil, nil))
+	}
+
+	blocks = append(blocks, slack.NewActionBlock("", slack.NewButtonBlockElement(KudosGiveActionID, "", slack.NewTextBlockObject(slack.PlainTextType, "Give kudos", true, false))))
+
+	return slack.HomeTabViewRequest{Type: slack.VTHomeTab, Blocks: slack.Blocks{BlockSet: blocks}}, nil
+}
+
+// OpenGiveKudosModal opens the modal to give kudos in response to a click on the home tab button identified by KudosGiveActionID
+func (k *Kudos) OpenGiveKudosModal(triggerID string) (err error) {
+	recipient := slack.NewOptionsSelectBlockElement(slack.OptTypeUser, slack.NewTextBlockObject(slack.PlainTextType, "Pick someone", false, false), kudosRecipientAction)
+	reason := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, "What did they do?", false, false), kudosReasonAction)
+
+	modal := slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: KudosModalCallbackID,
+		Title:      slack.NewTextBlockObject(slack.PlainTextType, "Give kudos", false, false),
+		Submit:     slack.NewTextBlockObject(slack.PlainTextType, "Send", false, false),
+		Close:      slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(kudosRecipientBlock, slack.NewTextBlockObject(slack.PlainTextType, "Kudos to", false, false), recipient),
+			slack.NewInputBlock(kudosReasonBlock, slack.NewTextBlockObject(slack.PlainTextType, "For", false, false), reason),
+		}},
+	}
+
+	_, err = k.SlackClient.OpenView(triggerID, modal)
+	return err
+}
+
+// SubmitKudos records kudos given via the modal identified by KudosModalCallbackID
+func (k *Kudos) SubmitKudos(giverID string, state *slack.ViewState) (err error) {
+	if state == nil {
+		return fmt.Errorf("Missing kudos values")
+	}
+
+	recipientID := state.Values[kudosRecipientBlock][kudosRecipientAction].SelectedUser
+	if recipientID == "" {
+		return fmt.Errorf("Missing kudos recipient")
+	}
+
+	return k.giveKudos(giverID, recipientID, strings.TrimSpace(state.Values[kudosReasonBlock][kudosReasonAction].Value))
+}
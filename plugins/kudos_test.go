@@ -0,0 +1,137 @@
+package plugins_test
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type publishedViews struct {
+	sync.Mutex
+	byUserID map[string]string
+}
+
+func newViewsServer(published *publishedViews) (testServer *slacktest.Server) {
+	testServer = slacktest.NewTestServer(func(c slacktest.Customize) {
+		c.Handle("/views.publish", func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				UserID string          `json:"user_id"`
+				View   json.RawMessage `json:"view"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+
+			published.Lock()
+			published.byUserID[req.UserID] = string(req.View)
+			published.Unlock()
+
+			_, _ = w.Write([]byte(`{"ok": true, "view": {"id": "V1"}}`))
+		})
+	})
+	testServer.Start()
+
+	return testServer
+}
+
+func TestKudosPublishesHomeTabs(t *testing.T) {
+	published := publishedViews{byUserID: make(map[string]string)}
+	testServer := newViewsServer(&published)
+	defer testServer.Stop()
+
+	storer, cleanup := plugins.NewTestStorer(t, "kudosTest")
+	defer cleanup()
+
+	k := plugins.NewKudos(storer)
+	k.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(k.Plugin, &slack.Msg{Channel: "Cgeneral", User: "Ugiver", Text: "<@bot> kudos <@Ualice> for shipping the release"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":clap: Kudos to <@Ualice> from <@Ugiver> for shipping the release")
+	})
+
+	assertplugin.AnswersAndReacts(k.Plugin, &slack.Msg{Channel: "Cgeneral", User: "Ubob", Text: "<@bot> kudos <@Ualice>"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":clap: Kudos to <@Ualice> from <@Ubob>!")
+	})
+
+	published.Lock()
+	defer published.Unlock()
+
+	require.Contains(t, published.byUserID, "Ualice")
+	alice := published.byUserID["Ualice"]
+	assert.Contains(t, alice, `"type":"home"`)
+	assert.Contains(t, alice, "*Received*\\n2")
+	assert.Contains(t, alice, "*Given*\\n0")
+	assert.Contains(t, alice, "• From \\u003c@Ubob\\u003e\\n• From \\u003c@Ugiver\\u003e for shipping the release")
+	assert.Contains(t, alice, `"action_id":"kudos.give"`)
+
+	require.Contains(t, published.byUserID, "Ugiver")
+	assert.Contains(t, published.byUserID["Ugiver"], "*Given*\\n1")
+	assert.Contains(t, published.byUserID["Ugiver"], "No kudos received yet")
+}
+
+func TestKudosToSelf(t *testing.T) {
+	storer, cleanup := plugins.NewTestStorer(t, "kudosTest")
+	defer cleanup()
+
+	k := plugins.NewKudos(storer)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(k.Plugin, &slack.Msg{Channel: "Cgeneral", User: "Ualice", Text: "<@bot> kudos <@Ualice> for being awesome"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Nice try but you can't give yourself kudos :wink:")
+	})
+}
+
+func TestKudosBoardRefresh(t *testing.T) {
+	published := publishedViews{byUserID: make(map[string]string)}
+	testServer := newViewsServer(&published)
+	defer testServer.Stop()
+
+	storer, cleanup := plugins.NewTestStorer(t, "kudosTest")
+	defer cleanup()
+
+	k := plugins.NewKudos(storer)
+	k.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(k.Plugin, &slack.Msg{Channel: "Cgeneral", User: "Ualice", Text: "<@bot> kudos board"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Your kudos board is up to date in my *Home* tab :trophy:")
+	})
+
+	published.Lock()
+	defer published.Unlock()
+	assert.True(t, strings.Contains(published.byUserID["Ualice"], "*Received*\\n0"))
+}
+
+func TestKudosSubmittedFromModal(t *testing.T) {
+	published := publishedViews{byUserID: make(map[string]string)}
+	testServer := newViewsServer(&published)
+	defer testServer.Stop()
+
+	storer, cleanup := plugins.NewTestStorer(t, "kudosTest")
+	defer cleanup()
+
+	k := plugins.NewKudos(storer)
+	k.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	state := slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+		"kudos.recipient": {"kudos.recipient.select": {SelectedUser: "Ualice"}},
+		"kudos.reason":    {"kudos.reason.input": {Value: " fixing the build "}},
+	}}
+
+	require.NoError(t, k.SubmitKudos("Ubob", &state))
+
+	published.Lock()
+	defer published.Unlock()
+	assert.Contains(t, published.byUserID["Ualice"], "• From \\u003c@Ubob\\u003e for fixing the build")
+
+	assert.EqualError(t, k.SubmitKudos("Ubob", &slack.ViewState{}), "Missing kudos recipient")
+}
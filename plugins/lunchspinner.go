@@ -0,0 +1,314 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LunchSpinnerPluginName holds identifying name for the lunch spinner plugin
+	LunchSpinnerPluginName = "lunchSpinner"
+)
+
+// Configuration keys
+const (
+	lunchRecencyKey = "recency" // How long a winner is de-prioritized for, defaults to 7 days (168h)
+)
+
+const (
+	defaultLunchRecency = 7 * 24 * time.Hour
+	minLunchWeight      = 0.1
+	lunchOptionsSiloFmt = "options:%s"
+	lunchPicksSiloFmt   = "picks:%s"
+)
+
+var lunchAddRegex = regexp.MustCompile("(?i)\\Alunch add (.+)\\z")
+var lunchRemoveRegex = regexp.MustCompile("(?i)\\Alunch remove (.+)\\z")
+
+// lunchPicks holds the history of an option's picks
+type lunchPicks struct {
+	Count      int       `json:"count"`
+	LastPicked time.Time `json:"lastPicked"`
+}
+
+// LunchSpinner holds the plugin data for the lunch spinner plugin
+type LunchSpinner struct {
+	*slackscot.Plugin
+	storer  store.GlobalSiloStringStorer
+	recency time.Duration
+	now     func() time.Time
+
+	// Guards the random source which isn't safe for concurrent use
+	sync.Mutex
+	random *rand.Rand
+}
+
+// NewLunchSpinner creates a new instance of the lunch spinner plugin. Each channel keeps its own list of options
+// to spin from. Recent winners are less likely to be picked again until the recency period is over
+func NewLunchSpinner(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	ls, err := newLunchSpinner(c, storer, time.Now, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if err != nil {
+		return nil, err
+	}
+
+	return ls.Plugin, nil
+}
+
+// newLunchSpinner creates a new instance of the lunch spinner plugin with the given function to get the current time and random source
+func newLunchSpinner(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time, random *rand.Rand) (ls *LunchSpinner, err error) {
+	c.SetDefault(lunchRecencyKey, defaultLunchRecency)
+
+	ls = new(LunchSpinner)
+	ls.storer = storer
+	ls.now = now
+	ls.random = random
+	ls.recency = c.GetDuration(lunchRecencyKey)
+
+	if ls.recency < 0 {
+		return nil, fmt.Errorf("Invalid %s recency [%s], it should be 0 or more", LunchSpinnerPluginName, ls.recency)
+	}
+
+	ls.Plugin = plugin.New(LunchSpinnerPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return lunchAddRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("lunch add <option>").
+			WithDescription("Add an option to this channel's lunch wheel").
+			WithAnswerer(ls.addOption).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return lunchRemoveRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("lunch remove <option>").
+			WithDescription("Remove an option from this channel's lunch wheel").
+			WithAnswerer(ls.removeOption).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.EqualFold(m.NormalizedText, "lunch options")
+			}).
+			WithUsage("lunch options").
+			WithDescription("List the options of this channel's lunch wheel").
+			WithAnswerer(ls.listOptions).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.EqualFold(m.NormalizedText, "lunch spin")
+			}).
+			WithUsage("lunch spin").
+			WithDescription("Spin the wheel to decide where to go for lunch").
+			WithAnswerer(ls.spin).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.EqualFold(m.NormalizedText, "lunch stats")
+			}).
+			WithUsage("lunch stats").
+			WithDescription("Show how often each option was picked").
+			WithAnswerer(ls.stats).
+			Build()).
+		Build()
+
+	return ls, nil
+}
+
+// addOption adds an option to the channel's wheel
+func (ls *LunchSpinner) addOption(m *slackscot.IncomingMessage) *slackscot.Answer {
+	option := strings.TrimSpace(lunchAddRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	if err := ls.storer.PutSiloString(fmt.Sprintf(lunchOptionsSiloFmt, m.Channel), strings.ToLower(option), option); err != nil {
+		ls.Logger.Printf("[%s] Error adding option [%s] on [%s]: %v", LunchSpinnerPluginName, option, m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't add `%s` :disappointed: (%v)", option, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Added `%s` to the wheel :knife_fork_plate:", option)}
+}
+
+// removeOption removes an option from the channel's wheel
+func (ls *LunchSpinner) removeOption(m *slackscot.IncomingMessage) *slackscot.Answer {
+	option := strings.TrimSpace(lunchRemoveRegex.FindStringSubmatch(m.NormalizedText)[1])
+	silo := fmt.Sprintf(lunchOptionsSiloFmt, m.Channel)
+
+	if _, err := ls.storer.GetSiloString(silo, strings.ToLower(option)); err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("`%s` isn't on the wheel :shrug:", option)}
+	}
+
+	if err := ls.storer.DeleteSiloString(silo, strings.ToLower(option)); err != nil {
+		ls.Logger.Printf("[%s] Error removing option [%s] on [%s]: %v", LunchSpinnerPluginName, option, m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't remove `%s` :disappointed: (%v)", option, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Removed `%s` from the wheel", option)}
+}
+
+// listOptions answers with the options of the channel's wheel
+func (ls *LunchSpinner) listOptions(m *slackscot.IncomingMessage) *slackscot.Answer {
+	options, err := ls.options(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the options :disappointed: (%v)", err)}
+	}
+
+	if len(options) == 0 {
+		return &slackscot.Answer{Text: "The wheel is empty, add options with `lunch add <option>`"}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("*Lunch options* :knife_fork_plate:\n• %s", strings.Join(options, "\n• "))}
+}
+
+// spin picks an option at random, de-prioritizing recent winners, and records the pick
+func (ls *LunchSpinner) spin(m *slackscot.IncomingMessage) *slackscot.Answer {
+	options, err := ls.options(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the options :disappointed: (%v)", err)}
+	}
+
+	if len(options) == 0 {
+		return &slackscot.Answer{Text: "The wheel is empty, add options with `lunch add <option>`"}
+	}
+
+	picks, err := ls.picks(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load past picks :disappointed: (%v)", err)}
+	}
+
+	now := ls.now()
+	weights := make([]float64, len(options))
+	for i, option := range options {
+		weights[i] = lunchWeight(picks[strings.ToLower(option)], now, ls.recency)
+	}
+
+	ls.Lock()
+	winner := options[pickWeighted(weights, ls.random.Float64())]
+	ls.Unlock()
+
+	p := picks[strings.ToLower(winner)]
+	p.Count = p.Count + 1
+	p.LastPicked = now
+
+	encoded, err := json.Marshal(p)
+	if err == nil {
+		err = ls.storer.PutSiloString(fmt.Sprintf(lunchPicksSiloFmt, m.Channel), strings.ToLower(winner), string(encoded))
+	}
+
+	if err != nil {
+		ls.Logger.Printf("[%s] Error recording pick of [%s] on [%s]: %v", LunchSpinnerPluginName, winner, m.Channel, err)
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf(":ferris_wheel: The wheel has spoken: *%s*!", winner)}
+}
+
+// stats answers with the number of times each option was picked
+func (ls *LunchSpinner) stats(m *slackscot.IncomingMessage) *slackscot.Answer {
+	options, err := ls.options(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the options :disappointed: (%v)", err)}
+	}
+
+	picks, err := ls.picks(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load past picks :disappointed: (%v)", err)}
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return picks[strings.ToLower(options[i])].Count > picks[strings.ToLower(options[j])].Count
+	})
+
+	lines := make([]string, 0)
+	for _, option := range options {
+		if p := picks[strings.ToLower(option)]; p.Count > 0 {
+			lines = append(lines, fmt.Sprintf("• %s: picked %d time(s), last on %s", option, p.Count, p.LastPicked.Format("2006-01-02")))
+		}
+	}
+
+	if len(lines) == 0 {
+		return &slackscot.Answer{Text: "Nothing was picked yet, spin with `lunch spin`"}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("*Lunch stats* :bar_chart:\n%s", strings.Join(lines, "\n"))}
+}
+
+// options returns the options of a channel's wheel, sorted by name
+func (ls *LunchSpinner) options(channelID string) (options []string, err error) {
+	entries, err := ls.storer.ScanSilo(fmt.Sprintf(lunchOptionsSiloFmt, channelID))
+	if err != nil {
+		ls.Logger.Printf("[%s] Error loading options of [%s]: %v", LunchSpinnerPluginName, channelID, err)
+		return nil, err
+	}
+
+	options = make([]string, 0, len(entries))
+	for _, option := range entries {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	return options, nil
+}
+
+// picks returns the picks of a channel's options keyed by their lowercased name
+func (ls *LunchSpinner) picks(channelID string) (picks map[string]lunchPicks, err error) {
+	entries, err := ls.storer.ScanSilo(fmt.Sprintf(lunchPicksSiloFmt, channelID))
+	if err != nil {
+		ls.Logger.Printf("[%s] Error loading picks of [%s]: %v", LunchSpinnerPluginName, channelID, err)
+		return nil, err
+	}
+
+	picks = make(map[string]lunchPicks)
+	for option, encoded := range entries {
+		var p lunchPicks
+		if err := json.Unmarshal([]byte(encoded), &p); err != nil {
+			ls.Logger.Printf("[%s] Ignoring invalid picks of [%s] on [%s]: %v", LunchSpinnerPluginName, option, channelID, err)
+			continue
+		}
+
+		picks[option] = p
+	}
+
+	return picks, nil
+}
+
+// lunchWeight returns the weight of an option given its picks. Options never picked (or not picked within the recency period)
+// have a weight of 1 and the weight of the most recent winners goes down to minLunchWeight
+func lunchWeight(p lunchPicks, now time.Time, recency time.Duration) (weight float64) {
+	elapsed := now.Sub(p.LastPicked)
+	if p.Count == 0 || recency == 0 || elapsed >= recency {
+		return 1
+	}
+
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return minLunchWeight + (1-minLunchWeight)*float64(elapsed)/float64(recency)
+}
+
+// pickWeighted returns the index picked by the roll (in [0, 1)) with each index having a chance proportional to its weight
+func pickWeighted(weights []float64, roll float64) (index int) {
+	total := 0.
+	for _, w := range weights {
+		total = total + w
+	}
+
+	target := roll * total
+	for i, w := range weights {
+		if target < w {
+			return i
+		}
+
+		target = target - w
+	}
+
+	return len(weights) - 1
+}
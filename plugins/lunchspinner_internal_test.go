@@ -0,0 +1,73 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLunchWeight(t *testing.T) {
+	now := time.Date(2023, time.March, 14, 12, 0, 0, 0, time.UTC)
+	recency := 10 * 24 * time.Hour
+
+	assert.Equal(t, 1., lunchWeight(lunchPicks{}, now, recency))
+	assert.Equal(t, minLunchWeight, lunchWeight(lunchPicks{Count: 1, LastPicked: now}, now, recency))
+	assert.InDelta(t, 0.55, lunchWeight(lunchPicks{Count: 1, LastPicked: now.AddDate(0, 0, -5)}, now, recency), 0.0001)
+	assert.Equal(t, 1., lunchWeight(lunchPicks{Count: 3, LastPicked: now.AddDate(0, 0, -10)}, now, recency))
+	assert.Equal(t, 1., lunchWeight(lunchPicks{Count: 3, LastPicked: now}, now, 0))
+}
+
+func TestPickWeighted(t *testing.T) {
+	weights := []float64{0.1, 1, 1}
+
+	assert.Equal(t, 0, pickWeighted(weights, 0))
+	assert.Equal(t, 0, pickWeighted(weights, 0.04))
+	assert.Equal(t, 1, pickWeighted(weights, 0.05))
+	assert.Equal(t, 1, pickWeighted(weights, 0.5))
+	assert.Equal(t, 2, pickWeighted(weights, 0.53))
+	assert.Equal(t, 2, pickWeighted(weights, 0.9999))
+}
+
+func TestLunchSpinnerDeprioritizesRecentWinners(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("lunchSpinnerTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	require.NoError(t, storer.PutSiloString("options:Cfood", "thai", "Thai"))
+	require.NoError(t, storer.PutSiloString("options:Cfood", "sushi", "Sushi"))
+
+	now := time.Date(2023, time.March, 14, 12, 0, 0, 0, time.UTC)
+	ls, err := newLunchSpinner(viper.New(), storer, func() time.Time { return now }, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	winners := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		assertplugin.AnswersAndReacts(ls.Plugin, &slack.Msg{Channel: "Cfood", User: "U21355", Text: "<@bot> lunch spin"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			winners[answers[0].Text] = winners[answers[0].Text] + 1
+			return assert.Len(t, answers, 1)
+		})
+	}
+
+	// Since spins all happen at the same time, winners keep getting a weight of 0.1 against the other option
+	// which makes it unlikely for the same option to keep winning
+	assert.InDelta(t, 50, winners[":ferris_wheel: The wheel has spoken: *Thai*!"], 15)
+	assert.InDelta(t, 50, winners[":ferris_wheel: The wheel has spoken: *Sushi*!"], 15)
+
+	assertplugin.AnswersAndReacts(ls.Plugin, &slack.Msg{Channel: "Cfood", User: "U21355", Text: "<@bot> lunch stats"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assert.Contains(t, answers[0].Text, "*Lunch stats* :bar_chart:\n• ") && assert.Contains(t, answers[0].Text, "last on 2023-03-14")
+	})
+}
@@ -0,0 +1,64 @@
+package plugins_test
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestLunchSpinnerOptions(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("lunchSpinnerTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	p, err := plugins.NewLunchSpinner(viper.New(), storer)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		channel        string
+		text           string
+		expectedAnswer string
+	}{
+		{"Cfood", "<@bot> lunch spin", "The wheel is empty, add options with `lunch add <option>`"},
+		{"Cfood", "<@bot> lunch add Thai", "Added `Thai` to the wheel :knife_fork_plate:"},
+		{"Cfood", "<@bot> lunch add Burritos", "Added `Burritos` to the wheel :knife_fork_plate:"},
+		{"Cfood", "<@bot> lunch add Sushi", "Added `Sushi` to the wheel :knife_fork_plate:"},
+		{"Cother", "<@bot> lunch options", "The wheel is empty, add options with `lunch add <option>`"},
+		{"Cfood", "<@bot> lunch options", "*Lunch options* :knife_fork_plate:\n• Burritos\n• Sushi\n• Thai"},
+		{"Cfood", "<@bot> lunch remove burritos", "Removed `burritos` from the wheel"},
+		{"Cfood", "<@bot> lunch remove pizza", "`pizza` isn't on the wheel :shrug:"},
+		{"Cfood", "<@bot> lunch remove sushi", "Removed `sushi` from the wheel"},
+		{"Cfood", "<@bot> lunch stats", "Nothing was picked yet, spin with `lunch spin`"},
+		{"Cfood", "<@bot> lunch spin", ":ferris_wheel: The wheel has spoken: *Thai*!"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin := assertplugin.New(t, "bot")
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: tc.channel, User: "U21355", Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
+
+func TestLunchSpinnerInvalidRecency(t *testing.T) {
+	c := viper.New()
+	c.Set("recency", "-1h")
+
+	_, err := plugins.NewLunchSpinner(c, nil)
+	assert.EqualError(t, err, "Invalid lunchSpinner recency [-1h0m0s], it should be 0 or more")
+}
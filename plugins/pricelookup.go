@@ -0,0 +1,305 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PriceLookupPluginName holds identifying name for the price lookup plugin
+	PriceLookupPluginName = "priceLookup"
+)
+
+// Configuration keys
+const (
+	priceCacheTTLKey                  = "cacheTTL"                  // How long quotes are cached for, duration. Defaults to 1 minute. A value of 0 disables caching
+	priceAlertCheckIntervalMinutesKey = "alertCheckIntervalMinutes" // How often price alerts are checked, in minutes. Defaults to 5
+)
+
+const (
+	defaultPriceCacheTTL                  = time.Minute
+	defaultPriceAlertCheckIntervalMinutes = 5
+	priceAlertAbove                       = "above"
+	priceAlertBelow                       = "below"
+)
+
+var priceRegex = regexp.MustCompile("(?i)\\Aprice ([\\w.-]{1,12})\\s*\\z")
+var priceAlertRegex = regexp.MustCompile("(?i)\\Aprice alert ([\\w.-]{1,12}) (above|below) (\\d+(?:\\.\\d+)?)\\s*\\z")
+var forgetPriceAlertsRegex = regexp.MustCompile("(?i)\\Aforget price alerts ([\\w.-]{1,12})\\s*\\z")
+
+// Quote holds the price of a stock or crypto currency
+type Quote struct {
+	Symbol   string
+	Price    float64
+	Currency string
+}
+
+// PriceProvider is implemented by price data providers (i.e. a client of a market data API)
+type PriceProvider interface {
+	// GetQuote returns the current quote for a symbol (i.e. AAPL or BTC)
+	GetQuote(symbol string) (quote Quote, err error)
+}
+
+// PriceProviderFunc is an adapter to allow the use of a function as a PriceProvider
+type PriceProviderFunc func(symbol string) (quote Quote, err error)
+
+// GetQuote calls f(symbol)
+func (f PriceProviderFunc) GetQuote(symbol string) (quote Quote, err error) {
+	return f(symbol)
+}
+
+// cachedQuote is a quote along with the time it was fetched at
+type cachedQuote struct {
+	quote     Quote
+	fetchedAt time.Time
+}
+
+// PriceLookup holds the plugin data for the price lookup plugin
+type PriceLookup struct {
+	*slackscot.Plugin
+	provider PriceProvider
+	storer   store.GlobalSiloStringStorer
+	cacheTTL time.Duration
+
+	cacheLock sync.Mutex
+	cache     map[string]cachedQuote
+}
+
+// NewPriceLookup creates a new instance of the price lookup plugin. Quotes come from the PriceProvider and are cached
+// for the configured TTL. Price alerts are persisted with the storer and checked on a schedule
+func NewPriceLookup(c *config.PluginConfig, provider PriceProvider, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	c.SetDefault(priceCacheTTLKey, defaultPriceCacheTTL)
+	c.SetDefault(priceAlertCheckIntervalMinutesKey, defaultPriceAlertCheckIntervalMinutes)
+
+	checkInterval := c.GetInt(priceAlertCheckIntervalMinutesKey)
+	if checkInterval <= 0 {
+		return nil, fmt.Errorf("Invalid %s config key value for %s: [%d], should be greater than 0", PriceLookupPluginName, priceAlertCheckIntervalMinutesKey, checkInterval)
+	}
+
+	pl := new(PriceLookup)
+	pl.provider = provider
+	pl.storer = storer
+	pl.cacheTTL = c.GetDuration(priceCacheTTLKey)
+	pl.cache = make(map[string]cachedQuote)
+
+	pl.Plugin = plugin.New(PriceLookupPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return priceRegex.MatchString(m.NormalizedText) }).
+			WithUsage("price <symbol>").
+			WithDescription("Looks up the current price of a stock or crypto currency").
+			WithAnswerer(pl.answerPrice).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return priceAlertRegex.MatchString(m.NormalizedText) }).
+			WithUsage("price alert <symbol> above|below <price>").
+			WithDescription("Alerts this channel when the price of `symbol` goes above or below `price`").
+			WithAnswerer(pl.registerAlert).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "list price alerts")
+			}).
+			WithUsage("list price alerts").
+			WithDescription("Lists the price alerts of this channel").
+			WithAnswerer(pl.listAlerts).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return forgetPriceAlertsRegex.MatchString(m.NormalizedText) }).
+			WithUsage("forget price alerts <symbol>").
+			WithDescription("Deletes all price alerts of this channel for `symbol`").
+			WithAnswerer(pl.forgetAlerts).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithInterval(uint64(checkInterval), schedule.Minutes).Build()).
+			WithDescription("Check price alerts and notify channels of the ones reached").
+			WithAction(pl.checkAlerts).
+			Build()).
+		Build()
+
+	return pl.Plugin, nil
+}
+
+// getQuote returns the quote for a symbol from the cache if it's fresh enough or from the provider otherwise
+func (pl *PriceLookup) getQuote(symbol string) (quote Quote, err error) {
+	pl.cacheLock.Lock()
+	cached, ok := pl.cache[symbol]
+	pl.cacheLock.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < pl.cacheTTL {
+		return cached.quote, nil
+	}
+
+	quote, err = pl.provider.GetQuote(symbol)
+	if err != nil {
+		return quote, err
+	}
+
+	if pl.cacheTTL > 0 {
+		pl.cacheLock.Lock()
+		pl.cache[symbol] = cachedQuote{quote: quote, fetchedAt: time.Now()}
+		pl.cacheLock.Unlock()
+	}
+
+	return quote, nil
+}
+
+// answerPrice answers with the current price of a symbol
+func (pl *PriceLookup) answerPrice(m *slackscot.IncomingMessage) *slackscot.Answer {
+	symbol := strings.ToUpper(priceRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	quote, err := pl.getQuote(symbol)
+	if err != nil {
+		pl.Logger.Printf("[%s] Error getting quote for [%s]: %v", PriceLookupPluginName, symbol, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't get the price of `%s` :disappointed: (%v)", symbol, err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("`%s`: %s", quote.Symbol, formatQuotePrice(quote))}
+}
+
+// registerAlert registers a price alert for the channel
+func (pl *PriceLookup) registerAlert(m *slackscot.IncomingMessage) *slackscot.Answer {
+	match := priceAlertRegex.FindStringSubmatch(m.NormalizedText)
+	symbol, direction, threshold := strings.ToUpper(match[1]), strings.ToLower(match[2]), match[3]
+
+	if err := pl.storer.PutSiloString(m.Channel, encodePriceAlert(symbol, direction, threshold), m.User); err != nil {
+		pl.Logger.Printf("[%s] Error persisting price alert: %v", PriceLookupPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't register the alert :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("I'll let you know when `%s` goes %s %s :bell:", symbol, direction, threshold)}
+}
+
+// listAlerts answers with the price alerts registered for the channel
+func (pl *PriceLookup) listAlerts(m *slackscot.IncomingMessage) *slackscot.Answer {
+	entries, err := pl.storer.ScanSilo(m.Channel)
+	if err != nil {
+		pl.Logger.Printf("[%s] Error loading price alerts: %v", PriceLookupPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the alerts :disappointed: (%v)", err)}
+	}
+
+	if len(entries) == 0 {
+		return &slackscot.Answer{Text: "There are no price alerts for this channel"}
+	}
+
+	alerts := make([]string, 0, len(entries))
+	for key, userID := range entries {
+		if symbol, direction, threshold, ok := decodePriceAlert(key); ok {
+			alerts = append(alerts, fmt.Sprintf("• `%s` %s %s (set by <@%s>)", symbol, direction, threshold, userID))
+		}
+	}
+	sort.Strings(alerts)
+
+	return &slackscot.Answer{Text: fmt.Sprintf("*Price alerts* :bell:\n%s", strings.Join(alerts, "\n"))}
+}
+
+// forgetAlerts deletes all the price alerts registered for a symbol in the channel
+func (pl *PriceLookup) forgetAlerts(m *slackscot.IncomingMessage) *slackscot.Answer {
+	symbol := strings.ToUpper(forgetPriceAlertsRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	entries, err := pl.storer.ScanSilo(m.Channel)
+	if err != nil {
+		pl.Logger.Printf("[%s] Error loading price alerts: %v", PriceLookupPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the alerts :disappointed: (%v)", err)}
+	}
+
+	deleted := 0
+	for key := range entries {
+		if s, _, _, ok := decodePriceAlert(key); ok && s == symbol {
+			if err := pl.storer.DeleteSiloString(m.Channel, key); err != nil {
+				pl.Logger.Printf("[%s] Error deleting price alert [%s]: %v", PriceLookupPluginName, key, err)
+				continue
+			}
+
+			deleted++
+		}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Deleted %d price alert(s) for `%s`", deleted, symbol)}
+}
+
+// checkAlerts checks all price alerts and notifies channels of the ones reached. Reached alerts are deleted
+// so that they only fire once
+func (pl *PriceLookup) checkAlerts() {
+	alertsByChannel, err := pl.storer.GlobalScan()
+	if err != nil {
+		pl.Logger.Printf("[%s] Error loading price alerts: %v", PriceLookupPluginName, err)
+		return
+	}
+
+	channels := make([]string, 0, len(alertsByChannel))
+	for channelID := range alertsByChannel {
+		channels = append(channels, channelID)
+	}
+	sort.Strings(channels)
+
+	for _, channelID := range channels {
+		keys := make([]string, 0, len(alertsByChannel[channelID]))
+		for key := range alertsByChannel[channelID] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			pl.checkAlert(channelID, key, alertsByChannel[channelID][key])
+		}
+	}
+}
+
+// checkAlert checks a single price alert and notifies the channel if it's reached
+func (pl *PriceLookup) checkAlert(channelID string, key string, userID string) {
+	symbol, direction, rawThreshold, ok := decodePriceAlert(key)
+	if !ok {
+		return
+	}
+
+	threshold, err := strconv.ParseFloat(rawThreshold, 64)
+	if err != nil {
+		return
+	}
+
+	quote, err := pl.getQuote(symbol)
+	if err != nil {
+		pl.Logger.Printf("[%s] Error getting quote for [%s]: %v", PriceLookupPluginName, symbol, err)
+		return
+	}
+
+	if (direction == priceAlertAbove && quote.Price > threshold) || (direction == priceAlertBelow && quote.Price < threshold) {
+		message := fmt.Sprintf(":rotating_light: <@%s> `%s` is now %s %s at %s", userID, symbol, direction, rawThreshold, formatQuotePrice(quote))
+		pl.RealTimeMsgSender.SendMessage(pl.RealTimeMsgSender.NewOutgoingMessage(message, channelID))
+
+		if err := pl.storer.DeleteSiloString(channelID, key); err != nil {
+			pl.Logger.Printf("[%s] Error deleting fired price alert [%s]: %v", PriceLookupPluginName, key, err)
+		}
+	}
+}
+
+// formatQuotePrice renders the price of a quote with its currency, if any
+func formatQuotePrice(quote Quote) (rendered string) {
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", quote.Price, quote.Currency))
+}
+
+// encodePriceAlert returns the key under which a price alert is persisted
+func encodePriceAlert(symbol string, direction string, threshold string) (key string) {
+	return fmt.Sprintf("%s:%s:%s", symbol, direction, threshold)
+}
+
+// decodePriceAlert returns the components of a persisted price alert key
+func decodePriceAlert(key string) (symbol string, direction string, threshold string, ok bool) {
+	parts := strings.Split(key, ":")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+
+	return parts[0], parts[1], parts[2], true
+}
@@ -0,0 +1,162 @@
+package plugins_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+type fakePriceProvider struct {
+	prices map[string]float64
+	calls  int
+}
+
+func (f *fakePriceProvider) GetQuote(symbol string) (quote plugins.Quote, err error) {
+	f.calls++
+
+	price, ok := f.prices[symbol]
+	if !ok {
+		return quote, fmt.Errorf("unknown symbol")
+	}
+
+	return plugins.Quote{Symbol: symbol, Price: price, Currency: "USD"}, nil
+}
+
+func TestPriceLookup(t *testing.T) {
+	testCases := []struct {
+		text           string
+		expectedAnswer string
+	}{
+		{"<@bot> price aapl", "`AAPL`: 172.50 USD"},
+		{"<@bot> price BTC", "`BTC`: 64000.00 USD"},
+		{"<@bot> price NOPE", "Sorry, I couldn't get the price of `NOPE` :disappointed: (unknown symbol)"},
+		{"<@bot> price of everything", ""},
+	}
+
+	provider := &fakePriceProvider{prices: map[string]float64{"AAPL": 172.5, "BTC": 64000}}
+	p, err := plugins.NewPriceLookup(viper.New(), provider, nil)
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin := assertplugin.New(t, "bot")
+			assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				if tc.expectedAnswer == "" {
+					return assert.Empty(t, answers)
+				}
+
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
+
+func TestPriceLookupCachesQuotes(t *testing.T) {
+	provider := &fakePriceProvider{prices: map[string]float64{"AAPL": 172.5}}
+	p, err := plugins.NewPriceLookup(viper.New(), provider, nil)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	for i := 0; i < 3; i++ {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> price AAPL"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "`AAPL`: 172.50 USD")
+		})
+	}
+
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestPriceLookupWithoutCaching(t *testing.T) {
+	pc := viper.New()
+	pc.Set("cacheTTL", 0)
+
+	provider := &fakePriceProvider{prices: map[string]float64{"AAPL": 172.5}}
+	p, err := plugins.NewPriceLookup(pc, provider, nil)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	for i := 0; i < 3; i++ {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> price AAPL"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1)
+		})
+	}
+
+	assert.Equal(t, 3, provider.calls)
+}
+
+func TestPriceLookupInvalidAlertCheckInterval(t *testing.T) {
+	pc := viper.New()
+	pc.Set("alertCheckIntervalMinutes", 0)
+
+	_, err := plugins.NewPriceLookup(pc, &fakePriceProvider{}, nil)
+	assert.EqualError(t, err, "Invalid priceLookup config key value for alertCheckIntervalMinutes: [0], should be greater than 0")
+}
+
+func TestPriceAlerts(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("priceLookupTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	pc := viper.New()
+	pc.Set("cacheTTL", 0)
+	pc.Set("alertCheckIntervalMinutes", 10)
+
+	provider := &fakePriceProvider{prices: map[string]float64{"AAPL": 172.5, "BTC": 64000}}
+	p, err := plugins.NewPriceLookup(pc, provider, storer)
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> price alert aapl above 180"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "I'll let you know when `AAPL` goes above 180 :bell:")
+	})
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Ccrypto", User: "U21355", Text: "<@bot> price alert BTC below 60000.5"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> list price alerts"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "*Price alerts* :bell:\n• `AAPL` above 180 (set by <@U21355>)")
+	})
+
+	every10Minutes := schedule.New().WithInterval(10, schedule.Minutes).Build()
+	assertplugin.RunsOnSchedule(p, every10Minutes, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, sentMsgs)
+	})
+
+	provider.prices["AAPL"] = 181
+	provider.prices["BTC"] = 59000
+	assertplugin.RunsOnSchedule(p, every10Minutes, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{
+			"Cgeneral": {":rotating_light: <@U21355> `AAPL` is now above 180 at 181.00 USD"},
+			"Ccrypto":  {":rotating_light: <@U21355> `BTC` is now below 60000.5 at 59000.00 USD"},
+		}, sentMsgs)
+	})
+
+	// Alerts fire only once
+	assertplugin.RunsOnSchedule(p, every10Minutes, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, sentMsgs)
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> price alert AAPL below 100"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> forget price alerts aapl"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Deleted 1 price alert(s) for `AAPL`")
+	})
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> list price alerts"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "There are no price alerts for this channel")
+	})
+}
@@ -0,0 +1,345 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ProposalsPluginName holds identifying name for the proposals plugin
+	ProposalsPluginName = "proposals"
+)
+
+// Configuration keys
+const (
+	proposalsDurationKey = "duration" // Default voting duration of proposals, defaults to 24h
+	proposalsQuorumKey   = "quorum"   // Default minimum number of votes for a proposal to be decided, defaults to 3
+)
+
+const (
+	defaultProposalDuration = 24 * time.Hour
+	defaultProposalQuorum   = 3
+	proposalsSilo           = "proposals"
+	proposalVoteYes         = "yes"
+	proposalVoteNo          = "no"
+	proposalVoteActionFmt   = "proposals.vote.%s"
+	proposalAccepted        = "accepted"
+	proposalRejected        = "rejected"
+	proposalNoQuorum        = "no quorum"
+)
+
+var proposeRegex = regexp.MustCompile("(?i)\\Apropose '([^']+)'(?:\\s+for\\s+(\\d+)\\s*(hours?|days?))?(?:\\s+quorum\\s+(\\d+))?\\z")
+
+// proposal is a proposal voted on by a channel. Proposals are kept once closed as a record of their outcome
+// and of every action taken on them
+type proposal struct {
+	ID               string            `json:"id"`
+	Title            string            `json:"title"`
+	ChannelID        string            `json:"channelID"`
+	AuthorID         string            `json:"authorID"`
+	ClosesAt         time.Time         `json:"closesAt"`
+	Quorum           int               `json:"quorum"`
+	Votes            map[string]string `json:"votes"`
+	MessageTimestamp string            `json:"messageTimestamp,omitempty"`
+	Outcome          string            `json:"outcome,omitempty"`
+	Audit            []proposalAudit   `json:"audit"`
+}
+
+// proposalAudit is an action taken on a proposal
+type proposalAudit struct {
+	At     time.Time `json:"at"`
+	UserID string    `json:"userID,omitempty"`
+	Action string    `json:"action"`
+}
+
+// Proposals holds the plugin data for the proposals plugin
+type Proposals struct {
+	*slackscot.Plugin
+	storer          store.GlobalSiloStringStorer
+	defaultDuration time.Duration
+	defaultQuorum   int
+	now             func() time.Time
+
+	// Guards the read-modify-write of proposals since votes are received concurrently
+	sync.Mutex
+}
+
+// NewProposals creates a new instance of the proposals plugin. Teams open proposals that channel members vote on with
+// buttons until the voting closes. Button clicks are routed to the plugin by slackscot's interaction handler (see
+// slackscot.InteractionPath)
+func NewProposals(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	pp, err := newProposals(c, storer, time.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	return pp.Plugin, nil
+}
+
+// newProposals creates a new instance of the proposals plugin with the given function to get the current time
+func newProposals(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (pp *Proposals, err error) {
+	c.SetDefault(proposalsDurationKey, defaultProposalDuration)
+	c.SetDefault(proposalsQuorumKey, defaultProposalQuorum)
+
+	pp = new(Proposals)
+	pp.storer = storer
+	pp.now = now
+	pp.defaultDuration = c.GetDuration(proposalsDurationKey)
+	pp.defaultQuorum = c.GetInt(proposalsQuorumKey)
+
+	if pp.defaultDuration <= 0 {
+		return nil, fmt.Errorf("Invalid %s duration [%s], it should be more than 0", ProposalsPluginName, pp.defaultDuration)
+	}
+
+	pp.Plugin = plugin.New(ProposalsPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return proposeRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("propose '<title>' [for <n> hours|days] [quorum <n>]").
+			WithDescriptionf("Open a proposal for the channel to vote on (voting lasts %s with a quorum of %d votes unless specified)", pp.defaultDuration, pp.defaultQuorum).
+			WithAnswerer(pp.propose).
+			WithInteractionHandler(pp.receiveInteraction).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithInterval(1, schedule.Minutes).Build()).
+			WithDescription("Close proposals at the end of their voting and announce their outcome").
+			WithAction(pp.closeDueProposals).
+			Build()).
+		Build()
+
+	return pp, nil
+}
+
+// propose opens a new proposal and answers with its vote buttons
+func (pp *Proposals) propose(m *slackscot.IncomingMessage) *slackscot.Answer {
+	matches := proposeRegex.FindStringSubmatch(m.NormalizedText)
+
+	duration := pp.defaultDuration
+	if matches[2] != "" {
+		n, _ := strconv.Atoi(matches[2])
+		duration = time.Duration(n) * time.Hour
+		if strings.HasPrefix(strings.ToLower(matches[3]), "day") {
+			duration = duration * 24
+		}
+	}
+
+	quorum := pp.defaultQuorum
+	if matches[4] != "" {
+		quorum, _ = strconv.Atoi(matches[4])
+	}
+
+	if duration <= 0 {
+		return &slackscot.Answer{Text: "A proposal needs some time to be voted on :hourglass:"}
+	}
+
+	now := pp.now()
+	p := proposal{ID: fmt.Sprintf("%s-%s", m.Channel, m.Timestamp), Title: strings.TrimSpace(matches[1]), ChannelID: m.Channel, AuthorID: m.User, ClosesAt: now.Add(duration), Quorum: quorum, Votes: make(map[string]string)}
+	p.Audit = append(p.Audit, proposalAudit{At: now, UserID: m.User, Action: "opened"})
+
+	pp.Lock()
+	defer pp.Unlock()
+
+	if err := pp.saveProposal(p); err != nil {
+		pp.Logger.Printf("[%s] Error saving proposal [%s]: %v", ProposalsPluginName, p.ID, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't open the proposal :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Proposal: %s", p.Title), ContentBlocks: renderProposal(p), InteractiveElements: renderVoteButtons(p)}
+}
+
+// receiveInteraction records votes from clicks on the proposals' buttons
+func (pp *Proposals) receiveInteraction(i *slackscot.Interaction) *slack.ViewSubmissionResponse {
+	if i.Type != slack.InteractionTypeBlockActions {
+		return nil
+	}
+
+	for _, action := range i.ActionCallback.BlockActions {
+		for _, choice := range []string{proposalVoteYes, proposalVoteNo} {
+			if action.ActionID == fmt.Sprintf(proposalVoteActionFmt, choice) {
+				pp.vote(action.Value, i.User.ID, choice, i.Message.Timestamp, i.CallbackID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// vote records the vote of a user on an open proposal and refreshes the proposal's message with the new tally, keeping
+// the identifier of its vote buttons' block
+func (pp *Proposals) vote(proposalID string, userID string, choice string, messageTimestamp string, blockID string) {
+	pp.Lock()
+	defer pp.Unlock()
+
+	p, err := pp.loadProposal(proposalID)
+	if err != nil {
+		pp.Logger.Printf("[%s] Error loading proposal [%s]: %v", ProposalsPluginName, proposalID, err)
+		return
+	}
+
+	now := pp.now()
+	if p.Outcome != "" || !now.Before(p.ClosesAt) {
+		pp.Logger.Debugf("[%s] Ignoring vote of [%s] on closed proposal [%s]", ProposalsPluginName, userID, proposalID)
+		return
+	}
+
+	if p.Votes[userID] == choice {
+		return
+	}
+
+	action := fmt.Sprintf("voted %s", choice)
+	if _, voted := p.Votes[userID]; voted {
+		action = fmt.Sprintf("changed vote to %s", choice)
+	}
+
+	p.Votes[userID] = choice
+	p.MessageTimestamp = messageTimestamp
+	p.Audit = append(p.Audit, proposalAudit{At: now, UserID: userID, Action: action})
+
+	if err := pp.saveProposal(p); err != nil {
+		pp.Logger.Printf("[%s] Error saving vote of [%s] on proposal [%s]: %v", ProposalsPluginName, userID, proposalID, err)
+		return
+	}
+
+	pp.refreshMessage(p, slack.NewActionBlock(blockID, renderVoteButtons(p)...))
+}
+
+// closeDueProposals closes all open proposals whose voting is over and announces their outcome
+func (pp *Proposals) closeDueProposals() {
+	pp.Lock()
+	defer pp.Unlock()
+
+	entries, err := pp.storer.ScanSilo(proposalsSilo)
+	if err != nil {
+		pp.Logger.Printf("[%s] Error loading proposals: %v", ProposalsPluginName, err)
+		return
+	}
+
+	now := pp.now()
+	for id, encoded := range entries {
+		var p proposal
+		if err := json.Unmarshal([]byte(encoded), &p); err != nil {
+			pp.Logger.Printf("[%s] Error decoding proposal [%s]: %v", ProposalsPluginName, id, err)
+			continue
+		}
+
+		if p.Outcome != "" || now.Before(p.ClosesAt) {
+			continue
+		}
+
+		yes, no := tallyVotes(p)
+		switch {
+		case yes+no < p.Quorum:
+			p.Outcome = proposalNoQuorum
+		case yes > no:
+			p.Outcome = proposalAccepted
+		default:
+			p.Outcome = proposalRejected
+		}
+
+		p.Audit = append(p.Audit, proposalAudit{At: now, Action: fmt.Sprintf("closed as %s with %d yes and %d no", p.Outcome, yes, no)})
+		if err := pp.saveProposal(p); err != nil {
+			pp.Logger.Printf("[%s] Error closing proposal [%s]: %v", ProposalsPluginName, p.ID, err)
+			continue
+		}
+
+		pp.refreshMessage(p)
+		pp.RealTimeMsgSender.SendMessage(pp.RealTimeMsgSender.NewOutgoingMessage(formatOutcome(p, yes, no), p.ChannelID))
+	}
+}
+
+// refreshMessage updates the message of a proposal, if known, with the additional blocks (i.e. its vote buttons)
+func (pp *Proposals) refreshMessage(p proposal, additionalBlocks ...slack.Block) {
+	if p.MessageTimestamp == "" {
+		return
+	}
+
+	blocks := append(renderProposal(p), additionalBlocks...)
+	if _, _, _, err := pp.SlackClient.UpdateMessage(p.ChannelID, p.MessageTimestamp, slack.MsgOptionText(fmt.Sprintf("Proposal: %s", p.Title), false), slack.MsgOptionBlocks(blocks...)); err != nil {
+		pp.Logger.Printf("[%s] Error updating message of proposal [%s]: %v", ProposalsPluginName, p.ID, err)
+	}
+}
+
+// loadProposal loads a proposal by ID
+func (pp *Proposals) loadProposal(id string) (p proposal, err error) {
+	encoded, err := pp.storer.GetSiloString(proposalsSilo, id)
+	if err != nil {
+		return p, err
+	}
+
+	err = json.Unmarshal([]byte(encoded), &p)
+	return p, err
+}
+
+// saveProposal persists a proposal
+func (pp *Proposals) saveProposal(p proposal) (err error) {
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return pp.storer.PutSiloString(proposalsSilo, p.ID, string(encoded))
+}
+
+// tallyVotes returns the number of yes and no votes of a proposal
+func tallyVotes(p proposal) (yes int, no int) {
+	for _, choice := range p.Votes {
+		if choice == proposalVoteYes {
+			yes = yes + 1
+		} else {
+			no = no + 1
+		}
+	}
+
+	return yes, no
+}
+
+// renderProposal renders the content blocks of a proposal's message, without its vote buttons (see renderVoteButtons)
+func renderProposal(p proposal) (blocks []slack.Block) {
+	yes, no := tallyVotes(p)
+
+	blocks = []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":ballot_box_with_ballot: *%s*\nProposed by <@%s>", p.Title, p.AuthorID), false, false), nil, nil),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Yes: *%d* · No: *%d* · Quorum: %d votes · Voting closes <!date^%d^{date_short_pretty} at {time}|%s>", yes, no, p.Quorum, p.ClosesAt.Unix(), p.ClosesAt.UTC().Format(time.RFC1123)), false, false)),
+	}
+
+	if p.Outcome != "" {
+		return append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Voting is closed: *%s*", p.Outcome), false, false), nil, nil))
+	}
+
+	return blocks
+}
+
+// renderVoteButtons renders the vote buttons of an open proposal. Slackscot puts them in a block routing clicks to the
+// plugin so the identifier of that block must be kept when refreshing the proposal's message
+func renderVoteButtons(p proposal) (buttons []slack.BlockElement) {
+	yesButton := slack.NewButtonBlockElement(fmt.Sprintf(proposalVoteActionFmt, proposalVoteYes), p.ID, slack.NewTextBlockObject(slack.PlainTextType, ":thumbsup: Yes", true, false))
+	yesButton.Style = slack.StylePrimary
+	noButton := slack.NewButtonBlockElement(fmt.Sprintf(proposalVoteActionFmt, proposalVoteNo), p.ID, slack.NewTextBlockObject(slack.PlainTextType, ":thumbsdown: No", true, false))
+
+	return []slack.BlockElement{yesButton, noButton}
+}
+
+// formatOutcome renders the announcement of the outcome of a closed proposal
+func formatOutcome(p proposal, yes int, no int) string {
+	switch p.Outcome {
+	case proposalAccepted:
+		return fmt.Sprintf(":tada: Proposal *%s* was accepted with %d yes and %d no", p.Title, yes, no)
+	case proposalRejected:
+		return fmt.Sprintf(":no_entry_sign: Proposal *%s* was rejected with %d yes and %d no", p.Title, yes, no)
+	}
+
+	return fmt.Sprintf(":shrug: Proposal *%s* didn't reach its quorum of %d votes (%d voted)", p.Title, p.Quorum, yes+no)
+}
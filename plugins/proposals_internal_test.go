@@ -0,0 +1,144 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newVote creates a click of a user on a vote button of a proposal
+func newVote(userID string, choice string, proposalID string) (i *slackscot.Interaction) {
+	i = newBlockActionsInteraction(userID, "slackscot:proposals.command[0]", fmt.Sprintf(proposalVoteActionFmt, choice), proposalID)
+	i.Channel.ID = "Cproduct"
+	i.Message.Timestamp = "1546833300.000100"
+
+	return i
+}
+
+func TestProposalsVotingAndOutcome(t *testing.T) {
+	var mutex sync.Mutex
+	updates := make([]string, 0)
+
+	testServer := slacktest.NewTestServer(func(c slacktest.Customize) {
+		c.Handle("/chat.update", func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			mutex.Lock()
+			updates = append(updates, r.Form.Get("ts")+": "+r.Form.Get("blocks"))
+			mutex.Unlock()
+			_, _ = w.Write([]byte(`{"ok": true, "channel": "Cproduct", "ts": "1546833300.000100"}`))
+		})
+	})
+	testServer.Start()
+	defer testServer.Stop()
+
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("proposalsTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	now := time.Date(2023, time.March, 14, 10, 0, 0, 0, time.UTC)
+	pp, err := newProposals(viper.New(), storer, func() time.Time { return now })
+	require.NoError(t, err)
+	pp.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(pp.Plugin, &slack.Msg{Channel: "Cproduct", User: "Uauthor", Timestamp: "1546833210.036900", Text: "<@bot> propose 'dark mode' for 2 days quorum 2"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		if !assert.Len(t, answers, 1) {
+			return false
+		}
+
+		render, err := json.Marshal(answers[0].ContentBlocks)
+		return assert.NoError(t, err) && assert.Equal(t, "Proposal: dark mode", answers[0].Text) &&
+			assert.Contains(t, string(render), "Yes: *0* · No: *0* · Quorum: 2 votes") &&
+			assert.Len(t, answers[0].InteractiveElements, 2) &&
+			assert.Equal(t, "Cproduct-1546833210.036900", answers[0].InteractiveElements[0].(*slack.ButtonBlockElement).Value)
+	})
+
+	votes := []struct {
+		user   string
+		choice string
+	}{{"U1", "yes"}, {"U2", "no"}, {"U2", "yes"}}
+
+	vote := pp.Commands[0].InteractionHandler
+	for _, v := range votes {
+		assert.Nil(t, vote(newVote(v.user, v.choice, "Cproduct-1546833210.036900")))
+	}
+
+	// Refreshed messages keep the block routing clicks to the plugin
+	require.Len(t, updates, 3)
+	assert.Contains(t, updates[2], "1546833300.000100: ")
+	assert.Contains(t, updates[2], "Yes: *2* · No: *0*")
+	assert.Contains(t, updates[2], `"block_id":"slackscot:proposals.command[0]"`)
+	assert.Contains(t, updates[2], `"action_id":"proposals.vote.yes","value":"Cproduct-1546833210.036900"`)
+
+	everyMinute := schedule.New().WithInterval(1, schedule.Minutes).Build()
+
+	// Voting isn't over yet
+	assertplugin.RunsOnSchedule(pp.Plugin, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, sentMsgs)
+	})
+
+	now = now.Add(48 * time.Hour)
+	assertplugin.RunsOnSchedule(pp.Plugin, everyMinute, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Cproduct": {":tada: Proposal *dark mode* was accepted with 2 yes and 0 no"}}, sentMsgs)
+	})
+
+	require.Len(t, updates, 4)
+	assert.Contains(t, updates[3], "Voting is closed: *accepted*")
+	assert.NotContains(t, updates[3], "proposals.vote.yes")
+
+	// Closed proposals don't take votes anymore
+	assert.Nil(t, vote(newVote("U3", "no", "Cproduct-1546833210.036900")))
+	assert.Len(t, updates, 4)
+
+	p, err := pp.loadProposal("Cproduct-1546833210.036900")
+	require.NoError(t, err)
+
+	actions := make([]string, 0)
+	for _, a := range p.Audit {
+		actions = append(actions, a.UserID+" "+a.Action)
+	}
+
+	assert.Equal(t, []string{"Uauthor opened", "U1 voted yes", "U2 voted no", "U2 changed vote to yes", " closed as accepted with 2 yes and 0 no"}, actions)
+}
+
+func TestProposalsWithoutQuorum(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("proposalsTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	now := time.Date(2023, time.March, 14, 10, 0, 0, 0, time.UTC)
+	pp, err := newProposals(viper.New(), storer, func() time.Time { return now })
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(pp.Plugin, &slack.Msg{Channel: "Cproduct", User: "Uauthor", Timestamp: "1546833210.036900", Text: "<@bot> propose 'tabs over spaces'"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+
+	now = now.Add(24 * time.Hour)
+	assertplugin.RunsOnSchedule(pp.Plugin, schedule.New().WithInterval(1, schedule.Minutes).Build(), func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Cproduct": {":shrug: Proposal *tabs over spaces* didn't reach its quorum of 3 votes (0 voted)"}}, sentMsgs)
+	})
+}
@@ -0,0 +1,136 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// SecretScannerPluginName holds identifying name for the secret scanner plugin
+	SecretScannerPluginName = "secretScanner"
+)
+
+// Configuration keys
+const (
+	secretPatternsKey       = "patterns"       // Additional named patterns (map of name to regular expression) to detect as secrets
+	secretAllowlistKey      = "allowlist"      // Regular expressions of values that look like secrets but should be ignored (i.e. documented example keys)
+	secretAdminChannelIDKey = "adminChannelID" // Optional channel to alert when a secret is detected
+)
+
+// defaultSecretPatterns holds the built-in patterns of likely credentials by kind
+var defaultSecretPatterns = map[string]string{
+	"AWS access key": "\\b(?:AKIA|ASIA)[0-9A-Z]{16}\\b",
+	"Slack token":    "\\bxox[abposr]-[0-9A-Za-z-]{10,}",
+	"private key":    "-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY(?: BLOCK)?-----",
+}
+
+// SecretScanner holds the plugin data for the secret scanner plugin
+type SecretScanner struct {
+	*slackscot.Plugin
+	patterns        map[string]*regexp.Regexp
+	allowlist       []*regexp.Regexp
+	adminChannelID  string
+	channels        []string
+	ignoredChannels []string
+}
+
+// NewSecretScanner creates a new instance of the secret scanner plugin. It listens to messages on channels
+// and warns authors (with an ephemeral message) of likely credentials (AWS keys, Slack tokens, private keys
+// and any other configured patterns) they posted. If an admin channel is configured, an alert is also sent there
+func NewSecretScanner(c *config.PluginConfig) (p *slackscot.Plugin, err error) {
+	s := new(SecretScanner)
+	s.channels = c.GetStringSlice(channelIDsKey)
+	s.ignoredChannels = c.GetStringSlice(ignoredChannelIDsKey)
+	s.adminChannelID = c.GetString(secretAdminChannelIDKey)
+
+	rawPatterns := make(map[string]string)
+	for kind, pattern := range defaultSecretPatterns {
+		rawPatterns[kind] = pattern
+	}
+
+	for kind, pattern := range c.GetStringMapString(secretPatternsKey) {
+		rawPatterns[kind] = pattern
+	}
+
+	s.patterns = make(map[string]*regexp.Regexp)
+	for kind, pattern := range rawPatterns {
+		if s.patterns[kind], err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("Invalid %s pattern for [%s]: %v", SecretScannerPluginName, kind, err)
+		}
+	}
+
+	for _, allowed := range c.GetStringSlice(secretAllowlistKey) {
+		r, err := regexp.Compile(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s allowlist entry [%s]: %v", SecretScannerPluginName, allowed, err)
+		}
+
+		s.allowlist = append(s.allowlist, r)
+	}
+
+	s.Plugin = plugin.New(SecretScannerPluginName).
+		WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(s.matchSecrets).
+			WithUsage("just converse").
+			WithDescription("secret scanner warns you when you post something that looks like a credential").
+			WithAnswerer(s.warnAboutSecrets).
+			Build()).
+		Build()
+
+	return s.Plugin, nil
+}
+
+// matchSecrets returns true if the message is on an enabled channel and contains likely secrets
+func (s *SecretScanner) matchSecrets(m *slackscot.IncomingMessage) bool {
+	return isChannelEnabled(m.Channel, s.channels, s.ignoredChannels) && len(s.findSecretKinds(m.Text)) > 0
+}
+
+// warnAboutSecrets answers with an ephemeral warning to the author of the message and alerts the admin channel, if configured
+func (s *SecretScanner) warnAboutSecrets(m *slackscot.IncomingMessage) *slackscot.Answer {
+	kinds := strings.Join(s.findSecretKinds(m.Text), ", ")
+
+	if s.adminChannelID != "" {
+		alert := fmt.Sprintf(":rotating_light: <@%s> posted what looks like a secret (%s) in <#%s>", m.User, kinds, m.Channel)
+		s.RealTimeMsgSender.SendMessage(s.RealTimeMsgSender.NewOutgoingMessage(alert, s.adminChannelID))
+	}
+
+	return &slackscot.Answer{
+		Text:    fmt.Sprintf(":warning: Your message looks like it contains a secret (%s). Please delete it and rotate the credential :key:", kinds),
+		Options: []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)},
+	}
+}
+
+// findSecretKinds returns the sorted kinds of secrets found in the text, ignoring allowlisted values
+func (s *SecretScanner) findSecretKinds(text string) (kinds []string) {
+	kinds = make([]string, 0)
+
+	for kind, pattern := range s.patterns {
+		for _, candidate := range pattern.FindAllString(text, -1) {
+			if !s.isAllowed(candidate) {
+				kinds = append(kinds, kind)
+				break
+			}
+		}
+	}
+
+	sort.Strings(kinds)
+	return kinds
+}
+
+// isAllowed returns true if the value matches an entry of the allowlist
+func (s *SecretScanner) isAllowed(value string) bool {
+	for _, allowed := range s.allowlist {
+		if allowed.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,253 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ShoutoutsPluginName holds identifying name for the shoutouts plugin
+	ShoutoutsPluginName = "shoutouts"
+)
+
+// Configuration keys
+const (
+	shoutoutsChannelIDKey = "channelID" // Channel where the weekly digest is posted, required
+	shoutoutsWeekdayKey   = "weekday"   // Day of the week the digest is posted, defaults to Friday
+	shoutoutsAtTimeKey    = "atTime"    // Time of the day the digest is posted, defaults to 16:00
+)
+
+const (
+	defaultShoutoutsWeekday = "Friday"
+	defaultShoutoutsAtTime  = "16:00"
+	shoutoutsSilo           = "shoutouts"
+	shoutoutsOptOutsSilo    = "optOuts"
+)
+
+var shoutoutRegex = regexp.MustCompile("(?i)\\bshout-?outs? to ((?:<@[A-Z0-9]+>(?:\\s*,\\s*|\\s+and\\s+|\\s*&\\s*|\\s+)?)+)(.*)")
+var shoutoutMentionRegex = regexp.MustCompile("<@([A-Z0-9]+)>")
+
+// shoutout is what's persisted for every shoutout
+type shoutout struct {
+	From      string   `json:"from"`
+	To        []string `json:"to"`
+	Reason    string   `json:"reason"`
+	ChannelID string   `json:"channelID"`
+}
+
+// Shoutouts holds the plugin data for the shoutouts plugin
+type Shoutouts struct {
+	*slackscot.Plugin
+	storer    store.GlobalSiloStringStorer
+	now       func() time.Time
+	channelID string
+}
+
+// NewShoutouts creates a new instance of the shoutouts plugin. Shoutouts heard in channels are recorded and compiled into
+// a weekly digest posted to the configured channel. Users can opt out of being featured
+func NewShoutouts(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	return newShoutouts(c, storer, time.Now)
+}
+
+func newShoutouts(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (p *slackscot.Plugin, err error) {
+	if !c.IsSet(shoutoutsChannelIDKey) {
+		return nil, fmt.Errorf("Missing %s config key: %s", ShoutoutsPluginName, shoutoutsChannelIDKey)
+	}
+
+	c.SetDefault(shoutoutsWeekdayKey, defaultShoutoutsWeekday)
+	c.SetDefault(shoutoutsAtTimeKey, defaultShoutoutsAtTime)
+
+	s := new(Shoutouts)
+	s.storer = storer
+	s.now = now
+	s.channelID = c.GetString(shoutoutsChannelIDKey)
+
+	s.Plugin = plugin.New(ShoutoutsPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "shoutouts opt out")
+			}).
+			WithUsage("shoutouts opt out").
+			WithDescription("Stop being featured in the shoutouts digest").
+			WithAnswerer(s.optOut).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "shoutouts opt in")
+			}).
+			WithUsage("shoutouts opt in").
+			WithDescription("Be featured in the shoutouts digest again").
+			WithAnswerer(s.optIn).
+			Build()).
+		WithHearAction(actions.NewHearAction().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool { return shoutoutRegex.MatchString(m.NormalizedText) }).
+			WithUsage("shoutout to @someone for <reason>").
+			WithDescription("Record shoutouts for the weekly digest").
+			WithAnswerer(s.recordShoutout).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().Every(c.GetString(shoutoutsWeekdayKey)).AtTime(c.GetString(shoutoutsAtTimeKey)).Build()).
+			WithDescription("Post the weekly shoutouts digest").
+			WithAction(s.postDigest).
+			Build()).
+		Build()
+
+	return s.Plugin, nil
+}
+
+// optOut excludes the user from the digest
+func (s *Shoutouts) optOut(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := s.storer.PutSiloString(shoutoutsOptOutsSilo, m.User, "true"); err != nil {
+		s.Logger.Printf("[%s] Error opting out [%s]: %v", ShoutoutsPluginName, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't opt you out :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: "You won't be featured in the shoutouts digest anymore :see_no_evil:"}
+}
+
+// optIn includes the user in the digest again
+func (s *Shoutouts) optIn(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if err := s.storer.DeleteSiloString(shoutoutsOptOutsSilo, m.User); err != nil {
+		s.Logger.Printf("[%s] Error opting in [%s]: %v", ShoutoutsPluginName, m.User, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't opt you in :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: "You'll be featured in the shoutouts digest again :mega:"}
+}
+
+// recordShoutout records a shoutout and reacts to the message to acknowledge it. Users shouting themselves
+// out and users who opted out aren't recorded
+func (s *Shoutouts) recordShoutout(m *slackscot.IncomingMessage) *slackscot.Answer {
+	match := shoutoutRegex.FindStringSubmatch(m.NormalizedText)
+
+	optOuts, err := s.storer.ScanSilo(shoutoutsOptOutsSilo)
+	if err != nil {
+		s.Logger.Printf("[%s] Error loading opt-outs: %v", ShoutoutsPluginName, err)
+		return nil
+	}
+
+	so := shoutout{From: m.User, To: make([]string, 0), Reason: strings.TrimLeft(strings.TrimSpace(match[2]), ":,- "), ChannelID: m.Channel}
+	for _, mention := range shoutoutMentionRegex.FindAllStringSubmatch(match[1], -1) {
+		if _, optedOut := optOuts[mention[1]]; mention[1] != m.User && !optedOut {
+			so.To = append(so.To, mention[1])
+		}
+	}
+
+	if len(so.To) == 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(so)
+	if err == nil {
+		err = s.storer.PutSiloString(shoutoutsSilo, fmt.Sprintf("%s:%s", m.Channel, m.Timestamp), string(value))
+	}
+
+	if err != nil {
+		s.Logger.Printf("[%s] Error recording shoutout: %v", ShoutoutsPluginName, err)
+		return nil
+	}
+
+	s.EmojiReactor.AddReaction("mega", slack.NewRefToMessage(m.Channel, m.Timestamp))
+
+	return nil
+}
+
+// postDigest posts all shoutouts recorded since the last digest and clears them
+func (s *Shoutouts) postDigest() {
+	raw, err := s.storer.ScanSilo(shoutoutsSilo)
+	if err != nil {
+		s.Logger.Printf("[%s] Error loading shoutouts: %v", ShoutoutsPluginName, err)
+		return
+	}
+
+	optOuts, err := s.storer.ScanSilo(shoutoutsOptOutsSilo)
+	if err != nil {
+		s.Logger.Printf("[%s] Error loading opt-outs: %v", ShoutoutsPluginName, err)
+		return
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+
+	// Keys end with the message timestamp so shoutouts are listed in the order they were given
+	sort.Slice(keys, func(i, j int) bool {
+		return shoutoutTimestamp(keys[i]) < shoutoutTimestamp(keys[j])
+	})
+
+	shoutouts := make([]shoutout, 0)
+	for _, key := range keys {
+		var so shoutout
+		if err := json.Unmarshal([]byte(raw[key]), &so); err != nil {
+			continue
+		}
+
+		// Users may have opted out since the shoutout was recorded
+		recipients := make([]string, 0)
+		for _, userID := range so.To {
+			if _, optedOut := optOuts[userID]; !optedOut {
+				recipients = append(recipients, userID)
+			}
+		}
+
+		if len(recipients) > 0 {
+			so.To = recipients
+			shoutouts = append(shoutouts, so)
+		}
+	}
+
+	if len(shoutouts) > 0 {
+		s.RealTimeMsgSender.SendMessage(s.RealTimeMsgSender.NewOutgoingMessage(formatShoutoutsDigest(shoutouts, s.now()), s.channelID))
+	}
+
+	for _, key := range keys {
+		if err := s.storer.DeleteSiloString(shoutoutsSilo, key); err != nil {
+			s.Logger.Printf("[%s] Error clearing shoutout [%s]: %v", ShoutoutsPluginName, key, err)
+		}
+	}
+}
+
+// shoutoutTimestamp returns the message timestamp of a shoutout key
+func shoutoutTimestamp(key string) string {
+	return key[strings.LastIndex(key, ":")+1:]
+}
+
+// formatShoutoutsDigest renders the digest of the week's shoutouts
+func formatShoutoutsDigest(shoutouts []shoutout, now time.Time) string {
+	recipients := make(map[string]bool)
+	for _, so := range shoutouts {
+		for _, userID := range so.To {
+			recipients[userID] = true
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ":mega: *Shoutouts for the week of %s*: %d shoutouts to %d people", now.AddDate(0, 0, -6).Format("January 2"), len(shoutouts), len(recipients))
+
+	for _, so := range shoutouts {
+		mentions := make([]string, 0, len(so.To))
+		for _, userID := range so.To {
+			mentions = append(mentions, fmt.Sprintf("<@%s>", userID))
+		}
+
+		fmt.Fprintf(&b, "\n• <@%s> → %s", so.From, strings.Join(mentions, ", "))
+		if so.Reason != "" {
+			fmt.Fprintf(&b, ": %s", so.Reason)
+		}
+		fmt.Fprintf(&b, " (in <#%s>)", so.ChannelID)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestShoutoutsMissingChannel(t *testing.T) {
+	_, err := NewShoutouts(viper.New(), nil)
+	assert.EqualError(t, err, "Missing shoutouts config key: channelID")
+}
+
+func TestShoutoutsWeeklyDigest(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("shoutoutsTest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	c := viper.New()
+	c.Set("channelID", "Ckudos")
+
+	p, err := newShoutouts(c, storer, func() time.Time { return time.Date(2023, time.March, 17, 16, 0, 0, 0, time.UTC) })
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	messages := []struct {
+		msg            slack.Msg
+		expectedEmojis []string
+	}{
+		{slack.Msg{Channel: "Cdev", User: "U1", Timestamp: "1679000000.000100", Text: "Huge shoutout to <@U2> and <@U3> for fixing the build"}, []string{"mega"}},
+		{slack.Msg{Channel: "Cops", User: "U2", Timestamp: "1679000100.000100", Text: "shout-out to <@U4>, <@U2>: on-call hero"}, []string{"mega"}},
+		{slack.Msg{Channel: "Cdev", User: "U3", Timestamp: "1679000200.000100", Text: "shoutout to <@U3> for being me"}, []string{}},
+		{slack.Msg{Channel: "Cdev", User: "U3", Timestamp: "1679000300.000100", Text: "I should do a shoutout sometime"}, []string{}},
+	}
+
+	for _, m := range messages {
+		msg := m.msg
+		assertplugin.AnswersAndReacts(p, &msg, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers) && assert.ElementsMatch(t, m.expectedEmojis, emojis)
+		})
+	}
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U3", Text: "<@bot> shoutouts opt out"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "You won't be featured in the shoutouts digest anymore :see_no_evil:")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U1", Timestamp: "1679000400.000100", Text: "shoutout to <@U3> again"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Empty(t, answers) && assert.Empty(t, emojis)
+	})
+
+	digestSchedule := schedule.New().Every("Friday").AtTime("16:00").Build()
+
+	assertplugin.RunsOnSchedule(p, digestSchedule, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Ckudos": {":mega: *Shoutouts for the week of March 11*: 2 shoutouts to 2 people\n" +
+			"• <@U1> → <@U2>: for fixing the build (in <#Cdev>)\n" +
+			"• <@U2> → <@U4>: on-call hero (in <#Cops>)"}}, sentMsgs)
+	})
+
+	// Shoutouts are cleared once posted
+	assertplugin.RunsOnSchedule(p, digestSchedule, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, sentMsgs)
+	})
+}
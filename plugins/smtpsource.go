@@ -0,0 +1,203 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultSMTPMaxMessageSize = 10 * 1024 * 1024
+)
+
+// SMTPEmailSource is an EmailSource receiving emails over SMTP. It implements the minimal subset of the protocol
+// needed to accept emails from an MTA relay or from systems configured to send alerts directly to it. It doesn't
+// support TLS or authentication and only accepts recipients from its list of addresses so it should only
+// be reachable from trusted networks
+type SMTPEmailSource struct {
+	listener       net.Listener
+	hostname       string
+	addresses      map[string]bool
+	maxMessageSize int64
+
+	sync.Mutex
+	received []*mail.Message
+}
+
+// NewSMTPEmailSource starts listening for SMTP connections on the listen address (i.e. ":2525"), accepting emails
+// to the given addresses
+func NewSMTPEmailSource(listenAddress string, hostname string, addresses []string) (s *SMTPEmailSource, err error) {
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	s = new(SMTPEmailSource)
+	s.listener = listener
+	s.hostname = hostname
+	s.maxMessageSize = defaultSMTPMaxMessageSize
+	s.addresses = make(map[string]bool)
+	for _, a := range addresses {
+		s.addresses[strings.ToLower(a)] = true
+	}
+
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the address the SMTP source is listening on
+func (s *SMTPEmailSource) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops listening for SMTP connections
+func (s *SMTPEmailSource) Close() (err error) {
+	return s.listener.Close()
+}
+
+// FetchEmails returns the emails received since the last call
+func (s *SMTPEmailSource) FetchEmails() (emails []*mail.Message, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	emails = s.received
+	s.received = nil
+
+	return emails, nil
+}
+
+// serve accepts connections until the listener is closed
+func (s *SMTPEmailSource) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn runs an SMTP session on the connection
+func (s *SMTPEmailSource) handleConn(conn net.Conn) {
+	tc := textproto.NewConn(conn)
+	defer tc.Close()
+
+	var recipients []string
+	reply := func(code int, msg string) error {
+		return tc.PrintfLine("%d %s", code, msg)
+	}
+
+	if reply(220, fmt.Sprintf("%s ESMTP slackscot", s.hostname)) != nil {
+		return
+	}
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg := line, ""
+		if i := strings.Index(line, " "); i != -1 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		switch strings.ToUpper(verb) {
+		case "HELO", "EHLO":
+			recipients = nil
+			err = reply(250, s.hostname)
+		case "MAIL":
+			recipients = nil
+			err = reply(250, "OK")
+		case "RCPT":
+			address, ok := parseSMTPPath(arg, "TO:")
+			if !ok {
+				err = reply(501, "Syntax: RCPT TO:<address>")
+			} else if !s.addresses[strings.ToLower(address)] {
+				err = reply(550, fmt.Sprintf("No such user here: %s", address))
+			} else {
+				recipients = append(recipients, address)
+				err = reply(250, "OK")
+			}
+		case "DATA":
+			if len(recipients) == 0 {
+				err = reply(503, "RCPT first")
+				break
+			}
+
+			if err = reply(354, "End data with <CR><LF>.<CR><LF>"); err != nil {
+				return
+			}
+
+			err = s.receive(tc, recipients)
+			recipients = nil
+		case "RSET":
+			recipients = nil
+			err = reply(250, "OK")
+		case "NOOP":
+			err = reply(250, "OK")
+		case "QUIT":
+			_ = reply(221, "Bye")
+			return
+		default:
+			err = reply(502, "Command not implemented")
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// receive reads an email's data and queues it, replying with the outcome
+func (s *SMTPEmailSource) receive(tc *textproto.Conn, recipients []string) (err error) {
+	r := tc.DotReader()
+	data, err := ioutil.ReadAll(io.LimitReader(r, s.maxMessageSize+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(data)) > s.maxMessageSize {
+		// Discard the rest of the data before replying
+		if _, err = io.Copy(ioutil.Discard, r); err != nil {
+			return err
+		}
+
+		return tc.PrintfLine("552 Message exceeds maximum size of %d bytes", s.maxMessageSize)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return tc.PrintfLine("554 Invalid message: %v", err)
+	}
+
+	msg.Header[EnvelopeRecipientsHeader] = []string{strings.Join(recipients, ", ")}
+
+	s.Lock()
+	s.received = append(s.received, msg)
+	s.Unlock()
+
+	return tc.PrintfLine("250 OK: queued")
+}
+
+// parseSMTPPath returns the address of a MAIL or RCPT argument such as "TO:<alerts@example.com>"
+func parseSMTPPath(arg string, prefix string) (address string, ok bool) {
+	if !strings.HasPrefix(strings.ToUpper(arg), prefix) {
+		return "", false
+	}
+
+	path := strings.TrimSpace(arg[len(prefix):])
+	if i := strings.Index(path, ">"); strings.HasPrefix(path, "<") && i != -1 {
+		return path[1:i], path[1:i] != ""
+	}
+
+	return "", false
+}
@@ -0,0 +1,197 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/hashicorp/golang-lru"
+	"github.com/slack-go/slack"
+	"regexp"
+	"strings"
+)
+
+const (
+	// SnippetsPluginName holds identifying name for the snippets plugin
+	SnippetsPluginName = "snippets"
+
+	// SnippetsConvertActionID is the action ID of the button offering to convert a code block to a snippet
+	SnippetsConvertActionID = "snippets.convert"
+)
+
+// Configuration keys
+const (
+	snippetsMinLinesKey = "minLines" // Minimum number of lines of code blocks to offer converting, defaults to 25
+)
+
+const (
+	defaultSnippetsMinLines = 25
+	// Code blocks are kept until converted (or evicted) since button values are too small to hold them
+	pendingSnippetsCacheSize = 100
+)
+
+var codeBlockRegex = regexp.MustCompile("(?s)```(.*?)```")
+var startSnippetRegex = regexp.MustCompile("(?i)\\Asnippet ([\\w+#-]+)\\z")
+
+// snippetTemplate is the starting content of a snippet of a language along with its file extension
+type snippetTemplate struct {
+	extension string
+	content   string
+}
+
+// snippetTemplates holds the templates of snippets by language
+var snippetTemplates = map[string]snippetTemplate{
+	"go":         {"go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"Hello, world\")\n}\n"},
+	"python":     {"py", "def main():\n    print(\"Hello, world\")\n\n\nif __name__ == \"__main__\":\n    main()\n"},
+	"javascript": {"js", "function main() {\n  console.log('Hello, world');\n}\n\nmain();\n"},
+	"java":       {"java", "public class Main {\n    public static void main(String[] args) {\n        System.out.println(\"Hello, world\");\n    }\n}\n"},
+	"shell":      {"sh", "#!/usr/bin/env bash\nset -euo pipefail\n\necho \"Hello, world\"\n"},
+	"sql":        {"sql", "SELECT *\nFROM table_name\nWHERE condition\nLIMIT 10;\n"},
+}
+
+// snippetLanguageAliases maps common names of languages to the ones of snippetTemplates
+var snippetLanguageAliases = map[string]string{
+	"golang": "go",
+	"py":     "python",
+	"js":     "javascript",
+	"bash":   "shell",
+	"sh":     "shell",
+}
+
+// pendingSnippet is a long code block offered to be converted to a snippet
+type pendingSnippet struct {
+	channelID string
+	timestamp string
+	code      string
+}
+
+// Snippets holds the plugin data for the snippets plugin
+type Snippets struct {
+	*slackscot.Plugin
+	minLines int
+	pending  *lru.ARCCache
+}
+
+// NewSnippets creates a new instance of the snippets plugin. When someone pastes a long code block, it offers them to
+// convert it to a snippet in the message's thread. Button clicks are routed to the plugin by slackscot's interaction
+// handler (see slackscot.InteractionPath)
+func NewSnippets(c *config.PluginConfig) (p *slackscot.Plugin, err error) {
+	c.SetDefault(snippetsMinLinesKey, defaultSnippetsMinLines)
+
+	s := new(Snippets)
+	s.minLines = c.GetInt(snippetsMinLinesKey)
+
+	if s.pending, err = lru.NewARC(pendingSnippetsCacheSize); err != nil {
+		return nil, err
+	}
+
+	s.Plugin = plugin.New(SnippetsPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return startSnippetRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("snippet <language>").
+			WithDescription("Start a snippet from a template for a language (i.e. go, python, javascript, java, shell or sql)").
+			WithAnswerer(s.startSnippet).
+			Build()).
+		WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return countCodeLines(extractCode(m.NormalizedText)) >= s.minLines
+			}).
+			WithUsage("```<long code block>```").
+			WithDescription("Offer to convert long code blocks to snippets").
+			WithAnswerer(s.offerConversion).
+			WithInteractionHandler(s.receiveInteraction).
+			Build()).
+		Build()
+
+	return s.Plugin, nil
+}
+
+// offerConversion answers privately to the author of a long code block with a button to convert it to a snippet
+func (s *Snippets) offerConversion(m *slackscot.IncomingMessage) *slackscot.Answer {
+	key := fmt.Sprintf("%s:%s", m.Channel, m.Timestamp)
+	s.pending.Add(key, pendingSnippet{channelID: m.Channel, timestamp: m.Timestamp, code: extractCode(m.NormalizedText)})
+
+	text := "That's a long code block :scroll:. Want me to turn it into a snippet for readability?"
+	button := slack.NewButtonBlockElement(SnippetsConvertActionID, key, slack.NewTextBlockObject(slack.PlainTextType, "Convert to snippet", false, false))
+	button.Style = slack.StylePrimary
+
+	return &slackscot.Answer{Text: text,
+		ContentBlocks:       []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)},
+		InteractiveElements: []slack.BlockElement{button},
+		Options:             []slackscot.AnswerOption{slackscot.AnswerEphemeral(m.User)}}
+}
+
+// receiveInteraction converts code blocks to snippets on clicks of the convert button
+func (s *Snippets) receiveInteraction(i *slackscot.Interaction) *slack.ViewSubmissionResponse {
+	if i.Type != slack.InteractionTypeBlockActions {
+		return nil
+	}
+
+	for _, action := range i.ActionCallback.BlockActions {
+		if action.ActionID == SnippetsConvertActionID {
+			s.convert(action.Value)
+		}
+	}
+
+	return nil
+}
+
+// convert uploads a pending code block as a snippet in the thread of its message. Code blocks are only converted once
+func (s *Snippets) convert(key string) {
+	cached, ok := s.pending.Get(key)
+	if !ok {
+		s.Logger.Debugf("[%s] Ignoring conversion of unknown or already converted code block [%s]", SnippetsPluginName, key)
+		return
+	}
+	s.pending.Remove(key)
+
+	ps := cached.(pendingSnippet)
+	_, err := s.FileUploader.UploadFile(slack.FileUploadParameters{Content: ps.code, Filetype: "text", Filename: "snippet.txt", Title: "Code snippet", Channels: []string{ps.channelID}, ThreadTimestamp: ps.timestamp})
+	if err != nil {
+		s.Logger.Printf("[%s] Error uploading snippet of [%s]: %v", SnippetsPluginName, key, err)
+	}
+}
+
+// startSnippet uploads a snippet from the template of a language
+func (s *Snippets) startSnippet(m *slackscot.IncomingMessage) *slackscot.Answer {
+	language := strings.ToLower(startSnippetRegex.FindStringSubmatch(m.NormalizedText)[1])
+	if alias, ok := snippetLanguageAliases[language]; ok {
+		language = alias
+	}
+
+	template, ok := snippetTemplates[language]
+	if !ok {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I don't have a template for `%s` :shrug:", language)}
+	}
+
+	_, err := s.FileUploader.UploadFile(slack.FileUploadParameters{Content: template.content, Filetype: language, Filename: fmt.Sprintf("snippet.%s", template.extension), Title: fmt.Sprintf("%s snippet", language), Channels: []string{m.Channel}})
+	if err != nil {
+		s.Logger.Printf("[%s] Error uploading %s template: %v", SnippetsPluginName, language, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't upload the template :disappointed: (%v)", err)}
+	}
+
+	return nil
+}
+
+// extractCode returns the content of the code blocks of a message, separated by blank lines
+func extractCode(text string) (code string) {
+	blocks := make([]string, 0)
+	for _, match := range codeBlockRegex.FindAllStringSubmatch(text, -1) {
+		blocks = append(blocks, strings.Trim(match[1], "\n"))
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// countCodeLines returns the number of lines of code
+func countCodeLines(code string) int {
+	if code == "" {
+		return 0
+	}
+
+	return strings.Count(code, "\n") + 1
+}
@@ -0,0 +1,76 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/alexandre-normand/slackscot/test/capture"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func newSnippetsTestConfig() *viper.Viper {
+	c := viper.New()
+	c.Set("minLines", 5)
+
+	return c
+}
+
+func TestSnippetsConvertLongCodeBlocks(t *testing.T) {
+	p, err := NewSnippets(newSnippetsTestConfig())
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Timestamp: "1546833210.036900", Text: "short one ```\nfmt.Println(\"hi\")\n```"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Empty(t, answers)
+	})
+
+	code := strings.Repeat("log.Println(\"line\")\n", 6)
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Timestamp: "1546833210.036900", Text: "this fails:\n```\n" + code + "```"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) &&
+			assertanswer.HasText(t, answers[0], "That's a long code block :scroll:. Want me to turn it into a snippet for readability?") &&
+			assertanswer.HasOptions(t, answers[0], assertanswer.ResolvedAnswerOption{Key: slackscot.EphemeralAnswerToOpt, Value: "U21355"}) &&
+			assert.Len(t, answers[0].ContentBlocks, 1) &&
+			assert.Len(t, answers[0].InteractiveElements, 1) &&
+			assert.Equal(t, "Cdev:1546833210.036900", answers[0].InteractiveElements[0].(*slack.ButtonBlockElement).Value)
+	})
+
+	uploads := capture.NewFileUploader()
+	p.FileUploader = slackscot.NewFileUploader(uploads)
+	handle := p.HearActions[0].InteractionHandler
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, handle(newBlockActionsInteraction("U21355", slackscot.HearActionInteractionBlockID(SnippetsPluginName, 0), SnippetsConvertActionID, "Cdev:1546833210.036900")))
+	}
+
+	// The code block is only converted once, even when the button is clicked again
+	if assert.Len(t, uploads.FileUploads, 1) {
+		assert.Equal(t, strings.TrimSuffix(code, "\n"), uploads.FileUploads[0].Content)
+		assert.Equal(t, []string{"Cdev"}, uploads.FileUploads[0].Channels)
+		assert.Equal(t, "1546833210.036900", uploads.FileUploads[0].ThreadTimestamp)
+	}
+}
+
+func TestSnippetsStartFromTemplate(t *testing.T) {
+	p, err := NewSnippets(newSnippetsTestConfig())
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReactsWithUploads(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> snippet golang"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Empty(t, answers) && assert.Len(t, fileUploads, 1) &&
+			assert.Equal(t, "snippet.go", fileUploads[0].Filename) &&
+			assert.Equal(t, "go", fileUploads[0].Filetype) &&
+			assert.Equal(t, []string{"Cdev"}, fileUploads[0].Channels) &&
+			assert.Contains(t, fileUploads[0].Content, "package main")
+	})
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cdev", User: "U21355", Text: "<@bot> snippet cobol"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, I don't have a template for `cobol` :shrug:")
+	})
+}
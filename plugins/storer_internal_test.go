@@ -0,0 +1,25 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// NewTestStorer returns a LevelDB storer with the given name in a new temporary directory along with the function
+// closing it and removing the directory. It's defined in an internal test file to be shared with the external tests
+// (as plugins.NewTestStorer) without being part of the package's API
+func NewTestStorer(t *testing.T, name string) (storer *store.LevelDB, cleanup func()) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+
+	storer, err = store.NewLevelDB(name, tmpdir)
+	require.NoError(t, err)
+
+	return storer, func() {
+		storer.Close()
+		os.RemoveAll(tmpdir)
+	}
+}
@@ -0,0 +1,364 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SupportSLAPluginName holds identifying name for the support SLA plugin
+	SupportSLAPluginName = "supportSLA"
+)
+
+// Configuration keys
+const (
+	slaChannelIDsKey   = "channelIDs"   // Support channels to track, required
+	slaThresholdKey    = "threshold"    // Time to first response after which unanswered requests are alerted on, defaults to 30m
+	slaResponderIDsKey = "responderIDs" // Users whose replies count as responses, defaults to anyone but the requester
+	slaAtTimeKey       = "atTime"       // Time of day at which daily stats are posted, defaults to 09:00
+	slaRetentionKey    = "retention"    // How long response times are kept, defaults to 720h (30 days)
+)
+
+const (
+	defaultSLAThreshold = 30 * time.Minute
+	defaultSLAAtTime    = "09:00"
+	defaultSLARetention = 30 * 24 * time.Hour
+	slaRequestsSiloFmt  = "requests:%s"
+	slaResponsesSiloFmt = "responses:%s"
+)
+
+var slaStatsRegex = regexp.MustCompile("(?i)\\Asla stats\\z")
+
+// supportRequest is a message posted in a support channel waiting for its first response
+type supportRequest struct {
+	UserID  string    `json:"userID"`
+	AskedAt time.Time `json:"askedAt"`
+	Alerted bool      `json:"alerted"`
+}
+
+// supportResponse is the first response time of a support request
+type supportResponse struct {
+	AskedAt      time.Time     `json:"askedAt"`
+	ResponseTime time.Duration `json:"responseTime"`
+	ResponderID  string        `json:"responderID"`
+}
+
+// SupportSLA holds the plugin data for the support SLA plugin
+type SupportSLA struct {
+	*slackscot.Plugin
+	storer     store.GlobalSiloStringStorer
+	channelIDs map[string]bool
+	responders map[string]bool
+	threshold  time.Duration
+	retention  time.Duration
+	now        func() time.Time
+
+	// Guards the read-modify-write of requests since messages and scheduled checks run concurrently
+	sync.Mutex
+}
+
+// NewSupportSLA creates a new instance of the support SLA plugin. It tracks the time to the first thread reply of
+// messages posted in support channels, alerts in the thread of requests left unanswered past the threshold and posts
+// daily stats to each channel. Requests and response times are persisted per channel
+func NewSupportSLA(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	return newSupportSLA(c, storer, time.Now)
+}
+
+// newSupportSLA creates a new instance of the support SLA plugin using now to get the current time
+func newSupportSLA(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time) (p *slackscot.Plugin, err error) {
+	if !c.IsSet(slaChannelIDsKey) {
+		return nil, fmt.Errorf("Missing %s config key: %s", SupportSLAPluginName, slaChannelIDsKey)
+	}
+
+	c.SetDefault(slaThresholdKey, defaultSLAThreshold)
+	c.SetDefault(slaAtTimeKey, defaultSLAAtTime)
+	c.SetDefault(slaRetentionKey, defaultSLARetention)
+
+	sla := new(SupportSLA)
+	sla.storer = storer
+	sla.threshold = c.GetDuration(slaThresholdKey)
+	sla.retention = c.GetDuration(slaRetentionKey)
+	sla.now = now
+
+	sla.channelIDs = make(map[string]bool)
+	for _, channelID := range c.GetStringSlice(slaChannelIDsKey) {
+		sla.channelIDs[channelID] = true
+	}
+
+	sla.responders = make(map[string]bool)
+	for _, userID := range c.GetStringSlice(slaResponderIDsKey) {
+		sla.responders[userID] = true
+	}
+
+	if sla.threshold <= 0 {
+		return nil, fmt.Errorf("Invalid %s threshold [%s], it should be more than 0", SupportSLAPluginName, sla.threshold)
+	}
+
+	sla.Plugin = plugin.New(SupportSLAPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return sla.channelIDs[m.Channel] && slaStatsRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("sla stats").
+			WithDescription("Reports the first response time stats of the support channel for the last 24 hours").
+			WithAnswerer(func(m *slackscot.IncomingMessage) *slackscot.Answer {
+				return &slackscot.Answer{Text: sla.stats(m.Channel)}
+			}).
+			Build()).
+		WithHearAction(actions.NewHearAction().
+			Hidden().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return sla.channelIDs[m.Channel]
+			}).
+			WithAnswerer(sla.track).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithInterval(1, schedule.Minutes).Build()).
+			WithDescriptionf("Alert on support requests unanswered for more than %s", sla.threshold).
+			WithAction(sla.alertUnanswered).
+			Build()).
+		WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithUnit(schedule.Days).AtTime(c.GetString(slaAtTimeKey)).Build()).
+			WithDescription("Post the daily first response time stats of support channels").
+			WithAction(sla.postDailyStats).
+			Build()).
+		Build()
+
+	return sla.Plugin, nil
+}
+
+// track records new support requests and the first response to each of them. It never answers
+func (sla *SupportSLA) track(m *slackscot.IncomingMessage) *slackscot.Answer {
+	sla.Lock()
+	defer sla.Unlock()
+
+	if !m.IsThreadReply() {
+		askedAt, err := parseSlackTimestamp(m.Timestamp)
+		if err != nil {
+			sla.Logger.Printf("[%s] Error parsing timestamp of message [%s]: %v", SupportSLAPluginName, m.Timestamp, err)
+			return nil
+		}
+
+		if err := sla.save(fmt.Sprintf(slaRequestsSiloFmt, m.Channel), m.Timestamp, supportRequest{UserID: m.User, AskedAt: askedAt}); err != nil {
+			sla.Logger.Printf("[%s] Error saving request [%s]: %v", SupportSLAPluginName, m.Timestamp, err)
+		}
+
+		return nil
+	}
+
+	requestsSilo := fmt.Sprintf(slaRequestsSiloFmt, m.Channel)
+	encoded, err := sla.storer.GetSiloString(requestsSilo, m.ThreadTimestamp)
+	if err != nil {
+		// Not a tracked request or already answered
+		return nil
+	}
+
+	var request supportRequest
+	if err := json.Unmarshal([]byte(encoded), &request); err != nil {
+		sla.Logger.Printf("[%s] Error decoding request [%s]: %v", SupportSLAPluginName, m.ThreadTimestamp, err)
+		return nil
+	}
+
+	if m.User == request.UserID || (len(sla.responders) > 0 && !sla.responders[m.User]) {
+		return nil
+	}
+
+	respondedAt, err := parseSlackTimestamp(m.Timestamp)
+	if err != nil {
+		sla.Logger.Printf("[%s] Error parsing timestamp of message [%s]: %v", SupportSLAPluginName, m.Timestamp, err)
+		return nil
+	}
+
+	response := supportResponse{AskedAt: request.AskedAt, ResponseTime: respondedAt.Sub(request.AskedAt), ResponderID: m.User}
+	if err := sla.save(fmt.Sprintf(slaResponsesSiloFmt, m.Channel), m.ThreadTimestamp, response); err != nil {
+		sla.Logger.Printf("[%s] Error saving response to request [%s]: %v", SupportSLAPluginName, m.ThreadTimestamp, err)
+		return nil
+	}
+
+	if err := sla.storer.DeleteSiloString(requestsSilo, m.ThreadTimestamp); err != nil {
+		sla.Logger.Printf("[%s] Error deleting answered request [%s]: %v", SupportSLAPluginName, m.ThreadTimestamp, err)
+	}
+
+	return nil
+}
+
+// alertUnanswered replies in the thread of requests unanswered for longer than the threshold. Requests are only
+// alerted on once
+func (sla *SupportSLA) alertUnanswered() {
+	sla.Lock()
+	defer sla.Unlock()
+
+	now := sla.now()
+	for channelID := range sla.channelIDs {
+		silo := fmt.Sprintf(slaRequestsSiloFmt, channelID)
+		entries, err := sla.storer.ScanSilo(silo)
+		if err != nil {
+			sla.Logger.Printf("[%s] Error loading requests of [%s]: %v", SupportSLAPluginName, channelID, err)
+			continue
+		}
+
+		for ts, encoded := range entries {
+			var request supportRequest
+			if err := json.Unmarshal([]byte(encoded), &request); err != nil {
+				sla.Logger.Printf("[%s] Error decoding request [%s]: %v", SupportSLAPluginName, ts, err)
+				continue
+			}
+
+			waiting := now.Sub(request.AskedAt)
+			if request.Alerted || waiting < sla.threshold {
+				continue
+			}
+
+			text := fmt.Sprintf(":rotating_light: This request from <@%s> has been waiting for a response for %s (SLA is %s)", request.UserID, formatSLADuration(waiting), formatSLADuration(sla.threshold))
+			if _, _, err := sla.SlackClient.PostMessage(channelID, slack.MsgOptionText(text, false), slack.MsgOptionTS(ts), slack.MsgOptionAsUser(true)); err != nil {
+				sla.Logger.Printf("[%s] Error alerting on request [%s]: %v", SupportSLAPluginName, ts, err)
+				continue
+			}
+
+			request.Alerted = true
+			if err := sla.save(silo, ts, request); err != nil {
+				sla.Logger.Printf("[%s] Error saving request [%s]: %v", SupportSLAPluginName, ts, err)
+			}
+		}
+	}
+}
+
+// postDailyStats posts the stats of the last 24 hours to each support channel and drops response times older than
+// the retention
+func (sla *SupportSLA) postDailyStats() {
+	for channelID := range sla.channelIDs {
+		sla.RealTimeMsgSender.SendMessage(sla.RealTimeMsgSender.NewOutgoingMessage(sla.stats(channelID), channelID))
+		sla.pruneResponses(channelID)
+	}
+}
+
+// stats renders the first response time stats of requests of the last 24 hours of a channel
+func (sla *SupportSLA) stats(channelID string) string {
+	sla.Lock()
+	defer sla.Unlock()
+
+	now := sla.now()
+	since := now.Add(-24 * time.Hour)
+
+	responses, err := sla.storer.ScanSilo(fmt.Sprintf(slaResponsesSiloFmt, channelID))
+	if err != nil {
+		sla.Logger.Printf("[%s] Error loading responses of [%s]: %v", SupportSLAPluginName, channelID, err)
+		return fmt.Sprintf("Sorry, I couldn't load the response times :disappointed: (%v)", err)
+	}
+
+	durations := make([]time.Duration, 0)
+	for _, encoded := range responses {
+		var r supportResponse
+		if err := json.Unmarshal([]byte(encoded), &r); err == nil && !r.AskedAt.Before(since) {
+			durations = append(durations, r.ResponseTime)
+		}
+	}
+
+	unanswered := 0
+	if requests, err := sla.storer.ScanSilo(fmt.Sprintf(slaRequestsSiloFmt, channelID)); err == nil {
+		for _, encoded := range requests {
+			var r supportRequest
+			if err := json.Unmarshal([]byte(encoded), &r); err == nil && !r.AskedAt.Before(since) {
+				unanswered = unanswered + 1
+			}
+		}
+	}
+
+	if len(durations) == 0 && unanswered == 0 {
+		return ":bar_chart: No support requests in the last 24 hours"
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	withinSLA := 0
+	for _, d := range durations {
+		if d <= sla.threshold {
+			withinSLA = withinSLA + 1
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ":bar_chart: *Support stats for the last 24 hours*\n• Requests: %d (%d unanswered)", len(durations)+unanswered, unanswered)
+	if len(durations) > 0 {
+		fmt.Fprintf(&b, "\n• First response: median %s, p90 %s", formatSLADuration(percentile(durations, 50)), formatSLADuration(percentile(durations, 90)))
+		fmt.Fprintf(&b, "\n• Within SLA (%s): %d%%", formatSLADuration(sla.threshold), withinSLA*100/len(durations))
+	}
+
+	return b.String()
+}
+
+// pruneResponses deletes the response times of requests older than the retention
+func (sla *SupportSLA) pruneResponses(channelID string) {
+	sla.Lock()
+	defer sla.Unlock()
+
+	silo := fmt.Sprintf(slaResponsesSiloFmt, channelID)
+	responses, err := sla.storer.ScanSilo(silo)
+	if err != nil {
+		sla.Logger.Printf("[%s] Error loading responses of [%s]: %v", SupportSLAPluginName, channelID, err)
+		return
+	}
+
+	expiry := sla.now().Add(-sla.retention)
+	for ts, encoded := range responses {
+		var r supportResponse
+		if err := json.Unmarshal([]byte(encoded), &r); err != nil || r.AskedAt.Before(expiry) {
+			if err := sla.storer.DeleteSiloString(silo, ts); err != nil {
+				sla.Logger.Printf("[%s] Error deleting response [%s]: %v", SupportSLAPluginName, ts, err)
+			}
+		}
+	}
+}
+
+// save persists a value encoded as json
+func (sla *SupportSLA) save(silo string, key string, v interface{}) (err error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return sla.storer.PutSiloString(silo, key, string(encoded))
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := int(math.Ceil(float64(p) / 100. * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// formatSLADuration renders a duration rounded to the minute (or second under a minute)
+func formatSLADuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// parseSlackTimestamp returns the time of a slack message timestamp such as "1546833210.036900"
+func parseSlackTimestamp(ts string) (t time.Time, err error) {
+	seconds, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return t, err
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC(), nil
+}
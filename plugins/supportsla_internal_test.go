@@ -0,0 +1,153 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// slackTimestamp returns the slack message timestamp of a time
+func slackTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.000100", t.Unix())
+}
+
+func TestSupportSLAMissingChannels(t *testing.T) {
+	_, err := NewSupportSLA(viper.New(), nil)
+	assert.EqualError(t, err, "Missing supportSLA config key: channelIDs")
+}
+
+func TestParseSlackTimestamp(t *testing.T) {
+	ts, err := parseSlackTimestamp("1546833210.036900")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1546833210), ts.Unix())
+	}
+
+	_, err = parseSlackTimestamp("not a timestamp")
+	assert.Error(t, err)
+}
+
+func TestSupportSLATracksResponsesAndAlerts(t *testing.T) {
+	posted := postedMessages{}
+	testServer := newPostedMessagesServer(&posted)
+	defer testServer.Stop()
+
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("supportSLATest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	c := viper.New()
+	c.Set("channelIDs", []string{"Csupport"})
+	c.Set("threshold", "30m")
+
+	start := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	clock := testClock{now: start}
+	p, err := newSupportSLA(c, storer, clock.Now)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	hears := func(channel string, user string, ts time.Time, threadTS time.Time) {
+		m := &slack.Msg{Channel: channel, User: user, Timestamp: slackTimestamp(ts), Text: "help please"}
+		if !threadTS.IsZero() {
+			m.ThreadTimestamp = slackTimestamp(threadTS)
+		}
+
+		assertplugin.AnswersAndReacts(p, m, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		})
+	}
+
+	first, second, third := start, start.Add(time.Minute), start.Add(2*time.Minute)
+	hears("Csupport", "Uasker", first, time.Time{})
+	hears("Csupport", "Uasker", second, time.Time{})
+	hears("Csupport", "Uasker", third, time.Time{})
+	hears("Crandom", "Uasker", third, time.Time{})
+
+	// Replies from the requester don't count as responses
+	hears("Csupport", "Uasker", first.Add(2*time.Minute), first)
+	hears("Csupport", "Uhelper", first.Add(10*time.Minute), first)
+	hears("Csupport", "Uhelper", first.Add(11*time.Minute), first)
+	hears("Csupport", "Uhelper", second.Add(40*time.Minute), second)
+
+	everyMinute := schedule.New().WithInterval(1, schedule.Minutes).Build()
+	runsOnSchedule := func(def schedule.Definition) {
+		assertplugin.RunsOnSchedule(p, def, func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+			return true
+		})
+	}
+
+	clock.now = start.Add(20 * time.Minute)
+	runsOnSchedule(everyMinute)
+	assert.Empty(t, posted.messages)
+
+	// Only the third request is unanswered past the threshold and it's only alerted on once
+	clock.now = start.Add(45 * time.Minute)
+	runsOnSchedule(everyMinute)
+	runsOnSchedule(everyMinute)
+
+	if assert.Len(t, posted.messages, 1) {
+		assert.Equal(t, postedMessage{channel: "Csupport", threadTS: slackTimestamp(third), text: ":rotating_light: This request from <@Uasker> has been waiting for a response for 43m (SLA is 30m)"}, posted.messages[0])
+	}
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Csupport", User: "Uasker", Text: "<@bot> sla stats"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":bar_chart: *Support stats for the last 24 hours*\n• Requests: 3 (1 unanswered)\n• First response: median 10m, p90 40m\n• Within SLA (30m): 50%")
+	})
+
+	clock.now = start.Add(24 * time.Hour)
+	assertplugin.RunsOnSchedule(p, schedule.New().WithUnit(schedule.Days).AtTime("09:00").Build(), func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, map[string][]string{"Csupport": {":bar_chart: *Support stats for the last 24 hours*\n• Requests: 3 (1 unanswered)\n• First response: median 10m, p90 40m\n• Within SLA (30m): 50%"}}, sentMsgs)
+	})
+
+	clock.now = start.Add(48 * time.Hour)
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Csupport", User: "Uasker", Text: "<@bot> sla stats"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":bar_chart: No support requests in the last 24 hours")
+	})
+}
+
+func TestSupportSLAOnlyCountsResponders(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	storer, err := store.NewLevelDB("supportSLATest", tmpdir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	c := viper.New()
+	c.Set("channelIDs", []string{"Csupport"})
+	c.Set("responderIDs", []string{"Usupport"})
+
+	start := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	p, err := newSupportSLA(c, storer, func() time.Time { return start.Add(time.Hour) })
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	for _, m := range []*slack.Msg{
+		{Channel: "Csupport", User: "Uasker", Timestamp: slackTimestamp(start), Text: "help"},
+		{Channel: "Csupport", User: "Ucolleague", Timestamp: slackTimestamp(start.Add(time.Minute)), ThreadTimestamp: slackTimestamp(start), Text: "+1"},
+		{Channel: "Csupport", User: "Usupport", Timestamp: slackTimestamp(start.Add(5 * time.Minute)), ThreadTimestamp: slackTimestamp(start), Text: "on it"},
+	} {
+		assertplugin.AnswersAndReacts(p, m, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		})
+	}
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Csupport", User: "Uasker", Text: "<@bot> sla stats"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], ":bar_chart: *Support stats for the last 24 hours*\n• Requests: 1 (0 unanswered)\n• First response: median 5m, p90 5m\n• Within SLA (30m): 100%")
+	})
+}
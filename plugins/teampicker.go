@@ -0,0 +1,300 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/store"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TeamPickerPluginName holds identifying name for the team picker plugin
+	TeamPickerPluginName = "teamPicker"
+)
+
+// Configuration keys
+const (
+	teamPickerRecencyKey = "recency" // How long someone picked for a role is de-prioritized for that role, defaults to 14 days (336h)
+)
+
+const (
+	defaultTeamPickerRecency = 14 * 24 * time.Hour
+	teamRosterSiloFmt        = "roster:%s"
+	teamPicksSiloFmt         = "picks:%s"
+)
+
+var rosterAddRegex = regexp.MustCompile("(?i)\\Aroster add ((?:<@[A-Z0-9]+>\\s*)+)\\z")
+var rosterRemoveRegex = regexp.MustCompile("(?i)\\Aroster remove ((?:<@[A-Z0-9]+>\\s*)+)\\z")
+var pickRoleRegex = regexp.MustCompile("(?i)\\Apick ([\\w-]+)\\z")
+var rosterMentionRegex = regexp.MustCompile("<@([A-Z0-9]+)>")
+
+// TeamPicker holds the plugin data for the team picker plugin
+type TeamPicker struct {
+	*slackscot.Plugin
+	storer  store.GlobalSiloStringStorer
+	recency time.Duration
+	now     func() time.Time
+
+	// Guards the random source which isn't safe for concurrent use
+	sync.Mutex
+	random *rand.Rand
+}
+
+// NewTeamPicker creates a new instance of the team picker plugin. Each channel keeps its own roster to pick from
+// for any role (i.e. reviewer or presenter). People recently picked for a role are less likely to be picked for
+// it again until the recency period is over
+func NewTeamPicker(c *config.PluginConfig, storer store.GlobalSiloStringStorer) (p *slackscot.Plugin, err error) {
+	tp, err := newTeamPicker(c, storer, time.Now, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if err != nil {
+		return nil, err
+	}
+
+	return tp.Plugin, nil
+}
+
+// newTeamPicker creates a new instance of the team picker plugin with the given function to get the current time and random source
+func newTeamPicker(c *config.PluginConfig, storer store.GlobalSiloStringStorer, now func() time.Time, random *rand.Rand) (tp *TeamPicker, err error) {
+	c.SetDefault(teamPickerRecencyKey, defaultTeamPickerRecency)
+
+	tp = new(TeamPicker)
+	tp.storer = storer
+	tp.now = now
+	tp.random = random
+	tp.recency = c.GetDuration(teamPickerRecencyKey)
+
+	if tp.recency < 0 {
+		return nil, fmt.Errorf("Invalid %s recency [%s], it should be 0 or more", TeamPickerPluginName, tp.recency)
+	}
+
+	tp.Plugin = plugin.New(TeamPickerPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return rosterAddRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("roster add @someone [@someone-else...]").
+			WithDescription("Add people to this channel's roster").
+			WithAnswerer(tp.addToRoster).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return rosterRemoveRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("roster remove @someone [@someone-else...]").
+			WithDescription("Remove people from this channel's roster").
+			WithAnswerer(tp.removeFromRoster).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return strings.EqualFold(m.NormalizedText, "roster")
+			}).
+			WithUsage("roster").
+			WithDescription("List this channel's roster along with how often everyone was picked").
+			WithAnswerer(tp.listRoster).
+			Build()).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return pickRoleRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("pick <role>").
+			WithDescription("Pick someone from this channel's roster for a role (i.e. `pick reviewer`), never the requester").
+			WithAnswerer(tp.pick).
+			Build()).
+		Build()
+
+	return tp, nil
+}
+
+// addToRoster adds the mentioned people to the channel's roster
+func (tp *TeamPicker) addToRoster(m *slackscot.IncomingMessage) *slackscot.Answer {
+	userIDs := extractRosterMentions(rosterAddRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	for _, userID := range userIDs {
+		if err := tp.storer.PutSiloString(fmt.Sprintf(teamRosterSiloFmt, m.Channel), userID, "true"); err != nil {
+			tp.Logger.Printf("[%s] Error adding [%s] to the roster of [%s]: %v", TeamPickerPluginName, userID, m.Channel, err)
+			return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't add <@%s> :disappointed: (%v)", userID, err)}
+		}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Added %s to the roster :clipboard:", formatRosterMentions(userIDs))}
+}
+
+// removeFromRoster removes the mentioned people from the channel's roster
+func (tp *TeamPicker) removeFromRoster(m *slackscot.IncomingMessage) *slackscot.Answer {
+	userIDs := extractRosterMentions(rosterRemoveRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	for _, userID := range userIDs {
+		if err := tp.storer.DeleteSiloString(fmt.Sprintf(teamRosterSiloFmt, m.Channel), userID); err != nil {
+			tp.Logger.Printf("[%s] Error removing [%s] from the roster of [%s]: %v", TeamPickerPluginName, userID, m.Channel, err)
+			return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't remove <@%s> :disappointed: (%v)", userID, err)}
+		}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("Removed %s from the roster", formatRosterMentions(userIDs))}
+}
+
+// listRoster answers with the channel's roster and how many times everyone was picked for each role
+func (tp *TeamPicker) listRoster(m *slackscot.IncomingMessage) *slackscot.Answer {
+	roster, err := tp.roster(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the roster :disappointed: (%v)", err)}
+	}
+
+	if len(roster) == 0 {
+		return &slackscot.Answer{Text: "The roster is empty, add people with `roster add @someone`"}
+	}
+
+	picks, err := tp.picks(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load past picks :disappointed: (%v)", err)}
+	}
+
+	countsByUser := make(map[string][]string)
+	for key, p := range picks {
+		parts := strings.SplitN(key, ":", 2)
+		countsByUser[parts[1]] = append(countsByUser[parts[1]], fmt.Sprintf("%s ×%d", parts[0], p.Count))
+	}
+
+	lines := make([]string, 0, len(roster))
+	for _, userID := range roster {
+		counts := countsByUser[userID]
+		if len(counts) == 0 {
+			lines = append(lines, fmt.Sprintf("• <@%s>: never picked", userID))
+			continue
+		}
+
+		sort.Strings(counts)
+		lines = append(lines, fmt.Sprintf("• <@%s>: %s", userID, strings.Join(counts, ", ")))
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("*Roster* :clipboard:\n%s", strings.Join(lines, "\n"))}
+}
+
+// pick picks someone from the roster for a role at random, de-prioritizing people recently picked for that role,
+// and records the pick
+func (tp *TeamPicker) pick(m *slackscot.IncomingMessage) *slackscot.Answer {
+	role := strings.ToLower(pickRoleRegex.FindStringSubmatch(m.NormalizedText)[1])
+
+	roster, err := tp.roster(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load the roster :disappointed: (%v)", err)}
+	}
+
+	candidates := make([]string, 0, len(roster))
+	for _, userID := range roster {
+		if userID != m.User {
+			candidates = append(candidates, userID)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return &slackscot.Answer{Text: "There's nobody to pick from, add people with `roster add @someone`"}
+	}
+
+	picks, err := tp.picks(m.Channel)
+	if err != nil {
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't load past picks :disappointed: (%v)", err)}
+	}
+
+	now := tp.now()
+	weights := make([]float64, len(candidates))
+	for i, userID := range candidates {
+		weights[i] = lunchWeight(picks[teamPickKey(role, userID)], now, tp.recency)
+	}
+
+	tp.Lock()
+	picked := candidates[pickWeighted(weights, tp.random.Float64())]
+	tp.Unlock()
+
+	p := picks[teamPickKey(role, picked)]
+	p.Count = p.Count + 1
+	p.LastPicked = now
+
+	encoded, err := json.Marshal(p)
+	if err == nil {
+		err = tp.storer.PutSiloString(fmt.Sprintf(teamPicksSiloFmt, m.Channel), teamPickKey(role, picked), string(encoded))
+	}
+
+	if err != nil {
+		tp.Logger.Printf("[%s] Error recording pick of [%s] as [%s] on [%s]: %v", TeamPickerPluginName, picked, role, m.Channel, err)
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf(":game_die: <@%s>, you're the %s!", picked, role)}
+}
+
+// roster returns the user IDs of a channel's roster, sorted
+func (tp *TeamPicker) roster(channelID string) (userIDs []string, err error) {
+	entries, err := tp.storer.ScanSilo(fmt.Sprintf(teamRosterSiloFmt, channelID))
+	if err != nil {
+		tp.Logger.Printf("[%s] Error loading the roster of [%s]: %v", TeamPickerPluginName, channelID, err)
+		return nil, err
+	}
+
+	userIDs = make([]string, 0, len(entries))
+	for userID := range entries {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+
+	return userIDs, nil
+}
+
+// picks returns the picks of a channel keyed by role and user ID
+func (tp *TeamPicker) picks(channelID string) (picks map[string]lunchPicks, err error) {
+	entries, err := tp.storer.ScanSilo(fmt.Sprintf(teamPicksSiloFmt, channelID))
+	if err != nil {
+		tp.Logger.Printf("[%s] Error loading picks of [%s]: %v", TeamPickerPluginName, channelID, err)
+		return nil, err
+	}
+
+	picks = make(map[string]lunchPicks)
+	for key, encoded := range entries {
+		var p lunchPicks
+		if err := json.Unmarshal([]byte(encoded), &p); err != nil {
+			tp.Logger.Printf("[%s] Ignoring invalid picks [%s] on [%s]: %v", TeamPickerPluginName, key, channelID, err)
+			continue
+		}
+
+		picks[key] = p
+	}
+
+	return picks, nil
+}
+
+// teamPickKey returns the key of the picks of a user for a role
+func teamPickKey(role string, userID string) string {
+	return fmt.Sprintf("%s:%s", role, userID)
+}
+
+// extractRosterMentions returns the distinct user IDs mentioned in the text
+func extractRosterMentions(text string) (userIDs []string) {
+	userIDs = make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, match := range rosterMentionRegex.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			userIDs = append(userIDs, match[1])
+		}
+	}
+
+	return userIDs
+}
+
+// formatRosterMentions renders user IDs as a list of mentions
+func formatRosterMentions(userIDs []string) string {
+	mentions := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		mentions[i] = fmt.Sprintf("<@%s>", userID)
+	}
+
+	return strings.Join(mentions, ", ")
+}
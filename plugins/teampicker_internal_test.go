@@ -0,0 +1,91 @@
+package plugins
+
+import (
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTeamPickerInvalidRecency(t *testing.T) {
+	c := viper.New()
+	c.Set("recency", "-1h")
+
+	_, err := NewTeamPicker(c, nil)
+	assert.EqualError(t, err, "Invalid teamPicker recency [-1h0m0s], it should be 0 or more")
+}
+
+func TestTeamPickerRosterManagement(t *testing.T) {
+	storer, cleanup := NewTestStorer(t, "teamPickerTest")
+	defer cleanup()
+
+	tp, err := newTeamPicker(viper.New(), storer, time.Now, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		channel        string
+		user           string
+		text           string
+		expectedAnswer string
+	}{
+		{"Cdev", "U1", "<@bot> roster", "The roster is empty, add people with `roster add @someone`"},
+		{"Cdev", "U1", "<@bot> pick reviewer", "There's nobody to pick from, add people with `roster add @someone`"},
+		{"Cdev", "U1", "<@bot> roster add <@U1> <@U2> <@U2>", "Added <@U1>, <@U2> to the roster :clipboard:"},
+		{"Cdev", "U1", "<@bot> pick reviewer", ":game_die: <@U2>, you're the reviewer!"},
+		{"Cdev", "U2", "<@bot> pick presenter", ":game_die: <@U1>, you're the presenter!"},
+		{"Cdev", "U1", "<@bot> roster", "*Roster* :clipboard:\n• <@U1>: presenter ×1\n• <@U2>: reviewer ×1"},
+		{"Cdev", "U1", "<@bot> roster remove <@U2>", "Removed <@U2> from the roster"},
+		{"Cdev", "U1", "<@bot> pick reviewer", "There's nobody to pick from, add people with `roster add @someone`"},
+		{"Cother", "U1", "<@bot> roster", "The roster is empty, add people with `roster add @someone`"},
+	}
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assertplugin.AnswersAndReacts(tp.Plugin, &slack.Msg{Channel: tc.channel, User: tc.user, Text: tc.text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], tc.expectedAnswer)
+			})
+		})
+	}
+}
+
+func TestTeamPickerDeprioritizesRecentPicks(t *testing.T) {
+	storer, cleanup := NewTestStorer(t, "teamPickerTest")
+	defer cleanup()
+
+	now := time.Date(2023, time.March, 14, 12, 0, 0, 0, time.UTC)
+	tp, err := newTeamPicker(viper.New(), storer, func() time.Time { return now }, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(tp.Plugin, &slack.Msg{Channel: "Cdev", User: "U0", Text: "<@bot> roster add <@U1> <@U2> <@U3>"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1)
+	})
+
+	// Recent picks get a weight of 0.1 against 1 for the others so about 11 repeats are expected over 50 rounds
+	// of 3 picks, compared to 50 if everyone always had the same chance
+	repeats := 0
+	for round := 0; round < 50; round++ {
+		picked := make(map[string]bool)
+		for i := 0; i < 3; i++ {
+			assertplugin.AnswersAndReacts(tp.Plugin, &slack.Msg{Channel: "Cdev", User: "U0", Text: "<@bot> pick presenter"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+				if picked[answers[0].Text] {
+					repeats++
+				}
+				picked[answers[0].Text] = true
+				return assert.Len(t, answers, 1)
+			})
+		}
+
+		now = now.Add(15 * 24 * time.Hour)
+	}
+
+	assert.True(t, repeats < 25, "expected less than 25 repeats but got %d", repeats)
+}
@@ -0,0 +1,116 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/slack-go/slack"
+	"regexp"
+)
+
+const (
+	// ThreadSummarizerPluginName holds identifying name for the thread summarizer plugin
+	ThreadSummarizerPluginName = "threadSummarizer"
+	threadRepliesPageSize      = 200
+)
+
+var summarizeThreadRegex = regexp.MustCompile("(?i)\\Asummarize (?:this )?thread\\s*\\z")
+
+// ThreadMessage is a message of a thread handed to a Summarizer
+type ThreadMessage struct {
+	UserID string
+	Text   string
+}
+
+// Summarizer is implemented by summarization providers (i.e. a client of an LLM API or a local model). Implementations
+// are responsible for their own credentials which should be loaded by the caller when creating them
+type Summarizer interface {
+	// Summarize returns a summary of the thread messages, in the order they were posted
+	Summarize(messages []ThreadMessage) (summary string, err error)
+}
+
+// SummarizerFunc is an adapter to allow the use of a function as a Summarizer
+type SummarizerFunc func(messages []ThreadMessage) (summary string, err error)
+
+// Summarize calls f(messages)
+func (f SummarizerFunc) Summarize(messages []ThreadMessage) (summary string, err error) {
+	return f(messages)
+}
+
+// ThreadSummarizer holds the plugin data for the thread summarizer plugin
+type ThreadSummarizer struct {
+	*slackscot.Plugin
+	summarizer Summarizer
+}
+
+// NewThreadSummarizer creates a new instance of the thread summarizer plugin. The thread history is read with
+// the injected SlackClient and summarized by the given Summarizer
+func NewThreadSummarizer(summarizer Summarizer) (p *slackscot.Plugin) {
+	ts := new(ThreadSummarizer)
+	ts.summarizer = summarizer
+
+	ts.Plugin = plugin.New(ThreadSummarizerPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return summarizeThreadRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("summarize this thread").
+			WithDescription("Summarizes the thread the command is sent in").
+			WithAnswerer(ts.summarizeThread).
+			Build()).
+		Build()
+
+	return ts.Plugin
+}
+
+// summarizeThread answers with a summary of the thread the message is part of
+func (ts *ThreadSummarizer) summarizeThread(m *slackscot.IncomingMessage) *slackscot.Answer {
+	if !m.IsThreadReply() {
+		return &slackscot.Answer{Text: "I can only summarize a thread when asked from within it :thread:"}
+	}
+
+	messages, err := ts.getThreadMessages(m.Channel, m.ThreadID(), m.Timestamp)
+	if err != nil {
+		ts.Logger.Printf("[%s] Error getting messages of thread [%s] on channel [%s]: %v", ThreadSummarizerPluginName, m.ThreadID(), m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't read this thread :disappointed: (%v)", err)}
+	}
+
+	if len(messages) == 0 {
+		return &slackscot.Answer{Text: "There's nothing to summarize yet :shrug:"}
+	}
+
+	summary, err := ts.summarizer.Summarize(messages)
+	if err != nil {
+		ts.Logger.Printf("[%s] Error summarizing thread [%s] on channel [%s]: %v", ThreadSummarizerPluginName, m.ThreadID(), m.Channel, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't summarize this thread :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: fmt.Sprintf("*Thread summary* :memo:\n%s", summary), Options: []slackscot.AnswerOption{slackscot.AnswerInExistingThread(m.ThreadID())}}
+}
+
+// getThreadMessages returns all messages of a thread (going through all pages of replies) except for the one
+// with the excluded timestamp (the summarize command itself)
+func (ts *ThreadSummarizer) getThreadMessages(channelID string, threadID string, excludedTimestamp string) (messages []ThreadMessage, err error) {
+	messages = make([]ThreadMessage, 0)
+	params := slack.GetConversationRepliesParameters{ChannelID: channelID, Timestamp: threadID, Limit: threadRepliesPageSize}
+
+	for {
+		replies, hasMore, nextCursor, err := ts.SlackClient.GetConversationReplies(&params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range replies {
+			if r.Timestamp != excludedTimestamp && r.Text != "" {
+				messages = append(messages, ThreadMessage{UserID: r.User, Text: r.Text})
+			}
+		}
+
+		if !hasMore || nextCursor == "" {
+			return messages, nil
+		}
+
+		params.Cursor = nextCursor
+	}
+}
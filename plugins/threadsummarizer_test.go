@@ -0,0 +1,82 @@
+package plugins_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newThreadRepliesServer() (testServer *slacktest.Server) {
+	handler := func(c slacktest.Customize) {
+		c.Handle("/conversations.replies", func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			if r.Form.Get("cursor") == "" {
+				_, _ = w.Write([]byte(`{"ok": true, "messages": [{"user": "U1", "text": "Should we ship on friday?", "ts": "1546833210.036900", "thread_ts": "1546833210.036900"}, {"user": "U2", "text": "No, monday", "ts": "1546833211.036900", "thread_ts": "1546833210.036900"}], "has_more": true, "response_metadata": {"next_cursor": "page2"}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"ok": true, "messages": [{"user": "U1", "text": "<@bot> summarize this thread", "ts": "1546833215.036900", "thread_ts": "1546833210.036900"}], "has_more": false}`))
+			}
+		})
+	}
+
+	testServer = slacktest.NewTestServer(handler)
+	testServer.Start()
+
+	return testServer
+}
+
+func TestThreadSummarizer(t *testing.T) {
+	testServer := newThreadRepliesServer()
+	defer testServer.Stop()
+
+	var summarized []plugins.ThreadMessage
+	p := plugins.NewThreadSummarizer(plugins.SummarizerFunc(func(messages []plugins.ThreadMessage) (summary string, err error) {
+		summarized = messages
+		return fmt.Sprintf("%d messages: shipping monday", len(messages)), nil
+	}))
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> summarize this thread", Timestamp: "1546833215.036900", ThreadTimestamp: "1546833210.036900"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "*Thread summary* :memo:\n2 messages: shipping monday") && assertanswer.HasOptions(t, answers[0], assertanswer.ResolvedAnswerOption{Key: slackscot.ThreadedReplyOpt, Value: "true"}, assertanswer.ResolvedAnswerOption{Key: slackscot.ThreadTimestamp, Value: "1546833210.036900"})
+	})
+
+	assert.Equal(t, []plugins.ThreadMessage{{UserID: "U1", Text: "Should we ship on friday?"}, {UserID: "U2", Text: "No, monday"}}, summarized)
+}
+
+func TestThreadSummarizerOutsideOfThread(t *testing.T) {
+	p := plugins.NewThreadSummarizer(plugins.SummarizerFunc(func(messages []plugins.ThreadMessage) (summary string, err error) {
+		return "", fmt.Errorf("should not be called")
+	}))
+
+	assertplugin := assertplugin.New(t, "bot")
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> summarize this thread", Timestamp: "1546833215.036900"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "I can only summarize a thread when asked from within it :thread:")
+	})
+}
+
+func TestThreadSummarizerProviderError(t *testing.T) {
+	testServer := newThreadRepliesServer()
+	defer testServer.Stop()
+
+	var b strings.Builder
+	p := plugins.NewThreadSummarizer(plugins.SummarizerFunc(func(messages []plugins.ThreadMessage) (summary string, err error) {
+		return "", fmt.Errorf("quota exceeded")
+	}))
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot", assertplugin.OptionLog(log.New(&b, "", 0)))
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", Text: "<@bot> summarize thread", Timestamp: "1546833215.036900", ThreadTimestamp: "1546833210.036900"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, I couldn't summarize this thread :disappointed: (quota exceeded)")
+	})
+
+	assert.Contains(t, b.String(), "Error summarizing thread [1546833210.036900] on channel [Cgeneral]: quota exceeded")
+}
@@ -289,7 +289,9 @@ func (t *Triggerer) reactOnEmojiTriggers(m *slackscot.IncomingMessage, emojiTrig
 
 		if exp.MatchString(m.NormalizedText) {
 			for _, emoji := range parseEmojiList(reaction) {
-				t.EmojiReactor.AddReaction(emoji, slack.NewRefToMessage(m.Channel, m.Timestamp))
+				if err := t.EmojiReactor.AddReaction(emoji, slack.NewRefToMessage(m.Channel, m.Timestamp)); err != nil {
+					t.Logger.Printf("Error reacting with [%s] on trigger [%s]: %v", emoji, trigger, err)
+				}
 			}
 		}
 	}
@@ -0,0 +1,248 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/plugin"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/hashicorp/golang-lru"
+	"github.com/slack-go/slack"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// XkcdPluginName holds identifying name for the xkcd plugin
+	XkcdPluginName = "xkcd"
+)
+
+// Configuration keys
+const (
+	xkcdBaseURLKey     = "baseURL"     // Base URL of the xkcd API, defaults to https://xkcd.com
+	xkcdChannelIDsKey  = "channelIDs"  // Channels where the comic of the day is posted, defaults to none (no daily post)
+	xkcdAtTimeKey      = "atTime"      // Time of the day the comic of the day is posted, defaults to 09:00
+	xkcdLatestCacheKey = "latestCache" // How long the latest comic is cached for, defaults to 1h
+)
+
+const (
+	defaultXkcdBaseURL     = "https://xkcd.com"
+	defaultXkcdAtTime      = "09:00"
+	defaultXkcdLatestCache = time.Hour
+	xkcdComicCacheSize     = 256
+	xkcdRequestTimeout     = 10 * time.Second
+	// Comic 404 famously doesn't exist
+	xkcdMissingComic = 404
+)
+
+var xkcdRegex = regexp.MustCompile("(?i)\\Axkcd(?:\\s+(\\d+|random|latest))?\\z")
+
+// xkcdComic holds the metadata of a comic as returned by the xkcd API
+type xkcdComic struct {
+	Num       int    `json:"num"`
+	SafeTitle string `json:"safe_title"`
+	Img       string `json:"img"`
+	Alt       string `json:"alt"`
+}
+
+// Xkcd holds the plugin data for the xkcd plugin
+type Xkcd struct {
+	*slackscot.Plugin
+	baseURL     string
+	channelIDs  []string
+	latestCache time.Duration
+	client      *http.Client
+
+	// Comics never change once published so their metadata is cached by number. Only the
+	// latest comic expires to pick up new ones
+	comics        *lru.ARCCache
+	latestMutex   sync.Mutex
+	latest        *xkcdComic
+	latestFetched time.Time
+	lastPosted    int
+
+	randomMutex sync.Mutex
+	random      *rand.Rand
+}
+
+// NewXkcd creates a new instance of the xkcd plugin. If channels are configured, the comic of the day (the latest one
+// if new, a random one otherwise) is posted to them daily
+func NewXkcd(c *config.PluginConfig) (p *slackscot.Plugin, err error) {
+	c.SetDefault(xkcdBaseURLKey, defaultXkcdBaseURL)
+	c.SetDefault(xkcdAtTimeKey, defaultXkcdAtTime)
+	c.SetDefault(xkcdLatestCacheKey, defaultXkcdLatestCache)
+
+	x := new(Xkcd)
+	x.baseURL = strings.TrimSuffix(c.GetString(xkcdBaseURLKey), "/")
+	x.channelIDs = c.GetStringSlice(xkcdChannelIDsKey)
+	x.latestCache = c.GetDuration(xkcdLatestCacheKey)
+	x.client = &http.Client{Timeout: xkcdRequestTimeout}
+	x.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	if x.comics, err = lru.NewARC(xkcdComicCacheSize); err != nil {
+		return nil, err
+	}
+
+	pb := plugin.New(XkcdPluginName).
+		WithCommand(actions.NewCommand().
+			WithMatcher(func(m *slackscot.IncomingMessage) bool {
+				return xkcdRegex.MatchString(m.NormalizedText)
+			}).
+			WithUsage("xkcd [<number>|random|latest]").
+			WithDescription("Show an xkcd comic (the latest one by default)").
+			WithAnswerer(x.showComic).
+			Build())
+
+	if len(x.channelIDs) > 0 {
+		pb = pb.WithScheduledAction(actions.NewScheduledAction().
+			WithSchedule(schedule.New().WithUnit(schedule.Days).AtTime(c.GetString(xkcdAtTimeKey)).Build()).
+			WithDescription("Post the xkcd comic of the day").
+			WithAction(x.postComicOfTheDay).
+			Build())
+	}
+
+	x.Plugin = pb.Build()
+
+	return x.Plugin, nil
+}
+
+// showComic answers with the requested comic
+func (x *Xkcd) showComic(m *slackscot.IncomingMessage) *slackscot.Answer {
+	var comic *xkcdComic
+	var err error
+
+	switch which := strings.ToLower(xkcdRegex.FindStringSubmatch(m.NormalizedText)[1]); which {
+	case "", "latest":
+		comic, err = x.getLatest()
+	case "random":
+		comic, err = x.getRandom()
+	default:
+		num, _ := strconv.Atoi(which)
+		comic, err = x.getComic(num)
+	}
+
+	if err != nil {
+		x.Logger.Printf("[%s] Error getting comic: %v", XkcdPluginName, err)
+		return &slackscot.Answer{Text: fmt.Sprintf("Sorry, I couldn't get that comic :disappointed: (%v)", err)}
+	}
+
+	return &slackscot.Answer{Text: comicFallbackText(comic), ContentBlocks: renderComic(comic)}
+}
+
+// postComicOfTheDay posts the latest comic if it wasn't posted yet or a random one otherwise
+func (x *Xkcd) postComicOfTheDay() {
+	comic, err := x.getLatest()
+	if err == nil && comic.Num == x.lastPosted {
+		comic, err = x.getRandom()
+	} else if err == nil {
+		x.lastPosted = comic.Num
+	}
+
+	if err != nil {
+		x.Logger.Printf("[%s] Error getting comic of the day: %v", XkcdPluginName, err)
+		return
+	}
+
+	for _, channelID := range x.channelIDs {
+		if _, _, err := x.SlackClient.PostMessage(channelID, slack.MsgOptionText(comicFallbackText(comic), false), slack.MsgOptionBlocks(renderComic(comic)...), slack.MsgOptionAsUser(true)); err != nil {
+			x.Logger.Printf("[%s] Error posting comic of the day on [%s]: %v", XkcdPluginName, channelID, err)
+		}
+	}
+}
+
+// getLatest returns the latest comic, fetching it again once the cached one expires
+func (x *Xkcd) getLatest() (comic *xkcdComic, err error) {
+	x.latestMutex.Lock()
+	defer x.latestMutex.Unlock()
+
+	if x.latest != nil && time.Since(x.latestFetched) < x.latestCache {
+		return x.latest, nil
+	}
+
+	comic, err = x.fetch(fmt.Sprintf("%s/info.0.json", x.baseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	x.latest = comic
+	x.latestFetched = time.Now()
+	x.comics.Add(comic.Num, comic)
+
+	return comic, nil
+}
+
+// getRandom returns a random comic
+func (x *Xkcd) getRandom() (comic *xkcdComic, err error) {
+	latest, err := x.getLatest()
+	if err != nil {
+		return nil, err
+	}
+
+	x.randomMutex.Lock()
+	num := x.random.Intn(latest.Num) + 1
+	x.randomMutex.Unlock()
+
+	if num == xkcdMissingComic {
+		num++
+	}
+
+	return x.getComic(num)
+}
+
+// getComic returns a comic by number
+func (x *Xkcd) getComic(num int) (comic *xkcdComic, err error) {
+	if cached, ok := x.comics.Get(num); ok {
+		return cached.(*xkcdComic), nil
+	}
+
+	comic, err = x.fetch(fmt.Sprintf("%s/%d/info.0.json", x.baseURL, num))
+	if err != nil {
+		return nil, err
+	}
+
+	x.comics.Add(num, comic)
+
+	return comic, nil
+}
+
+// fetch gets and decodes the comic metadata at the given URL
+func (x *Xkcd) fetch(url string) (comic *xkcdComic, err error) {
+	resp, err := x.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("comic not found")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status [%s] from [%s]", resp.Status, url)
+	}
+
+	comic = new(xkcdComic)
+	err = json.NewDecoder(resp.Body).Decode(comic)
+
+	return comic, err
+}
+
+// comicFallbackText returns the text of a comic message for clients that can't show blocks
+func comicFallbackText(comic *xkcdComic) string {
+	return fmt.Sprintf("xkcd #%d: %s", comic.Num, comic.SafeTitle)
+}
+
+// renderComic renders a comic as an image block followed by its alt-text
+func renderComic(comic *xkcdComic) (blocks []slack.Block) {
+	return []slack.Block{
+		slack.NewImageBlock(comic.Img, comic.SafeTitle, "", slack.NewTextBlockObject(slack.PlainTextType, comicFallbackText(comic), false, false)),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("_%s_ · <https://xkcd.com/%d/|view on xkcd>", comic.Alt, comic.Num), false, false)),
+	}
+}
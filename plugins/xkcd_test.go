@@ -0,0 +1,152 @@
+package plugins_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/plugins"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/test/assertanswer"
+	"github.com/alexandre-normand/slackscot/test/assertplugin"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type xkcdRequests struct {
+	sync.Mutex
+	paths []string
+}
+
+func newXkcdServer(requests *xkcdRequests) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Lock()
+		requests.paths = append(requests.paths, r.URL.Path)
+		requests.Unlock()
+
+		switch r.URL.Path {
+		case "/info.0.json", "/2/info.0.json":
+			fmt.Fprint(w, `{"num": 2, "safe_title": "Petit Trees (sketch)", "img": "https://imgs.xkcd.com/comics/tree_cropped_(1).jpg", "alt": "'Petit' being a reference to Le Petit Prince"}`)
+		case "/1/info.0.json":
+			fmt.Fprint(w, `{"num": 1, "safe_title": "Barrel - Part 1", "img": "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg", "alt": "Don't we all."}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func newXkcdConfig(baseURL string) *viper.Viper {
+	c := viper.New()
+	c.Set("baseURL", baseURL)
+
+	return c
+}
+
+func TestXkcdShowsLatestComic(t *testing.T) {
+	requests := xkcdRequests{}
+	server := newXkcdServer(&requests)
+	defer server.Close()
+
+	p, err := plugins.NewXkcd(newXkcdConfig(server.URL))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	for _, text := range []string{"<@bot> xkcd", "<@bot> xkcd latest"} {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: text}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "xkcd #2: Petit Trees (sketch)") && assert.Len(t, answers[0].ContentBlocks, 2) &&
+				assert.Equal(t, "https://imgs.xkcd.com/comics/tree_cropped_(1).jpg", answers[0].ContentBlocks[0].(*slack.ImageBlock).ImageURL) &&
+				assert.Equal(t, "_'Petit' being a reference to Le Petit Prince_ · <https://xkcd.com/2/|view on xkcd>", answers[0].ContentBlocks[1].(*slack.ContextBlock).ContextElements.Elements[0].(*slack.TextBlockObject).Text)
+		})
+	}
+
+	// The latest comic is cached so it's only fetched once
+	assert.Equal(t, []string{"/info.0.json"}, requests.paths)
+}
+
+func TestXkcdShowsComicByNumber(t *testing.T) {
+	requests := xkcdRequests{}
+	server := newXkcdServer(&requests)
+	defer server.Close()
+
+	p, err := plugins.NewXkcd(newXkcdConfig(server.URL))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	for i := 0; i < 2; i++ {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> xkcd 1"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "xkcd #1: Barrel - Part 1")
+		})
+	}
+
+	assert.Equal(t, []string{"/1/info.0.json"}, requests.paths)
+}
+
+func TestXkcdUnknownComic(t *testing.T) {
+	requests := xkcdRequests{}
+	server := newXkcdServer(&requests)
+	defer server.Close()
+
+	p, err := plugins.NewXkcd(newXkcdConfig(server.URL))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> xkcd 9999"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Sorry, I couldn't get that comic :disappointed: (comic not found)")
+	})
+}
+
+func TestXkcdShowsRandomComic(t *testing.T) {
+	requests := xkcdRequests{}
+	server := newXkcdServer(&requests)
+	defer server.Close()
+
+	p, err := plugins.NewXkcd(newXkcdConfig(server.URL))
+	require.NoError(t, err)
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	for i := 0; i < 10; i++ {
+		assertplugin.AnswersAndReacts(p, &slack.Msg{Channel: "Cgeneral", User: "U21355", Text: "<@bot> xkcd random"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Len(t, answers, 1) && assert.True(t, strings.HasPrefix(answers[0].Text, "xkcd #1:") || strings.HasPrefix(answers[0].Text, "xkcd #2:"), answers[0].Text)
+		})
+	}
+}
+
+func TestXkcdWithoutChannelsDoesNotPostDaily(t *testing.T) {
+	p, err := plugins.NewXkcd(newXkcdConfig("https://xkcd.example.com"))
+	require.NoError(t, err)
+
+	assert.Empty(t, p.ScheduledActions)
+}
+
+func TestXkcdPostsComicOfTheDay(t *testing.T) {
+	requests := xkcdRequests{}
+	server := newXkcdServer(&requests)
+	defer server.Close()
+
+	calls := chatCalls{}
+	testServer := newChatServer(&calls)
+	defer testServer.Stop()
+
+	c := newXkcdConfig(server.URL)
+	c.Set("channelIDs", []string{"Cfun"})
+	c.Set("atTime", "10:30")
+
+	p, err := plugins.NewXkcd(c)
+	require.NoError(t, err)
+	p.SlackClient = slack.New("token", slack.OptionAPIURL(testServer.GetAPIURL()))
+
+	assertplugin := assertplugin.New(t, "bot")
+
+	assertplugin.RunsOnSchedule(p, schedule.New().WithUnit(schedule.Days).AtTime("10:30").Build(), func(t *testing.T, sentMsgs map[string][]string, fileUploads []slack.FileUploadParameters) bool {
+		return assert.Equal(t, []string{"post Cfun: xkcd #2: Petit Trees (sketch)"}, calls.calls)
+	})
+}
@@ -0,0 +1,243 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"sync"
+	"time"
+)
+
+// Indicators prefixing the messages of answers with a progress task while it runs and once it's done
+const (
+	progressRunningIndicator = "⏳"
+	progressDoneIndicator    = "✅"
+	progressFailedIndicator  = "❌"
+)
+
+// ProgressTask is a long-running task (i.e. generating a report or calling a slow API) started in the background once
+// the answer it's attached to is sent (see AnswerWithProgress). It reports its progress with the reporter and returns
+// its final answer which replaces the answer's message. An error replaces it with the error instead.
+//
+// The context is canceled when slackscot shuts down or when the task is superseded (the triggering message is edited,
+// running the action again, or deleted) so tasks should stop early when it's done
+type ProgressTask func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error)
+
+// AnswerWithProgress returns an answer with the given text that starts the task once sent. The answer's message is
+// updated with the progress reported by the task (i.e. "⏳ 30%… Crunching numbers") and then with its final answer
+// (i.e. "✅ Report ready"). Progress updates are sent at most every config.ProgressUpdateIntervalKey, only with the
+// latest progress reported, to stay within slack's rate limits.
+//
+// Ephemeral and scheduled answers can't be updated so their tasks are never started
+func AnswerWithProgress(text string, task ProgressTask) *Answer {
+	return &Answer{Text: fmt.Sprintf("%s %s", progressRunningIndicator, text), Progress: task}
+}
+
+// ProgressReporter reports the progress of a ProgressTask. Only the latest report is kept until it's sent so tasks can
+// report as often as they like
+type ProgressReporter struct {
+	mutex   sync.Mutex
+	percent int
+	status  string
+	pending bool
+}
+
+// Report reports the completion percentage (clamped between 0 and 100) and an optional status of the task
+func (p *ProgressReporter) Report(percent int, status string) {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.percent = percent
+	p.status = status
+	p.pending = true
+}
+
+// takeReport returns the latest report not sent yet, if any
+func (p *ProgressReporter) takeReport() (text string, pending bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.pending {
+		return "", false
+	}
+
+	p.pending = false
+	if p.status == "" {
+		return fmt.Sprintf("%s %d%%…", progressRunningIndicator, p.percent), true
+	}
+
+	return fmt.Sprintf("%s %d%%… %s", progressRunningIndicator, p.percent, p.status), true
+}
+
+// progressTask is a running ProgressTask, registered by the message of its answer
+type progressTask struct {
+	cancel context.CancelFunc
+
+	// triggeringMsgID is the message the updates of the answer are sequenced with (see messageSequencer)
+	triggeringMsgID SlackMessageID
+}
+
+// startProgressTask starts the progress task of an outgoing message sent (or updated) as the message identified by
+// rID in response to the triggering message. Answers to events other than messages (i.e. channel events) have no
+// triggering message and their updates are sequenced by their own message instead. A task already running for the
+// same message is superseded by the new one
+func (s *Slackscot) startProgressTask(updater messageUpdater, triggeringMsgID SlackMessageID, rID SlackMessageID, o OutgoingMessage) {
+	if o.Progress == nil {
+		return
+	}
+
+	if !rID.IsMsgModifiable() {
+		s.log.Printf("Not starting the progress task of [%s] since its answer [%s] can't be updated", o.pluginActionID, rID)
+		return
+	}
+
+	if triggeringMsgID == (SlackMessageID{}) {
+		triggeringMsgID = rID
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	t := &progressTask{cancel: cancel, triggeringMsgID: triggeringMsgID}
+
+	s.progressTasksMutex.Lock()
+	if previous, running := s.progressTasks[rID]; running {
+		previous.cancel()
+	}
+	s.progressTasks[rID] = t
+	s.progressTasksMutex.Unlock()
+
+	s.runningProgressTasks.Add(1)
+	go func() {
+		defer s.runningProgressTasks.Done()
+		defer cancel()
+
+		s.runProgressTask(ctx, updater, rID, o, t)
+	}()
+}
+
+// stopProgressTask cancels the progress task running for the message identified by rID, if any. Its message isn't
+// updated with the final state
+func (s *Slackscot) stopProgressTask(rID SlackMessageID) {
+	s.progressTasksMutex.Lock()
+	defer s.progressTasksMutex.Unlock()
+
+	if t, running := s.progressTasks[rID]; running {
+		t.cancel()
+		delete(s.progressTasks, rID)
+	}
+}
+
+// isCurrentProgressTask returns true if t is still the task running for the message identified by rID
+func (s *Slackscot) isCurrentProgressTask(rID SlackMessageID, t *progressTask) bool {
+	s.progressTasksMutex.Lock()
+	defer s.progressTasksMutex.Unlock()
+
+	return s.progressTasks[rID] == t
+}
+
+// runProgressTask runs a progress task, updating its message with the progress it reports every
+// config.ProgressUpdateIntervalKey and with its final state once done. Updates are sequenced with the other operations
+// on the responses to the triggering message and are skipped if the task was stopped or superseded before their turn
+// (i.e. when its answer was deleted)
+func (s *Slackscot) runProgressTask(ctx context.Context, updater messageUpdater, rID SlackMessageID, o OutgoingMessage, t *progressTask) {
+	reporter := new(ProgressReporter)
+
+	type result struct {
+		done *Answer
+		err  error
+	}
+
+	finished := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				finished <- result{err: fmt.Errorf("task panicked: %v", r)}
+			}
+		}()
+
+		done, err := o.Progress(ctx, reporter)
+		finished <- result{done: done, err: err}
+	}()
+
+	ticker := time.NewTicker(s.config.GetDuration(config.ProgressUpdateIntervalKey))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if text, pending := reporter.takeReport(); pending {
+				s.messageSequencer.do(t.triggeringMsgID, func() {
+					if s.isCurrentProgressTask(rID, t) {
+						s.updateProgressMessage(updater, rID, o, &Answer{Text: text})
+					}
+				})
+			}
+		case r := <-finished:
+			s.messageSequencer.do(t.triggeringMsgID, func() {
+				s.progressTasksMutex.Lock()
+				current := s.progressTasks[rID] == t
+				if current {
+					delete(s.progressTasks, rID)
+				}
+				s.progressTasksMutex.Unlock()
+
+				if !current {
+					s.log.Debugf("Progress task of [%s] superseded, leaving its answer [%s] as is", o.pluginActionID, rID)
+					return
+				}
+
+				s.updateProgressMessage(updater, rID, o, finalProgressAnswer(ctx, r.done, r.err))
+			})
+			return
+		}
+	}
+}
+
+// finalProgressAnswer returns the answer replacing the message of a finished progress task
+func finalProgressAnswer(ctx context.Context, done *Answer, err error) (final *Answer) {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return &Answer{Text: fmt.Sprintf("%s Interrupted", progressFailedIndicator)}
+	case err != nil:
+		return &Answer{Text: fmt.Sprintf("%s %v", progressFailedIndicator, err)}
+	case done == nil:
+		return &Answer{Text: fmt.Sprintf("%s Done", progressDoneIndicator)}
+	}
+
+	final = new(Answer)
+	*final = *done
+	final.Text = fmt.Sprintf("%s %s", progressDoneIndicator, done.Text)
+
+	return final
+}
+
+// updateProgressMessage updates the message of a progress task with the answer, keeping the mention of the user the
+// original answer replied to, if any
+func (s *Slackscot) updateProgressMessage(updater messageUpdater, rID SlackMessageID, o OutgoingMessage, answer *Answer) {
+	// Progress updates are new content so answer hooks run on them too
+	u := o
+	u.Answer = *answer
+	u.OutgoingMessage.Text = o.replyPrefix() + answer.Text
+	u.hooked = false
+
+	if _, err := s.updateExistingMessage(updater, rID, u); err != nil {
+		s.log.Printf("Unable to update progress of [%s] on [%s]: %v", o.pluginActionID, rID, err)
+	}
+}
+
+// waitForProgressTasks waits until the running progress tasks are done or the context is done, in which case the
+// context's error is returned
+func (s *Slackscot) waitForProgressTasks(ctx context.Context) (err error) {
+	done := make(chan struct{})
+	go func() {
+		s.runningProgressTasks.Wait()
+		close(done)
+	}()
+
+	return waitUntilClosed(ctx, done)
+}
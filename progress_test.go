@@ -0,0 +1,196 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// progressRecorder is a chat driver recording the texts of the messages sent and updated, safe for use by progress
+// tasks updating messages in the background
+type progressRecorder struct {
+	mutex   sync.Mutex
+	sent    []string
+	updates []string
+}
+
+func (r *progressRecorder) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	endpoint, vals, _ := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	r.sent = append(r.sent, vals.Get("text"))
+
+	if strings.Contains(endpoint, "chat.postEphemeral") {
+		return "", "", "", nil
+	}
+
+	return channelID, timestamp2, "", nil
+}
+
+func (r *progressRecorder) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.updates = append(r.updates, fmt.Sprintf("%s/%s: %s", channelID, timestamp, applySlackOptions(options...).Get("text")))
+	return channelID, timestamp, "", nil
+}
+
+func (r *progressRecorder) updatedTexts() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]string{}, r.updates...)
+}
+
+func newProgressSlackscot(t *testing.T) (s *Slackscot) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ProgressUpdateIntervalKey, 10*time.Millisecond)
+
+	s, err := New("BobbyTables", v, OptionLog(log.New(&strings.Builder{}, "", 0)))
+	require.NoError(t, err)
+
+	return s
+}
+
+func newProgressOutgoingMessage(answer *Answer) OutgoingMessage {
+	return newOutMessageForAnswer(newSlackOutgoingMessage("Cgeneral", fmt.Sprintf("<@Alphonse>: %s", answer.Text)), "reporter.command[0]", *answer)
+}
+
+func waitForProgressTasks(t *testing.T, s *Slackscot) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, s.waitForProgressTasks(ctx))
+}
+
+func TestAnswerWithProgressUpdatedWithProgressAndFinalAnswer(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := new(progressRecorder)
+
+	answer := AnswerWithProgress("Generating report", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		progress.Report(10, "Fetching data")
+		progress.Report(30, "Crunching numbers")
+		time.Sleep(50 * time.Millisecond)
+
+		return &Answer{Text: "Report ready"}, nil
+	})
+
+	s.sendOutgoingMessages(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, []OutgoingMessage{newProgressOutgoingMessage(answer)})
+	waitForProgressTasks(t, s)
+
+	assert.Equal(t, []string{"<@Alphonse>: ⏳ Generating report"}, recorder.sent)
+	assert.Equal(t, []string{
+		"Cgeneral/1546833214.036900: <@Alphonse>: ⏳ 30%… Crunching numbers",
+		"Cgeneral/1546833214.036900: <@Alphonse>: ✅ Report ready",
+	}, recorder.updatedTexts())
+}
+
+func TestAnswerWithProgressOfFailedTask(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := new(progressRecorder)
+
+	answer := AnswerWithProgress("Syncing", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		return nil, fmt.Errorf("api unavailable")
+	})
+
+	s.sendOutgoingMessages(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, []OutgoingMessage{newProgressOutgoingMessage(answer)})
+	waitForProgressTasks(t, s)
+
+	assert.Equal(t, []string{"Cgeneral/1546833214.036900: <@Alphonse>: ❌ api unavailable"}, recorder.updatedTexts())
+}
+
+func TestAnswerWithProgressOfPanickingTask(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := new(progressRecorder)
+
+	answer := AnswerWithProgress("Syncing", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		panic("oops")
+	})
+
+	s.sendOutgoingMessages(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, []OutgoingMessage{newProgressOutgoingMessage(answer)})
+	waitForProgressTasks(t, s)
+
+	assert.Equal(t, []string{"Cgeneral/1546833214.036900: <@Alphonse>: ❌ task panicked: oops"}, recorder.updatedTexts())
+}
+
+func TestEphemeralAnswerWithProgressNotStarted(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := new(progressRecorder)
+
+	started := false
+	answer := AnswerWithProgress("Syncing", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		started = true
+		return nil, nil
+	})
+	answer.Options = []AnswerOption{AnswerEphemeral("Alphonse")}
+
+	s.sendOutgoingMessages(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, []OutgoingMessage{newProgressOutgoingMessage(answer)})
+	waitForProgressTasks(t, s)
+
+	assert.False(t, started)
+	assert.Empty(t, recorder.updatedTexts())
+}
+
+func TestSupersededProgressTaskLeavesAnswerAsIs(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := new(progressRecorder)
+	rID := SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2}
+
+	first := AnswerWithProgress("Syncing", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	second := AnswerWithProgress("Syncing again", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		return &Answer{Text: "Synced"}, nil
+	})
+
+	s.startProgressTask(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, rID, newProgressOutgoingMessage(first))
+	s.startProgressTask(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, rID, newProgressOutgoingMessage(second))
+	waitForProgressTasks(t, s)
+
+	assert.Equal(t, []string{"Cgeneral/1546833214.036900: <@Alphonse>: ✅ Synced"}, recorder.updatedTexts())
+}
+
+func TestShutdownInterruptsProgressTasks(t *testing.T) {
+	s := newProgressSlackscot(t)
+	recorder := new(progressRecorder)
+
+	answer := AnswerWithProgress("Syncing", func(ctx context.Context, progress *ProgressReporter) (done *Answer, err error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	s.startProgressTask(recorder, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2}, newProgressOutgoingMessage(answer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	assert.Equal(t, []string{"Cgeneral/1546833214.036900: <@Alphonse>: ❌ Interrupted"}, recorder.updatedTexts())
+}
+
+func TestProgressReporterKeepsLatestReport(t *testing.T) {
+	p := new(ProgressReporter)
+
+	_, pending := p.takeReport()
+	assert.False(t, pending)
+
+	p.Report(20, "")
+	p.Report(120, "Almost")
+
+	text, pending := p.takeReport()
+	assert.True(t, pending)
+	assert.Equal(t, "⏳ 100%… Almost", text)
+
+	_, pending = p.takeReport()
+	assert.False(t, pending)
+}
@@ -0,0 +1,49 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+)
+
+// Quarantiner moves corrupted values (i.e. values that can't be parsed) out of a plugin's storer into its
+// store.QuarantineSilo and notifies the store admins (see config.StoreAdminIDsKey). This gives plugins a single way
+// to deal with bad data that doesn't silently lose it nor fail on every read of it
+type Quarantiner interface {
+	// Quarantine moves the value of the key in the silo to the storer's store.QuarantineSilo. The reason is kept
+	// with the value and included in the notification
+	Quarantine(storer store.SiloStringStorer, silo string, key string, reason error) (err error)
+}
+
+// quarantiner is the Quarantiner implementation injected in plugins
+type quarantiner struct {
+	plugin string
+	admins []string
+	sender messageSender
+	logger SLogger
+}
+
+// Quarantine moves a corrupted value to the storer's store.QuarantineSilo and notifies the store admins with a direct
+// message. Failures to notify are logged and don't fail the quarantine
+func (q *quarantiner) Quarantine(storer store.SiloStringStorer, silo string, key string, reason error) (err error) {
+	qv, err := store.Quarantine(storer, silo, key, reason)
+	if err != nil {
+		q.logger.Printf("[%s] Error quarantining [%s] in silo [%s]: %v", q.plugin, key, silo, err)
+		return err
+	}
+
+	q.logger.Printf("[%s] Quarantined value [%s] of [%s] in silo [%s]: %s", q.plugin, qv.Value, key, silo, qv.Reason)
+
+	if q.sender == nil {
+		return nil
+	}
+
+	text := fmt.Sprintf(":biohazard_sign: `%s` quarantined the corrupted value `%s` of `%s` in silo `%s` (%s). It's kept in the `%s` silo of its store", q.plugin, qv.Value, key, silo, qv.Reason, store.QuarantineSilo)
+	for _, adminID := range q.admins {
+		if _, _, _, err := q.sender.SendMessage(adminID, slack.MsgOptionText(text, false), slack.MsgOptionAsUser(true)); err != nil {
+			q.logger.Printf("[%s] Error notifying [%s] of quarantined value of [%s] in silo [%s]: %v", q.plugin, adminID, key, silo, err)
+		}
+	}
+
+	return nil
+}
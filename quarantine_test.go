@@ -0,0 +1,50 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestQuarantineNotifiesAdmins(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	require.NoError(t, storer.PutSiloString("Cgeneral", "thing", "abc"))
+
+	var logBuilder strings.Builder
+	driver := inMemoryChatDriver{timeCursor: firstReplyTimestamp - replyTimeIncrementInSeconds}
+	q := &quarantiner{plugin: "karma", admins: []string{"Alphonse", "Marie"}, sender: &driver, logger: NewSLogger(log.New(&logBuilder, "", 0), false)}
+
+	require.NoError(t, q.Quarantine(storer, "Cgeneral", "thing", fmt.Errorf("not a number")))
+
+	quarantined, err := store.ListQuarantined(storer)
+	require.NoError(t, err)
+	if assert.Len(t, quarantined, 1) {
+		assert.Equal(t, "abc", quarantined[0].Value)
+	}
+
+	if assert.Len(t, driver.sentMsgs, 2) {
+		assert.Equal(t, "Alphonse", driver.sentMsgs[0].channelID)
+		assert.Equal(t, "Marie", driver.sentMsgs[1].channelID)
+		assert.Equal(t, ":biohazard_sign: `karma` quarantined the corrupted value `abc` of `thing` in silo `Cgeneral` (not a number). It's kept in the `quarantine` silo of its store", applySlackOptions(driver.sentMsgs[0].msgOptions...).Get("text"))
+	}
+
+	assert.Contains(t, logBuilder.String(), "[karma] Quarantined value [abc] of [thing] in silo [Cgeneral]: not a number")
+}
+
+func TestQuarantineOfMissingValueFails(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	var logBuilder strings.Builder
+	driver := inMemoryChatDriver{timeCursor: firstReplyTimestamp - replyTimeIncrementInSeconds}
+	q := &quarantiner{plugin: "karma", admins: []string{"Alphonse"}, sender: &driver, logger: NewSLogger(log.New(&logBuilder, "", 0), false)}
+
+	assert.Error(t, q.Quarantine(storer, "Cgeneral", "thing", fmt.Errorf("not a number")))
+	assert.Empty(t, driver.sentMsgs)
+}
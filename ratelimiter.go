@@ -0,0 +1,190 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"sync"
+	"time"
+)
+
+// Minimum intervals between calls to slack's chat methods, derived from their rate limit tiers (see
+// https://api.slack.com/docs/rate-limits). Sending messages is limited to roughly one per second per
+// channel while updates, deletes and reactions are tier 3 methods (50+ per minute)
+const (
+	sendMessageInterval   = time.Second
+	updateMessageInterval = 1200 * time.Millisecond
+	deleteMessageInterval = 1200 * time.Millisecond
+	addReactionInterval   = 1200 * time.Millisecond
+)
+
+// Bounds of the exponential backoff between retries of calls failing with errors that don't come with
+// a Retry-After delay (i.e. slack server errors)
+const (
+	initialRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// retryable is implemented by slack errors that can be retried (i.e. slack.RateLimitedError and server errors)
+type retryable interface {
+	Retryable() bool
+}
+
+// callLimiter spaces calls by a minimum interval
+type callLimiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// rateLimitedChatDriver wraps a chatDriver to space calls to slack according to the rate limits of the methods and
+// to retry calls rejected by slack with the delay of their Retry-After header (or an exponential backoff). At most
+// queueSize calls can be waiting for their turn at once, others fail immediately rather than piling up
+type rateLimitedChatDriver struct {
+	chatDriver
+
+	maxRetries int
+	log        *sLogger
+
+	// limiters holds the limiter of each method (and channel, for sending messages), by key
+	limiters      map[string]*callLimiter
+	limitersMutex sync.Mutex
+
+	// waiting is a semaphore bounding the number of calls waiting for their turn
+	waiting chan struct{}
+
+	now   func() time.Time
+	sleep func(d time.Duration)
+}
+
+// newRateLimitedChatDriver creates a new rateLimitedChatDriver retrying calls at most maxRetries times and
+// holding at most queueSize waiting calls
+func newRateLimitedChatDriver(driver chatDriver, maxRetries int, queueSize int, log *sLogger) (rd *rateLimitedChatDriver) {
+	rd = new(rateLimitedChatDriver)
+	rd.chatDriver = driver
+	rd.maxRetries = maxRetries
+	rd.log = log
+	rd.limiters = make(map[string]*callLimiter)
+	rd.waiting = make(chan struct{}, queueSize)
+	rd.now = time.Now
+	rd.sleep = time.Sleep
+
+	return rd
+}
+
+// SendMessage sends a message, waiting for the channel's turn and retrying if rate limited
+func (rd *rateLimitedChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	err = rd.call(fmt.Sprintf("SendMessage/%s", channelID), sendMessageInterval, func() (err error) {
+		rChannelID, rTimestamp, rText, err = rd.chatDriver.SendMessage(channelID, options...)
+		return err
+	})
+
+	return rChannelID, rTimestamp, rText, err
+}
+
+// UpdateMessage updates a message, waiting for its turn and retrying if rate limited
+func (rd *rateLimitedChatDriver) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	err = rd.call("UpdateMessage", updateMessageInterval, func() (err error) {
+		rChannelID, rTimestamp, rText, err = rd.chatDriver.UpdateMessage(channelID, timestamp, options...)
+		return err
+	})
+
+	return rChannelID, rTimestamp, rText, err
+}
+
+// DeleteMessage deletes a message, waiting for its turn and retrying if rate limited
+func (rd *rateLimitedChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	err = rd.call("DeleteMessage", deleteMessageInterval, func() (err error) {
+		rChannelID, rTimestamp, err = rd.chatDriver.DeleteMessage(channelID, timestamp)
+		return err
+	})
+
+	return rChannelID, rTimestamp, err
+}
+
+// AddReaction adds a reaction to a message, waiting for its turn and retrying if rate limited
+func (rd *rateLimitedChatDriver) AddReaction(name string, item slack.ItemRef) (err error) {
+	return rd.call("AddReaction", addReactionInterval, func() (err error) {
+		return rd.chatDriver.AddReaction(name, item)
+	})
+}
+
+// call makes a call once its limiter allows it and retries it as long as it fails with retryable errors, up to maxRetries times
+func (rd *rateLimitedChatDriver) call(limiterKey string, interval time.Duration, f func() error) (err error) {
+	select {
+	case rd.waiting <- struct{}{}:
+		defer func() { <-rd.waiting }()
+	default:
+		return fmt.Errorf("Rate limited call queue is full (%d calls waiting), dropping [%s] call", cap(rd.waiting), limiterKey)
+	}
+
+	backoff := initialRetryBackoff
+	for attempt := 0; ; attempt++ {
+		rd.sleep(rd.reserve(limiterKey, interval))
+
+		err = f()
+		if err == nil {
+			return nil
+		}
+
+		if r, ok := err.(retryable); !ok || !r.Retryable() || attempt >= rd.maxRetries {
+			return err
+		}
+
+		retryAfter := backoff
+		if rateLimitedErr, ok := err.(*slack.RateLimitedError); ok {
+			retryAfter = rateLimitedErr.RetryAfter
+		} else {
+			backoff = minDuration(backoff*2, maxRetryBackoff)
+		}
+
+		rd.log.Printf("Retrying [%s] call in [%s] (attempt %d of %d) after error: %s", limiterKey, retryAfter, attempt+1, rd.maxRetries, err.Error())
+		rd.delay(limiterKey, interval, retryAfter)
+	}
+}
+
+// reserve takes the next turn of the limiter with the given key and returns how long to wait for it
+func (rd *rateLimitedChatDriver) reserve(limiterKey string, interval time.Duration) (wait time.Duration) {
+	rd.limitersMutex.Lock()
+	defer rd.limitersMutex.Unlock()
+
+	l := rd.limiter(limiterKey, interval)
+	now := rd.now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	wait = l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+
+	return wait
+}
+
+// delay pushes back the next turn of the limiter with the given key so that no call is made before the delay elapses
+func (rd *rateLimitedChatDriver) delay(limiterKey string, interval time.Duration, delay time.Duration) {
+	rd.limitersMutex.Lock()
+	defer rd.limitersMutex.Unlock()
+
+	l := rd.limiter(limiterKey, interval)
+	if notBefore := rd.now().Add(delay); l.next.Before(notBefore) {
+		l.next = notBefore
+	}
+}
+
+// limiter returns the limiter with the given key, creating it if it doesn't exist yet. The limiters mutex must be held
+func (rd *rateLimitedChatDriver) limiter(limiterKey string, interval time.Duration) (l *callLimiter) {
+	l, ok := rd.limiters[limiterKey]
+	if !ok {
+		l = &callLimiter{interval: interval}
+		rd.limiters[limiterKey] = l
+	}
+
+	return l
+}
+
+// minDuration returns the smallest of two durations
+func minDuration(a time.Duration, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,168 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+// flakyChatDriver fails calls with the given errors, in order, before delegating to an inMemoryChatDriver
+type flakyChatDriver struct {
+	*inMemoryChatDriver
+
+	errs  []error
+	calls int
+}
+
+func (d *flakyChatDriver) nextErr() (err error) {
+	d.calls = d.calls + 1
+	if len(d.errs) > 0 {
+		err, d.errs = d.errs[0], d.errs[1:]
+	}
+
+	return err
+}
+
+func (d *flakyChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	if err = d.nextErr(); err != nil {
+		return "", "", "", err
+	}
+
+	return d.inMemoryChatDriver.SendMessage(channelID, options...)
+}
+
+func (d *flakyChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	if err = d.nextErr(); err != nil {
+		return "", "", err
+	}
+
+	return d.inMemoryChatDriver.DeleteMessage(channelID, timestamp)
+}
+
+func (d *flakyChatDriver) AddReaction(name string, item slack.ItemRef) (err error) {
+	if err = d.nextErr(); err != nil {
+		return err
+	}
+
+	return d.inMemoryChatDriver.AddReaction(name, item)
+}
+
+// retryableError is a server error that can be retried, like those returned by slack for 5xx responses
+type retryableError struct{}
+
+func (e retryableError) Error() string {
+	return "slack server error"
+}
+
+func (e retryableError) Retryable() bool {
+	return true
+}
+
+// newTestRateLimitedChatDriver creates a rateLimitedChatDriver with a fake clock that advances when sleeping. The sleeps
+// are recorded in the returned slice
+func newTestRateLimitedChatDriver(driver chatDriver, maxRetries int, queueSize int) (rd *rateLimitedChatDriver, sleeps *[]time.Duration) {
+	rd = newRateLimitedChatDriver(driver, maxRetries, queueSize, &sLogger{logger: log.New(os.Stdout, "", log.LstdFlags)})
+
+	clock := time.Date(2020, time.March, 1, 10, 0, 0, 0, time.UTC)
+	sleeps = new([]time.Duration)
+	rd.now = func() time.Time {
+		return clock
+	}
+	rd.sleep = func(d time.Duration) {
+		*sleeps = append(*sleeps, d)
+		clock = clock.Add(d)
+	}
+
+	return rd, sleeps
+}
+
+func newFlakyChatDriver(errs ...error) (d *flakyChatDriver) {
+	return &flakyChatDriver{inMemoryChatDriver: &inMemoryChatDriver{timeCursor: firstReplyTimestamp - replyTimeIncrementInSeconds}, errs: errs}
+}
+
+func TestRateLimitedSendMessagesSpacedPerChannel(t *testing.T) {
+	driver := newFlakyChatDriver()
+	rd, sleeps := newTestRateLimitedChatDriver(driver, 3, 10)
+
+	rd.SendMessage("Cgeneral", slack.MsgOptionText("first", false))
+	rd.SendMessage("Cgeneral", slack.MsgOptionText("second", false))
+	rd.SendMessage("Crandom", slack.MsgOptionText("first", false))
+
+	assert.Len(t, driver.sentMsgs, 3)
+	assert.Equal(t, []time.Duration{0, sendMessageInterval, 0}, *sleeps)
+}
+
+func TestRateLimitedSendMessageRetriedAfterRetryAfterDelay(t *testing.T) {
+	driver := newFlakyChatDriver(&slack.RateLimitedError{RetryAfter: 7 * time.Second})
+	rd, sleeps := newTestRateLimitedChatDriver(driver, 3, 10)
+
+	channelID, _, _, err := rd.SendMessage("Cgeneral", slack.MsgOptionText("hello", false))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Cgeneral", channelID)
+	assert.Equal(t, 2, driver.calls)
+	assert.Len(t, driver.sentMsgs, 1)
+	assert.Equal(t, []time.Duration{0, 7 * time.Second}, *sleeps)
+}
+
+func TestRateLimitedAddReactionRetried(t *testing.T) {
+	driver := newFlakyChatDriver(&slack.RateLimitedError{RetryAfter: 3 * time.Second})
+	rd, sleeps := newTestRateLimitedChatDriver(driver, 3, 10)
+
+	assert.NoError(t, rd.AddReaction("thumbsup", slack.NewRefToMessage("Cgeneral", "1546833210.036900")))
+	assert.NoError(t, rd.AddReaction("rocket", slack.NewRefToMessage("Cgeneral", "1546833210.036900")))
+
+	assert.Equal(t, 3, driver.calls)
+	assert.Len(t, driver.addedReactions, 2)
+	assert.Equal(t, []time.Duration{0, 3 * time.Second, addReactionInterval}, *sleeps)
+}
+
+func TestRateLimitedCallsRetriedWithExponentialBackoff(t *testing.T) {
+	driver := newFlakyChatDriver(retryableError{}, retryableError{}, retryableError{}, retryableError{}, retryableError{})
+	rd, sleeps := newTestRateLimitedChatDriver(driver, 5, 10)
+
+	_, _, err := rd.DeleteMessage("Cgeneral", "1546833210.036900")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, driver.calls)
+	// Retries wait for the longest of the backoff and the method's interval
+	assert.Equal(t, []time.Duration{0, deleteMessageInterval, deleteMessageInterval, 4 * initialRetryBackoff, 8 * initialRetryBackoff, 16 * initialRetryBackoff}, *sleeps)
+}
+
+func TestRateLimitedCallsFailAfterMaxRetries(t *testing.T) {
+	driver := newFlakyChatDriver(&slack.RateLimitedError{RetryAfter: time.Second}, &slack.RateLimitedError{RetryAfter: time.Second}, &slack.RateLimitedError{RetryAfter: time.Second})
+	rd, _ := newTestRateLimitedChatDriver(driver, 2, 10)
+
+	_, _, _, err := rd.SendMessage("Cgeneral", slack.MsgOptionText("hello", false))
+
+	assert.EqualError(t, err, "slack rate limit exceeded, retry after 1s")
+	assert.Equal(t, 3, driver.calls)
+	assert.Len(t, driver.sentMsgs, 0)
+}
+
+func TestRateLimitedCallsNotRetriedOnNonRetryableErrors(t *testing.T) {
+	driver := newFlakyChatDriver(fmt.Errorf("channel_not_found"))
+	rd, _ := newTestRateLimitedChatDriver(driver, 3, 10)
+
+	_, _, _, err := rd.SendMessage("Cgeneral", slack.MsgOptionText("hello", false))
+
+	assert.EqualError(t, err, "channel_not_found")
+	assert.Equal(t, 1, driver.calls)
+}
+
+func TestRateLimitedCallsDroppedWhenQueueIsFull(t *testing.T) {
+	driver := newFlakyChatDriver()
+	rd, _ := newTestRateLimitedChatDriver(driver, 3, 1)
+
+	// Take the only spot in the queue
+	rd.waiting <- struct{}{}
+
+	_, _, _, err := rd.SendMessage("Cgeneral", slack.MsgOptionText("hello", false))
+
+	assert.EqualError(t, err, "Rate limited call queue is full (1 calls waiting), dropping [SendMessage/Cgeneral] call")
+	assert.Len(t, driver.sentMsgs, 0)
+}
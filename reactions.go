@@ -0,0 +1,167 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/slack-go/slack"
+	"time"
+)
+
+const (
+	reactionActionType = "reactionAction"
+)
+
+// ReactionActionDefinition represents how a reaction action is triggered and what it does. Reaction actions are
+// triggered by emoji reactions being added to (or removed from) messages
+type ReactionActionDefinition struct {
+	// Indicates whether the action should be omitted from the help message
+	Hidden bool
+
+	// Matcher that will determine whether or not the action should be triggered
+	Match ReactionMatcher
+
+	// Usage example
+	Usage string
+
+	// Help description for the action
+	Description string
+
+	// Function to execute if the Matcher matches
+	Answer ReactionAnswerer
+}
+
+// IncomingReaction represents an emoji reaction added to or removed from a message
+type IncomingReaction struct {
+	// Reaction is the name of the emoji, without colons (i.e. +1)
+	Reaction string
+
+	// User is the id of the user who added or removed the reaction
+	User string
+
+	// Added is true for reactions added and false for reactions removed
+	Added bool
+
+	// Channel is the id of the channel of the message reacted to
+	Channel string
+
+	// Timestamp is the timestamp of the message reacted to
+	Timestamp string
+
+	// Msg is the message reacted to, as resolved with the conversation history. It's nil if it couldn't be resolved
+	// (i.e. for reactions to files)
+	Msg *slack.Msg
+}
+
+// ReactionMatcher is the function that determines whether or not a reaction action should be triggered
+type ReactionMatcher func(r *IncomingReaction) bool
+
+// ReactionAnswerer is what gets executed when a ReactionActionDefinition is triggered. To signal the absence of an
+// answer, an action should return nil. Answers are sent in the channel of the message reacted to (in its thread, if
+// threaded replies are enabled)
+type ReactionAnswerer func(r *IncomingReaction) *Answer
+
+// reactionEvent holds the fields common to slack.ReactionAddedEvent and slack.ReactionRemovedEvent
+type reactionEvent struct {
+	user      string
+	reaction  string
+	itemType  string
+	channel   string
+	timestamp string
+}
+
+// newIncomingReaction returns an IncomingReaction for the event with the message reacted to resolved with the fetcher.
+// Failures to resolve the message are logged and result in an IncomingReaction without Msg
+func (s *Slackscot) newIncomingReaction(fetcher messageFetcher, e reactionEvent, added bool) (r *IncomingReaction) {
+	r = &IncomingReaction{Reaction: e.reaction, User: e.user, Added: added, Channel: e.channel, Timestamp: e.timestamp}
+
+	if e.itemType != "message" {
+		return r
+	}
+
+	history, err := fetcher.GetConversationHistory(&slack.GetConversationHistoryParameters{ChannelID: e.channel, Latest: e.timestamp, Oldest: e.timestamp, Inclusive: true, Limit: 1})
+	if err != nil {
+		s.log.Printf("Unable to get message [%s] reacted to in channel [%s]: %v", e.timestamp, e.channel, err)
+		return r
+	}
+
+	if len(history.Messages) > 0 {
+		r.Msg = &history.Messages[0].Msg
+	}
+
+	return r
+}
+
+// processReaction routes a reaction to the reaction actions of all plugins and sends any triggered answers. Reactions
+// from slackscot itself are ignored
+func (s *Slackscot) processReaction(driver chatDriver, e reactionEvent, added bool) {
+	if e.user == s.selfIdentity.id || !s.hasReactionActions() {
+		return
+	}
+
+	// Only some plugins run in externally shared channels in safe mode and users of other organizations are ignored there
+	safeMode := s.isSafeModeChannel(e.channel)
+	if safeMode && s.ignoresUser(e.user, "") {
+		s.log.Debugf("Ignoring reaction from user [%s] in externally shared channel [%s]", e.user, e.channel)
+		return
+	}
+
+	r := s.newIncomingReaction(driver, e, added)
+
+	threadTS := r.Timestamp
+	if r.Msg != nil && r.Msg.ThreadTimestamp != "" {
+		threadTS = r.Msg.ThreadTimestamp
+	}
+
+	pluginResps := make([]pluginResponses, 0)
+	for _, p := range s.plugins {
+		if !s.pluginRuns(p.Name, r.Channel, safeMode) || s.ignoresUserData(p, r.User) {
+			continue
+		}
+
+		pluginResps = append(pluginResps, pluginResponses{priority: p.Priority, outMsgs: withPluginDefaults(p, s.tryReactionActions(p.Name, p.ReactionActions, r))})
+	}
+
+	// Answers to reactions go through the same answer policy and hooks (see addAnswerReactions) as answers to messages
+	reactedMsgID := SlackMessageID{channelID: r.Channel, timestamp: r.Timestamp}
+	partThreads := make(map[string]string)
+	for _, o := range s.addAnswerReactions(driver, reactedMsgID, splitOutgoingMessages(s.answerPolicy.apply(pluginResps))) {
+		if _, err := s.sendMessagePart(driver, reactedMsgID, o, threadTS, partThreads); err != nil {
+			s.log.Printf("Unable to send new message triggered by reaction [%s] to [%s/%s]: %v\n", r.Reaction, r.Channel, r.Timestamp, err)
+		}
+	}
+}
+
+// hasReactionActions returns true if any plugin has reaction actions
+func (s *Slackscot) hasReactionActions() bool {
+	for _, p := range s.plugins {
+		if len(p.ReactionActions) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tryReactionActions tries all reaction actions of a plugin and returns the messages of their answers
+func (s *Slackscot) tryReactionActions(pluginName string, actions []ReactionActionDefinition, r *IncomingReaction) (outMsgs []OutgoingMessage) {
+	before := time.Now()
+
+	for i, action := range actions {
+		if !action.Match(r) {
+			continue
+		}
+
+		if answer := action.Answer(r); answer != nil {
+			outMsgs = append(outMsgs, newOutMessageForAnswer(newSlackOutgoingMessage(r.Channel, answer.Text), getActionID(pluginName, reactionActionType, i), *answer))
+		}
+	}
+
+	pm := s.getOrCreatePluginMetrics(pluginName)
+	pm.processingTimeMillis.Record(context.Background(), time.Since(before).Milliseconds())
+	pm.reactionCount.Add(context.Background(), int64(len(outMsgs)))
+
+	if len(outMsgs) > 0 && s.eventBus != nil {
+		s.eventBus.Publish(PluginAnswered{Plugin: pluginName, ActionType: reactionActionType, Channel: r.Channel, Answers: len(outMsgs)})
+	}
+
+	return outMsgs
+}
@@ -0,0 +1,117 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newRTMReactionEvent(added bool, user string, reaction string, channel string, timestamp string) (e slack.RTMEvent) {
+	if added {
+		re := &slack.ReactionAddedEvent{Type: "reaction_added", User: user, Reaction: reaction}
+		re.Item.Type = "message"
+		re.Item.Channel = channel
+		re.Item.Timestamp = timestamp
+
+		e.Type = "reaction_added"
+		e.Data = re
+		return e
+	}
+
+	re := &slack.ReactionRemovedEvent{Type: "reaction_removed", User: user, Reaction: reaction}
+	re.Item.Type = "message"
+	re.Item.Channel = channel
+	re.Item.Timestamp = timestamp
+
+	e.Type = "reaction_removed"
+	e.Data = re
+	return e
+}
+
+func newReactionPlugin(reactions *[]IncomingReaction) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "applause"
+	p.ReactionActions = []ReactionActionDefinition{{
+		Match: func(r *IncomingReaction) bool {
+			*reactions = append(*reactions, *r)
+			return r.Reaction == "clap"
+		},
+		Usage:       "react with :clap:",
+		Description: "Applaud along",
+		Answer: func(r *IncomingReaction) *Answer {
+			if !r.Added {
+				return &Answer{Text: "Oh, never mind then"}
+			}
+
+			if r.Msg == nil {
+				return &Answer{Text: fmt.Sprintf("<@%s> applauds something", r.User)}
+			}
+
+			return &Answer{Text: fmt.Sprintf("<@%s> applauds [%s] from <@%s>", r.User, r.Msg.Text, r.Msg.User)}
+		},
+	}}
+
+	return p
+}
+
+func TestReactionActions(t *testing.T) {
+	var reactions []IncomingReaction
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newReactionPlugin(&reactions), []slack.RTMEvent{
+		newRTMReactionEvent(true, "Bernard", "clap", "Cgeneral", timestamp1),
+		newRTMReactionEvent(true, "Bernard", "eyes", "Cgeneral", timestamp1),
+		newRTMReactionEvent(false, "Bernard", "clap", "Cgeneral", timestamp2),
+		newRTMReactionEvent(true, "Bernard", "clap", "Cunknown", timestamp1),
+	})
+
+	if assert.Len(t, reactions, 4) {
+		assert.Equal(t, IncomingReaction{Reaction: "clap", User: "Bernard", Added: true, Channel: "Cgeneral", Timestamp: timestamp1, Msg: &slack.Msg{Channel: "Cgeneral", Timestamp: timestamp1, User: "Alphonse", Text: "Message at " + timestamp1}}, reactions[0])
+		assert.Equal(t, "eyes", reactions[1].Reaction)
+		assert.False(t, reactions[2].Added)
+		assert.Nil(t, reactions[3].Msg)
+	}
+
+	if assert.Len(t, sentMsgs, 3) {
+		assert.Equal(t, "Cgeneral", sentMsgs[0].channelID)
+		assert.Equal(t, "<@Bernard> applauds [Message at 1546833210.036900] from <@Alphonse>", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+
+		assert.Equal(t, "Cgeneral", sentMsgs[1].channelID)
+		assert.Equal(t, "Oh, never mind then", applySlackOptions(sentMsgs[1].msgOptions...).Get("text"))
+
+		assert.Equal(t, "Cunknown", sentMsgs[2].channelID)
+		assert.Equal(t, "<@Bernard> applauds something", applySlackOptions(sentMsgs[2].msgOptions...).Get("text"))
+	}
+}
+
+func TestReactionActionAnswerInThreadOfMessage(t *testing.T) {
+	var reactions []IncomingReaction
+	v := config.NewViperWithDefaults()
+	v.Set(config.ThreadedRepliesKey, true)
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newReactionPlugin(&reactions), []slack.RTMEvent{
+		newRTMReactionEvent(true, "Bernard", "clap", "Cgeneral", timestamp1),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, timestamp1, applySlackOptions(sentMsgs[0].msgOptions...).Get("thread_ts"))
+	}
+}
+
+func TestReactionActionAnswersFollowAnswerPolicy(t *testing.T) {
+	var reactions []IncomingReaction
+	encore := newReactionPlugin(&reactions)
+	encore.Name = "encore"
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.AnswerPolicyKey, answerPolicyFirstMatch)
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, v, []*Plugin{newReactionPlugin(&reactions), encore}, []slack.RTMEvent{
+		newRTMReactionEvent(true, "Bernard", "clap", "Cgeneral", timestamp1),
+	}, nil)
+
+	assert.Len(t, reactions, 2)
+	assert.Len(t, sentMsgs, 1)
+}
@@ -0,0 +1,50 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+)
+
+// ReplyBehavior overrides the threaded replies configuration (config.ThreadedRepliesKey, config.BroadcastThreadedRepliesKey
+// and the FeatureThreadedReplies feature flag) for the answers of a plugin or of one of its actions (i.e. a plugin with
+// long reports always answering in threads while the rest of the bot answers in channels). An action's ReplyBehavior
+// takes precedence over its plugin's. Answers can still reply in threads with AnswerInThread and friends
+type ReplyBehavior struct {
+	// ThreadedReplies makes answers go in threads (of the triggering message or of its existing thread)
+	ThreadedReplies bool
+
+	// BroadcastThreadedReplies also broadcasts threaded answers to the channel. Only applies if ThreadedReplies is set
+	BroadcastThreadedReplies bool
+}
+
+// withPluginDefaults applies the identity and the reply behavior of a plugin to its outgoing messages (see
+// withPluginIdentity and withPluginReplyBehavior)
+func withPluginDefaults(p *Plugin, outMsgs []OutgoingMessage) []OutgoingMessage {
+	return withPluginReplyBehavior(p.ReplyBehavior, withPluginIdentity(p.Identity, outMsgs))
+}
+
+// withPluginReplyBehavior applies the reply behavior of a plugin, if any, to its outgoing messages that don't already
+// have the reply behavior of their action
+func withPluginReplyBehavior(replyBehavior *ReplyBehavior, outMsgs []OutgoingMessage) []OutgoingMessage {
+	if replyBehavior == nil {
+		return outMsgs
+	}
+
+	for i := range outMsgs {
+		if outMsgs[i].replyBehavior == nil {
+			outMsgs[i].replyBehavior = replyBehavior
+		}
+	}
+
+	return outMsgs
+}
+
+// replyBehavior returns whether an outgoing message is to be threaded and broadcast by default: as set by the reply
+// behavior of its action or plugin, if any, or as configured
+func (s *Slackscot) replyBehavior(o OutgoingMessage) (threaded bool, broadcast bool) {
+	if o.replyBehavior != nil {
+		return o.replyBehavior.ThreadedReplies, o.replyBehavior.BroadcastThreadedReplies
+	}
+
+	threaded = s.config.GetBool(config.ThreadedRepliesKey) || s.featureFlags.IsEnabled(FeatureThreadedReplies, o.OutgoingMessage.Channel)
+	return threaded, s.config.GetBool(config.BroadcastThreadedRepliesKey)
+}
@@ -0,0 +1,91 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func newReporterPlugin(replyBehavior *ReplyBehavior) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "reporter"
+	p.ReplyBehavior = replyBehavior
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "report")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Here's your report"}
+		},
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "headline")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Here's the headline"}
+		},
+		ReplyBehavior: &ReplyBehavior{ThreadedReplies: true, BroadcastThreadedReplies: true},
+	}}
+
+	return p
+}
+
+func TestPluginReplyBehaviorOverridesConfig(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ThreadedRepliesKey, false)
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newReporterPlugin(&ReplyBehavior{ThreadedReplies: true}), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "report", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "headline", "Alphonse", timestamp2)),
+	})
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Here's your report", vals.Get("text"))
+		assert.Equal(t, timestamp1, vals.Get("thread_ts"))
+		assert.Equal(t, "", vals.Get("reply_broadcast"))
+
+		// The action's reply behavior takes precedence over its plugin's
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "Here's the headline", vals.Get("text"))
+		assert.Equal(t, timestamp2, vals.Get("thread_ts"))
+		assert.Equal(t, "true", vals.Get("reply_broadcast"))
+	}
+}
+
+func TestPluginReplyBehaviorDisablesConfiguredThreading(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ThreadedRepliesKey, true)
+	v.Set(config.BroadcastThreadedRepliesKey, true)
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newReporterPlugin(&ReplyBehavior{ThreadedReplies: false}), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "report", "Alphonse", timestamp1)),
+		// Messages already in a thread are still answered in it
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "report", "Alphonse", timestamp2, optionMessageOnThread("1212314125"))),
+	})
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "", vals.Get("thread_ts"))
+		assert.Equal(t, "", vals.Get("reply_broadcast"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "1212314125", vals.Get("thread_ts"))
+		assert.Equal(t, "", vals.Get("reply_broadcast"))
+	}
+}
+
+func TestPluginWithoutReplyBehaviorFollowsConfig(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ThreadedRepliesKey, true)
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newReporterPlugin(nil), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "report", "Alphonse", timestamp1)),
+	})
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		assert.Equal(t, timestamp1, applySlackOptions(sentMsgs[0].msgOptions...).Get("thread_ts"))
+	}
+}
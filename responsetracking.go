@@ -0,0 +1,169 @@
+package slackscot
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"time"
+)
+
+const (
+	// responsesSilo is the silo of the responses persisted with OptionResponseStorer, keyed by triggering message
+	responsesSilo = "responses"
+
+	// responsePruningInterval is the interval between prunings of the persisted responses older than the
+	// config.ResponseRetentionKey duration
+	responsePruningInterval = time.Hour
+)
+
+// OptionResponseStorer persists the responses sent to triggering messages in the storer, in addition to keeping them in
+// the response cache. This lets slackscot update or delete its responses to messages edited or deleted after a restart.
+// Responses are pruned once older than config.ResponseRetentionKey
+func OptionResponseStorer(storer store.GlobalSiloStringStorer) Option {
+	return func(s *Slackscot) {
+		s.responseStorer = storer
+	}
+}
+
+// trackedResponse is the persisted form of a SlackMessageID
+type trackedResponse struct {
+	ChannelID string `json:"channelID"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Scheduled bool   `json:"scheduled,omitempty"`
+}
+
+// trackedResponses is the persisted form of the responses to a triggering message, by response key
+type trackedResponses struct {
+	Responses map[string]trackedResponse `json:"responses"`
+	TrackedAt time.Time                  `json:"trackedAt"`
+}
+
+// getResponses returns the responses to a triggering message from the response cache or, if missing from it, from the
+// response storer, if any
+func (s *Slackscot) getResponses(triggeringMsgID SlackMessageID) (responses map[string]SlackMessageID, exists bool) {
+	if cached, exists := s.triggeringMsgToResponse.Get(triggeringMsgID); exists {
+		return cached.(map[string]SlackMessageID), true
+	}
+
+	if s.responseStorer == nil {
+		return nil, false
+	}
+
+	value, err := s.responseStorer.GetSiloString(responsesSilo, triggeringMsgID.String())
+	if err != nil {
+		return nil, false
+	}
+
+	var tracked trackedResponses
+	if err := json.Unmarshal([]byte(value), &tracked); err != nil {
+		s.log.Printf("Error decoding persisted responses to [%s], ignoring them: %v", triggeringMsgID, err)
+		return nil, false
+	}
+
+	if s.isExpired(tracked.TrackedAt, time.Now()) {
+		return nil, false
+	}
+
+	responses = make(map[string]SlackMessageID)
+	for key, r := range tracked.Responses {
+		responses[key] = SlackMessageID{channelID: r.ChannelID, timestamp: r.Timestamp, scheduled: r.Scheduled}
+	}
+
+	s.log.Debugf("Loaded persisted responses to [%s]: %s", triggeringMsgID, responses)
+	s.triggeringMsgToResponse.Add(triggeringMsgID, responses)
+
+	return responses, true
+}
+
+// trackResponses keeps track of the responses to a triggering message in the response cache and, if any, in the
+// response storer. Failures to persist them are logged and only affect handling of edits after a restart
+func (s *Slackscot) trackResponses(triggeringMsgID SlackMessageID, responses map[string]SlackMessageID) {
+	s.triggeringMsgToResponse.Add(triggeringMsgID, responses)
+
+	if s.responseStorer == nil {
+		return
+	}
+
+	tracked := trackedResponses{Responses: make(map[string]trackedResponse), TrackedAt: time.Now()}
+	for key, r := range responses {
+		tracked.Responses[key] = trackedResponse{ChannelID: r.channelID, Timestamp: r.timestamp, Scheduled: r.scheduled}
+	}
+
+	value, err := json.Marshal(tracked)
+	if err == nil {
+		err = s.responseStorer.PutSiloString(responsesSilo, triggeringMsgID.String(), string(value))
+	}
+
+	if err != nil {
+		s.log.Printf("Error persisting responses to [%s]: %v", triggeringMsgID, err)
+	}
+}
+
+// untrackResponses stops tracking the responses to a triggering message
+func (s *Slackscot) untrackResponses(triggeringMsgID SlackMessageID) {
+	s.triggeringMsgToResponse.Remove(triggeringMsgID)
+
+	if s.responseStorer == nil {
+		return
+	}
+
+	if err := s.responseStorer.DeleteSiloString(responsesSilo, triggeringMsgID.String()); err != nil {
+		s.log.Printf("Error deleting persisted responses to [%s]: %v", triggeringMsgID, err)
+	}
+}
+
+// isExpired returns true if what was persisted at the given time is older than the config.ResponseRetentionKey duration
+func (s *Slackscot) isExpired(persistedAt time.Time, now time.Time) bool {
+	return now.Sub(persistedAt) > s.config.GetDuration(config.ResponseRetentionKey)
+}
+
+// pruneResponses deletes the persisted responses that are expired (or can't be decoded) and returns how many were
+func (s *Slackscot) pruneResponses(now time.Time) (pruned int, err error) {
+	entries, err := s.responseStorer.ScanSilo(responsesSilo)
+	if err != nil {
+		return 0, err
+	}
+
+	for key, value := range entries {
+		var tracked trackedResponses
+		if err := json.Unmarshal([]byte(value), &tracked); err == nil && !s.isExpired(tracked.TrackedAt, now) {
+			continue
+		}
+
+		if err := s.responseStorer.DeleteSiloString(responsesSilo, key); err != nil {
+			return pruned, err
+		}
+
+		pruned = pruned + 1
+	}
+
+	return pruned, nil
+}
+
+// pruneResponsesPeriodically prunes the persisted responses (and markers of actions run, see markActionRun) right away
+// and then every responsePruningInterval until the context is done
+func (s *Slackscot) pruneResponsesPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(responsePruningInterval)
+	defer ticker.Stop()
+
+	for {
+		if pruned, err := s.pruneResponses(time.Now()); err != nil {
+			s.log.Printf("Error pruning persisted responses: %v", err)
+		} else if pruned > 0 {
+			s.log.Debugf("Pruned %d expired persisted responses", pruned)
+		}
+
+		if pruned, err := s.pruneExecutedActions(time.Now()); err != nil {
+			s.log.Printf("Error pruning persisted runs of actions: %v", err)
+		} else if pruned > 0 {
+			s.log.Debugf("Pruned %d expired persisted runs of actions", pruned)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestResponsesPersistedAcrossRestarts(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionResponseStorer(storer))
+	assert.Len(t, sentMsgs, 1)
+
+	// After a restart, the edit of the message still updates the response sent before it
+	_, updatedMsgs, _, _ := runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Ignored", timestamp2, optionChangedMessage("blue jays eat acorn", "Alphonse", timestamp1))),
+	}, nil, OptionResponseStorer(storer))
+
+	if assert.Len(t, updatedMsgs, 1) {
+		assert.Equal(t, "Cgeneral", updatedMsgs[0].channelID)
+		assert.Equal(t, formatTimestamp(firstReplyTimestamp), updatedMsgs[0].timestamp)
+	}
+
+	// And after another restart, deleting the message deletes the response
+	_, _, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", timestamp2, optionDeletedMessage("Cgeneral", timestamp1))),
+	}, nil, OptionResponseStorer(storer))
+
+	if assert.Len(t, deletedMsgs, 1) {
+		assert.Equal(t, formatTimestamp(firstReplyTimestamp), deletedMsgs[0].timestamp)
+	}
+
+	entries, err := storer.ScanSilo(responsesSilo)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestExpiredResponsesIgnoredAndPruned(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.ResponseRetentionKey, time.Hour)
+
+	s, err := New("chickadee", v, OptionResponseStorer(storer))
+	require.NoError(t, err)
+
+	expired, err := json.Marshal(trackedResponses{Responses: map[string]trackedResponse{"test.hearAction[0]": {ChannelID: "Cgeneral", Timestamp: "1546833211.036900"}}, TrackedAt: time.Now().Add(-2 * time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, storer.PutSiloString(responsesSilo, "Cgeneral/1546833210.036900", string(expired)))
+
+	s.trackResponses(SlackMessageID{channelID: "Cgeneral", timestamp: "1546833212.036900"}, map[string]SlackMessageID{"test.hearAction[0]": {channelID: "Cgeneral", timestamp: "1546833213.036900"}})
+	require.NoError(t, storer.PutSiloString(responsesSilo, "Cgeneral/1546833214.036900", "not json"))
+
+	_, exists := s.getResponses(SlackMessageID{channelID: "Cgeneral", timestamp: "1546833210.036900"})
+	assert.False(t, exists)
+
+	pruned, err := s.pruneResponses(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 2, pruned)
+
+	entries, err := storer.ScanSilo(responsesSilo)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Contains(t, entries, "Cgeneral/1546833212.036900")
+}
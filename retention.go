@@ -0,0 +1,117 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/metric"
+	"sort"
+	"time"
+)
+
+// NewRetentionStorer returns a store.RetentionStorer enforcing the retention configured for the silos of the plugin
+// (see config.GetPluginRetention) on its storer. It should be registered with OptionRetentionStorer for its entries to
+// be swept
+func NewRetentionStorer(v *viper.Viper, pluginName string, storer store.GlobalSiloStringStorer) (rs *store.RetentionStorer, err error) {
+	allSilos, silos, err := config.GetPluginRetention(v, pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []store.RetentionOption{store.OptionDefaultRetention(allSilos)}
+	for silo, retention := range silos {
+		options = append(options, store.OptionSiloRetention(silo, retention))
+	}
+
+	return store.NewRetentionStorer(storer, options...), nil
+}
+
+// OptionRetentionStorer registers the retention storer of a plugin (see NewRetentionStorer) for slackscot to sweep the
+// entries past their retention every config.RetentionSweepIntervalKey. The number of entries purged is reported by the
+// retentionPurgedKeys metric, labeled with the name of the plugin and the silo
+func OptionRetentionStorer(pluginName string, storer *store.RetentionStorer) Option {
+	return func(s *Slackscot) {
+		if s.retentionStorers == nil {
+			s.retentionStorers = make(map[string]*store.RetentionStorer)
+		}
+
+		s.retentionStorers[pluginName] = storer
+	}
+}
+
+// retentionSweeper sweeps the entries past their retention from the retention storers of plugins
+type retentionSweeper struct {
+	appName string
+	storers map[string]*store.RetentionStorer
+	logger  SLogger
+
+	meter          metric.Meter
+	purgedMetric   metric.Int64Counter
+	purgedCounters map[string]metric.BoundInt64Counter
+}
+
+// newRetentionSweeper creates a new retentionSweeper of the storers, by plugin name
+func newRetentionSweeper(storers map[string]*store.RetentionStorer, appName string, meter metric.Meter, logger SLogger) (rs *retentionSweeper) {
+	rs = new(retentionSweeper)
+	rs.appName = appName
+	rs.storers = storers
+	rs.logger = logger
+	rs.meter = meter
+	rs.purgedCounters = make(map[string]metric.BoundInt64Counter)
+	rs.purgedMetric = meter.NewInt64Counter("retentionPurgedKeys", metric.WithKeys(key.New("name"), key.New("plugin"), key.New("silo")))
+
+	return rs
+}
+
+// sweep sweeps all storers, in plugin name order, and returns the number of entries purged by plugin and silo. Errors
+// are logged and don't stop the sweeping of other storers
+func (rs *retentionSweeper) sweep(now time.Time) (purged map[string]map[string]int) {
+	purged = make(map[string]map[string]int)
+
+	pluginNames := make([]string, 0, len(rs.storers))
+	for pluginName := range rs.storers {
+		pluginNames = append(pluginNames, pluginName)
+	}
+	sort.Strings(pluginNames)
+
+	for _, pluginName := range pluginNames {
+		siloPurged, err := rs.storers[pluginName].Sweep(now)
+		if err != nil {
+			rs.logger.Printf("Error sweeping the entries of [%s] past their retention: %v", pluginName, err)
+		}
+
+		for silo, count := range siloPurged {
+			counterID := pluginName + "/" + silo
+			if _, ok := rs.purgedCounters[counterID]; !ok {
+				rs.purgedCounters[counterID] = rs.purgedMetric.Bind(rs.meter.Labels(key.New("name").String(rs.appName), key.New("plugin").String(pluginName), key.New("silo").String(silo)))
+			}
+			rs.purgedCounters[counterID].Add(context.Background(), int64(count))
+
+			rs.logger.Debugf("Purged %d entries of silo [%s] of [%s] past their retention", count, silo, pluginName)
+		}
+
+		if len(siloPurged) > 0 {
+			purged[pluginName] = siloPurged
+		}
+	}
+
+	return purged
+}
+
+// run sweeps the storers right away and then every interval until the context is done
+func (rs *retentionSweeper) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		rs.sweep(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewRetentionStorerFromConfig(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.archiver.retention", map[string]interface{}{"*": "365d", "links": "90d", "settings": "0"})
+
+	rs, err := NewRetentionStorer(v, "archiver", storer)
+	require.NoError(t, err)
+
+	require.NoError(t, rs.PutSiloString("Cgeneral", "@U21355", "10"))
+	require.NoError(t, rs.PutSiloString("links", "https://slackscot.io", "Cgeneral"))
+	require.NoError(t, rs.PutSiloString("settings", "theme", "podium"))
+
+	purged, err := rs.Sweep(time.Now().Add(100 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"links": 1}, purged)
+
+	purged, err = rs.Sweep(time.Now().Add(10 * 365 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Cgeneral": 1}, purged)
+}
+
+func TestNewRetentionStorerWithInvalidConfig(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.archiver.retention", map[string]interface{}{"links": "a while"})
+
+	_, err := NewRetentionStorer(v, "archiver", storer)
+	assert.Error(t, err)
+}
+
+func TestRetentionSweeperSweepsRegisteredStorers(t *testing.T) {
+	karmaStorer, cleanupKarma := newFeatureFlagStorer(t)
+	defer cleanupKarma()
+	archiverStorer, cleanupArchiver := newFeatureFlagStorer(t)
+	defer cleanupArchiver()
+
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.karma.retention", map[string]interface{}{"*": "365d"})
+	v.Set("plugins.archiver.retention", map[string]interface{}{"links": "90d"})
+
+	karma, err := NewRetentionStorer(v, "karma", karmaStorer)
+	require.NoError(t, err)
+	archiver, err := NewRetentionStorer(v, "archiver", archiverStorer)
+	require.NoError(t, err)
+
+	s, err := New("robert", v, OptionRetentionStorer("karma", karma), OptionRetentionStorer("archiver", archiver))
+	require.NoError(t, err)
+
+	require.NoError(t, karma.PutSiloString("Cgeneral", "@U21355", "10"))
+	require.NoError(t, archiver.PutSiloString("links", "https://slackscot.io", "Cgeneral"))
+	require.NoError(t, archiver.PutSiloString("settings", "theme", "podium"))
+
+	assert.Empty(t, s.retentionSweeper.sweep(time.Now().Add(30*24*time.Hour)))
+	assert.Equal(t, map[string]map[string]int{"archiver": {"links": 1}}, s.retentionSweeper.sweep(time.Now().Add(100*24*time.Hour)))
+	assert.Equal(t, map[string]map[string]int{"karma": {"Cgeneral": 1}}, s.retentionSweeper.sweep(time.Now().Add(400*24*time.Hour)))
+
+	entries, err := archiver.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"settings": {"theme": "podium"}}, entries)
+}
@@ -0,0 +1,227 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchYears is how far in the future Cron.Next looks for a run before concluding that there's none (i.e. for
+// February 30th)
+const cronSearchYears = 5
+
+// cronMacros are the cron expressions corresponding to the supported macros
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+
+var dayOfWeekNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// cronField defines the range of values of a field of a cron expression and the names its values can be given by
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField     = cronField{name: "minute", min: 0, max: 59}
+	hourField       = cronField{name: "hour", min: 0, max: 23}
+	dayOfMonthField = cronField{name: "day of month", min: 1, max: 31}
+	monthField      = cronField{name: "month", min: 1, max: 12, names: monthNames}
+
+	// Sunday is both 0 and 7, like most cron implementations
+	dayOfWeekField = cronField{name: "day of week", min: 0, max: 7, names: dayOfWeekNames}
+)
+
+// Cron is a parsed cron expression. The standard five fields (minute, hour, day of month, month and day of week) are
+// supported with lists (1,15), ranges (MON-FRI), steps (*/15 or 9-17/2), month and day names as well as the @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight and @hourly macros. Like with the standard cron, a run happens when
+// either the day of month or the day of week matches if both are restricted
+type Cron struct {
+	expression string
+
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// ParseCron parses a cron expression
+func ParseCron(expression string) (c *Cron, err error) {
+	fields := strings.Fields(expression)
+	if len(fields) == 1 {
+		if macro, ok := cronMacros[strings.ToLower(fields[0])]; ok {
+			fields = strings.Fields(macro)
+		}
+	}
+
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Invalid cron expression [%s]: expected 5 fields (minute, hour, day of month, month and day of week) but got %d", expression, len(fields))
+	}
+
+	c = new(Cron)
+	c.expression = expression
+
+	for _, f := range []struct {
+		value string
+		field cronField
+		bits  *uint64
+	}{
+		{fields[0], minuteField, &c.minutes},
+		{fields[1], hourField, &c.hours},
+		{fields[2], dayOfMonthField, &c.daysOfMonth},
+		{fields[3], monthField, &c.months},
+		{fields[4], dayOfWeekField, &c.daysOfWeek},
+	} {
+		if *f.bits, err = parseCronField(f.value, f.field); err != nil {
+			return nil, fmt.Errorf("Invalid cron expression [%s]: %v", expression, err)
+		}
+	}
+
+	// Fold Sunday as 7 into Sunday as 0
+	if c.daysOfWeek&(1<<7) != 0 {
+		c.daysOfWeek = c.daysOfWeek&^(1<<7) | 1
+	}
+
+	c.anyDayOfMonth = strings.HasPrefix(fields[2], "*")
+	c.anyDayOfWeek = strings.HasPrefix(fields[4], "*")
+
+	return c, nil
+}
+
+// parseCronField returns the bits of the values of a field of a cron expression
+func parseCronField(value string, field cronField) (bits uint64, err error) {
+	for _, part := range strings.Split(value, ",") {
+		rangeValue, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangeValue = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step [%s] of %s", part[i+1:], field.name)
+			}
+		}
+
+		start, end := field.min, field.max
+		switch {
+		case rangeValue == "*":
+		case strings.Contains(rangeValue, "-"):
+			bounds := strings.SplitN(rangeValue, "-", 2)
+			if start, err = parseCronValue(bounds[0], field); err != nil {
+				return 0, err
+			}
+
+			if end, err = parseCronValue(bounds[1], field); err != nil {
+				return 0, err
+			}
+
+			if start > end {
+				return 0, fmt.Errorf("invalid range [%s] of %s", rangeValue, field.name)
+			}
+		default:
+			if start, err = parseCronValue(rangeValue, field); err != nil {
+				return 0, err
+			}
+
+			// A single value with a step (i.e. 5/15) runs from that value to the end of the range
+			if step == 1 {
+				end = start
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// parseCronValue parses a single value of a field of a cron expression, given as a number or a name
+func parseCronValue(value string, field cronField) (v int, err error) {
+	if named, ok := field.names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+
+	if v, err = strconv.Atoi(value); err != nil {
+		return 0, fmt.Errorf("invalid %s [%s]", field.name, value)
+	}
+
+	if v < field.min || v > field.max {
+		return 0, fmt.Errorf("%s [%d] out of range [%d-%d]", field.name, v, field.min, field.max)
+	}
+
+	return v, nil
+}
+
+// String returns the cron expression
+func (c *Cron) String() string {
+	return c.expression
+}
+
+// Next returns the first time strictly after the given time matching the cron expression, in the location of the given
+// time. The zero time is returned if there's no match in the next few years. Like with the standard cron, times skipped
+// by a daylight saving change don't match that day and times repeated by one match twice
+func (c *Cron) Next(after time.Time) (next time.Time) {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Year() + cronSearchYears
+
+	for t.Year() <= limit {
+		if c.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if c.hours&(1<<uint(t.Hour())) == 0 {
+			nextHour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+
+			// When falling back, the next hour on the clock can be an hour that already passed
+			if !nextHour.After(t) {
+				nextHour = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			}
+
+			t = nextHour
+			continue
+		}
+
+		if c.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchesDay returns true if the day of the time matches the day of month and day of week fields
+func (c *Cron) matchesDay(t time.Time) bool {
+	dayOfMonth := c.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := c.daysOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
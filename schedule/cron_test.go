@@ -0,0 +1,89 @@
+package schedule_test
+
+import (
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) (loc *time.Location) {
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+
+	return loc
+}
+
+func TestCronNext(t *testing.T) {
+	toronto := mustLoadLocation(t, "America/Toronto")
+
+	testCases := []struct {
+		expression string
+		after      time.Time
+		next       time.Time
+	}{
+		// Friday, 10:00 -> Monday, 09:00
+		{"0 9 * * MON-FRI", time.Date(2026, time.October, 16, 10, 0, 0, 0, toronto), time.Date(2026, time.October, 19, 9, 0, 0, 0, toronto)},
+		{"0 9 * * 1-5", time.Date(2026, time.October, 19, 8, 59, 59, 0, toronto), time.Date(2026, time.October, 19, 9, 0, 0, 0, toronto)},
+		{"0 9 * * 1-5", time.Date(2026, time.October, 19, 9, 0, 0, 0, toronto), time.Date(2026, time.October, 20, 9, 0, 0, 0, toronto)},
+		{"*/15 * * * *", time.Date(2026, time.October, 19, 9, 7, 0, 0, time.UTC), time.Date(2026, time.October, 19, 9, 15, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2026, time.October, 19, 13, 31, 0, 0, time.UTC), time.Date(2026, time.October, 19, 17, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2026, time.October, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * FEB sun", time.Date(2026, time.October, 2, 0, 0, 0, 0, time.UTC), time.Date(2027, time.February, 7, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)},
+		// Either the day of month or the day of week match when both are restricted
+		{"0 0 13 * FRI", time.Date(2026, time.October, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, time.October, 19, 9, 7, 0, 0, time.UTC), time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, time.October, 15, 9, 7, 0, 0, time.UTC), time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, time.October, 15, 9, 7, 0, 0, time.UTC), time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// 02:30 doesn't happen on the day clocks spring forward
+		{"30 2 * * *", time.Date(2026, time.March, 7, 12, 0, 0, 0, toronto), time.Date(2026, time.March, 9, 2, 30, 0, 0, toronto)},
+		{"0 3 * * *", time.Date(2026, time.March, 7, 12, 0, 0, 0, toronto), time.Date(2026, time.March, 8, 3, 0, 0, 0, toronto)},
+		{"0 9 * * *", time.Date(2026, time.November, 1, 0, 30, 0, 0, toronto), time.Date(2026, time.November, 1, 9, 0, 0, 0, toronto)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			c, err := schedule.ParseCron(tc.expression)
+			require.NoError(t, err)
+
+			next := c.Next(tc.after)
+			assert.Truef(t, tc.next.Equal(next), "Expected next run of [%s] after [%s] to be [%s] but got [%s]", tc.expression, tc.after, tc.next, next)
+			assert.Equal(t, tc.after.Location(), next.Location())
+		})
+	}
+}
+
+func TestCronNextWithoutRun(t *testing.T) {
+	c, err := schedule.ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+
+	assert.True(t, c.Next(time.Now()).IsZero())
+}
+
+func TestParseInvalidCron(t *testing.T) {
+	testCases := []struct {
+		expression   string
+		errorMessage string
+	}{
+		{"0 9 * *", "Invalid cron expression [0 9 * *]: expected 5 fields (minute, hour, day of month, month and day of week) but got 4"},
+		{"@sometimes", "Invalid cron expression [@sometimes]: expected 5 fields (minute, hour, day of month, month and day of week) but got 1"},
+		{"60 9 * * *", "Invalid cron expression [60 9 * * *]: minute [60] out of range [0-59]"},
+		{"0 9 0 * *", "Invalid cron expression [0 9 0 * *]: day of month [0] out of range [1-31]"},
+		{"0 9 * * FRI-MON", "Invalid cron expression [0 9 * * FRI-MON]: invalid range [FRI-MON] of day of week"},
+		{"*/0 9 * * *", "Invalid cron expression [*/0 9 * * *]: invalid step [0] of minute"},
+		{"0 nine * * *", "Invalid cron expression [0 nine * * *]: invalid hour [nine]"},
+		{"0 9 * SMARCH *", "Invalid cron expression [0 9 * SMARCH *]: invalid month [SMARCH]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			_, err := schedule.ParseCron(tc.expression)
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.errorMessage, err.Error())
+			}
+		})
+	}
+}
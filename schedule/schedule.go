@@ -21,6 +21,13 @@ type Definition struct {
 
 	// Optional "at time" value (i.e. "10:30")
 	AtTime string
+
+	// Optional cron expression (i.e. "0 9 * * MON-FRI", see ParseCron). If set, it replaces all other fields
+	Cron string
+
+	// Optional time zone of a cron schedule, as understood by time.LoadLocation (i.e. "America/Toronto"). Defaults to
+	// the time location configured for slackscot
+	Timezone string
 }
 
 // DayOfWeek is the type definition for a string value of days of the week (based on time.Day.String())
@@ -52,6 +59,10 @@ var weekdayToNumeral = map[string]time.Weekday{
 func (d Definition) String() string {
 	var b strings.Builder
 
+	if d.Cron != "" {
+		return d.Cron
+	}
+
 	fmt.Fprintf(&b, "Every ")
 
 	if d.Weekday != "" {
@@ -107,6 +118,19 @@ func (sdb *ScheduleDefinitionBuilder) AtTime(atTime string) *ScheduleDefinitionB
 	return sdb
 }
 
+// WithCron sets a cron expression to run on (i.e. "0 9 * * MON-FRI" for every weekday at 9:00). Replaces all other
+// schedule settings except for the time zone
+func (sdb *ScheduleDefinitionBuilder) WithCron(expression string) *ScheduleDefinitionBuilder {
+	sdb.definition.Cron = expression
+	return sdb
+}
+
+// InTimezone sets the time zone of a cron schedule (i.e. "America/Toronto")
+func (sdb *ScheduleDefinitionBuilder) InTimezone(timezone string) *ScheduleDefinitionBuilder {
+	sdb.definition.Timezone = timezone
+	return sdb
+}
+
 // Build returns the schedule Definition
 func (sdb *ScheduleDefinitionBuilder) Build() Definition {
 	return sdb.definition
@@ -162,8 +186,53 @@ func optionAtTime(atTime string) scheduleOption {
 	}
 }
 
-// NewJob sets up the gocron.Job with the schedule and leaves the task undefined for the caller to set up
+// Period returns the time between runs of the schedule or 0 for cron schedules, which don't have a fixed period
+func (d Definition) Period() (period time.Duration) {
+	if d.Cron != "" {
+		return 0
+	}
+
+	if d.Weekday != "" {
+		return 7 * 24 * time.Hour
+	}
+
+	var unit time.Duration
+	switch d.Unit {
+	case Weeks:
+		unit = 7 * 24 * time.Hour
+	case Days:
+		unit = 24 * time.Hour
+	case Hours:
+		unit = time.Hour
+	case Minutes:
+		unit = time.Minute
+	case Seconds:
+		unit = time.Second
+	}
+
+	return time.Duration(d.Interval) * unit
+}
+
+// Location returns the location of the time zone of the schedule or the default location if it doesn't have one
+func (d Definition) Location(defaultLocation *time.Location) (loc *time.Location, err error) {
+	if d.Timezone == "" {
+		return defaultLocation, nil
+	}
+
+	return time.LoadLocation(d.Timezone)
+}
+
+// NewJob sets up the gocron.Job with the schedule and leaves the task undefined for the caller to set up. Cron schedules
+// aren't supported by gocron and must be run on the times given by their Cron (see ParseCron)
 func NewJob(s *gocron.Scheduler, def Definition) (j *gocron.Job, err error) {
+	if def.Cron != "" {
+		return nil, fmt.Errorf("Can't run cron schedule [%s] as a gocron job", def)
+	}
+
+	if def.Timezone != "" {
+		return nil, fmt.Errorf("Can't run job on schedule [%s] in time zone [%s]: time zones are only supported with cron schedules", def, def.Timezone)
+	}
+
 	j = s.Every(def.Interval, false)
 
 	scheduleOptions := make([]scheduleOption, 0)
@@ -4,6 +4,7 @@ import (
 	"github.com/alexandre-normand/slackscot/schedule"
 	"github.com/marcsantiago/gocron"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
 )
@@ -51,6 +52,7 @@ func TestScheduleDefinitionBuilder(t *testing.T) {
 		{schedule.New().WithUnit(schedule.Seconds).Build(), "Every second"},
 		{schedule.New().WithInterval(2, schedule.Seconds).Build(), "Every 2 seconds"},
 		{schedule.New().Every(time.Monday.String()).Build(), "Every Monday"},
+		{schedule.New().WithCron("0 9 * * MON-FRI").InTimezone("America/Toronto").Build(), "0 9 * * MON-FRI"},
 	}
 
 	for _, testCase := range scheduleDefinitionToString {
@@ -89,6 +91,8 @@ func TestNewScheduledJobFromScheduleDefinition(t *testing.T) {
 		{schedule.Definition{Interval: 1, Unit: schedule.Seconds, AtTime: "10:00"}, "Can't run job on schedule [Every second at 10:00] with AtTime in conjunction with a sub-day IntervalUnit"},
 		{schedule.Definition{Interval: 1, Unit: schedule.Minutes, AtTime: "10:00"}, "Can't run job on schedule [Every minute at 10:00] with AtTime in conjunction with a sub-day IntervalUnit"},
 		{schedule.Definition{Interval: 1, Unit: schedule.Hours, AtTime: "10:00"}, "Can't run job on schedule [Every hour at 10:00] with AtTime in conjunction with a sub-day IntervalUnit"},
+		{schedule.Definition{Cron: "0 9 * * MON-FRI"}, "Can't run cron schedule [0 9 * * MON-FRI] as a gocron job"},
+		{schedule.Definition{Interval: 1, Unit: schedule.Days, AtTime: "09:00", Timezone: "America/Toronto"}, "Can't run job on schedule [Every day at 09:00] in time zone [America/Toronto]: time zones are only supported with cron schedules"},
 	}
 
 	scheduler := gocron.NewScheduler()
@@ -107,3 +111,26 @@ func TestNewScheduledJobFromScheduleDefinition(t *testing.T) {
 		})
 	}
 }
+
+func TestScheduleDefinitionLocation(t *testing.T) {
+	loc, err := schedule.New().WithCron("@daily").Build().Location(time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = schedule.New().WithCron("@daily").InTimezone("America/Toronto").Build().Location(time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, "America/Toronto", loc.String())
+
+	_, err = schedule.New().WithCron("@daily").InTimezone("Nowhere/Special").Build().Location(time.UTC)
+	assert.Error(t, err)
+}
+
+func TestScheduleDefinitionPeriod(t *testing.T) {
+	assert.Equal(t, 7*24*time.Hour, schedule.New().Every(time.Monday.String()).AtTime("10:00").Build().Period())
+	assert.Equal(t, 14*24*time.Hour, schedule.New().WithInterval(2, schedule.Weeks).Build().Period())
+	assert.Equal(t, 24*time.Hour, schedule.New().WithUnit(schedule.Days).AtTime("10:00").Build().Period())
+	assert.Equal(t, 3*time.Hour, schedule.New().WithInterval(3, schedule.Hours).Build().Period())
+	assert.Equal(t, 5*time.Minute, schedule.New().WithInterval(5, schedule.Minutes).Build().Period())
+	assert.Equal(t, time.Second, schedule.New().WithUnit(schedule.Seconds).Build().Period())
+	assert.Equal(t, time.Duration(0), schedule.New().WithCron("@daily").Build().Period())
+}
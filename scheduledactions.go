@@ -0,0 +1,544 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/marcsantiago/gocron"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	scheduledActionType        = "scheduledAction"
+	scheduledActionsPluginName = "schedule"
+	scheduledActionsSilo       = "scheduledActions"
+	scheduledActionRunsSilo    = "scheduledActionRuns"
+	pausedScheduledActionState = "paused"
+)
+
+// MissedRunPolicy defines what happens to the runs of a scheduled action missed while slackscot wasn't running (i.e.
+// during a restart)
+type MissedRunPolicy string
+
+// MissedRunPolicy values
+const (
+	// SkipMissedRuns drops missed runs, the action runs again at its next scheduled time. This is the default
+	SkipMissedRuns = MissedRunPolicy("skip")
+
+	// RunOnceMissedRuns runs the action once on startup if it missed any run since it last ran, which requires the
+	// time of its last run to be persisted (see OptionScheduledActionStorer)
+	RunOnceMissedRuns = MissedRunPolicy("runOnce")
+)
+
+var scheduleListRegex = regexp.MustCompile("(?i)\\Aschedule list\\z")
+var scheduleControlRegex = regexp.MustCompile("(?i)\\Aschedule (pause|resume|run) ([\\w.\\[\\]-]+)\\z")
+
+// scheduledActionEntry is a plugin's scheduled action registered with the scheduler
+type scheduledActionEntry struct {
+	id     string
+	plugin string
+	ScheduledActionDefinition
+
+	// job is the scheduler's job of the action, nil until scheduled, if scheduling failed or for cron schedules
+	job *gocron.Job
+
+	// cron is the parsed cron expression of actions on a cron schedule, run in their location rather than by the
+	// scheduler. next is their next run, zero until scheduled
+	cron     *schedule.Cron
+	location *time.Location
+	next     time.Time
+}
+
+// scheduledActionRegistry keeps track of the scheduled actions of all plugins and of the ones paused by admins. Pause
+// states are kept in memory and written through to the storer, if any
+type scheduledActionRegistry struct {
+	storer store.GlobalSiloStringStorer
+
+	sync.RWMutex
+	entries []*scheduledActionEntry
+	byID    map[string]*scheduledActionEntry
+	paused  map[string]bool
+
+	// lastRuns are the times of the last runs of actions catching up on missed runs (see RunOnceMissedRuns)
+	lastRuns map[string]time.Time
+
+	// random picks the jitter of runs
+	random *rand.Rand
+
+	// running tracks the runs of scheduled actions for a shutdown to wait for them. No more runs start once stopped
+	// and done is closed to end the timers of cron schedules
+	running sync.WaitGroup
+	stopped bool
+	done    chan struct{}
+}
+
+// OptionScheduledActionStorer sets the storer persisting the pause state of scheduled actions. Setting it also
+// adds the schedule command for admins to list, pause, resume or immediately run scheduled actions
+func OptionScheduledActionStorer(storer store.GlobalSiloStringStorer) Option {
+	return func(s *Slackscot) {
+		s.scheduledActionStorer = storer
+	}
+}
+
+// newScheduledActionRegistry creates the registry of the scheduled actions of all plugins with the pause states
+// persisted in the storer, if any. Actions are identified by their plugin and index (i.e. ohMonday.scheduledAction[0])
+func newScheduledActionRegistry(plugins []*Plugin, storer store.GlobalSiloStringStorer) (r *scheduledActionRegistry, err error) {
+	r = new(scheduledActionRegistry)
+	r.storer = storer
+	r.byID = make(map[string]*scheduledActionEntry)
+	r.paused = make(map[string]bool)
+	r.done = make(chan struct{})
+	r.lastRuns = make(map[string]time.Time)
+	r.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, p := range plugins {
+		for i, sa := range p.ScheduledActions {
+			e := &scheduledActionEntry{id: getActionID(p.Name, scheduledActionType, i), plugin: p.Name, ScheduledActionDefinition: sa}
+			r.entries = append(r.entries, e)
+			r.byID[strings.ToLower(e.id)] = e
+		}
+	}
+
+	if storer == nil {
+		return r, nil
+	}
+
+	states, err := storer.ScanSilo(scheduledActionsSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, state := range states {
+		r.paused[id] = state == pausedScheduledActionState
+	}
+
+	runs, err := storer.ScanSilo(scheduledActionRunsSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, value := range runs {
+		if lastRun, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			r.lastRuns[id] = lastRun
+		}
+	}
+
+	return r, nil
+}
+
+// loadScheduledActions creates the registry of scheduled actions. This only happens once (on Run or when processing
+// starts, whichever comes first) so that plugins registered afterwards (like the help plugin) aren't included
+func (s *Slackscot) loadScheduledActions() (err error) {
+	if s.scheduledActions == nil {
+		s.scheduledActions, err = newScheduledActionRegistry(s.plugins, s.scheduledActionStorer)
+	}
+
+	return err
+}
+
+// schedule registers all scheduled actions with the scheduler, except for the ones on a cron schedule which run on
+// their own timer, in their time zone or the default location. Paused actions are scheduled like the others but skip
+// their runs. Actions with missed runs to catch up on run once right away (see RunOnceMissedRuns)
+func (r *scheduledActionRegistry) schedule(sc *gocron.Scheduler, defaultLocation *time.Location, logger SLogger) {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	for _, e := range r.entries {
+		if e.Schedule.Cron != "" {
+			if err := r.scheduleCron(e, defaultLocation, logger); err != nil {
+				logger.Printf("Error: failed to schedule scheduled action ['%s' - %s]: %v\n", e.Schedule, e.Description, err)
+				continue
+			}
+
+			r.catchUp(e, now, logger)
+			continue
+		}
+
+		j, err := schedule.NewJob(sc, e.Schedule)
+		if err == nil {
+			logger.Debugf("Adding job [%v] to scheduler\n", j)
+			err = j.Do(r.runner(e, logger))
+		}
+
+		if err != nil {
+			logger.Printf("Error: failed to schedule job for scheduled action ['%s' - %s]: %v\n", e.Schedule, e.Description, err)
+			continue
+		}
+
+		e.job = j
+		r.catchUp(e, now, logger)
+	}
+}
+
+// catchUp runs a scheduled action once if its policy is to catch up on missed runs and it missed any since its last
+// run. Actions that never ran are considered to have run when first scheduled. The caller must hold the lock
+func (r *scheduledActionRegistry) catchUp(e *scheduledActionEntry, now time.Time, logger SLogger) {
+	if e.MissedRuns != RunOnceMissedRuns {
+		return
+	}
+
+	if r.storer == nil {
+		logger.Printf("Warning: missed runs of scheduled action [%s] can't be caught up without a scheduled action storer (see OptionScheduledActionStorer)\n", e.id)
+		return
+	}
+
+	lastRun, ok := r.lastRuns[e.id]
+	if !ok {
+		if err := r.recordRunLocked(e.id, now); err != nil {
+			logger.Printf("Error recording the first schedule of scheduled action [%s]: %v\n", e.id, err)
+		}
+
+		return
+	}
+
+	if e.missedRun(lastRun, now) {
+		logger.Printf("Scheduled action [%s] missed runs since its last run at [%s], running it once to catch up\n", e.id, lastRun)
+		go r.runner(e, logger)()
+	}
+}
+
+// missedRun returns true if a run of a scheduled action was due between its last run and now. For schedules without a
+// time of the day or weekday, that's when more than a period went by since the last run. The caller must hold the lock
+func (e *scheduledActionEntry) missedRun(lastRun time.Time, now time.Time) bool {
+	if e.cron != nil {
+		due := e.cron.Next(lastRun.In(e.location))
+		return !due.IsZero() && !due.After(now)
+	}
+
+	period := e.Schedule.Period()
+	if e.Schedule.Weekday == "" && e.Schedule.AtTime == "" {
+		return !lastRun.Add(period).After(now)
+	}
+
+	if e.job == nil {
+		return false
+	}
+
+	due := e.job.NextScheduledTime().Add(-period)
+	return lastRun.Before(due) && !due.After(now)
+}
+
+// recordRunLocked persists the time of the last run of a scheduled action. The caller must hold the lock
+func (r *scheduledActionRegistry) recordRunLocked(id string, lastRun time.Time) (err error) {
+	if err = r.storer.PutSiloString(scheduledActionRunsSilo, id, lastRun.Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+
+	r.lastRuns[id] = lastRun
+	return nil
+}
+
+// scheduleCron parses the cron expression of a scheduled action and starts its timer. The caller must hold the lock
+func (r *scheduledActionRegistry) scheduleCron(e *scheduledActionEntry, defaultLocation *time.Location, logger SLogger) (err error) {
+	if e.cron, err = schedule.ParseCron(e.Schedule.Cron); err != nil {
+		return err
+	}
+
+	if e.location, err = e.Schedule.Location(defaultLocation); err != nil {
+		return err
+	}
+
+	if e.next = e.cron.Next(time.Now().In(e.location)); e.next.IsZero() {
+		return fmt.Errorf("Cron schedule [%s] never runs", e.Schedule)
+	}
+
+	logger.Debugf("Running scheduled action [%s] on cron schedule [%s] in [%s], next at [%s]\n", e.id, e.Schedule, e.location, e.next)
+	go r.runCron(e, r.runner(e, logger))
+
+	return nil
+}
+
+// runCron runs a scheduled action at the next runs of its cron schedule until the registry is stopped
+func (r *scheduledActionRegistry) runCron(e *scheduledActionEntry, run func()) {
+	for {
+		next, _ := r.nextRun(e)
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-r.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		run()
+
+		r.Lock()
+		e.next = e.cron.Next(time.Now().In(e.location))
+		r.Unlock()
+	}
+}
+
+// runner returns the function run by the scheduler for a scheduled action
+func (r *scheduledActionRegistry) runner(e *scheduledActionEntry, logger SLogger) func() {
+	return func() {
+		if r.isPaused(e.id) {
+			logger.Debugf("Skipping run of paused scheduled action [%s]\n", e.id)
+			return
+		}
+
+		if !r.track() {
+			logger.Debugf("Skipping run of scheduled action [%s] since shutting down\n", e.id)
+			return
+		}
+
+		// Runs with a jitter wait in a go routine so that they don't hold up the other scheduled actions
+		if e.Jitter > 0 {
+			go func() {
+				defer r.running.Done()
+
+				if r.waitJitter(e) {
+					r.run(e, logger)
+				}
+			}()
+
+			return
+		}
+
+		defer r.running.Done()
+		r.run(e, logger)
+	}
+}
+
+// waitJitter waits for a random delay of up to the jitter of a scheduled action and returns false if the registry was
+// stopped in the meantime
+func (r *scheduledActionRegistry) waitJitter(e *scheduledActionEntry) bool {
+	r.Lock()
+	delay := time.Duration(r.random.Int63n(int64(e.Jitter) + 1))
+	r.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-r.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// run runs a scheduled action, recording the time of the run first for actions catching up on missed runs
+func (r *scheduledActionRegistry) run(e *scheduledActionEntry, logger SLogger) {
+	if e.MissedRuns == RunOnceMissedRuns && r.storer != nil {
+		r.Lock()
+		err := r.recordRunLocked(e.id, time.Now())
+		r.Unlock()
+
+		if err != nil {
+			logger.Printf("Error recording the run of scheduled action [%s]: %v\n", e.id, err)
+		}
+	}
+
+	e.Action()
+}
+
+// track registers the run of a scheduled action for stop to wait for it (the caller must call running.Done once the
+// action is done). It returns false, without registering anything, once stopped
+func (r *scheduledActionRegistry) track() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.stopped {
+		return false
+	}
+
+	r.running.Add(1)
+	return true
+}
+
+// stop prevents any further run of scheduled actions and waits for the running ones to finish or for the context to be
+// done, in which case the context's error is returned
+func (r *scheduledActionRegistry) stop(ctx context.Context) (err error) {
+	r.Lock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.done)
+	}
+	r.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+
+	return waitUntilClosed(ctx, done)
+}
+
+// find returns the scheduled action with the given id (case insensitive)
+func (r *scheduledActionRegistry) find(id string) (e *scheduledActionEntry, ok bool) {
+	e, ok = r.byID[strings.ToLower(id)]
+	return e, ok
+}
+
+// isPaused returns true if the scheduled action is paused
+func (r *scheduledActionRegistry) isPaused(id string) bool {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.paused[id]
+}
+
+// nextRun returns the time of the next run of a scheduled action, in the location of its schedule, and false if it
+// isn't scheduled
+func (r *scheduledActionRegistry) nextRun(e *scheduledActionEntry) (next time.Time, scheduled bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if e.cron != nil {
+		return e.next, !e.next.IsZero()
+	}
+
+	if e.job == nil {
+		return next, false
+	}
+
+	return e.job.NextScheduledTime(), true
+}
+
+// setPaused persists the pause state of a scheduled action
+func (r *scheduledActionRegistry) setPaused(id string, paused bool) (err error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if paused {
+		err = r.storer.PutSiloString(scheduledActionsSilo, id, pausedScheduledActionState)
+	} else {
+		err = r.storer.DeleteSiloString(scheduledActionsSilo, id)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.paused[id] = paused
+	return nil
+}
+
+// scheduledActionsPlugin holds the schedule command letting admins manage scheduled actions at runtime
+type scheduledActionsPlugin struct {
+	Plugin
+
+	registry *scheduledActionRegistry
+	admins   map[string]bool
+}
+
+// newScheduledActionsPlugin creates the plugin of the schedule command
+func (s *Slackscot) newScheduledActionsPlugin() *scheduledActionsPlugin {
+	sp := new(scheduledActionsPlugin)
+	sp.registry = s.scheduledActions
+	sp.admins = make(map[string]bool)
+	for _, userID := range s.config.GetStringSlice(config.ScheduleAdminIDsKey) {
+		sp.admins[userID] = true
+	}
+
+	sp.Plugin = Plugin{Name: scheduledActionsPluginName, NormalizeCommands: true, Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return scheduleListRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "schedule list",
+		Description: "Lists all scheduled actions with their next run (admins only)",
+		Answer:      sp.listScheduledActions,
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return scheduleControlRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "schedule pause|resume|run `<id>`",
+		Description: "Pauses, resumes or immediately runs a scheduled action (admins only)",
+		Answer:      sp.controlScheduledAction,
+	}}}
+
+	return sp
+}
+
+// rejectNonAdmin returns the answer to a user that isn't a schedule admin or nil if the user is one
+func (sp *scheduledActionsPlugin) rejectNonAdmin(m *IncomingMessage) *Answer {
+	if sp.admins[m.User] {
+		return nil
+	}
+
+	return &Answer{Text: "Sorry, only schedule admins can manage scheduled actions :no_entry_sign:", Options: []AnswerOption{AnswerEphemeral(m.User)}}
+}
+
+// listScheduledActions lists all scheduled actions with their schedule, state and next run
+func (sp *scheduledActionsPlugin) listScheduledActions(m *IncomingMessage) *Answer {
+	if rejection := sp.rejectNonAdmin(m); rejection != nil {
+		return rejection
+	}
+
+	if len(sp.registry.entries) == 0 {
+		return &Answer{Text: "There are no scheduled actions :zzz:"}
+	}
+
+	var b strings.Builder
+	for _, e := range sp.registry.entries {
+		if e.Schedule.Timezone != "" {
+			fmt.Fprintf(&b, "• `%s` (%s in %s): %s", e.id, e.Schedule, e.Schedule.Timezone, e.Description)
+		} else {
+			fmt.Fprintf(&b, "• `%s` (%s): %s", e.id, e.Schedule, e.Description)
+		}
+
+		next, scheduled := sp.registry.nextRun(e)
+		if sp.registry.isPaused(e.id) {
+			fmt.Fprintf(&b, " - *paused* :double_vertical_bar:\n")
+		} else if !scheduled {
+			fmt.Fprintf(&b, " - *not scheduled* :warning:\n")
+		} else {
+			fmt.Fprintf(&b, " - next run at %s\n", next.Format(time.RFC1123))
+		}
+	}
+
+	return &Answer{Text: strings.TrimSuffix(b.String(), "\n")}
+}
+
+// controlScheduledAction pauses, resumes or immediately runs a scheduled action
+func (sp *scheduledActionsPlugin) controlScheduledAction(m *IncomingMessage) *Answer {
+	if rejection := sp.rejectNonAdmin(m); rejection != nil {
+		return rejection
+	}
+
+	matches := scheduleControlRegex.FindStringSubmatch(m.NormalizedText)
+	verb := strings.ToLower(matches[1])
+
+	e, ok := sp.registry.find(matches[2])
+	if !ok {
+		return &Answer{Text: fmt.Sprintf("Sorry, there's no scheduled action `%s` :thinking_face: (see `schedule list`)", matches[2])}
+	}
+
+	if verb == "run" {
+		// Run in a go routine so that a long action doesn't hold up message processing
+		if !sp.registry.track() {
+			return &Answer{Text: fmt.Sprintf("Sorry, `%s` can't run while shutting down :wave:", e.id)}
+		}
+
+		sp.Logger.Printf("Running scheduled action [%s] on demand of [%s]", e.id, m.User)
+		go func() {
+			defer sp.registry.running.Done()
+			e.Action()
+		}()
+
+		return &Answer{Text: fmt.Sprintf("`%s` is running :runner:", e.id)}
+	}
+
+	if err := sp.registry.setPaused(e.id, verb == "pause"); err != nil {
+		sp.Logger.Printf("Error setting scheduled action [%s] paused state: %v", e.id, err)
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't %s `%s` :disappointed: (%v)", verb, e.id, err)}
+	}
+
+	if verb == "pause" {
+		return &Answer{Text: fmt.Sprintf("`%s` is paused :double_vertical_bar:", e.id)}
+	}
+
+	return &Answer{Text: fmt.Sprintf("`%s` is resumed :arrow_forward:", e.id)}
+}
@@ -0,0 +1,309 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/marcsantiago/gocron"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newWeeklyReportPlugin(runs chan<- bool) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "report"
+	p.ScheduledActions = []ScheduledActionDefinition{{Schedule: schedule.Definition{Interval: 1, Weekday: time.Monday.String(), AtTime: "10:00"}, Description: "Send the weekly report", Action: func() {
+		runs <- true
+	}}}
+
+	return p
+}
+
+func TestScheduledActionPauseStatePersisted(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	runs := make(chan bool, 1)
+	plugins := []*Plugin{newWeeklyReportPlugin(runs)}
+
+	r, err := newScheduledActionRegistry(plugins, storer)
+	require.NoError(t, err)
+
+	e, ok := r.find("REPORT.scheduledAction[0]")
+	require.True(t, ok)
+	assert.Equal(t, "report.scheduledAction[0]", e.id)
+
+	require.NoError(t, r.setPaused(e.id, true))
+
+	// Paused actions skip their runs
+	r.runner(e, NewSLogger(log.New(&strings.Builder{}, "", 0), false))()
+	assert.Len(t, runs, 0)
+
+	// Pause states are persisted
+	r, err = newScheduledActionRegistry(plugins, storer)
+	require.NoError(t, err)
+	assert.True(t, r.isPaused("report.scheduledAction[0]"))
+
+	require.NoError(t, r.setPaused("report.scheduledAction[0]", false))
+	r.runner(r.entries[0], NewSLogger(log.New(&strings.Builder{}, "", 0), false))()
+	assert.Len(t, runs, 1)
+
+	r, err = newScheduledActionRegistry(plugins, storer)
+	require.NoError(t, err)
+	assert.False(t, r.isPaused("report.scheduledAction[0]"))
+}
+
+func TestListScheduledActions(t *testing.T) {
+	brokenPlugin := new(Plugin)
+	brokenPlugin.Name = "broken"
+	brokenPlugin.ScheduledActions = []ScheduledActionDefinition{{Schedule: schedule.Definition{Interval: 1, Unit: schedule.Hours, AtTime: "10:00"}, Description: "Never scheduled", Action: func() {}}}
+
+	r, err := newScheduledActionRegistry([]*Plugin{newWeeklyReportPlugin(make(chan bool)), brokenPlugin}, nil)
+	require.NoError(t, err)
+
+	r.schedule(gocron.NewScheduler(), time.Local, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+
+	sp := &scheduledActionsPlugin{registry: r, admins: map[string]bool{"Alphonse": true}}
+	next, _ := r.nextRun(r.entries[0])
+
+	answer := sp.listScheduledActions(&IncomingMessage{Msg: slack.Msg{User: "Alphonse"}})
+	assert.Equal(t, "• `report.scheduledAction[0]` (Every Monday at 10:00): Send the weekly report - next run at "+next.Format(time.RFC1123)+"\n"+
+		"• `broken.scheduledAction[0]` (Every hour at 10:00): Never scheduled - *not scheduled* :warning:", answer.Text)
+}
+
+func TestScheduleCommands(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.ScheduleAdminIDsKey, []string{"Alphonse"})
+
+	runs := make(chan bool, 1)
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newWeeklyReportPlugin(runs), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "schedule list", "Ignored", "1546833200.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "schedule pause report.scheduledAction[0]", "Alphonse", "1546833210.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "schedule list", "Alphonse", "1546833220.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "schedule run report.scheduledAction[0]", "Alphonse", "1546833230.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "schedule resume report.scheduledAction[0]", "Alphonse", "1546833240.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "schedule pause report.scheduledAction[1]", "Alphonse", "1546833250.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+	}, nil, OptionScheduledActionStorer(storer))
+
+	if assert.Equal(t, 6, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Ignored>: Sorry, only schedule admins can manage scheduled actions :no_entry_sign:", vals.Get("text"))
+		assert.Equal(t, "Ignored", vals.Get("user"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `report.scheduledAction[0]` is paused :double_vertical_bar:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[2].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: • `report.scheduledAction[0]` (Every Monday at 10:00): Send the weekly report - *paused* :double_vertical_bar:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[3].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `report.scheduledAction[0]` is running :runner:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[4].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `report.scheduledAction[0]` is resumed :arrow_forward:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[5].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: Sorry, there's no scheduled action `report.scheduledAction[1]` :thinking_face: (see `schedule list`)", vals.Get("text"))
+	}
+
+	// Immediate runs happen even when the action is paused
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		assert.Fail(t, "scheduled action wasn't run on demand")
+	}
+
+	// The pause state was persisted and then cleared
+	states, err := storer.ScanSilo(scheduledActionsSilo)
+	require.NoError(t, err)
+	assert.Empty(t, states)
+}
+
+func TestCronScheduledActions(t *testing.T) {
+	reportPlugin := new(Plugin)
+	reportPlugin.Name = "report"
+	reportPlugin.ScheduledActions = []ScheduledActionDefinition{
+		{Schedule: schedule.New().WithCron("0 9 * * MON-FRI").InTimezone("America/Toronto").Build(), Description: "Send the daily report", Action: func() {}},
+		{Schedule: schedule.New().WithCron("@daily").Build(), Description: "Roll the logs", Action: func() {}},
+		{Schedule: schedule.New().WithCron("0 0 30 2 *").Build(), Description: "Never runs", Action: func() {}},
+		{Schedule: schedule.New().WithCron("@daily").InTimezone("Nowhere/Special").Build(), Description: "Lost", Action: func() {}},
+	}
+
+	r, err := newScheduledActionRegistry([]*Plugin{reportPlugin}, nil)
+	require.NoError(t, err)
+	defer r.stop(context.Background())
+
+	utc, err := time.LoadLocation("UTC")
+	require.NoError(t, err)
+	r.schedule(gocron.NewScheduler(), utc, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+
+	weekdayReport, scheduled := r.nextRun(r.entries[0])
+	require.True(t, scheduled)
+	assert.Equal(t, "America/Toronto", weekdayReport.Location().String())
+	assert.Equal(t, 9, weekdayReport.Hour())
+	assert.NotContains(t, []time.Weekday{time.Saturday, time.Sunday}, weekdayReport.Weekday())
+
+	logRoll, scheduled := r.nextRun(r.entries[1])
+	require.True(t, scheduled)
+	assert.Equal(t, utc, logRoll.Location())
+	assert.Equal(t, 0, logRoll.Hour())
+
+	_, scheduled = r.nextRun(r.entries[2])
+	assert.False(t, scheduled)
+	_, scheduled = r.nextRun(r.entries[3])
+	assert.False(t, scheduled)
+
+	sp := &scheduledActionsPlugin{registry: r, admins: map[string]bool{"Alphonse": true}}
+	answer := sp.listScheduledActions(&IncomingMessage{Msg: slack.Msg{User: "Alphonse"}})
+	assert.Equal(t, "• `report.scheduledAction[0]` (0 9 * * MON-FRI in America/Toronto): Send the daily report - next run at "+weekdayReport.Format(time.RFC1123)+"\n"+
+		"• `report.scheduledAction[1]` (@daily): Roll the logs - next run at "+logRoll.Format(time.RFC1123)+"\n"+
+		"• `report.scheduledAction[2]` (0 0 30 2 *): Never runs - *not scheduled* :warning:\n"+
+		"• `report.scheduledAction[3]` (@daily in Nowhere/Special): Lost - *not scheduled* :warning:", answer.Text)
+
+	var b strings.Builder
+	appendScheduledActions(&b, "UTC", filterNonHiddenScheduledActions(reportPlugin.Name, reportPlugin.ScheduledActions[:2]), r)
+	assert.Equal(t, "\t• [`report`] `0 9 * * MON-FRI` (`America/Toronto`) - Send the daily report (next run at "+weekdayReport.Format(time.RFC1123)+")\n"+
+		"\t• [`report`] `@daily` (`UTC`) - Roll the logs (next run at "+logRoll.Format(time.RFC1123)+")\n", b.String())
+}
+
+func TestCronScheduledActionTimersEndOnStop(t *testing.T) {
+	p := new(Plugin)
+	p.Name = "report"
+	p.ScheduledActions = []ScheduledActionDefinition{{Schedule: schedule.New().WithCron("* * * * *").Build(), Description: "Every minute", Action: func() {}}}
+
+	r, err := newScheduledActionRegistry([]*Plugin{p}, nil)
+	require.NoError(t, err)
+
+	r.schedule(gocron.NewScheduler(), time.Local, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, r.stop(context.Background()))
+
+	// Stopping again is a no-op
+	require.NoError(t, r.stop(context.Background()))
+}
+
+func newCatchingUpPlugin(runs chan<- string, policy MissedRunPolicy) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "announce"
+
+	newAction := func(name string, def schedule.Definition) ScheduledActionDefinition {
+		return ScheduledActionDefinition{Schedule: def, Description: name, MissedRuns: policy, Action: func() {
+			runs <- name
+		}}
+	}
+
+	p.ScheduledActions = []ScheduledActionDefinition{
+		newAction("daily", schedule.New().WithCron("@daily").Build()),
+		newAction("dailyAtTen", schedule.New().WithUnit(schedule.Days).AtTime("10:00").Build()),
+		newAction("hourly", schedule.New().WithUnit(schedule.Hours).Build()),
+	}
+
+	return p
+}
+
+func receiveRuns(runs <-chan string, timeout time.Duration) (names []string) {
+	names = make([]string, 0)
+	for {
+		select {
+		case name := <-runs:
+			names = append(names, name)
+		case <-time.After(timeout):
+			return names
+		}
+	}
+}
+
+func TestScheduledActionsCatchUpOnMissedRuns(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	twoDaysAgo := time.Now().Add(-48 * time.Hour).Format(time.RFC3339Nano)
+	require.NoError(t, storer.PutSiloString(scheduledActionRunsSilo, "announce.scheduledAction[0]", twoDaysAgo))
+	require.NoError(t, storer.PutSiloString(scheduledActionRunsSilo, "announce.scheduledAction[1]", twoDaysAgo))
+	require.NoError(t, storer.PutSiloString(scheduledActionRunsSilo, "announce.scheduledAction[2]", time.Now().Add(-30*time.Minute).Format(time.RFC3339Nano)))
+
+	runs := make(chan string, 10)
+	r, err := newScheduledActionRegistry([]*Plugin{newCatchingUpPlugin(runs, RunOnceMissedRuns)}, storer)
+	require.NoError(t, err)
+	defer r.stop(context.Background())
+
+	before := time.Now()
+	r.schedule(gocron.NewScheduler(), time.Local, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+
+	// The hourly action last ran 30 minutes ago so it didn't miss a run
+	assert.ElementsMatch(t, []string{"daily", "dailyAtTen"}, receiveRuns(runs, 100*time.Millisecond))
+
+	lastRuns, err := storer.ScanSilo(scheduledActionRunsSilo)
+	require.NoError(t, err)
+	for _, id := range []string{"announce.scheduledAction[0]", "announce.scheduledAction[1]"} {
+		lastRun, err := time.Parse(time.RFC3339Nano, lastRuns[id])
+		require.NoError(t, err)
+		assert.Falsef(t, lastRun.Before(before), "Expected last run of [%s] to be recorded", id)
+	}
+}
+
+func TestScheduledActionsSkipMissedRuns(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	twoDaysAgo := time.Now().Add(-48 * time.Hour).Format(time.RFC3339Nano)
+	require.NoError(t, storer.PutSiloString(scheduledActionRunsSilo, "announce.scheduledAction[0]", twoDaysAgo))
+
+	runs := make(chan string, 10)
+	r, err := newScheduledActionRegistry([]*Plugin{newCatchingUpPlugin(runs, SkipMissedRuns)}, storer)
+	require.NoError(t, err)
+	defer r.stop(context.Background())
+
+	r.schedule(gocron.NewScheduler(), time.Local, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+
+	assert.Empty(t, receiveRuns(runs, 100*time.Millisecond))
+}
+
+func TestScheduledActionsFirstScheduleRecordedAsLastRun(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	runs := make(chan string, 10)
+	r, err := newScheduledActionRegistry([]*Plugin{newCatchingUpPlugin(runs, RunOnceMissedRuns)}, storer)
+	require.NoError(t, err)
+	defer r.stop(context.Background())
+
+	r.schedule(gocron.NewScheduler(), time.Local, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+
+	assert.Empty(t, receiveRuns(runs, 100*time.Millisecond))
+
+	lastRuns, err := storer.ScanSilo(scheduledActionRunsSilo)
+	require.NoError(t, err)
+	assert.Len(t, lastRuns, 3)
+}
+
+func TestScheduledActionRunsWithJitter(t *testing.T) {
+	runs := make(chan string, 10)
+	p := new(Plugin)
+	p.Name = "announce"
+	p.ScheduledActions = []ScheduledActionDefinition{
+		{Schedule: schedule.New().WithUnit(schedule.Hours).Build(), Jitter: 20 * time.Millisecond, Action: func() { runs <- "soon" }},
+		{Schedule: schedule.New().WithUnit(schedule.Hours).Build(), Jitter: time.Hour, Action: func() { runs <- "later" }},
+	}
+
+	r, err := newScheduledActionRegistry([]*Plugin{p}, nil)
+	require.NoError(t, err)
+
+	logger := NewSLogger(log.New(&strings.Builder{}, "", 0), false)
+	r.runner(r.entries[0], logger)()
+	r.runner(r.entries[1], logger)()
+
+	assert.Equal(t, []string{"soon"}, receiveRuns(runs, 100*time.Millisecond))
+
+	// Stopping ends the wait of runs with a jitter without running them
+	require.NoError(t, r.stop(context.Background()))
+	assert.Empty(t, receiveRuns(runs, 50*time.Millisecond))
+}
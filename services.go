@@ -0,0 +1,130 @@
+package slackscot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ServiceRegistry gives access to the services provided by plugins (via Plugin.Provides). A plugin
+// should only look up services it declared in Plugin.Requires since those are the ones guaranteed to be
+// present once slackscot is running
+type ServiceRegistry interface {
+	// Service returns the service registered under the given name along with whether or not it exists
+	Service(name string) (service interface{}, exists bool)
+}
+
+// serviceRegistry holds all services provided by plugins indexed by name
+type serviceRegistry struct {
+	services map[string]interface{}
+}
+
+// Service returns the service registered under the given name along with whether or not it exists
+func (sr *serviceRegistry) Service(name string) (service interface{}, exists bool) {
+	service, exists = sr.services[name]
+	return service, exists
+}
+
+// newServiceRegistry resolves the services provided and required by plugins and returns the registry holding them.
+// An error is returned if two plugins provide the same service, if a required service isn't provided by any plugin
+// or if there is a dependency cycle between plugins
+func newServiceRegistry(plugins []*Plugin) (sr *serviceRegistry, err error) {
+	sr = new(serviceRegistry)
+	sr.services = make(map[string]interface{})
+	providers := make(map[string]*Plugin)
+
+	for _, p := range plugins {
+		for name, service := range p.Provides {
+			if provider, exists := providers[name]; exists {
+				return nil, fmt.Errorf("Service [%s] provided by plugin [%s] is already provided by plugin [%s]", name, p.Name, provider.Name)
+			}
+
+			providers[name] = p
+			sr.services[name] = service
+		}
+	}
+
+	for _, p := range plugins {
+		for _, name := range p.Requires {
+			if _, exists := providers[name]; !exists {
+				return nil, fmt.Errorf("Service [%s] required by plugin [%s] isn't provided by any registered plugin", name, p.Name)
+			}
+		}
+	}
+
+	if cycle := findDependencyCycle(plugins, providers); cycle != nil {
+		return nil, fmt.Errorf("Dependency cycle detected between plugins: [%s]", strings.Join(cycle, " -> "))
+	}
+
+	return sr, nil
+}
+
+// Plugin visit states used for dependency cycle detection
+const (
+	pluginUnvisited = iota
+	pluginVisiting
+	pluginVisited
+)
+
+// findDependencyCycle does a depth-first traversal of the plugin dependency graph (a plugin depends on the providers of the
+// services it requires) and returns the names of the plugins forming a cycle or nil if there are none
+func findDependencyCycle(plugins []*Plugin, providers map[string]*Plugin) (cycle []string) {
+	states := make(map[*Plugin]int)
+	path := make([]*Plugin, 0)
+
+	var visit func(p *Plugin) []string
+	visit = func(p *Plugin) []string {
+		states[p] = pluginVisiting
+		path = append(path, p)
+
+		// Sort requirements to make cycle reporting deterministic
+		requires := append([]string{}, p.Requires...)
+		sort.Strings(requires)
+
+		for _, name := range requires {
+			dep := providers[name]
+
+			// Depending on a service provided by itself isn't a cycle
+			if dep == p {
+				continue
+			}
+
+			switch states[dep] {
+			case pluginVisiting:
+				return cycleNames(path, dep)
+			case pluginUnvisited:
+				if c := visit(dep); c != nil {
+					return c
+				}
+			}
+		}
+
+		states[p] = pluginVisited
+		path = path[:len(path)-1]
+		return nil
+	}
+
+	for _, p := range plugins {
+		if states[p] == pluginUnvisited {
+			if c := visit(p); c != nil {
+				return c
+			}
+		}
+	}
+
+	return nil
+}
+
+// cycleNames returns the names of the plugins forming the cycle starting at the given plugin on the traversal path
+func cycleNames(path []*Plugin, start *Plugin) (names []string) {
+	names = make([]string, 0)
+	for i, p := range path {
+		if p == start {
+			for _, c := range path[i:] {
+				names = append(names, c.Name)
+			}
+		}
+	}
+
+	return append(names, start.Name)
+}
@@ -0,0 +1,122 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"strings"
+	"testing"
+)
+
+func newServicePlugin(name string, provides []string, requires ...string) (p *Plugin) {
+	p = &Plugin{Name: name, Provides: make(map[string]interface{}), Requires: requires}
+	for _, s := range provides {
+		p.Provides[s] = name + "." + s
+	}
+
+	return p
+}
+
+func TestNewServiceRegistry(t *testing.T) {
+	tests := map[string]struct {
+		plugins          []*Plugin
+		expectedErr      string
+		expectedServices map[string]interface{}
+	}{
+		"NoServices": {
+			plugins:          []*Plugin{newServicePlugin("karma", nil), newServicePlugin("triggerer", nil)},
+			expectedServices: map[string]interface{}{},
+		},
+		"SatisfiedRequirement": {
+			plugins:          []*Plugin{newServicePlugin("recognition", nil, "reader"), newServicePlugin("karma", []string{"reader"})},
+			expectedServices: map[string]interface{}{"reader": "karma.reader"},
+		},
+		"DuplicateProvider": {
+			plugins:     []*Plugin{newServicePlugin("karma", []string{"reader"}), newServicePlugin("otherKarma", []string{"reader"})},
+			expectedErr: "Service [reader] provided by plugin [otherKarma] is already provided by plugin [karma]",
+		},
+		"MissingRequirement": {
+			plugins:     []*Plugin{newServicePlugin("recognition", nil, "reader")},
+			expectedErr: "Service [reader] required by plugin [recognition] isn't provided by any registered plugin",
+		},
+		"TwoPluginCycle": {
+			plugins:     []*Plugin{newServicePlugin("a", []string{"x"}, "y"), newServicePlugin("b", []string{"y"}, "x")},
+			expectedErr: "Dependency cycle detected between plugins: [a -> b -> a]",
+		},
+		"ThreePluginCycle": {
+			plugins:     []*Plugin{newServicePlugin("a", []string{"x"}, "y"), newServicePlugin("b", []string{"y"}, "z"), newServicePlugin("c", []string{"z"}, "x")},
+			expectedErr: "Dependency cycle detected between plugins: [a -> b -> c -> a]",
+		},
+		"SelfProvidedRequirement": {
+			plugins:          []*Plugin{newServicePlugin("a", []string{"x"}, "x")},
+			expectedServices: map[string]interface{}{"x": "a.x"},
+		},
+		"DiamondIsNotACycle": {
+			plugins:          []*Plugin{newServicePlugin("a", nil, "x", "y"), newServicePlugin("b", []string{"x"}, "z"), newServicePlugin("c", []string{"y"}, "z"), newServicePlugin("d", []string{"z"})},
+			expectedServices: map[string]interface{}{"x": "b.x", "y": "c.y", "z": "d.z"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			sr, err := newServiceRegistry(tc.plugins)
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				assert.Nil(t, sr)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.expectedServices, sr.services)
+			}
+		})
+	}
+}
+
+func TestServiceLookup(t *testing.T) {
+	sr, err := newServiceRegistry([]*Plugin{newServicePlugin("karma", []string{"reader"})})
+	assert.NoError(t, err)
+
+	service, exists := sr.Service("reader")
+	assert.True(t, exists)
+	assert.Equal(t, "karma.reader", service)
+
+	_, exists = sr.Service("writer")
+	assert.False(t, exists)
+}
+
+func TestServicesInjectedAndResolvableFromAction(t *testing.T) {
+	provider := newServicePlugin("karma", []string{"reader"})
+	consumer := &Plugin{Name: "recognition", Requires: []string{"reader"}}
+	consumer.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "who provides")
+		},
+		Usage:       "who provides",
+		Description: "Tells who provides the reader",
+		Answer: func(m *IncomingMessage) *Answer {
+			service, exists := consumer.Services.Service("reader")
+			return &Answer{Text: fmt.Sprintf("%v (%t)", service, exists)}
+		},
+	}}
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, nil, []*Plugin{provider, consumer}, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "who provides", "Alphonse", timestamp1)),
+	}, nil)
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "karma.reader (true)", vals.Get("text"))
+	}
+}
+
+func TestUnsatisfiedServicesAbortProcessing(t *testing.T) {
+	var logBuilder strings.Builder
+	logger := log.New(&logBuilder, "", 0)
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, nil, []*Plugin{newServicePlugin("recognition", nil, "reader")}, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "help", "Alphonse", timestamp1)),
+	}, nil, OptionLog(logger))
+
+	assert.Empty(t, sentMsgs)
+	assert.Contains(t, logBuilder.String(), "Error resolving plugin services: Service [reader] required by plugin [recognition] isn't provided by any registered plugin")
+}
@@ -0,0 +1,53 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+)
+
+// isSafeModeChannel returns true if safe mode is enabled (see config.SharedChannelsSafeModeKey) and the channel is
+// shared, or about to be, with other organizations. Channels whose info can't be loaded are considered shared to err on
+// the safe side
+func (s *Slackscot) isSafeModeChannel(channelID string) bool {
+	if !s.config.GetBool(config.SharedChannelsSafeModeKey) || s.channelInfoFinder == nil {
+		return false
+	}
+
+	channel, err := s.channelInfoFinder.GetConversationInfo(channelID, false)
+	if err != nil {
+		s.log.Printf("Error loading the info of channel [%s], considering it externally shared: %v", channelID, err)
+		return true
+	}
+
+	return channel.IsExtShared || channel.IsPendingExtShared
+}
+
+// pluginRuns returns true if the plugin runs in the channel (see pluginChannels) and, for channels in safe mode, if it's
+// one of the plugins allowed in externally shared channels (see config.SharedChannelsAllowedPluginsKey)
+func (s *Slackscot) pluginRuns(pluginName string, channelID string, safeMode bool) bool {
+	if safeMode && !containsString(s.config.GetStringSlice(config.SharedChannelsAllowedPluginsKey), pluginName) {
+		return false
+	}
+
+	return s.pluginChannels.isEnabled(pluginName, channelID)
+}
+
+// ignoresUser returns true if the messages and reactions of the user are ignored in a channel in safe mode because the
+// user belongs to another organization (see config.SharedChannelsIgnoreExternalKey). The team of the user is the one of
+// their message, if known
+func (s *Slackscot) ignoresUser(userID string, teamID string) bool {
+	if !s.config.GetBool(config.SharedChannelsIgnoreExternalKey) {
+		return false
+	}
+
+	if teamID != "" && s.selfIdentity.teamID != "" {
+		return teamID != s.selfIdentity.teamID
+	}
+
+	user, err := s.userInfoFinder.GetUserInfo(userID)
+	if err != nil {
+		s.log.Printf("Error loading the info of user [%s], considering them external: %v", userID, err)
+		return true
+	}
+
+	return user.IsStranger || (s.selfIdentity.teamID != "" && user.TeamID != "" && user.TeamID != s.selfIdentity.teamID)
+}
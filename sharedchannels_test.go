@@ -0,0 +1,132 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slacktest"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newSharedChannelsTestServer creates a slack test server where Cpartners is shared with another organization and
+// Cbroken can't be loaded
+func newSharedChannelsTestServer() (testServer *slacktest.Server) {
+	testServer = slacktest.NewTestServer(func(c slacktest.Customize) {
+		c.Handle("/conversations.info", func(w http.ResponseWriter, r *http.Request) {
+			channelID := r.FormValue("channel")
+			if channelID == "Cbroken" {
+				_, _ = w.Write([]byte(`{"ok": false, "error": "channel_not_found"}`))
+				return
+			}
+
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"ok": true, "channel": {"id": "%s", "is_channel": true, "is_ext_shared": %t}}`, channelID, channelID == "Cpartners")))
+		})
+	})
+	testServer.Start()
+
+	return testServer
+}
+
+func newPingPlugin(name string) (p *Plugin) {
+	return &Plugin{Name: name, HearActions: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "ping")
+		},
+		Usage:       "ping",
+		Description: "Answers pong",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("pong from %s", name)}
+		},
+	}}}
+}
+
+func optionTeam(teamID string) testMsgOption {
+	return func(e *slack.MessageEvent) {
+		e.Team = teamID
+	}
+}
+
+func newSafeModeViper() (v *viper.Viper) {
+	v = config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.SharedChannelsSafeModeKey, true)
+	v.Set(config.SharedChannelsAllowedPluginsKey, []string{"work"})
+
+	return v
+}
+
+func sentTexts(sentMsgs []sentMessage) (texts []string) {
+	texts = make([]string, 0)
+	for _, m := range sentMsgs {
+		texts = append(texts, fmt.Sprintf("%s: %s", m.channelID, applySlackOptions(m.msgOptions...).Get("text")))
+	}
+
+	return texts
+}
+
+func TestSafeModeOnlyRunsAllowedPluginsInSharedChannels(t *testing.T) {
+	testServer := newSharedChannelsTestServer()
+	defer testServer.Stop()
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, newSafeModeViper(), []*Plugin{newPingPlugin("fun"), newPingPlugin("work")}, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "ping", "Alphonse", timestamp1, optionTeam("Tbirds"))),
+		newRTMMessageEvent(newMessageEvent("Cpartners", "ping", "Alphonse", timestamp1, optionTeam("Tbirds"))),
+	}, testServer)
+
+	assert.Equal(t, []string{"Cgeneral: pong from fun", "Cgeneral: pong from work", "Cpartners: pong from work"}, sentTexts(sentMsgs))
+}
+
+func TestSafeModeIgnoresExternalUsersInSharedChannels(t *testing.T) {
+	testServer := newSharedChannelsTestServer()
+	defer testServer.Stop()
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newSafeModeViper(), newPingPlugin("work"), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cpartners", "ping", "Uexternal", timestamp1, optionTeam("Tpartner"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "ping", "Uexternal", timestamp1, optionTeam("Tpartner"))),
+	}, testServer)
+
+	assert.Equal(t, []string{"Cgeneral: pong from work"}, sentTexts(sentMsgs))
+}
+
+func TestSafeModeWithExternalUsersAllowed(t *testing.T) {
+	testServer := newSharedChannelsTestServer()
+	defer testServer.Stop()
+
+	v := newSafeModeViper()
+	v.Set(config.SharedChannelsIgnoreExternalKey, false)
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newPingPlugin("work"), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cpartners", "ping", "Uexternal", timestamp1, optionTeam("Tpartner"))),
+	}, testServer)
+
+	assert.Equal(t, []string{"Cpartners: pong from work"}, sentTexts(sentMsgs))
+}
+
+func TestSafeModeConsidersChannelsWithoutInfoShared(t *testing.T) {
+	testServer := newSharedChannelsTestServer()
+	defer testServer.Stop()
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, newSafeModeViper(), []*Plugin{newPingPlugin("fun"), newPingPlugin("work")}, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cbroken", "ping", "Alphonse", timestamp1, optionTeam("Tbirds"))),
+	}, testServer)
+
+	assert.Equal(t, []string{"Cbroken: pong from work"}, sentTexts(sentMsgs))
+}
+
+func TestSharedChannelsUnrestrictedWithoutSafeMode(t *testing.T) {
+	testServer := newSharedChannelsTestServer()
+	defer testServer.Stop()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	sentMsgs, _, _, _ := runSlackscotWithPluginsAndIncomingEvents(t, v, []*Plugin{newPingPlugin("fun")}, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cpartners", "ping", "Uexternal", timestamp1, optionTeam("Tpartner"))),
+	}, testServer)
+
+	assert.Equal(t, []string{"Cpartners: pong from fun"}, sentTexts(sentMsgs))
+}
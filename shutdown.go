@@ -0,0 +1,148 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/alexandre-normand/slackscot/config"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Shutdown gracefully stops slackscot. It stops accepting new events, waits for the messages already received to be
+// processed and for running scheduled actions to finish, cancels progress tasks (see AnswerWithProgress), stops the webhook server and closes all closers (i.e. storers
+// added with the builder's WithCloser). Responses and other cached state are written through to their storers as
+// they change so closing them is all it takes to flush them. The user info cache is saved to the response storer,
+// if any, to be reloaded on startup (see config.UserInfoCacheRetentionKey).
+//
+// Waiting is bounded by the context: once it's done, actions still running get their context canceled (see
+// IncomingMessage.Context) and shutdown completes without waiting for them, returning the context's error. Shutdown
+// is called on SIGTERM and SIGINT with a context bounded by config.ShutdownTimeoutKey. Shutting down more than once
+// waits for the first shutdown and returns its error
+func (s *Slackscot) Shutdown(ctx context.Context) (err error) {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+
+	return s.shutdownErr
+}
+
+// shutdown does the work of Shutdown
+func (s *Slackscot) shutdown(ctx context.Context) (err error) {
+	s.log.Printf("Shutting down, waiting for in-flight messages and scheduled actions to finish\n")
+	close(s.shutdownCh)
+
+	select {
+	case <-s.started:
+		err = waitUntilClosed(ctx, s.drained)
+	default:
+		// Never started processing so there's nothing to wait for
+	}
+
+	if s.scheduledActions != nil {
+		if serr := s.scheduledActions.stop(ctx); err == nil {
+			err = serr
+		}
+	}
+
+	if err != nil {
+		s.log.Printf("Warning: shutdown didn't complete in time (%v), canceling running actions\n", err)
+	}
+
+	s.cancel()
+
+	// Progress tasks stop once canceled but still update their answers with their final state
+	if werr := s.waitForProgressTasks(ctx); err == nil {
+		err = werr
+	}
+
+	// Running jobs are interrupted and run again once restarted
+	if s.jobQueue != nil {
+		if werr := s.jobQueue.wait(ctx); err == nil {
+			err = werr
+		}
+	}
+
+	if saved, serr := s.saveUserInfoCache(time.Now()); serr != nil {
+		s.log.Printf("Error saving the user info cache: %v\n", serr)
+	} else if saved > 0 {
+		s.log.Debugf("Saved the info of %d cached users\n", saved)
+	}
+
+	if s.webhookServer != nil {
+		if serr := s.webhookServer.Shutdown(ctx); err == nil {
+			err = serr
+		}
+	}
+
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+
+	close(s.stopped)
+	s.log.Printf("Shutdown complete\n")
+
+	return err
+}
+
+// isShuttingDown returns true if Shutdown was called
+func (s *Slackscot) isShuttingDown() bool {
+	select {
+	case <-s.shutdownCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// drainMessageProcessing stops the processing of messages once all those already received are processed
+func (s *Slackscot) drainMessageProcessing() {
+	if s.dispatcher != nil {
+		s.dispatcher.close()
+		return
+	}
+
+	// Close all processing queues and wait for the terminations
+	for _, wq := range s.messageQueues {
+		close(wq)
+	}
+
+	// Wait for all workers to terminate processing
+	for _, tc := range s.workerTerminationSignals {
+		<-tc
+	}
+}
+
+// watchForTerminationSignal waits for a SIGTERM or SIGINT and gracefully shuts down (see Shutdown), giving in-flight
+// work up to config.ShutdownTimeoutKey to finish. Note that this is meant to run in a go routine given that this is blocking
+func (s *Slackscot) watchForTerminationSignal() {
+	tSignals := make(chan os.Signal, 1)
+	// Register to be notified of termination signals so we can shut down
+	signal.Notify(tSignals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case sig := <-tSignals:
+		s.log.Debugf("Received termination signal [%s], shutting down\n", sig)
+	case <-s.shutdownCh:
+		// Shutdown was called directly
+		signal.Stop(tSignals)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.GetDuration(config.ShutdownTimeoutKey))
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != nil {
+		s.log.Printf("Error shutting down: %v\n", err)
+	}
+}
+
+// waitUntilClosed waits until the channel is closed or the context is done, in which case the context's error is returned
+func waitUntilClosed(ctx context.Context, ch chan struct{}) (err error) {
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
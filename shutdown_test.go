@@ -0,0 +1,135 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type closerRecorder struct {
+	closed int32
+}
+
+func (c *closerRecorder) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func newDrainedPlugin(answer func(m *IncomingMessage) *Answer) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "slow"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "slow")
+		},
+		Answer: answer,
+	}}
+
+	return p
+}
+
+// startSlackscotForShutdown starts processing events with a slackscot and returns the channel of events along with
+// the termination channel signaled once processing is done
+func startSlackscotForShutdown(t *testing.T, plugin *Plugin, driver *inMemoryChatDriver) (s *Slackscot, ec chan slack.RTMEvent, termination chan bool) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	termination = make(chan bool, 1)
+	s, err := New("chickadee", v, OptionTestMode(termination), OptionLog(log.New(&strings.Builder{}, "", 0)))
+	require.NoError(t, err)
+	s.RegisterPlugin(plugin)
+
+	var selfFinder selfFinder
+	var userInfoFinder userInfoFinder
+	var emojiReactor emojiReactor
+
+	ec = make(chan slack.RTMEvent)
+	go s.runInternal(ec, &runDependencies{chatDriver: driver, userInfoFinder: &userInfoFinder, emojiReactor: &emojiReactor, selfInfoFinder: &selfFinder, realTimeMsgSender: nil})
+
+	ec <- slack.RTMEvent{Type: "connected_event", Data: &slack.ConnectedEvent{}}
+
+	return s, ec, termination
+}
+
+func TestShutdownWaitsForInFlightMessages(t *testing.T) {
+	driver := inMemoryChatDriver{timeCursor: firstReplyTimestamp - replyTimeIncrementInSeconds}
+	s, ec, termination := startSlackscotForShutdown(t, newDrainedPlugin(func(m *IncomingMessage) *Answer {
+		time.Sleep(100 * time.Millisecond)
+		return &Answer{Text: "Finally done"}
+	}), &driver)
+
+	closer := new(closerRecorder)
+	s.closers = append(s.closers, closer)
+
+	ec <- newRTMMessageEvent(newMessageEvent("Cgeneral", "slow", "Alphonse", timestamp1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, s.Shutdown(ctx))
+	<-termination
+
+	if assert.Len(t, driver.sentMsgs, 1) {
+		assert.Equal(t, "Finally done", applySlackOptions(driver.sentMsgs[0].msgOptions...).Get("text"))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&closer.closed))
+
+	// Shutting down again is harmless
+	assert.NoError(t, s.Shutdown(ctx))
+}
+
+func TestShutdownBoundedByContext(t *testing.T) {
+	canceled := make(chan bool, 1)
+	driver := inMemoryChatDriver{timeCursor: firstReplyTimestamp - replyTimeIncrementInSeconds}
+	s, ec, termination := startSlackscotForShutdown(t, newDrainedPlugin(func(m *IncomingMessage) *Answer {
+		<-m.Context().Done()
+		canceled <- true
+		return nil
+	}), &driver)
+
+	ec <- newRTMMessageEvent(newMessageEvent("Cgeneral", "slow", "Alphonse", timestamp1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, s.Shutdown(ctx))
+
+	// The action still running gets its context canceled
+	assert.True(t, <-canceled)
+	<-termination
+}
+
+func TestShutdownWithoutProcessing(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&strings.Builder{}, "", 0)))
+	require.NoError(t, err)
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+}
+
+func TestScheduledActionRegistryStopWaitsForRunningActions(t *testing.T) {
+	r, err := newScheduledActionRegistry(nil, nil)
+	require.NoError(t, err)
+
+	finished := int32(0)
+	e := &scheduledActionEntry{id: "report.scheduledAction[0]", ScheduledActionDefinition: ScheduledActionDefinition{Action: func() {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+	}}}
+
+	logger := NewSLogger(log.New(&strings.Builder{}, "", 0), false)
+	go r.runner(e, logger)()
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, r.stop(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&finished))
+
+	// No more runs once stopped
+	assert.False(t, r.track())
+}
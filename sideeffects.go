@@ -0,0 +1,91 @@
+package slackscot
+
+import (
+	"fmt"
+	"time"
+)
+
+// executedActionsSilo is the silo of the markers of actions with side effects already run on messages, persisted with
+// the responses when a response storer is set (see OptionResponseStorer)
+const executedActionsSilo = "executedActions"
+
+// executedActionKey returns the key of the marker of an action run on a message (i.e. Cgeneral/1546833210.036900:reset.command[0])
+func executedActionKey(channelID string, timestamp string, actionID string) string {
+	return fmt.Sprintf("%s:%s", SlackMessageID{channelID: channelID, timestamp: timestamp}, actionID)
+}
+
+// actionIDOfResponseKey returns the identifier of the action that sent the response with the given key (see
+// OutgoingMessage.responseKey)
+func actionIDOfResponseKey(responseKey string) string {
+	for i, c := range responseKey {
+		if c == '#' || (c == '.' && i > 0 && responseKey[i-1] == ']') {
+			return responseKey[:i]
+		}
+	}
+
+	return responseKey
+}
+
+// hasRunAction returns true if the action with side effects already ran on the message with the given timestamp
+func (s *Slackscot) hasRunAction(channelID string, timestamp string, actionID string) bool {
+	key := executedActionKey(channelID, timestamp, actionID)
+	if s.executedActions.Contains(key) {
+		return true
+	}
+
+	if s.responseStorer == nil {
+		return false
+	}
+
+	value, err := s.responseStorer.GetSiloString(executedActionsSilo, key)
+	if err != nil {
+		return false
+	}
+
+	executedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil || s.isExpired(executedAt, time.Now()) {
+		return false
+	}
+
+	s.executedActions.Add(key, executedAt)
+	return true
+}
+
+// markActionRun records that the action with side effects ran on the message with the given timestamp so that edits of
+// the message don't run it again
+func (s *Slackscot) markActionRun(channelID string, timestamp string, actionID string) {
+	key := executedActionKey(channelID, timestamp, actionID)
+	now := time.Now()
+	s.executedActions.Add(key, now)
+
+	if s.responseStorer == nil {
+		return
+	}
+
+	if err := s.responseStorer.PutSiloString(executedActionsSilo, key, now.Format(time.RFC3339)); err != nil {
+		s.log.Printf("Error persisting the run of [%s]: %v", key, err)
+	}
+}
+
+// pruneExecutedActions deletes the persisted markers of actions run that are expired (or can't be decoded) and returns
+// how many were
+func (s *Slackscot) pruneExecutedActions(now time.Time) (pruned int, err error) {
+	entries, err := s.responseStorer.ScanSilo(executedActionsSilo)
+	if err != nil {
+		return 0, err
+	}
+
+	for key, value := range entries {
+		if executedAt, err := time.Parse(time.RFC3339, value); err == nil && !s.isExpired(executedAt, now) {
+			continue
+		}
+
+		if err := s.responseStorer.DeleteSiloString(executedActionsSilo, key); err != nil {
+			return pruned, err
+		}
+
+		pruned = pruned + 1
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,93 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newResetterPlugin(resets *int32, sideEffects bool) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "resetter"
+	p.NamespaceCommands = false
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "reset")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			count := atomic.AddInt32(resets, 1)
+			return &Answer{Text: fmt.Sprintf("Reset done (%d)", count)}
+		},
+		SideEffects: sideEffects,
+	}}
+
+	return p
+}
+
+func TestActionWithSideEffectsNotRunAgainOnEdits(t *testing.T) {
+	var resets int32
+	sentMsgs, updatedMsgs, deletedMsgs, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newResetterPlugin(&resets, true), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "reset", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp2)),
+		// Fixing a typo doesn't reset again and the answer is kept
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage(fmt.Sprintf("<@%s> reset!", botUserID), "Alphonse", timestamp2))),
+		// But an edit making a message a command that didn't run on it yet still runs it
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage(fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp1))),
+	})
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&resets))
+	if assert.Len(t, sentMsgs, 2) {
+		assert.Equal(t, "<@Alphonse>: Reset done (1)", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+		assert.Equal(t, "<@Alphonse>: Reset done (2)", applySlackOptions(sentMsgs[1].msgOptions...).Get("text"))
+	}
+	assert.Empty(t, updatedMsgs)
+	assert.Empty(t, deletedMsgs)
+}
+
+func TestActionWithoutSideEffectsRunAgainOnEdits(t *testing.T) {
+	var resets int32
+	_, updatedMsgs, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newResetterPlugin(&resets, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage(fmt.Sprintf("<@%s> reset!", botUserID), "Alphonse", timestamp1))),
+	})
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&resets))
+	assert.Len(t, updatedMsgs, 1)
+}
+
+func TestActionWithSideEffectsNotRunAgainAfterRestart(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	var resets int32
+	runSlackscotWithIncomingEvents(t, nil, newResetterPlugin(&resets, true), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("<@%s> reset", botUserID), "Alphonse", timestamp1)),
+	}, nil, OptionResponseStorer(storer))
+
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newResetterPlugin(&resets, true), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage(fmt.Sprintf("<@%s> reset!", botUserID), "Alphonse", timestamp1))),
+	}, nil, OptionResponseStorer(storer))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&resets))
+	assert.Empty(t, sentMsgs)
+	assert.Empty(t, updatedMsgs)
+	assert.Empty(t, deletedMsgs)
+}
+
+func TestActionIDOfResponseKey(t *testing.T) {
+	tests := map[string]string{
+		"maker.command[0]":           "maker.command[0]",
+		"maker.command[0]#1":         "maker.command[0]",
+		"maker.command[0].part[1]":   "maker.command[0]",
+		"maker.command[0]#1.part[2]": "maker.command[0]",
+	}
+
+	for key, expected := range tests {
+		t.Run(key, func(t *testing.T) {
+			assert.Equal(t, expected, actionIDOfResponseKey(key))
+		})
+	}
+}
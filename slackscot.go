@@ -2,9 +2,13 @@ package slackscot
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"github.com/alexandre-normand/slackscot/assets"
 	"github.com/alexandre-normand/slackscot/config"
 	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/theme"
 	"github.com/hashicorp/golang-lru"
 	"github.com/marcsantiago/gocron"
 	"github.com/slack-go/slack"
@@ -14,11 +18,11 @@ import (
 	"go.opentelemetry.io/otel/api/metric"
 	"io"
 	"log"
+	"net/http"
 	"os"
-	"os/signal"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 )
 
@@ -39,7 +43,27 @@ type Slackscot struct {
 	config                  *viper.Viper
 	defaultAction           Answerer
 	plugins                 []*Plugin
-	triggeringMsgToResponse *lru.ARCCache
+	triggeringMsgToResponse *resizableCache
+
+	// Sizes of the caches, adaptive if enabled (see config.CacheSizingAdaptiveKey)
+	cacheSizer *cacheSizer
+
+	// Calls to slack's Web API counted against their rate limit budget
+	apiBudget *apiBudget
+
+	// Markers of the actions with side effects already run on messages (see ActionDefinition.SideEffects)
+	executedActions *lru.ARCCache
+
+	// Message events recently seen, to ignore the ones replayed by slack (i.e. after a reconnection)
+	seenEvents *lru.ARCCache
+
+	// Progress tasks of answers (see AnswerWithProgress) running, by message of their answer
+	progressTasks        map[SlackMessageID]*progressTask
+	progressTasksMutex   sync.Mutex
+	runningProgressTasks sync.WaitGroup
+
+	// Sequencer of the operations on the responses to each triggering message
+	messageSequencer *messageSequencer
 
 	// Runtime configuration options
 	namespaceCommands bool
@@ -59,6 +83,77 @@ type Slackscot struct {
 	// Logger
 	log *sLogger
 
+	// Services attached to incoming messages to resolve their context
+	userInfoFinder    UserInfoFinder
+	channelInfoFinder ChannelInfoFinder
+
+	// Custom emoji of the workspace, listed on behalf of plugins (see EmojiFinder)
+	emojiLister emojiLister
+	emojiFinder EmojiFinder
+
+	// Recent failures to load user info, shared by all plugins
+	userInfoFailures *userInfoFailures
+
+	// Policy deciding which answers get sent when a message triggers more than one
+	answerPolicy answerPolicy
+
+	// Hooks run on all answers before they're sent
+	answerHooks []AnswerHook
+
+	// Middleware wrapping all command and hear actions, see UseMiddleware
+	middleware []Middleware
+
+	// Services provided by plugins and the error from their resolution, if any
+	services    *serviceRegistry
+	servicesErr error
+
+	// Bus used by plugins to publish and subscribe to events
+	eventBus *eventBus
+
+	// Feature flags gating core behaviors and the storer of their runtime overrides, if any
+	featureFlags      *featureFlags
+	theme             *theme.Theme
+	assets            *assets.Registry
+	featureFlagStorer store.GlobalSiloStringStorer
+
+	// Channels plugins run in, with runtime overrides persisted in the feature flag storer, if any
+	pluginChannels *pluginChannels
+
+	// Users who opted out of the collection of data about them and the storer persisting them, if any
+	userConsent   *userConsent
+	consentStorer store.GlobalSiloStringStorer
+
+	// Scheduled actions of all plugins and the storer of their pause states, if any
+	scheduledActions      *scheduledActionRegistry
+	scheduledActionStorer store.GlobalSiloStringStorer
+
+	// Storer persisting the responses to triggering messages across restarts, if any
+	responseStorer store.GlobalSiloStringStorer
+
+	// Storer persisting the jobs of plugins and the queue running them, only created if plugins have jobs
+	jobStorer store.GlobalSiloStringStorer
+	jobQueue  *jobQueue
+
+	// Storers inspectable by admins with the store command, by name
+	inspectableStorers map[string]store.GlobalSiloStringStorer
+
+	// Storers of plugins with a retention on their entries, by plugin name, and their sweeper
+	retentionStorers map[string]*store.RetentionStorer
+	retentionSweeper *retentionSweeper
+
+	// Sender of the direct messages notifying admins
+	adminNotifier messageSender
+
+	// Server receiving the plugins' webhooks, only started when config.WebhookListenAddressKey is set
+	webhookServer *http.Server
+
+	// Time at which slackscot started running (see BotAdmin.Uptime)
+	startedAt time.Time
+
+	// Environment variable prefix and flag set used to report the source of configuration values on startup and to admins
+	configEnvPrefix string
+	configFlags     *flag.FlagSet
+
 	// Resources to close on shutdown
 	closers []io.Closer
 
@@ -68,10 +163,26 @@ type Slackscot struct {
 	// Termination channel
 	terminationCh chan bool
 
+	// Context of the event processing, canceled on shutdown to let running actions know they should stop
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Graceful shutdown (see Shutdown): shutdownCh is closed to stop accepting events, started and drained are closed
+	// when the processing of events starts and once it's done and stopped is closed when the shutdown is complete
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+	shutdownErr  error
+	started      chan struct{}
+	drained      chan struct{}
+	stopped      chan struct{}
+
 	meter metric.Meter
 
 	*partitionRouter
 
+	// dispatcher processing messages with a queue per channel, used instead of the partitions when config.MaxConcurrentHandlersKey is set
+	dispatcher *channelDispatcher
+
 	*instrumenter
 }
 
@@ -105,17 +216,76 @@ type Plugin struct {
 
 	NamespaceCommands bool // Set to true for slackscot-managed namespacing of commands where the namespace/cmdPrefix to all commands is set to the plugin name
 
+	Priority int // Priority of the plugin's answers over those of other plugins when the answer policy is priority (higher goes first). See config.AnswerPolicyKey
+
+	Identity *Identity // Optional username and icon the plugin's answers are posted with instead of the bot's. See Identity
+
+	ReplyBehavior *ReplyBehavior // Optional override of the threaded replies configuration for the plugin's answers. See ReplyBehavior
+
+	NormalizeCommands bool // Set to true to have slackscot normalize the command text (case folding, whitespace collapsing and trailing punctuation removal) before it's handed to Match functions. See NormalizeCommandText
+
+	ListenToBots bool // Set to true for the plugin's commands and hear actions to also be triggered by messages from other bots. Messages from slackscot itself are always ignored
+
+	CollectsUserData bool // Set to true for plugins passively collecting data about users (i.e. karma or archives). Their hear and reaction actions ignore users who opted out. See UserConsent
+
+	PurgeUserData UserDataPurger // Optional function deleting the data the plugin collected about a user when they opt out. See UserConsent
+
+	Middleware []Middleware // Optional middleware wrapping all of the plugin's commands and hear actions, inside slackscot's middleware. See Middleware
+
 	Commands         []ActionDefinition
 	HearActions      []ActionDefinition
 	ScheduledActions []ScheduledActionDefinition
 
+	// Provides holds the services this plugin makes available to other plugins, by name
+	Provides map[string]interface{}
+
+	// Requires holds the names of the services provided by other plugins that this plugin needs. Slackscot
+	// fails to start if any of those isn't provided or if there is a dependency cycle between plugins
+	Requires []string
+
+	// EventSubscriptions holds the topics (and their handlers) of the events published by other plugins that
+	// this plugin wants to receive. Slackscot subscribes those to the EventBus when starting
+	EventSubscriptions []EventSubscription
+
+	// Webhooks holds the inbound http endpoints of the plugin. See WebhookDefinition
+	Webhooks []WebhookDefinition
+
+	// ReactionActions holds the actions triggered by emoji reactions added to or removed from messages. See ReactionActionDefinition
+	ReactionActions []ReactionActionDefinition
+
+	// ChannelActions holds the actions triggered by channel lifecycle events (i.e. channels created or members joining
+	// them). See ChannelActionDefinition
+	ChannelActions []ChannelActionDefinition
+
+	// SlashCommands holds the slash commands (i.e. /weather) handled by the plugin. See SlashCommandDefinition
+	SlashCommands []SlashCommandDefinition
+
+	// WorkflowSteps holds the custom steps of slack's Workflow Builder handled by the plugin. See WorkflowStepDefinition
+	WorkflowSteps []WorkflowStepDefinition
+
+	// Jobs holds the types of deferred work the plugin can enqueue on its JobQueue. See JobDefinition
+	Jobs []JobDefinition
+
 	// Those slackscot services are injected post-creation when slackscot is called.
 	// A plugin shouldn't rely on those being available during creation
-	UserInfoFinder    UserInfoFinder
-	Logger            SLogger
-	EmojiReactor      EmojiReactor
-	FileUploader      FileUploader
-	RealTimeMsgSender RealTimeMessageSender
+	UserInfoFinder      UserInfoFinder
+	UsersInfoFinder     UsersInfoFinder
+	UserInfoInvalidator UserInfoInvalidator
+	ChannelInfoFinder   ChannelInfoFinder
+	Logger              SLogger
+	EmojiReactor        EmojiReactor
+	EmojiFinder         EmojiFinder
+	FileUploader        FileUploader
+	RealTimeMsgSender   RealTimeMessageSender
+	Services            ServiceRegistry
+	EventBus            EventBus
+	FeatureFlags        FeatureFlags
+	Quarantiner         Quarantiner
+	JobQueue            JobQueue
+	BotAdmin            BotAdmin
+	UserConsent         UserConsent
+	Theme               *theme.Theme
+	Assets              *assets.Registry
 
 	// The slack.Client is injected post-creation. It gives access to all the https://godoc.org/github.com/slack-go/slack#Client.
 	// Plugin writers might want to check out https://godoc.org/github.com/slack-go/slack/slacktest to create a slack test server in order
@@ -140,6 +310,31 @@ type ActionDefinition struct {
 
 	// Function to execute if the Matcher matches
 	Answer Answerer
+
+	// Optional function to execute instead of Answer if the Matcher matches, for actions answering with more than
+	// one message. Each answer is sent as a separate message and tracked on its own for updates and deletions
+	AnswerAll MultiAnswerer
+
+	// Optional function to execute when a user interacts with the InteractiveElements of the action's answers
+	InteractionHandler InteractionHandler
+
+	// Optional override of the threaded replies configuration for the action's answers, taking precedence over the
+	// plugin's. See ReplyBehavior
+	ReplyBehavior *ReplyBehavior
+
+	// Indicates whether the action has side effects (i.e. a command resetting something) and must therefore only run
+	// once per message. Edits of the message (i.e. fixing a typo) don't run it again and its answers are kept as they
+	// are. Actions without side effects (pure) run again on edits to update their answers. The help flags actions with
+	// side effects with a warning
+	SideEffects bool
+
+	// Indicates whether only admins (see config.AdminUsersKey) can run the action (i.e. a command resetting or
+	// disabling something). Others get a polite refusal without the action being answered (or its middleware run).
+	// The help flags admin-only actions
+	AdminOnly bool
+
+	// Optional middleware wrapping the action, inside the middleware of slackscot and of the plugin. See Middleware
+	Middleware []Middleware
 }
 
 // Matcher is the function that determines whether or not an action should be triggered based on a IncomingMessage (which
@@ -151,6 +346,30 @@ type Matcher func(m *IncomingMessage) bool
 // should return nil
 type Answerer func(m *IncomingMessage) *Answer
 
+// MultiAnswerer is what gets executed when an ActionDefinition with AnswerAll is triggered. To signal the absence of
+// answers, an action should return no answers. Nil answers are ignored
+type MultiAnswerer func(m *IncomingMessage) []*Answer
+
+// Answers returns the answers of the action to a message: the answers of AnswerAll, if set, or the answer of Answer
+// otherwise. Nil answers are left out
+func (a ActionDefinition) Answers(m *IncomingMessage) (answers []*Answer) {
+	if a.AnswerAll == nil {
+		if answer := a.Answer(m); answer != nil {
+			answers = append(answers, answer)
+		}
+
+		return answers
+	}
+
+	for _, answer := range a.AnswerAll(m) {
+		if answer != nil {
+			answers = append(answers, answer)
+		}
+	}
+
+	return answers
+}
+
 // ActionDefinitionWithID holds an action definition along with its identifier string
 type ActionDefinitionWithID struct {
 	ActionDefinition
@@ -171,6 +390,13 @@ type ScheduledActionDefinition struct {
 
 	// ScheduledAction is the function that is invoked when the schedule activates
 	Action ScheduledAction
+
+	// Optional maximum random delay added to every run so that replicas of a bot don't all run the action at the same
+	// time
+	Jitter time.Duration
+
+	// What to do about runs missed while slackscot wasn't running. Defaults to SkipMissedRuns
+	MissedRuns MissedRunPolicy
 }
 
 // ScheduledAction is what gets executed when a ScheduledActionDefinition is triggered (by its ScheduleDefinition)
@@ -184,6 +410,10 @@ type ScheduledAction func()
 type SlackMessageID struct {
 	channelID string
 	timestamp string
+
+	// scheduled is true for messages scheduled for later delivery. Those don't have a timestamp until they're posted
+	// and therefore can't be updated or deleted
+	scheduled bool
 }
 
 // IsMsgModifiable returns true if this slack message id can be used to update/delete the message.
@@ -205,11 +435,19 @@ type responseStrategy func(m IncomingMessage, answer *Answer) slack.OutgoingMess
 // a normalized text that is the original text stripped from the "<@Mention>" cmdPrefix when a message
 // is addressed to a slackscot instance. Since commands are usually received either via direct message
 // (without @Mention) or on channels with @Mention, the normalized text is useful there to allow plugins
-// to have a single version to do Match and Answer against
+// to have a single version to do Match and Answer against. It also offers helper methods to resolve the
+// message's context (user and channel info, thread, team) without plugins having to re-derive it themselves
 type IncomingMessage struct {
 	// The original slack.Msg text stripped from the "<@Mention>" cmdPrefix, if applicable
 	NormalizedText string
 	slack.Msg
+
+	// Services used to resolve user and channel info on demand
+	userInfoFinder    UserInfoFinder
+	channelInfoFinder ChannelInfoFinder
+
+	// Context of the action handling the message, see Context
+	ctx context.Context
 }
 
 // OutgoingMessage holds a plugin generated slack outgoing message along with the plugin identifier
@@ -221,6 +459,50 @@ type OutgoingMessage struct {
 
 	// The identifier of the source of the outgoing message. The format being: <pluginName>.command[<commandIndex>] (for a command) or <pluginName>.hearAction[actionIndex] (for an hear action)
 	pluginActionID string
+
+	// The index of the answer among the answers of the same action (see ActionDefinition.AnswerAll)
+	answerIndex int
+
+	// The index of the part of an answer too long to be sent as a single message (see splitOutgoingMessages)
+	part int
+
+	// The reply behavior of the action or plugin that answered, if any (see ReplyBehavior)
+	replyBehavior *ReplyBehavior
+
+	// Whether the answer hooks already ran on the outgoing message (see applyAnswerHooksToMsg)
+	hooked bool
+}
+
+// replyPrefix returns what the reply strategy put before the answer's text in the outgoing message (i.e. the mention of
+// the user replied to), if anything
+func (o OutgoingMessage) replyPrefix() (prefix string) {
+	if strings.HasSuffix(o.OutgoingMessage.Text, o.Answer.Text) {
+		return strings.TrimSuffix(o.OutgoingMessage.Text, o.Answer.Text)
+	}
+
+	return ""
+}
+
+// responseKey returns the key tracking the response sent for the outgoing message. It's the plugin action identifier
+// for the first answer of an action and the identifier suffixed with the answer index (i.e. maker.command[0]#1)
+// for the others. Parts of split answers, after the first one, are further suffixed with their index (i.e.
+// maker.command[0].part[1])
+func (o OutgoingMessage) responseKey() (key string) {
+	key = o.firstPartKey()
+	if o.part > 0 {
+		key = fmt.Sprintf("%s.part[%d]", key, o.part)
+	}
+
+	return key
+}
+
+// firstPartKey returns the response key of the first part of the outgoing message's answer
+func (o OutgoingMessage) firstPartKey() (key string) {
+	if o.answerIndex == 0 {
+		return o.pluginActionID
+	}
+
+	return fmt.Sprintf("%s#%d", o.pluginActionID, o.answerIndex)
 }
 
 // runDependencies represents all runtime dependencies. Note that they're mostly satisfied by slack.RTM or slack.Client
@@ -228,7 +510,9 @@ type OutgoingMessage struct {
 type runDependencies struct {
 	chatDriver        chatDriver
 	userInfoFinder    UserInfoFinder
+	channelInfoFinder ChannelInfoFinder
 	emojiReactor      EmojiReactor
+	emojiLister       emojiLister
 	fileUploader      FileUploader
 	selfInfoFinder    selfInfoFinder
 	realTimeMsgSender RealTimeMessageSender
@@ -260,6 +544,9 @@ type selfIdentity struct {
 	botID      string
 	name       string
 	userPrefix string
+
+	// Team of the bot, telling users of other organizations apart in shared channels (see config.SharedChannelsIgnoreExternalKey)
+	teamID string
 }
 
 func (s *selfIdentity) IsCmd(msg slack.Msg) bool {
@@ -356,7 +643,18 @@ func OptionTestMode(terminationCh chan bool) Option {
 	}
 }
 
-//OptionCommandPrefix sets a cmdPrefix to all commands that is used instead of at-mentioning the bot
+// OptionConfigSources sets the environment variable prefix and the flag set the configuration was loaded from. These
+// are only used to report the source of each configuration value when logging the effective configuration on startup
+// (in debug mode). Defaults to config.DefaultEnvPrefix and flag.CommandLine
+func OptionConfigSources(envPrefix string, fs *flag.FlagSet) Option {
+	return func(s *Slackscot) {
+		s.configEnvPrefix = envPrefix
+		s.configFlags = fs
+	}
+}
+
+//OptionCommandPrefix sets a cmdPrefix to all commands that is used instead of at-mentioning the bot. This takes
+// precedence over config.CommandPrefixKey which recognizes its prefix in addition to at-mentions
 func OptionCommandPrefix(cmdPrefix string) Option {
 	return func(s *Slackscot) {
 		pc := new(prefixedCommand)
@@ -385,6 +683,41 @@ func (pc *prefixedCommand) String() string {
 	return fmt.Sprintf("Prefixed-Command{%v}", pc.prefix)
 }
 
+// mentionOrPrefixedCommand identifies commands either by a mention of the bot or by a command prefix.
+// This is what is used when the command prefix is set via config.CommandPrefixKey
+type mentionOrPrefixedCommand struct {
+	mention  *selfIdentity
+	prefixed prefixedCommand
+}
+
+func newMentionOrPrefixedCommand(mention *selfIdentity, cmdPrefix string) (mpc *mentionOrPrefixedCommand) {
+	mpc = new(mentionOrPrefixedCommand)
+	mpc.mention = mention
+	mpc.prefixed.prefix = cmdPrefix
+
+	return mpc
+}
+
+func (mpc *mentionOrPrefixedCommand) IsCmd(msg slack.Msg) bool {
+	return mpc.mention.IsCmd(msg) || mpc.prefixed.IsCmd(msg)
+}
+
+func (mpc *mentionOrPrefixedCommand) UsagePrefix() string {
+	return mpc.prefixed.UsagePrefix()
+}
+
+func (mpc *mentionOrPrefixedCommand) TrimPrefix(text string) string {
+	if strings.HasPrefix(text, mpc.mention.userPrefix) {
+		return mpc.mention.TrimPrefix(text)
+	}
+
+	return mpc.prefixed.TrimPrefix(text)
+}
+
+func (mpc *mentionOrPrefixedCommand) String() string {
+	return fmt.Sprintf("Mention-Or-Prefixed-Command{%v %v}", mpc.mention.userPrefix, mpc.prefixed.prefix)
+}
+
 // NewSlackscot creates a new slackscot from an array of plugins and a name
 //
 // Deprecated: Use New instead. Will be removed in 2.0.0
@@ -396,18 +729,38 @@ func NewSlackscot(name string, v *viper.Viper, options ...Option) (s *Slackscot,
 func New(name string, v *viper.Viper, options ...Option) (s *Slackscot, err error) {
 	s = new(Slackscot)
 
-	s.triggeringMsgToResponse, err = lru.NewARC(v.GetInt(config.ResponseCacheSizeKey))
+	s.triggeringMsgToResponse, err = newResizableCache(v.GetInt(config.ResponseCacheSizeKey))
 	if err != nil {
 		return nil, err
 	}
 
+	s.executedActions, err = lru.NewARC(v.GetInt(config.ResponseCacheSizeKey))
+	if err != nil {
+		return nil, err
+	}
+
+	s.seenEvents, err = lru.NewARC(v.GetInt(config.ResponseCacheSizeKey))
+	if err != nil {
+		return nil, err
+	}
+
+	s.progressTasks = make(map[SlackMessageID]*progressTask)
+	s.messageSequencer = newMessageSequencer()
+
 	v = config.LayerConfigWithDefaults(v)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.shutdownCh = make(chan struct{})
+	s.started = make(chan struct{})
+	s.drained = make(chan struct{})
+	s.stopped = make(chan struct{})
 	s.name = name
 	s.config = v
 	s.namespaceCommands = true
 	s.testMode = false
 	s.closers = make([]io.Closer, 0)
 	s.defaultAction = defaultAction
+	s.configEnvPrefix = config.DefaultEnvPrefix
+	s.configFlags = flag.CommandLine
 	s.log = NewSLogger(log.New(os.Stdout, defaultLogPrefix, defaultLogFlag), v.GetBool(config.DebugKey))
 
 	partitionCount := s.config.GetInt(config.MessageProcessingPartitionCount)
@@ -415,12 +768,40 @@ func New(name string, v *viper.Viper, options ...Option) (s *Slackscot, err erro
 		return nil, fmt.Errorf("%s config should be a power of two but was [%d]", config.MessageProcessingPartitionCount, partitionCount)
 	}
 
+	if queueSize := s.config.GetInt(config.RateLimitQueueSizeKey); queueSize <= 0 {
+		return nil, fmt.Errorf("%s config should be greater than 0 but was [%d]", config.RateLimitQueueSizeKey, queueSize)
+	}
+
+	if maxConcurrentHandlers := s.config.GetInt(config.MaxConcurrentHandlersKey); maxConcurrentHandlers < 0 {
+		return nil, fmt.Errorf("%s config should be positive but was [%d]", config.MaxConcurrentHandlersKey, maxConcurrentHandlers)
+	}
+
+	if updateAgeLimit := s.config.GetInt(config.MessageUpdateAgeLimitKey); updateAgeLimit < 0 {
+		return nil, fmt.Errorf("%s config should be positive but was [%d]", config.MessageUpdateAgeLimitKey, updateAgeLimit)
+	}
+
+	if maxCacheSize := s.config.GetInt(config.CacheSizingMaxSizeKey); s.config.GetBool(config.CacheSizingAdaptiveKey) && maxCacheSize <= 0 {
+		return nil, fmt.Errorf("%s config should be greater than 0 but was [%d]", config.CacheSizingMaxSizeKey, maxCacheSize)
+	}
+
+	s.answerPolicy, err = newAnswerPolicy(s.config.GetString(config.AnswerPolicyKey), s.config.GetInt(config.MaxAnswersPerMessageKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validateThreadParentDeletion(s.config.GetString(config.ThreadParentDeletionKey)); err != nil {
+		return nil, err
+	}
+
 	s.slackOpts = make([]slack.Option, 0)
 	s.slackOpts = append(s.slackOpts, slack.OptionDebug(s.config.GetBool(config.DebugKey)))
 	s.slackOpts = append(s.slackOpts, slack.OptionLog(log.New(s.log.logger.Writer(), "slack: ", defaultLogFlag)))
 
 	s.botMatcher = &s.selfIdentity
 	s.cmdMatcher = &s.selfIdentity
+	if cmdPrefix := s.config.GetString(config.CommandPrefixKey); cmdPrefix != "" {
+		s.cmdMatcher = newMentionOrPrefixedCommand(&s.selfIdentity, cmdPrefix)
+	}
 
 	s.meter = opentelemetry.MeterProvider().Meter("github.com/alexandre-normand/slackscot")
 
@@ -429,6 +810,44 @@ func New(name string, v *viper.Viper, options ...Option) (s *Slackscot, err erro
 	}
 
 	s.instrumenter = newInstrumenter(name, s.meter)
+	s.instrumentStorers()
+
+	s.cacheSizer = newCacheSizer(s.config, name, s.meter, s.log)
+	s.cacheSizer.register(responsesCacheName, s.triggeringMsgToResponse)
+
+	s.apiBudget = newAPIBudget(s.config, name, s.meter)
+
+	s.retentionSweeper = newRetentionSweeper(s.retentionStorers, name, s.meter, s.log)
+
+	s.userConsent, err = newUserConsent(s.consentStorer)
+	if err != nil {
+		return nil, err
+	}
+
+	s.featureFlags, err = newFeatureFlags(s.config, s.featureFlagStorer)
+	if err != nil {
+		return nil, err
+	}
+
+	s.pluginChannels, err = newPluginChannels(s.config, s.featureFlagStorer)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = newUserInfoFallbackMode(s.config.GetString(config.UserInfoFallbackKey), config.UserInfoFallbackKey); err != nil {
+		return nil, err
+	}
+
+	t, err := theme.Get(s.config.GetString(config.ThemeKey))
+	if err != nil {
+		return nil, err
+	}
+	s.theme = &t
+
+	s.assets = assets.NewRegistry(s.config.GetString(config.AssetsBaseURLKey))
+	if err = s.assets.AddFromConfig(s.config.GetStringMapString(config.AssetsKey)); err != nil {
+		return nil, err
+	}
 
 	s.partitionRouter, err = newPartitionRouter(partitionCount, s.config.GetInt(config.MessageProcessingBufferedMessageCount), s.log, s.instrumenter)
 	if err != nil {
@@ -438,6 +857,16 @@ func New(name string, v *viper.Viper, options ...Option) (s *Slackscot, err erro
 	return s, nil
 }
 
+// logEffectiveConfig logs, in debug mode, the fully-resolved configuration with secrets redacted and the source of each value
+func (s *Slackscot) logEffectiveConfig() {
+	var b strings.Builder
+	for _, setting := range config.EffectiveSettings(s.config, s.configEnvPrefix, s.configFlags) {
+		fmt.Fprintf(&b, "\n  %s: [%v] (%s)", setting.Key, setting.Value, setting.Source)
+	}
+
+	s.log.Debugf("Starting [%s] with slackscot [%s] and effective configuration:%s\n", s.name, VERSION, b.String())
+}
+
 // defaultAnswer for a message directed to slackscot that isn't matching any known plugin command
 func defaultAction(m *IncomingMessage) *Answer {
 	return &Answer{Text: fmt.Sprintf("I don't understand. Ask me for \"%s\" to get a list of things I do", helpPluginName)}
@@ -462,13 +891,46 @@ func (s *Slackscot) Close() (err error) {
 // prior to calling Run
 func (s *Slackscot) RegisterPlugin(p *Plugin) {
 	s.plugins = append(s.plugins, p)
+
+	// Invalidate any previously resolved services if the new plugin might change them
+	if len(p.Provides) > 0 || len(p.Requires) > 0 {
+		s.services = nil
+		s.servicesErr = nil
+	}
+}
+
+// resolveServices resolves the services provided and required by all registered plugins. An error is
+// returned if any requirement isn't satisfied or if there is a dependency cycle between plugins. Resolution
+// only happens once (on Build or Run, whichever comes first) and registering a plugin afterwards invalidates it
+func (s *Slackscot) resolveServices() (err error) {
+	if s.services == nil && s.servicesErr == nil {
+		s.services, s.servicesErr = newServiceRegistry(s.plugins)
+	}
+
+	return s.servicesErr
 }
 
 // Run starts the Slackscot and loops until the process is interrupted
 func (s *Slackscot) Run() (err error) {
+	s.logEffectiveConfig()
+
+	// Resolve plugin services first to fail fast on unsatisfied dependencies (this is a no-op if Build already did it)
+	if err = s.resolveServices(); err != nil {
+		return err
+	}
+
+	// Create the server of the plugins' webhooks, if enabled. It only starts serving once services are injected into plugins
+	if address := s.config.GetString(config.WebhookListenAddressKey); address != "" {
+		if err = s.newWebhookServer(address, s.config.GetString(config.WebhookSharedSecretKey), s.config.GetString(config.SigningSecretKey)); err != nil {
+			return err
+		}
+	}
+
+	// Calls are counted against their budget unless an http client is given with OptionWithSlackOption
+	slackOpts := append([]slack.Option{slack.OptionHTTPClient(budgetedHTTPClient{client: &http.Client{}, budget: s.apiBudget})}, s.slackOpts...)
 	sc := slack.New(
 		s.config.GetString(config.TokenKey),
-		s.slackOpts...,
+		slackOpts...,
 	)
 
 	// This will initiate the connection to the slack RTM and start the reception of messages
@@ -484,21 +946,28 @@ func (s *Slackscot) Run() (err error) {
 	}
 
 	// Start scheduling of all plugins' scheduled actions
+	if err = s.loadScheduledActions(); err != nil {
+		return err
+	}
 	go s.startActionScheduler(timeLoc)
 
 	// runInternal is blocking call so it's running in a goroutine. The way slackscot would usually terminate
 	// in a production scenario is by its process getting killed which would result in a last message sent on the termination channel
 	if s.terminationCh != nil {
 		// Start the main processing and send the termination to the externally defined termination channel (so a test can block and wait for processing after sending all of its test messages)
-		go s.runInternal(rtm.IncomingEvents, &runDependencies{chatDriver: NewchatDriverWithTelemetry(sc, s.name, s.instrumenter.meter), userInfoFinder: NewUserInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), emojiReactor: NewEmojiReactorWithTelemetry(sc, s.name, s.instrumenter.meter), fileUploader: NewFileUploaderWithTelemetry(NewFileUploader(sc), s.name, s.instrumenter.meter), selfInfoFinder: rtm, realTimeMsgSender: rtm, slackClient: sc})
+		go s.runInternal(rtm.IncomingEvents, &runDependencies{chatDriver: s.newChatDriver(sc), userInfoFinder: batchUserInfoFinder{UserInfoFinder: NewUserInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), lister: sc}, channelInfoFinder: NewChannelInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), emojiReactor: NewEmojiReactorWithTelemetry(sc, s.name, s.instrumenter.meter), emojiLister: sc, fileUploader: NewFileUploaderWithTelemetry(NewFileUploader(sc), s.name, s.instrumenter.meter), selfInfoFinder: rtm, realTimeMsgSender: rtm, slackClient: sc})
 	} else {
 		// This is production and the lifecycle is managed here so we create the termination channel and wait for the termination signal
 		s.terminationCh = make(chan bool)
 
-		go s.runInternal(rtm.IncomingEvents, &runDependencies{chatDriver: NewchatDriverWithTelemetry(sc, s.name, s.instrumenter.meter), userInfoFinder: NewUserInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), emojiReactor: NewEmojiReactorWithTelemetry(sc, s.name, s.instrumenter.meter), fileUploader: NewFileUploaderWithTelemetry(NewFileUploader(sc), s.name, s.instrumenter.meter), selfInfoFinder: rtm, realTimeMsgSender: rtm, slackClient: sc})
+		go s.runInternal(rtm.IncomingEvents, &runDependencies{chatDriver: s.newChatDriver(sc), userInfoFinder: batchUserInfoFinder{UserInfoFinder: NewUserInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), lister: sc}, channelInfoFinder: NewChannelInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), emojiReactor: NewEmojiReactorWithTelemetry(sc, s.name, s.instrumenter.meter), emojiLister: sc, fileUploader: NewFileUploaderWithTelemetry(NewFileUploader(sc), s.name, s.instrumenter.meter), selfInfoFinder: rtm, realTimeMsgSender: rtm, slackClient: sc})
 
-		// Wait for termination
+		// Wait for termination and, if shutting down, for the shutdown to complete
 		<-s.terminationCh
+		if s.isShuttingDown() {
+			<-s.stopped
+		}
+		s.stopWebhookServer()
 	}
 
 	return nil
@@ -514,28 +983,134 @@ func (s *Slackscot) runInternal(events <-chan slack.RTMEvent, deps *runDependenc
 		s.terminationCh <- true
 	}()
 
-	// Register to receive a notification for a termination signal which will, in turn, send a termination message to the
-	// termination channel
-	go s.watchForTerminationSignalToAbort()
+	// Cancel the context of running actions on shutdown
+	defer s.cancel()
+
+	s.startedAt = time.Now()
+
+	// Let a shutdown know that there's processing to wait for until it's drained
+	close(s.started)
+	defer close(s.drained)
+
+	// Register to receive a notification for a termination signal which will, in turn, gracefully shut down
+	go s.watchForTerminationSignal()
+
+	// Prune the expired responses persisted to handle edits and deletions across restarts
+	if s.responseStorer != nil {
+		go s.pruneResponsesPeriodically(s.ctx)
+	}
+
+	// Sweep the entries of plugins past their retention
+	if len(s.retentionStorers) > 0 {
+		go s.retentionSweeper.run(s.ctx, s.config.GetDuration(config.RetentionSweepIntervalKey))
+	}
+
+	// Add the feature command if feature flags can be overridden at runtime
+	if s.featureFlagStorer != nil {
+		featureFlagsPlugin := s.newFeatureFlagsPlugin()
+		s.RegisterPlugin(&featureFlagsPlugin.Plugin)
+	}
+
+	// Add the schedule command if scheduled actions can be paused at runtime (a no-op load if already done by Run)
+	if s.scheduledActionStorer != nil {
+		if err := s.loadScheduledActions(); err != nil {
+			s.log.Printf("Error loading scheduled actions: %s", err.Error())
+			return
+		}
+
+		scheduledActionsPlugin := s.newScheduledActionsPlugin()
+		s.RegisterPlugin(&scheduledActionsPlugin.Plugin)
+	}
+
+	// Add the store command if storers are inspectable
+	if len(s.inspectableStorers) > 0 {
+		storeInspectionPlugin := s.newStoreInspectionPlugin()
+		s.RegisterPlugin(&storeInspectionPlugin.Plugin)
+	}
+
+	// Add the opt out and opt in commands if users can opt out of the collection of data about them
+	if s.consentStorer != nil {
+		consentPlugin := s.newConsentPlugin()
+		s.RegisterPlugin(&consentPlugin.Plugin)
+	}
+
+	// Add the commands enabling and disabling plugins in channels, if they can be overridden at runtime. This goes
+	// after other plugins so that they can all be enabled and disabled
+	if s.featureFlagStorer != nil {
+		pluginChannelsPlugin := s.newPluginChannelsPlugin()
+		s.RegisterPlugin(&pluginChannelsPlugin.Plugin)
+	}
 
 	// Start by adding the help command now that we know all plugins have been registered
 	helpPlugin := s.newHelpPlugin(VERSION)
 	s.RegisterPlugin(&helpPlugin.Plugin)
 
+	// Resolve plugin services (a no-op if already done by Run or Build). The help plugin doesn't provide or require any service
+	// so registering it doesn't invalidate a previous resolution
+	if err := s.resolveServices(); err != nil {
+		s.log.Printf("Error resolving plugin services: %s", err.Error())
+		return
+	}
+
+	// Keep the channel info finder to attach to incoming messages
+	s.channelInfoFinder = deps.channelInfoFinder
+
+	// Keep the emoji lister to find the custom emoji of the workspace on behalf of plugins
+	s.emojiLister = deps.emojiLister
+
+	// Keep the chat driver to notify admins (i.e. of quarantined values)
+	s.adminNotifier = deps.chatDriver
+
 	// Inject services into plugins before starting to process events
-	s.injectServicesToPlugins(deps.userInfoFinder, s.log, deps.emojiReactor, deps.fileUploader, deps.realTimeMsgSender, deps.slackClient)
+	if err := s.injectServicesToPlugins(deps.userInfoFinder, s.log, deps.emojiReactor, deps.fileUploader, deps.realTimeMsgSender, deps.slackClient); err != nil {
+		s.log.Printf("Error injecting services into plugins: %s", err.Error())
+		return
+	}
 
-	// start all worker go routines
-	for i := range s.messageQueues {
-		go s.processMessages(deps.chatDriver, s.messageQueues[i], s.workerTerminationSignals[i])
+	// Adapt the sizes of caches, if enabled, now that the user info cache is created
+	go s.cacheSizer.run(s.ctx)
+
+	// Start receiving webhooks now that plugins have their services
+	s.serveWebhooks()
+
+	// Start running the plugins' jobs, including those enqueued before a restart
+	if s.jobQueue != nil {
+		go s.jobQueue.run(s.ctx)
 	}
 
-	for msg := range events {
+	// start all worker go routines or, with a maximum of concurrent handlers, the dispatcher starting one per channel with messages in flight
+	if maxConcurrentHandlers := s.config.GetInt(config.MaxConcurrentHandlersKey); maxConcurrentHandlers > 0 {
+		s.dispatcher = newChannelDispatcher(maxConcurrentHandlers, s.config.GetInt(config.MessageProcessingBufferedMessageCount), func(msg slack.MessageEvent) {
+			s.processMessage(deps.chatDriver, msg)
+		}, s.log, s.instrumenter)
+	} else {
+		for i := range s.messageQueues {
+			go s.processMessages(deps.chatDriver, s.messageQueues[i], s.workerTerminationSignals[i])
+		}
+	}
+
+	for {
+		var msg slack.RTMEvent
+		select {
+		case <-s.shutdownCh:
+			// Stop accepting events and finish processing the messages already received
+			s.drainMessageProcessing()
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			msg = event
+		}
+
 		switch e := msg.Data.(type) {
 		case *slack.ConnectedEvent:
 			s.coreMetrics.slackLatencyMillis.Set(context.Background(), 0)
 			s.log.Printf("Infos: %v\n", e.Info)
 			s.log.Printf("Connection counter: %d\n", e.ConnectionCount)
+			if e.ConnectionCount > 0 {
+				s.log.Printf("Reconnected to slack, ignoring message events replayed within [%s]\n", s.config.GetDuration(config.EventDedupWindowKey))
+			}
 			err := s.cacheSelfIdentity(deps.selfInfoFinder, deps.userInfoFinder)
 			if err != nil {
 				s.log.Printf("Error getting self identity: %s", err.Error())
@@ -544,7 +1119,47 @@ func (s *Slackscot) runInternal(events <-chan slack.RTMEvent, deps *runDependenc
 
 		case *slack.MessageEvent:
 			s.coreMetrics.msgsSeen.Add(context.Background(), 1)
-			s.routeMessageEvent(*e)
+			if s.isDuplicateEvent(*e, time.Now()) {
+				s.log.Debugf("Ignoring duplicate message event [%s]\n", eventKey(*e))
+				continue
+			}
+
+			if s.dispatcher != nil {
+				if err := s.dispatcher.dispatch(*e); err != nil {
+					s.log.Printf("Dropping message event: %v\n", err)
+				}
+			} else {
+				s.routeMessageEvent(*e)
+			}
+
+		case *slack.ReactionAddedEvent:
+			s.processReaction(deps.chatDriver, reactionEvent{user: e.User, reaction: e.Reaction, itemType: e.Item.Type, channel: e.Item.Channel, timestamp: e.Item.Timestamp}, true)
+
+		case *slack.ReactionRemovedEvent:
+			s.processReaction(deps.chatDriver, reactionEvent{user: e.User, reaction: e.Reaction, itemType: e.Item.Type, channel: e.Item.Channel, timestamp: e.Item.Timestamp}, false)
+
+		case *slack.ChannelCreatedEvent:
+			s.processChannelEvent(deps.chatDriver, IncomingChannelEvent{Type: ChannelCreated, Channel: e.Channel.ID, ChannelName: e.Channel.Name, User: e.Channel.Creator})
+
+		case *slack.ChannelRenameEvent:
+			s.invalidateChannelInfo(e.Channel.ID)
+			s.processChannelEvent(deps.chatDriver, IncomingChannelEvent{Type: ChannelRenamed, Channel: e.Channel.ID, ChannelName: e.Channel.Name})
+
+		case *slack.ChannelArchiveEvent:
+			s.invalidateChannelInfo(e.Channel)
+			s.processChannelEvent(deps.chatDriver, IncomingChannelEvent{Type: ChannelArchived, Channel: e.Channel, User: e.User})
+
+		case *slack.MemberJoinedChannelEvent:
+			s.processChannelEvent(deps.chatDriver, IncomingChannelEvent{Type: MemberJoinedChannel, Channel: e.Channel, User: e.User, Inviter: e.Inviter})
+
+		case *slack.MemberLeftChannelEvent:
+			s.processChannelEvent(deps.chatDriver, IncomingChannelEvent{Type: MemberLeftChannel, Channel: e.Channel, User: e.User})
+
+		case *slack.UserChangeEvent:
+			s.refreshUserInfo(e.User)
+
+		case *slack.EmojiChangedEvent:
+			s.invalidateEmoji()
 
 		case *slack.LatencyReport:
 			s.coreMetrics.slackLatencyMillis.Set(context.Background(), e.Value.Milliseconds())
@@ -560,18 +1175,13 @@ func (s *Slackscot) runInternal(events <-chan slack.RTMEvent, deps *runDependenc
 		case *slack.DisconnectedEvent:
 			if s.testMode && e.Cause != nil && e.Cause == slack.ErrRTMGoodbye {
 				s.log.Printf("Received termination event in test mode, terminating\n")
-				// Close all processing queues and wait for the terminations
-				for _, wq := range s.messageQueues {
-					close(wq)
-				}
-
-				// Wait for all workers to terminate processing
-				for _, tc := range s.workerTerminationSignals {
-					<-tc
-				}
+				s.drainMessageProcessing()
 
 				return
 			}
+
+			// The connection is managed by the RTM which reconnects on its own
+			s.log.Printf("Disconnected from slack (intentional: %t, cause: %v), reconnecting\n", e.Intentional, e.Cause)
 		default:
 			// Ignoring other messages
 		}
@@ -585,10 +1195,71 @@ func (s *Slackscot) injectServicesToPlugins(loadingUserInfoFinder UserInfoFinder
 		return err
 	}
 
+	s.userInfoFinder = userInfoFinder
+	s.eventBus = newEventBus(logger)
+
+	if cuf, ok := userInfoFinder.(*cachingUserInfoFinder); ok && cuf.userProfileCache != nil {
+		s.cacheSizer.register(userInfoCacheName, cuf.userProfileCache)
+	}
+
+	// Reload the user info cache saved on shutdown to avoid loading every user from slack again
+	if loaded, err := s.loadUserInfoCache(time.Now()); err != nil {
+		s.log.Printf("Error loading the saved user info cache, starting with an empty cache: %v", err)
+	} else if loaded > 0 {
+		s.log.Debugf("Loaded the saved info of %d users\n", loaded)
+	}
+
+	if s.emojiLister != nil {
+		s.emojiFinder = newCachingEmojiFinder(s.emojiLister, s.config.GetDuration(config.EmojiListRefreshIntervalKey))
+	}
+
+	// Channel info is cached for plugins and incoming messages alike
+	if s.channelInfoFinder != nil {
+		s.channelInfoFinder, err = NewCachingChannelInfoFinder(s.config, s.channelInfoFinder, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.jobQueue, err = newJobQueue(s.config, s.jobStorer, s.plugins, logger)
+	if err != nil {
+		return err
+	}
+
+	// Failures to load user info are shared by all plugins but each plugin gets its own fallback mode
+	failures := newUserInfoFailures(s.config.GetDuration(config.UserInfoRetryAfterKey))
+	s.userInfoFailures = failures
+
 	for _, p := range s.plugins {
+		mode, err := newUserInfoFallbackMode(config.GetUserInfoFallback(s.config, p.Name))
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range p.EventSubscriptions {
+			s.eventBus.Subscribe(sub.Topic, sub.Handle)
+		}
+
+		p.Services = s.services
+		p.EventBus = s.eventBus
+		p.FeatureFlags = s.featureFlags
+		if s.jobQueue != nil {
+			p.JobQueue = &pluginJobQueue{plugin: p.Name, queue: s.jobQueue}
+		}
+
+		p.BotAdmin = botAdmin{s: s}
+		p.UserConsent = s.userConsent
+		p.Quarantiner = &quarantiner{plugin: p.Name, admins: s.config.GetStringSlice(config.StoreAdminIDsKey), sender: s.adminNotifier, logger: logger}
+		p.Theme = s.theme
+		p.Assets = s.assets
 		p.Logger = logger
-		p.UserInfoFinder = userInfoFinder
+		uf := fallbackUserInfoFinder{finder: userInfoFinder, failures: failures, mode: mode, logger: logger}
+		p.UserInfoFinder = uf
+		p.UsersInfoFinder = uf
+		p.UserInfoInvalidator = uf
+		p.ChannelInfoFinder = s.channelInfoFinder
 		p.EmojiReactor = emojiReactor
+		p.EmojiFinder = s.emojiFinder
 		p.FileUploader = fileUploader
 		p.RealTimeMsgSender = msgSender
 		p.SlackClient = slackClient
@@ -597,18 +1268,6 @@ func (s *Slackscot) injectServicesToPlugins(loadingUserInfoFinder UserInfoFinder
 	return nil
 }
 
-// watchForTerminationSignalToAbort waits for a SIGTERM or SIGINT and sends a termination signal on the termination channel to finish
-// the main Run() loop and terminate cleanly. Note that this is meant to run in a go routine given that this is blocking
-func (s *Slackscot) watchForTerminationSignalToAbort() {
-	tSignals := make(chan os.Signal, 1)
-	// Register to be notified of termination signals so we can abort
-	signal.Notify(tSignals, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-tSignals
-
-	s.log.Debugf("Received termination signal [%s], closing RTM's incoming events channel to terminate processing\n", sig)
-	s.terminationCh <- true
-}
-
 // getActionID returns a formatted identifier for an action. It includes the plugin name,
 // the action type (command or hear action) and its index within the list of such actions for the plugin
 //
@@ -624,6 +1283,9 @@ func getActionID(pluginName string, actionType string, index int) (actionID stri
 func (s *Slackscot) cacheSelfIdentity(selfInfoFinder selfInfoFinder, userInfoFinder UserInfoFinder) (err error) {
 	s.selfIdentity.id = selfInfoFinder.GetInfo().User.ID
 	s.selfIdentity.name = selfInfoFinder.GetInfo().User.Name
+	if team := selfInfoFinder.GetInfo().Team; team != nil {
+		s.selfIdentity.teamID = team.ID
+	}
 
 	user, err := userInfoFinder.GetUserInfo(s.selfIdentity.id)
 	if err != nil {
@@ -636,27 +1298,13 @@ func (s *Slackscot) cacheSelfIdentity(selfInfoFinder selfInfoFinder, userInfoFin
 	return nil
 }
 
-// startActionScheduler creates all ScheduledActionDefinition from all plugins and registers them with the scheduler
+// startActionScheduler registers all plugins' scheduled actions with the scheduler (see scheduledActionRegistry)
 // Very importantly, it also starts the scheduler
 func (s *Slackscot) startActionScheduler(timeLoc *time.Location) {
 	gocron.ChangeLoc(timeLoc)
 	sc := gocron.NewScheduler()
 
-	for _, p := range s.plugins {
-		if p.ScheduledActions != nil {
-			for _, sa := range p.ScheduledActions {
-				j, err := schedule.NewJob(sc, sa.Schedule)
-				if err == nil {
-					s.log.Debugf("Adding job [%v] to scheduler\n", j)
-					err = j.Do(sa.Action)
-				}
-
-				if err != nil {
-					s.log.Printf("Error: failed to schedule job for scheduled action ['%s' - %s]: %v\n", sa.Schedule, sa.Description, err)
-				}
-			}
-		}
-	}
+	s.scheduledActions.schedule(sc, timeLoc, s.log)
 
 	_, t := sc.NextRun()
 	s.log.Debugf("Starting scheduler with first job scheduled at [%s]\n", t)
@@ -665,53 +1313,68 @@ func (s *Slackscot) startActionScheduler(timeLoc *time.Location) {
 	<-sc.Start()
 }
 
+// newChatDriver returns the chat driver of a slack client, instrumented and, unless disabled, rate limited
+func (s *Slackscot) newChatDriver(sc *slack.Client) (driver chatDriver) {
+	driver = sc
+	if s.config.GetBool(config.RateLimitEnabledKey) {
+		driver = newRateLimitedChatDriver(sc, s.config.GetInt(config.RateLimitMaxRetriesKey), s.config.GetInt(config.RateLimitQueueSizeKey), s.log)
+	}
+
+	return NewchatDriverWithTelemetry(driver, s.name, s.instrumenter.meter)
+}
+
 // processMessages processes messages from a queue and sends a termination signal on terminationChan when done
 func (s *Slackscot) processMessages(driver chatDriver, queue chan slack.MessageEvent, terminationChan chan bool) {
 	for msg := range queue {
-		// reply_to is an field set to 1 sent by slack when a sent message has been acknowledged and should be considered
-		// officially sent to others. Therefore, we ignore all of those since it's mostly for clients/UI to show status
-		isReply := msg.ReplyTo > 0
+		s.processMessage(driver, msg)
+	}
 
-		s.log.Debugf("Processing event: %v", msg)
+	terminationChan <- true
+}
+
+// processMessage processes a single message event (new, updated or deleted message)
+func (s *Slackscot) processMessage(driver chatDriver, msg slack.MessageEvent) {
+	// reply_to is an field set to 1 sent by slack when a sent message has been acknowledged and should be considered
+	// officially sent to others. Therefore, we ignore all of those since it's mostly for clients/UI to show status
+	isReply := msg.ReplyTo > 0
+
+	s.log.Debugf("Processing event: %v", msg)
 
-		if !isReply && msg.Type == "message" {
-			if msg.SubType == "message_deleted" {
+	if !isReply && msg.Type == "message" {
+		if msg.SubType == "message_deleted" || isTombstone(msg) {
+			d := measure(func() {
+				s.processDeletedMessage(driver, msg)
+			})
+
+			c := s.coreMetrics.msgsProcessed[deleteMsgType]
+			c.Add(context.Background(), 1)
+
+			m := s.coreMetrics.msgProcessingLatencyMillis[deleteMsgType]
+			m.Record(context.Background(), d.Milliseconds())
+		} else {
+			if msg.SubType == "message_changed" {
 				d := measure(func() {
-					s.processDeletedMessage(driver, msg)
+					s.processUpdatedMessage(driver, msg)
 				})
 
-				c := s.coreMetrics.msgsProcessed[deleteMsgType]
+				c := s.coreMetrics.msgsProcessed[updateMsgType]
 				c.Add(context.Background(), 1)
 
-				m := s.coreMetrics.msgProcessingLatencyMillis[deleteMsgType]
+				m := s.coreMetrics.msgProcessingLatencyMillis[updateMsgType]
+				m.Record(context.Background(), d.Milliseconds())
+			} else if isRoutedSubType(msg.SubType) {
+				d := measure(func() {
+					s.processNewMessage(driver, msg)
+				})
+
+				c := s.coreMetrics.msgsProcessed[newMsgType]
+				c.Add(context.Background(), 1)
+
+				m := s.coreMetrics.msgProcessingLatencyMillis[newMsgType]
 				m.Record(context.Background(), d.Milliseconds())
-			} else {
-				if msg.SubType == "message_changed" {
-					d := measure(func() {
-						s.processUpdatedMessage(driver, msg)
-					})
-
-					c := s.coreMetrics.msgsProcessed[updateMsgType]
-					c.Add(context.Background(), 1)
-
-					m := s.coreMetrics.msgProcessingLatencyMillis[updateMsgType]
-					m.Record(context.Background(), d.Milliseconds())
-				} else if msg.SubType != "message_replied" {
-					d := measure(func() {
-						s.processNewMessage(driver, msg)
-					})
-
-					c := s.coreMetrics.msgsProcessed[newMsgType]
-					c.Add(context.Background(), 1)
-
-					m := s.coreMetrics.msgProcessingLatencyMillis[newMsgType]
-					m.Record(context.Background(), d.Milliseconds())
-				}
 			}
 		}
 	}
-
-	terminationChan <- true
 }
 
 // getOriginalMessageID returns the message ID of the original message if it's linked
@@ -742,8 +1405,18 @@ func getAgeOriginalMsg(m slack.MessageEvent) (age time.Duration, err error) {
 	return time.Duration(int64(ageInSeconds)) * time.Second, nil
 }
 
+// maxUpdatedMessageAge returns the maximum age of messages for their updates to be handled. That's the
+// config.MessageUpdateAgeLimitKey minutes, when set, or config.MaxAgeHandledMessages
+func (s *Slackscot) maxUpdatedMessageAge() time.Duration {
+	if limit := s.config.GetInt(config.MessageUpdateAgeLimitKey); limit > 0 {
+		return time.Duration(limit) * time.Minute
+	}
+
+	return s.config.GetDuration(config.MaxAgeHandledMessages)
+}
+
 // processUpdatedMessage processes changed messages. This is a more complicated scenario but slackscot handles it by doing the following:
-// 1. If the message age is older than the config.MaxAgeHandledMessages threshold, the message update is ignored
+// 1. If the message age is older than the config.MessageUpdateAgeLimitKey (or, if unset, config.MaxAgeHandledMessages) threshold, the message update is ignored
 // 2. If the message isn't present in the triggering message cache, we process it as we would any other regular new message (check if it triggers an action and sends responses accordingly)
 // 3. If the message is present in cache, we had pre-existing responses so we handle this by updating responses on a plugin action basis. A plugin action that isn't triggering anymore gets its previous
 //    response deleted while a still triggering response will result in a message update. Newly triggered actions will be sent out as new messages.
@@ -752,7 +1425,7 @@ func (s *Slackscot) processUpdatedMessage(driver chatDriver, m slack.MessageEven
 	incomingMessageID := SlackMessageID{channelID: m.Channel, timestamp: m.Timestamp}
 	editedMsgID := getOriginalMessageID(m)
 
-	maxAgeThreshold := s.config.GetDuration(config.MaxAgeHandledMessages)
+	maxAgeThreshold := s.maxUpdatedMessageAge()
 	msgAge, err := getAgeOriginalMsg(m)
 	if err != nil {
 		s.log.Printf("Unable to determine max age for message [%v]: %s", m, err.Error())
@@ -766,89 +1439,138 @@ func (s *Slackscot) processUpdatedMessage(driver chatDriver, m slack.MessageEven
 
 	s.log.Debugf("Updated message: [%s], does cache contain it => [%t]", editedMsgID, s.triggeringMsgToResponse.Contains(editedMsgID))
 
-	if cachedResponses, exists := s.triggeringMsgToResponse.Get(editedMsgID); exists {
-		s.processUpdatedMessageWithCachedResponses(driver, m, editedMsgID, cachedResponses.(map[string]SlackMessageID))
-	} else {
-		outMsgs := s.routeMessage(m)
+	s.messageSequencer.do(editedMsgID, func() {
+		if cachedResponses, exists := s.getResponses(editedMsgID); exists {
+			s.processUpdatedMessageWithCachedResponses(driver, m, editedMsgID, cachedResponses)
+		} else {
+			outMsgs := s.routeMessage(m)
 
-		s.sendOutgoingMessages(driver, incomingMessageID, outMsgs)
-	}
+			s.sendOutgoingMessages(driver, incomingMessageID, outMsgs)
+		}
+	})
 }
 
 // processUpdatedMessageWithCachedResponses handles a message update for which we still have cached responses in cache. This is where we take care of deleting responses that are no longer
 // triggering the action they're coming from, updating the reactions for still triggering plugin actions as well as sending new reactions for plugin actions that are now triggering
 func (s *Slackscot) processUpdatedMessageWithCachedResponses(driver chatDriver, m slack.MessageEvent, editedMsgID SlackMessageID, cachedResponses map[string]SlackMessageID) {
 	newResponseByActionID := make(map[string]SlackMessageID)
+	partThreads := make(map[string]string)
 
-	outMsgs := s.routeMessage(m)
+	outMsgs := s.addAnswerReactions(driver, editedMsgID, s.routeMessage(m))
 	s.log.Debugf("Detected %d existing responses to message [%s]\n", len(cachedResponses), editedMsgID)
 
 	for _, o := range outMsgs {
 		// We had a previous response for that same plugin action so edit it instead of posting a new message
-		if r, ok := cachedResponses[o.pluginActionID]; ok {
+		if r, ok := cachedResponses[o.responseKey()]; ok && r.scheduled {
+			// Scheduled messages can't be updated so we keep the one already scheduled rather than scheduling another one
+			s.log.Debugf("Keeping response scheduled on [%s] for plugin action [%s] as scheduled messages can't be updated\n", r.channelID, o.responseKey())
+			newResponseByActionID[o.responseKey()] = r
+			delete(cachedResponses, o.responseKey())
+		} else if ok {
 			s.log.Debugf("Trying to update response at [%s] with message [%s]\n", r, o.OutgoingMessage.Text)
 
+			// The action ran again so any progress task of its previous answer is superseded
+			s.stopProgressTask(r)
 			rID, err := s.updateExistingMessage(driver, r, o)
 			if err != nil {
 				s.log.Printf("Unable to update message [%s] to triggering message [%s]: %v\n", r, editedMsgID, err)
 			} else {
 				// Add the new updated message to the new responses
-				newResponseByActionID[o.pluginActionID] = rID
+				newResponseByActionID[o.responseKey()] = rID
+				s.trackPartThread(partThreads, o, rID, editedMsgID.timestamp)
+				s.startProgressTask(driver, editedMsgID, rID, o)
 
 				// Remove entries for plugin actions as we process them so that we can detect afterwards if a plugin isn't triggering
 				// anymore (to delete those responses).
-				delete(cachedResponses, o.pluginActionID)
+				delete(cachedResponses, o.responseKey())
 			}
 		} else {
 			s.log.Debugf("New response triggered to updated message [%s] [%s]: [%s]\n", o.OutgoingMessage.Text, r, o.OutgoingMessage.Text)
 
 			// It's a new message for that action so post it as a new message
-			rID, err := s.sendNewMessage(driver, o, editedMsgID.timestamp)
+			rID, err := s.sendMessagePart(driver, editedMsgID, o, editedMsgID.timestamp, partThreads)
 			if err != nil {
 				s.log.Printf("Unable to send new message to updated message [%s]: %v\n", r, err)
-			} else if rID.IsMsgModifiable() {
-				// Add the new updated message to the new responses if it can be modified later
-				newResponseByActionID[o.pluginActionID] = rID
+			} else if rID.IsMsgModifiable() || rID.scheduled {
+				// Add the new updated message to the new responses if it can be modified later (or if it's scheduled)
+				newResponseByActionID[o.responseKey()] = rID
 			}
 		}
 	}
 
 	// Delete any previous triggered responses that aren't triggering anymore
 	for pa, r := range cachedResponses {
+		// Actions with side effects don't run again on edits so their responses are kept as they are
+		if s.hasRunAction(editedMsgID.channelID, editedMsgID.timestamp, actionIDOfResponseKey(pa)) {
+			newResponseByActionID[pa] = r
+			continue
+		}
+
+		if r.scheduled {
+			s.log.Printf("Unable to delete previous response scheduled on [%s] for a now non-triggered plugin action [%s] as scheduled messages can't be deleted\n", r.channelID, pa)
+			continue
+		}
+
 		s.log.Debugf("Deleting previous response [%s] on a now non-triggered plugin action [%s]\n", r, pa)
+		s.stopProgressTask(r)
 		driver.DeleteMessage(r.channelID, r.timestamp)
 	}
 
 	// Since the updated message now has new responses, update the entry with those or remove if no actions are triggered
 	if len(newResponseByActionID) > 0 {
 		s.log.Debugf("Updating responses to edited message [%s]\n", editedMsgID)
-		s.triggeringMsgToResponse.Add(editedMsgID, newResponseByActionID)
+		s.trackResponses(editedMsgID, newResponseByActionID)
 	} else {
 		s.log.Debugf("Deleting entry for edited message [%s] since no more triggered response\n", editedMsgID)
-		s.triggeringMsgToResponse.Remove(editedMsgID)
+		s.untrackResponses(editedMsgID)
 	}
 }
 
 // processDeletedMessage handles a deleted message. Slackscot cares about those in order to
-// delete any previous responses triggered by that now inexistant message
+// delete any previous responses triggered by that now inexistant message. Responses to a deleted
+// message that started a thread are deleted, replaced or left as they are depending on
+// config.ThreadParentDeletionKey
 func (s *Slackscot) processDeletedMessage(deleter messageDeleter, msgEvent slack.MessageEvent) {
-	deletedMessageID := SlackMessageID{channelID: msgEvent.Channel, timestamp: msgEvent.DeletedTimestamp}
+	deletedMessageID := getDeletedMessageID(msgEvent)
 
 	s.log.Debugf("Message deleted: [%s] and cache contains: [%s]", deletedMessageID, s.triggeringMsgToResponse.Keys())
 
-	if existingResponses, exists := s.triggeringMsgToResponse.Get(deletedMessageID); exists {
-		byAction := existingResponses.(map[string]SlackMessageID)
+	behavior := threadParentDeletionDelete
+	if isDeletedThreadParent(msgEvent) {
+		behavior = s.config.GetString(config.ThreadParentDeletionKey)
+	}
 
-		for _, v := range byAction {
-			// Delete existing response since the triggering message was deleted
-			_, _, err := deleter.DeleteMessage(v.channelID, v.timestamp)
-			if err != nil {
-				s.log.Printf("Error deleting existing response to triggering message [%s]: %s: %v", deletedMessageID, v, err)
+	s.messageSequencer.do(deletedMessageID, func() {
+		if existingResponses, exists := s.getResponses(deletedMessageID); exists {
+			for _, v := range existingResponses {
+				if behavior == threadParentDeletionLeave {
+					s.log.Debugf("Leaving response [%s] to deleted thread parent [%s] as is", v, deletedMessageID)
+					continue
+				}
+
+				if v.scheduled {
+					s.log.Printf("Unable to delete response scheduled on [%s] to deleted triggering message [%s] as scheduled messages can't be deleted", v.channelID, deletedMessageID)
+					continue
+				}
+
+				s.stopProgressTask(v)
+				if behavior == threadParentDeletionReplace {
+					if err := s.markOriginalMessageDeleted(deleter, v); err != nil {
+						s.log.Printf("Error replacing existing response to deleted thread parent [%s]: %s: %v", deletedMessageID, v, err)
+					}
+					continue
+				}
+
+				// Delete existing response since the triggering message was deleted
+				_, _, err := deleter.DeleteMessage(v.channelID, v.timestamp)
+				if err != nil {
+					s.log.Printf("Error deleting existing response to triggering message [%s]: %s: %v", deletedMessageID, v, err)
+				}
 			}
-		}
 
-		s.triggeringMsgToResponse.Remove(deletedMessageID)
-	}
+			s.untrackResponses(deletedMessageID)
+		}
+	})
 }
 
 // processNewMessage handles a regular new message and sends any triggered response
@@ -856,21 +1578,29 @@ func (s *Slackscot) processNewMessage(msgSender messageSender, m slack.MessageEv
 	incomingMessageID := SlackMessageID{channelID: m.Channel, timestamp: m.Timestamp}
 	outMsgs := s.routeMessage(m)
 
-	s.sendOutgoingMessages(msgSender, incomingMessageID, outMsgs)
+	s.messageSequencer.do(incomingMessageID, func() {
+		s.sendOutgoingMessages(msgSender, incomingMessageID, outMsgs)
+	})
 }
 
 // sendOutgoingMessages sends out any triggered plugin responses and keeps track of those in the internal cache
 func (s *Slackscot) sendOutgoingMessages(sender messageSender, incomingMessageID SlackMessageID, outMsgs []OutgoingMessage) {
 	newResponseByActionID := make(map[string]SlackMessageID)
+	partThreads := make(map[string]string)
+
+	if reactor, ok := sender.(messageReactor); ok {
+		outMsgs = s.addAnswerReactions(reactor, incomingMessageID, outMsgs)
+	}
 
 	for _, o := range outMsgs {
 		// Send the message and keep track of our response in cache to be able to update it as needed later
-		rID, err := s.sendNewMessage(sender, o, incomingMessageID.timestamp)
+		rID, err := s.sendMessagePart(sender, incomingMessageID, o, incomingMessageID.timestamp, partThreads)
 		if err != nil {
 			s.log.Printf("Unable to send new message triggered by [%s]: %v\n", incomingMessageID, err)
-		} else if rID.IsMsgModifiable() {
-			// Add the new updated message to the new responses if it's one that can be modified later
-			newResponseByActionID[o.pluginActionID] = rID
+		} else if rID.IsMsgModifiable() || rID.scheduled {
+			// Add the new updated message to the new responses if it's one that can be modified later (or one that is scheduled
+			// so that edits of the triggering message don't schedule it again)
+			newResponseByActionID[o.responseKey()] = rID
 		}
 	}
 
@@ -878,49 +1608,175 @@ func (s *Slackscot) sendOutgoingMessages(sender messageSender, incomingMessageID
 		s.log.Debugf("Adding responses to triggering message [%s]: %s", incomingMessageID, newResponseByActionID)
 
 		// Add current responses for that triggering message
-		s.triggeringMsgToResponse.Add(incomingMessageID, newResponseByActionID)
+		s.trackResponses(incomingMessageID, newResponseByActionID)
 	}
 }
 
 // sendNewMessage sends a new outgoingMsg and waits for the response to return that message's identifier
 func (s *Slackscot) sendNewMessage(sender messageSender, o OutgoingMessage, defaultThreadTS string) (rID SlackMessageID, err error) {
+	// Give answer hooks a last look before anything is logged or reaches slack
+	o, allow := s.applyAnswerHooksToMsg(o)
+	if !allow {
+		return rID, fmt.Errorf("answer of [%s] not sent: blocked by answer hook", o.pluginActionID)
+	}
+
 	s.log.Printf("Sending new message: %s", o.OutgoingMessage.Text)
 	sendOpts := ApplyAnswerOpts(o.Options...)
-	options := []slack.MsgOption{slack.MsgOptionText(o.OutgoingMessage.Text, false), slack.MsgOptionAsUser(true)}
-	if s.config.GetBool(config.ThreadedRepliesKey) || cast.ToBool(sendOpts[ThreadedReplyOpt]) {
-		if threadTS := cast.ToString(sendOpts[ThreadTimestamp]); threadTS != "" {
-			options = append(options, slack.MsgOptionTS(threadTS))
-		} else {
-			options = append(options, slack.MsgOptionTS(defaultThreadTS))
-		}
+	options := append([]slack.MsgOption{slack.MsgOptionText(o.OutgoingMessage.Text, false)}, s.identityMsgOptions(sendOpts)...)
+	if threadTS := s.replyThreadTimestamp(o, sendOpts, defaultThreadTS); threadTS != "" {
+		options = append(options, slack.MsgOptionTS(threadTS))
 
-		if s.config.GetBool(config.BroadcastThreadedRepliesKey) || cast.ToBool(sendOpts[BroadcastOpt]) {
+		if _, broadcast := s.replyBehavior(o); broadcast || cast.ToBool(sendOpts[BroadcastOpt]) {
 			options = append(options, slack.MsgOptionBroadcast())
 		}
 	}
 
 	// Add ephemeral option if present
-	if userID, ok := sendOpts[EphemeralAnswerToOpt]; ok {
+	userID, ephemeral := sendOpts[EphemeralAnswerToOpt]
+
+	// Attach metadata before options changing where the message is sent (ephemeral messages don't support it)
+	metadataOption, err := metadataMsgOption(sendOpts, slack.MsgOptionPost())
+	if err != nil {
+		return rID, fmt.Errorf("answer of [%s] not sent: %w", o.pluginActionID, err)
+	}
+
+	if metadataOption != nil && ephemeral {
+		s.log.Printf("Ephemeral messages don't support metadata so sending answer of [%s] without it", o.pluginActionID)
+	} else if metadataOption != nil {
+		options = append([]slack.MsgOption{metadataOption}, options...)
+	}
+
+	if ephemeral {
 		options = append(options, slack.MsgOptionPostEphemeral(userID))
 	}
 
-	// Add any block kit content blocks, if any
-	if len(o.ContentBlocks) > 0 {
-		options = append(options, slack.MsgOptionBlocks(o.ContentBlocks...))
+	// Schedule the delivery for later, if requested (and possible)
+	postAt, scheduled := sendOpts[DeliverAtOpt]
+	if scheduled && ephemeral {
+		s.log.Printf("Ephemeral messages can't be scheduled so delivering immediately instead of at [%s]", postAt)
+		scheduled = false
+	} else if scheduled && !isInFuture(postAt) {
+		s.log.Debugf("Delivery time [%s] isn't in the future so delivering immediately", postAt)
+		scheduled = false
+	} else if scheduled {
+		options = append(options, slack.MsgOptionSchedule(postAt))
+	}
+
+	// Add any block kit content blocks (including interactive elements), if any, after making sure slack would accept them
+	blocks := answerBlocks(o)
+	if err = validateMessage(o.OutgoingMessage.Text, blocks); err != nil {
+		return rID, fmt.Errorf("answer of [%s] not sent: %w", o.pluginActionID, err)
+	}
+
+	if len(blocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(blocks...))
 	}
 
 	channelID, newOutgoingMsgTimestamp, _, err := sender.SendMessage(o.OutgoingMessage.Channel, options...)
 	rID = SlackMessageID{channelID: channelID, timestamp: newOutgoingMsgTimestamp}
 
+	// Scheduled messages aren't posted yet so they can't be updated or deleted like regular responses
+	if scheduled {
+		rID = SlackMessageID{channelID: channelID, scheduled: true}
+	}
+
 	return rID, err
 }
 
+// replyThreadTimestamp returns the timestamp of the thread an outgoing message is sent in or an empty string if it's not
+// to be sent in a thread
+func (s *Slackscot) replyThreadTimestamp(o OutgoingMessage, sendOpts map[string]string, defaultThreadTS string) (threadTS string) {
+	// Answers posted in the channel rather than in the thread of their message only go in threads given explicitly
+	// (i.e. the thread of the first part of a split answer)
+	if cast.ToBool(sendOpts[outsideOfThreadOpt]) && cast.ToString(sendOpts[ThreadTimestamp]) == "" {
+		return ""
+	}
+
+	if threaded, _ := s.replyBehavior(o); !threaded && !cast.ToBool(sendOpts[ThreadedReplyOpt]) {
+		return ""
+	}
+
+	if threadTS = cast.ToString(sendOpts[ThreadTimestamp]); threadTS != "" {
+		return threadTS
+	}
+
+	return defaultThreadTS
+}
+
+// sendMessagePart sends an outgoing message like sendNewMessage but, for parts of split answers after the first one,
+// in the thread of the first part (see trackPartThread) so that all parts are threaded together. The triggering message
+// sequences the updates of the answer's progress task, if any (see messageSequencer)
+func (s *Slackscot) sendMessagePart(sender messageSender, triggeringMsgID SlackMessageID, o OutgoingMessage, defaultThreadTS string, partThreads map[string]string) (rID SlackMessageID, err error) {
+	if threadTS, ok := partThreads[o.firstPartKey()]; ok && o.part > 0 {
+		o.Options = append(append([]AnswerOption{}, o.Options...), AnswerInExistingThread(threadTS), AnswerInThreadWithoutBroadcast())
+	}
+
+	rID, err = s.sendNewMessage(sender, o, defaultThreadTS)
+	if err == nil {
+		s.trackPartThread(partThreads, o, rID, defaultThreadTS)
+
+		if updater, ok := sender.(messageUpdater); ok {
+			s.startProgressTask(updater, triggeringMsgID, rID, o)
+		}
+	}
+
+	return rID, err
+}
+
+// trackPartThread keeps track, in partThreads, of the thread the parts of a split answer go in given the message of its
+// first part: the thread the first part is in or, if it's not in a thread, the one started by it. Ephemeral and scheduled
+// first parts can't start threads so their other parts are sent like them
+func (s *Slackscot) trackPartThread(partThreads map[string]string, o OutgoingMessage, rID SlackMessageID, defaultThreadTS string) {
+	if o.part > 0 || !rID.IsMsgModifiable() {
+		return
+	}
+
+	threadTS := s.replyThreadTimestamp(o, ApplyAnswerOpts(o.Options...), defaultThreadTS)
+	if threadTS == "" {
+		threadTS = rID.timestamp
+	}
+
+	partThreads[o.firstPartKey()] = threadTS
+}
+
+// isInFuture returns true if the unix time is after the current time. Slack rejects the scheduling of messages
+// that aren't in the future
+func isInFuture(unixTime string) bool {
+	t, err := strconv.ParseInt(unixTime, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Unix(t, 0).After(time.Now())
+}
+
 // updateExistingMessage updates an existing message with the content of a newly triggered OutgoingMessage
 func (s *Slackscot) updateExistingMessage(updater messageUpdater, r SlackMessageID, o OutgoingMessage) (rID SlackMessageID, err error) {
+	o, allow := s.applyAnswerHooksToMsg(o)
+	if !allow {
+		return rID, fmt.Errorf("answer of [%s] not updated: blocked by answer hook", o.pluginActionID)
+	}
+
 	options := []slack.MsgOption{slack.MsgOptionText(o.OutgoingMessage.Text, false), slack.MsgOptionAsUser(true)}
-	// Add any block kit content blocks, if any
-	if len(o.ContentBlocks) > 0 {
-		options = append(options, slack.MsgOptionBlocks(o.ContentBlocks...))
+
+	// Keep the metadata, if any, of the answer
+	metadataOption, err := metadataMsgOption(ApplyAnswerOpts(o.Options...), slack.MsgOptionUpdate(r.timestamp))
+	if err != nil {
+		return rID, fmt.Errorf("answer of [%s] not updated: %w", o.pluginActionID, err)
+	}
+
+	if metadataOption != nil {
+		options = append([]slack.MsgOption{metadataOption}, options...)
+	}
+
+	// Add any block kit content blocks (including interactive elements), if any, after making sure slack would accept them
+	blocks := answerBlocks(o)
+	if err = validateMessage(o.OutgoingMessage.Text, blocks); err != nil {
+		return rID, fmt.Errorf("answer of [%s] not updated: %w", o.pluginActionID, err)
+	}
+
+	if len(blocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(blocks...))
 	}
 
 	channelID, newOutgoingMsgTimestamp, _, err := updater.UpdateMessage(r.channelID, r.timestamp, options...)
@@ -936,7 +1792,7 @@ func (s *Slackscot) updateExistingMessage(updater messageUpdater, r SlackMessage
 // it convenient for plugins using the timestamp to know that they're looking at the same one they've seen before. Regarding this timestamp, we sort of treat
 // is like the identifier that it is which would be initialized when first posted.
 //
-// Essentially, take everything from the main message except for the text, user and timestamp that is set on the SubMessage, if present.
+// Essentially, take everything from the main message except for the text, user, bot ID and timestamp that is set on the SubMessage, if present.
 func normalizeIncomingMessage(m slack.MessageEvent) (normalized slack.Msg) {
 	normalized = m.Msg
 
@@ -944,6 +1800,7 @@ func normalizeIncomingMessage(m slack.MessageEvent) (normalized slack.Msg) {
 		normalized.Text = m.SubMessage.Text
 		normalized.User = m.SubMessage.User
 		normalized.Timestamp = m.SubMessage.Timestamp
+		normalized.BotID = m.SubMessage.BotID
 	}
 	return normalized
 }
@@ -967,10 +1824,13 @@ func resolveThreadTimestamp(m slack.Msg) (threadTs string, isThreadedMessage boo
 // 	1. If the message is on a channel with a direct mention to us (@name), we route to commands
 // 	2. If the message is a direct message to us, we route to commands
 // 	3. If the message is on a channel without mention (regular conversation), we route to hear actions
+// Messages from other bots are only routed to the plugins with ListenToBots and messages are only routed to the plugins
+// running in their channel (see pluginChannels and config.SharedChannelsSafeModeKey)
 func (s *Slackscot) routeMessage(me slack.MessageEvent) (responses []OutgoingMessage) {
 	m := normalizeIncomingMessage(me)
 
 	responses = make([]OutgoingMessage, 0)
+	pluginResps := make([]pluginResponses, 0)
 
 	// Ignore messages_replied and messages send by "us"
 	if s.botMatcher.IsBot(m) {
@@ -979,6 +1839,17 @@ func (s *Slackscot) routeMessage(me slack.MessageEvent) (responses []OutgoingMes
 		return responses
 	}
 
+	// Messages from other bots only reach plugins listening to them
+	fromBot := isBotMessage(m)
+
+	// Only some plugins run in externally shared channels in safe mode and users of other organizations are ignored there
+	safeMode := s.isSafeModeChannel(m.Channel)
+	if safeMode && s.ignoresUser(m.User, m.Team) {
+		s.log.Debugf("Ignoring message from user [%s] of team [%s] in externally shared channel [%s]", m.User, m.Team, m.Channel)
+
+		return responses
+	}
+
 	// Try commands or hear actions depending on the format of the message
 	if s.isCommand(m) {
 		replyStrategy := reply
@@ -986,29 +1857,46 @@ func (s *Slackscot) routeMessage(me slack.MessageEvent) (responses []OutgoingMes
 			replyStrategy = directReply
 		}
 
+		alreadyRun := false
 		for _, p := range s.plugins {
-			matchedNamespace, inMsg := s.newCmdInMsgWithNormalizedText(p, m)
+			if (fromBot && !p.ListenToBots) || !s.pluginRuns(p.Name, m.Channel, safeMode) {
+				continue
+			}
+
+			matchedNamespace, inMsg, matchMsg := s.newCmdInMsgWithNormalizedText(p, m)
 
 			if matchedNamespace {
-				outMsgs := s.tryPluginActions(p.Name, commandType, p.Commands, inMsg, replyStrategy)
-				responses = append(responses, outMsgs...)
+				outMsgs, skipped := s.tryPluginActions(p, commandType, p.Commands, matchMsg, inMsg, replyStrategy)
+				alreadyRun = alreadyRun || skipped
+				pluginResps = append(pluginResps, pluginResponses{priority: p.Priority, outMsgs: withPluginDefaults(p, outMsgs)})
 			}
 		}
 
-		// Use default answer if this was a message formatted as a command for which we didn't have any answer to
-		if len(responses) == 0 {
+		responses = s.answerPolicy.apply(pluginResps)
+
+		// Use default answer if this was a message formatted as a command for which we didn't have any answer to (unless
+		// it's for a command with side effects that already ran on the message or from another bot, to avoid bots
+		// endlessly answering each other)
+		if len(responses) == 0 && !alreadyRun && !fromBot {
 			responses = append(responses, defaultAnswer(s.defaultAction, s.newIncomingMsgWithNormalizedText(m), replyStrategy))
 		}
 	} else {
 		for _, p := range s.plugins {
+			if (fromBot && !p.ListenToBots) || !s.pluginRuns(p.Name, m.Channel, safeMode) || s.ignoresUserData(p, m.User) {
+				continue
+			}
+
 			inMsg := s.newIncomingMsgWithNormalizedText(m)
 
-			outMsgs := s.tryPluginActions(p.Name, hearActionType, p.HearActions, inMsg, send)
-			responses = append(responses, outMsgs...)
+			outMsgs, _ := s.tryPluginActions(p, hearActionType, p.HearActions, inMsg, inMsg, send)
+			pluginResps = append(pluginResps, pluginResponses{priority: p.Priority, outMsgs: withPluginDefaults(p, outMsgs)})
 		}
+
+		responses = s.answerPolicy.apply(pluginResps)
 	}
 
-	return responses
+	// Split what doesn't fit in single messages. Answer hooks run on each part when it's sent
+	return splitOutgoingMessages(responses)
 }
 
 // defaultAnswer returns the answer by invocation of the default action
@@ -1024,27 +1912,52 @@ func defaultAnswer(answerDefault Answerer, inMsg IncomingMessage, rs responseStr
 // newCmdInMsgWithNormalizedText creates a new IncomingMessage for a command and generates the normalized text for plugins
 // to have a normalized view of the message regardless of context. For commands part of a Plugin with NamespaceCommands,
 // the normalized text removes the namespace if the proper namespace is found. If not, matchedNamespace is false
-// and the normalized text is the same as what newIncomingMsgWithNormalizedText would return
-func (s *Slackscot) newCmdInMsgWithNormalizedText(p *Plugin, m slack.Msg) (matchedNamespace bool, inMsg IncomingMessage) {
+// and the normalized text is the same as what newIncomingMsgWithNormalizedText would return.
+//
+// For plugins with NormalizeCommands, the namespace matching is case-insensitive and matchMsg is a copy of inMsg with its text
+// further normalized with NormalizeCommandText. That copy is only meant for Match functions so that Answer functions still get
+// the text as typed (i.e. with the case of arguments preserved). For all other plugins, matchMsg is the same as inMsg
+func (s *Slackscot) newCmdInMsgWithNormalizedText(p *Plugin, m slack.Msg) (matchedNamespace bool, inMsg IncomingMessage, matchMsg IncomingMessage) {
 	inMsg = s.newIncomingMsgWithNormalizedText(m)
 	matchedNamespace = true
 
 	if p != nil && s.namespaceCommands && p.NamespaceCommands {
-		namespacePrefix := fmt.Sprintf("%s ", p.Name)
-		if matchedNamespace = strings.HasPrefix(inMsg.NormalizedText, namespacePrefix); matchedNamespace {
-			inMsg.NormalizedText = strings.TrimPrefix(inMsg.NormalizedText, namespacePrefix)
+		if p.NormalizeCommands {
+			inMsg.NormalizedText, matchedNamespace = trimNamespaceIgnoringCase(inMsg.NormalizedText, p.Name)
+		} else {
+			namespacePrefix := fmt.Sprintf("%s ", p.Name)
+			if matchedNamespace = strings.HasPrefix(inMsg.NormalizedText, namespacePrefix); matchedNamespace {
+				inMsg.NormalizedText = strings.TrimPrefix(inMsg.NormalizedText, namespacePrefix)
+			}
 		}
 	}
 
-	return matchedNamespace, inMsg
+	matchMsg = inMsg
+	if p != nil && p.NormalizeCommands {
+		matchMsg.NormalizedText = NormalizeCommandText(inMsg.NormalizedText)
+	}
+
+	return matchedNamespace, inMsg, matchMsg
+}
+
+// trimNamespaceIgnoringCase removes the namespace from the start of the text if it's the first word of it, regardless of case.
+// The returned text has its leading and trailing whitespace trimmed but is otherwise preserved
+func trimNamespaceIgnoringCase(text string, namespace string) (trimmed string, matchedNamespace bool) {
+	trimmed = strings.TrimSpace(text)
+	fields := strings.Fields(trimmed)
+
+	if len(fields) < 2 || !strings.EqualFold(fields[0], namespace) {
+		return text, false
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(trimmed, fields[0])), true
 }
 
 // newIncomingMsgWithNormalizedText creates a new IncomingMessage and generates the normalized text for plugins
 // to have a normalized view of the message regardless of context. This includes having the text stripped of the "<@user>"
 // for commands sent via a directed message on a channel
 func (s *Slackscot) newIncomingMsgWithNormalizedText(m slack.Msg) (inMsg IncomingMessage) {
-	inMsg.NormalizedText = m.Text
-	inMsg.Msg = m
+	inMsg = NewIncomingMessage(m.Text, m, s.userInfoFinder, s.channelInfoFinder)
 	if isCmd, isDirectMsg := s.cmdMatcher.IsCmd(m), isDirectMessage(m); isCmd && !isDirectMsg {
 		inMsg.NormalizedText = s.cmdMatcher.TrimPrefix(m.Text)
 	}
@@ -1064,34 +1977,68 @@ func isDirectMessage(m slack.Msg) (isDirectMsg bool) {
 	return strings.HasPrefix(m.Channel, "D")
 }
 
-// useExistingThreadIfAny sets the option on an Answer to reply in the existing thread if there is one
+// useExistingThreadIfAny sets the option on an Answer to reply in the existing thread if there is one, unless
+// the answer opted out of it with AnswerInChannel
 func (a *Answer) useExistingThreadIfAny(m *IncomingMessage) {
-	// If the message we're reacting to is happening on an existing thread, make sure we reply on that
-	// thread too and avoid the awkward situation of responding on the parent channel
 	threadTimestamp, threaded := resolveThreadTimestamp(m.Msg)
-	if threaded {
-		a.Options = append(a.Options, AnswerInExistingThread(threadTimestamp))
+	if !threaded {
+		return
 	}
+
+	if cast.ToBool(ApplyAnswerOpts(a.Options...)[InChannelOpt]) {
+		a.Options = append(a.Options, answerOutsideOfThread())
+		return
+	}
+
+	// If the message we're reacting to is happening on an existing thread, make sure we reply on that
+	// thread too and avoid the awkward situation of responding on the parent channel
+	a.Options = append(a.Options, AnswerInExistingThread(threadTimestamp))
 }
 
 // tryPluginActions loops over all action definitions and invokes its action if the incoming message matches it's regular expression
-// Note that more than one action can be triggered during the processing of a single message
-func (s *Slackscot) tryPluginActions(pluginName string, actionType string, actions []ActionDefinition, m IncomingMessage, rs responseStrategy) (outMsgs []OutgoingMessage) {
+// Note that more than one action can be triggered during the processing of a single message. The matchMsg is what is given
+// to Match functions while m is what is given to Answer functions (see newCmdInMsgWithNormalizedText)
+func (s *Slackscot) tryPluginActions(p *Plugin, actionType string, actions []ActionDefinition, matchMsg IncomingMessage, m IncomingMessage, rs responseStrategy) (outMsgs []OutgoingMessage, alreadyRun bool) {
 	before := time.Now()
+	pluginName := p.Name
 
 	outMsgs = make([]OutgoingMessage, 0)
 
 	for i, action := range actions {
-		matches := action.Match(&m)
+		matches := action.Match(&matchMsg)
+
+		// Admin-only actions are refused to others before anything runs
+		refused := matches && action.AdminOnly && !s.isAdmin(m.User)
+		if refused {
+			s.log.Printf("Refusing admin-only action [%s] to non-admin user [%s]", getActionID(pluginName, actionType, i), m.User)
+		}
+
+		if matches && action.SideEffects && !refused {
+			if s.hasRunAction(m.Channel, m.Timestamp, getActionID(pluginName, actionType, i)) {
+				s.log.Debugf("Action [%s] with side effects already ran on message [%s/%s], skipping", getActionID(pluginName, actionType, i), m.Channel, m.Timestamp)
+				alreadyRun = true
+				continue
+			}
+
+			s.markActionRun(m.Channel, m.Timestamp, getActionID(pluginName, actionType, i))
+		}
 
 		if matches {
-			answer := action.Answer(&m)
+			var answers []*Answer
+			if refused {
+				answers = []*Answer{adminOnlyRefusal(m)}
+			} else {
+				call := ActionCall{Plugin: pluginName, ActionType: actionType, ActionID: getActionID(pluginName, actionType, i), Action: action}
+				answers = s.answerWithTimeout(call, p, m)
+			}
 
-			if answer != nil {
+			for j, answer := range answers {
 				answer.useExistingThreadIfAny(&m)
 				slackOutMsg := rs(m, answer)
 
 				outMsg := newOutMessageForAnswer(slackOutMsg, getActionID(pluginName, actionType, i), *answer)
+				outMsg.answerIndex = j
+				outMsg.replyBehavior = action.ReplyBehavior
 				outMsgs = append(outMsgs, outMsg)
 			}
 		}
@@ -1101,7 +2048,48 @@ func (s *Slackscot) tryPluginActions(pluginName string, actionType string, actio
 	pm.processingTimeMillis.Record(context.Background(), time.Since(before).Milliseconds())
 	pm.reactionCount.Add(context.Background(), int64(len(outMsgs)))
 
-	return outMsgs
+	if len(outMsgs) > 0 && s.eventBus != nil {
+		s.eventBus.Publish(PluginAnswered{Plugin: pluginName, ActionType: actionType, Channel: m.Channel, Answers: len(outMsgs)})
+	}
+
+	return outMsgs, alreadyRun
+}
+
+// answerWithTimeout returns the answers of an action to a message, through the middleware chain (see UseMiddleware).
+// The action gets a context canceled on shutdown or, if an action timeout is configured, once the timeout is exceeded
+// in which case the answers are dropped with a warning
+func (s *Slackscot) answerWithTimeout(call ActionCall, p *Plugin, m IncomingMessage) (answers []*Answer) {
+	parent := s.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	timeout := s.config.GetDuration(config.ActionTimeoutKey)
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		m.ctx = ctx
+		return s.handleAction(call, p, &m)
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	m.ctx = ctx
+
+	// Buffered so that an action finishing after its timeout doesn't block forever
+	answered := make(chan []*Answer, 1)
+	go func() {
+		answered <- s.handleAction(call, p, &m)
+	}()
+
+	select {
+	case answers = <-answered:
+		return answers
+	case <-ctx.Done():
+		s.log.Printf("Warning: action [%s] didn't answer within [%s] (%v), dropping its answer", call.ActionID, timeout, ctx.Err())
+		return nil
+	}
 }
 
 // newOutMessageForAnswer creates a new internal OutgoingMessage for the given Answer
@@ -1,6 +1,7 @@
 package slackscot
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/alexandre-normand/slackscot/config"
@@ -17,6 +18,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -28,6 +30,8 @@ const (
 	timestamp1                  = "1546833210.036900"
 	oneDayLaterTimestamp        = "1546919611.036900" // One second more than 24 hours after timestamp1
 	timestamp2                  = "1546833214.036900"
+	twoMinutesLaterTimestamp    = "1546833330.036900" // Two minutes after timestamp1
+	tenMinutesLaterTimestamp    = "1546833810.036900" // Ten minutes after timestamp1
 	firstReplyTimestamp         = 1547785956
 	replyTimeIncrementInSeconds = 10
 )
@@ -48,6 +52,11 @@ type deletedMessage struct {
 	timestamp string
 }
 
+type addedReaction struct {
+	name string
+	item slack.ItemRef
+}
+
 type rtmMessage struct {
 	channelID string
 	message   string
@@ -65,6 +74,8 @@ type inMemoryChatDriver struct {
 	sentMsgs    []sentMessage
 	updatedMsgs []updatedMessage
 	deletedMsgs []deletedMessage
+
+	addedReactions []addedReaction
 }
 
 func (c *inMemoryChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
@@ -88,11 +99,27 @@ func (c *inMemoryChatDriver) UpdateMessage(channelID, timestamp string, options
 	return channelID, c.nextTimestamp(), fmt.Sprintf("Message updated on %s", channelID), nil
 }
 
+func (c *inMemoryChatDriver) AddReaction(name string, item slack.ItemRef) (err error) {
+	c.addedReactions = append(c.addedReactions, addedReaction{name: name, item: item})
+	return nil
+}
+
 func (c *inMemoryChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
 	c.deletedMsgs = append(c.deletedMsgs, deletedMessage{channelID: channelID, timestamp: timestamp})
 	return channelID, c.nextTimestamp(), nil
 }
 
+func (c *inMemoryChatDriver) GetConversationHistory(params *slack.GetConversationHistoryParameters) (history *slack.GetConversationHistoryResponse, err error) {
+	if params.ChannelID == "Cunknown" {
+		return nil, fmt.Errorf("channel_not_found")
+	}
+
+	history = &slack.GetConversationHistoryResponse{}
+	history.Messages = []slack.Message{{Msg: slack.Msg{Channel: params.ChannelID, Timestamp: params.Latest, User: "Alphonse", Text: fmt.Sprintf("Message at %s", params.Latest)}}}
+
+	return history, nil
+}
+
 func (c *inMemoryChatDriver) nextTimestamp() (fmtTime string) {
 	c.timeCursor = c.timeCursor + replyTimeIncrementInSeconds
 	return formatTimestamp(c.timeCursor)
@@ -106,7 +133,7 @@ type selfFinder struct {
 }
 
 func (i *selfFinder) GetInfo() (user *slack.Info) {
-	return &slack.Info{User: &slack.UserDetails{ID: "BotUserID", Name: "Daniel Quinn"}}
+	return &slack.Info{User: &slack.UserDetails{ID: "BotUserID", Name: "Daniel Quinn"}, Team: &slack.Team{ID: "Tbirds"}}
 }
 
 type userInfoFinder struct {
@@ -252,6 +279,25 @@ func TestLogfileOverrideUsed(t *testing.T) {
 	assert.Contains(t, string(logs), "Connection counter: 0")
 }
 
+func TestEffectiveConfigLoggedInDebugMode(t *testing.T) {
+	var logBuilder strings.Builder
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.DebugKey, true)
+	v.Set(config.TokenKey, "xoxb-secret")
+
+	s, err := New("BobbyTables", v, OptionLog(log.New(&logBuilder, "", 0)), OptionConfigSources("", nil))
+	require.NoError(t, err)
+
+	s.logEffectiveConfig()
+
+	logs := logBuilder.String()
+	assert.Contains(t, logs, fmt.Sprintf("Starting [BobbyTables] with slackscot [%s] and effective configuration:", VERSION))
+	assert.Contains(t, logs, "\n  replybehavior.threadedreplies: [false] (default)\n")
+	assert.Contains(t, logs, "\n  token: [[redacted]] (override)\n")
+	assert.NotContains(t, logs, "xoxb-secret")
+}
+
 func TestLatencyReport(t *testing.T) {
 	_, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, newTestPlugin(), []slack.RTMEvent{
 		{Type: "latency_report", Data: &slack.LatencyReport{Value: 120}},
@@ -380,6 +426,41 @@ func TestHandleIncomingThreadedMessageTriggeringResponse(t *testing.T) {
 	assert.Equal(t, 0, len(rtmSender.SentMessages))
 }
 
+func newInChannelPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "announcer"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "announce")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Hear ye, hear ye", Options: []AnswerOption{AnswerInChannel()}}
+		},
+	}}
+
+	return p
+}
+
+func TestIncomingThreadedMessageAnsweredInChannel(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ThreadedRepliesKey, true)
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newInChannelPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "announce", "Alphonse", timestamp1, optionMessageOnThread("1212314125"))),
+		// Answers to messages that aren't in a thread still follow the threaded replies configuration
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "announce", "Alphonse", timestamp2)),
+	})
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Hear ye, hear ye", vals.Get("text"))
+		assert.Equal(t, "", vals.Get("thread_ts"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, timestamp2, vals.Get("thread_ts"))
+	}
+}
+
 func TestIgnoreIncomingMessageReplied(t *testing.T) {
 	sentMsgs, updatedMsgs, deletedMsgs, rtmSender, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newTestPlugin(), []slack.RTMEvent{
 		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1, optionMessageReplied())),
@@ -789,6 +870,30 @@ func testHelpTriggering(t *testing.T, v *viper.Viper) {
 	assert.Equal(t, 0, len(rtmSender.SentMessages))
 }
 
+// TestHelpTriggeringWithCommandNormalization validates that the help command is triggered regardless of
+// case and trailing punctuation since the help plugin has command normalization enabled
+func TestHelpTriggeringWithCommandNormalization(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	sentMsgs, updatedMsgs, deletedMsgs, rtmSender, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("<@%s> Help!", botUserID), "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "  HELP?", "Alphonse", timestamp2)),
+	})
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Contains(t, vals.Get("text"), "I currently support the following commands")
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Contains(t, vals.Get("text"), "I currently support the following commands")
+	}
+
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+	assert.Equal(t, 0, len(rtmSender.SentMessages))
+}
+
 // TestHelpTriggeringNoUserInfoCache indirectly tests the user info caching (or absence of) by exercising the
 // help plugin which makes a call to it in order to find info about the user who requested help
 func TestHelpTriggeringNoUserInfoCache(t *testing.T) {
@@ -887,6 +992,59 @@ func TestNewWithInvalidResponseCacheSize(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestNewWithUnknownTheme(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ThemeKey, "disco")
+
+	_, err := New("chicadee", v)
+	assert.EqualError(t, err, "Unknown theme [disco], should be one of [classic corporatePlain podium trophyCase]")
+}
+
+func TestNewWithInvalidRateLimitQueueSize(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.RateLimitQueueSizeKey, 0)
+
+	_, err := New("chicadee", v)
+	assert.EqualError(t, err, "rateLimit.queueSize config should be greater than 0 but was [0]")
+}
+
+func TestNewWithNegativeMaxConcurrentHandlers(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MaxConcurrentHandlersKey, -1)
+
+	_, err := New("chicadee", v)
+	assert.EqualError(t, err, "maxConcurrentHandlers config should be positive but was [-1]")
+}
+
+func TestNewWithNegativeMessageUpdateAgeLimit(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageUpdateAgeLimitKey, -5)
+
+	_, err := New("chicadee", v)
+	assert.EqualError(t, err, "messageUpdateAgeLimit config should be positive but was [-5]")
+}
+
+// TestMessageUpdateWithMaxConcurrentHandlers validates that, when dispatched to channel queues rather than partitions,
+// a message and its update are processed in order
+func TestMessageUpdateWithMaxConcurrentHandlers(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MaxConcurrentHandlersKey, 4)
+
+	sentMsgs, updatedMsgs, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Ignored", timestamp2, optionChangedMessage("blue jays eat acorn", "Alphonse", timestamp1))),
+	})
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "I heard you say something about blue jays?", vals.Get("text"))
+	}
+
+	if assert.Equal(t, 1, len(updatedMsgs)) {
+		assert.Equal(t, "Cgeneral", updatedMsgs[0].channelID)
+	}
+}
+
 func TestMessageUpdatedAfterHandlingThresholdIgnored(t *testing.T) {
 	sentMsgs, updatedMsgs, deletedMsgs, rtmSender, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newTestPlugin(), []slack.RTMEvent{
 		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
@@ -906,6 +1064,36 @@ func TestMessageUpdatedAfterHandlingThresholdIgnored(t *testing.T) {
 	assert.Equal(t, 0, len(rtmSender.SentMessages))
 }
 
+func TestMessageUpdatedAfterUpdateAgeLimitIgnored(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageUpdateAgeLimitKey, 5)
+
+	sentMsgs, updatedMsgs, deletedMsgs, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		// Ten minutes after the original message, neither updating nor deleting its answer
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Ignored", tenMinutesLaterTimestamp, optionChangedMessage("sparrows", "Alphonse", timestamp1))),
+		// Ten minutes after the original message, not triggering a new answer
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sparrows", "Ignored", tenMinutesLaterTimestamp, optionChangedMessage("blue jays eat acorn", "Alphonse", timestamp2))),
+	})
+
+	assert.Equal(t, 1, len(sentMsgs))
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+}
+
+func TestMessageUpdatedWithinUpdateAgeLimitHandled(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageUpdateAgeLimitKey, 5)
+
+	sentMsgs, updatedMsgs, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Ignored", twoMinutesLaterTimestamp, optionChangedMessage("blue jays eat acorn", "Alphonse", timestamp1))),
+	})
+
+	assert.Equal(t, 1, len(sentMsgs))
+	assert.Equal(t, 1, len(updatedMsgs))
+}
+
 // This shouldn't happen but if slack was sending invalid message timestamps (not float values), we
 // want to default to handling the message
 func TestMessageUpdatedHandledWhenUnableToCalculateAge(t *testing.T) {
@@ -1190,7 +1378,7 @@ func TestConcurrentProcessingOfNonRelatedMessages(t *testing.T) {
 }
 
 func TestSlackMessageIDStringer(t *testing.T) {
-	assert.Equal(t, "channel/2324", SlackMessageID{"channel", "2324"}.String())
+	assert.Equal(t, "channel/2324", SlackMessageID{channelID: "channel", timestamp: "2324"}.String())
 }
 
 func newRTMMessageEvent(msgEvent *slack.MessageEvent) (e slack.RTMEvent) {
@@ -1233,6 +1421,10 @@ func runSlackscotWithIncomingEventsWithLogs(t *testing.T, v *viper.Viper, plugin
 }
 
 func runSlackscotWithIncomingEvents(t *testing.T, v *viper.Viper, plugin *Plugin, events []slack.RTMEvent, slackTestServer *slacktest.Server, options ...Option) (sentMessages []sentMessage, updatedMsgs []updatedMessage, deletedMsgs []deletedMessage, rtmSenderCaptor *capture.RealTimeSenderCaptor) {
+	return runSlackscotWithPluginsAndIncomingEvents(t, v, []*Plugin{plugin}, events, slackTestServer, options...)
+}
+
+func runSlackscotWithPluginsAndIncomingEvents(t *testing.T, v *viper.Viper, plugins []*Plugin, events []slack.RTMEvent, slackTestServer *slacktest.Server, options ...Option) (sentMessages []sentMessage, updatedMsgs []updatedMessage, deletedMsgs []deletedMessage, rtmSenderCaptor *capture.RealTimeSenderCaptor) {
 	if v == nil {
 		v = config.NewViperWithDefaults()
 		v.Set(config.MessageProcessingPartitionCount, 1)
@@ -1249,7 +1441,9 @@ func runSlackscotWithIncomingEvents(t *testing.T, v *viper.Viper, plugin *Plugin
 	options = append(options, OptionTestMode(termination))
 	s, err := New("chickadee", v, options...)
 
-	s.RegisterPlugin(plugin)
+	for _, p := range plugins {
+		s.RegisterPlugin(p)
+	}
 
 	assert.Nil(t, err)
 
@@ -1257,17 +1451,20 @@ func runSlackscotWithIncomingEvents(t *testing.T, v *viper.Viper, plugin *Plugin
 	assert.Nil(t, err)
 
 	// Start the scheduler, it is up to the test to wait enough time to make sure scheduled actions run
+	require.NoError(t, s.loadScheduledActions())
 	go s.startActionScheduler(timeLoc)
 
 	ec := make(chan slack.RTMEvent)
 
 	var sc *slack.Client
+	var channelInfoFinder ChannelInfoFinder
 	if slackTestServer != nil {
 		sc = slack.New("", slack.OptionAPIURL(slackTestServer.GetAPIURL()))
 		require.NotNil(t, sc)
+		channelInfoFinder = NewChannelInfoFinderWithTelemetry(sc, "chickadee", s.instrumenter.meter)
 	}
 
-	go s.runInternal(ec, &runDependencies{chatDriver: &inMemoryChatDriver, userInfoFinder: &userInfoFinder, emojiReactor: &emojiReactor, selfInfoFinder: &selfFinder, realTimeMsgSender: rtmSenderCaptor, slackClient: sc})
+	go s.runInternal(ec, &runDependencies{chatDriver: &inMemoryChatDriver, userInfoFinder: &userInfoFinder, channelInfoFinder: channelInfoFinder, emojiReactor: &emojiReactor, selfInfoFinder: &selfFinder, realTimeMsgSender: rtmSenderCaptor, slackClient: sc})
 
 	go sendTestEventsForProcessing(ec, events)
 
@@ -1323,3 +1520,335 @@ func TestCommandMatcherOverrideWithNamespace(t *testing.T) {
 		assert.Equal(t, "true", vals.Get("as_user"))
 	}
 }
+
+func TestCommandPrefixFromConfigAlongsideMentions(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.CommandPrefixKey, "!")
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "!noRules make something nice", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s noRules make something nicer", formattedBotUserID), "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "noRules make something ignored", "Alphonse", "1546833215.036900")),
+	}, nil)
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		for _, sentMsg := range sentMsgs {
+			assert.Equal(t, "Cgeneral", sentMsg.channelID)
+
+			vals := applySlackOptions(sentMsg.msgOptions...)
+			assert.Equal(t, "<@Alphonse>: Make it yourself, @Alphonse", vals.Get("text"))
+		}
+	}
+}
+
+func TestHelpUsesConfigCommandPrefix(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.CommandPrefixKey, "!")
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "!help", "Alphonse", timestamp1)),
+	}, nil)
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Contains(t, vals.Get("text"), "• `!noRules make `<something>``")
+	}
+}
+
+func TestIncomingMessageResolvesUserInfo(t *testing.T) {
+	p := &Plugin{Name: "whoami", Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "whoami")
+		},
+		Usage:       "whoami",
+		Description: "Tells you who you are",
+		Answer: func(m *IncomingMessage) *Answer {
+			user, err := m.UserInfo()
+			if err != nil {
+				return &Answer{Text: err.Error()}
+			}
+
+			return &Answer{Text: fmt.Sprintf("You're %s", user.RealName)}
+		},
+	}}}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, p, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "whoami", "Alphonse", timestamp1)),
+	}, nil)
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "You're Daniel Quinn", vals.Get("text"))
+	}
+}
+
+func newSchedulingTestPlugin(opts ...AnswerOption) (p *Plugin) {
+	return &Plugin{Name: "later", Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "remind me")
+		},
+		Usage:       "remind me",
+		Description: "Reminds you later",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Here's your reminder", Options: opts}
+		},
+	}}}
+}
+
+func TestScheduledAnswer(t *testing.T) {
+	deliverAt := time.Now().Add(time.Hour)
+
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newSchedulingTestPlugin(AnswerDeliveredAt(deliverAt)), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s remind me", formattedBotUserID), "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s remind me", formattedBotUserID), "Ignored", timestamp2, optionChangedMessage(fmt.Sprintf("%s remind me please", formattedBotUserID), "Alphonse", timestamp1))),
+	}, nil, OptionNoPluginNamespacing())
+
+	// Scheduled answers can't be updated and the edit shouldn't result in the answer being scheduled a second time
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		endpoint, vals, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", sentMsgs[0].msgOptions...)
+		require.NoError(t, err)
+
+		assert.Equal(t, "https://slack.com/api/chat.scheduleMessage", endpoint)
+		assert.Equal(t, strconv.FormatInt(deliverAt.Unix(), 10), vals.Get("post_at"))
+		assert.Equal(t, "<@Alphonse>: Here's your reminder", vals.Get("text"))
+	}
+
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+}
+
+func TestScheduledAnswerNotDeletedWithTriggeringMessage(t *testing.T) {
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newSchedulingTestPlugin(AnswerWithDelay(time.Hour)), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s remind me", formattedBotUserID), "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Alphonse", timestamp2, optionDeletedMessage("Cgeneral", timestamp1))),
+	}, nil, OptionNoPluginNamespacing())
+
+	assert.Equal(t, 1, len(sentMsgs))
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+}
+
+func TestScheduledAnswerInThePastDeliveredImmediately(t *testing.T) {
+	tests := map[string]AnswerOption{
+		"DeliveredAtPastTime": AnswerDeliveredAt(time.Now().Add(-time.Minute)),
+		"ZeroDelay":           AnswerWithDelay(0),
+		"NegativeDelay":       AnswerWithDelay(-time.Minute),
+	}
+
+	for name, opt := range tests {
+		t.Run(name, func(t *testing.T) {
+			sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newSchedulingTestPlugin(opt), []slack.RTMEvent{
+				newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s remind me", formattedBotUserID), "Alphonse", timestamp1)),
+			}, nil, OptionNoPluginNamespacing())
+
+			if assert.Equal(t, 1, len(sentMsgs)) {
+				endpoint, vals, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", sentMsgs[0].msgOptions...)
+				require.NoError(t, err)
+
+				assert.Equal(t, "https://slack.com/api/chat.postMessage", endpoint)
+				assert.Equal(t, "", vals.Get("post_at"))
+			}
+		})
+	}
+}
+
+func TestScheduledEphemeralAnswerDeliveredImmediately(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newSchedulingTestPlugin(AnswerWithDelay(time.Hour), AnswerEphemeral("Alphonse")), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s remind me", formattedBotUserID), "Alphonse", timestamp1)),
+	}, nil, OptionNoPluginNamespacing())
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		endpoint, vals, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", sentMsgs[0].msgOptions...)
+		require.NoError(t, err)
+
+		assert.Equal(t, "https://slack.com/api/chat.postEphemeral", endpoint)
+		assert.Equal(t, "", vals.Get("post_at"))
+	}
+}
+
+func newNormalizingNamespacedTestPlugin() (p *Plugin) {
+	return &Plugin{Name: "KarmaBot", NamespaceCommands: true, NormalizeCommands: true, Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "echo ")
+		},
+		Usage:       "echo <something>",
+		Description: "Echoes what you said",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: m.NormalizedText}
+		},
+	}}}
+}
+
+func TestNormalizedCommandsWithNamespace(t *testing.T) {
+	tests := map[string]struct {
+		text         string
+		expectedText string
+	}{
+		"SameCaseNamespace":  {text: fmt.Sprintf("%s KarmaBot echo Hello World!", formattedBotUserID), expectedText: "<@Alphonse>: echo Hello World!"},
+		"LowerCaseNamespace": {text: fmt.Sprintf("%s karmabot ECHO Hello World!", formattedBotUserID), expectedText: "<@Alphonse>: ECHO Hello World!"},
+		"UpperCaseNamespace": {text: fmt.Sprintf("%s KARMABOT   Echo Hi?", formattedBotUserID), expectedText: "<@Alphonse>: Echo Hi?"},
+		"OtherNamespace":     {text: fmt.Sprintf("%s otherbot echo Hi", formattedBotUserID), expectedText: "<@Alphonse>: I don't understand. Ask me for \"help\" to get a list of things I do"},
+		"NamespaceOnly":      {text: fmt.Sprintf("%s karmabot", formattedBotUserID), expectedText: "<@Alphonse>: I don't understand. Ask me for \"help\" to get a list of things I do"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newNormalizingNamespacedTestPlugin(), []slack.RTMEvent{
+				newRTMMessageEvent(newMessageEvent("Cgeneral", tc.text, "Alphonse", timestamp1)),
+			}, nil)
+
+			if assert.Equal(t, 1, len(sentMsgs)) {
+				vals := applySlackOptions(sentMsgs[0].msgOptions...)
+				assert.Equal(t, tc.expectedText, vals.Get("text"))
+			}
+		})
+	}
+}
+
+func TestCommandPrefixOptionTakesPrecedenceOverConfig(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.CommandPrefixKey, "!")
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "!!noRules make something nice", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "!noRules make something ignored", "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s noRules make something ignored", formattedBotUserID), "Alphonse", "1546833215.036900")),
+	}, nil, OptionCommandPrefix("!!"))
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: Make it yourself, @Alphonse", vals.Get("text"))
+	}
+}
+
+func TestIncomingMessageResolvesChannelInfo(t *testing.T) {
+	handler := func(c slacktest.Customize) {
+		c.Handle("/conversations.info", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"ok": true, "channel": {"id": "Cgeneral", "name": "general", "is_channel": true}}`))
+		})
+	}
+
+	testServer := slacktest.NewTestServer(handler)
+	testServer.Start()
+	defer testServer.Stop()
+
+	p := &Plugin{Name: "whereami", Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "where am i")
+		},
+		Usage:       "where am i",
+		Description: "Tells you which channel you're on",
+		Answer: func(m *IncomingMessage) *Answer {
+			channel, err := m.ChannelInfo()
+			if err != nil {
+				return &Answer{Text: err.Error()}
+			}
+
+			return &Answer{Text: fmt.Sprintf("You're on #%s", channel.Name)}
+		},
+	}}}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, p, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s where am i", formattedBotUserID), "Alphonse", timestamp1)),
+	}, testServer)
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: You're on #general", vals.Get("text"))
+	}
+}
+
+func newCountingPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "counter"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "count to ")
+		},
+		Usage:       "count to <n>",
+		Description: "Count along, one message at a time",
+		AnswerAll: func(m *IncomingMessage) (answers []*Answer) {
+			n, _ := strconv.Atoi(strings.TrimPrefix(m.NormalizedText, "count to "))
+			for i := 1; i <= n; i++ {
+				answers = append(answers, &Answer{Text: strconv.Itoa(i)})
+			}
+
+			return answers
+		},
+	}}
+
+	return p
+}
+
+func TestMultipleAnswersSentAndTrackedSeparately(t *testing.T) {
+	sentMsgs, updatedMsgs, deletedMsgs, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newCountingPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "count to 3", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "count to 3", "Ignored", timestamp2, optionChangedMessage("count to 2", "Alphonse", timestamp1))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Alphonse", timestamp2, optionDeletedMessage("Cgeneral", timestamp1))),
+	})
+
+	if assert.Len(t, sentMsgs, 3) {
+		for i, m := range sentMsgs {
+			assert.Equal(t, strconv.Itoa(i+1), applySlackOptions(m.msgOptions...).Get("text"))
+		}
+	}
+
+	if assert.Len(t, updatedMsgs, 2) {
+		assert.Equal(t, formatTimestamp(firstReplyTimestamp), updatedMsgs[0].timestamp)
+		assert.Equal(t, "1", applySlackOptions(updatedMsgs[0].msgOptions...).Get("text"))
+		assert.Equal(t, formatTimestamp(firstReplyTimestamp+replyTimeIncrementInSeconds), updatedMsgs[1].timestamp)
+		assert.Equal(t, "2", applySlackOptions(updatedMsgs[1].msgOptions...).Get("text"))
+	}
+
+	// The third answer isn't triggered anymore after the edit and the updated answers go away with the deleted message
+	if assert.Len(t, deletedMsgs, 3) {
+		assert.Equal(t, deletedMessage{channelID: "Cgeneral", timestamp: formatTimestamp(firstReplyTimestamp + 2*replyTimeIncrementInSeconds)}, deletedMsgs[0])
+		assert.ElementsMatch(t, []deletedMessage{{channelID: "Cgeneral", timestamp: formatTimestamp(firstReplyTimestamp + 3*replyTimeIncrementInSeconds)}, {channelID: "Cgeneral", timestamp: formatTimestamp(firstReplyTimestamp + 4*replyTimeIncrementInSeconds)}}, deletedMsgs[1:])
+	}
+}
+
+func newSlowPlugin(ctxErrs chan error) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "slowpoke"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "slow")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			<-m.Context().Done()
+			ctxErrs <- m.Context().Err()
+
+			return &Answer{Text: "Sorry, what was the question?"}
+		},
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "fast")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("Done before the deadline: %t", m.Context().Err() == nil)}
+		},
+	}}
+
+	return p
+}
+
+func TestActionTimeoutDropsSlowAnswers(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionTimeoutKey, 50*time.Millisecond)
+
+	ctxErrs := make(chan error, 1)
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, v, newSlowPlugin(ctxErrs), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "slow and steady", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "fast and furious", "Alphonse", timestamp2)),
+	})
+
+	assert.Equal(t, context.DeadlineExceeded, <-ctxErrs)
+	assert.Contains(t, logs, "Warning: action [slowpoke.hearAction[0]] didn't answer within [50ms] (context deadline exceeded), dropping its answer")
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, "Done before the deadline: true", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+	}
+}
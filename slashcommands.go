@@ -0,0 +1,199 @@
+package slackscot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// SlashCommandPath is the path slack sends slash command requests to (the request URL of the slack app's slash
+	// commands). It's served by the webhook server so slash commands are only handled when config.WebhookListenAddressKey is set
+	SlashCommandPath = "/slack/commands"
+
+	slashCommandResponseTypeInChannel = "in_channel"
+	slashCommandResponseTypeEphemeral = "ephemeral"
+)
+
+// SlashCommandDefinition represents a slash command (i.e. /weather) handled by a plugin. Slash commands need to be
+// created in the slack app with SlashCommandPath as their request URL
+type SlashCommandDefinition struct {
+	// Indicates whether the slash command should be omitted from the help message
+	Hidden bool
+
+	// Command, including the leading slash (i.e. /weather)
+	Command string
+
+	// Usage example of the arguments, if any
+	Usage string
+
+	// Help description for the slash command
+	Description string
+
+	// Function to execute when the slash command is invoked
+	Answer SlashCommandAnswerer
+}
+
+// SlashCommand is an invocation of a slash command
+type SlashCommand struct {
+	slack.SlashCommand
+
+	// RespondLater sends a delayed response to the slash command. Slack accepts up to 5 of those within 30 minutes
+	// of the invocation which is useful for answers that take longer than the 3 seconds slack waits for the immediate one
+	RespondLater func(answer *SlashCommandAnswer) (err error)
+}
+
+// SlashCommandAnswer holds the response to a slash command. By default, it's only visible to the user who invoked it
+type SlashCommandAnswer struct {
+	Text string
+
+	// Optional block kit content blocks
+	ContentBlocks []slack.Block
+
+	// InChannel makes the response visible to everyone in the channel rather than only to the user who invoked the command
+	InChannel bool
+}
+
+// SlashCommandAnswerer is what gets executed when a slash command is invoked. The answer returned is sent immediately
+// as the response to the invocation. A nil answer only acknowledges it, which is what answerers sending delayed
+// responses with RespondLater typically do
+type SlashCommandAnswerer func(cmd *SlashCommand) *SlashCommandAnswer
+
+// slashCommandAnswerer is a plugin's answerer of a slash command
+type slashCommandAnswerer struct {
+	plugin string
+	answer SlashCommandAnswerer
+}
+
+// newSlashCommandHandler creates the http handler routing slash command requests to the plugins. Requests must be
+// signed with the slack app's signing secret. A nil handler is returned if no plugin has slash commands and an error
+// is returned if two plugins have the same slash command or if the signing secret is missing
+func newSlashCommandHandler(plugins []*Plugin, signingSecret string, logger SLogger) (handler http.Handler, err error) {
+	answerers := make(map[string]slashCommandAnswerer)
+
+	for _, p := range plugins {
+		for _, sc := range p.SlashCommands {
+			if existing, ok := answerers[sc.Command]; ok {
+				return nil, fmt.Errorf("Duplicate slash command [%s] for plugin [%s], already registered by plugin [%s]", sc.Command, p.Name, existing.plugin)
+			}
+
+			logger.Debugf("Registering slash command [%s] for plugin [%s]\n", sc.Command, p.Name)
+			answerers[sc.Command] = slashCommandAnswerer{plugin: p.Name, answer: sc.Answer}
+		}
+	}
+
+	if len(answerers) == 0 {
+		return nil, nil
+	}
+
+	if signingSecret == "" {
+		return nil, fmt.Errorf("Missing signing secret, required to verify slash command requests")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd, status, err := parseSlashCommand(r, signingSecret)
+		if err != nil {
+			logger.Printf("Rejecting slash command request: %v", err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		answerer, ok := answerers[cmd.Command]
+		if !ok {
+			logger.Printf("Received unknown slash command [%s]", cmd.Command)
+			writeSlashCommandAnswer(w, &SlashCommandAnswer{Text: fmt.Sprintf("I don't know the `%s` command :thinking_face:", cmd.Command)})
+			return
+		}
+
+		logger.Debugf("Routing slash command [%s] to plugin [%s]\n", cmd.Command, answerer.plugin)
+		responseURL := cmd.ResponseURL
+		answer := answerer.answer(&SlashCommand{SlashCommand: cmd, RespondLater: func(answer *SlashCommandAnswer) (err error) {
+			return sendDelayedSlashCommandAnswer(responseURL, answer)
+		}})
+
+		if answer == nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		writeSlashCommandAnswer(w, answer)
+	}), nil
+}
+
+// parseSlashCommand verifies that a slash command request is signed with the slack app's signing secret and parses it.
+// On error, the http status to respond with is returned along with the error
+func parseSlashCommand(r *http.Request, signingSecret string) (cmd slack.SlashCommand, status int, err error) {
+	body, status, err := readVerifiedBody(r, signingSecret)
+	if err != nil {
+		return cmd, status, err
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if cmd, err = slack.SlashCommandParse(r); err != nil {
+		return cmd, http.StatusBadRequest, err
+	}
+
+	return cmd, http.StatusOK, nil
+}
+
+// readVerifiedBody reads the body of a request from slack and verifies that it's signed with the slack app's signing
+// secret. On error, the http status to respond with is returned along with the error
+func readVerifiedBody(r *http.Request, signingSecret string) (body []byte, status int, err error) {
+	body, err = ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+	if err == nil {
+		verifier.Write(body)
+		err = verifier.Ensure()
+	}
+
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	return body, http.StatusOK, nil
+}
+
+// newSlashCommandMsg returns the slack message of a slash command answer
+func newSlashCommandMsg(answer *SlashCommandAnswer) (msg slack.Msg) {
+	msg.Text = answer.Text
+	msg.Blocks = slack.Blocks{BlockSet: answer.ContentBlocks}
+	msg.ResponseType = slashCommandResponseTypeEphemeral
+	if answer.InChannel {
+		msg.ResponseType = slashCommandResponseTypeInChannel
+	}
+
+	return msg
+}
+
+// writeSlashCommandAnswer writes the answer as the immediate response to a slash command
+func writeSlashCommandAnswer(w http.ResponseWriter, answer *SlashCommandAnswer) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newSlashCommandMsg(answer))
+}
+
+// sendDelayedSlashCommandAnswer sends the answer as a delayed response to a slash command
+func sendDelayedSlashCommandAnswer(responseURL string, answer *SlashCommandAnswer) (err error) {
+	body, err := json.Marshal(newSlashCommandMsg(answer))
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error sending delayed slash command response: [%s]", resp.Status)
+	}
+
+	return nil
+}
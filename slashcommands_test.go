@@ -0,0 +1,175 @@
+package slackscot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSigningSecret = "e6b19c573432dcc6b075501d51b51bb8"
+
+func newSlashCommandRequest(signingSecret string, command string, text string, responseURL string) (r *http.Request) {
+	body := url.Values{"command": {command}, "text": {text}, "user_id": {"U123"}, "channel_id": {"C123"}, "response_url": {responseURL}}.Encode()
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, body)))
+
+	r = httptest.NewRequest("POST", SlashCommandPath, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	return r
+}
+
+func newSlashCommandPlugin(name string, commands ...SlashCommandDefinition) (p *Plugin) {
+	return &Plugin{Name: name, SlashCommands: commands}
+}
+
+func newEchoSlashCommand(command string, inChannel bool) SlashCommandDefinition {
+	return SlashCommandDefinition{Command: command, Answer: func(cmd *SlashCommand) *SlashCommandAnswer {
+		return &SlashCommandAnswer{Text: fmt.Sprintf("%s from <@%s>", cmd.Text, cmd.UserID), InChannel: inChannel}
+	}}
+}
+
+func decodeSlashCommandMsg(t *testing.T, body []byte) (msg slack.Msg) {
+	require.NoError(t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+func TestSlashCommandRouting(t *testing.T) {
+	handler, err := newSlashCommandHandler([]*Plugin{newSlashCommandPlugin("echo", newEchoSlashCommand("/echo", false)), newSlashCommandPlugin("shout", newEchoSlashCommand("/shout", true))}, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		command              string
+		expectedText         string
+		expectedResponseType string
+	}{
+		{"/echo", "hello from <@U123>", "ephemeral"},
+		{"/shout", "hello from <@U123>", "in_channel"},
+		{"/whisper", "I don't know the `/whisper` command :thinking_face:", "ephemeral"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.command, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, newSlashCommandRequest(testSigningSecret, tc.command, "hello", ""))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			msg := decodeSlashCommandMsg(t, rec.Body.Bytes())
+			assert.Equal(t, tc.expectedText, msg.Text)
+			assert.Equal(t, tc.expectedResponseType, msg.ResponseType)
+		})
+	}
+}
+
+func TestSlashCommandDelayedResponse(t *testing.T) {
+	responses := make(chan slack.Msg, 1)
+	responseServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		responses <- decodeSlashCommandMsg(t, body)
+	}))
+	defer responseServer.Close()
+
+	slow := SlashCommandDefinition{Command: "/slow", Answer: func(cmd *SlashCommand) *SlashCommandAnswer {
+		go func() {
+			assert.NoError(t, cmd.RespondLater(&SlashCommandAnswer{Text: "done with " + cmd.Text, InChannel: true}))
+		}()
+
+		return nil
+	}}
+
+	handler, err := newSlashCommandHandler([]*Plugin{newSlashCommandPlugin("slow", slow)}, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newSlashCommandRequest(testSigningSecret, "/slow", "chores", responseServer.URL))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	select {
+	case msg := <-responses:
+		assert.Equal(t, "done with chores", msg.Text)
+		assert.Equal(t, "in_channel", msg.ResponseType)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Timed out waiting for the delayed response")
+	}
+}
+
+func TestSlashCommandWithInvalidSignature(t *testing.T) {
+	handler, err := newSlashCommandHandler([]*Plugin{newSlashCommandPlugin("echo", newEchoSlashCommand("/echo", false))}, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newSlashCommandRequest("not the secret", "/echo", "hello", ""))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", SlashCommandPath, strings.NewReader("command=/echo")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestSlashCommandDuplicates(t *testing.T) {
+	_, err := newSlashCommandHandler([]*Plugin{newSlashCommandPlugin("echo", newEchoSlashCommand("/echo", false)), newSlashCommandPlugin("parrot", newEchoSlashCommand("/echo", true))}, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.EqualError(t, err, "Duplicate slash command [/echo] for plugin [parrot], already registered by plugin [echo]")
+}
+
+func TestSlashCommandHandlerWithoutSigningSecret(t *testing.T) {
+	_, err := newSlashCommandHandler([]*Plugin{newSlashCommandPlugin("echo", newEchoSlashCommand("/echo", false))}, "", NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.EqualError(t, err, "Missing signing secret, required to verify slash command requests")
+
+	handler, err := newSlashCommandHandler([]*Plugin{newWebhookPlugin("ci", "build")}, "", NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.NoError(t, err)
+	assert.Nil(t, handler)
+}
+
+func TestWebhookServerRoutesSlashCommandsWithoutWebhookSecret(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	webhookPlugin := newWebhookPlugin("ci", "build")
+	webhookPlugin.SlashCommands = []SlashCommandDefinition{newEchoSlashCommand("/echo", false)}
+	s.RegisterPlugin(webhookPlugin)
+
+	require.NoError(t, s.newWebhookServer(":0", "webhookSecret", testSigningSecret))
+
+	rec := httptest.NewRecorder()
+	s.webhookServer.Handler.ServeHTTP(rec, newSlashCommandRequest(testSigningSecret, "/echo", "hello", ""))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello from <@U123>", decodeSlashCommandMsg(t, rec.Body.Bytes()).Text)
+
+	rec = httptest.NewRecorder()
+	s.webhookServer.Handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhooks/ci/build", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestWebhookServerServesAssetsWithoutWebhookSecret(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	require.NoError(t, s.newWebhookServer(":0", "webhookSecret", ""))
+
+	rec := httptest.NewRecorder()
+	s.webhookServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/assets/trophy", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+}
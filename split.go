@@ -0,0 +1,138 @@
+package slackscot
+
+import (
+	"github.com/slack-go/slack"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of slack messages. Longer answers are split into parts sent as separate messages (see splitOutgoingMessages)
+const (
+	// maxMessageTextLength is the maximum length (in characters) of the text of a message
+	maxMessageTextLength = 4000
+
+	// maxMessageBlocks is the maximum number of blocks of a message
+	maxMessageBlocks = 50
+)
+
+// splitOutgoingMessages splits the outgoing messages that exceed slack's limits into parts. Each part immediately
+// follows the part before it
+func splitOutgoingMessages(outMsgs []OutgoingMessage) (split []OutgoingMessage) {
+	split = make([]OutgoingMessage, 0, len(outMsgs))
+
+	for _, o := range outMsgs {
+		split = append(split, splitOutgoingMessage(o)...)
+	}
+
+	return split
+}
+
+// splitOutgoingMessage splits an outgoing message into parts that each fit within slack's limits. Messages with
+// content blocks are split by blocks (their text being only a notification fallback, it's truncated) while messages
+// without blocks are split by text. Interactive elements always go with the last part
+func splitOutgoingMessage(o OutgoingMessage) (parts []OutgoingMessage) {
+	if len(o.ContentBlocks) > 0 {
+		return splitOutgoingMessageBlocks(o)
+	}
+
+	chunks := splitText(o.OutgoingMessage.Text, maxMessageTextLength)
+	if len(chunks) == 1 {
+		return []OutgoingMessage{o}
+	}
+
+	parts = make([]OutgoingMessage, len(chunks))
+	for i, chunk := range chunks {
+		parts[i] = newOutgoingMessagePart(o, i, chunk, nil, i == len(chunks)-1)
+	}
+
+	return parts
+}
+
+// splitOutgoingMessageBlocks splits an outgoing message with content blocks into parts of at most maxMessageBlocks blocks
+// (counting the block of the interactive elements, if any)
+func splitOutgoingMessageBlocks(o OutgoingMessage) (parts []OutgoingMessage) {
+	blockCount := len(o.ContentBlocks)
+	if len(o.InteractiveElements) > 0 {
+		blockCount = blockCount + 1
+	}
+
+	if blockCount <= maxMessageBlocks && utf8.RuneCountInString(o.OutgoingMessage.Text) <= maxMessageTextLength {
+		return []OutgoingMessage{o}
+	}
+
+	text := splitText(o.OutgoingMessage.Text, maxMessageTextLength)[0]
+	partCount := (blockCount + maxMessageBlocks - 1) / maxMessageBlocks
+
+	parts = make([]OutgoingMessage, partCount)
+	for i := range parts {
+		start := i * maxMessageBlocks
+		end := start + maxMessageBlocks
+		if end > len(o.ContentBlocks) {
+			end = len(o.ContentBlocks)
+		}
+
+		// The last part might only hold the interactive elements
+		var blocks []slack.Block
+		if start < end {
+			blocks = o.ContentBlocks[start:end]
+		}
+
+		parts[i] = newOutgoingMessagePart(o, i, text, blocks, i == partCount-1)
+	}
+
+	return parts
+}
+
+// newOutgoingMessagePart returns the part of an outgoing message with the given text and content blocks. The interactive
+// elements are only kept in the last part
+func newOutgoingMessagePart(o OutgoingMessage, part int, text string, blocks []slack.Block, last bool) (p OutgoingMessage) {
+	p = o
+	p.part = part
+	p.OutgoingMessage.Text = text
+	p.ContentBlocks = blocks
+	if part > 0 {
+		p.Reactions = nil
+	}
+
+	if !last {
+		p.InteractiveElements = nil
+		p.Progress = nil
+	}
+
+	return p
+}
+
+// splitText splits text into chunks of at most maxLength characters, preferably at line breaks and otherwise at spaces
+// (which are dropped). Words longer than maxLength are cut
+func splitText(text string, maxLength int) (chunks []string) {
+	for utf8.RuneCountInString(text) > maxLength {
+		limit := byteOffset(text, maxLength)
+
+		cut := strings.LastIndex(text[:limit+1], "\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:limit+1], " ")
+		}
+
+		if cut <= 0 {
+			chunks = append(chunks, text[:limit])
+			text = text[limit:]
+		} else {
+			chunks = append(chunks, text[:cut])
+			text = text[cut+1:]
+		}
+	}
+
+	return append(chunks, text)
+}
+
+// byteOffset returns the byte offset of the character at the given index in text
+func byteOffset(text string, index int) (offset int) {
+	for offset = range text {
+		if index == 0 {
+			return offset
+		}
+		index--
+	}
+
+	return len(text)
+}
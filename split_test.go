@@ -0,0 +1,136 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := map[string]struct {
+		text           string
+		maxLength      int
+		expectedChunks []string
+	}{
+		"Short":            {text: "hello", maxLength: 10, expectedChunks: []string{"hello"}},
+		"ExactLength":      {text: "0123456789", maxLength: 10, expectedChunks: []string{"0123456789"}},
+		"AtLineBreaks":     {text: "first line\nsecond one\nthird", maxLength: 22, expectedChunks: []string{"first line\nsecond one", "third"}},
+		"AtSpaces":         {text: "some words to split", maxLength: 10, expectedChunks: []string{"some words", "to split"}},
+		"LineBreakAtLimit": {text: "0123456789\nabc", maxLength: 10, expectedChunks: []string{"0123456789", "abc"}},
+		"LongWordCut":      {text: "abcdefghijklmnopqrstuvwxyz", maxLength: 10, expectedChunks: []string{"abcdefghij", "klmnopqrst", "uvwxyz"}},
+		"MultiByte":        {text: "🍕🍕🍕🍕🍕 éééé", maxLength: 6, expectedChunks: []string{"🍕🍕🍕🍕🍕", "éééé"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedChunks, splitText(tc.text, tc.maxLength))
+		})
+	}
+}
+
+func newSectionBlocks(count int) (blocks []slack.Block) {
+	for i := 0; i < count; i++ {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("block %d", i), false, false), nil, nil))
+	}
+
+	return blocks
+}
+
+func TestOutgoingMessagesWithinLimitsNotSplit(t *testing.T) {
+	o := OutgoingMessage{OutgoingMessage: newSlackOutgoingMessage("Cgeneral", "hello"), pluginActionID: "maker.command[0]", Answer: Answer{ContentBlocks: newSectionBlocks(maxMessageBlocks)}}
+
+	assert.Equal(t, []OutgoingMessage{o}, splitOutgoingMessages([]OutgoingMessage{o}))
+}
+
+func TestOutgoingMessageSplitByText(t *testing.T) {
+	button := slack.NewButtonBlockElement("yes", "pizza", slack.NewTextBlockObject(slack.PlainTextType, "Yes", false, false))
+	text := strings.Repeat("a", maxMessageTextLength) + "\n" + "the rest"
+	o := OutgoingMessage{OutgoingMessage: newSlackOutgoingMessage("Cgeneral", text), pluginActionID: "maker.command[0]", Answer: Answer{InteractiveElements: []slack.BlockElement{button}}}
+
+	parts := splitOutgoingMessages([]OutgoingMessage{o})
+	if assert.Len(t, parts, 2) {
+		assert.Equal(t, strings.Repeat("a", maxMessageTextLength), parts[0].OutgoingMessage.Text)
+		assert.Empty(t, parts[0].InteractiveElements)
+		assert.Equal(t, "maker.command[0]", parts[0].responseKey())
+
+		assert.Equal(t, "the rest", parts[1].OutgoingMessage.Text)
+		assert.Len(t, parts[1].InteractiveElements, 1)
+		assert.Equal(t, "maker.command[0].part[1]", parts[1].responseKey())
+	}
+}
+
+func TestOutgoingMessageSplitByBlocks(t *testing.T) {
+	button := slack.NewButtonBlockElement("yes", "pizza", slack.NewTextBlockObject(slack.PlainTextType, "Yes", false, false))
+	blocks := newSectionBlocks(2 * maxMessageBlocks)
+	o := OutgoingMessage{OutgoingMessage: newSlackOutgoingMessage("Cgeneral", strings.Repeat("b", maxMessageTextLength+10)), pluginActionID: "maker.command[0]", answerIndex: 1,
+		Answer: Answer{ContentBlocks: blocks, InteractiveElements: []slack.BlockElement{button}}}
+
+	parts := splitOutgoingMessages([]OutgoingMessage{o})
+	if assert.Len(t, parts, 3) {
+		assert.Equal(t, blocks[:maxMessageBlocks], parts[0].ContentBlocks)
+		assert.Equal(t, blocks[maxMessageBlocks:], parts[1].ContentBlocks)
+		assert.Empty(t, parts[1].InteractiveElements)
+
+		// The interactive block doesn't fit with the last content blocks so it's alone in the last part
+		assert.Empty(t, parts[2].ContentBlocks)
+		assert.Len(t, answerBlocks(parts[2]), 1)
+		assert.Equal(t, "maker.command[0]#1.part[2]", parts[2].responseKey())
+
+		for _, p := range parts {
+			assert.Equal(t, strings.Repeat("b", maxMessageTextLength), p.OutgoingMessage.Text)
+		}
+	}
+}
+
+func newLongAnswerPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "lorem"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "lorem")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: strings.Repeat("lorem ipsum ", maxMessageTextLength/12) + "lorem ipsum"}
+		},
+	}}
+
+	return p
+}
+
+func TestLongAnswerSentAsThreadedParts(t *testing.T) {
+	sentMsgs, _, deletedMsgs, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newLongAnswerPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "lorem", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "lorem", "Ignored", timestamp2, optionDeletedMessage("Cgeneral", timestamp1))),
+	})
+
+	if assert.Len(t, sentMsgs, 2) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, strings.TrimSpace(strings.Repeat("lorem ipsum ", maxMessageTextLength/12)), vals.Get("text"))
+		assert.Equal(t, "", vals.Get("thread_ts"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "lorem ipsum", vals.Get("text"))
+		assert.Equal(t, formatTimestamp(firstReplyTimestamp), vals.Get("thread_ts"))
+		assert.Equal(t, "", vals.Get("reply_broadcast"))
+	}
+
+	// Both parts are deleted along with the triggering message
+	assert.ElementsMatch(t, []deletedMessage{{channelID: "Cgeneral", timestamp: formatTimestamp(firstReplyTimestamp)}, {channelID: "Cgeneral", timestamp: formatTimestamp(firstReplyTimestamp + replyTimeIncrementInSeconds)}}, deletedMsgs)
+}
+
+func TestLongAnswerPartsThreadedInExistingThread(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ThreadedRepliesKey, true)
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, v, newLongAnswerPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "lorem", "Alphonse", timestamp1)),
+	})
+
+	if assert.Len(t, sentMsgs, 2) {
+		assert.Equal(t, timestamp1, applySlackOptions(sentMsgs[0].msgOptions...).Get("thread_ts"))
+		assert.Equal(t, timestamp1, applySlackOptions(sentMsgs[1].msgOptions...).Get("thread_ts"))
+	}
+}
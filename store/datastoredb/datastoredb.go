@@ -3,6 +3,8 @@ package datastoredb
 import (
 	"cloud.google.com/go/datastore"
 	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
 	"google.golang.org/api/option"
 )
 
@@ -91,6 +93,10 @@ func (dsdb *DatastoreDB) GetSiloString(silo string, key string) (value string, e
 		dsdb.connect()
 	}
 
+	if err == datastore.ErrNoSuchEntity {
+		return "", fmt.Errorf("%s %w", key, store.ErrNotFound)
+	}
+
 	if err != nil {
 		return "", err
 	}
@@ -4,6 +4,7 @@ import (
 	"cloud.google.com/go/datastore"
 	"context"
 	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
@@ -179,6 +180,22 @@ func TestGetSiloString(t *testing.T) {
 	}
 }
 
+func TestGetMissingSiloStringIsNotFound(t *testing.T) {
+	mockDS := mockDatastore{}
+	defer mockDS.AssertExpectations(t)
+
+	mockDS.On("connect").Return(nil)
+	mockDS.On("Get", mock.Anything, datastore.NameKey(testEntityName, "testConnectivity", nil), mock.Anything).Return(datastore.ErrNoSuchEntity)
+	mockDS.On("Get", mock.Anything, newKeyWithNamespace("channelSilo", testEntityName, "renée"), mock.Anything).Return(datastore.ErrNoSuchEntity)
+
+	dsdb, err := newWithDatastorer(testEntityName, &mockDS)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dsdb) {
+		_, err := dsdb.GetSiloString("channelSilo", "renée")
+		assert.True(t, store.IsNotFound(err))
+	}
+}
+
 func TestReconnectOnGetFailure(t *testing.T) {
 	// Very importantly, we set up our mock to *not* return an error on repeated calls for the same key
 	mockDS := mockDatastore{returnNoErrOnRepeatedKey: true}
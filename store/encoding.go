@@ -0,0 +1,250 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"strings"
+)
+
+// Compression is the compression of values stored by an EncodedStorer
+type Compression byte
+
+// Compressions of values
+const (
+	NoCompression     Compression = 'n'
+	SnappyCompression Compression = 's'
+	ZstdCompression   Compression = 'z'
+)
+
+// Encoding is the encoding of values stored by an EncodedStorer
+type Encoding byte
+
+// Encodings of values
+const (
+	// RawEncoding stores (compressed) values as raw bytes. This suits storers accepting any bytes as values (like
+	// the leveldb storer)
+	RawEncoding Encoding = 'r'
+
+	// Base64Encoding stores (compressed) values as base64 text. This suits storers requiring values to be valid
+	// UTF-8 text (like the datastore storer)
+	Base64Encoding Encoding = 'b'
+)
+
+// encodedValueMarker starts the header of encoded values, which is followed by the compression and the encoding of
+// the value. Values without that header are read as they are
+const encodedValueMarker = "\x00"
+
+// ValueFormat is the compression and encoding of values
+type ValueFormat struct {
+	Compression Compression
+	Encoding    Encoding
+}
+
+// PlainFormat stores values as they are, which is how they're stored without an EncodedStorer
+var PlainFormat = ValueFormat{Compression: NoCompression, Encoding: RawEncoding}
+
+// EncodedStorer transparently compresses and encodes the values of a storer (i.e. for plugins storing large blobs
+// like journals or exports) according to the ValueFormat of their silo. Keys are left as they are.
+//
+// Reads are backwards-compatible: values written before compression was enabled (or with a different format) are
+// read as they were written given that encoded values carry their format in a short header
+type EncodedStorer struct {
+	GlobalSiloStringStorer
+
+	defaultFormat ValueFormat
+	siloFormats   map[string]ValueFormat
+	zstdEncoder   *zstd.Encoder
+	zstdDecoder   *zstd.Decoder
+}
+
+// EncodingOption defines an option for an EncodedStorer
+type EncodingOption func(es *EncodedStorer)
+
+// OptionDefaultValueFormat sets the format of values of silos without a format of their own (see
+// OptionSiloValueFormat). It's PlainFormat unless set
+func OptionDefaultValueFormat(format ValueFormat) EncodingOption {
+	return func(es *EncodedStorer) {
+		es.defaultFormat = format
+	}
+}
+
+// OptionSiloValueFormat sets the format of the values of a silo
+func OptionSiloValueFormat(silo string, format ValueFormat) EncodingOption {
+	return func(es *EncodedStorer) {
+		es.siloFormats[silo] = format
+	}
+}
+
+// NewEncodedStorer creates a new EncodedStorer compressing and encoding the values of the storer
+func NewEncodedStorer(storer GlobalSiloStringStorer, options ...EncodingOption) (es *EncodedStorer, err error) {
+	es = new(EncodedStorer)
+	es.GlobalSiloStringStorer = storer
+	es.defaultFormat = PlainFormat
+	es.siloFormats = make(map[string]ValueFormat)
+
+	for _, opt := range options {
+		opt(es)
+	}
+
+	if es.zstdEncoder, err = zstd.NewWriter(nil); err != nil {
+		return nil, err
+	}
+
+	if es.zstdDecoder, err = zstd.NewReader(nil); err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+// siloFormat returns the format of the values of a silo
+func (es *EncodedStorer) siloFormat(silo string) ValueFormat {
+	if format, ok := es.siloFormats[silo]; ok {
+		return format
+	}
+
+	return es.defaultFormat
+}
+
+// encode compresses and encodes a value in the format. Values in the PlainFormat are returned as they are
+func (es *EncodedStorer) encode(value string, format ValueFormat) (encoded string, err error) {
+	if format == PlainFormat {
+		return value, nil
+	}
+
+	var compressed []byte
+	switch format.Compression {
+	case NoCompression:
+		compressed = []byte(value)
+	case SnappyCompression:
+		compressed = snappy.Encode(nil, []byte(value))
+	case ZstdCompression:
+		compressed = es.zstdEncoder.EncodeAll([]byte(value), nil)
+	default:
+		return "", fmt.Errorf("Unsupported compression [%c]", format.Compression)
+	}
+
+	var payload string
+	switch format.Encoding {
+	case RawEncoding:
+		payload = string(compressed)
+	case Base64Encoding:
+		payload = base64.StdEncoding.EncodeToString(compressed)
+	default:
+		return "", fmt.Errorf("Unsupported encoding [%c]", format.Encoding)
+	}
+
+	return fmt.Sprintf("%s%c%c%s", encodedValueMarker, format.Compression, format.Encoding, payload), nil
+}
+
+// decode decodes and decompresses a value according to its header. Values without a header are returned as they are
+func (es *EncodedStorer) decode(encoded string) (value string, err error) {
+	if !strings.HasPrefix(encoded, encodedValueMarker) || len(encoded) < len(encodedValueMarker)+2 {
+		return encoded, nil
+	}
+
+	compression := Compression(encoded[len(encodedValueMarker)])
+	encoding := Encoding(encoded[len(encodedValueMarker)+1])
+	payload := encoded[len(encodedValueMarker)+2:]
+
+	var compressed []byte
+	switch encoding {
+	case RawEncoding:
+		compressed = []byte(payload)
+	case Base64Encoding:
+		if compressed, err = base64.StdEncoding.DecodeString(payload); err != nil {
+			return "", fmt.Errorf("Invalid base64 encoded value: %v", err)
+		}
+	default:
+		// Not a header after all
+		return encoded, nil
+	}
+
+	var decompressed []byte
+	switch compression {
+	case NoCompression:
+		decompressed = compressed
+	case SnappyCompression:
+		decompressed, err = snappy.Decode(nil, compressed)
+	case ZstdCompression:
+		decompressed, err = es.zstdDecoder.DecodeAll(compressed, nil)
+	default:
+		// Not a header after all
+		return encoded, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("Invalid compressed value: %v", err)
+	}
+
+	return string(decompressed), nil
+}
+
+// GetSiloString returns the decoded value of a key in a silo
+func (es *EncodedStorer) GetSiloString(silo string, key string) (value string, err error) {
+	encoded, err := es.GlobalSiloStringStorer.GetSiloString(silo, key)
+	if err != nil {
+		return "", err
+	}
+
+	return es.decode(encoded)
+}
+
+// PutSiloString encodes the value in the format of the silo and stores it
+func (es *EncodedStorer) PutSiloString(silo string, key string, value string) (err error) {
+	encoded, err := es.encode(value, es.siloFormat(silo))
+	if err != nil {
+		return err
+	}
+
+	return es.GlobalSiloStringStorer.PutSiloString(silo, key, encoded)
+}
+
+// ScanSilo returns all decoded entries of a silo
+func (es *EncodedStorer) ScanSilo(silo string) (entries map[string]string, err error) {
+	encodedEntries, err := es.GlobalSiloStringStorer.ScanSilo(silo)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.decodeEntries(encodedEntries)
+}
+
+// GlobalScan returns all decoded entries of all silos
+func (es *EncodedStorer) GlobalScan() (entries map[string]map[string]string, err error) {
+	encodedEntries, err := es.GlobalSiloStringStorer.GlobalScan()
+	if err != nil {
+		return nil, err
+	}
+
+	entries = make(map[string]map[string]string)
+	for silo, siloEntries := range encodedEntries {
+		if entries[silo], err = es.decodeEntries(siloEntries); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// decodeEntries decodes the values of entries
+func (es *EncodedStorer) decodeEntries(encodedEntries map[string]string) (entries map[string]string, err error) {
+	entries = make(map[string]string)
+	for key, encoded := range encodedEntries {
+		if entries[key], err = es.decode(encoded); err != nil {
+			return nil, fmt.Errorf("Error decoding value of [%s]: %v", key, err)
+		}
+	}
+
+	return entries, nil
+}
+
+// Close releases the resources of the compressors and closes the storer
+func (es *EncodedStorer) Close() (err error) {
+	es.zstdEncoder.Close()
+	es.zstdDecoder.Close()
+
+	return es.GlobalSiloStringStorer.Close()
+}
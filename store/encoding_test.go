@@ -0,0 +1,96 @@
+package store_test
+
+import (
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var journal = strings.Repeat("Dear journal, today was a good day for blue jays. ", 200)
+
+func TestEncodedStorerRoundTrips(t *testing.T) {
+	for name, format := range map[string]store.ValueFormat{
+		"snappyRaw":    {Compression: store.SnappyCompression, Encoding: store.RawEncoding},
+		"snappyBase64": {Compression: store.SnappyCompression, Encoding: store.Base64Encoding},
+		"zstdRaw":      {Compression: store.ZstdCompression, Encoding: store.RawEncoding},
+		"zstdBase64":   {Compression: store.ZstdCompression, Encoding: store.Base64Encoding},
+		"base64":       {Compression: store.NoCompression, Encoding: store.Base64Encoding},
+		"plain":        store.PlainFormat,
+	} {
+		t.Run(name, func(t *testing.T) {
+			storer, cleanup := newTestLevelDB(t, "encoded")
+			defer cleanup()
+
+			es, err := store.NewEncodedStorer(storer, store.OptionSiloValueFormat("journals", format))
+			require.NoError(t, err)
+
+			require.NoError(t, es.PutSiloString("journals", "alphonse", journal))
+
+			value, err := es.GetSiloString("journals", "alphonse")
+			require.NoError(t, err)
+			assert.Equal(t, journal, value)
+
+			entries, err := es.ScanSilo("journals")
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"alphonse": journal}, entries)
+
+			globalEntries, err := es.GlobalScan()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]map[string]string{"journals": {"alphonse": journal}}, globalEntries)
+
+			stored, err := storer.GetSiloString("journals", "alphonse")
+			require.NoError(t, err)
+			if format.Compression != store.NoCompression {
+				assert.True(t, len(stored) < len(journal))
+			}
+
+			if format.Encoding == store.Base64Encoding {
+				assert.True(t, utf8.ValidString(stored))
+			}
+		})
+	}
+}
+
+func TestEncodedStorerReadsValuesWrittenBefore(t *testing.T) {
+	storer, cleanup := newTestLevelDB(t, "encoded")
+	defer cleanup()
+
+	require.NoError(t, storer.PutSiloString("journals", "bernard", "Dear journal"))
+
+	es, err := store.NewEncodedStorer(storer, store.OptionDefaultValueFormat(store.ValueFormat{Compression: store.ZstdCompression, Encoding: store.RawEncoding}))
+	require.NoError(t, err)
+
+	value, err := es.GetSiloString("journals", "bernard")
+	require.NoError(t, err)
+	assert.Equal(t, "Dear journal", value)
+
+	// Changing the format of a silo keeps values written in the previous format readable
+	require.NoError(t, es.PutSiloString("journals", "alphonse", journal))
+
+	es, err = store.NewEncodedStorer(storer, store.OptionSiloValueFormat("journals", store.ValueFormat{Compression: store.SnappyCompression, Encoding: store.Base64Encoding}))
+	require.NoError(t, err)
+	defer es.Close()
+
+	entries, err := es.ScanSilo("journals")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alphonse": journal, "bernard": "Dear journal"}, entries)
+}
+
+func TestEncodedStorerCorruptedValue(t *testing.T) {
+	storer, cleanup := newTestLevelDB(t, "encoded")
+	defer cleanup()
+
+	require.NoError(t, storer.PutSiloString("journals", "alphonse", "\x00sb!!!"))
+
+	es, err := store.NewEncodedStorer(storer)
+	require.NoError(t, err)
+
+	_, err = es.GetSiloString("journals", "alphonse")
+	assert.Error(t, err)
+
+	_, err = es.ScanSilo("journals")
+	assert.Error(t, err)
+}
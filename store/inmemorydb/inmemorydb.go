@@ -41,12 +41,12 @@ func (imdb *InMemoryDB) GetString(key string) (value string, err error) {
 func (imdb *InMemoryDB) GetSiloString(silo string, key string) (value string, err error) {
 	s, ok := imdb.data[silo]
 	if !ok {
-		return "", fmt.Errorf("%s not found", key)
+		return "", fmt.Errorf("%s %w", key, store.ErrNotFound)
 	}
 
 	v, ok := s[key]
 	if !ok {
-		return "", fmt.Errorf("%s not found", key)
+		return "", fmt.Errorf("%s %w", key, store.ErrNotFound)
 	}
 
 	return v, nil
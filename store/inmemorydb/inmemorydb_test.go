@@ -2,6 +2,7 @@ package inmemorydb_test
 
 import (
 	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
 	"github.com/alexandre-normand/slackscot/store/inmemorydb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -274,7 +275,7 @@ func TestDeleteExistingSiloContent(t *testing.T) {
 		err := imdb.DeleteSiloString("ns1", "key1")
 		if assert.Nil(t, err) {
 			imv1, err := imdb.GetSiloString("ns1", "key1")
-			assert.Error(t, err)
+			assert.True(t, store.IsNotFound(err))
 			assert.Equal(t, "", imv1)
 
 			// Check it's also really deleted from the "persistent" storer
@@ -49,6 +49,25 @@ func TestGetAfterCloseShouldResultInError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestGetMissingKeyIsNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tmpTest")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ldb, err := store.NewLevelDB("test", dir)
+	assert.Nil(t, err)
+
+	_, err = ldb.GetSiloString("silo", "missing")
+	assert.True(t, store.IsNotFound(err))
+
+	ldb.Close()
+	_, err = ldb.GetSiloString("silo", "missing")
+	if assert.Error(t, err) {
+		assert.False(t, store.IsNotFound(err))
+	}
+}
+
 func TestPutGetScanAsBytes(t *testing.T) {
 	dir, err := ioutil.TempDir("", "tmpTest")
 	assert.Nil(t, err)
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// partitionKeySeparators are the separators following a partition (i.e. a channel id) at the start of the keys scoped
+// to it (i.e. Cgeneral/1546833210.036900 or Cgeneral:thing)
+const partitionKeySeparators = "/:.-_"
+
+// PartitionEntry is an entry of a partition
+type PartitionEntry struct {
+	Silo  string `json:"silo"`
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// PartitionManifest lists the entries of a partition that were exported (or removed)
+type PartitionManifest struct {
+	Partition string           `json:"partition"`
+	Entries   []PartitionEntry `json:"entries"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+// inPartition returns true if the entry belongs to the partition: it's in the silo named after it (i.e. karma kept
+// per channel) or its key is scoped to it (the partition itself or starting with it followed by a separator)
+func inPartition(partition string, silo string, key string) bool {
+	if silo == partition || key == partition {
+		return true
+	}
+
+	return strings.HasPrefix(key, partition) && len(key) > len(partition) && strings.ContainsRune(partitionKeySeparators, rune(key[len(partition)]))
+}
+
+// ExportPartition returns a manifest of all entries of a partition (i.e. a channel or team id) across every silo of
+// the storer, along with their values. Entries belong to a partition if they're in the silo named after it or if their
+// key is scoped to it (the partition itself or starting with it followed by one of /:.-_). Since that depends on how
+// plugins name their silos and keys, data of other partitions could be included in rare cases (i.e. a key of another
+// partition that happens to start with a channel id) and the manifest is worth a review before deleting
+func ExportPartition(storer GlobalSiloStringStorer, partition string) (manifest PartitionManifest, err error) {
+	manifest = PartitionManifest{Partition: partition, Entries: make([]PartitionEntry, 0), CreatedAt: time.Now()}
+
+	entries, err := storer.GlobalScan()
+	if err != nil {
+		return manifest, err
+	}
+
+	for silo, siloEntries := range entries {
+		for key, value := range siloEntries {
+			if inPartition(partition, silo, key) {
+				manifest.Entries = append(manifest.Entries, PartitionEntry{Silo: silo, Key: key, Value: value})
+			}
+		}
+	}
+
+	sort.Slice(manifest.Entries, func(i, j int) bool {
+		if manifest.Entries[i].Silo != manifest.Entries[j].Silo {
+			return manifest.Entries[i].Silo < manifest.Entries[j].Silo
+		}
+
+		return manifest.Entries[i].Key < manifest.Entries[j].Key
+	})
+
+	return manifest, nil
+}
+
+// DeletePartition deletes all entries of a partition (see ExportPartition) across every silo of the storer and returns
+// a manifest of the entries removed, without their values. On error, the manifest lists the entries removed until then
+func DeletePartition(storer GlobalSiloStringStorer, partition string) (manifest PartitionManifest, err error) {
+	exported, err := ExportPartition(storer, partition)
+	if err != nil {
+		return exported, err
+	}
+
+	manifest = PartitionManifest{Partition: partition, Entries: make([]PartitionEntry, 0, len(exported.Entries)), CreatedAt: exported.CreatedAt}
+	for _, e := range exported.Entries {
+		if err = storer.DeleteSiloString(e.Silo, e.Key); err != nil {
+			return manifest, err
+		}
+
+		manifest.Entries = append(manifest.Entries, PartitionEntry{Silo: e.Silo, Key: e.Key})
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,40 @@
+package store_test
+
+import (
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExportAndDeletePartition(t *testing.T) {
+	storer, cleanup := newTestLevelDB(t, "partition")
+	defer cleanup()
+
+	require.NoError(t, storer.PutSiloString("Cgeneral", "birds", "3"))
+	require.NoError(t, storer.PutSiloString("Crandom", "birds", "1"))
+	require.NoError(t, storer.PutSiloString("responses", "Cgeneral/1546833210.036900", "{}"))
+	require.NoError(t, storer.PutSiloString("responses", "Cgeneralist/1546833210.036900", "{}"))
+	require.NoError(t, storer.PutSiloString("", "Cgeneral", "welcome"))
+
+	manifest, err := store.ExportPartition(storer, "Cgeneral")
+	require.NoError(t, err)
+	assert.Equal(t, "Cgeneral", manifest.Partition)
+	assert.Equal(t, []store.PartitionEntry{
+		{Silo: "", Key: "Cgeneral", Value: "welcome"},
+		{Silo: "Cgeneral", Key: "birds", Value: "3"},
+		{Silo: "responses", Key: "Cgeneral/1546833210.036900", Value: "{}"},
+	}, manifest.Entries)
+
+	manifest, err = store.DeletePartition(storer, "Cgeneral")
+	require.NoError(t, err)
+	assert.Equal(t, []store.PartitionEntry{
+		{Silo: "", Key: "Cgeneral"},
+		{Silo: "Cgeneral", Key: "birds"},
+		{Silo: "responses", Key: "Cgeneral/1546833210.036900"},
+	}, manifest.Entries)
+
+	entries, err := storer.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"Crandom": {"birds": "1"}, "responses": {"Cgeneralist/1546833210.036900": "{}"}}, entries)
+}
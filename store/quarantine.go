@@ -0,0 +1,69 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// QuarantineSilo is the diagnostics silo corrupted values are moved to by Quarantine
+const QuarantineSilo = "quarantine"
+
+// QuarantinedValue holds a corrupted value moved out of its silo along with where it was and why it was quarantined
+type QuarantinedValue struct {
+	Silo          string    `json:"silo"`
+	Key           string    `json:"key"`
+	Value         string    `json:"value"`
+	Reason        string    `json:"reason"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+}
+
+// quarantineKey returns the key of a quarantined value in the QuarantineSilo
+func quarantineKey(silo string, key string) string {
+	return fmt.Sprintf("%s:%s", silo, key)
+}
+
+// Quarantine moves a corrupted value (i.e. one that can't be parsed) out of its silo into the QuarantineSilo so that
+// it's kept for diagnostics while reads of the key carry on as if it was never set. A later quarantine of the same
+// key replaces the previous one
+func Quarantine(storer SiloStringStorer, silo string, key string, reason error) (qv QuarantinedValue, err error) {
+	value, err := storer.GetSiloString(silo, key)
+	if err != nil {
+		return qv, err
+	}
+
+	qv = QuarantinedValue{Silo: silo, Key: key, Value: value, Reason: reason.Error(), QuarantinedAt: time.Now()}
+	encoded, err := json.Marshal(qv)
+	if err != nil {
+		return qv, err
+	}
+
+	if err = storer.PutSiloString(QuarantineSilo, quarantineKey(silo, key), string(encoded)); err != nil {
+		return qv, err
+	}
+
+	return qv, storer.DeleteSiloString(silo, key)
+}
+
+// ListQuarantined returns all quarantined values, ordered by the time they were quarantined
+func ListQuarantined(storer SiloStringStorer) (values []QuarantinedValue, err error) {
+	entries, err := storer.ScanSilo(QuarantineSilo)
+	if err != nil {
+		return nil, err
+	}
+
+	values = make([]QuarantinedValue, 0, len(entries))
+	for key, encoded := range entries {
+		var qv QuarantinedValue
+		if err = json.Unmarshal([]byte(encoded), &qv); err != nil {
+			return nil, fmt.Errorf("Invalid quarantined value [%s]: %v", key, err)
+		}
+
+		values = append(values, qv)
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].QuarantinedAt.Before(values[j].QuarantinedAt) })
+
+	return values, nil
+}
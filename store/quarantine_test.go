@@ -0,0 +1,70 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tmpTest")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	storer, err := store.NewLevelDB("test", dir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	require.NoError(t, storer.PutSiloString("Cgeneral", "thing", "abc"))
+	require.NoError(t, storer.PutSiloString("Cgeneral", "other", "1"))
+	require.NoError(t, storer.PutSiloString("Crandom", "thing", "$$"))
+
+	qv, err := store.Quarantine(storer, "Cgeneral", "thing", fmt.Errorf("not a number"))
+	require.NoError(t, err)
+	assert.Equal(t, "abc", qv.Value)
+
+	_, err = store.Quarantine(storer, "Crandom", "thing", fmt.Errorf("not a number either"))
+	require.NoError(t, err)
+
+	// The corrupted values are gone from their silo and the others are untouched
+	_, err = storer.GetSiloString("Cgeneral", "thing")
+	assert.Error(t, err)
+
+	entries, err := storer.ScanSilo("Cgeneral")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"other": "1"}, entries)
+
+	quarantined, err := store.ListQuarantined(storer)
+	require.NoError(t, err)
+	if assert.Len(t, quarantined, 2) {
+		assert.Equal(t, "Cgeneral", quarantined[0].Silo)
+		assert.Equal(t, "thing", quarantined[0].Key)
+		assert.Equal(t, "abc", quarantined[0].Value)
+		assert.Equal(t, "not a number", quarantined[0].Reason)
+		assert.False(t, quarantined[0].QuarantinedAt.IsZero())
+
+		assert.Equal(t, "Crandom", quarantined[1].Silo)
+		assert.Equal(t, "$$", quarantined[1].Value)
+	}
+}
+
+func TestQuarantineMissingKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tmpTest")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	storer, err := store.NewLevelDB("test", dir)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	_, err = store.Quarantine(storer, "Cgeneral", "thing", fmt.Errorf("not a number"))
+	assert.Error(t, err)
+
+	quarantined, err := store.ListQuarantined(storer)
+	require.NoError(t, err)
+	assert.Empty(t, quarantined)
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicatedStorer routes reads (gets and scans) to read replicas, in turn, and writes (puts and deletes) to a primary.
+// This takes the load of read-heavy plugins (i.e. leaderboards scanning whole silos) off the primary of storage
+// backends with separate read and write endpoints: each endpoint is a storer of its own and replication between them
+// is left to the backend.
+//
+// Replicas lag behind the primary so reads might be stale. By default, reads go to replicas regardless. With
+// OptionStaleReadTolerance, reads of silos written within the tolerance go to the primary instead so that plugins
+// read their own writes. Reads failing on a replica (i.e. because it's unreachable or doesn't have a recently
+// written key yet) are retried on the primary
+type ReplicatedStorer struct {
+	primary            GlobalSiloStringStorer
+	replicas           []GlobalSiloStringStorer
+	next               uint32
+	staleReadTolerance time.Duration
+	now                func() time.Time
+
+	sync.Mutex
+	writtenSilos map[string]time.Time
+}
+
+// ReplicaOption defines an option for a ReplicatedStorer
+type ReplicaOption func(rs *ReplicatedStorer)
+
+// OptionStaleReadTolerance sets how long replicas are given to catch up with writes. Reads of a silo written less than
+// tolerance ago go to the primary rather than to a replica
+func OptionStaleReadTolerance(tolerance time.Duration) ReplicaOption {
+	return func(rs *ReplicatedStorer) {
+		rs.staleReadTolerance = tolerance
+	}
+}
+
+// NewReplicatedStorer creates a new ReplicatedStorer writing to the primary and reading from the replicas. Without
+// replicas, everything goes to the primary
+func NewReplicatedStorer(primary GlobalSiloStringStorer, replicas []GlobalSiloStringStorer, options ...ReplicaOption) (rs *ReplicatedStorer) {
+	rs = new(ReplicatedStorer)
+	rs.primary = primary
+	rs.replicas = replicas
+	rs.now = time.Now
+	rs.writtenSilos = make(map[string]time.Time)
+
+	for _, opt := range options {
+		opt(rs)
+	}
+
+	return rs
+}
+
+// reader returns the storer to read the silo from: the next replica in turn or the primary if there are no replicas
+// or if the silo was written within the stale read tolerance
+func (rs *ReplicatedStorer) reader(silos ...string) GlobalSiloStringStorer {
+	if len(rs.replicas) == 0 || rs.recentlyWritten(silos...) {
+		return rs.primary
+	}
+
+	i := atomic.AddUint32(&rs.next, 1)
+	return rs.replicas[int(i)%len(rs.replicas)]
+}
+
+// recentlyWritten returns true if any of the silos (or any silo at all, if none are given) was written within the
+// stale read tolerance
+func (rs *ReplicatedStorer) recentlyWritten(silos ...string) bool {
+	if rs.staleReadTolerance <= 0 {
+		return false
+	}
+
+	rs.Lock()
+	defer rs.Unlock()
+
+	now := rs.now()
+	if len(silos) == 0 {
+		for _, writtenAt := range rs.writtenSilos {
+			if now.Sub(writtenAt) < rs.staleReadTolerance {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, silo := range silos {
+		if writtenAt, ok := rs.writtenSilos[silo]; ok && now.Sub(writtenAt) < rs.staleReadTolerance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trackWrite records the time a silo was written, forgetting writes older than the stale read tolerance
+func (rs *ReplicatedStorer) trackWrite(silo string) {
+	if rs.staleReadTolerance <= 0 {
+		return
+	}
+
+	rs.Lock()
+	defer rs.Unlock()
+
+	now := rs.now()
+	for s, writtenAt := range rs.writtenSilos {
+		if now.Sub(writtenAt) >= rs.staleReadTolerance {
+			delete(rs.writtenSilos, s)
+		}
+	}
+
+	rs.writtenSilos[silo] = now
+}
+
+// GetSiloString returns the value of a key in a silo from a replica (or from the primary, see ReplicatedStorer)
+func (rs *ReplicatedStorer) GetSiloString(silo string, key string) (value string, err error) {
+	reader := rs.reader(silo)
+	if value, err = reader.GetSiloString(silo, key); err != nil && reader != rs.primary {
+		return rs.primary.GetSiloString(silo, key)
+	}
+
+	return value, err
+}
+
+// ScanSilo returns all entries of a silo from a replica (or from the primary, see ReplicatedStorer)
+func (rs *ReplicatedStorer) ScanSilo(silo string) (entries map[string]string, err error) {
+	reader := rs.reader(silo)
+	if entries, err = reader.ScanSilo(silo); err != nil && reader != rs.primary {
+		return rs.primary.ScanSilo(silo)
+	}
+
+	return entries, err
+}
+
+// GlobalScan returns all entries of all silos from a replica (or from the primary, see ReplicatedStorer)
+func (rs *ReplicatedStorer) GlobalScan() (entries map[string]map[string]string, err error) {
+	reader := rs.reader()
+	if entries, err = reader.GlobalScan(); err != nil && reader != rs.primary {
+		return rs.primary.GlobalScan()
+	}
+
+	return entries, err
+}
+
+// PutSiloString writes the value of a key in a silo to the primary
+func (rs *ReplicatedStorer) PutSiloString(silo string, key string, value string) (err error) {
+	rs.trackWrite(silo)
+	return rs.primary.PutSiloString(silo, key, value)
+}
+
+// DeleteSiloString deletes a key in a silo from the primary
+func (rs *ReplicatedStorer) DeleteSiloString(silo string, key string) (err error) {
+	rs.trackWrite(silo)
+	return rs.primary.DeleteSiloString(silo, key)
+}
+
+// Close closes the primary and all replicas, returning the first error, if any
+func (rs *ReplicatedStorer) Close() (err error) {
+	err = rs.primary.Close()
+
+	for _, replica := range rs.replicas {
+		if cerr := replica.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,115 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/alexandre-normand/slackscot/store/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestLevelDB(t *testing.T, name string) (storer *store.LevelDB, cleanup func()) {
+	dir, err := ioutil.TempDir("", "tmpTest")
+	require.NoError(t, err)
+
+	storer, err = store.NewLevelDB(name, dir)
+	require.NoError(t, err)
+
+	return storer, func() {
+		storer.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestReplicatedStorerRoutesReadsToReplicasAndWritesToPrimary(t *testing.T) {
+	primary, cleanupPrimary := newTestLevelDB(t, "primary")
+	defer cleanupPrimary()
+
+	replica, cleanupReplica := newTestLevelDB(t, "replica")
+	defer cleanupReplica()
+
+	require.NoError(t, replica.PutSiloString("karma", "alphonse", "10"))
+
+	rs := store.NewReplicatedStorer(primary, []store.GlobalSiloStringStorer{replica})
+	require.NoError(t, rs.PutSiloString("karma", "bernard", "3"))
+
+	value, err := primary.GetSiloString("karma", "bernard")
+	require.NoError(t, err)
+	assert.Equal(t, "3", value)
+
+	value, err = rs.GetSiloString("karma", "alphonse")
+	require.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	entries, err := rs.ScanSilo("karma")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alphonse": "10"}, entries)
+
+	globalEntries, err := rs.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"karma": {"alphonse": "10"}}, globalEntries)
+
+	require.NoError(t, rs.DeleteSiloString("karma", "bernard"))
+
+	_, err = primary.GetSiloString("karma", "bernard")
+	assert.Error(t, err)
+}
+
+func TestReplicatedStorerReadsFromPrimaryWithinStaleReadTolerance(t *testing.T) {
+	primary, cleanupPrimary := newTestLevelDB(t, "primary")
+	defer cleanupPrimary()
+
+	replica, cleanupReplica := newTestLevelDB(t, "replica")
+	defer cleanupReplica()
+
+	require.NoError(t, replica.PutSiloString("karma", "bernard", "2"))
+
+	rs := store.NewReplicatedStorer(primary, []store.GlobalSiloStringStorer{replica}, store.OptionStaleReadTolerance(100*time.Millisecond))
+	require.NoError(t, rs.PutSiloString("karma", "bernard", "3"))
+
+	// The write is read back from the primary until the replica is given time to catch up
+	value, err := rs.GetSiloString("karma", "bernard")
+	require.NoError(t, err)
+	assert.Equal(t, "3", value)
+
+	globalEntries, err := rs.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"karma": {"bernard": "3"}}, globalEntries)
+
+	// Other silos are still read from the replica
+	_, err = rs.ScanSilo("coffee")
+	require.NoError(t, err)
+
+	time.Sleep(150 * time.Millisecond)
+
+	value, err = rs.GetSiloString("karma", "bernard")
+	require.NoError(t, err)
+	assert.Equal(t, "2", value)
+}
+
+func TestReplicatedStorerFallsBackToPrimaryOnReplicaErrors(t *testing.T) {
+	primary, cleanupPrimary := newTestLevelDB(t, "primary")
+	defer cleanupPrimary()
+
+	require.NoError(t, primary.PutSiloString("karma", "alphonse", "10"))
+
+	replica := &mocks.Storer{}
+	replica.On("GetSiloString", "karma", "alphonse").Return("", fmt.Errorf("connection refused"))
+	replica.On("ScanSilo", "karma").Return(map[string]string{}, fmt.Errorf("connection refused"))
+
+	rs := store.NewReplicatedStorer(primary, []store.GlobalSiloStringStorer{replica})
+
+	value, err := rs.GetSiloString("karma", "alphonse")
+	require.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	entries, err := rs.ScanSilo("karma")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alphonse": "10"}, entries)
+
+	replica.AssertExpectations(t)
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RetentionSilo is the silo where a RetentionStorer keeps the time at which the entries of silos with a retention were
+// last written
+const RetentionSilo = "retention"
+
+// RetentionStorer enforces a retention on the entries of some (or all) silos of a storer: entries not written for
+// longer than the retention of their silo are deleted by Sweep, which is meant to be called periodically (slackscot
+// does it for storers registered with slackscot.OptionRetentionStorer).
+//
+// The time at which entries are written is kept in the RetentionSilo of the storer. Entries written before the storer
+// had a retention don't have one so their retention starts on the first sweep that finds them
+type RetentionStorer struct {
+	GlobalSiloStringStorer
+
+	defaultRetention time.Duration
+	siloRetentions   map[string]time.Duration
+}
+
+// RetentionOption defines an option for a RetentionStorer
+type RetentionOption func(rs *RetentionStorer)
+
+// OptionDefaultRetention sets the retention of the entries of silos without a retention of their own (see
+// OptionSiloRetention). Entries are kept forever unless set
+func OptionDefaultRetention(retention time.Duration) RetentionOption {
+	return func(rs *RetentionStorer) {
+		rs.defaultRetention = retention
+	}
+}
+
+// OptionSiloRetention sets the retention of the entries of a silo. Silos are matched ignoring case
+func OptionSiloRetention(silo string, retention time.Duration) RetentionOption {
+	return func(rs *RetentionStorer) {
+		rs.siloRetentions[strings.ToLower(silo)] = retention
+	}
+}
+
+// NewRetentionStorer creates a new RetentionStorer enforcing the retention of the silos of the storer
+func NewRetentionStorer(storer GlobalSiloStringStorer, options ...RetentionOption) (rs *RetentionStorer) {
+	rs = new(RetentionStorer)
+	rs.GlobalSiloStringStorer = storer
+	rs.siloRetentions = make(map[string]time.Duration)
+
+	for _, opt := range options {
+		opt(rs)
+	}
+
+	return rs
+}
+
+// retentionKey returns the key, in the RetentionSilo, of the time at which an entry was written. The silo is escaped so
+// that it can't contain the separator
+func retentionKey(silo string, key string) string {
+	return url.PathEscape(silo) + "/" + key
+}
+
+// siloRetention returns the retention of a silo and whether it has one
+func (rs *RetentionStorer) siloRetention(silo string) (retention time.Duration, exists bool) {
+	if silo == RetentionSilo {
+		return 0, false
+	}
+
+	if retention, exists = rs.siloRetentions[strings.ToLower(silo)]; exists {
+		return retention, retention > 0
+	}
+
+	return rs.defaultRetention, rs.defaultRetention > 0
+}
+
+// PutSiloString puts the value of the key in the silo and, if the silo has a retention, the time at which it was written
+func (rs *RetentionStorer) PutSiloString(silo string, key string, value string) (err error) {
+	if err = rs.GlobalSiloStringStorer.PutSiloString(silo, key, value); err != nil {
+		return err
+	}
+
+	if _, exists := rs.siloRetention(silo); !exists {
+		return nil
+	}
+
+	return rs.GlobalSiloStringStorer.PutSiloString(RetentionSilo, retentionKey(silo, key), time.Now().UTC().Format(time.RFC3339Nano))
+}
+
+// DeleteSiloString deletes the key from the silo along with the time at which it was written, if the silo has a
+// retention
+func (rs *RetentionStorer) DeleteSiloString(silo string, key string) (err error) {
+	if err = rs.GlobalSiloStringStorer.DeleteSiloString(silo, key); err != nil {
+		return err
+	}
+
+	if _, exists := rs.siloRetention(silo); !exists {
+		return nil
+	}
+
+	return rs.GlobalSiloStringStorer.DeleteSiloString(RetentionSilo, retentionKey(silo, key))
+}
+
+// GlobalScan returns the entries of all silos except the RetentionSilo
+func (rs *RetentionStorer) GlobalScan() (entries map[string]map[string]string, err error) {
+	entries, err = rs.GlobalSiloStringStorer.GlobalScan()
+	if err != nil {
+		return nil, err
+	}
+
+	delete(entries, RetentionSilo)
+	return entries, nil
+}
+
+// Sweep deletes the entries not written for longer than the retention of their silo as of now and returns the number
+// of entries deleted by silo. Entries without a known write time are given the time of the sweep (starting their
+// retention) and the write times of entries deleted by other means are dropped. On error, the entries deleted until
+// then are returned
+func (rs *RetentionStorer) Sweep(now time.Time) (purged map[string]int, err error) {
+	purged = make(map[string]int)
+
+	entries, err := rs.GlobalSiloStringStorer.GlobalScan()
+	if err != nil {
+		return purged, err
+	}
+
+	writtenAt := entries[RetentionSilo]
+	swept := make(map[string]bool)
+
+	for silo, siloEntries := range entries {
+		retention, exists := rs.siloRetention(silo)
+		if !exists {
+			continue
+		}
+
+		for key := range siloEntries {
+			rKey := retentionKey(silo, key)
+			swept[rKey] = true
+
+			written, err := time.Parse(time.RFC3339Nano, writtenAt[rKey])
+			if err != nil {
+				if err = rs.GlobalSiloStringStorer.PutSiloString(RetentionSilo, rKey, now.UTC().Format(time.RFC3339Nano)); err != nil {
+					return purged, err
+				}
+
+				continue
+			}
+
+			if now.Sub(written) <= retention {
+				continue
+			}
+
+			if err = rs.DeleteSiloString(silo, key); err != nil {
+				return purged, err
+			}
+
+			purged[silo] = purged[silo] + 1
+		}
+	}
+
+	for rKey := range writtenAt {
+		if !swept[rKey] {
+			if err = rs.GlobalSiloStringStorer.DeleteSiloString(RetentionSilo, rKey); err != nil {
+				return purged, err
+			}
+		}
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,89 @@
+package store_test
+
+import (
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRetentionStorerSweepsExpiredEntries(t *testing.T) {
+	storer, cleanup := newTestLevelDB(t, "retention")
+	defer cleanup()
+
+	rs := store.NewRetentionStorer(storer, store.OptionDefaultRetention(365*24*time.Hour), store.OptionSiloRetention("Links", 90*24*time.Hour), store.OptionSiloRetention("settings", 0))
+
+	require.NoError(t, rs.PutSiloString("Cgeneral", "@U21355", "10"))
+	require.NoError(t, rs.PutSiloString("links", "https://slackscot.io", "Cgeneral"))
+	require.NoError(t, rs.PutSiloString("settings", "theme", "podium"))
+
+	purged, err := rs.Sweep(time.Now().Add(30 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	purged, err = rs.Sweep(time.Now().Add(100 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"links": 1}, purged)
+
+	purged, err = rs.Sweep(time.Now().Add(400 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Cgeneral": 1}, purged)
+
+	entries, err := storer.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"settings": {"theme": "podium"}}, entries)
+}
+
+func TestRetentionStartsOnFirstSweepForEntriesWrittenBefore(t *testing.T) {
+	storer, cleanup := newTestLevelDB(t, "retention")
+	defer cleanup()
+
+	require.NoError(t, storer.PutSiloString("links", "https://slackscot.io", "Cgeneral"))
+
+	rs := store.NewRetentionStorer(storer, store.OptionSiloRetention("links", time.Hour))
+	firstSweep := time.Now().Add(24 * time.Hour)
+
+	purged, err := rs.Sweep(firstSweep)
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	purged, err = rs.Sweep(firstSweep.Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"links": 1}, purged)
+}
+
+func TestRetentionStorerHidesWriteTimes(t *testing.T) {
+	storer, cleanup := newTestLevelDB(t, "retention")
+	defer cleanup()
+
+	rs := store.NewRetentionStorer(storer, store.OptionDefaultRetention(time.Hour))
+	require.NoError(t, rs.PutSiloString("Cgeneral", "@U21355", "10"))
+
+	entries, err := rs.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"Cgeneral": {"@U21355": "10"}}, entries)
+
+	// Write times are deleted with their entries
+	require.NoError(t, rs.DeleteSiloString("Cgeneral", "@U21355"))
+	entries, err = storer.GlobalScan()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRetentionSweepDropsWriteTimesOfEntriesDeletedElsewhere(t *testing.T) {
+	storer, cleanup := newTestLevelDB(t, "retention")
+	defer cleanup()
+
+	rs := store.NewRetentionStorer(storer, store.OptionDefaultRetention(time.Hour))
+	require.NoError(t, rs.PutSiloString("Cgeneral", "@U21355", "10"))
+	require.NoError(t, storer.DeleteSiloString("Cgeneral", "@U21355"))
+
+	purged, err := rs.Sweep(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	entries, err := storer.ScanSilo(store.RetentionSilo)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -3,9 +3,19 @@
 package store
 
 import (
+	"errors"
+	"github.com/syndtr/goleveldb/leveldb"
 	"io"
 )
 
+// ErrNotFound is the error, possibly wrapped, of storers not finding a key (see IsNotFound)
+var ErrNotFound = errors.New("not found")
+
+// IsNotFound returns true if the error is the one of a storer not finding a key, as opposed to failing to look it up
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, leveldb.ErrNotFound)
+}
+
 // GlobalSiloStringStorer is implemented by any value that has all the SiloStringStorer methods
 // and the GlobalScanSilo method
 type GlobalSiloStringStorer interface {
@@ -14,7 +24,7 @@ type GlobalSiloStringStorer interface {
 }
 
 // SiloStringStorer is implemented by any value that has the Get/Put/Delete/Scan and Closer methods
-// on string keys/values with a silo name.
+// on string keys/values with a silo name. Getting a missing key returns an error for which IsNotFound is true.
 type SiloStringStorer interface {
 	io.Closer
 
@@ -0,0 +1,211 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	storeInspectionPluginName = "store"
+
+	// globalSiloArg is the silo argument of store commands designating the global silo (the empty silo name)
+	globalSiloArg = "-"
+
+	// maxScannedEntries is the maximum number of entries listed by the store scan command
+	maxScannedEntries = 50
+)
+
+var storeGetRegex = regexp.MustCompile("(?i)\\Astore get (\\S+) (\\S+) (\\S+)\\z")
+var storeScanRegex = regexp.MustCompile("(?i)\\Astore scan (\\S+) (\\S+)(?: (\\S+))?\\z")
+var storeDeleteRegex = regexp.MustCompile("(?i)\\Astore delete (\\S+) (\\S+) (\\S+)( confirm)?\\z")
+
+// OptionInspectableStorer makes a storer (usually the one of a plugin) inspectable under the given name by admins
+// (see config.StoreAdminIDsKey) with the store command. This lets operators look up, list and delete entries
+// (i.e. to fix bad data) without access to the database
+func OptionInspectableStorer(name string, storer store.GlobalSiloStringStorer) Option {
+	return func(s *Slackscot) {
+		if s.inspectableStorers == nil {
+			s.inspectableStorers = make(map[string]store.GlobalSiloStringStorer)
+		}
+
+		s.inspectableStorers[strings.ToLower(name)] = storer
+	}
+}
+
+// storeInspectionPlugin holds the store command letting admins inspect and fix the data of inspectable storers
+type storeInspectionPlugin struct {
+	Plugin
+
+	storers map[string]store.GlobalSiloStringStorer
+	admins  map[string]bool
+}
+
+// newStoreInspectionPlugin creates the plugin of the store command
+func (s *Slackscot) newStoreInspectionPlugin() *storeInspectionPlugin {
+	sp := new(storeInspectionPlugin)
+	sp.storers = s.inspectableStorers
+	sp.admins = make(map[string]bool)
+	for _, userID := range s.config.GetStringSlice(config.StoreAdminIDsKey) {
+		sp.admins[userID] = true
+	}
+
+	sp.Plugin = Plugin{Name: storeInspectionPluginName, NormalizeCommands: true, Commands: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return storeGetRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "store get `<store>` `<silo>` `<key>`",
+		Description: "Shows the value of a key (use `-` for the global silo, admins only)",
+		Answer:      sp.getEntry,
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return storeScanRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "store scan `<store>` `<silo>` [`<prefix>`]",
+		Description: "Lists the entries of a silo, optionally only those with keys starting with a prefix (admins only)",
+		Answer:      sp.scanSilo,
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return storeDeleteRegex.MatchString(m.NormalizedText)
+		},
+		Usage:       "store delete `<store>` `<silo>` `<key>` [confirm]",
+		Description: "Deletes a key once confirmed (admins only)",
+		Answer:      sp.deleteEntry,
+	}}}
+
+	return sp
+}
+
+// parseStoreCommand checks that the user is an admin and parses the arguments of a store command from the text as
+// typed (to preserve the case of silos and keys). If the command can't go on, the answer to reply with is returned
+func (sp *storeInspectionPlugin) parseStoreCommand(m *IncomingMessage, commandRegex *regexp.Regexp) (storer store.GlobalSiloStringStorer, args []string, rejection *Answer) {
+	if !sp.admins[m.User] {
+		return nil, nil, &Answer{Text: "Sorry, only store admins can inspect stores :no_entry_sign:", Options: []AnswerOption{AnswerEphemeral(m.User)}}
+	}
+
+	args = commandRegex.FindStringSubmatch(strings.Join(strings.Fields(m.NormalizedText), " "))
+	if args == nil {
+		return nil, nil, &Answer{Text: "Sorry, I couldn't make sense of that :thinking_face:"}
+	}
+
+	storer, ok := sp.storers[strings.ToLower(args[1])]
+	if !ok {
+		return nil, nil, &Answer{Text: fmt.Sprintf("Sorry, there's no store `%s` :thinking_face: (should be one of %s)", args[1], sp.storerNames())}
+	}
+
+	return storer, args, nil
+}
+
+// storerNames returns the sorted names of inspectable storers, formatted as code
+func (sp *storeInspectionPlugin) storerNames() string {
+	names := make([]string, 0, len(sp.storers))
+	for name := range sp.storers {
+		names = append(names, fmt.Sprintf("`%s`", name))
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// siloFromArg returns the silo designated by a silo argument
+func siloFromArg(arg string) (silo string) {
+	if arg == globalSiloArg {
+		return ""
+	}
+
+	return arg
+}
+
+// siloName returns the name of a silo as displayed in answers
+func siloName(silo string) string {
+	if silo == "" {
+		return "global silo"
+	}
+
+	return fmt.Sprintf("silo `%s`", silo)
+}
+
+// getEntry answers with the value of a key
+func (sp *storeInspectionPlugin) getEntry(m *IncomingMessage) *Answer {
+	storer, args, rejection := sp.parseStoreCommand(m, storeGetRegex)
+	if rejection != nil {
+		return rejection
+	}
+
+	silo, key := siloFromArg(args[2]), args[3]
+	value, err := storer.GetSiloString(silo, key)
+	if err != nil {
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't get `%s` in %s of `%s` :disappointed: (%v)", key, siloName(silo), args[1], err)}
+	}
+
+	return &Answer{Text: fmt.Sprintf("`%s` in %s of `%s` is `%s`", key, siloName(silo), args[1], value)}
+}
+
+// scanSilo answers with the entries of a silo, sorted by key
+func (sp *storeInspectionPlugin) scanSilo(m *IncomingMessage) *Answer {
+	storer, args, rejection := sp.parseStoreCommand(m, storeScanRegex)
+	if rejection != nil {
+		return rejection
+	}
+
+	silo, prefix := siloFromArg(args[2]), args[3]
+	entries, err := storer.ScanSilo(silo)
+	if err != nil {
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't scan %s of `%s` :disappointed: (%v)", siloName(silo), args[1], err)}
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		return &Answer{Text: fmt.Sprintf("There are no entries in %s of `%s` :zzz:", siloName(silo), args[1])}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d entries in %s of `%s`:", len(keys), siloName(silo), args[1])
+	for i, key := range keys {
+		if i == maxScannedEntries {
+			fmt.Fprintf(&b, "\n… and %d more (use a prefix to narrow it down)", len(keys)-maxScannedEntries)
+			break
+		}
+
+		fmt.Fprintf(&b, "\n• `%s`: `%s`", key, entries[key])
+	}
+
+	return &Answer{Text: b.String()}
+}
+
+// deleteEntry deletes a key if the command is confirmed. Otherwise, it answers with the current value and how to
+// confirm the deletion
+func (sp *storeInspectionPlugin) deleteEntry(m *IncomingMessage) *Answer {
+	storer, args, rejection := sp.parseStoreCommand(m, storeDeleteRegex)
+	if rejection != nil {
+		return rejection
+	}
+
+	silo, key, confirmed := siloFromArg(args[2]), args[3], args[4] != ""
+	value, err := storer.GetSiloString(silo, key)
+	if err != nil {
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't get `%s` in %s of `%s` :disappointed: (%v)", key, siloName(silo), args[1], err)}
+	}
+
+	if !confirmed {
+		return &Answer{Text: fmt.Sprintf("`%s` in %s of `%s` is `%s`. To delete it, run `store delete %s %s %s confirm`", key, siloName(silo), args[1], value, args[1], args[2], key)}
+	}
+
+	if err = storer.DeleteSiloString(silo, key); err != nil {
+		sp.Logger.Printf("Error deleting [%s] in silo [%s] of store [%s]: %v", key, silo, args[1], err)
+		return &Answer{Text: fmt.Sprintf("Sorry, I couldn't delete `%s` in %s of `%s` :disappointed: (%v)", key, siloName(silo), args[1], err)}
+	}
+
+	sp.Logger.Printf("Deleted [%s] (was [%s]) in silo [%s] of store [%s] on demand of [%s]", key, value, silo, args[1], m.User)
+	return &Answer{Text: fmt.Sprintf("Deleted `%s` (was `%s`) in %s of `%s` :wastebasket:", key, value, siloName(silo), args[1])}
+}
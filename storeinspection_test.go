@@ -0,0 +1,68 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStoreCommands(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	require.NoError(t, storer.PutSiloString("Cgeneral", "Pizza", "abc"))
+	require.NoError(t, storer.PutSiloString("Cgeneral", "Poutine", "10"))
+	require.NoError(t, storer.PutSiloString("Cgeneral", "bagels", "3"))
+	require.NoError(t, storer.PutSiloString("", "hello", "world"))
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.StoreAdminIDsKey, []string{"Alphonse"})
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store get karma Cgeneral Pizza", "Ignored", "1546833200.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store get Karma Cgeneral Pizza", "Alphonse", "1546833210.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store scan karma Cgeneral P", "Alphonse", "1546833220.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store scan karma -", "Alphonse", "1546833230.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store delete karma Cgeneral Pizza", "Alphonse", "1546833240.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store delete karma Cgeneral Pizza confirm", "Alphonse", "1546833250.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store scan karma Cnothing", "Alphonse", "1546833260.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "store get triggers - hello", "Alphonse", "1546833270.036900", optionPublicMessageToBot(botUserID, "Cgeneral"))),
+	}, nil, OptionInspectableStorer("karma", storer))
+
+	if assert.Equal(t, 8, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Ignored>: Sorry, only store admins can inspect stores :no_entry_sign:", vals.Get("text"))
+		assert.Equal(t, "Ignored", vals.Get("user"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `Pizza` in silo `Cgeneral` of `Karma` is `abc`", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[2].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: 2 entries in silo `Cgeneral` of `karma`:\n• `Pizza`: `abc`\n• `Poutine`: `10`", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[3].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: 1 entries in global silo of `karma`:\n• `hello`: `world`", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[4].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: `Pizza` in silo `Cgeneral` of `karma` is `abc`. To delete it, run `store delete karma Cgeneral Pizza confirm`", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[5].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: Deleted `Pizza` (was `abc`) in silo `Cgeneral` of `karma` :wastebasket:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[6].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: There are no entries in silo `Cnothing` of `karma` :zzz:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[7].msgOptions...)
+		assert.Equal(t, "<@Alphonse>: Sorry, there's no store `triggers` :thinking_face: (should be one of `karma`)", vals.Get("text"))
+	}
+
+	_, err := storer.GetSiloString("Cgeneral", "Pizza")
+	assert.Error(t, err)
+
+	value, err := storer.GetSiloString("Cgeneral", "Poutine")
+	require.NoError(t, err)
+	assert.Equal(t, "10", value)
+}
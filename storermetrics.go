@@ -0,0 +1,125 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/alexandre-normand/slackscot/store"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/metric"
+	"time"
+)
+
+// storerMethods are the methods of a store.GlobalSiloStringStorer instrumented by StorerWithTelemetry
+var storerMethods = []string{"GetSiloString", "PutSiloString", "DeleteSiloString", "ScanSilo", "GlobalScan"}
+
+// StorerWithTelemetry implements store.GlobalSiloStringStorer with all methods wrapped with open telemetry metrics
+// labeled with the name of the plugin using the storer. This helps identify which plugin is hammering storage.
+// Note that lookups of missing keys count as errors for storers returning an error in that case (like the leveldb
+// storer)
+type StorerWithTelemetry struct {
+	base               store.GlobalSiloStringStorer
+	methodCounters     map[string]metric.BoundInt64Counter
+	errCounters        map[string]metric.BoundInt64Counter
+	methodTimeMeasures map[string]metric.BoundInt64Measure
+}
+
+// NewStorerWithTelemetry returns an instance of the store.GlobalSiloStringStorer decorated with open telemetry timing
+// and count metrics labeled with the name of the app and of the plugin using the storer
+func NewStorerWithTelemetry(base store.GlobalSiloStringStorer, name string, pluginName string, meter metric.Meter) StorerWithTelemetry {
+	return StorerWithTelemetry{
+		base:               base,
+		methodCounters:     newStorerMethodCounters("Calls", name, pluginName, meter),
+		errCounters:        newStorerMethodCounters("Errors", name, pluginName, meter),
+		methodTimeMeasures: newStorerMethodTimeMeasures(name, pluginName, meter),
+	}
+}
+
+func newStorerMethodTimeMeasures(appName string, pluginName string, meter metric.Meter) (boundTimeMeasures map[string]metric.BoundInt64Measure) {
+	boundTimeMeasures = make(map[string]metric.BoundInt64Measure)
+
+	for _, method := range storerMethods {
+		m := meter.NewInt64Measure("storer_"+method+"_ProcessingTimeMillis", metric.WithKeys(key.New("name"), key.New("plugin")))
+		boundTimeMeasures[method] = m.Bind(meter.Labels(key.New("name").String(appName), key.New("plugin").String(pluginName)))
+	}
+
+	return boundTimeMeasures
+}
+
+func newStorerMethodCounters(suffix string, appName string, pluginName string, meter metric.Meter) (boundCounters map[string]metric.BoundInt64Counter) {
+	boundCounters = make(map[string]metric.BoundInt64Counter)
+
+	for _, method := range storerMethods {
+		c := meter.NewInt64Counter("storer_"+method+"_"+suffix, metric.WithKeys(key.New("name"), key.New("plugin")))
+		boundCounters[method] = c.Bind(meter.Labels(key.New("name").String(appName), key.New("plugin").String(pluginName)))
+	}
+
+	return boundCounters
+}
+
+// record records the call of a method along with its duration and error, if any
+func (_d StorerWithTelemetry) record(method string, since time.Time, err error) {
+	if err != nil {
+		errCounter := _d.errCounters[method]
+		errCounter.Add(context.Background(), 1)
+	}
+
+	methodCounter := _d.methodCounters[method]
+	methodCounter.Add(context.Background(), 1)
+
+	methodTimeMeasure := _d.methodTimeMeasures[method]
+	methodTimeMeasure.Record(context.Background(), time.Since(since).Milliseconds())
+}
+
+// GetSiloString implements store.GlobalSiloStringStorer
+func (_d StorerWithTelemetry) GetSiloString(silo string, key string) (value string, err error) {
+	defer func(since time.Time) { _d.record("GetSiloString", since, err) }(time.Now())
+	return _d.base.GetSiloString(silo, key)
+}
+
+// PutSiloString implements store.GlobalSiloStringStorer
+func (_d StorerWithTelemetry) PutSiloString(silo string, key string, value string) (err error) {
+	defer func(since time.Time) { _d.record("PutSiloString", since, err) }(time.Now())
+	return _d.base.PutSiloString(silo, key, value)
+}
+
+// DeleteSiloString implements store.GlobalSiloStringStorer
+func (_d StorerWithTelemetry) DeleteSiloString(silo string, key string) (err error) {
+	defer func(since time.Time) { _d.record("DeleteSiloString", since, err) }(time.Now())
+	return _d.base.DeleteSiloString(silo, key)
+}
+
+// ScanSilo implements store.GlobalSiloStringStorer
+func (_d StorerWithTelemetry) ScanSilo(silo string) (entries map[string]string, err error) {
+	defer func(since time.Time) { _d.record("ScanSilo", since, err) }(time.Now())
+	return _d.base.ScanSilo(silo)
+}
+
+// GlobalScan implements store.GlobalSiloStringStorer
+func (_d StorerWithTelemetry) GlobalScan() (entries map[string]map[string]string, err error) {
+	defer func(since time.Time) { _d.record("GlobalScan", since, err) }(time.Now())
+	return _d.base.GlobalScan()
+}
+
+// Close implements store.GlobalSiloStringStorer. Closing isn't instrumented
+func (_d StorerWithTelemetry) Close() (err error) {
+	return _d.base.Close()
+}
+
+// instrumentStorers decorates the storers used by slackscot itself with open telemetry metrics labeled with the
+// name of their plugin (or of the feature using them)
+func (s *Slackscot) instrumentStorers() {
+	if s.featureFlagStorer != nil {
+		s.featureFlagStorer = NewStorerWithTelemetry(s.featureFlagStorer, s.name, featureFlagsPluginName, s.instrumenter.meter)
+	}
+
+	if s.scheduledActionStorer != nil {
+		s.scheduledActionStorer = NewStorerWithTelemetry(s.scheduledActionStorer, s.name, scheduledActionsPluginName, s.instrumenter.meter)
+	}
+
+	if s.responseStorer != nil {
+		s.responseStorer = NewStorerWithTelemetry(s.responseStorer, s.name, responsesStorerName, s.instrumenter.meter)
+	}
+
+	if s.jobStorer != nil {
+		s.jobStorer = NewStorerWithTelemetry(s.jobStorer, s.name, jobsStorerName, s.instrumenter.meter)
+	}
+}
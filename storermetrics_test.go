@@ -0,0 +1,47 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	opentelemetry "go.opentelemetry.io/otel/api/global"
+	"testing"
+)
+
+func TestStorerWithTelemetryDelegatesToBaseStorer(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	instrumented := NewStorerWithTelemetry(storer, "chickadee", "karma", opentelemetry.MeterProvider().Meter("test"))
+
+	require.NoError(t, instrumented.PutSiloString("Cgeneral", "alphonse", "10"))
+
+	value, err := instrumented.GetSiloString("Cgeneral", "alphonse")
+	require.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	entries, err := instrumented.ScanSilo("Cgeneral")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alphonse": "10"}, entries)
+
+	globalEntries, err := instrumented.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"Cgeneral": {"alphonse": "10"}}, globalEntries)
+
+	require.NoError(t, instrumented.DeleteSiloString("Cgeneral", "alphonse"))
+
+	_, err = instrumented.GetSiloString("Cgeneral", "alphonse")
+	assert.Error(t, err)
+}
+
+func TestSlackscotStorersInstrumented(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionResponseStorer(storer), OptionFeatureFlagStorer(storer))
+	require.NoError(t, err)
+
+	assert.IsType(t, StorerWithTelemetry{}, s.responseStorer)
+	assert.IsType(t, StorerWithTelemetry{}, s.featureFlagStorer)
+	assert.Nil(t, s.jobStorer)
+}
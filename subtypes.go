@@ -0,0 +1,36 @@
+package slackscot
+
+import (
+	"github.com/slack-go/slack"
+)
+
+// Subtypes of messages routed to plugins as new messages, along with regular messages (without a subtype). See
+// https://api.slack.com/events/message#message_subtypes
+const (
+	fileShareSubType       = "file_share"
+	threadBroadcastSubType = "thread_broadcast"
+	meMessageSubType       = "me_message"
+	botMessageSubType      = "bot_message"
+)
+
+// routedSubTypes are the subtypes of new messages routed to plugins. Messages of other subtypes (i.e. channel_join,
+// channel_topic or pinned_item) are notifications of something else happening rather than messages people (or bots)
+// wrote so they're ignored
+var routedSubTypes = map[string]bool{
+	"":                     true,
+	fileShareSubType:       true,
+	threadBroadcastSubType: true,
+	meMessageSubType:       true,
+	botMessageSubType:      true,
+}
+
+// isRoutedSubType returns true if new messages of the subtype are routed to plugins
+func isRoutedSubType(subType string) bool {
+	return routedSubTypes[subType]
+}
+
+// isBotMessage returns true if the message was sent by a bot (either as a bot_message or by a bot user), in which
+// case it's only routed to the plugins with ListenToBots
+func isBotMessage(m slack.Msg) bool {
+	return m.SubType == botMessageSubType || m.BotID != ""
+}
@@ -0,0 +1,76 @@
+package slackscot
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func optionSubType(subType string) testMsgOption {
+	return func(e *slack.MessageEvent) {
+		e.SubType = subType
+	}
+}
+
+func TestRoutedMessageSubTypes(t *testing.T) {
+	for _, subType := range []string{fileShareSubType, meMessageSubType, threadBroadcastSubType} {
+		t.Run(subType, func(t *testing.T) {
+			sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newTestPlugin(), []slack.RTMEvent{
+				newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays are cool", "Alphonse", timestamp1, optionSubType(subType))),
+			})
+
+			if assert.Len(t, sentMsgs, 1) {
+				assert.Equal(t, "I heard you say something about blue jays?", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+			}
+		})
+	}
+}
+
+func TestThreadBroadcastAnsweredInThread(t *testing.T) {
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays are cool", "Alphonse", timestamp1, optionSubType(threadBroadcastSubType), optionMessageOnThread(timestamp2))),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, timestamp2, applySlackOptions(sentMsgs[0].msgOptions...).Get("thread_ts"))
+	}
+}
+
+func TestNotificationMessageSubTypesIgnored(t *testing.T) {
+	sentMsgs, updatedMsgs, deletedMsgs, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "<@Alphonse> has joined the channel, blue jays", "Alphonse", timestamp1, optionSubType("channel_join"))),
+	})
+
+	assert.Empty(t, sentMsgs)
+	assert.Empty(t, updatedMsgs)
+	assert.Empty(t, deletedMsgs)
+}
+
+func TestMessagesFromOtherBotsIgnoredByDefault(t *testing.T) {
+	tests := map[string]*slack.MessageEvent{
+		"botMessage":        newMessageEvent("Cgeneral", "blue jays are cool", "", timestamp1, optionSubType(botMessageSubType), optionBotID("Bgithub")),
+		"botUserMessage":    newMessageEvent("Cgeneral", "blue jays are cool", "Ubuildbot", timestamp1, optionBotID("Bbuildbot")),
+		"unknownBotCommand": newMessageEvent("Cgeneral", "unknown command", "", timestamp1, optionSubType(botMessageSubType), optionBotID("Bgithub"), optionPublicMessageToBot(botUserID, "Cgeneral")),
+	}
+
+	for name, e := range tests {
+		t.Run(name, func(t *testing.T) {
+			sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, newTestPlugin(), []slack.RTMEvent{newRTMMessageEvent(e)})
+
+			assert.Empty(t, sentMsgs)
+		})
+	}
+}
+
+func TestMessagesFromOtherBotsHeardByPluginsListeningToBots(t *testing.T) {
+	p := newTestPlugin()
+	p.ListenToBots = true
+
+	sentMsgs, _, _, _, _ := runSlackscotWithIncomingEventsWithLogs(t, nil, p, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays are cool", "", timestamp1, optionSubType(botMessageSubType), optionBotID("Bgithub"))),
+	})
+
+	if assert.Len(t, sentMsgs, 1) {
+		assert.Equal(t, "I heard you say something about blue jays?", applySlackOptions(sentMsgs[0].msgOptions...).Get("text"))
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/slack-go/slack"
 	"github.com/stretchr/testify/assert"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -57,6 +59,12 @@ type ResultValidator func(t *testing.T, answers []*slackscot.Answer, emojis []st
 // if validation is successful and false otherwise (following the testify convention)
 type ResultWithUploadsValidator func(t *testing.T, answers []*slackscot.Answer, emojis []string, fileUploads []slack.FileUploadParameters) bool
 
+// ResultWithSentMessagesValidator is a function to do further validation of the answers, emoji reactions and messages
+// sent via the slackscot.RealTimeMessageSender resulting from a plugin processing of all of its commands and hear actions.
+// The messages sent are given as a map of channel IDs to messages sent on that channel. The return value is meant to be
+// true if validation is successful and false otherwise (following the testify convention)
+type ResultWithSentMessagesValidator func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMessagesByChannelID map[string][]string) bool
+
 // ScheduleResultValidator is a function to do further validation of the messages potentially sent by a
 // slackscot.ScheduledAction as well as files uploaded. The messages sent during the execution of
 // scheduled actions is given as a map of channel IDs to messages sent on that channel.
@@ -65,6 +73,13 @@ type ResultWithUploadsValidator func(t *testing.T, answers []*slackscot.Answer,
 // (following the testify convention)
 type ScheduleResultValidator func(t *testing.T, sentMessagesByChannelID map[string][]string, fileUploads []slack.FileUploadParameters) bool
 
+// WebhookResultValidator is a function to do further validation of the response of a plugin's webhook as well as the messages
+// sent during the handling of the request. The messages sent are given as a map of channel IDs to messages sent on that channel.
+//
+// The return value is meant to be true if validation is successful and false otherwise
+// (following the testify convention)
+type WebhookResultValidator func(t *testing.T, response *httptest.ResponseRecorder, sentMessagesByChannelID map[string][]string) bool
+
 // AnswersAndReacts drives a plugin and collects Answers as well as emoji reactions. Once all of those have been collected,
 // it passes handling to a validator to assert the expected answers and emoji reactions. It follows the style of
 // github.com/stretchr/testify/assert as far as returning true/false to indicate success for further nested testing.
@@ -84,6 +99,18 @@ func (a *Asserter) AnswersAndReactsWithUploads(p *slackscot.Plugin, m *slack.Msg
 	return validate(a.t, answers, emojis, fileUploads)
 }
 
+// AnswersAndReactsWithSentMessages drives a plugin and collects Answers as well as emoji reactions and messages sent via the
+// slackscot.RealTimeMessageSender (i.e. to channels other than the one of the incoming message). Once all of those have been
+// collected, it passes handling to a validator to assert the expected answers, emoji reactions and sent messages. It follows the
+// style of github.com/stretchr/testify/assert as far as returning true/false to indicate success for further nested testing.
+func (a *Asserter) AnswersAndReactsWithSentMessages(p *slackscot.Plugin, m *slack.Msg, validate ResultWithSentMessagesValidator) (valid bool) {
+	emojiCaptor, _, rtmSender := a.injectServices(p)
+
+	answers := a.driveActions(p, m)
+
+	return validate(a.t, answers, emojiCaptor.Emojis, rtmSender.SentMessages)
+}
+
 // RunsOnSchedule drives a plugin's scheduled actions that match the schedule definition being passed in (i.e. "Every 1 hour" will
 // run all actions scheduled to run every hour) and collects all the sent messages. Once all have been collected,
 // the results are passed to the ScheduleResultValidator as a map[string][]string where the key is the channel id
@@ -122,6 +149,26 @@ func (a *Asserter) DoesNotRunOnSchedule(p *slackscot.Plugin, schedule schedule.D
 	return assert.False(a.t, false)
 }
 
+// HandlesWebhook drives the plugin's webhook with the given path (relative to /webhooks/<plugin name>/) with the request and collects
+// the response as well as all the sent messages. Once all have been collected, the results are passed to the WebhookResultValidator
+func (a *Asserter) HandlesWebhook(p *slackscot.Plugin, path string, r *http.Request, validate WebhookResultValidator) (valid bool) {
+	_, _, rtmSender := a.injectServices(p)
+
+	paths := make([]string, 0)
+	for _, w := range p.Webhooks {
+		paths = append(paths, w.Path)
+
+		if w.Path == path {
+			response := httptest.NewRecorder()
+			w.Handle(response, r)
+
+			return validate(a.t, response, rtmSender.SentMessages)
+		}
+	}
+
+	return assert.Failf(a.t, "Webhook not found", "Expected a webhook with path [%s] but none found. Actual plugin webhook paths: %s", path, paths)
+}
+
 // injectServicesAndRun injects services in the plugin, drives all of its actions and returns the answers and captured data
 // from the execution
 func (a *Asserter) injectServicesAndRun(p *slackscot.Plugin, m *slack.Msg) (answers []*slackscot.Answer, emojis []string, fileUploads []slack.FileUploadParameters) {
@@ -158,30 +205,37 @@ func (a *Asserter) driveActions(p *slackscot.Plugin, m *slack.Msg) (answers []*s
 
 	if strings.HasPrefix(m.Text, botMentionPrefix) {
 		normalizedText := strings.TrimPrefix(m.Text, botMentionPrefix)
-		inMsg := slackscot.IncomingMessage{NormalizedText: normalizedText, Msg: *m}
+		inMsg := slackscot.NewIncomingMessage(normalizedText, *m, p.UserInfoFinder, nil)
 
-		return runActions(p.Commands, &inMsg)
+		return runActions(p.Commands, newCmdMatchMsg(p, inMsg), &inMsg)
 	}
 
-	inMsg := slackscot.IncomingMessage{NormalizedText: m.Text, Msg: *m}
+	inMsg := slackscot.NewIncomingMessage(m.Text, *m, p.UserInfoFinder, nil)
 
 	if strings.HasPrefix(m.Channel, "D") {
-		return runActions(p.Commands, &inMsg)
+		return runActions(p.Commands, newCmdMatchMsg(p, inMsg), &inMsg)
 	}
 
-	return runActions(p.HearActions, &inMsg)
+	return runActions(p.HearActions, &inMsg, &inMsg)
 }
 
-func runActions(actions []slackscot.ActionDefinition, m *slackscot.IncomingMessage) (answers []*slackscot.Answer) {
+// newCmdMatchMsg returns the message given to command Match functions. Just like slackscot would, the text is normalized
+// if the plugin has command normalization enabled
+func newCmdMatchMsg(p *slackscot.Plugin, inMsg slackscot.IncomingMessage) (matchMsg *slackscot.IncomingMessage) {
+	matchMsg = &inMsg
+	if p.NormalizeCommands {
+		matchMsg.NormalizedText = slackscot.NormalizeCommandText(inMsg.NormalizedText)
+	}
+
+	return matchMsg
+}
+
+func runActions(actions []slackscot.ActionDefinition, matchMsg *slackscot.IncomingMessage, m *slackscot.IncomingMessage) (answers []*slackscot.Answer) {
 	answers = make([]*slackscot.Answer, 0)
 
 	for _, action := range actions {
-		if action.Match(m) {
-			a := action.Answer(m)
-
-			if a != nil {
-				answers = append(answers, a)
-			}
+		if action.Match(matchMsg) {
+			answers = append(answers, action.Answers(m)...)
 		}
 	}
 
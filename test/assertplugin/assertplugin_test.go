@@ -1,6 +1,7 @@
 package assertplugin_test
 
 import (
+	"fmt"
 	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/schedule"
 	"github.com/alexandre-normand/slackscot/test/assertanswer"
@@ -8,6 +9,8 @@ import (
 	"github.com/slack-go/slack"
 	"github.com/stretchr/testify/assert"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -58,12 +61,25 @@ func newLittleTester() (mlt *myLittleTester) {
 			Description: "",
 			Answer:      mlt.emojiReact,
 		},
+		{
+			Hidden: true,
+			Match: func(m *slackscot.IncomingMessage) bool {
+				return strings.Contains(m.NormalizedText, "great horned owl")
+			},
+			Usage:       "",
+			Description: "",
+			Answer:      mlt.spreadTheWord,
+		},
 	}
 
 	mlt.ScheduledActions = []slackscot.ScheduledActionDefinition{
 		{Schedule: schedule.Definition{Interval: 1, Unit: schedule.Minutes}, Description: "Check health", Action: mlt.healthStatus},
 	}
 
+	mlt.Webhooks = []slackscot.WebhookDefinition{
+		{Path: "sightings", Handle: mlt.reportSighting},
+	}
+
 	return mlt
 }
 
@@ -94,6 +110,17 @@ func (mlt *myLittleTester) emojiReact(m *slackscot.IncomingMessage) *slackscot.A
 	return nil
 }
 
+func (mlt *myLittleTester) spreadTheWord(m *slackscot.IncomingMessage) *slackscot.Answer {
+	mlt.RealTimeMsgSender.SendMessage(mlt.RealTimeMsgSender.NewOutgoingMessage("great horned owl spotted", "birders"))
+
+	return &slackscot.Answer{Text: "🦉"}
+}
+
+func (mlt *myLittleTester) reportSighting(w http.ResponseWriter, r *http.Request) {
+	mlt.RealTimeMsgSender.SendMessage(mlt.RealTimeMsgSender.NewOutgoingMessage(fmt.Sprintf("%s spotted", r.URL.Query().Get("bird")), "birders"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
 func TestCommandResultNonValid(t *testing.T) {
 	mockT := new(testing.T)
 	assertplugin := assertplugin.New(mockT, "bot")
@@ -124,6 +151,16 @@ func TestFileUploadCapture(t *testing.T) {
 	}))
 }
 
+func TestSentMessagesCapture(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+
+	assert.Equal(t, true, assertplugin.AnswersAndReactsWithSentMessages(&myLittleTester.Plugin, &slack.Msg{Text: "I just saw a great horned owl"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string, sentMessages map[string][]string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "🦉") && assert.Equal(t, map[string][]string{"birders": {"great horned owl spotted"}}, sentMessages)
+	}))
+}
+
 func TestLoggerAttached(t *testing.T) {
 	mockT := new(testing.T)
 
@@ -207,6 +244,26 @@ func TestRunsOnScheduleAssertFailingValidator(t *testing.T) {
 	}))
 }
 
+func TestHandlesWebhookAssert(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+
+	assert.Equal(t, true, assertplugin.HandlesWebhook(&myLittleTester.Plugin, "sightings", httptest.NewRequest("POST", "/webhooks/tester/sightings?bird=snowy+owl", nil), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return assert.Equal(t, http.StatusAccepted, response.Code) && assert.Equal(t, map[string][]string{"birders": {"snowy owl spotted"}}, sentMsgs)
+	}))
+}
+
+func TestHandlesWebhookAssertWhenNotFound(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+
+	assert.Equal(t, false, assertplugin.HandlesWebhook(&myLittleTester.Plugin, "feeders", httptest.NewRequest("POST", "/webhooks/tester/feeders", nil), func(t *testing.T, response *httptest.ResponseRecorder, sentMsgs map[string][]string) bool {
+		return true
+	}))
+}
+
 func TestDoesNotOnScheduleAssert(t *testing.T) {
 	mockT := new(testing.T)
 	assertplugin := assertplugin.New(mockT, "bot")
@@ -222,3 +279,23 @@ func TestDoesNotOnScheduleAssertWhenRunsOnSchedule(t *testing.T) {
 
 	assert.Equal(t, false, assertplugin.DoesNotRunOnSchedule(&myLittleTester.Plugin, schedule.Definition{Interval: 1, Unit: schedule.Minutes}))
 }
+
+func TestNormalizedCommandAnswerGetsOriginalText(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+
+	p := &slackscot.Plugin{Name: "echo", NormalizeCommands: true, Commands: []slackscot.ActionDefinition{{
+		Match: func(m *slackscot.IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "echo ")
+		},
+		Usage:       "echo <something>",
+		Description: "Echoes what you said",
+		Answer: func(m *slackscot.IncomingMessage) *slackscot.Answer {
+			return &slackscot.Answer{Text: m.NormalizedText}
+		},
+	}}}
+
+	assert.Equal(t, true, assertplugin.AnswersAndReacts(p, &slack.Msg{Text: "<@bot> ECHO Hello World!"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "ECHO Hello World!")
+	}))
+}
@@ -0,0 +1,102 @@
+// Package theme defines the look (emojis, images and header flourishes) of the rich outputs of built-in plugins
+// (i.e. the karma top and worst leaderboards). The theme is selected by name with the theme configuration key
+// (see config.ThemeKey) and injected into plugins by slackscot:
+//
+//	{
+//	   "theme": "corporatePlain"
+//	}
+//
+// Banner images can be asset references (i.e. asset:trophy) resolved to images served by slackscot itself (see
+// assets.Registry). Custom themes can be registered with Register before creating slackscot.
+package theme
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/assets"
+	"github.com/alexandre-normand/slackscot/leaderboard"
+	"sort"
+	"sync"
+)
+
+// Theme is a named set of styles for rich outputs
+type Theme struct {
+	Name string
+
+	// TopLeaderboard is the look of rankings of the best things (i.e. karma top)
+	TopLeaderboard leaderboard.Theme
+
+	// WorstLeaderboard is the look of rankings of the worst things (i.e. karma worst)
+	WorstLeaderboard leaderboard.Theme
+}
+
+// Names of built-in themes
+const (
+	ClassicName        = "classic"
+	PodiumName         = "podium"
+	TrophyCaseName     = "trophyCase"
+	CorporatePlainName = "corporatePlain"
+)
+
+// Built-in themes
+var (
+	// Classic is the default theme with emoji flourishes around leaderboard banners and bullets in front of entries
+	Classic = Theme{Name: ClassicName,
+		TopLeaderboard:   leaderboard.Theme{RankIcon: leaderboard.BulletTheme.RankIcon, BannerPrefix: ":leaves::leaves::leaves::trophy: ", BannerSuffix: " :trophy::leaves::leaves::leaves:"},
+		WorstLeaderboard: leaderboard.Theme{RankIcon: leaderboard.BulletTheme.RankIcon, BannerPrefix: ":fallen_leaf::fallen_leaf::fallen_leaf::space_invader: ", BannerSuffix: " :space_invader::fallen_leaf::fallen_leaf::fallen_leaf:"}}
+
+	// Podium renders medals in front of the first things of top leaderboards and skulls in front of the first things
+	// of worst leaderboards
+	Podium = Theme{Name: PodiumName,
+		TopLeaderboard:   leaderboard.Theme{RankIcon: leaderboard.MedalTheme.RankIcon, BannerPrefix: ":trophy: ", BannerSuffix: " :trophy:"},
+		WorstLeaderboard: leaderboard.Theme{RankIcon: leaderboard.SkullTheme.RankIcon, BannerPrefix: ":coffin: ", BannerSuffix: " :coffin:"}}
+
+	// TrophyCase renders the classic leaderboards with trophy and tombstone banner images served by slackscot itself
+	// (see assets.Registry) rather than by external image hosts
+	TrophyCase = Theme{Name: TrophyCaseName,
+		TopLeaderboard:   leaderboard.Theme{RankIcon: leaderboard.BulletTheme.RankIcon, BannerPrefix: Classic.TopLeaderboard.BannerPrefix, BannerSuffix: Classic.TopLeaderboard.BannerSuffix, BannerImageURL: assets.RefPrefix + assets.BundledTrophy, BannerImageAltText: "trophy"},
+		WorstLeaderboard: leaderboard.Theme{RankIcon: leaderboard.BulletTheme.RankIcon, BannerPrefix: Classic.WorstLeaderboard.BannerPrefix, BannerSuffix: Classic.WorstLeaderboard.BannerSuffix, BannerImageURL: assets.RefPrefix + assets.BundledTombstone, BannerImageAltText: "tombstone"}}
+
+	// CorporatePlain renders plain numbered leaderboards without emojis or images for locked-down workspaces
+	CorporatePlain = Theme{Name: CorporatePlainName,
+		TopLeaderboard:   leaderboard.Theme{RankIcon: leaderboard.NumberedRankIcon},
+		WorstLeaderboard: leaderboard.Theme{RankIcon: leaderboard.NumberedRankIcon}}
+)
+
+// Default is the theme used when none is configured
+var Default = Classic
+
+var (
+	themesMutex sync.RWMutex
+	themes      = map[string]Theme{ClassicName: Classic, PodiumName: Podium, TrophyCaseName: TrophyCase, CorporatePlainName: CorporatePlain}
+)
+
+// Register makes a custom theme selectable by name. Registering a theme with the name of an existing one replaces it
+func Register(t Theme) {
+	themesMutex.Lock()
+	defer themesMutex.Unlock()
+
+	themes[t.Name] = t
+}
+
+// Get returns the theme with the given name. An error is returned if no such theme is registered
+func Get(name string) (t Theme, err error) {
+	themesMutex.RLock()
+	defer themesMutex.RUnlock()
+
+	t, ok := themes[name]
+	if !ok {
+		return t, fmt.Errorf("Unknown theme [%s], should be one of %v", name, names())
+	}
+
+	return t, nil
+}
+
+// names returns the sorted names of the registered themes
+func names() (names []string) {
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,40 @@
+package theme_test
+
+import (
+	"github.com/alexandre-normand/slackscot/leaderboard"
+	"github.com/alexandre-normand/slackscot/theme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetBuiltInThemes(t *testing.T) {
+	for _, expected := range []theme.Theme{theme.Classic, theme.Podium, theme.CorporatePlain} {
+		th, err := theme.Get(expected.Name)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Name, th.Name)
+		assert.Equal(t, expected.TopLeaderboard.BannerPrefix, th.TopLeaderboard.BannerPrefix)
+	}
+}
+
+func TestCorporatePlainThemeHasNoEmojisOrImages(t *testing.T) {
+	for _, lt := range []leaderboard.Theme{theme.CorporatePlain.TopLeaderboard, theme.CorporatePlain.WorstLeaderboard} {
+		assert.Empty(t, lt.BannerPrefix)
+		assert.Empty(t, lt.BannerSuffix)
+		assert.Empty(t, lt.BannerImageURL)
+		assert.Equal(t, "1.", lt.RankIcon(1))
+	}
+}
+
+func TestGetUnknownTheme(t *testing.T) {
+	_, err := theme.Get("disco")
+	assert.EqualError(t, err, "Unknown theme [disco], should be one of [classic corporatePlain podium trophyCase]")
+}
+
+func TestRegisterCustomTheme(t *testing.T) {
+	theme.Register(theme.Theme{Name: "autumn", TopLeaderboard: leaderboard.Theme{RankIcon: leaderboard.NumberedRankIcon, BannerImageURL: "https://example.com/leaves.png"}})
+
+	th, err := theme.Get("autumn")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/leaves.png", th.TopLeaderboard.BannerImageURL)
+}
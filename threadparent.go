@@ -0,0 +1,70 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+)
+
+// Behaviors for the responses to deleted triggering messages that started a thread (see config.ThreadParentDeletionKey)
+const (
+	threadParentDeletionDelete  = "delete"
+	threadParentDeletionReplace = "replace"
+	threadParentDeletionLeave   = "leave"
+)
+
+// originalMessageDeletedText replaces the text of responses to a deleted thread parent with the "replace" behavior
+const originalMessageDeletedText = "_(original message deleted)_"
+
+// tombstoneSubType is the subtype of the message slack leaves in place of a deleted thread parent that has replies. The
+// deletion is then sent as a message_changed to the tombstone rather than a message_deleted
+const tombstoneSubType = "tombstone"
+
+// validateThreadParentDeletion returns an error if the behavior isn't one of the known behaviors for the responses to
+// deleted thread parents
+func validateThreadParentDeletion(behavior string) (err error) {
+	switch behavior {
+	case threadParentDeletionDelete, threadParentDeletionReplace, threadParentDeletionLeave:
+		return nil
+	}
+
+	return fmt.Errorf("%s config should be one of [%s, %s, %s] but was [%s]", config.ThreadParentDeletionKey, threadParentDeletionDelete, threadParentDeletionReplace, threadParentDeletionLeave, behavior)
+}
+
+// isTombstone returns true if the message event is the change of a deleted thread parent to its tombstone
+func isTombstone(m slack.MessageEvent) bool {
+	return m.SubType == "message_changed" && m.SubMessage != nil && m.SubMessage.SubType == tombstoneSubType
+}
+
+// isDeletedThreadParent returns true if the deleted message started a thread, as seen from its previous state (or
+// because slack replaced it with a tombstone)
+func isDeletedThreadParent(m slack.MessageEvent) bool {
+	if isTombstone(m) {
+		return true
+	}
+
+	previous := m.PreviousMessage
+	return previous != nil && (previous.ReplyCount > 0 || (previous.ThreadTimestamp != "" && previous.ThreadTimestamp == previous.Timestamp))
+}
+
+// getDeletedMessageID returns the identifier of the message deleted by a message_deleted event or replaced by a tombstone
+func getDeletedMessageID(m slack.MessageEvent) (msgID SlackMessageID) {
+	if isTombstone(m) {
+		return getOriginalMessageID(m)
+	}
+
+	return SlackMessageID{channelID: m.Channel, timestamp: m.DeletedTimestamp}
+}
+
+// markOriginalMessageDeleted replaces a response to a deleted thread parent with a note that its triggering message was
+// deleted. Responses are deleted instead if the deleter can't update messages
+func (s *Slackscot) markOriginalMessageDeleted(deleter messageDeleter, r SlackMessageID) (err error) {
+	updater, ok := deleter.(messageUpdater)
+	if !ok {
+		_, _, err = deleter.DeleteMessage(r.channelID, r.timestamp)
+		return err
+	}
+
+	_, _, _, err = updater.UpdateMessage(r.channelID, r.timestamp, slack.MsgOptionText(originalMessageDeletedText, false), slack.MsgOptionAsUser(true))
+	return err
+}
@@ -0,0 +1,95 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func optionDeletedThreadParent(channelID string, timestamp string) testMsgOption {
+	return func(e *slack.MessageEvent) {
+		optionDeletedMessage(channelID, timestamp)(e)
+		e.PreviousMessage = &slack.Msg{Text: "blue jays", User: "Alphonse", Timestamp: timestamp, ThreadTimestamp: timestamp, ReplyCount: 1}
+	}
+}
+
+func optionTombstone(originalTs string) testMsgOption {
+	return func(e *slack.MessageEvent) {
+		e.SubType = "message_changed"
+		e.SubMessage = &slack.Msg{Text: "This message was deleted.", SubType: "tombstone", Timestamp: originalTs, ThreadTimestamp: originalTs}
+	}
+}
+
+func newThreadParentDeletionViper(behavior string) (v *viper.Viper) {
+	v = config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.ThreadParentDeletionKey, behavior)
+
+	return v
+}
+
+func TestThreadParentDeletionDeletesResponsesByDefault(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, v, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "", timestamp2, optionDeletedThreadParent("Cgeneral", timestamp1))),
+	}, nil)
+
+	assert.Len(t, sentMsgs, 1)
+	assert.Empty(t, updatedMsgs)
+	assert.Equal(t, []deletedMessage{{channelID: "Cgeneral", timestamp: formatTimestamp(firstReplyTimestamp)}}, deletedMsgs)
+}
+
+func TestThreadParentDeletionReplacesResponses(t *testing.T) {
+	for name, deletion := range map[string]testMsgOption{
+		"Deleted":   optionDeletedThreadParent("Cgeneral", timestamp1),
+		"Tombstone": optionTombstone(timestamp1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, newThreadParentDeletionViper("replace"), newTestPlugin(), []slack.RTMEvent{
+				newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+				newRTMMessageEvent(newMessageEvent("Cgeneral", "", "", timestamp2, deletion)),
+			}, nil)
+
+			assert.Len(t, sentMsgs, 1)
+			assert.Empty(t, deletedMsgs)
+			if assert.Len(t, updatedMsgs, 1) {
+				assert.Equal(t, formatTimestamp(firstReplyTimestamp), updatedMsgs[0].timestamp)
+				assert.Equal(t, "_(original message deleted)_", applySlackOptions(updatedMsgs[0].msgOptions...).Get("text"))
+			}
+		})
+	}
+}
+
+func TestThreadParentDeletionLeavesResponses(t *testing.T) {
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, newThreadParentDeletionViper("leave"), newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "", timestamp2, optionTombstone(timestamp1))),
+	}, nil)
+
+	assert.Len(t, sentMsgs, 1)
+	assert.Empty(t, updatedMsgs)
+	assert.Empty(t, deletedMsgs)
+}
+
+func TestThreadParentDeletionBehaviorOnlyAppliesToThreadParents(t *testing.T) {
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, newThreadParentDeletionViper("leave"), newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "", timestamp2, optionDeletedMessage("Cgeneral", timestamp1))),
+	}, nil)
+
+	assert.Len(t, sentMsgs, 1)
+	assert.Empty(t, updatedMsgs)
+	assert.Len(t, deletedMsgs, 1)
+}
+
+func TestInvalidThreadParentDeletion(t *testing.T) {
+	_, err := New("chickadee", newThreadParentDeletionViper("archive"))
+	require.Error(t, err)
+	assert.Equal(t, "threadParentDeletion config should be one of [delete, replace, leave] but was [archive]", err.Error())
+}
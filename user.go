@@ -3,20 +3,54 @@ package slackscot
 import (
 	"fmt"
 	"github.com/alexandre-normand/slackscot/config"
-	"github.com/hashicorp/golang-lru"
 	"github.com/slack-go/slack"
 	"github.com/spf13/viper"
+	"sync"
+	"time"
 )
 
 const (
 	userInfoCacheSizeDisabledValue = 0
 )
 
+// User info fallback modes deciding what plugins get when user info can't be loaded from slack
+// (see config.UserInfoFallbackKey)
+const (
+	// UserInfoFallbackError returns the error to plugins (the default)
+	UserInfoFallbackError = "error"
+
+	// UserInfoFallbackMention returns a placeholder user with the user's mention (i.e. <@U21355>) as its names
+	UserInfoFallbackMention = "mention"
+)
+
 // UserInfoFinder defines the interface for finding a slack user's info
 type UserInfoFinder interface {
 	GetUserInfo(userID string) (user *slack.User, err error)
 }
 
+// UsersInfoFinder defines the interface for finding the info of many slack users at once (i.e. to render leaderboards
+// mentioning many users). Users are returned in the order of the given ids. Users that can't be found are left out and
+// reported in the error
+type UsersInfoFinder interface {
+	GetUsersInfo(userIDs []string) (users []slack.User, err error)
+}
+
+// UserInfoInvalidator defines the interface for invalidating the cached info of a slack user (i.e. after a plugin
+// changed the user's profile) so that the next lookup loads it from slack
+type UserInfoInvalidator interface {
+	InvalidateUser(userID string)
+}
+
+// usersLister defines the interface for listing all users of a slack workspace. It is satisfied by *slack.Client
+type usersLister interface {
+	GetUsers() (users []slack.User, err error)
+}
+
+// ChannelInfoFinder defines the interface for finding a slack channel's info. It is satisfied by *slack.Client
+type ChannelInfoFinder interface {
+	GetConversationInfo(channelID string, includeLocale bool) (channel *slack.Channel, err error)
+}
+
 // selfInfoFinder defines the interface for finding our (the slackscot instance) user info
 type selfInfoFinder interface {
 	GetInfo() (user *slack.Info)
@@ -26,7 +60,7 @@ type selfInfoFinder interface {
 type cachingUserInfoFinder struct {
 	loader           UserInfoFinder
 	logger           SLogger
-	userProfileCache *lru.ARCCache
+	userProfileCache *resizableCache
 }
 
 // NewCachingUserInfoFinder creates a new user info service with caching if enabled via userProfileCacheSizeKey. It requires an implementation
@@ -37,7 +71,7 @@ func NewCachingUserInfoFinder(v *viper.Viper, loader UserInfoFinder, logger SLog
 	cs := v.GetInt(config.UserInfoCacheSizeKey)
 
 	if cs > userInfoCacheSizeDisabledValue {
-		cuf.userProfileCache, err = lru.NewARC(cs)
+		cuf.userProfileCache, err = newResizableCache(cs)
 		if err != nil {
 			return nil, err
 		}
@@ -78,3 +112,339 @@ func (c cachingUserInfoFinder) GetUserInfo(userID string) (u *slack.User, err er
 
 	return u, err
 }
+
+// GetUsersInfo gets the info of users from cache, if enabled, and loads the ones not in cache with a single batched
+// lookup, if the loader supports it (see batchUserInfoFinder). Loaded users are added to the cache
+func (c cachingUserInfoFinder) GetUsersInfo(userIDs []string) (users []slack.User, err error) {
+	found := make(map[string]slack.User)
+	misses := make([]string, 0)
+
+	for _, userID := range uniqueUserIDs(userIDs) {
+		if c.userProfileCache != nil {
+			if userProfile, exists := c.userProfileCache.Get(userID); exists {
+				if userProfile, ok := userProfile.(slack.User); ok {
+					found[userID] = userProfile
+					continue
+				}
+			}
+		}
+
+		misses = append(misses, userID)
+	}
+
+	if len(misses) > 0 {
+		c.logger.Debugf("User info for [%d] users not found in cache, retrieving from slack\n", len(misses))
+
+		var loaded []slack.User
+		loaded, err = getUsersInfo(c.loader, misses)
+		for _, u := range loaded {
+			found[u.ID] = u
+
+			if c.userProfileCache != nil {
+				c.userProfileCache.Add(u.ID, u)
+			}
+		}
+	}
+
+	return orderedUsers(userIDs, found), err
+}
+
+// InvalidateUser evicts the user's info from cache, if enabled
+func (c cachingUserInfoFinder) InvalidateUser(userID string) {
+	if c.userProfileCache != nil {
+		c.userProfileCache.Remove(userID)
+	}
+}
+
+// refreshUser replaces the cached info of a user with its latest version (i.e. from a user_change event). Users not
+// in cache aren't added so that changes of users nobody looked up don't push others out of the cache
+func (c cachingUserInfoFinder) refreshUser(u slack.User) {
+	if c.userProfileCache != nil && c.userProfileCache.Contains(u.ID) {
+		c.userProfileCache.Add(u.ID, u)
+	}
+}
+
+// userInfoRefresher defines the interface for refreshing the cached info of a user with its latest version
+type userInfoRefresher interface {
+	refreshUser(u slack.User)
+}
+
+// refreshUserInfo refreshes the cached info of a user that changed (i.e. was renamed) so that plugins don't get stale
+// names for the cache lifetime. Recent failures to load the user's info are also forgotten
+func (s *Slackscot) refreshUserInfo(u slack.User) {
+	s.log.Debugf("User [%s] changed, refreshing its cached info\n", u.ID)
+
+	if s.userInfoFailures != nil {
+		s.userInfoFailures.forget(u.ID)
+	}
+
+	if refresher, ok := s.userInfoFinder.(userInfoRefresher); ok {
+		refresher.refreshUser(u)
+	}
+}
+
+// batchUserInfoFinder adds batched lookups to a UserInfoFinder by listing the users of the workspace in a single
+// (paginated) call rather than looking users up one by one
+type batchUserInfoFinder struct {
+	UserInfoFinder
+	lister usersLister
+}
+
+// GetUsersInfo lists the users of the workspace and returns the ones with the given ids
+func (b batchUserInfoFinder) GetUsersInfo(userIDs []string) (users []slack.User, err error) {
+	all, err := b.lister.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]slack.User)
+	for _, u := range all {
+		found[u.ID] = u
+	}
+
+	return orderedUsers(userIDs, found), newUsersNotFoundError(userIDs, found, fmt.Errorf("user_not_found"))
+}
+
+// getUsersInfo gets the info of users with a batched lookup if the finder supports it or one user at a time otherwise
+func getUsersInfo(finder UserInfoFinder, userIDs []string) (users []slack.User, err error) {
+	if uf, ok := finder.(UsersInfoFinder); ok {
+		return uf.GetUsersInfo(userIDs)
+	}
+
+	found := make(map[string]slack.User)
+	var firstErr error
+
+	for _, userID := range uniqueUserIDs(userIDs) {
+		u, err := finder.GetUserInfo(userID)
+		if err != nil || u == nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		found[userID] = *u
+	}
+
+	return orderedUsers(userIDs, found), newUsersNotFoundError(userIDs, found, firstErr)
+}
+
+// newUsersNotFoundError returns an error naming the users that weren't found along with the cause or nil if all
+// users were found
+func newUsersNotFoundError(userIDs []string, found map[string]slack.User, cause error) (err error) {
+	missing := make([]string, 0)
+	for _, userID := range uniqueUserIDs(userIDs) {
+		if _, ok := found[userID]; !ok {
+			missing = append(missing, userID)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("Error getting user info for %v: %v", missing, cause)
+}
+
+// uniqueUserIDs returns the user ids without duplicates, in order
+func uniqueUserIDs(userIDs []string) (unique []string) {
+	seen := make(map[string]bool)
+	for _, userID := range userIDs {
+		if !seen[userID] {
+			seen[userID] = true
+			unique = append(unique, userID)
+		}
+	}
+
+	return unique
+}
+
+// orderedUsers returns the found users in the order of the user ids
+func orderedUsers(userIDs []string, found map[string]slack.User) (users []slack.User) {
+	users = make([]slack.User, 0, len(found))
+	for _, userID := range uniqueUserIDs(userIDs) {
+		if u, ok := found[userID]; ok {
+			users = append(users, u)
+		}
+	}
+
+	return users
+}
+
+// userInfoFailures remembers failures to load user info so that lookups of the same users aren't retried
+// until retryAfter has passed (i.e. to avoid hammering slack during an outage). It's shared by all plugins
+type userInfoFailures struct {
+	retryAfter time.Duration
+	now        func() time.Time
+
+	mutex    sync.Mutex
+	failures map[string]userInfoFailure
+}
+
+// userInfoFailure is a failure to load a user's info and the time it happened
+type userInfoFailure struct {
+	err error
+	at  time.Time
+}
+
+// newUserInfoFailures creates a new userInfoFailures retrying failed lookups after retryAfter. A retryAfter of 0
+// disables the remembering of failures
+func newUserInfoFailures(retryAfter time.Duration) (f *userInfoFailures) {
+	f = new(userInfoFailures)
+	f.retryAfter = retryAfter
+	f.now = time.Now
+	f.failures = make(map[string]userInfoFailure)
+
+	return f
+}
+
+// get returns the error of a recent failure to load a user's info or nil if there's none (or it's time to retry)
+func (f *userInfoFailures) get(userID string) (err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	failure, ok := f.failures[userID]
+	if !ok {
+		return nil
+	}
+
+	if f.now().Sub(failure.at) >= f.retryAfter {
+		delete(f.failures, userID)
+		return nil
+	}
+
+	return failure.err
+}
+
+// forget forgets the last failure to load a user's info, if any, so that the next lookup goes to slack
+func (f *userInfoFailures) forget(userID string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.failures, userID)
+}
+
+// record records the outcome of loading a user's info
+func (f *userInfoFailures) record(userID string, err error) {
+	if f.retryAfter <= 0 {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err == nil {
+		delete(f.failures, userID)
+		return
+	}
+
+	f.failures[userID] = userInfoFailure{err: err, at: f.now()}
+}
+
+// fallbackUserInfoFinder is the UserInfoFinder injected into plugins. It applies the plugin's fallback mode
+// when user info can't be loaded so that transient failures of the slack user api don't break answers
+type fallbackUserInfoFinder struct {
+	finder   UserInfoFinder
+	failures *userInfoFailures
+	mode     string
+	logger   SLogger
+}
+
+// newUserInfoFallbackMode returns the user info fallback mode after validating it. An empty mode is the default
+// (UserInfoFallbackError). The mode key is included in the error to help find the faulty configuration
+func newUserInfoFallbackMode(mode string, modeKey string) (validMode string, err error) {
+	switch mode {
+	case "":
+		return UserInfoFallbackError, nil
+	case UserInfoFallbackError, UserInfoFallbackMention:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%s config should be one of [%s, %s] but was [%s]", modeKey, UserInfoFallbackError, UserInfoFallbackMention, mode)
+	}
+}
+
+// GetUserInfo gets the user info with the finder. Recent failures are returned without retrying and, on failure,
+// a placeholder user is returned instead of the error if the fallback mode is UserInfoFallbackMention
+func (f fallbackUserInfoFinder) GetUserInfo(userID string) (u *slack.User, err error) {
+	if err = f.failures.get(userID); err == nil {
+		u, err = f.finder.GetUserInfo(userID)
+		f.failures.record(userID, err)
+	}
+
+	if err == nil || f.mode != UserInfoFallbackMention {
+		return u, err
+	}
+
+	f.logger.Debugf("Falling back to mentioning user [%s] after failing to get user info: %v\n", userID, err)
+
+	mention := newMentionUser(userID)
+	return &mention, nil
+}
+
+// GetUsersInfo gets the info of users with a batched lookup, skipping users with recent failures. Just like
+// GetUserInfo, users that can't be found are replaced with placeholders if the fallback mode is UserInfoFallbackMention
+func (f fallbackUserInfoFinder) GetUsersInfo(userIDs []string) (users []slack.User, err error) {
+	found := make(map[string]slack.User)
+	lookups := make([]string, 0)
+
+	var failureErr error
+	for _, userID := range uniqueUserIDs(userIDs) {
+		if failure := f.failures.get(userID); failure != nil {
+			failureErr = failure
+			continue
+		}
+
+		lookups = append(lookups, userID)
+	}
+
+	if len(lookups) > 0 {
+		var loaded []slack.User
+		loaded, err = getUsersInfo(f.finder, lookups)
+		for _, u := range loaded {
+			found[u.ID] = u
+		}
+
+		for _, userID := range lookups {
+			if _, ok := found[userID]; ok {
+				f.failures.record(userID, nil)
+			} else {
+				f.failures.record(userID, err)
+			}
+		}
+	}
+
+	// Users with recent failures are reported with the error of their last lookup
+	if failureErr != nil {
+		err = newUsersNotFoundError(userIDs, found, failureErr)
+	}
+
+	if err == nil || f.mode != UserInfoFallbackMention {
+		return orderedUsers(userIDs, found), err
+	}
+
+	f.logger.Debugf("Falling back to mentioning users after failing to get user info: %v\n", err)
+
+	for _, userID := range userIDs {
+		if _, ok := found[userID]; !ok {
+			found[userID] = newMentionUser(userID)
+		}
+	}
+
+	return orderedUsers(userIDs, found), nil
+}
+
+// InvalidateUser evicts the user's info from cache and forgets recent failures to load it so that the next lookup
+// loads it from slack
+func (f fallbackUserInfoFinder) InvalidateUser(userID string) {
+	f.failures.forget(userID)
+
+	if invalidator, ok := f.finder.(UserInfoInvalidator); ok {
+		invalidator.InvalidateUser(userID)
+	}
+}
+
+// newMentionUser returns a placeholder user named with its mention (i.e. <@U21355>)
+func newMentionUser(userID string) (u slack.User) {
+	mention := fmt.Sprintf("<@%s>", userID)
+	return slack.User{ID: userID, Name: mention, RealName: mention, Profile: slack.UserProfile{RealName: mention, DisplayName: mention}}
+}
@@ -0,0 +1,302 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyUserInfoFinder fails to load user info while failing is true and counts calls
+type flakyUserInfoFinder struct {
+	failing bool
+	calls   int
+}
+
+func (f *flakyUserInfoFinder) GetUserInfo(userID string) (user *slack.User, err error) {
+	f.calls++
+	if f.failing {
+		return nil, fmt.Errorf("slack is down")
+	}
+
+	return &slack.User{ID: userID, RealName: "Daniel Quinn"}, nil
+}
+
+func newTestFallbackUserInfoFinder(loader UserInfoFinder, mode string, retryAfter time.Duration) (f fallbackUserInfoFinder, now *time.Time) {
+	now = new(time.Time)
+	*now = time.Date(2019, 1, 7, 10, 0, 0, 0, time.UTC)
+
+	failures := newUserInfoFailures(retryAfter)
+	failures.now = func() time.Time { return *now }
+
+	return fallbackUserInfoFinder{finder: loader, failures: failures, mode: mode, logger: NewSLogger(log.New(&strings.Builder{}, "", 0), false)}, now
+}
+
+func TestUserInfoFallbackWithErrorMode(t *testing.T) {
+	f, _ := newTestFallbackUserInfoFinder(&flakyUserInfoFinder{failing: true}, UserInfoFallbackError, 0)
+
+	u, err := f.GetUserInfo("U21355")
+	assert.EqualError(t, err, "slack is down")
+	assert.Nil(t, u)
+}
+
+func TestUserInfoFallbackWithMentionMode(t *testing.T) {
+	f, _ := newTestFallbackUserInfoFinder(&flakyUserInfoFinder{failing: true}, UserInfoFallbackMention, 0)
+
+	u, err := f.GetUserInfo("U21355")
+	require.NoError(t, err)
+	assert.Equal(t, "U21355", u.ID)
+	assert.Equal(t, "<@U21355>", u.RealName)
+	assert.Equal(t, "<@U21355>", u.Profile.DisplayName)
+}
+
+func TestUserInfoFailuresRetriedLater(t *testing.T) {
+	loader := &flakyUserInfoFinder{failing: true}
+	f, now := newTestFallbackUserInfoFinder(loader, UserInfoFallbackMention, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		u, err := f.GetUserInfo("U21355")
+		require.NoError(t, err)
+		assert.Equal(t, "<@U21355>", u.RealName)
+	}
+	assert.Equal(t, 1, loader.calls)
+
+	// Failures of other users aren't shared
+	_, err := f.GetUserInfo("U99999")
+	require.NoError(t, err)
+	assert.Equal(t, 2, loader.calls)
+
+	loader.failing = false
+	*now = now.Add(time.Minute)
+
+	u, err := f.GetUserInfo("U21355")
+	require.NoError(t, err)
+	assert.Equal(t, "Daniel Quinn", u.RealName)
+	assert.Equal(t, 3, loader.calls)
+}
+
+func TestUserInfoFailuresNotRememberedWithoutRetryAfter(t *testing.T) {
+	loader := &flakyUserInfoFinder{failing: true}
+	f, _ := newTestFallbackUserInfoFinder(loader, UserInfoFallbackError, 0)
+
+	f.GetUserInfo("U21355")
+	f.GetUserInfo("U21355")
+	assert.Equal(t, 2, loader.calls)
+}
+
+func TestInjectUserInfoFallbackPerPlugin(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.karma.userInfoFallback", UserInfoFallbackMention)
+
+	s, err := New("robert", v)
+	require.NoError(t, err)
+
+	karma := &Plugin{Name: "karma"}
+	help := &Plugin{Name: "help"}
+	s.RegisterPlugin(karma)
+	s.RegisterPlugin(help)
+
+	require.NoError(t, s.injectServicesToPlugins(&flakyUserInfoFinder{failing: true}, s.log, nil, nil, nil, nil))
+
+	u, err := karma.UserInfoFinder.GetUserInfo("U21355")
+	require.NoError(t, err)
+	assert.Equal(t, "<@U21355>", u.RealName)
+
+	_, err = help.UserInfoFinder.GetUserInfo("U21355")
+	assert.EqualError(t, err, "slack is down")
+
+	users, err := karma.UsersInfoFinder.GetUsersInfo([]string{"U21355"})
+	require.NoError(t, err)
+	assert.Equal(t, "<@U21355>", users[0].RealName)
+}
+
+func TestInvalidateUserReloadsUserInfo(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.UserInfoCacheSizeKey, 10)
+
+	s, err := New("robert", v)
+	require.NoError(t, err)
+
+	karma := &Plugin{Name: "karma"}
+	s.RegisterPlugin(karma)
+
+	loader := &flakyUserInfoFinder{}
+	require.NoError(t, s.injectServicesToPlugins(loader, s.log, nil, nil, nil, nil))
+
+	karma.UserInfoFinder.GetUserInfo("U21355")
+	karma.UserInfoFinder.GetUserInfo("U21355")
+	assert.Equal(t, 1, loader.calls)
+
+	karma.UserInfoInvalidator.InvalidateUser("U21355")
+
+	karma.UserInfoFinder.GetUserInfo("U21355")
+	assert.Equal(t, 2, loader.calls)
+}
+
+func TestInvalidateUserForgetsFailures(t *testing.T) {
+	loader := &flakyUserInfoFinder{failing: true}
+	f, _ := newTestFallbackUserInfoFinder(loader, UserInfoFallbackError, time.Minute)
+
+	f.GetUserInfo("U21355")
+	loader.failing = false
+	f.InvalidateUser("U21355")
+
+	u, err := f.GetUserInfo("U21355")
+	require.NoError(t, err)
+	assert.Equal(t, "Daniel Quinn", u.RealName)
+	assert.Equal(t, 2, loader.calls)
+}
+
+func TestUserChangeRefreshesCachedUserInfo(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.UserInfoCacheSizeKey, 10)
+
+	s, err := New("robert", v)
+	require.NoError(t, err)
+
+	karma := &Plugin{Name: "karma"}
+	s.RegisterPlugin(karma)
+
+	loader := &flakyUserInfoFinder{}
+	require.NoError(t, s.injectServicesToPlugins(loader, s.log, nil, nil, nil, nil))
+
+	karma.UserInfoFinder.GetUserInfo("U21355")
+	s.refreshUserInfo(slack.User{ID: "U21355", RealName: "Danny Quinn"})
+
+	// Users not in cache aren't added on change
+	s.refreshUserInfo(slack.User{ID: "U99999", RealName: "Bernard"})
+
+	u, err := karma.UserInfoFinder.GetUserInfo("U21355")
+	require.NoError(t, err)
+	assert.Equal(t, "Danny Quinn", u.RealName)
+	assert.Equal(t, 1, loader.calls)
+
+	u, err = karma.UserInfoFinder.GetUserInfo("U99999")
+	require.NoError(t, err)
+	assert.Equal(t, "Daniel Quinn", u.RealName)
+	assert.Equal(t, 2, loader.calls)
+}
+
+func TestInvalidUserInfoFallback(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.UserInfoFallbackKey, "shrug")
+
+	_, err := New("robert", v)
+	assert.EqualError(t, err, "userInfoFallback.mode config should be one of [error, mention] but was [shrug]")
+
+	v = config.NewViperWithDefaults()
+	v.Set("plugins.karma.userInfoFallback", "shrug")
+
+	s, err := New("robert", v)
+	require.NoError(t, err)
+
+	s.RegisterPlugin(&Plugin{Name: "karma"})
+	assert.EqualError(t, s.injectServicesToPlugins(&flakyUserInfoFinder{}, s.log, nil, nil, nil, nil), "plugins.karma.userInfoFallback config should be one of [error, mention] but was [shrug]")
+}
+
+// workspace lists users and counts the calls to list them
+type workspace struct {
+	users []slack.User
+	calls int
+	err   error
+}
+
+func (w *workspace) GetUsers() (users []slack.User, err error) {
+	w.calls++
+	return w.users, w.err
+}
+
+func newTestWorkspace() (w *workspace) {
+	return &workspace{users: []slack.User{{ID: "U1", RealName: "Bernard"}, {ID: "U2", RealName: "Alphonse"}, {ID: "U3", RealName: "Daniel"}}}
+}
+
+func TestBatchUserInfoFinderListsUsersOnce(t *testing.T) {
+	w := newTestWorkspace()
+	loader := &flakyUserInfoFinder{}
+	b := batchUserInfoFinder{UserInfoFinder: loader, lister: w}
+
+	users, err := b.GetUsersInfo([]string{"U3", "U1", "U3"})
+	require.NoError(t, err)
+	assert.Equal(t, []slack.User{{ID: "U3", RealName: "Daniel"}, {ID: "U1", RealName: "Bernard"}}, users)
+	assert.Equal(t, 1, w.calls)
+	assert.Equal(t, 0, loader.calls)
+
+	users, err = b.GetUsersInfo([]string{"U2", "U9"})
+	assert.EqualError(t, err, "Error getting user info for [U9]: user_not_found")
+	assert.Equal(t, []slack.User{{ID: "U2", RealName: "Alphonse"}}, users)
+}
+
+func TestCachingUsersInfoFinderOnlyLoadsMisses(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.UserInfoCacheSizeKey, 10)
+
+	w := newTestWorkspace()
+	uf, err := NewCachingUserInfoFinder(v, batchUserInfoFinder{UserInfoFinder: &flakyUserInfoFinder{}, lister: w}, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	users, err := uf.(UsersInfoFinder).GetUsersInfo([]string{"U1", "U2"})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, 1, w.calls)
+
+	// Cached users don't need another listing and single lookups get them from cache too
+	users, err = uf.(UsersInfoFinder).GetUsersInfo([]string{"U2", "U1"})
+	require.NoError(t, err)
+	assert.Equal(t, []slack.User{{ID: "U2", RealName: "Alphonse"}, {ID: "U1", RealName: "Bernard"}}, users)
+
+	u, err := uf.GetUserInfo("U1")
+	require.NoError(t, err)
+	assert.Equal(t, "Bernard", u.RealName)
+	assert.Equal(t, 1, w.calls)
+
+	_, err = uf.(UsersInfoFinder).GetUsersInfo([]string{"U1", "U3"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, w.calls)
+}
+
+func TestUsersInfoWithoutBatchSupportLoadedOneByOne(t *testing.T) {
+	loader := &flakyUserInfoFinder{}
+
+	users, err := getUsersInfo(loader, []string{"U1", "U2", "U1"})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, 2, loader.calls)
+
+	loader.failing = true
+	users, err = getUsersInfo(loader, []string{"U1", "U2"})
+	assert.EqualError(t, err, "Error getting user info for [U1 U2]: slack is down")
+	assert.Empty(t, users)
+}
+
+func TestUsersInfoFallback(t *testing.T) {
+	w := newTestWorkspace()
+	w.err = fmt.Errorf("slack is down")
+
+	f, now := newTestFallbackUserInfoFinder(batchUserInfoFinder{UserInfoFinder: &flakyUserInfoFinder{}, lister: w}, UserInfoFallbackMention, time.Minute)
+
+	users, err := f.GetUsersInfo([]string{"U1", "U2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"<@U1>", "<@U2>"}, []string{users[0].RealName, users[1].RealName})
+
+	// Failures are remembered until it's time to retry
+	w.err = nil
+	_, err = f.GetUsersInfo([]string{"U1", "U2"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, w.calls)
+
+	*now = now.Add(time.Minute)
+	users, err = f.GetUsersInfo([]string{"U1", "U2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bernard", "Alphonse"}, []string{users[0].RealName, users[1].RealName})
+	assert.Equal(t, 2, w.calls)
+
+	f.mode = UserInfoFallbackError
+	users, err = f.GetUsersInfo([]string{"U1", "U9"})
+	assert.EqualError(t, err, "Error getting user info for [U9]: user_not_found")
+	assert.Equal(t, []slack.User{{ID: "U1", RealName: "Bernard"}}, users)
+}
@@ -0,0 +1,127 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"time"
+)
+
+// userInfoCacheSilo is the silo of the user info cache persisted on shutdown with the responses when a response storer
+// is set (see OptionResponseStorer), keyed by user id
+const userInfoCacheSilo = "userInfoCache"
+
+// persistedUserInfo is the persisted form of a cached user's info
+type persistedUserInfo struct {
+	User    slack.User `json:"user"`
+	SavedAt time.Time  `json:"savedAt"`
+}
+
+// cachedUsers returns the info of all users in cache, if enabled
+func (c cachingUserInfoFinder) cachedUsers() (users []slack.User) {
+	users = make([]slack.User, 0)
+	if c.userProfileCache == nil {
+		return users
+	}
+
+	for _, key := range c.userProfileCache.Keys() {
+		if cached, exists := c.userProfileCache.Peek(key); exists {
+			if u, ok := cached.(slack.User); ok {
+				users = append(users, u)
+			}
+		}
+	}
+
+	return users
+}
+
+// warmUp adds the info of users to the cache, if enabled, without replacing users already cached
+func (c cachingUserInfoFinder) warmUp(users []slack.User) {
+	if c.userProfileCache == nil {
+		return
+	}
+
+	for _, u := range users {
+		if !c.userProfileCache.Contains(u.ID) {
+			c.userProfileCache.Add(u.ID, u)
+		}
+	}
+}
+
+// userInfoCachePersister defines the interface of user info caches that can be saved and reloaded
+type userInfoCachePersister interface {
+	cachedUsers() (users []slack.User)
+	warmUp(users []slack.User)
+}
+
+// saveUserInfoCache persists the user info cache in the response storer (on shutdown) so that it's reloaded on startup
+// instead of loading every user from slack again. Users persisted before and no longer in cache are deleted
+func (s *Slackscot) saveUserInfoCache(now time.Time) (saved int, err error) {
+	persister, ok := s.userInfoFinder.(userInfoCachePersister)
+	if !ok || s.responseStorer == nil || s.config.GetDuration(config.UserInfoCacheRetentionKey) == 0 {
+		return 0, nil
+	}
+
+	users := persister.cachedUsers()
+	cached := make(map[string]bool)
+	for _, u := range users {
+		value, err := json.Marshal(persistedUserInfo{User: u, SavedAt: now})
+		if err != nil {
+			return saved, err
+		}
+
+		if err := s.responseStorer.PutSiloString(userInfoCacheSilo, u.ID, string(value)); err != nil {
+			return saved, err
+		}
+
+		cached[u.ID] = true
+		saved = saved + 1
+	}
+
+	entries, err := s.responseStorer.ScanSilo(userInfoCacheSilo)
+	if err != nil {
+		return saved, err
+	}
+
+	for userID := range entries {
+		if !cached[userID] {
+			if err := s.responseStorer.DeleteSiloString(userInfoCacheSilo, userID); err != nil {
+				return saved, err
+			}
+		}
+	}
+
+	return saved, nil
+}
+
+// loadUserInfoCache reloads the user info cache persisted on shutdown (see saveUserInfoCache). Users saved longer than
+// config.UserInfoCacheRetentionKey ago are left out given that their info might have changed while slackscot was down
+func (s *Slackscot) loadUserInfoCache(now time.Time) (loaded int, err error) {
+	persister, ok := s.userInfoFinder.(userInfoCachePersister)
+	if !ok || s.responseStorer == nil {
+		return 0, nil
+	}
+
+	entries, err := s.responseStorer.ScanSilo(userInfoCacheSilo)
+	if err != nil {
+		return 0, err
+	}
+
+	retention := s.config.GetDuration(config.UserInfoCacheRetentionKey)
+	users := make([]slack.User, 0, len(entries))
+	for userID, value := range entries {
+		var persisted persistedUserInfo
+		if err := json.Unmarshal([]byte(value), &persisted); err != nil {
+			s.log.Printf("Error decoding persisted user info of [%s], ignoring it: %v", userID, err)
+			continue
+		}
+
+		if now.Sub(persisted.SavedAt) <= retention {
+			users = append(users, persisted.User)
+		}
+	}
+
+	persister.warmUp(users)
+
+	return len(users), nil
+}
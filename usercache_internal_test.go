@@ -0,0 +1,82 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestUserInfoCacheReloadedAfterRestart(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.UserInfoCacheSizeKey, 10)
+
+	s, err := New("robert", v, OptionResponseStorer(storer))
+	require.NoError(t, err)
+
+	karma := &Plugin{Name: "karma"}
+	s.RegisterPlugin(karma)
+
+	loader := &flakyUserInfoFinder{}
+	require.NoError(t, s.injectServicesToPlugins(loader, s.log, nil, nil, nil, nil))
+
+	karma.UserInfoFinder.GetUserInfo("U21355")
+	karma.UserInfoFinder.GetUserInfo("U99999")
+	require.NoError(t, s.Shutdown(context.Background()))
+
+	// Restart with slack down: users cached before the restart are still found
+	s, err = New("robert", v, OptionResponseStorer(storer))
+	require.NoError(t, err)
+
+	karma = &Plugin{Name: "karma"}
+	s.RegisterPlugin(karma)
+
+	restartedLoader := &flakyUserInfoFinder{failing: true}
+	require.NoError(t, s.injectServicesToPlugins(restartedLoader, s.log, nil, nil, nil, nil))
+
+	for _, userID := range []string{"U21355", "U99999"} {
+		u, err := karma.UserInfoFinder.GetUserInfo(userID)
+		require.NoError(t, err)
+		assert.Equal(t, "Daniel Quinn", u.RealName)
+	}
+	assert.Equal(t, 0, restartedLoader.calls)
+}
+
+func TestExpiredUserInfoCacheNotReloaded(t *testing.T) {
+	storer, cleanup := newFeatureFlagStorer(t)
+	defer cleanup()
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.UserInfoCacheSizeKey, 10)
+
+	s, err := New("robert", v, OptionResponseStorer(storer))
+	require.NoError(t, err)
+
+	loader := &flakyUserInfoFinder{}
+	require.NoError(t, s.injectServicesToPlugins(loader, s.log, nil, nil, nil, nil))
+	s.userInfoFinder.GetUserInfo("U21355")
+
+	now := time.Now()
+	saved, err := s.saveUserInfoCache(now.Add(-25 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved)
+
+	loaded, err := s.loadUserInfoCache(now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, loaded)
+
+	// Users no longer in cache are deleted on the next save
+	s.userInfoFinder.(UserInfoInvalidator).InvalidateUser("U21355")
+	saved, err = s.saveUserInfoCache(now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, saved)
+
+	entries, err := storer.ScanSilo(userInfoCacheSilo)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
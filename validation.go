@@ -0,0 +1,282 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of block kit elements, as documented at https://api.slack.com/reference/block-kit. Answers that don't respect
+// them are rejected before being sent (see validateMessage) rather than failing with slack's opaque invalid_blocks error
+const (
+	maxBlockIDLength      = 255
+	maxSectionTextLength  = 3000
+	maxSectionFields      = 10
+	maxSectionFieldLength = 2000
+	maxContextElements    = 10
+	maxActionElements     = 25
+	maxButtonTextLength   = 75
+	maxActionIDLength     = 255
+	maxButtonValueLength  = 2000
+	maxURLLength          = 3000
+	maxImageAltTextLength = 2000
+	maxImageTitleLength   = 2000
+	maxContextTextLength  = 2000
+	maxConfirmTitleLength = 100
+	maxConfirmTextLength  = 300
+)
+
+// InvalidAnswerError is the error of an answer that slack would reject. Each violation names the offending block or
+// element along with the limit it exceeds so that it can be fixed by the plugin that answered
+type InvalidAnswerError struct {
+	Violations []string
+}
+
+// Error returns all the violations of the answer
+func (e *InvalidAnswerError) Error() string {
+	return fmt.Sprintf("invalid answer: %s", strings.Join(e.Violations, "; "))
+}
+
+// ValidateAnswer checks an answer's content blocks and interactive elements against slack's limits and its text for
+// invalid mrkdwn, returning an InvalidAnswerError listing the violations, if any. The length of the text and the
+// number of blocks aren't validated since answers exceeding them are split into many messages. Plugins can use it to
+// validate their answers in tests or before sending messages on their own
+func ValidateAnswer(answer *Answer) (err error) {
+	v := new(answerValidator)
+	v.validateMrkdwn("text", answer.Text)
+	v.validateBlocks(answer.ContentBlocks)
+
+	if len(answer.InteractiveElements) > 0 {
+		v.validateActionElements("interactive elements", answer.InteractiveElements)
+	}
+
+	return v.err()
+}
+
+// validateMessage checks the text and blocks of a message about to be sent (or updated) against slack's limits,
+// including the length of the text and the number of blocks
+func validateMessage(text string, blocks []slack.Block) (err error) {
+	v := new(answerValidator)
+
+	if length := utf8.RuneCountInString(text); length > maxMessageTextLength {
+		v.addViolation("text has %d characters, more than the %d allowed", length, maxMessageTextLength)
+	}
+
+	if len(blocks) > maxMessageBlocks {
+		v.addViolation("message has %d blocks, more than the %d allowed", len(blocks), maxMessageBlocks)
+	}
+
+	v.validateMrkdwn("text", text)
+	v.validateBlocks(blocks)
+
+	return v.err()
+}
+
+// answerValidator accumulates the violations of slack's limits found in an answer
+type answerValidator struct {
+	violations []string
+}
+
+// err returns an InvalidAnswerError with the violations found or nil if there are none
+func (v *answerValidator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+
+	return &InvalidAnswerError{Violations: v.violations}
+}
+
+func (v *answerValidator) addViolation(format string, args ...interface{}) {
+	v.violations = append(v.violations, fmt.Sprintf(format, args...))
+}
+
+// validateLength adds a violation if value is longer than maxLength characters
+func (v *answerValidator) validateLength(field string, value string, maxLength int) {
+	if length := utf8.RuneCountInString(value); length > maxLength {
+		v.addViolation("%s has %d characters, more than the %d allowed", field, length, maxLength)
+	}
+}
+
+func (v *answerValidator) validateBlocks(blocks []slack.Block) {
+	for i, block := range blocks {
+		v.validateBlock(fmt.Sprintf("block [%d]", i), block)
+	}
+}
+
+func (v *answerValidator) validateBlock(name string, block slack.Block) {
+	switch b := block.(type) {
+	case *slack.SectionBlock:
+		v.validateSection(name, *b)
+	case slack.SectionBlock:
+		v.validateSection(name, b)
+	case *slack.ContextBlock:
+		v.validateContext(name, *b)
+	case slack.ContextBlock:
+		v.validateContext(name, b)
+	case *slack.ActionBlock:
+		v.validateActions(name, *b)
+	case slack.ActionBlock:
+		v.validateActions(name, b)
+	case *slack.ImageBlock:
+		v.validateImage(name, *b)
+	case slack.ImageBlock:
+		v.validateImage(name, b)
+	}
+}
+
+func (v *answerValidator) validateSection(name string, b slack.SectionBlock) {
+	name = fmt.Sprintf("%s (section)", name)
+	v.validateLength(name+" block_id", b.BlockID, maxBlockIDLength)
+
+	if b.Text == nil && len(b.Fields) == 0 {
+		v.addViolation("%s needs a text or fields", name)
+	}
+
+	if b.Text != nil {
+		v.validateText(name+" text", b.Text, maxSectionTextLength)
+	}
+
+	if len(b.Fields) > maxSectionFields {
+		v.addViolation("%s has %d fields, more than the %d allowed", name, len(b.Fields), maxSectionFields)
+	}
+
+	for i, field := range b.Fields {
+		v.validateText(fmt.Sprintf("%s field [%d]", name, i), field, maxSectionFieldLength)
+	}
+}
+
+func (v *answerValidator) validateContext(name string, b slack.ContextBlock) {
+	name = fmt.Sprintf("%s (context)", name)
+	v.validateLength(name+" block_id", b.BlockID, maxBlockIDLength)
+
+	if len(b.ContextElements.Elements) > maxContextElements {
+		v.addViolation("%s has %d elements, more than the %d allowed", name, len(b.ContextElements.Elements), maxContextElements)
+	}
+
+	for i, element := range b.ContextElements.Elements {
+		switch text := element.(type) {
+		case *slack.TextBlockObject:
+			v.validateText(fmt.Sprintf("%s element [%d]", name, i), text, maxContextTextLength)
+		case slack.TextBlockObject:
+			v.validateText(fmt.Sprintf("%s element [%d]", name, i), &text, maxContextTextLength)
+		}
+	}
+}
+
+func (v *answerValidator) validateActions(name string, b slack.ActionBlock) {
+	name = fmt.Sprintf("%s (actions)", name)
+	v.validateLength(name+" block_id", b.BlockID, maxBlockIDLength)
+	v.validateActionElements(name, b.Elements.ElementSet)
+}
+
+func (v *answerValidator) validateActionElements(name string, elements []slack.BlockElement) {
+	if len(elements) > maxActionElements {
+		v.addViolation("%s has %d elements, more than the %d allowed", name, len(elements), maxActionElements)
+	}
+
+	for i, element := range elements {
+		if button, ok := element.(*slack.ButtonBlockElement); ok {
+			v.validateButton(fmt.Sprintf("%s button [%d]", name, i), *button)
+		}
+	}
+}
+
+func (v *answerValidator) validateButton(name string, b slack.ButtonBlockElement) {
+	if b.Text == nil {
+		v.addViolation("%s needs a text", name)
+	} else {
+		if b.Text.Type != slack.PlainTextType {
+			v.addViolation("%s text must be of type %s but was %s", name, slack.PlainTextType, b.Text.Type)
+		}
+
+		v.validateText(name+" text", b.Text, maxButtonTextLength)
+	}
+
+	v.validateLength(name+" action_id", b.ActionID, maxActionIDLength)
+	v.validateLength(name+" value", b.Value, maxButtonValueLength)
+	v.validateLength(name+" url", b.URL, maxURLLength)
+
+	if b.Confirm != nil {
+		v.validateText(name+" confirm title", b.Confirm.Title, maxConfirmTitleLength)
+		v.validateText(name+" confirm text", b.Confirm.Text, maxConfirmTextLength)
+	}
+}
+
+func (v *answerValidator) validateImage(name string, b slack.ImageBlock) {
+	name = fmt.Sprintf("%s (image)", name)
+	v.validateLength(name+" block_id", b.BlockID, maxBlockIDLength)
+
+	if b.ImageURL == "" {
+		v.addViolation("%s needs an image_url", name)
+	}
+
+	if b.AltText == "" {
+		v.addViolation("%s needs an alt_text", name)
+	}
+
+	v.validateLength(name+" image_url", b.ImageURL, maxURLLength)
+	v.validateLength(name+" alt_text", b.AltText, maxImageAltTextLength)
+
+	if b.Title != nil {
+		v.validateText(name+" title", b.Title, maxImageTitleLength)
+	}
+}
+
+// validateText checks a text object's type, length and, if it's mrkdwn, its formatting
+func (v *answerValidator) validateText(name string, text *slack.TextBlockObject, maxLength int) {
+	if text == nil {
+		return
+	}
+
+	if text.Type != slack.PlainTextType && text.Type != slack.MarkdownType {
+		v.addViolation("%s must be of type %s or %s but was [%s]", name, slack.PlainTextType, slack.MarkdownType, text.Type)
+	}
+
+	if text.Text == "" {
+		v.addViolation("%s is empty", name)
+	}
+
+	v.validateLength(name, text.Text, maxLength)
+
+	if text.Type == slack.MarkdownType {
+		v.validateMrkdwn(name, text.Text)
+	}
+}
+
+// validateMrkdwn adds a violation for every link or mention (i.e. <@U21355> or <https://example.com|example>) that
+// isn't closed. Those render as garbage or get the message rejected
+func (v *answerValidator) validateMrkdwn(name string, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		for start := strings.Index(line, "<"); start != -1; start = strings.Index(line, "<") {
+			line = line[start+1:]
+			if !isMrkdwnControlSequence(line) {
+				continue
+			}
+
+			if end := strings.IndexAny(line, "<>"); end == -1 || line[end] != '>' {
+				v.addViolation("%s has an unclosed link or mention [<%s] (close it with > or escape < as &lt;)", name, truncate(line, 30))
+			}
+		}
+	}
+}
+
+// isMrkdwnControlSequence returns true if text (following a <) starts a mention (user, channel or special) or a link
+func isMrkdwnControlSequence(text string) bool {
+	for _, prefix := range []string{"@", "#", "!", "http://", "https://", "mailto:"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// truncate returns the first maxLength characters of text followed by an ellipsis if it's longer
+func truncate(text string, maxLength int) string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	return text[:byteOffset(text, maxLength)] + "…"
+}
@@ -0,0 +1,90 @@
+package slackscot
+
+import (
+	"errors"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestValidateAnswer(t *testing.T) {
+	button := slack.NewButtonBlockElement("yes", "pizza", slack.NewTextBlockObject(slack.PlainTextType, "Yes", false, false))
+	markdownButton := slack.NewButtonBlockElement("no", "salad", slack.NewTextBlockObject(slack.MarkdownType, "*No*", false, false))
+
+	tests := map[string]struct {
+		answer             Answer
+		expectedViolations []string
+	}{
+		"Valid":                {answer: Answer{Text: "Hi <@U21355>, see <https://example.com|this>", ContentBlocks: newSectionBlocks(2), InteractiveElements: []slack.BlockElement{button}}},
+		"LongTextSplitInstead": {answer: Answer{Text: strings.Repeat("a", maxMessageTextLength+1), ContentBlocks: newSectionBlocks(maxMessageBlocks + 1)}},
+		"UnclosedMention": {answer: Answer{Text: "Hi <@U21355, how are you?"},
+			expectedViolations: []string{"text has an unclosed link or mention [<@U21355, how are you?] (close it with > or escape < as &lt;)"}},
+		"EscapedLessThan": {answer: Answer{Text: "1 < 2 and 3 > 2"}},
+		"LongSectionText": {answer: Answer{ContentBlocks: []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, strings.Repeat("a", maxSectionTextLength+1), false, false), nil, nil)}},
+			expectedViolations: []string{"block [0] (section) text has 3001 characters, more than the 3000 allowed"}},
+		"EmptySection": {answer: Answer{ContentBlocks: []slack.Block{slack.NewSectionBlock(nil, nil, nil), slack.NewSectionBlock(slack.NewTextBlockObject(slack.PlainTextType, "", false, false), nil, nil)}},
+			expectedViolations: []string{"block [0] (section) needs a text or fields", "block [1] (section) text is empty"}},
+		"TooManyFields": {answer: Answer{ContentBlocks: []slack.Block{slack.NewSectionBlock(nil, newTextObjects(maxSectionFields+1), nil)}},
+			expectedViolations: []string{"block [0] (section) has 11 fields, more than the 10 allowed"}},
+		"InvalidTextType": {answer: Answer{ContentBlocks: []slack.Block{*slack.NewContextBlock("", *slack.NewTextBlockObject("markdown", "hi", false, false))}},
+			expectedViolations: []string{"block [0] (context) element [0] must be of type plain_text or mrkdwn but was [markdown]"}},
+		"ImageWithoutAltText": {answer: Answer{ContentBlocks: []slack.Block{slack.NewImageBlock("https://example.com/pizza.png", "", "", nil)}},
+			expectedViolations: []string{"block [0] (image) needs an alt_text"}},
+		"MarkdownButton": {answer: Answer{InteractiveElements: []slack.BlockElement{button, markdownButton}},
+			expectedViolations: []string{"interactive elements button [1] text must be of type plain_text but was mrkdwn"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateAnswer(&tc.answer)
+			if len(tc.expectedViolations) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var invalidErr *InvalidAnswerError
+			if assert.True(t, errors.As(err, &invalidErr)) {
+				assert.Equal(t, tc.expectedViolations, invalidErr.Violations)
+			}
+		})
+	}
+}
+
+func TestValidateMessageLimits(t *testing.T) {
+	err := validateMessage(strings.Repeat("a", maxMessageTextLength+1), newSectionBlocks(maxMessageBlocks+1))
+
+	assert.EqualError(t, err, "invalid answer: text has 4001 characters, more than the 4000 allowed; message has 51 blocks, more than the 50 allowed")
+}
+
+func newTextObjects(count int) (texts []*slack.TextBlockObject) {
+	for i := 0; i < count; i++ {
+		texts = append(texts, slack.NewTextBlockObject(slack.PlainTextType, "field", false, false))
+	}
+
+	return texts
+}
+
+func newInvalidBlocksPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "broken"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "broken")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "broken", ContentBlocks: []slack.Block{slack.NewSectionBlock(nil, nil, nil)}}
+		},
+	}}
+
+	return p
+}
+
+func TestInvalidAnswerNotSent(t *testing.T) {
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, newInvalidBlocksPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "broken", "Alphonse", timestamp1)),
+	})
+
+	assert.Empty(t, sentMsgs)
+	assert.Contains(t, logs, "Unable to send new message triggered by [Cgeneral/1546833210.036900]: answer of [broken.hearAction[0]] not sent: invalid answer: block [0] (section) needs a text or fields")
+}
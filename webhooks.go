@@ -0,0 +1,139 @@
+package slackscot
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/assets"
+	"github.com/alexandre-normand/slackscot/config"
+	"net/http"
+	"strings"
+)
+
+const (
+	// WebhookPathPrefix is the path prefix of all webhooks. A plugin's webhook is served at /webhooks/<plugin name>/<path>
+	WebhookPathPrefix = "/webhooks/"
+
+	// WebhookSecretHeader is the header webhook requests must include with the shared secret when one is configured
+	WebhookSecretHeader = "X-Slackscot-Webhook-Secret"
+)
+
+// WebhookDefinition represents an inbound http endpoint of a plugin, used to receive events from other
+// systems (i.e. CI servers, issue trackers or alerting). Webhooks are only served when config.WebhookListenAddressKey is set
+type WebhookDefinition struct {
+	// Path of the webhook, relative to /webhooks/<plugin name>/
+	Path string
+
+	// Handle is the function invoked for every request to the webhook. Since webhooks are received outside of
+	// any conversation, handlers post messages with the RealTimeMsgSender or the SlackClient
+	Handle http.HandlerFunc
+}
+
+// webhookPath returns the full path of a plugin's webhook
+func webhookPath(pluginName string, path string) string {
+	return WebhookPathPrefix + pluginName + "/" + strings.TrimPrefix(path, "/")
+}
+
+// newWebhookHandler creates the http handler routing requests to the webhooks of all plugins. If the secret isn't empty,
+// requests without the matching WebhookSecretHeader are rejected. An error is returned if two webhooks have the same path
+func newWebhookHandler(plugins []*Plugin, secret string, logger SLogger) (handler http.Handler, err error) {
+	mux := http.NewServeMux()
+	paths := make(map[string]bool)
+
+	for _, p := range plugins {
+		for _, w := range p.Webhooks {
+			path := webhookPath(p.Name, w.Path)
+			if paths[path] {
+				return nil, fmt.Errorf("Duplicate webhook path [%s] for plugin [%s]", path, p.Name)
+			}
+
+			paths[path] = true
+			logger.Debugf("Registering webhook [%s] for plugin [%s]\n", path, p.Name)
+			mux.HandleFunc(path, w.Handle)
+		}
+	}
+
+	if secret == "" {
+		return mux, nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(WebhookSecretHeader)), []byte(secret)) != 1 {
+			logger.Printf("Rejecting webhook request to [%s] with missing or invalid secret", r.URL.Path)
+			http.Error(w, "invalid webhook secret", http.StatusUnauthorized)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// newWebhookServer creates the server of the plugins' webhooks, slash commands, interactions, workflow steps and assets
+// on the given address. It's started by serveWebhooks once services are injected into plugins
+func (s *Slackscot) newWebhookServer(address string, secret string, signingSecret string) (err error) {
+	handler, err := newWebhookHandler(s.plugins, secret, s.log)
+	if err != nil {
+		return err
+	}
+
+	slashCommandHandler, err := newSlashCommandHandler(s.plugins, signingSecret, s.log)
+	if err != nil {
+		return err
+	}
+
+	workflowSteps, err := newWorkflowStepRouter(s.plugins, newSlackWorkflowStepsAPI(s.config.GetString(config.TokenKey)), s.log)
+	if err != nil {
+		return err
+	}
+
+	interactionHandler, err := newInteractionHandler(s.plugins, signingSecret, workflowSteps, s.log)
+	if err != nil {
+		return err
+	}
+
+	workflowEventHandler, err := newWorkflowStepEventHandler(workflowSteps, signingSecret, s.log)
+	if err != nil {
+		return err
+	}
+
+	// Slash command, interaction and workflow step event requests are signed by slack and assets are fetched by slack
+	// clients so none of those include the webhook secret and they're routed before it's checked
+	mux := http.NewServeMux()
+	if slashCommandHandler != nil {
+		mux.Handle(SlashCommandPath, slashCommandHandler)
+	}
+
+	if interactionHandler != nil {
+		mux.Handle(InteractionPath, interactionHandler)
+	}
+
+	if workflowEventHandler != nil {
+		mux.Handle(WorkflowEventPath, workflowEventHandler)
+	}
+
+	mux.Handle(assets.PathPrefix, s.assets)
+	mux.Handle("/", handler)
+
+	s.webhookServer = &http.Server{Addr: address, Handler: mux}
+	return nil
+}
+
+// serveWebhooks starts the webhook server in a go routine, if created. Errors while serving are logged
+func (s *Slackscot) serveWebhooks() {
+	if s.webhookServer == nil {
+		return
+	}
+
+	go func(server *http.Server) {
+		s.log.Printf("Serving webhooks on [%s]\n", server.Addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.log.Printf("Error serving webhooks on [%s]: %v\n", server.Addr, err)
+		}
+	}(s.webhookServer)
+}
+
+// stopWebhookServer stops the webhook server, if started
+func (s *Slackscot) stopWebhookServer() {
+	if s.webhookServer != nil {
+		s.webhookServer.Close()
+	}
+}
@@ -0,0 +1,72 @@
+package slackscot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newWebhookPlugin(name string, paths ...string) (p *Plugin) {
+	p = &Plugin{Name: name}
+	for _, path := range paths {
+		body := name + ":" + path
+		p.Webhooks = append(p.Webhooks, WebhookDefinition{Path: path, Handle: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}})
+	}
+
+	return p
+}
+
+func TestWebhookRouting(t *testing.T) {
+	handler, err := newWebhookHandler([]*Plugin{newWebhookPlugin("ci", "build", "/deploy"), newWebhookPlugin("jira", "build")}, "", NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"/webhooks/ci/build", http.StatusOK, "ci:build"},
+		{"/webhooks/ci/deploy", http.StatusOK, "ci:/deploy"},
+		{"/webhooks/jira/build", http.StatusOK, "jira:build"},
+		{"/webhooks/jira/deploy", http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("POST", tc.path, nil))
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Equal(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestWebhookSharedSecret(t *testing.T) {
+	var logs strings.Builder
+	handler, err := newWebhookHandler([]*Plugin{newWebhookPlugin("ci", "build")}, "s3cr3t", NewSLogger(log.New(&logs, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhooks/ci/build", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Rejecting webhook request to [/webhooks/ci/build] with missing or invalid secret\n", logs.String())
+
+	req := httptest.NewRequest("POST", "/webhooks/ci/build", nil)
+	req.Header.Set(WebhookSecretHeader, "s3cr3t")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ci:build", rec.Body.String())
+}
+
+func TestWebhookDuplicatePaths(t *testing.T) {
+	_, err := newWebhookHandler([]*Plugin{newWebhookPlugin("ci", "build", "/build")}, "", NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.EqualError(t, err, "Duplicate webhook path [/webhooks/ci/build] for plugin [ci]")
+}
@@ -0,0 +1,403 @@
+package slackscot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"net/http"
+)
+
+const (
+	// WorkflowEventPath is the path slack sends the events of workflow steps to (the events request URL of the slack
+	// app). Like slash commands, it's served by the webhook server so workflow steps are only executed when
+	// config.WebhookListenAddressKey is set
+	WorkflowEventPath = "/slack/events"
+
+	workflowStepViewType          = "workflow_step"
+	workflowStepEditType          = "workflow_step_edit"
+	workflowStepExecuteEventType  = "workflow_step_execute"
+	eventCallbackType             = "event_callback"
+	urlVerificationType           = "url_verification"
+	workflowStepInputActionSuffix = ".value"
+)
+
+// Types of workflow step outputs
+const (
+	WorkflowStepOutputText    = "text"
+	WorkflowStepOutputUser    = "user"
+	WorkflowStepOutputChannel = "channel"
+)
+
+// WorkflowStepDefinition represents a custom step of slack's Workflow Builder (i.e. "give karma") handled by a plugin.
+// Steps need to be added to the slack app (in its Workflow Steps section) with the same CallbackID. When a step is
+// added to a workflow, slackscot opens its configuration view with an input for each of the step's Inputs and
+// saves the values entered (which can include variables of the workflow) when submitted. When the workflow runs,
+// the step is executed with those values
+type WorkflowStepDefinition struct {
+	// CallbackID of the step, as set in the slack app
+	CallbackID string
+
+	// Inputs configured when the step is added to a workflow
+	Inputs []WorkflowStepInput
+
+	// Outputs of the step, usable by the following steps of a workflow
+	Outputs []WorkflowStepOutput
+
+	// Function to execute when a workflow runs the step
+	Execute WorkflowStepExecutor
+}
+
+// WorkflowStepInput is an input of a workflow step, configured with a text field of the step's configuration view
+type WorkflowStepInput struct {
+	// Name of the input, used as the key of its value in WorkflowStep.Inputs
+	Name string
+
+	// Label of the input's text field
+	Label string
+
+	// Optional placeholder of the input's text field
+	Placeholder string
+
+	// Set to true if the input doesn't have to be configured
+	Optional bool
+
+	// Set to true for a multi-line text field
+	Multiline bool
+}
+
+// WorkflowStepOutput is an output of a workflow step. Its value is given by the WorkflowStepExecutor
+type WorkflowStepOutput struct {
+	// Name of the output, used as the key of its value in the outputs of the WorkflowStepExecutor
+	Name string `json:"name"`
+
+	// Type of the output, one of WorkflowStepOutputText, WorkflowStepOutputUser or WorkflowStepOutputChannel
+	Type string `json:"type"`
+
+	// Label of the output, shown to workflow creators
+	Label string `json:"label"`
+}
+
+// WorkflowStep is an execution of a workflow step
+type WorkflowStep struct {
+	// CallbackID of the step
+	CallbackID string
+
+	// ExecuteID identifies this execution of the step
+	ExecuteID string
+
+	// Inputs holds the values of the step's inputs (with the workflow's variables replaced), by name
+	Inputs map[string]string
+}
+
+// WorkflowStepExecutor is what gets executed when a workflow runs a step. The outputs returned, by name, are given
+// to the following steps of the workflow. On error, the workflow run is stopped with the error's message
+type WorkflowStepExecutor func(step *WorkflowStep) (outputs map[string]string, err error)
+
+// workflowStepHandler is a plugin's handler of a workflow step
+type workflowStepHandler struct {
+	plugin string
+	step   WorkflowStepDefinition
+}
+
+// workflowStepValue is the value of a workflow step input
+type workflowStepValue struct {
+	Value string `json:"value"`
+}
+
+// workflowStepView is the configuration view of a workflow step
+type workflowStepView struct {
+	Type       string        `json:"type"`
+	CallbackID string        `json:"callback_id"`
+	Blocks     []slack.Block `json:"blocks"`
+}
+
+// workflowStepInteraction is an interaction payload with the workflow step fields that slack.InteractionCallback
+// doesn't have
+type workflowStepInteraction struct {
+	Type         string `json:"type"`
+	CallbackID   string `json:"callback_id"`
+	TriggerID    string `json:"trigger_id"`
+	WorkflowStep struct {
+		WorkflowStepEditID string                       `json:"workflow_step_edit_id"`
+		Inputs             map[string]workflowStepValue `json:"inputs"`
+	} `json:"workflow_step"`
+	View struct {
+		Type       string `json:"type"`
+		CallbackID string `json:"callback_id"`
+		State      struct {
+			Values map[string]map[string]workflowStepValue `json:"values"`
+		} `json:"state"`
+	} `json:"view"`
+}
+
+// workflowStepEvent is an events API payload, of which only workflow_step_execute events are handled
+type workflowStepEvent struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type         string `json:"type"`
+		CallbackID   string `json:"callback_id"`
+		WorkflowStep struct {
+			WorkflowStepExecuteID string                       `json:"workflow_step_execute_id"`
+			Inputs                map[string]workflowStepValue `json:"inputs"`
+		} `json:"workflow_step"`
+	} `json:"event"`
+}
+
+// workflowStepsAPI is implemented by any value that has the methods of slack's web api needed by workflow steps
+type workflowStepsAPI interface {
+	openView(triggerID string, view workflowStepView) (err error)
+	updateStep(editID string, inputs map[string]workflowStepValue, outputs []WorkflowStepOutput) (err error)
+	completeStep(executeID string, outputs map[string]string) (err error)
+	failStep(executeID string, message string) (err error)
+}
+
+// slackWorkflowStepsAPI implements workflowStepsAPI with calls to slack's web api (which slack.Client doesn't support)
+type slackWorkflowStepsAPI struct {
+	token  string
+	apiURL string
+	client *http.Client
+}
+
+// workflowStepRouter routes workflow step interactions and events to the plugins' workflow steps
+type workflowStepRouter struct {
+	steps  map[string]workflowStepHandler
+	api    workflowStepsAPI
+	logger SLogger
+}
+
+// newWorkflowStepRouter creates the router of the workflow steps of all plugins. A nil router is returned if no plugin
+// has workflow steps and an error is returned if two plugins have steps with the same callback id
+func newWorkflowStepRouter(plugins []*Plugin, api workflowStepsAPI, logger SLogger) (router *workflowStepRouter, err error) {
+	steps := make(map[string]workflowStepHandler)
+
+	for _, p := range plugins {
+		for _, ws := range p.WorkflowSteps {
+			if existing, ok := steps[ws.CallbackID]; ok {
+				return nil, fmt.Errorf("Duplicate workflow step [%s] for plugin [%s], already registered by plugin [%s]", ws.CallbackID, p.Name, existing.plugin)
+			}
+
+			logger.Debugf("Registering workflow step [%s] for plugin [%s]\n", ws.CallbackID, p.Name)
+			steps[ws.CallbackID] = workflowStepHandler{plugin: p.Name, step: ws}
+		}
+	}
+
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	return &workflowStepRouter{steps: steps, api: api, logger: logger}, nil
+}
+
+// handleInteraction handles the interactions of workflow steps: opening a step's configuration view when it's added
+// to (or edited in) a workflow and saving its inputs when the view is submitted. It returns false, without writing
+// a response, for interactions that aren't about workflow steps
+func (wr *workflowStepRouter) handleInteraction(w http.ResponseWriter, payload []byte) (handled bool) {
+	var interaction workflowStepInteraction
+	if err := json.Unmarshal(payload, &interaction); err != nil {
+		return false
+	}
+
+	switch {
+	case interaction.Type == workflowStepEditType:
+		if handler, ok := wr.steps[interaction.CallbackID]; ok {
+			wr.logger.Debugf("Opening configuration of workflow step [%s] of plugin [%s]\n", interaction.CallbackID, handler.plugin)
+			if err := wr.api.openView(interaction.TriggerID, newWorkflowStepView(handler.step, interaction.WorkflowStep.Inputs)); err != nil {
+				wr.logger.Printf("Error opening configuration of workflow step [%s]: %v", interaction.CallbackID, err)
+			}
+		} else {
+			wr.logger.Printf("Ignoring edit of unknown workflow step [%s]", interaction.CallbackID)
+		}
+
+	case interaction.Type == string(slack.InteractionTypeViewSubmission) && interaction.View.Type == workflowStepViewType:
+		if handler, ok := wr.steps[interaction.View.CallbackID]; ok {
+			wr.logger.Debugf("Saving configuration of workflow step [%s] of plugin [%s]\n", interaction.View.CallbackID, handler.plugin)
+			if err := wr.api.updateStep(interaction.WorkflowStep.WorkflowStepEditID, workflowStepInputValues(handler.step, interaction.View.State.Values), handler.step.Outputs); err != nil {
+				wr.logger.Printf("Error saving configuration of workflow step [%s]: %v", interaction.View.CallbackID, err)
+			}
+		} else {
+			wr.logger.Printf("Ignoring configuration of unknown workflow step [%s]", interaction.View.CallbackID)
+		}
+
+	default:
+		return false
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
+// execute runs a workflow step and reports its completion (with its outputs) or failure to slack
+func (wr *workflowStepRouter) execute(callbackID string, executeID string, inputs map[string]workflowStepValue) {
+	handler, ok := wr.steps[callbackID]
+	if !ok {
+		wr.logger.Printf("Ignoring execution of unknown workflow step [%s]", callbackID)
+		return
+	}
+
+	step := WorkflowStep{CallbackID: callbackID, ExecuteID: executeID, Inputs: make(map[string]string)}
+	for name, input := range inputs {
+		step.Inputs[name] = input.Value
+	}
+
+	wr.logger.Debugf("Executing workflow step [%s] of plugin [%s]\n", callbackID, handler.plugin)
+	outputs, err := handler.step.Execute(&step)
+	if err != nil {
+		wr.logger.Printf("Workflow step [%s] of plugin [%s] failed: %v", callbackID, handler.plugin, err)
+		err = wr.api.failStep(executeID, err.Error())
+	} else {
+		err = wr.api.completeStep(executeID, outputs)
+	}
+
+	if err != nil {
+		wr.logger.Printf("Error reporting execution of workflow step [%s]: %v", callbackID, err)
+	}
+}
+
+// newWorkflowStepEventHandler creates the http handler of the events API requests that execute workflow steps. Requests
+// must be signed with the slack app's signing secret. A nil handler is returned if there are no workflow steps and an
+// error is returned if the signing secret is missing
+func newWorkflowStepEventHandler(router *workflowStepRouter, signingSecret string, logger SLogger) (handler http.Handler, err error) {
+	if router == nil {
+		return nil, nil
+	}
+
+	if signingSecret == "" {
+		return nil, fmt.Errorf("Missing signing secret, required to verify workflow step requests")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, status, err := readVerifiedBody(r, signingSecret)
+		if err != nil {
+			logger.Printf("Rejecting workflow step event request: %v", err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		var e workflowStepEvent
+		if err = json.Unmarshal(body, &e); err != nil {
+			logger.Printf("Rejecting workflow step event request: %v", err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case e.Type == urlVerificationType:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(e.Challenge))
+			return
+
+		case e.Type == eventCallbackType && e.Event.Type == workflowStepExecuteEventType:
+			// Acknowledge right away since slack retries events not acknowledged within 3 seconds
+			go router.execute(e.Event.CallbackID, e.Event.WorkflowStep.WorkflowStepExecuteID, e.Event.WorkflowStep.Inputs)
+
+		default:
+			logger.Debugf("Ignoring [%s] event [%s]\n", e.Type, e.Event.Type)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}), nil
+}
+
+// workflowStepInputActionID returns the action id of the text field of a workflow step input
+func workflowStepInputActionID(name string) string {
+	return name + workflowStepInputActionSuffix
+}
+
+// newWorkflowStepView returns the configuration view of a workflow step with a text field for each input, initialized
+// with the current value of the input, if any
+func newWorkflowStepView(step WorkflowStepDefinition, current map[string]workflowStepValue) (view workflowStepView) {
+	view = workflowStepView{Type: workflowStepViewType, CallbackID: step.CallbackID, Blocks: make([]slack.Block, 0)}
+
+	for _, input := range step.Inputs {
+		var placeholder *slack.TextBlockObject
+		if input.Placeholder != "" {
+			placeholder = slack.NewTextBlockObject(slack.PlainTextType, input.Placeholder, false, false)
+		}
+
+		element := slack.NewPlainTextInputBlockElement(placeholder, workflowStepInputActionID(input.Name))
+		element.Multiline = input.Multiline
+		element.InitialValue = current[input.Name].Value
+
+		block := slack.NewInputBlock(input.Name, slack.NewTextBlockObject(slack.PlainTextType, input.Label, false, false), element)
+		block.Optional = input.Optional
+		view.Blocks = append(view.Blocks, block)
+	}
+
+	return view
+}
+
+// workflowStepInputValues returns the values of a workflow step's inputs submitted with its configuration view
+func workflowStepInputValues(step WorkflowStepDefinition, values map[string]map[string]workflowStepValue) (inputs map[string]workflowStepValue) {
+	inputs = make(map[string]workflowStepValue)
+
+	for _, input := range step.Inputs {
+		if v, ok := values[input.Name][workflowStepInputActionID(input.Name)]; ok {
+			inputs[input.Name] = v
+		}
+	}
+
+	return inputs
+}
+
+// newSlackWorkflowStepsAPI creates a new slackWorkflowStepsAPI calling slack with the bot's token
+func newSlackWorkflowStepsAPI(token string) (api *slackWorkflowStepsAPI) {
+	return &slackWorkflowStepsAPI{token: token, apiURL: slack.APIURL, client: http.DefaultClient}
+}
+
+func (api *slackWorkflowStepsAPI) openView(triggerID string, view workflowStepView) (err error) {
+	return api.call("views.open", map[string]interface{}{"trigger_id": triggerID, "view": view})
+}
+
+func (api *slackWorkflowStepsAPI) updateStep(editID string, inputs map[string]workflowStepValue, outputs []WorkflowStepOutput) (err error) {
+	if outputs == nil {
+		outputs = make([]WorkflowStepOutput, 0)
+	}
+
+	return api.call("workflows.updateStep", map[string]interface{}{"workflow_step_edit_id": editID, "inputs": inputs, "outputs": outputs})
+}
+
+func (api *slackWorkflowStepsAPI) completeStep(executeID string, outputs map[string]string) (err error) {
+	if outputs == nil {
+		outputs = make(map[string]string)
+	}
+
+	return api.call("workflows.stepCompleted", map[string]interface{}{"workflow_step_execute_id": executeID, "outputs": outputs})
+}
+
+func (api *slackWorkflowStepsAPI) failStep(executeID string, message string) (err error) {
+	return api.call("workflows.stepFailed", map[string]interface{}{"workflow_step_execute_id": executeID, "error": map[string]string{"message": message}})
+}
+
+// call calls a method of slack's web api with a json body and returns an error if slack doesn't respond with ok
+func (api *slackWorkflowStepsAPI) call(method string, body interface{}) (err error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", api.apiURL+method, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+api.token)
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r slack.SlackResponse
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("Error decoding [%s] response (%s): %v", method, resp.Status, err)
+	}
+
+	if !r.Ok {
+		return fmt.Errorf("Error calling [%s]: %s", method, r.Error)
+	}
+
+	return nil
+}
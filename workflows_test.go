@@ -0,0 +1,258 @@
+package slackscot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// workflowStepsAPICall is a call made to the fake workflowStepsAPI
+type workflowStepsAPICall struct {
+	method string
+	id     string
+	args   interface{}
+}
+
+// fakeWorkflowStepsAPI records calls on a channel
+type fakeWorkflowStepsAPI struct {
+	calls chan workflowStepsAPICall
+}
+
+func (api *fakeWorkflowStepsAPI) openView(triggerID string, view workflowStepView) (err error) {
+	api.calls <- workflowStepsAPICall{method: "views.open", id: triggerID, args: view}
+	return nil
+}
+
+func (api *fakeWorkflowStepsAPI) updateStep(editID string, inputs map[string]workflowStepValue, outputs []WorkflowStepOutput) (err error) {
+	api.calls <- workflowStepsAPICall{method: "workflows.updateStep", id: editID, args: []interface{}{inputs, outputs}}
+	return nil
+}
+
+func (api *fakeWorkflowStepsAPI) completeStep(executeID string, outputs map[string]string) (err error) {
+	api.calls <- workflowStepsAPICall{method: "workflows.stepCompleted", id: executeID, args: outputs}
+	return nil
+}
+
+func (api *fakeWorkflowStepsAPI) failStep(executeID string, message string) (err error) {
+	api.calls <- workflowStepsAPICall{method: "workflows.stepFailed", id: executeID, args: message}
+	return nil
+}
+
+func newKarmaWorkflowPlugin() (p *Plugin) {
+	return &Plugin{Name: "karma", WorkflowSteps: []WorkflowStepDefinition{{
+		CallbackID: "give_karma",
+		Inputs:     []WorkflowStepInput{{Name: "thing", Label: "Thing", Placeholder: "Who or what gets karma"}, {Name: "reason", Label: "Reason", Optional: true, Multiline: true}},
+		Outputs:    []WorkflowStepOutput{{Name: "karma", Type: WorkflowStepOutputText, Label: "New karma"}},
+		Execute: func(step *WorkflowStep) (outputs map[string]string, err error) {
+			if step.Inputs["thing"] == "" {
+				return nil, fmt.Errorf("Nothing to give karma to")
+			}
+
+			return map[string]string{"karma": fmt.Sprintf("%s: 1", step.Inputs["thing"])}, nil
+		},
+	}}}
+}
+
+func newTestWorkflowStepRouter(t *testing.T) (router *workflowStepRouter, api *fakeWorkflowStepsAPI) {
+	api = &fakeWorkflowStepsAPI{calls: make(chan workflowStepsAPICall, 1)}
+	router, err := newWorkflowStepRouter([]*Plugin{newKarmaWorkflowPlugin()}, api, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	return router, api
+}
+
+func newWorkflowStepEventRequest(signingSecret string, event interface{}) (r *http.Request) {
+	body, _ := json.Marshal(event)
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, body)))
+
+	r = httptest.NewRequest("POST", WorkflowEventPath, strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	return r
+}
+
+func newWorkflowStepExecuteEvent(callbackID string, inputs map[string]string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for name, value := range inputs {
+		values[name] = map[string]string{"value": value}
+	}
+
+	return map[string]interface{}{"type": "event_callback", "event": map[string]interface{}{"type": "workflow_step_execute", "callback_id": callbackID,
+		"workflow_step": map[string]interface{}{"workflow_step_execute_id": "E123", "inputs": values}}}
+}
+
+func receiveWorkflowStepsAPICall(t *testing.T, api *fakeWorkflowStepsAPI) (call workflowStepsAPICall) {
+	select {
+	case call = <-api.calls:
+	case <-time.After(time.Second):
+		assert.Fail(t, "timed out waiting for a workflow steps api call")
+	}
+
+	return call
+}
+
+func TestWorkflowStepEditOpensConfigurationView(t *testing.T) {
+	router, api := newTestWorkflowStepRouter(t)
+	handler, err := newInteractionHandler(nil, testSigningSecret, router, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newInteractionRequest(testSigningSecret, map[string]interface{}{"type": "workflow_step_edit", "callback_id": "give_karma", "trigger_id": "T123",
+		"workflow_step": map[string]interface{}{"workflow_step_edit_id": "W123", "inputs": map[string]interface{}{"thing": map[string]string{"value": "{{user}}"}}}}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	call := receiveWorkflowStepsAPICall(t, api)
+	assert.Equal(t, "views.open", call.method)
+	assert.Equal(t, "T123", call.id)
+
+	view, err := json.Marshal(call.args)
+	require.NoError(t, err)
+	assert.Equal(t, `{"type":"workflow_step","callback_id":"give_karma","blocks":[`+
+		`{"type":"input","block_id":"thing","label":{"type":"plain_text","text":"Thing"},"element":{"type":"plain_text_input","action_id":"thing.value","placeholder":{"type":"plain_text","text":"Who or what gets karma"},"initial_value":"{{user}}"}},`+
+		`{"type":"input","block_id":"reason","label":{"type":"plain_text","text":"Reason"},"element":{"type":"plain_text_input","action_id":"reason.value","multiline":true},"optional":true}]}`, string(view))
+}
+
+func TestWorkflowStepConfigurationSaved(t *testing.T) {
+	router, api := newTestWorkflowStepRouter(t)
+	handler, err := newInteractionHandler(nil, testSigningSecret, router, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newInteractionRequest(testSigningSecret, map[string]interface{}{"type": "view_submission", "workflow_step": map[string]string{"workflow_step_edit_id": "W123"},
+		"view": map[string]interface{}{"type": "workflow_step", "callback_id": "give_karma", "state": map[string]interface{}{"values": map[string]interface{}{
+			"thing": map[string]interface{}{"thing.value": map[string]string{"type": "plain_text_input", "value": "{{user}}"}}}}}}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	call := receiveWorkflowStepsAPICall(t, api)
+	assert.Equal(t, "workflows.updateStep", call.method)
+	assert.Equal(t, "W123", call.id)
+	assert.Equal(t, []interface{}{map[string]workflowStepValue{"thing": {Value: "{{user}}"}}, []WorkflowStepOutput{{Name: "karma", Type: "text", Label: "New karma"}}}, call.args)
+}
+
+func TestInteractionsStillRoutedToActionsWithWorkflowSteps(t *testing.T) {
+	router, _ := newTestWorkflowStepRouter(t)
+	interactions := make(chan *Interaction, 1)
+	handler, err := newInteractionHandler([]*Plugin{newInteractivePlugin(interactions)}, testSigningSecret, router, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newInteractionRequest(testSigningSecret, newBlockActionsPayload("slackscot:vote.command[1]", "yes", "pizza")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	interaction := <-interactions
+	assert.Equal(t, "slackscot:vote.command[1]", interaction.CallbackID)
+}
+
+func TestWorkflowStepExecuted(t *testing.T) {
+	router, api := newTestWorkflowStepRouter(t)
+	handler, err := newWorkflowStepEventHandler(router, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newWorkflowStepEventRequest(testSigningSecret, newWorkflowStepExecuteEvent("give_karma", map[string]string{"thing": "alexandre"})))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	call := receiveWorkflowStepsAPICall(t, api)
+	assert.Equal(t, workflowStepsAPICall{method: "workflows.stepCompleted", id: "E123", args: map[string]string{"karma": "alexandre: 1"}}, call)
+}
+
+func TestWorkflowStepExecutionFailure(t *testing.T) {
+	router, api := newTestWorkflowStepRouter(t)
+	handler, err := newWorkflowStepEventHandler(router, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newWorkflowStepEventRequest(testSigningSecret, newWorkflowStepExecuteEvent("give_karma", map[string]string{})))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	call := receiveWorkflowStepsAPICall(t, api)
+	assert.Equal(t, workflowStepsAPICall{method: "workflows.stepFailed", id: "E123", args: "Nothing to give karma to"}, call)
+}
+
+func TestWorkflowStepEventURLVerification(t *testing.T) {
+	router, _ := newTestWorkflowStepRouter(t)
+	handler, err := newWorkflowStepEventHandler(router, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newWorkflowStepEventRequest(testSigningSecret, map[string]string{"type": "url_verification", "challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P", rec.Body.String())
+}
+
+func TestWorkflowStepEventWithInvalidSignatureRejected(t *testing.T) {
+	router, api := newTestWorkflowStepRouter(t)
+	handler, err := newWorkflowStepEventHandler(router, testSigningSecret, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newWorkflowStepEventRequest("notTheSecret", newWorkflowStepExecuteEvent("give_karma", map[string]string{"thing": "alexandre"})))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Len(t, api.calls, 0)
+}
+
+func TestWorkflowStepRouterWithDuplicateCallbackIDs(t *testing.T) {
+	other := newKarmaWorkflowPlugin()
+	other.Name = "kudos"
+
+	_, err := newWorkflowStepRouter([]*Plugin{newKarmaWorkflowPlugin(), other}, &fakeWorkflowStepsAPI{}, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.EqualError(t, err, "Duplicate workflow step [give_karma] for plugin [kudos], already registered by plugin [karma]")
+}
+
+func TestNoWorkflowStepHandlersWithoutWorkflowSteps(t *testing.T) {
+	router, err := newWorkflowStepRouter([]*Plugin{newWebhookPlugin("ci", "build")}, &fakeWorkflowStepsAPI{}, NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.NoError(t, err)
+	assert.Nil(t, router)
+
+	handler, err := newWorkflowStepEventHandler(router, "", NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.NoError(t, err)
+	assert.Nil(t, handler)
+}
+
+func TestWorkflowStepEventHandlerWithoutSigningSecret(t *testing.T) {
+	router, _ := newTestWorkflowStepRouter(t)
+
+	_, err := newWorkflowStepEventHandler(router, "", NewSLogger(log.New(&strings.Builder{}, "", 0), false))
+	assert.EqualError(t, err, "Missing signing secret, required to verify workflow step requests")
+}
+
+func TestSlackWorkflowStepsAPICalls(t *testing.T) {
+	requests := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- fmt.Sprintf("%s %s %s", r.URL.Path, r.Header.Get("Authorization"), body)
+
+		if strings.HasSuffix(r.URL.Path, "workflows.stepFailed") {
+			w.Write([]byte(`{"ok":false,"error":"invalid_workflow_step_execute_id"}`))
+			return
+		}
+
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	api := newSlackWorkflowStepsAPI("xoxb-token")
+	api.apiURL = server.URL + "/api/"
+
+	assert.NoError(t, api.completeStep("E123", nil))
+	assert.Equal(t, `/api/workflows.stepCompleted Bearer xoxb-token {"outputs":{},"workflow_step_execute_id":"E123"}`, <-requests)
+
+	assert.EqualError(t, api.failStep("E123", "boom"), "Error calling [workflows.stepFailed]: invalid_workflow_step_execute_id")
+	assert.Equal(t, `/api/workflows.stepFailed Bearer xoxb-token {"error":{"message":"boom"},"workflow_step_execute_id":"E123"}`, <-requests)
+}